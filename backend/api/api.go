@@ -43,21 +43,22 @@ func GetUserInfo(event Request) *UserInfo {
 	return &UserInfo{Username: username, Email: email, Name: name}
 }
 
-// errorToResponse converts the given error into an AWS ApiGateway Response object.
-func errorToResponse(e *errors.Error) Response {
+// errorToResponse converts the given error into an AWS ApiGateway Response object. The error
+// is logged with the provided Logger.
+func errorToResponse(logger *log.Logger, e *errors.Error) Response {
 	if e == nil {
 		return Response{StatusCode: 200}
 	}
 
 	if e.Code >= 500 {
-		log.Error(e)
+		logger.Errorf("%v", e)
 	} else {
-		log.Warn(e)
+		logger.Warnf("%v", e)
 	}
 
 	var message string
 	if body, err := json.Marshal(e); err != nil {
-		log.Error("Cannot marshal error:", err)
+		logger.Errorf("Cannot marshal error: %v", err)
 		message = fmt.Sprintf("Unknown error (cannot marshal): %s", e.PublicMessage)
 	} else {
 		message = string(body)
@@ -74,14 +75,22 @@ func errorToResponse(e *errors.Error) Response {
 	}
 }
 
-// Failure converts the given error into an AWS ApiGateway Response object.
+// Failure converts the given error into an AWS ApiGateway Response object. The error is logged
+// with the default Logger, so handlers wrapped in Handle should return the error instead, which
+// logs it with the request's Logger.
 func Failure(err error) Response {
+	return failure(log.Default(), err)
+}
+
+// failure converts the given error into an AWS ApiGateway Response object. The error is logged
+// with the provided Logger.
+func failure(logger *log.Logger, err error) Response {
 	var lerr *errors.Error
 	if errors.As(err, &lerr) {
-		return errorToResponse(lerr)
+		return errorToResponse(logger, lerr)
 	}
 
-	log.Error(err)
+	logger.Errorf("%v", err)
 	var message string
 	body, err := json.Marshal(map[string]interface{}{
 		"code":    500,
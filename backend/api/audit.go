@@ -0,0 +1,21 @@
+package api
+
+import (
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// Audit records an admin or moderator action taken by the caller of the provided request.
+// before and after are the state of the target before and after the action, and either may
+// be nil. The action has already been performed when Audit is called, so failures are logged
+// rather than returned.
+func Audit(repository database.AuditEntryPutter, request Request, action database.AuditAction, target string, before, after any) {
+	actor := GetUserInfo(request).Username
+	entry, err := database.NewAuditEntry(actor, action, target, request.RequestContext.RequestID, before, after)
+	if err == nil {
+		err = repository.PutAuditEntry(entry)
+	}
+	if err != nil {
+		log.Default().With(log.RequestId(request.RequestContext.RequestID)).Errorf("Failed to record audit entry (actor %q, action %s, target %q): %v", actor, action, target, err)
+	}
+}
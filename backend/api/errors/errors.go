@@ -136,3 +136,27 @@ func (e *Error) Error() string {
 
 	return toString(e, 1)
 }
+
+// ConflictError is the cause of a 409 error returned when a write fails because the resource
+// was modified after it was read. The write can be retried after reading the resource again.
+type ConflictError struct {
+	// The ids of the resources which were modified.
+	Ids []string
+}
+
+// NewConflict returns a 409 error whose cause is a ConflictError for the provided resource ids.
+func NewConflict(publicMsg, privateMsg string, ids ...string) error {
+	return Wrap(409, publicMsg, privateMsg, &ConflictError{Ids: ids})
+}
+
+// Error returns a description of the error as a string.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("resources modified concurrently: %v", e.Ids)
+}
+
+// AsConflict returns the ConflictError in err's chain, if any.
+func AsConflict(err error) (*ConflictError, bool) {
+	var conflict *ConflictError
+	ok := As(err, &conflict)
+	return conflict, ok
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// IdempotencyKeyHeader is the request header which makes a request idempotent.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses which were replayed from an earlier request.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// idempotencyTTL is how long the response to an idempotent request is replayed.
+	idempotencyTTL = 24 * time.Hour
+
+	// idempotencyLockTTL is how long an in-progress request blocks retries. It is longer
+	// than the API Gateway timeout, so it only expires if the handler crashed.
+	idempotencyLockTTL = time.Minute
+
+	// maxIdempotencyKeyLength is the maximum length of an Idempotency-Key header.
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotent returns a Middleware which saves the response to requests made with an
+// Idempotency-Key header in the provided store. Retries with the same key and caller receive
+// the saved response for idempotencyTTL, with the Idempotent-Replayed header set, instead of
+// running the handler again. Reusing a key with a different request fails with a 422 error,
+// and retrying while the first request is still running fails with a 409 error.
+//
+// Only responses with a status code below 500 are saved. If the handler fails with a 5xx
+// response or returns an error, the key is released so that the request can be retried.
+// Requests without the header, or from callers who are not signed in, are not affected.
+func Idempotent(store database.IdempotencyStore) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request Request) (Response, error) {
+			key := idempotencyKey(request)
+			username := GetUserInfo(request).Username
+			if key == "" || username == "" {
+				return next(ctx, request)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return Response{}, errors.New(400, "Invalid request: Idempotency-Key must be at most 255 characters", "")
+			}
+
+			now := time.Now()
+			record := &database.IdempotencyRecord{
+				Key:            username + "#" + key,
+				RequestHash:    idempotencyRequestHash(request),
+				Status:         database.IdempotencyStatus_InProgress,
+				CreatedAt:      now.Format(time.RFC3339),
+				ExpirationTime: now.Add(idempotencyLockTTL).Unix(),
+			}
+			existing, err := store.CreateIdempotencyRecord(record)
+			if err != nil {
+				return Response{}, err
+			}
+			if existing != nil {
+				return replayIdempotent(existing, record)
+			}
+
+			logger := log.FromContext(ctx)
+			response, err := next(ctx, request)
+			if err != nil || response.StatusCode >= 500 {
+				if derr := store.DeleteIdempotencyRecord(record.Key); derr != nil {
+					logger.Error("Failed to release idempotency key", log.Err(derr))
+				}
+				return response, err
+			}
+
+			record.Status = database.IdempotencyStatus_Complete
+			record.StatusCode = response.StatusCode
+			record.Headers = response.Headers
+			record.Body = response.Body
+			record.ExpirationTime = now.Add(idempotencyTTL).Unix()
+			if perr := store.PutIdempotencyRecord(record); perr != nil {
+				// The request has already succeeded, so the failure is logged rather than returned.
+				// A retry will run the handler again once the in-progress record expires.
+				logger.Error("Failed to save idempotent response", log.Err(perr))
+			}
+			return response, nil
+		}
+	}
+}
+
+// idempotencyKey returns the Idempotency-Key header of the provided request. API Gateway
+// lowercases header names, but other casings are also accepted.
+func idempotencyKey(request Request) string {
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, IdempotencyKeyHeader) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// idempotencyRequestHash returns a hash of the parts of the request which must match when
+// an Idempotency-Key is reused.
+func idempotencyRequestHash(request Request) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		request.RouteKey,
+		request.RawPath,
+		request.RawQueryString,
+		request.Body,
+	}, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// replayIdempotent returns the response saved on existing for the retried request.
+func replayIdempotent(existing, retry *database.IdempotencyRecord) (Response, error) {
+	if existing.RequestHash != retry.RequestHash {
+		return Response{}, errors.New(422, "Invalid request: Idempotency-Key was already used with a different request", "")
+	}
+	if existing.Status != database.IdempotencyStatus_Complete {
+		return Response{}, errors.New(409, "Invalid request: a request with this Idempotency-Key is still in progress", "")
+	}
+
+	headers := maps.Clone(existing.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers[IdempotentReplayedHeader] = "true"
+	return Response{
+		StatusCode: existing.StatusCode,
+		Headers:    headers,
+		Body:       existing.Body,
+	}, nil
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func idempotentRequest(username, key, body string) Request {
+	request := testRequest(username, body)
+	request.RouteKey = "PUT /event/{id}/book"
+	request.RawPath = "/event/1/book"
+	if key != "" {
+		request.Headers = map[string]string{"idempotency-key": key}
+	}
+	return request
+}
+
+func TestIdempotent(t *testing.T) {
+	calls := 0
+	status := 200
+	handler := Handle(
+		func(ctx context.Context, request Request) (Response, error) {
+			calls++
+			response := Success(map[string]int{"call": calls})
+			response.StatusCode = status
+			return response, nil
+		},
+		Idempotent(database.NewMemoryRepository()),
+	)
+
+	table := []struct {
+		name       string
+		request    Request
+		status     int
+		wantCode   int
+		wantCalls  int
+		wantBody   string
+		wantReplay bool
+	}{
+		{
+			name:      "NoKey",
+			request:   idempotentRequest("user", "", `{}`),
+			wantCode:  200,
+			wantCalls: 1,
+			wantBody:  `{"call":1}`,
+		},
+		{
+			name:      "FirstRequest",
+			request:   idempotentRequest("user", "key1", `{}`),
+			wantCode:  200,
+			wantCalls: 2,
+			wantBody:  `{"call":2}`,
+		},
+		{
+			name:       "Retry",
+			request:    idempotentRequest("user", "key1", `{}`),
+			wantCode:   200,
+			wantCalls:  2,
+			wantBody:   `{"call":2}`,
+			wantReplay: true,
+		},
+		{
+			name:      "DifferentBody",
+			request:   idempotentRequest("user", "key1", `{"startTime":"2024"}`),
+			wantCode:  422,
+			wantCalls: 2,
+		},
+		{
+			name:      "SameKeyOtherUser",
+			request:   idempotentRequest("other", "key1", `{}`),
+			wantCode:  200,
+			wantCalls: 3,
+			wantBody:  `{"call":3}`,
+		},
+		{
+			name:      "AnonymousCaller",
+			request:   idempotentRequest("", "key1", `{}`),
+			wantCode:  200,
+			wantCalls: 4,
+			wantBody:  `{"call":4}`,
+		},
+		{
+			name:      "ServerError",
+			request:   idempotentRequest("user", "key2", `{}`),
+			status:    500,
+			wantCode:  500,
+			wantCalls: 5,
+		},
+		{
+			name:      "RetryAfterServerError",
+			request:   idempotentRequest("user", "key2", `{}`),
+			wantCode:  200,
+			wantCalls: 6,
+			wantBody:  `{"call":6}`,
+		},
+		{
+			name:      "ClientError",
+			request:   idempotentRequest("user", "key3", `{}`),
+			status:    400,
+			wantCode:  400,
+			wantCalls: 7,
+		},
+		{
+			name:       "RetryAfterClientError",
+			request:    idempotentRequest("user", "key3", `{}`),
+			wantCode:   400,
+			wantCalls:  7,
+			wantReplay: true,
+		},
+		{
+			name:      "KeyTooLong",
+			request:   idempotentRequest("user", fmt.Sprintf("%0256d", 0), `{}`),
+			wantCode:  400,
+			wantCalls: 7,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			status = 200
+			if tc.status != 0 {
+				status = tc.status
+			}
+
+			response, err := handler(context.Background(), tc.request)
+			if err != nil {
+				t.Fatalf("handler got err %v", err)
+			}
+			if response.StatusCode != tc.wantCode {
+				t.Errorf("handler got status %d, want %d", response.StatusCode, tc.wantCode)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("handler was called %d times, want %d", calls, tc.wantCalls)
+			}
+			if tc.wantBody != "" && response.Body != tc.wantBody {
+				t.Errorf("handler got body %s, want %s", response.Body, tc.wantBody)
+			}
+			if gotReplay := response.Headers[IdempotentReplayedHeader] == "true"; gotReplay != tc.wantReplay {
+				t.Errorf("handler got replayed %t, want %t", gotReplay, tc.wantReplay)
+			}
+		})
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	var handler Handler
+	var retry Response
+	handler = Handle(
+		func(ctx context.Context, request Request) (Response, error) {
+			if retry.StatusCode == 0 {
+				retry, _ = handler(ctx, request)
+			}
+			return Success(nil), nil
+		},
+		Idempotent(database.NewMemoryRepository()),
+	)
+
+	response, _ := handler(context.Background(), idempotentRequest("user", "key", `{}`))
+	if response.StatusCode != 200 {
+		t.Errorf("handler got status %d, want 200", response.StatusCode)
+	}
+	if retry.StatusCode != 409 {
+		t.Errorf("concurrent retry got status %d, want 409", retry.StatusCode)
+	}
+}
@@ -1,10 +1,25 @@
+// Package log writes structured JSON logs. Each log line contains the time, level and message,
+// along with any fields attached to the Logger which wrote it. A Logger is usually carried in the
+// request context (see NewContext and FromContext), so that fields like the request id and username
+// are attached to every log line of the request without global state.
+//
+// The package-level functions (Info, Infof, etc.) write to the default Logger, which has no fields.
+// Since a Lambda function can serve several invocations at once, request fields are never stored
+// globally, and code handling a request should log with FromContext instead. Formatted values and
+// values passed to Any are redacted: fields tagged `json:"-"` (such as User.Email and
+// User.PaymentInfo) and map entries with sensitive keys are replaced with "[REDACTED]".
 package log
 
 import (
+	"context"
 	"fmt"
-	stdlog "log"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 )
 
 var isTest = os.Getenv("IS_TEST") == "true"
@@ -30,112 +45,282 @@ const (
 	DebugLevel
 )
 
-type logger struct {
-	level     logLevel
-	requestId string
+// slogLevel returns the slog level corresponding to the provided level.
+func (l logLevel) slogLevel() slog.Level {
+	switch l {
+	case TestLevel:
+		return slog.LevelError + 4
+	case ErrorLevel:
+		return slog.LevelError
+	case WarnLevel:
+		return slog.LevelWarn
+	case InfoLevel:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
 }
 
-var defaultLogger = logger{
-	level:     DebugLevel,
-	requestId: "",
+// parseLevel returns the level with the provided name (error, warn, info or debug). DebugLevel
+// is returned if the name is not recognized.
+func parseLevel(name string) logLevel {
+	switch strings.ToLower(name) {
+	case "error":
+		return ErrorLevel
+	case "warn":
+		return WarnLevel
+	case "info":
+		return InfoLevel
+	default:
+		return DebugLevel
+	}
 }
 
+// level is the minimum level written by all Loggers. It is initialized from the logLevel
+// environment variable, which is set per stage.
+var level = new(slog.LevelVar)
+
+// Logger writes structured logs containing a fixed set of fields.
+type Logger struct {
+	logger *slog.Logger
+}
+
+// defaultLogger is the Logger used by the package-level functions and by FromContext when
+// the context has no Logger.
+var defaultLogger = newLogger(os.Stderr)
+
 func init() {
 	if isTest {
-		defaultLogger.level = TestLevel
+		level.Set(TestLevel.slogLevel())
+	} else {
+		level.Set(parseLevel(os.Getenv("logLevel")).slogLevel())
 	}
 }
 
+// newLogger returns a Logger which writes JSON lines to the provided writer.
+func newLogger(out io.Writer) *Logger {
+	handler := slog.NewJSONHandler(out, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.MessageKey {
+				a.Key = "message"
+			}
+			return a
+		},
+	})
+	return &Logger{logger: slog.New(handler)}
+}
+
+// Default returns the default Logger.
+func Default() *Logger {
+	return defaultLogger
+}
+
 // SetLevel sets the current logging level.
-func SetLevel(level logLevel) {
-	defaultLogger.level = level
+func SetLevel(l logLevel) {
+	level.Set(l.slogLevel())
 }
 
-// SetRequestId sets the request id associated with future logs.
-func SetRequestId(requestId string) {
-	defaultLogger.requestId = requestId
+type contextKey struct{}
+
+// NewContext returns a copy of ctx which carries the provided Logger.
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
 }
 
-// levelPrint prints the provided arguments prefixed by the provided level name
-// and the current request id.
-func levelPrint(level string, v ...any) {
-	result := fmt.Sprint(v...)
-	if !isTest {
-		result = strings.ReplaceAll(result, "\n", "\r")
+// FromContext returns the Logger carried by ctx, or the default Logger if ctx has none.
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return logger
 	}
-	stdlog.Printf("[%s] - [%s]: %s", defaultLogger.requestId, level, result)
+	return Default()
 }
 
-// levelPrint prints the provided format string prefixed by the provided level name
-// and the current request id.
-func levelPrintf(level, format string, v ...any) {
-	result := fmt.Sprintf(format, v...)
-	if !isTest {
-		result = strings.ReplaceAll(result, "\n", "\r")
+// With returns a Logger which adds the provided fields to every log line.
+func (l *Logger) With(fields ...Field) *Logger {
+	args := make([]any, len(fields))
+	for i, f := range fields {
+		args[i] = f
 	}
-	stdlog.Printf("[%s] - [%s]: %s", defaultLogger.requestId, level, result)
+	return &Logger{logger: l.logger.With(args...)}
 }
 
-// Error prints the provided arguments only if the current log level is
-// >= ErrorLevel.
-func Error(v ...any) {
-	if defaultLogger.level >= ErrorLevel {
-		levelPrint("ERROR", v...)
+func (l *Logger) log(lvl logLevel, msg string, fields []Field) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, lvl.slogLevel()) {
+		return
 	}
+	l.logger.LogAttrs(ctx, lvl.slogLevel(), msg, fields...)
 }
 
-// Errorf prints the provided format string and arguments only if the
-// current log level is >= ErrorLevel.
-func Errorf(format string, v ...any) {
-	if defaultLogger.level >= ErrorLevel {
-		levelPrintf("ERROR", format, v...)
+func (l *Logger) logf(lvl logLevel, format string, v []any) {
+	if !l.logger.Enabled(context.Background(), lvl.slogLevel()) {
+		return
 	}
+	l.log(lvl, fmt.Sprintf(format, redactArgs(v)...), errorCodes(v))
 }
 
-// Warn prints the provided arguments only if the current log level is
-// >= WarnLevel.
-func Warn(v ...any) {
-	if defaultLogger.level >= WarnLevel {
-		levelPrint("WARN", v...)
+// errorCodes returns an ErrorCode Field for each errors.Error in the provided arguments.
+func errorCodes(v []any) []Field {
+	var fields []Field
+	for _, arg := range v {
+		if err, ok := arg.(error); ok {
+			var aerr *errors.Error
+			if errors.As(err, &aerr) {
+				fields = append(fields, ErrorCode(aerr.Code))
+			}
+		}
 	}
+	return fields
 }
 
-// Warnf prints the provided format string and arguments only if the
-// current log level is >= WarnLevel.
-func Warnf(format string, v ...any) {
-	if defaultLogger.level >= WarnLevel {
-		levelPrintf("WARN", format, v...)
+// Error writes the provided message and fields at ErrorLevel.
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fields)
+}
+
+// Errorf writes the provided format string and arguments at ErrorLevel.
+func (l *Logger) Errorf(format string, v ...any) {
+	l.logf(ErrorLevel, format, v)
+}
+
+// Warn writes the provided message and fields at WarnLevel.
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.log(WarnLevel, msg, fields)
+}
+
+// Warnf writes the provided format string and arguments at WarnLevel.
+func (l *Logger) Warnf(format string, v ...any) {
+	l.logf(WarnLevel, format, v)
+}
+
+// Info writes the provided message and fields at InfoLevel.
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.log(InfoLevel, msg, fields)
+}
+
+// Infof writes the provided format string and arguments at InfoLevel.
+func (l *Logger) Infof(format string, v ...any) {
+	l.logf(InfoLevel, format, v)
+}
+
+// Debug writes the provided message and fields at DebugLevel.
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.log(DebugLevel, msg, fields)
+}
+
+// Debugf writes the provided format string and arguments at DebugLevel.
+func (l *Logger) Debugf(format string, v ...any) {
+	l.logf(DebugLevel, format, v)
+}
+
+// levelPrint writes the provided arguments to the default Logger at the provided level.
+func levelPrint(lvl logLevel, v ...any) {
+	logger := Default()
+	if !logger.logger.Enabled(context.Background(), lvl.slogLevel()) {
+		return
 	}
+
+	logger.log(lvl, fmt.Sprint(redactArgs(v)...), errorCodes(v))
 }
 
-// Info prints the provided arguments only if the current log level is
-// >= InfoLevel.
+// Error writes the provided arguments to the default Logger at ErrorLevel.
+func Error(v ...any) {
+	levelPrint(ErrorLevel, v...)
+}
+
+// Errorf writes the provided format string and arguments to the default Logger
+// at ErrorLevel.
+func Errorf(format string, v ...any) {
+	Default().Errorf(format, v...)
+}
+
+// Warn writes the provided arguments to the default Logger at WarnLevel.
+func Warn(v ...any) {
+	levelPrint(WarnLevel, v...)
+}
+
+// Warnf writes the provided format string and arguments to the default Logger
+// at WarnLevel.
+func Warnf(format string, v ...any) {
+	Default().Warnf(format, v...)
+}
+
+// Info writes the provided arguments to the default Logger at InfoLevel.
 func Info(v ...any) {
-	if defaultLogger.level >= InfoLevel {
-		levelPrint("INFO", v...)
-	}
+	levelPrint(InfoLevel, v...)
 }
 
-// Infof prints the provided format string and arguments only if the
-// current log level is >= InfoLevel.
+// Infof writes the provided format string and arguments to the default Logger
+// at InfoLevel.
 func Infof(format string, v ...any) {
-	if defaultLogger.level >= InfoLevel {
-		levelPrintf("INFO", format, v...)
-	}
+	Default().Infof(format, v...)
 }
 
-// Debug prints the provided arguments only if the current log level is
-// >= DebugLevel.
+// Debug writes the provided arguments to the default Logger at DebugLevel.
 func Debug(v ...any) {
-	if defaultLogger.level >= DebugLevel {
-		levelPrint("DEBUG", v...)
-	}
+	levelPrint(DebugLevel, v...)
 }
 
-// Debugf prints the provided format string and arguments only if the
-// current log level is >= DebugLevel.
+// Debugf writes the provided format string and arguments to the default Logger
+// at DebugLevel.
 func Debugf(format string, v ...any) {
-	if defaultLogger.level >= DebugLevel {
-		levelPrintf("DEBUG", format, v...)
+	Default().Debugf(format, v...)
+}
+
+// Field is a key-value pair attached to a log line.
+type Field = slog.Attr
+
+// String returns a Field with the provided string value.
+func String(key, value string) Field {
+	return slog.String(key, value)
+}
+
+// Int returns a Field with the provided int value.
+func Int(key string, value int) Field {
+	return slog.Int(key, value)
+}
+
+// Any returns a Field with the provided value. Structs, maps and slices are logged as JSON
+// objects and arrays, with sensitive fields redacted.
+func Any(key string, value any) Field {
+	return slog.Any(key, redact(value))
+}
+
+// RequestId returns a Field containing the id of the current request.
+func RequestId(requestId string) Field {
+	return slog.String("requestId", requestId)
+}
+
+// Username returns a Field containing the username of the caller.
+func Username(username string) Field {
+	return slog.String("username", username)
+}
+
+// Route returns a Field containing the route of the current request, such as "GET /user".
+func Route(route string) Field {
+	return slog.String("route", route)
+}
+
+// Latency returns a Field containing the provided duration in milliseconds.
+func Latency(d time.Duration) Field {
+	return slog.Int64("latencyMs", d.Milliseconds())
+}
+
+// StatusCode returns a Field containing the HTTP status code of a response.
+func StatusCode(code int) Field {
+	return slog.Int("statusCode", code)
+}
+
+// ErrorCode returns a Field containing the code of an errors.Error.
+func ErrorCode(code int) Field {
+	return slog.Int("errorCode", code)
+}
+
+// Err returns a Field containing the message of the provided error.
+func Err(err error) Field {
+	if err == nil {
+		return slog.String("error", "")
 	}
+	return slog.String("error", err.Error())
 }
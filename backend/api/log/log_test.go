@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+type testPaymentInfo struct {
+	CustomerId string `json:"customerId"`
+}
+
+type testUser struct {
+	Username    string            `json:"username"`
+	Email       string            `json:"-"`
+	PaymentInfo *testPaymentInfo  `json:"-"`
+	Bio         string            `json:"bio,omitempty"`
+	Attributes  map[string]string `json:"attributes"`
+	DisplayName string
+	secret      string
+}
+
+// decodeLines returns the JSON log lines written to buf.
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var line map[string]any
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("Failed to decode log line: %v", err)
+		}
+		delete(line, "time")
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRedact(t *testing.T) {
+	user := &testUser{
+		Username:    "alice",
+		Email:       "alice@example.com",
+		PaymentInfo: &testPaymentInfo{CustomerId: "cus_123"},
+		Attributes:  map[string]string{"email": "alice@example.com", "cohort": "1500-1600"},
+		DisplayName: "Alice",
+		secret:      "secret",
+	}
+
+	got := redact([]*testUser{user, {Username: "bob"}})
+	want := []any{
+		map[string]any{
+			"username":    "alice",
+			"Email":       redacted,
+			"PaymentInfo": redacted,
+			"attributes":  map[string]any{"email": redacted, "cohort": "1500-1600"},
+			"DisplayName": "Alice",
+		},
+		map[string]any{
+			"username":    "bob",
+			"attributes":  nil,
+			"DisplayName": "",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("redact mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	defer SetLevel(DebugLevel)
+
+	var buf bytes.Buffer
+	logger := newLogger(&buf).With(RequestId("request"), Username("alice"))
+	ctx := NewContext(context.Background(), logger)
+
+	SetLevel(InfoLevel)
+	FromContext(ctx).Debug("Hidden")
+	FromContext(ctx).Info("Response", Route("GET /user"), StatusCode(200), Latency(1500*time.Millisecond))
+	FromContext(ctx).Warnf("Got user %v", &testUser{Username: "alice", Email: "alice@example.com"})
+
+	want := []map[string]any{
+		{
+			"level":      "INFO",
+			"message":    "Response",
+			"requestId":  "request",
+			"username":   "alice",
+			"route":      "GET /user",
+			"statusCode": float64(200),
+			"latencyMs":  float64(1500),
+		},
+		{
+			"level":     "WARN",
+			"message":   `Got user {"DisplayName":"","Email":"[REDACTED]","attributes":null,"username":"alice"}`,
+			"requestId": "request",
+			"username":  "alice",
+		},
+	}
+	if diff := cmp.Diff(want, decodeLines(t, &buf)); diff != "" {
+		t.Errorf("Logger output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFromContextDefault(t *testing.T) {
+	if got := FromContext(context.Background()); got != Default() {
+		t.Errorf("FromContext without Logger got %v, want Default()", got)
+	}
+}
+
+func TestLevelPrintErrorCode(t *testing.T) {
+	var buf bytes.Buffer
+	original := defaultLogger
+	defaultLogger = newLogger(&buf)
+	SetLevel(DebugLevel)
+	defer func() { defaultLogger = original }()
+
+	Error(errors.New(404, "Invalid request: user not found", ""))
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 1 || lines[0]["errorCode"] != float64(404) {
+		t.Errorf("Error got lines %v, want errorCode 404", lines)
+	}
+}
@@ -0,0 +1,199 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redacted replaces the values of sensitive fields.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the map keys whose values are redacted, compared case-insensitively.
+// They cover sensitive data in maps which have no struct tags, such as the user attributes
+// of Cognito events and the headers of API Gateway requests.
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"email":         true,
+	"password":      true,
+}
+
+// maxRedactDepth is the maximum depth of nested values which are logged.
+const maxRedactDepth = 10
+
+// redact returns a copy of v which is safe to log. Structs are converted to maps keyed by their
+// JSON field names. Fields tagged `json:"-"` and map entries with sensitive keys are replaced with
+// "[REDACTED]", or omitted if they are empty.
+func redact(v any) any {
+	return redactValue(reflect.ValueOf(v), 0)
+}
+
+func redactValue(v reflect.Value, depth int) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > maxRedactDepth {
+		return "..."
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case time.Time:
+			return x
+		case error:
+			return x.Error()
+		case json.Marshaler:
+			return redactMarshaler(x, depth)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return redactValue(v.Elem(), depth+1)
+
+	case reflect.Struct:
+		out := make(map[string]any, v.NumField())
+		redactStruct(v, out, depth)
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if sensitiveKeys[strings.ToLower(key)] {
+				out[key] = redacted
+			} else {
+				out[key] = redactValue(iter.Value(), depth+1)
+			}
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			return string(v.Bytes())
+		}
+		out := make([]any, v.Len())
+		for i := range v.Len() {
+			out[i] = redactValue(v.Index(i), depth+1)
+		}
+		return out
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return v.Type().String()
+
+	default:
+		if v.CanInterface() {
+			return v.Interface()
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+// redactStruct adds the exported fields of the struct v to out, following the naming rules of
+// encoding/json. The fields of embedded structs without a JSON name are added directly to out.
+func redactStruct(v reflect.Value, out map[string]any, depth int) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		value := v.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if field.Anonymous && name == "" {
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				redactStruct(value, out, depth)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "-" && opts == "" {
+			if !value.IsZero() {
+				out[field.Name] = redacted
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(opts, "omitempty") && value.IsZero() {
+			continue
+		}
+		out[name] = redactValue(value, depth+1)
+	}
+}
+
+// redactMarshaler redacts the JSON encoding of a value with a custom MarshalJSON method, such as
+// the attribute values of DynamoDB stream events.
+func redactMarshaler(m json.Marshaler, depth int) any {
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("<failed to marshal: %v>", err)
+	}
+	var decoded any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return string(b)
+	}
+	return redactValue(reflect.ValueOf(decoded), depth+1)
+}
+
+// redactedArg formats a redacted value as JSON, regardless of the verb.
+type redactedArg struct {
+	value any
+}
+
+func (r redactedArg) Format(f fmt.State, verb rune) {
+	b, err := json.Marshal(redact(r.value))
+	if err != nil {
+		fmt.Fprintf(f, "<failed to marshal: %v>", err)
+		return
+	}
+	f.Write(b)
+}
+
+// redactArgs returns a copy of the provided format arguments where structs, maps and slices are
+// redacted. Errors and other values with their own String method are left unchanged.
+func redactArgs(v []any) []any {
+	out := make([]any, len(v))
+	for i, arg := range v {
+		out[i] = arg
+		switch arg.(type) {
+		case error, fmt.Stringer, fmt.Formatter, []byte:
+			continue
+		}
+
+		rv := reflect.ValueOf(arg)
+		for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		switch rv.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+			out[i] = redactedArg{value: arg}
+		}
+	}
+	return out
+}
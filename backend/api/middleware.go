@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// Handler is a Lambda handler for an API Gateway request.
+type Handler func(ctx context.Context, request Request) (Response, error)
+
+// Middleware wraps a Handler with additional behavior. A Middleware can either call the next
+// Handler or return early, usually with an error.
+type Middleware func(next Handler) Handler
+
+// Handle returns a Handler which calls the provided handler wrapped in the provided middleware.
+// The middleware are run in the order they are given. The returned Handler is wrapped in Logged,
+// so the request is logged before running the middleware. If the handler or any middleware
+// returns an error, it is logged with the request's Logger and converted into a response like
+// Failure does.
+func Handle(handler Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return Logged(func(ctx context.Context, request Request) (Response, error) {
+		response, err := handler(ctx, request)
+		if err != nil {
+			return failure(log.FromContext(ctx), err), nil
+		}
+		return response, nil
+	})
+}
+
+// Logged returns a Handler which attaches a Logger to the context of the provided handler. The
+// Logger adds the request id, route and caller's username to every log line. The returned Handler
+// logs a summary of the request before calling handler, and the response status and latency after.
+// The body and headers of the request are not logged, as they can contain credentials and payment
+// details.
+func Logged(handler Handler) Handler {
+	return func(ctx context.Context, request Request) (Response, error) {
+		start := time.Now()
+
+		logger := log.FromContext(ctx).With(log.RequestId(request.RequestContext.RequestID), log.Route(request.RouteKey))
+		if username := GetUserInfo(request).Username; username != "" {
+			logger = logger.With(log.Username(username))
+		}
+		ctx = log.NewContext(ctx, logger)
+
+		logger.Info("Request",
+			log.Any("pathParameters", request.PathParameters),
+			log.Any("queryStringParameters", request.QueryStringParameters),
+		)
+
+		response, err := handler(ctx, request)
+
+		fields := []log.Field{log.StatusCode(response.StatusCode), log.Latency(time.Since(start))}
+		if err != nil {
+			fields = append(fields, log.Err(err))
+			var aerr *errors.Error
+			if errors.As(err, &aerr) {
+				fields = append(fields, log.ErrorCode(aerr.Code))
+			}
+		}
+		logger.Info("Response", fields...)
+		return response, err
+	}
+}
+
+type contextKey int
+
+const (
+	userInfoKey contextKey = iota
+	userKey
+	bodyKey
+)
+
+// RequireUser returns a Middleware which requires the request to come from a signed-in user.
+// The user is fetched using the provided repository. The caller's UserInfo and User can be
+// accessed by later handlers with UserInfoFromContext and UserFromContext.
+func RequireUser(repository database.UserGetter) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request Request) (Response, error) {
+			info := GetUserInfo(request)
+			if info.Username == "" {
+				return Response{}, errors.New(400, "Invalid request: username is required", "")
+			}
+
+			user, err := repository.GetUser(info.Username)
+			if err != nil {
+				return Response{}, err
+			}
+
+			ctx = context.WithValue(ctx, userInfoKey, info)
+			ctx = context.WithValue(ctx, userKey, user)
+			return next(ctx, request)
+		}
+	}
+}
+
+// UserInfoFromContext returns the UserInfo of the caller. It returns nil if the
+// context was not passed through RequireUser.
+func UserInfoFromContext(ctx context.Context) *UserInfo {
+	info, _ := ctx.Value(userInfoKey).(*UserInfo)
+	return info
+}
+
+// UserFromContext returns the User of the caller. It returns nil if the context was
+// not passed through RequireUser.
+func UserFromContext(ctx context.Context) *database.User {
+	user, _ := ctx.Value(userKey).(*database.User)
+	return user
+}
+
+// RequirePermission returns a Middleware which requires the caller to have at least one of
+// the provided permissions. It must be run after RequireUser.
+func RequirePermission(permissions ...database.Permission) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request Request) (Response, error) {
+			user := UserFromContext(ctx)
+			if user == nil {
+				return Response{}, errors.New(500, "Temporary server error", "RequirePermission used without RequireUser")
+			}
+
+			for _, permission := range permissions {
+				if user.HasPermission(permission) {
+					return next(ctx, request)
+				}
+			}
+			return Response{}, errors.New(403, "Invalid request: you do not have permission to perform this action", "")
+		}
+	}
+}
+
+// DecodeJSON returns a Middleware which unmarshals the request body into a value of type T.
+// The value can be accessed by later handlers with Body.
+func DecodeJSON[T any]() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request Request) (Response, error) {
+			body := new(T)
+			if err := json.Unmarshal([]byte(request.Body), body); err != nil {
+				return Response{}, errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)
+			}
+			return next(context.WithValue(ctx, bodyKey, body), request)
+		}
+	}
+}
+
+// Body returns the request body decoded by DecodeJSON. It returns nil if the context
+// was not passed through DecodeJSON[T].
+func Body[T any](ctx context.Context) *T {
+	body, _ := ctx.Value(bodyKey).(*T)
+	return body
+}
+
+// Validate returns a Middleware which passes the request body decoded by DecodeJSON to the
+// provided function. If the function returns an error, the request fails with that error.
+// It must be run after DecodeJSON[T].
+func Validate[T any](validate func(body *T) error) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request Request) (Response, error) {
+			body := Body[T](ctx)
+			if body == nil {
+				return Response{}, errors.New(500, "Temporary server error", "Validate used without DecodeJSON")
+			}
+			if err := validate(body); err != nil {
+				return Response{}, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
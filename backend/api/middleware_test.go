@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type fakeUserGetter map[string]*database.User
+
+func (f fakeUserGetter) GetUser(username string) (*database.User, error) {
+	if user, ok := f[username]; ok {
+		return user, nil
+	}
+	return nil, errors.New(404, "Invalid request: user not found", "")
+}
+
+type testBody struct {
+	Name string `json:"name"`
+}
+
+func validateTestBody(body *testBody) error {
+	if body.Name == "" {
+		return errors.New(400, "Invalid request: name is required", "")
+	}
+	return nil
+}
+
+func testRequest(username, body string) Request {
+	request := Request{Body: body}
+	if username != "" {
+		request.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+			JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+				Claims: map[string]string{"cognito:username": username},
+			},
+		}
+	}
+	return request
+}
+
+func TestHandle(t *testing.T) {
+	users := fakeUserGetter{
+		"user":             {Username: "user"},
+		"admin":            {Username: "admin", Roles: []database.Role{database.Role_Admin}},
+		"tournamentAdmin":  {Username: "tournamentAdmin", Roles: []database.Role{database.Role_TournamentDirector}},
+		"calendarAdmin":    {Username: "calendarAdmin", Roles: []database.Role{database.Role_CalendarEditor}},
+		"tournamentAndCal": {Username: "tournamentAndCal", Roles: []database.Role{database.Role_TournamentDirector, database.Role_CalendarEditor}},
+		"legacyAdmin":      {Username: "legacyAdmin", IsAdmin: true},
+	}
+
+	handler := Handle(
+		func(ctx context.Context, request Request) (Response, error) {
+			return Success(map[string]string{
+				"username": UserFromContext(ctx).Username,
+				"name":     Body[testBody](ctx).Name,
+			}), nil
+		},
+		RequireUser(users),
+		RequirePermission(database.Permission_ManageTournaments),
+		DecodeJSON[testBody](),
+		Validate(validateTestBody),
+	)
+
+	table := []struct {
+		name     string
+		username string
+		body     string
+		wantCode int
+	}{
+		{name: "MissingUsername", body: `{"name":"test"}`, wantCode: 400},
+		{name: "UserNotFound", username: "missing", body: `{"name":"test"}`, wantCode: 404},
+		{name: "MissingRole", username: "user", body: `{"name":"test"}`, wantCode: 403},
+		{name: "OtherRole", username: "calendarAdmin", body: `{"name":"test"}`, wantCode: 403},
+		{name: "LegacyAdmin", username: "legacyAdmin", body: `{"name":"test"}`, wantCode: 200},
+		{name: "InvalidBody", username: "tournamentAdmin", body: `{"name":`, wantCode: 400},
+		{name: "FailedValidation", username: "tournamentAdmin", body: `{}`, wantCode: 400},
+		{name: "Success", username: "tournamentAdmin", body: `{"name":"test"}`, wantCode: 200},
+		{name: "AdminHasAllPermissions", username: "admin", body: `{"name":"test"}`, wantCode: 200},
+		{name: "MultipleRoles", username: "tournamentAndCal", body: `{"name":"test"}`, wantCode: 200},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := handler(context.Background(), testRequest(tc.username, tc.body))
+			if err != nil {
+				t.Fatalf("handler returned error: %v", err)
+			}
+			if response.StatusCode != tc.wantCode {
+				t.Fatalf("handler status code = %d; want %d (body %s)", response.StatusCode, tc.wantCode, response.Body)
+			}
+			if tc.wantCode != 200 {
+				return
+			}
+
+			var body map[string]string
+			if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+				t.Fatalf("failed to unmarshal response body: %v", err)
+			}
+			if body["username"] != tc.username || body["name"] != "test" {
+				t.Errorf("handler body = %v; want username %q and name %q", body, tc.username, "test")
+			}
+		})
+	}
+}
+
+func TestRequirePermissionWithoutUser(t *testing.T) {
+	handler := Handle(
+		func(ctx context.Context, request Request) (Response, error) {
+			return Success(nil), nil
+		},
+		RequirePermission(database.Permission_ManageRoles),
+	)
+
+	response, _ := handler(context.Background(), testRequest("admin", ""))
+	if response.StatusCode != 500 {
+		t.Errorf("handler status code = %d; want 500", response.StatusCode)
+	}
+}
+
+func TestLogged(t *testing.T) {
+	var got *log.Logger
+	handler := Logged(func(ctx context.Context, request Request) (Response, error) {
+		got = log.FromContext(ctx)
+		return Success(nil), nil
+	})
+
+	response, err := handler(context.Background(), testRequest("user", ""))
+	if err != nil || response.StatusCode != 200 {
+		t.Fatalf("Logged got response %v, err %v, want 200", response, err)
+	}
+	if got == nil || got == log.Default() {
+		t.Errorf("Logged did not attach a request Logger to the context")
+	}
+}
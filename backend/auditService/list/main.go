@@ -0,0 +1,58 @@
+// Implements a Lambda handler which lists the audit log entries for a target
+// or an actor, newest first. Exactly one of the target or actor query
+// parameters must be provided.
+//
+// The caller must have the VIEW_AUDIT_LOG permission.
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.AuditEntryLister](database.DynamoDB)
+
+type ListAuditEntriesResponse struct {
+	Entries []database.AuditEntry `json:"entries"`
+	LastKey string                `json:"lastEvaluatedKey,omitempty"`
+}
+
+func main() {
+	lambda.Start(api.Handle(handler,
+		api.RequireUser(repository),
+		api.RequirePermission(database.Permission_ViewAuditLog),
+	))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	target := event.QueryStringParameters["target"]
+	actor := event.QueryStringParameters["actor"]
+	startKey := event.QueryStringParameters["startKey"]
+
+	var entries []database.AuditEntry
+	var lastKey string
+	var err error
+
+	switch {
+	case target != "" && actor != "":
+		err = errors.New(400, "Invalid request: only one of target and actor can be provided", "")
+	case target != "":
+		entries, lastKey, err = repository.ListAuditEntriesByTarget(target, startKey)
+	case actor != "":
+		entries, lastKey, err = repository.ListAuditEntriesByActor(actor, startKey)
+	default:
+		err = errors.New(400, "Invalid request: target or actor is required", "")
+	}
+
+	if err != nil {
+		return api.Response{}, err
+	}
+	return api.Success(&ListAuditEntriesResponse{
+		Entries: entries,
+		LastKey: lastKey,
+	}), nil
+}
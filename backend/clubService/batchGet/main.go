@@ -10,20 +10,16 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ClubLister](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	ids := strings.Split(event.QueryStringParameters["ids"], ",")
 	if len(ids) == 0 || (len(ids) == 1 && ids[0] == "") {
 		return api.Failure(errors.New(400, "Invalid request: ids is required", "")), nil
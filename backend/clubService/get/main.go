@@ -7,11 +7,15 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+type ClubGetter interface {
+	database.ClubGetter
+	database.ScoreboardSummaryLister
+}
+
+var repository = database.Repository[ClubGetter](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 type GetClubResponse struct {
@@ -20,16 +24,10 @@ type GetClubResponse struct {
 }
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	id := event.PathParameters["id"]
 	if id == "" {
 		return api.Failure(errors.New(400, "Invalid request: id is required", "")), nil
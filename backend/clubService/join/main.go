@@ -12,23 +12,20 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ClubMemberEditor](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	if strings.Contains(event.RawPath, "requests") {
-		return handleJoinRequest(event), nil
+		return handleJoinRequest(ctx, event), nil
 	}
-	return handleImmediateJoin(event), nil
+	return handleImmediateJoin(ctx, event), nil
 }
 
-func handleJoinRequest(event api.Request) api.Response {
+func handleJoinRequest(ctx context.Context, event api.Request) api.Response {
 	id := event.PathParameters["id"]
 	if id == "" {
 		return api.Failure(errors.New(400, "Invalid request: id is required", ""))
@@ -56,14 +53,18 @@ func handleJoinRequest(event api.Request) api.Response {
 		return api.Failure(errors.New(400, "Invalid request: cohort is required", ""))
 	}
 
-	request.Username = info.Username
-	club, err := repository.RequestToJoinClub(id, &request, user.SubscriptionStatus != database.SubscriptionStatus_Subscribed)
+	// The notification is saved with the join request, so it is built from the club before
+	// the request is added.
+	existing, err := repository.GetClub(id)
 	if err != nil {
 		return api.Failure(err)
 	}
 
-	if err := database.SendClubJoinRequestEvent(club); err != nil {
-		log.Errorf("Failed to leave notification for club owner: %v", err)
+	request.Username = info.Username
+	club, err := repository.RequestToJoinClub(id, &request, user.SubscriptionStatus != database.SubscriptionStatus_Subscribed,
+		database.NewClubJoinRequestEvent(existing))
+	if err != nil {
+		return api.Failure(err)
 	}
 
 	return api.Success(club)
@@ -74,7 +75,8 @@ type ImmediateJoinResponse struct {
 	Scoreboard []database.ScoreboardSummary `json:"scoreboard,omitempty"`
 }
 
-func handleImmediateJoin(event api.Request) api.Response {
+func handleImmediateJoin(ctx context.Context, event api.Request) api.Response {
+	logger := log.FromContext(ctx)
 	id := event.PathParameters["id"]
 	if id == "" {
 		return api.Failure(errors.New(400, "Invalid request: id is required", ""))
@@ -98,7 +100,7 @@ func handleImmediateJoin(event api.Request) api.Response {
 	scoreboard, err := repository.GetScoreboardSummaries([]string{info.Username})
 	if err != nil {
 		// This didn't prevent the new member from being added, so just log the error and continue
-		log.Errorf("Failed to get new scoreboard summary: %v", err)
+		logger.Errorf("Failed to get new scoreboard summary: %v", err)
 	}
 
 	return api.Success(ImmediateJoinResponse{Club: club, Scoreboard: scoreboard})
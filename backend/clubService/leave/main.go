@@ -6,20 +6,16 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ClubMemberEditor](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
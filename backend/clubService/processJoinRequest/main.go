@@ -12,7 +12,7 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ClubMemberEditor](database.DynamoDB)
 
 type ProcessJoinRequest struct {
 	Status database.ClubJoinRequestStatus `json:"status"`
@@ -24,13 +24,10 @@ type ProcessJoinRequestResponse struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: caller username is required", "")), nil
@@ -56,7 +53,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	var err error
 
 	if request.Status == database.ClubJoinRequestStatus_Approved {
-		club, scoreboard, err = approveJoinRequest(id, username, info.Username)
+		club, scoreboard, err = approveJoinRequest(ctx, id, username, info.Username)
 	} else if request.Status == database.ClubJoinRequestStatus_Rejected {
 		club, err = repository.RejectClubJoinRequest(id, username, info.Username)
 	} else {
@@ -69,20 +66,24 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	return api.Success(ProcessJoinRequestResponse{Club: club, Scoreboard: scoreboard}), nil
 }
 
-func approveJoinRequest(id, username, caller string) (*database.Club, []database.ScoreboardSummary, error) {
-	club, err := repository.ApproveClubJoinRequest(id, username, caller)
+func approveJoinRequest(ctx context.Context, id, username, caller string) (*database.Club, []database.ScoreboardSummary, error) {
+	logger := log.FromContext(ctx)
+	// The notification is saved with the approval, so it is built from the club before
+	// the request is approved.
+	existing, err := repository.GetClub(id)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	scoreboard, err := repository.GetScoreboardSummaries([]string{username})
+	club, err := repository.ApproveClubJoinRequest(id, username, caller, database.NewClubJoinRequestApprovedEvent(existing, username))
 	if err != nil {
-		// This didn't prevent the new member from being added, so just log the error and continue
-		log.Errorf("Failed to get new scoreboard summary: %v", err)
+		return nil, nil, err
 	}
 
-	if err := database.SendClubJoinRequestApprovedEvent(club, username); err != nil {
-		log.Errorf("Failed to send approval notification: %v", err)
+	scoreboard, err := repository.GetScoreboardSummaries([]string{username})
+	if err != nil {
+		// This didn't prevent the new member from being added, so just log the error and continue
+		logger.Errorf("Failed to get new scoreboard summary: %v", err)
 	}
 
 	return club, scoreboard, nil
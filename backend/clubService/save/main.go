@@ -11,21 +11,17 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ClubEditor](database.DynamoDB)
 var mediaStore = database.S3
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	if id := event.PathParameters["id"]; id == "" {
 		return createClub(event), nil
 	}
@@ -90,6 +86,11 @@ func saveClub(event api.Request) api.Response {
 		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err))
 	}
 
+	caller, before, err := getCaller(event.PathParameters["id"], info.Username)
+	if err != nil {
+		return api.Failure(err)
+	}
+
 	if clubUpdate.LogoData != nil {
 		var err error
 		if *clubUpdate.LogoData == "" {
@@ -102,13 +103,36 @@ func saveClub(event api.Request) api.Response {
 		}
 	}
 
-	club, err := repository.UpdateClub(event.PathParameters["id"], info.Username, clubUpdate)
+	club, err := repository.UpdateClub(event.PathParameters["id"], caller, clubUpdate)
 	if err != nil {
 		return api.Failure(err)
 	}
+
+	if caller != info.Username {
+		api.Audit(repository, event, database.AuditAction_ModerateClub, database.ClubAuditTarget(club.Id), before, club)
+	}
 	return api.Success(club)
 }
 
+// getCaller returns the username to pass as the caller of UpdateClub. Club moderators
+// can edit any club, so the club's owner is returned for them, along with the club before
+// the update. Otherwise, the provided username is returned unchanged and the club is nil.
+func getCaller(clubId, username string) (string, *database.Club, error) {
+	user, err := repository.GetUser(username)
+	if err != nil {
+		return "", nil, err
+	}
+	if !user.HasPermission(database.Permission_ModerateClubs) {
+		return username, nil, nil
+	}
+
+	club, err := repository.GetClub(clubId)
+	if err != nil {
+		return "", nil, err
+	}
+	return club.Owner, club, nil
+}
+
 func checkClub(club *database.Club) error {
 	club.Name = strings.TrimSpace(club.Name)
 	club.Description = strings.TrimSpace(club.Description)
@@ -0,0 +1,247 @@
+// Package cohorts converts ratings into Dojo cohorts, normalized ratings and ratings
+// in other rating systems.
+//
+// The rating boundaries of the cohorts are versioned. Each version is a JSON file in the
+// boundaries directory, named after the date it takes effect. The files are embedded in the
+// binary, so a new version only applies once it is deployed. A version with a future effective
+// date can be deployed ahead of time, and the boundaries then change on that date.
+package cohorts
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+//go:embed boundaries/*.json
+var boundaryFiles embed.FS
+
+// Table is a single version of the cohort rating boundaries.
+type Table struct {
+	// The date the table takes effect, in time.DateOnly format.
+	EffectiveDate string `json:"effectiveDate"`
+
+	// A description of the changes made in this version.
+	Description string `json:"description,omitempty"`
+
+	// The boundaries of each rating system. Boundaries[rs][i] is the rating at which a user
+	// leaves database.Cohorts[i] and joins database.Cohorts[i+1]. Rating systems without
+	// boundaries cannot be converted.
+	Boundaries map[database.RatingSystem][]int `json:"boundaries"`
+}
+
+// versions contains every Table, sorted by effective date.
+var versions = mustLoadVersions()
+
+// mustLoadVersions returns the embedded tables sorted by effective date. It panics if
+// any table is invalid, so that an invalid table fails the tests instead of reaching users.
+func mustLoadVersions() []*Table {
+	entries, err := boundaryFiles.ReadDir("boundaries")
+	if err != nil {
+		panic(fmt.Sprintf("cohorts: failed to read boundaries: %v", err))
+	}
+
+	tables := make([]*Table, 0, len(entries))
+	for _, entry := range entries {
+		b, err := boundaryFiles.ReadFile(path.Join("boundaries", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("cohorts: failed to read %s: %v", entry.Name(), err))
+		}
+
+		var table Table
+		if err := json.Unmarshal(b, &table); err != nil {
+			panic(fmt.Sprintf("cohorts: failed to unmarshal %s: %v", entry.Name(), err))
+		}
+		if err := table.Validate(); err != nil {
+			panic(fmt.Sprintf("cohorts: invalid table %s: %v", entry.Name(), err))
+		}
+		tables = append(tables, &table)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].EffectiveDate < tables[j].EffectiveDate
+	})
+	return tables
+}
+
+// Versions returns every version of the boundaries, sorted by effective date.
+func Versions() []*Table {
+	return versions
+}
+
+// At returns the version of the boundaries in effect on the provided date, in time.DateOnly
+// format. If the date is before every version, the first version is returned.
+func At(date string) *Table {
+	result := versions[0]
+	for _, t := range versions[1:] {
+		if t.EffectiveDate > date {
+			break
+		}
+		result = t
+	}
+	return result
+}
+
+// Current returns the version of the boundaries in effect today.
+func Current() *Table {
+	return At(time.Now().UTC().Format(time.DateOnly))
+}
+
+// Validate returns an error if the table is malformed. Each rating system must have one
+// boundary for every cohort except the last, and the boundaries must not decrease.
+func (t *Table) Validate() error {
+	if _, err := time.Parse(time.DateOnly, t.EffectiveDate); err != nil {
+		return errors.Wrap(400, "Invalid request: effectiveDate must be in the format YYYY-MM-DD", "", err)
+	}
+	if len(t.Boundaries) == 0 {
+		return errors.New(400, "Invalid request: boundaries are required", "")
+	}
+
+	for system, boundaries := range t.Boundaries {
+		if len(boundaries) != len(database.Cohorts)-1 {
+			return errors.New(400, fmt.Sprintf("Invalid request: %s has %d boundaries, want %d", system, len(boundaries), len(database.Cohorts)-1), "")
+		}
+		for i := 1; i < len(boundaries); i++ {
+			if boundaries[i] < boundaries[i-1] {
+				return errors.New(400, fmt.Sprintf("Invalid request: %s boundaries decrease at cohort %s", system, database.Cohorts[i]), "")
+			}
+		}
+	}
+	return nil
+}
+
+// GetCohort returns the cohort of a user with the provided rating. NoCohort is returned if
+// the rating system has no boundaries.
+func (t *Table) GetCohort(system database.RatingSystem, rating int) database.DojoCohort {
+	boundaries := t.Boundaries[system]
+	if boundaries == nil {
+		return database.NoCohort
+	}
+
+	for i, boundary := range boundaries {
+		if rating < boundary {
+			return database.Cohorts[i]
+		}
+	}
+	return database.Cohorts[len(database.Cohorts)-1]
+}
+
+// Normalize returns the provided rating on the scale of the cohort names, by interpolating
+// between the boundaries of its cohort. For example, a rating halfway between the boundaries
+// of the 1500-1600 cohort is normalized to 1550. Ratings in the last cohort are extrapolated
+// from the second to last cohort. It returns false if the rating system has no boundaries.
+func (t *Table) Normalize(rating int, system database.RatingSystem) (float64, bool) {
+	boundaries := t.Boundaries[system]
+	if boundaries == nil {
+		return 0, false
+	}
+
+	i := 0
+	for i < len(boundaries) && rating >= boundaries[i] {
+		i++
+	}
+	if i == len(boundaries) {
+		i--
+	}
+
+	x1, x2 := lowerBoundary(boundaries, i), boundaries[i]
+	y1, y2 := cohortRange(database.Cohorts[i])
+	if x2 == x1 {
+		return float64(y1), true
+	}
+	return float64(y2-y1)/float64(x2-x1)*float64(rating-x1) + float64(y1), true
+}
+
+// Denormalize returns the rating in the provided rating system with the provided normalized
+// rating. It is the inverse of Normalize. It returns false if the rating system has no boundaries.
+func (t *Table) Denormalize(normalized float64, system database.RatingSystem) (int, bool) {
+	boundaries := t.Boundaries[system]
+	if boundaries == nil {
+		return 0, false
+	}
+
+	i := 0
+	for i < len(boundaries)-1 {
+		if _, y2 := cohortRange(database.Cohorts[i]); normalized < float64(y2) {
+			break
+		}
+		i++
+	}
+
+	x1, x2 := lowerBoundary(boundaries, i), boundaries[i]
+	y1, y2 := cohortRange(database.Cohorts[i])
+	return int(float64(x2-x1)/float64(y2-y1)*(normalized-float64(y1)) + float64(x1) + 0.5), true
+}
+
+// Convert returns the rating in rating system to which is equivalent to the provided rating
+// in rating system from. It returns false if either rating system has no boundaries.
+func (t *Table) Convert(rating int, from, to database.RatingSystem) (int, bool) {
+	normalized, ok := t.Normalize(rating, from)
+	if !ok {
+		return 0, false
+	}
+	return t.Denormalize(normalized, to)
+}
+
+// lowerBoundary returns the rating at which a user joins the cohort with the provided index.
+func lowerBoundary(boundaries []int, i int) int {
+	if i == 0 {
+		return 0
+	}
+	return boundaries[i-1]
+}
+
+// cohortRange returns the minimum and maximum of the provided cohort's name. The last
+// cohort has a maximum 100 above its minimum.
+func cohortRange(cohort database.DojoCohort) (int, int) {
+	tokens := strings.Split(strings.TrimSuffix(string(cohort), "+"), "-")
+	minRating, _ := strconv.Atoi(tokens[0])
+	if len(tokens) < 2 {
+		return minRating, minRating + 100
+	}
+	maxRating, _ := strconv.Atoi(tokens[1])
+	return minRating, maxRating
+}
+
+// GetCohort returns the cohort of a user with the provided rating, using the current boundaries.
+func GetCohort(system database.RatingSystem, rating int) database.DojoCohort {
+	return Current().GetCohort(system, rating)
+}
+
+// Normalize returns the provided rating on the scale of the cohort names, using the current
+// boundaries. It returns false if the rating system has no boundaries.
+func Normalize(rating int, system database.RatingSystem) (float64, bool) {
+	return Current().Normalize(rating, system)
+}
+
+// Convert returns the rating in rating system to which is equivalent to the provided rating
+// in rating system from, using the current boundaries. It returns false if either rating
+// system has no boundaries.
+func Convert(rating int, from, to database.RatingSystem) (int, bool) {
+	return Current().Convert(rating, from, to)
+}
+
+// Autopick sets the update's dojoCohort field based on its ratingSystem and ratings fields,
+// using the current boundaries. The chosen cohort is returned.
+func Autopick(update *database.UserUpdate) database.DojoCohort {
+	if update == nil || update.RatingSystem == nil || update.Ratings == nil {
+		return database.NoCohort
+	}
+
+	rating, ok := (*update.Ratings)[*update.RatingSystem]
+	if !ok || rating == nil {
+		return database.NoCohort
+	}
+
+	cohort := GetCohort(*update.RatingSystem, rating.CurrentRating)
+	update.DojoCohort = &cohort
+	return cohort
+}
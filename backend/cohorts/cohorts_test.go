@@ -0,0 +1,217 @@
+package cohorts
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestVersions(t *testing.T) {
+	if len(Versions()) == 0 {
+		t.Fatal("Versions returned no tables")
+	}
+	if !slices.IsSortedFunc(Versions(), func(a, b *Table) int {
+		if a.EffectiveDate < b.EffectiveDate {
+			return -1
+		}
+		return 1
+	}) {
+		t.Error("Versions are not sorted by effective date")
+	}
+	if got := At("1900-01-01"); got != Versions()[0] {
+		t.Errorf("At before every version got %s, want first version", got.EffectiveDate)
+	}
+}
+
+func TestGetCohort(t *testing.T) {
+	table := At("2024-01-01")
+	tests := []struct {
+		system database.RatingSystem
+		rating int
+		want   database.DojoCohort
+	}{
+		{system: database.Chesscom, rating: 0, want: "0-300"},
+		{system: database.Chesscom, rating: 549, want: "0-300"},
+		{system: database.Chesscom, rating: 550, want: "300-400"},
+		{system: database.Chesscom, rating: 1849, want: "1500-1600"},
+		{system: database.Chesscom, rating: 2550, want: "2400+"},
+		{system: database.Fide, rating: 1399, want: "0-300"},
+		{system: database.Fide, rating: 1400, want: "900-1000"},
+		{system: database.Lichess, rating: 2000, want: "1500-1600"},
+		{system: database.Custom, rating: 1500, want: database.NoCohort},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tc.system, tc.rating), func(t *testing.T) {
+			if got := table.GetCohort(tc.system, tc.rating); got != tc.want {
+				t.Errorf("GetCohort got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	table := At("2024-01-01")
+	tests := []struct {
+		system database.RatingSystem
+		rating int
+		want   float64
+	}{
+		{system: database.Chesscom, rating: 1800, want: 1550},
+		{system: database.Chesscom, rating: 550, want: 300},
+		{system: database.Chesscom, rating: 275, want: 150},
+		{system: database.Chesscom, rating: 2615, want: 2500},
+		{system: database.Fide, rating: 700, want: 150},
+		{system: database.Fide, rating: 1400, want: 900},
+		{system: database.Fide, rating: 1425, want: 950},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tc.system, tc.rating), func(t *testing.T) {
+			got, ok := table.Normalize(tc.rating, tc.system)
+			if !ok {
+				t.Fatalf("Normalize got !ok")
+			}
+			if math.Abs(got-tc.want) > 0.01 {
+				t.Errorf("Normalize got %f, want %f", got, tc.want)
+			}
+		})
+	}
+
+	if _, ok := table.Normalize(1500, database.Custom); ok {
+		t.Errorf("Normalize of custom rating got ok")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	table := At("2024-01-01")
+	tests := []struct {
+		rating   int
+		from, to database.RatingSystem
+		want     int
+	}{
+		{rating: 1800, from: database.Chesscom, to: database.Lichess, want: 2000},
+		{rating: 2000, from: database.Lichess, to: database.Chesscom, want: 1800},
+		{rating: 1600, from: database.Uscf, to: database.Uscf, want: 1600},
+		{rating: 2615, from: database.Chesscom, to: database.Fide, want: 2500},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%d/%s", tc.from, tc.rating, tc.to), func(t *testing.T) {
+			got, ok := table.Convert(tc.rating, tc.from, tc.to)
+			if !ok {
+				t.Fatalf("Convert got !ok")
+			}
+			if got != tc.want {
+				t.Errorf("Convert got %d, want %d", got, tc.want)
+			}
+		})
+	}
+
+	if _, ok := table.Convert(1500, database.Chesscom, database.Custom); ok {
+		t.Errorf("Convert to custom rating got ok")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := make([]int, len(database.Cohorts)-1)
+	for i := range valid {
+		valid[i] = 100 * (i + 1)
+	}
+	decreasing := slices.Clone(valid)
+	decreasing[5] = 0
+
+	tests := []struct {
+		name    string
+		table   Table
+		wantErr bool
+	}{
+		{
+			name:  "Valid",
+			table: Table{EffectiveDate: "2025-01-01", Boundaries: map[database.RatingSystem][]int{database.Chesscom: valid}},
+		},
+		{
+			name:    "InvalidDate",
+			table:   Table{EffectiveDate: "January", Boundaries: map[database.RatingSystem][]int{database.Chesscom: valid}},
+			wantErr: true,
+		},
+		{
+			name:    "NoBoundaries",
+			table:   Table{EffectiveDate: "2025-01-01"},
+			wantErr: true,
+		},
+		{
+			name:    "TooFewBoundaries",
+			table:   Table{EffectiveDate: "2025-01-01", Boundaries: map[database.RatingSystem][]int{database.Chesscom: valid[1:]}},
+			wantErr: true,
+		},
+		{
+			name:    "Decreasing",
+			table:   Table{EffectiveDate: "2025-01-01", Boundaries: map[database.RatingSystem][]int{database.Chesscom: decreasing}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.table.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate got err %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPreviewTable(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	users := []struct {
+		username string
+		system   database.RatingSystem
+		rating   int
+	}{
+		{username: "alice", system: database.Chesscom, rating: 1800},
+		{username: "bob", system: database.Chesscom, rating: 1900},
+		{username: "carol", system: database.Lichess, rating: 2000},
+		{username: "dave", system: database.Custom, rating: 1500},
+		{username: "erin", system: database.Chesscom},
+	}
+	distributions := database.NewRatingDistributions()
+	for _, u := range users {
+		database.AddRatingDistribution(distributions, &database.User{
+			Username:     u.username,
+			RatingSystem: u.system,
+			Ratings:      map[database.RatingSystem]*database.Rating{u.system: {CurrentRating: u.rating}},
+		})
+	}
+	if _, err := repo.PutRatingDistributions(slices.Collect(maps.Values(distributions))); err != nil {
+		t.Fatalf("PutRatingDistributions got err %v", err)
+	}
+	saved, err := repo.ListRatingDistributions()
+	if err != nil {
+		t.Fatalf("ListRatingDistributions got err %v", err)
+	}
+
+	current := At("2024-01-01")
+	proposed := &Table{EffectiveDate: "2030-01-01", Boundaries: map[database.RatingSystem][]int{
+		database.Chesscom: slices.Clone(current.Boundaries[database.Chesscom]),
+		database.Lichess:  current.Boundaries[database.Lichess],
+	}}
+	proposed.Boundaries[database.Chesscom][13] = 1925 // 1600-1700 now starts at 1925
+
+	got := PreviewTable(saved, current, proposed)
+	want := &Preview{
+		CurrentEffectiveDate: "2024-01-01",
+		UpdatedAt:            distributions[database.Chesscom].UpdatedAt,
+		Users:                3,
+		Changed:              1,
+		Changes:              map[database.DojoCohort]map[database.DojoCohort]int{"1600-1700": {"1500-1600": 1}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("PreviewTable mismatch (-want +got):\n%s", diff)
+	}
+}
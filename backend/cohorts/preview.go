@@ -0,0 +1,74 @@
+package cohorts
+
+import (
+	"strconv"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// Preview summarizes how users' cohorts would change if a proposed Table replaced the
+// current one.
+type Preview struct {
+	// The effective date of the Table the proposed Table is compared against.
+	CurrentEffectiveDate string `json:"currentEffectiveDate"`
+
+	// The time the oldest of the compared rating distributions was calculated, in time.RFC3339
+	// format. Ratings which changed since then are not reflected in the preview.
+	UpdatedAt string `json:"updatedAt,omitempty"`
+
+	// The number of users whose cohorts were compared. Users without a rating in their
+	// preferred rating system, or whose rating system has no boundaries, are skipped.
+	Users int `json:"users"`
+
+	// The number of users whose cohort would change.
+	Changed int `json:"changed"`
+
+	// The number of users whose cohort would change, keyed by their cohort under the current
+	// Table and then by their cohort under the proposed Table.
+	Changes map[database.DojoCohort]map[database.DojoCohort]int `json:"changes"`
+}
+
+// PreviewTable compares the cohorts of the users counted in the provided rating distributions
+// under the current and proposed Tables. The cohorts are calculated from the user's preferred
+// rating, so users who chose a different cohort than their rating suggests are only counted as
+// changed if the calculated cohort changes.
+func PreviewTable(distributions []database.RatingDistribution, current, proposed *Table) *Preview {
+	preview := &Preview{
+		CurrentEffectiveDate: current.EffectiveDate,
+		Changes:              make(map[database.DojoCohort]map[database.DojoCohort]int),
+	}
+
+	for _, d := range distributions {
+		if len(d.Counts) == 0 {
+			continue
+		}
+		if preview.UpdatedAt == "" || d.UpdatedAt < preview.UpdatedAt {
+			preview.UpdatedAt = d.UpdatedAt
+		}
+
+		for key, count := range d.Counts {
+			rating, err := strconv.Atoi(key)
+			if err != nil || rating <= 0 {
+				continue
+			}
+
+			before := current.GetCohort(d.RatingSystem, rating)
+			after := proposed.GetCohort(d.RatingSystem, rating)
+			if before == database.NoCohort || after == database.NoCohort {
+				continue
+			}
+
+			preview.Users += count
+			if before == after {
+				continue
+			}
+
+			preview.Changed += count
+			if preview.Changes[before] == nil {
+				preview.Changes[before] = make(map[database.DojoCohort]int)
+			}
+			preview.Changes[before][after] += count
+		}
+	}
+	return preview
+}
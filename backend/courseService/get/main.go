@@ -13,7 +13,7 @@ import (
 	"github.com/stripe/stripe-go/v81"
 )
 
-var repository database.CourseGetter = database.DynamoDB
+var repository = database.Repository[database.CourseGetter](database.DynamoDB)
 
 type GetCourseResponse struct {
 	// The requested course.
@@ -25,13 +25,10 @@ type GetCourseResponse struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	courseType := event.PathParameters["type"]
 	id := event.PathParameters["id"]
 	if courseType == "" || id == "" {
@@ -45,7 +42,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
-		return checkAnonymousAccess(event, course)
+		return checkAnonymousAccess(ctx, event, course)
 	}
 
 	caller, err := repository.GetUser(info.Username)
@@ -69,7 +66,8 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	return accessDenied(course)
 }
 
-func checkAnonymousAccess(event api.Request, course *database.Course) (api.Response, error) {
+func checkAnonymousAccess(ctx context.Context, event api.Request, course *database.Course) (api.Response, error) {
+	logger := log.FromContext(ctx)
 	checkoutId := event.QueryStringParameters["checkoutId"]
 	if checkoutId == "" {
 		return accessDenied(course)
@@ -77,7 +75,7 @@ func checkAnonymousAccess(event api.Request, course *database.Course) (api.Respo
 
 	checkoutSession, err := payment.GetCheckoutSession(checkoutId)
 	if err != nil {
-		log.Error("GetCheckoutSession err: ", err)
+		logger.Errorf("GetCheckoutSession err: %v", err)
 		return accessDenied(course)
 	}
 
@@ -5,15 +5,13 @@ import (
 	"os"
 	"strings"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.CourseLister = database.DynamoDB
+var repository = database.Repository[database.CourseLister](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 type ListCoursesResponse struct {
@@ -22,16 +20,10 @@ type ListCoursesResponse struct {
 }
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
-func handler(ctx context.Context, event events.APIGatewayV2HTTPRequest) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	var courses []database.Course
 	var lastKey string
 	var err error
@@ -11,20 +11,18 @@ import (
 	payment "github.com/jackstenglein/chess-dojo-scheduler/backend/paymentService"
 )
 
-var repository database.CourseGetter = database.DynamoDB
+var repository = database.Repository[database.CourseGetter](database.DynamoDB)
 
 type PurchaseCourseResponse struct {
 	Url string `json:"url"`
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Handle(handler, api.Idempotent(database.Repository[database.IdempotencyStore](database.DynamoDB))))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(event)
 
 	courseType := event.PathParameters["type"]
@@ -55,7 +53,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if info.Username != "" {
 		user, err = repository.GetUser(info.Username)
 		if err != nil {
-			log.Error("Failed to get user: ", err)
+			logger.Errorf("Failed to get user: %v", err)
 		}
 	}
 
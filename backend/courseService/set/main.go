@@ -9,20 +9,17 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
 )
 
-var repository database.CourseSetter = database.DynamoDB
+var repository = database.Repository[database.CourseSetter](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		err := errors.New(403, "Invalid request: username is required", "")
@@ -108,6 +105,30 @@ func checkCourse(user *database.User, course *database.Course) error {
 		}
 	}
 
+	for i, chapter := range course.Chapters {
+		if chapter == nil {
+			continue
+		}
+		if chapter.ThumbnailFen != "" {
+			if _, err := pgn.ParseFEN(chapter.ThumbnailFen); err != nil {
+				return errors.Wrap(400, fmt.Sprintf("Invalid request: chapters[%d].thumbnailFen is invalid: %v", i, err), "", err)
+			}
+		}
+		for j, module := range chapter.Modules {
+			if module == nil {
+				continue
+			}
+			if err := database.ValidatePositions(fmt.Sprintf("chapters[%d].modules[%d].positions", i, j), module.Positions); err != nil {
+				return err
+			}
+			for k, text := range module.Pgns {
+				if err := pgn.Validate(text); err != nil {
+					return errors.Wrap(400, fmt.Sprintf("Invalid request: chapters[%d].modules[%d].pgns[%d] is invalid: %v", i, j, k, err), "", err)
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
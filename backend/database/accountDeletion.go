@@ -0,0 +1,703 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// DeletedUsername and DeletedDisplayName replace the owner of records which are anonymised
+// rather than deleted when an account is deleted, such as comments on other users' games.
+const (
+	DeletedUsername    = "DELETED_USER"
+	DeletedDisplayName = "Deleted User"
+	deletedContent     = "[deleted]"
+)
+
+// accountDeletionTTL is how long a completed AccountDeletion is kept, so that the user can
+// confirm that their account was deleted.
+const accountDeletionTTL = 30 * 24 * time.Hour
+
+// AccountDeletionStatus is the state of an AccountDeletion.
+type AccountDeletionStatus string
+
+const (
+	AccountDeletionStatus_InProgress AccountDeletionStatus = "IN_PROGRESS"
+	AccountDeletionStatus_Complete   AccountDeletionStatus = "COMPLETE"
+)
+
+// AccountDeletionStep is a single stage of an AccountDeletion. Each step deletes or anonymises
+// one kind of record owned by the user.
+type AccountDeletionStep string
+
+const (
+	AccountDeletionStep_Followers     AccountDeletionStep = "FOLLOWERS"
+	AccountDeletionStep_Following     AccountDeletionStep = "FOLLOWING"
+	AccountDeletionStep_Clubs         AccountDeletionStep = "CLUBS"
+	AccountDeletionStep_Events        AccountDeletionStep = "EVENTS"
+	AccountDeletionStep_Timeline      AccountDeletionStep = "TIMELINE"
+	AccountDeletionStep_Newsfeed      AccountDeletionStep = "NEWSFEED"
+	AccountDeletionStep_NewsfeedPosts AccountDeletionStep = "NEWSFEED_POSTS"
+	AccountDeletionStep_Games         AccountDeletionStep = "GAMES"
+	AccountDeletionStep_Comments      AccountDeletionStep = "COMMENTS"
+	AccountDeletionStep_Notifications AccountDeletionStep = "NOTIFICATIONS"
+	AccountDeletionStep_Exams         AccountDeletionStep = "EXAMS"
+	AccountDeletionStep_Directories   AccountDeletionStep = "DIRECTORIES"
+	AccountDeletionStep_Graduations   AccountDeletionStep = "GRADUATIONS"
+	AccountDeletionStep_YearReviews   AccountDeletionStep = "YEAR_REVIEWS"
+	AccountDeletionStep_User          AccountDeletionStep = "USER"
+)
+
+// AccountDeletionSteps is the order in which the steps of an AccountDeletion run. Steps which
+// update denormalised data on other records, such as follower counts and club members, run
+// first, as they rely on the user's records still existing. The user itself is deleted last.
+var AccountDeletionSteps = []AccountDeletionStep{
+	AccountDeletionStep_Followers,
+	AccountDeletionStep_Following,
+	AccountDeletionStep_Clubs,
+	AccountDeletionStep_Events,
+	AccountDeletionStep_Timeline,
+	AccountDeletionStep_Newsfeed,
+	AccountDeletionStep_NewsfeedPosts,
+	AccountDeletionStep_Games,
+	AccountDeletionStep_Comments,
+	AccountDeletionStep_Notifications,
+	AccountDeletionStep_Exams,
+	AccountDeletionStep_Directories,
+	AccountDeletionStep_Graduations,
+	AccountDeletionStep_YearReviews,
+	AccountDeletionStep_User,
+}
+
+// AccountDeletion tracks the progress of deleting a user's account. Steps run in the order of
+// AccountDeletionSteps, one page at a time, and the deletion is saved after each page so that
+// it can resume from where it stopped.
+type AccountDeletion struct {
+	// The username of the account being deleted. The hash key of the table.
+	Username string `dynamodbav:"username" json:"username"`
+
+	// The state of the deletion.
+	Status AccountDeletionStatus `dynamodbav:"status" json:"status"`
+
+	// The step currently running. Empty once the deletion is complete.
+	Step AccountDeletionStep `dynamodbav:"step,omitempty" json:"step,omitempty"`
+
+	// The start key of the next page of the current step.
+	StartKey string `dynamodbav:"startKey,omitempty" json:"-"`
+
+	// The number of steps which have finished.
+	CompletedSteps int `dynamodbav:"completedSteps" json:"completedSteps"`
+
+	// The total number of steps.
+	TotalSteps int `dynamodbav:"totalSteps" json:"totalSteps"`
+
+	// The number of records deleted or anonymised by each step.
+	Progress map[AccountDeletionStep]int `dynamodbav:"progress" json:"progress"`
+
+	// The error from the last failed page, if any. The page is retried on the next run.
+	LastError string `dynamodbav:"lastError,omitempty" json:"-"`
+
+	// The time the deletion was requested, in time.RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The time the deletion was last saved, in time.RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+
+	// The time the deletion finished, in time.RFC3339 format.
+	CompletedAt string `dynamodbav:"completedAt,omitempty" json:"completedAt,omitempty"`
+
+	// The time the completed deletion expires, in Unix seconds.
+	ExpirationTime int64 `dynamodbav:"expirationTime,omitempty" json:"-"`
+}
+
+// NewAccountDeletion returns an AccountDeletion for the provided username, starting at
+// the first step.
+func NewAccountDeletion(username string) *AccountDeletion {
+	now := time.Now().Format(time.RFC3339)
+	return &AccountDeletion{
+		Username:   username,
+		Status:     AccountDeletionStatus_InProgress,
+		Step:       AccountDeletionSteps[0],
+		TotalSteps: len(AccountDeletionSteps),
+		Progress:   make(map[AccountDeletionStep]int),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// Advance records that a page of the current step processed count records. If nextKey is
+// empty, the step is finished and the deletion moves to the next step, or is marked complete
+// if there are no more steps.
+func (d *AccountDeletion) Advance(count int, nextKey string) {
+	now := time.Now()
+	if d.Progress == nil {
+		d.Progress = make(map[AccountDeletionStep]int)
+	}
+	d.Progress[d.Step] += count
+	d.StartKey = nextKey
+	d.LastError = ""
+	d.UpdatedAt = now.Format(time.RFC3339)
+	if nextKey != "" {
+		return
+	}
+
+	d.CompletedSteps++
+	if d.CompletedSteps < len(AccountDeletionSteps) {
+		d.Step = AccountDeletionSteps[d.CompletedSteps]
+		return
+	}
+
+	d.Step = ""
+	d.Status = AccountDeletionStatus_Complete
+	d.CompletedAt = d.UpdatedAt
+	d.ExpirationTime = now.Add(accountDeletionTTL).Unix()
+}
+
+// AccountDeletionRequester provides an interface for requesting and checking on account deletions.
+type AccountDeletionRequester interface {
+	// CreateAccountDeletion starts deleting the account with the provided username. If the
+	// account is already being deleted, the existing AccountDeletion is returned instead.
+	CreateAccountDeletion(username string) (*AccountDeletion, error)
+
+	// GetAccountDeletion returns the AccountDeletion for the provided username.
+	GetAccountDeletion(username string) (*AccountDeletion, error)
+}
+
+// AccountDeleter provides an interface for running account deletions.
+type AccountDeleter interface {
+	UserGetter
+
+	// ListAccountDeletions returns the deletions which are in progress. The next start key
+	// is also returned.
+	ListAccountDeletions(startKey string) ([]AccountDeletion, string, error)
+
+	// PutAccountDeletion saves the provided deletion.
+	PutAccountDeletion(deletion *AccountDeletion) error
+
+	// ListFollowers returns a list of FollowerEntries where the provided username is the poster.
+	ListFollowers(username, startKey string) ([]FollowerEntry, string, error)
+
+	// ListFollowing returns a list of FollowerEntries where the provided username is the follower.
+	ListFollowing(username, startKey string) ([]FollowerEntry, string, error)
+
+	// DeleteFollower removes a FollowerEntry and updates both users' follow counts.
+	DeleteFollower(poster, follower string) error
+
+	// ScanClubsByUser returns the clubs the provided username owns, is a member of or has
+	// requested to join. The next start key is also returned.
+	ScanClubsByUser(username, startKey string) ([]Club, string, error)
+
+	// DeleteClub removes the club with the provided id and removes it from its members' clubs.
+	DeleteClub(id string) (*Club, error)
+
+	// RemoveClubMember removes the provided username as a member of the provided club.
+	RemoveClubMember(id, username string) (*Club, error)
+
+	// DeleteClubJoinRequest removes the provided username's request to join the provided club.
+	DeleteClubJoinRequest(id, username string) (*Club, error)
+
+	// ScanEventsByUser returns the events the provided username owns or participates in.
+	// The next start key is also returned.
+	ScanEventsByUser(username, startKey string) ([]Event, string, error)
+
+	// DeleteEvent removes the event with the provided id.
+	DeleteEvent(id string) (*Event, error)
+
+	// LeaveEvent removes the provided participant from the provided event. If participant is
+	// nil, the owner leaves and the first participant becomes the new owner.
+	LeaveEvent(event *Event, participant *Participant, requireNoPayment bool) (*Event, error)
+
+	// ListTimelineEntries returns the timeline entries owned by the provided username.
+	ListTimelineEntries(owner, startKey string) ([]*TimelineEntry, string, error)
+
+	// DeleteTimelineEntries removes the provided timeline entries.
+	DeleteTimelineEntries(entries []*TimelineEntry) (int, error)
+
+	// DeleteNewsfeedPage removes a page of the entries in the provided newsfeed.
+	DeleteNewsfeedPage(newsfeedId, startKey string) (int, string, error)
+
+	// DeleteNewsfeedEntriesByPosterPage removes a page of the entries generated by the
+	// provided poster, across all newsfeeds.
+	DeleteNewsfeedEntriesByPosterPage(poster, startKey string) (int, string, error)
+
+	// ListGamesByOwner returns the games owned by the provided username.
+	ListGamesByOwner(isOwner bool, owner, startDate, endDate, startKey string) ([]*Game, string, error)
+
+	// DeleteGame removes the provided game, if it is owned by the provided username.
+	DeleteGame(username, cohort, id string) (*Game, error)
+
+	// ScanGameComments returns the cohort, id and comments of the games which have comments.
+	// The next start key is also returned.
+	ScanGameComments(startKey string) ([]Game, string, error)
+
+	// AnonymizeGameComments anonymises the provided username's comments on the provided game.
+	// The number of anonymised comments is returned.
+	AnonymizeGameComments(game *Game, username string) (int, error)
+
+	// DeleteNotificationsPage removes a page of the provided username's notifications.
+	DeleteNotificationsPage(username, startKey string) (int, string, error)
+
+	// ListExams lists the exam answers when examType is a username.
+	ListExams(examType ExamType, startKey string, out interface{}) (string, error)
+
+	// DeleteExamAnswer removes the provided answer and its summary on the exam.
+	DeleteExamAnswer(answer *ExamAnswer) error
+
+	// DeleteDirectoriesPage removes a page of the directories owned by the provided username.
+	DeleteDirectoriesPage(owner, startKey string) (int, string, error)
+
+	// DeleteGraduationsPage removes a page of the provided username's graduations.
+	DeleteGraduationsPage(username, startKey string) (int, string, error)
+
+	// DeleteYearReviewsPage removes a page of the provided username's year reviews.
+	DeleteYearReviewsPage(username, startKey string) (int, string, error)
+
+	// DeleteUser removes the user with the provided username.
+	DeleteUser(username string) error
+}
+
+// CreateAccountDeletion starts deleting the account with the provided username. If the
+// account is already being deleted, the existing AccountDeletion is returned instead.
+func (repo *dynamoRepository) CreateAccountDeletion(username string) (*AccountDeletion, error) {
+	deletion := NewAccountDeletion(username)
+	item, err := dynamodbattribute.MarshalMap(deletion)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal account deletion", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item: item,
+		// A completed deletion does not prevent deleting an account created later with the same username.
+		ConditionExpression: aws.String("attribute_not_exists(username) OR #status = :complete"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":complete": {S: aws.String(string(AccountDeletionStatus_Complete))},
+		},
+		TableName: aws.String(accountDeletionTable),
+	}
+	if _, err := repo.svc.PutItem(input); err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return repo.GetAccountDeletion(username)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+	}
+	return deletion, nil
+}
+
+// GetAccountDeletion returns the AccountDeletion for the provided username.
+func (repo *dynamoRepository) GetAccountDeletion(username string) (*AccountDeletion, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		TableName: aws.String(accountDeletionTable),
+	}
+	deletion := AccountDeletion{}
+	if err := repo.getItem(input, &deletion); err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// ListAccountDeletions returns the deletions which are in progress. The next start key
+// is also returned.
+func (repo *dynamoRepository) ListAccountDeletions(startKey string) ([]AccountDeletion, string, error) {
+	input := &dynamodb.ScanInput{
+		FilterExpression: aws.String("#status = :inProgress"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":inProgress": {S: aws.String(string(AccountDeletionStatus_InProgress))},
+		},
+		TableName: aws.String(accountDeletionTable),
+	}
+
+	var deletions []AccountDeletion
+	lastKey, err := repo.scan(input, startKey, &deletions)
+	if err != nil {
+		return nil, "", err
+	}
+	return deletions, lastKey, nil
+}
+
+// PutAccountDeletion saves the provided deletion.
+func (repo *dynamoRepository) PutAccountDeletion(deletion *AccountDeletion) error {
+	item, err := dynamodbattribute.MarshalMap(deletion)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal account deletion", err)
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(accountDeletionTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+}
+
+// deleteQueryPage deletes the items returned by a page of the provided query. keyNames are
+// the key attributes of the table, which must be strings. The number of deleted items and
+// the next start key are returned.
+func (repo *dynamoRepository) deleteQueryPage(input *dynamodb.QueryInput, keyNames []string, startKey string) (int, string, error) {
+	input.ProjectionExpression = aws.String("#k0")
+	if input.ExpressionAttributeNames == nil {
+		input.ExpressionAttributeNames = make(map[string]*string)
+	}
+	for i, name := range keyNames {
+		if i > 0 {
+			input.ProjectionExpression = aws.String(fmt.Sprintf("%s, #k%d", *input.ProjectionExpression, i))
+		}
+		input.ExpressionAttributeNames[fmt.Sprintf("#k%d", i)] = aws.String(name)
+	}
+
+	var keys []map[string]string
+	lastKey, err := repo.query(input, startKey, &keys)
+	if err != nil {
+		return 0, "", err
+	}
+
+	deleted := 0
+	for start := 0; start < len(keys); start += 25 {
+		end := min(start+25, len(keys))
+		reqs := make([]*dynamodb.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			av := make(map[string]*dynamodb.AttributeValue, len(keyNames))
+			for _, name := range keyNames {
+				av[name] = &dynamodb.AttributeValue{S: aws.String(key[name])}
+			}
+			reqs = append(reqs, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: av}})
+		}
+		if err := repo.batchWrite(reqs, *input.TableName); err != nil {
+			return deleted, "", err
+		}
+		deleted += len(reqs)
+	}
+	return deleted, lastKey, nil
+}
+
+// partitionQuery returns a QueryInput for all items with the provided hash key.
+func partitionQuery(tableName, hashName, hashValue string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#hash = :hash"),
+		ExpressionAttributeNames: map[string]*string{
+			"#hash": aws.String(hashName),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":hash": {S: aws.String(hashValue)},
+		},
+		TableName: aws.String(tableName),
+	}
+}
+
+// DeleteNewsfeedPage removes a page of the entries in the provided newsfeed. The number
+// of deleted entries and the next start key are returned.
+func (repo *dynamoRepository) DeleteNewsfeedPage(newsfeedId, startKey string) (int, string, error) {
+	input := partitionQuery(newsfeedTable, "newsfeedId", newsfeedId)
+	return repo.deleteQueryPage(input, []string{"newsfeedId", "sortKey"}, startKey)
+}
+
+// DeleteNewsfeedEntriesByPosterPage removes a page of the entries generated by the provided
+// poster, across all newsfeeds. The number of deleted entries and the next start key are returned.
+func (repo *dynamoRepository) DeleteNewsfeedEntriesByPosterPage(poster, startKey string) (int, string, error) {
+	input := partitionQuery(newsfeedTable, "poster", poster)
+	input.IndexName = aws.String("PosterIndex")
+	return repo.deleteQueryPage(input, []string{"newsfeedId", "sortKey"}, startKey)
+}
+
+// DeleteNotificationsPage removes a page of the provided username's notifications. The number
+// of deleted notifications and the next start key are returned.
+func (repo *dynamoRepository) DeleteNotificationsPage(username, startKey string) (int, string, error) {
+	input := partitionQuery(notificationTable, "username", username)
+	return repo.deleteQueryPage(input, []string{"username", "id"}, startKey)
+}
+
+// DeleteDirectoriesPage removes a page of the directories owned by the provided username. The
+// number of deleted directories and the next start key are returned.
+func (repo *dynamoRepository) DeleteDirectoriesPage(owner, startKey string) (int, string, error) {
+	input := partitionQuery(directoryTable, "owner", owner)
+	return repo.deleteQueryPage(input, []string{"owner", "id"}, startKey)
+}
+
+// DeleteGraduationsPage removes a page of the provided username's graduations. The number of
+// deleted graduations and the next start key are returned.
+func (repo *dynamoRepository) DeleteGraduationsPage(username, startKey string) (int, string, error) {
+	input := partitionQuery(graduationTable, "username", username)
+	return repo.deleteQueryPage(input, []string{"username", "previousCohort"}, startKey)
+}
+
+// DeleteYearReviewsPage removes a page of the provided username's year reviews. The number of
+// deleted year reviews and the next start key are returned.
+func (repo *dynamoRepository) DeleteYearReviewsPage(username, startKey string) (int, string, error) {
+	input := partitionQuery(yearReviewTable, "username", username)
+	return repo.deleteQueryPage(input, []string{"username", "period"}, startKey)
+}
+
+// ScanClubsByUser returns the clubs the provided username owns, is a member of or has
+// requested to join. The next start key is also returned.
+func (repo *dynamoRepository) ScanClubsByUser(username, startKey string) ([]Club, string, error) {
+	input := &dynamodb.ScanInput{
+		FilterExpression: aws.String("#owner = :username OR attribute_exists(#members.#username) OR attribute_exists(#joinRequests.#username)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":        aws.String("owner"),
+			"#members":      aws.String("members"),
+			"#joinRequests": aws.String("joinRequests"),
+			"#username":     aws.String(username),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+		TableName: aws.String(clubTable),
+	}
+
+	var clubs []Club
+	lastKey, err := repo.scan(input, startKey, &clubs)
+	if err != nil {
+		return nil, "", err
+	}
+	return clubs, lastKey, nil
+}
+
+// DeleteClub removes the club with the provided id. The club is also removed from the clubs
+// of each of its members. The deleted club is returned.
+func (repo *dynamoRepository) DeleteClub(id string) (*Club, error) {
+	input := &dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		ReturnValues:        aws.String("ALL_OLD"),
+		TableName:           aws.String(clubTable),
+	}
+	result, err := repo.svc.DeleteItem(input)
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: club not found", "DynamoDB conditional check failed", err)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB DeleteItem call", err)
+	}
+
+	club := &Club{}
+	if err := dynamodbattribute.UnmarshalMap(result.Attributes, club); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal DeleteItem result", err)
+	}
+
+	for username := range club.Members {
+		input := &dynamodb.UpdateItemInput{
+			Key: map[string]*dynamodb.AttributeValue{
+				"username": {S: aws.String(username)},
+			},
+			ConditionExpression: aws.String("attribute_exists(username)"),
+			UpdateExpression:    aws.String("DELETE clubs :id"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":id": {SS: []*string{aws.String(id)}},
+			},
+			TableName: aws.String(userTable),
+		}
+		if _, err := repo.svc.UpdateItem(input); err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				continue
+			}
+			return nil, errors.Wrap(500, "Temporary server error", "Failed to update club member", err)
+		}
+	}
+	return club, nil
+}
+
+// ScanEventsByUser returns the events the provided username owns or participates in.
+// The next start key is also returned.
+func (repo *dynamoRepository) ScanEventsByUser(username, startKey string) ([]Event, string, error) {
+	input := &dynamodb.ScanInput{
+		FilterExpression: aws.String("id <> :statistics AND (#owner = :username OR attribute_exists(#participants.#username))"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":        aws.String("owner"),
+			"#participants": aws.String("participants"),
+			"#username":     aws.String(username),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":statistics": {S: aws.String("STATISTICS")},
+			":username":   {S: aws.String(username)},
+		},
+		TableName: aws.String(eventTable),
+	}
+
+	var events []Event
+	lastKey, err := repo.scan(input, startKey, &events)
+	if err != nil {
+		return nil, "", err
+	}
+	return events, lastKey, nil
+}
+
+// ScanGameComments returns the cohort, id, owner and comments of the games which have
+// comments. The next start key is also returned. Comments have no index by their owner,
+// so this reads the whole games table.
+func (repo *dynamoRepository) ScanGameComments(startKey string) ([]Game, string, error) {
+	input := &dynamodb.ScanInput{
+		FilterExpression:     aws.String("attribute_exists(positionComments) OR attribute_exists(comments)"),
+		ProjectionExpression: aws.String("cohort, id, #owner, positionComments, comments"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("owner"),
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	var games []Game
+	lastKey, err := repo.scan(input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// maxCommentsPerUpdate is the maximum number of comments anonymised by a single UpdateItem
+// request, which keeps the update expression under DynamoDB's size limit.
+const maxCommentsPerUpdate = 20
+
+// AnonymizeGameComments anonymises the provided username's comments on the provided game.
+// The owner of each comment is replaced with DeletedUsername and its content is removed.
+// Replies from other users are kept. The number of anonymised comments is returned.
+func (repo *dynamoRepository) AnonymizeGameComments(game *Game, username string) (int, error) {
+	paths := gameCommentPaths(game, username)
+	for start := 0; start < len(paths); start += maxCommentsPerUpdate {
+		end := min(start+maxCommentsPerUpdate, len(paths))
+		names := map[string]*string{
+			"#owner":   aws.String("owner"),
+			"#content": aws.String("content"),
+		}
+		values := map[string]*dynamodb.AttributeValue{
+			":content": {S: aws.String(deletedContent)},
+		}
+
+		var set, remove []string
+		for i, path := range paths[start:end] {
+			var segments []string
+			for j, segment := range path.segments {
+				name := fmt.Sprintf("#p%d_%d", i, j)
+				names[name] = aws.String(segment)
+				segments = append(segments, name)
+			}
+			expr := strings.Join(segments, ".")
+
+			if path.index >= 0 {
+				// Legacy comments store the owner's fields directly on the comment.
+				expr += "[" + strconv.Itoa(path.index) + "]"
+				names["#ownerDisplayName"] = aws.String("ownerDisplayName")
+				names["#ownerCohort"] = aws.String("ownerCohort")
+				values[":username"] = &dynamodb.AttributeValue{S: aws.String(DeletedUsername)}
+				values[":displayName"] = &dynamodb.AttributeValue{S: aws.String(DeletedDisplayName)}
+				values[":empty"] = &dynamodb.AttributeValue{S: aws.String("")}
+				set = append(set, expr+".#owner = :username", expr+".#ownerDisplayName = :displayName",
+					expr+".#ownerCohort = :empty", expr+".#content = :content")
+				continue
+			}
+
+			names["#variation"] = aws.String("suggestedVariation")
+			values[":owner"] = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+				"username":    {S: aws.String(DeletedUsername)},
+				"displayName": {S: aws.String(DeletedDisplayName)},
+				"cohort":      {S: aws.String("")},
+			}}
+			set = append(set, expr+".#owner = :owner", expr+".#content = :content")
+			remove = append(remove, expr+".#variation")
+		}
+
+		update := "SET " + strings.Join(set, ", ")
+		if len(remove) > 0 {
+			update += " REMOVE " + strings.Join(remove, ", ")
+		}
+		input := &dynamodb.UpdateItemInput{
+			Key: map[string]*dynamodb.AttributeValue{
+				"cohort": {S: aws.String(string(game.Cohort))},
+				"id":     {S: aws.String(game.Id)},
+			},
+			ConditionExpression:       aws.String("attribute_exists(id)"),
+			UpdateExpression:          aws.String(update),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			TableName:                 aws.String(gameTable),
+		}
+		if _, err := repo.svc.UpdateItem(input); err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				// The game was deleted after it was scanned.
+				return start, nil
+			}
+			return start, errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem call", err)
+		}
+	}
+	return len(paths), nil
+}
+
+// gameCommentPath is the location of a comment within a Game. For position comments, segments
+// are the attribute names leading to the comment and index is -1. For legacy comments, segments
+// is the comments list and index is the comment's index in it.
+type gameCommentPath struct {
+	segments []string
+	index    int
+}
+
+// gameCommentPaths returns the paths of the comments on the provided game which are owned by
+// the provided username, including replies to other users' comments.
+func gameCommentPaths(game *Game, username string) []gameCommentPath {
+	var paths []gameCommentPath
+	var walk func(prefix []string, comments map[string]PositionComment)
+	walk = func(prefix []string, comments map[string]PositionComment) {
+		for id, comment := range comments {
+			path := append(append([]string{}, prefix...), id)
+			if comment.Owner.Username == username {
+				paths = append(paths, gameCommentPath{segments: path, index: -1})
+			}
+			walk(append(path, "replies"), comment.Replies)
+		}
+	}
+	for fen, comments := range game.PositionComments {
+		walk([]string{"positionComments", fen}, comments)
+	}
+
+	for i, comment := range game.Comments {
+		if comment != nil && comment.Owner == username {
+			paths = append(paths, gameCommentPath{segments: []string{"comments"}, index: i})
+		}
+	}
+	return paths
+}
+
+// DeleteExamAnswer removes the provided answer and the summary of it on the exam, so
+// that the user is no longer included in the exam's results.
+func (repo *dynamoRepository) DeleteExamAnswer(answer *ExamAnswer) error {
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"type": {S: aws.String(string(answer.ExamType))},
+			"id":   {S: aws.String(answer.Id)},
+		},
+		ConditionExpression: aws.String("attribute_exists(#answers.#user)"),
+		UpdateExpression:    aws.String("REMOVE #answers.#user"),
+		ExpressionAttributeNames: map[string]*string{
+			"#answers": aws.String("answers"),
+			"#user":    aws.String(string(answer.Type)),
+		},
+		TableName: aws.String(examsTable),
+	}
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); !ok {
+			return errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem call", err)
+		}
+	}
+
+	deleteInput := &dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"type": {S: aws.String(string(answer.Type))},
+			"id":   {S: aws.String(answer.Id)},
+		},
+		TableName: aws.String(examsTable),
+	}
+	_, err := repo.svc.DeleteItem(deleteInput)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB DeleteItem call", err)
+}
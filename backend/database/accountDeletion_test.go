@@ -0,0 +1,60 @@
+package database
+
+import "testing"
+
+func TestAccountDeletionAdvance(t *testing.T) {
+	deletion := NewAccountDeletion("alice")
+
+	deletion.Advance(25, "next")
+	if deletion.Step != AccountDeletionSteps[0] || deletion.StartKey != "next" {
+		t.Errorf("Advance with next key got step %s and start key %q, want same step", deletion.Step, deletion.StartKey)
+	}
+
+	deletion.Advance(5, "")
+	if deletion.Step != AccountDeletionSteps[1] || deletion.StartKey != "" || deletion.CompletedSteps != 1 {
+		t.Errorf("Advance without next key got step %s and %d completed steps, want step %s", deletion.Step, deletion.CompletedSteps, AccountDeletionSteps[1])
+	}
+	if got := deletion.Progress[AccountDeletionSteps[0]]; got != 30 {
+		t.Errorf("Progress got %d, want 30", got)
+	}
+
+	for range AccountDeletionSteps[1:] {
+		deletion.Advance(0, "")
+	}
+	if deletion.Status != AccountDeletionStatus_Complete || deletion.Step != "" || deletion.ExpirationTime == 0 {
+		t.Errorf("Advance past last step got status %s, step %q and expiration %d, want complete deletion", deletion.Status, deletion.Step, deletion.ExpirationTime)
+	}
+}
+
+func TestMemoryCreateAccountDeletion(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	first, err := repo.CreateAccountDeletion("alice")
+	if err != nil {
+		t.Fatalf("CreateAccountDeletion got err %v", err)
+	}
+	first.Advance(3, "")
+	if err := repo.PutAccountDeletion(first); err != nil {
+		t.Fatalf("PutAccountDeletion got err %v", err)
+	}
+
+	again, err := repo.CreateAccountDeletion("alice")
+	if err != nil {
+		t.Fatalf("CreateAccountDeletion got err %v", err)
+	}
+	if again.CompletedSteps != 1 {
+		t.Errorf("CreateAccountDeletion while in progress got %d completed steps, want existing deletion", again.CompletedSteps)
+	}
+
+	again.Status = AccountDeletionStatus_Complete
+	if err := repo.PutAccountDeletion(again); err != nil {
+		t.Fatalf("PutAccountDeletion got err %v", err)
+	}
+	restarted, err := repo.CreateAccountDeletion("alice")
+	if err != nil {
+		t.Fatalf("CreateAccountDeletion got err %v", err)
+	}
+	if restarted.Status != AccountDeletionStatus_InProgress || restarted.CompletedSteps != 0 {
+		t.Errorf("CreateAccountDeletion after completion got status %s, want new deletion", restarted.Status)
+	}
+}
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// AuditAction is the type of an admin or moderator action recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditAction_BanPlayer          AuditAction = "BAN_PLAYER"
+	AuditAction_UnbanPlayer        AuditAction = "UNBAN_PLAYER"
+	AuditAction_WithdrawPlayer     AuditAction = "WITHDRAW_PLAYER"
+	AuditAction_VerifyResult       AuditAction = "VERIFY_RESULT"
+	AuditAction_SetPairings        AuditAction = "SET_PAIRINGS"
+	AuditAction_CloseRegistrations AuditAction = "CLOSE_REGISTRATIONS"
+	AuditAction_CompleteTournament AuditAction = "COMPLETE_TOURNAMENT"
+	AuditAction_EmailPairings      AuditAction = "EMAIL_PAIRINGS"
+	AuditAction_ReviewGame         AuditAction = "REVIEW_GAME"
+	AuditAction_SetRoles           AuditAction = "SET_ROLES"
+	AuditAction_DeleteEvent        AuditAction = "DELETE_EVENT"
+	AuditAction_ModerateClub       AuditAction = "MODERATE_CLUB"
+	AuditAction_ReplayOutbox       AuditAction = "REPLAY_OUTBOX"
+)
+
+// UserAuditTarget returns the audit log target for the user with the provided username.
+func UserAuditTarget(username string) string {
+	return "USER#" + username
+}
+
+// OpenClassicalAuditTarget returns the audit log target for the provided region and section
+// of the current open classical. If region is empty, the target is the open classical as a whole.
+func OpenClassicalAuditTarget(region, section string) string {
+	if region == "" {
+		return "OPEN_CLASSICAL"
+	}
+	return "OPEN_CLASSICAL#" + region + "_" + section
+}
+
+// GameAuditTarget returns the audit log target for the game with the provided cohort and id.
+func GameAuditTarget(cohort, id string) string {
+	return "GAME#" + cohort + "#" + id
+}
+
+// EventAuditTarget returns the audit log target for the event with the provided id.
+func EventAuditTarget(id string) string {
+	return "EVENT#" + id
+}
+
+// ClubAuditTarget returns the audit log target for the club with the provided id.
+func ClubAuditTarget(id string) string {
+	return "CLUB#" + id
+}
+
+// OutboxAuditTarget returns the audit log target for the outbox entry with the provided id.
+func OutboxAuditTarget(id string) string {
+	return "OUTBOX#" + id
+}
+
+// AuditChange is the value of a single attribute before and after an audited action.
+type AuditChange struct {
+	// The value before the action. Nil if the attribute did not exist.
+	Before any `dynamodbav:"before" json:"before"`
+
+	// The value after the action. Nil if the attribute was removed.
+	After any `dynamodbav:"after" json:"after"`
+}
+
+// AuditEntry is a single entry in the audit log. Entries are never updated or deleted.
+type AuditEntry struct {
+	// The entity affected by the action, as returned by one of the *AuditTarget functions.
+	Target string `dynamodbav:"target" json:"target"`
+
+	// The sort key of the entry, in the form createdAt_uuid, so that entries are ordered
+	// by time within a target and actor.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The username of the admin or moderator who performed the action.
+	Actor string `dynamodbav:"actor" json:"actor"`
+
+	// The type of the action.
+	Action AuditAction `dynamodbav:"action" json:"action"`
+
+	// The attributes of the target changed by the action, mapped by their path. Paths use dots
+	// to separate nested attributes and list indices, for example `rounds.0.pairings.3.result`.
+	// If the target as a whole was created or removed, the path is `$`.
+	Diff map[string]AuditChange `dynamodbav:"diff,omitempty" json:"diff,omitempty"`
+
+	// The time the action was performed, in RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The API Gateway request id of the action, which can be used to find its logs.
+	RequestId string `dynamodbav:"requestId" json:"requestId"`
+}
+
+// NewAuditEntry returns an AuditEntry for the provided action. before and after are the state
+// of the target before and after the action and may be nil. They are converted to JSON, and only
+// the attributes which differ are saved in the entry's Diff.
+func NewAuditEntry(actor string, action AuditAction, target, requestId string, before, after any) (*AuditEntry, error) {
+	beforeValue, err := toAuditValue(before)
+	if err != nil {
+		return nil, err
+	}
+	afterValue, err := toAuditValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]AuditChange)
+	diffAuditValues("", beforeValue, afterValue, diff)
+
+	createdAt := time.Now().Format(time.RFC3339)
+	return &AuditEntry{
+		Target:    target,
+		Id:        createdAt + "_" + uuid.NewString(),
+		Actor:     actor,
+		Action:    action,
+		Diff:      diff,
+		CreatedAt: createdAt,
+		RequestId: requestId,
+	}, nil
+}
+
+// toAuditValue converts v to its generic JSON form.
+func toAuditValue(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal audit value", err)
+	}
+	var result any
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to unmarshal audit value", err)
+	}
+	return result, nil
+}
+
+// diffAuditValues adds the differences between before and after to diff. Objects and
+// lists of equal length are compared element by element. Other values are compared as a whole.
+func diffAuditValues(path string, before, after any, diff map[string]AuditChange) {
+	switch b := before.(type) {
+	case map[string]any:
+		if a, ok := after.(map[string]any); ok {
+			for k, bv := range b {
+				diffAuditValues(auditPath(path, k), bv, a[k], diff)
+			}
+			for k, av := range a {
+				if _, ok := b[k]; !ok {
+					diffAuditValues(auditPath(path, k), nil, av, diff)
+				}
+			}
+			return
+		}
+	case []any:
+		if a, ok := after.([]any); ok && len(a) == len(b) {
+			for i := range b {
+				diffAuditValues(auditPath(path, strconv.Itoa(i)), b[i], a[i], diff)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		if path == "" {
+			path = "$"
+		}
+		diff[path] = AuditChange{Before: before, After: after}
+	}
+}
+
+func auditPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// AuditEntryPutter provides an interface for writing to the audit log.
+type AuditEntryPutter interface {
+	// PutAuditEntry saves the provided entry in the audit log. Existing entries are never
+	// overwritten.
+	PutAuditEntry(entry *AuditEntry) error
+}
+
+// AuditEntryLister provides an interface for reading the audit log.
+type AuditEntryLister interface {
+	UserGetter
+
+	// ListAuditEntriesByTarget returns the audit log entries for the provided target, newest
+	// first. The next start key is also returned.
+	ListAuditEntriesByTarget(target, startKey string) ([]AuditEntry, string, error)
+
+	// ListAuditEntriesByActor returns the audit log entries performed by the provided actor,
+	// newest first. The next start key is also returned.
+	ListAuditEntriesByActor(actor, startKey string) ([]AuditEntry, string, error)
+}
+
+// PutAuditEntry saves the provided entry in the audit log. Existing entries are never
+// overwritten.
+func (repo *dynamoRepository) PutAuditEntry(entry *AuditEntry) error {
+	item, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal audit entry", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+		TableName:           aws.String(auditLogTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+}
+
+// ListAuditEntriesByTarget returns the audit log entries for the provided target, newest
+// first. The next start key is also returned.
+func (repo *dynamoRepository) ListAuditEntriesByTarget(target, startKey string) ([]AuditEntry, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#target = :target"),
+		ExpressionAttributeNames: map[string]*string{
+			"#target": aws.String("target"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":target": {S: aws.String(target)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		TableName:        aws.String(auditLogTable),
+	}
+
+	var entries []AuditEntry
+	lastKey, err := repo.query(input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// ListAuditEntriesByActor returns the audit log entries performed by the provided actor,
+// newest first. The next start key is also returned.
+func (repo *dynamoRepository) ListAuditEntriesByActor(actor, startKey string) ([]AuditEntry, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#actor = :actor"),
+		ExpressionAttributeNames: map[string]*string{
+			"#actor": aws.String("actor"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":actor": {S: aws.String(actor)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		IndexName:        aws.String(auditLogTableActorIndex),
+		TableName:        aws.String(auditLogTable),
+	}
+
+	var entries []AuditEntry
+	lastKey, err := repo.query(input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewAuditEntryDiff(t *testing.T) {
+	table := []struct {
+		name   string
+		before any
+		after  any
+		want   map[string]AuditChange
+	}{
+		{
+			name:   "Unchanged",
+			before: OpenClassicalPlayer{Status: OpenClassicalPlayerStatus_Banned},
+			after:  OpenClassicalPlayer{Status: OpenClassicalPlayerStatus_Banned},
+			want:   map[string]AuditChange{},
+		},
+		{
+			name:   "NestedField",
+			before: OpenClassicalPairing{White: OpenClassicalPlayerSummary{Username: "alice"}, Result: "1-0"},
+			after:  OpenClassicalPairing{White: OpenClassicalPlayerSummary{Username: "alice"}, Result: "0-1", Verified: true},
+			want: map[string]AuditChange{
+				"result":   {Before: "1-0", After: "0-1"},
+				"verified": {Before: false, After: true},
+			},
+		},
+		{
+			name:   "ListElement",
+			before: map[string]any{"round1": []OpenClassicalPairing{{Result: "1-0"}, {Result: ""}}},
+			after:  map[string]any{"round1": []OpenClassicalPairing{{Result: "1-0"}, {Result: "1/2-1/2"}}},
+			want: map[string]AuditChange{
+				"round1.1.result": {Before: "", After: "1/2-1/2"},
+			},
+		},
+		{
+			name:   "ListLengthChanged",
+			before: []Role{Role_Support},
+			after:  []Role{Role_Support, Role_GameReviewer},
+			want: map[string]AuditChange{
+				"$": {Before: []any{"SUPPORT"}, After: []any{"SUPPORT", "GAME_REVIEWER"}},
+			},
+		},
+		{
+			name:   "Removed",
+			before: map[string]string{"title": "Event"},
+			after:  nil,
+			want: map[string]AuditChange{
+				"$": {Before: map[string]any{"title": "Event"}, After: nil},
+			},
+		},
+		{
+			name:   "TypedNil",
+			before: (*OpenClassicalPlayer)(nil),
+			after:  nil,
+			want:   map[string]AuditChange{},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := NewAuditEntry("admin", AuditAction_VerifyResult, UserAuditTarget("alice"), "request", tc.before, tc.after)
+			if err != nil {
+				t.Fatalf("NewAuditEntry: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, entry.Diff); diff != "" {
+				t.Errorf("NewAuditEntry diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemoryAuditEntries(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	entries := []*AuditEntry{
+		{Target: UserAuditTarget("alice"), Id: "2024-01-01T00:00:00Z_a", Actor: "admin", Action: AuditAction_BanPlayer},
+		{Target: UserAuditTarget("alice"), Id: "2024-01-02T00:00:00Z_b", Actor: "director", Action: AuditAction_UnbanPlayer},
+		{Target: UserAuditTarget("bob"), Id: "2024-01-03T00:00:00Z_c", Actor: "admin", Action: AuditAction_WithdrawPlayer},
+	}
+	for _, e := range entries {
+		if err := repo.PutAuditEntry(e); err != nil {
+			t.Fatalf("PutAuditEntry: %v", err)
+		}
+	}
+	if err := repo.PutAuditEntry(entries[0]); errorCode(err) != 500 {
+		t.Errorf("PutAuditEntry(duplicate) err = %v; want 500", err)
+	}
+
+	byTarget, _, err := repo.ListAuditEntriesByTarget(UserAuditTarget("alice"), "")
+	if err != nil {
+		t.Fatalf("ListAuditEntriesByTarget: %v", err)
+	}
+	if got := auditEntryIds(byTarget); !cmp.Equal(got, []string{"2024-01-02T00:00:00Z_b", "2024-01-01T00:00:00Z_a"}) {
+		t.Errorf("ListAuditEntriesByTarget ids = %v; want newest first for alice", got)
+	}
+
+	byActor, _, err := repo.ListAuditEntriesByActor("admin", "")
+	if err != nil {
+		t.Fatalf("ListAuditEntriesByActor: %v", err)
+	}
+	if got := auditEntryIds(byActor); !cmp.Equal(got, []string{"2024-01-03T00:00:00Z_c", "2024-01-01T00:00:00Z_a"}) {
+		t.Errorf("ListAuditEntriesByActor ids = %v; want newest first for admin", got)
+	}
+}
+
+func auditEntryIds(entries []AuditEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
@@ -140,6 +140,63 @@ type ClubUpdate struct {
 	LogoData *string `dynamodbav:"-" json:"logoData,omitempty"`
 }
 
+type ClubGetter interface {
+	// GetClub returns the club with the given id.
+	GetClub(id string) (*Club, error)
+}
+
+type ClubLister interface {
+	// ListClubs returns a list of clubs, excluding the promo code, members and join requests.
+	// The next start key is also returned.
+	ListClubs(startKey string) ([]Club, string, error)
+
+	// BatchGetClubs returns a list of clubs with the provided ids. Up to 100 ids can be
+	// specified at a time.
+	BatchGetClubs(ids []string) ([]Club, error)
+}
+
+type ClubEditor interface {
+	UserGetter
+	AuditEntryPutter
+	ClubGetter
+
+	// CreateClub creates the given club in the database. The club id must not already exist.
+	CreateClub(club *Club) error
+
+	// UpdateClub applies the given update to the given club. The club after the update is returned.
+	UpdateClub(id string, caller string, update *ClubUpdate) (*Club, error)
+}
+
+type ClubMemberEditor interface {
+	UserGetter
+	ClubGetter
+
+	// Allows fetching the scoreboard summaries of the club's members.
+	ScoreboardSummaryLister
+
+	// JoinClub adds the given username as a member of the given club. The club must have
+	// ApprovalRequired set to false. The club after updating is returned. Also adds the club id
+	// to the given user's clubs attributes.
+	JoinClub(id string, username string, isFreeTier bool) (*Club, error)
+
+	// RequestToJoinClub adds the given join request to the given club. The outbox entries are
+	// saved in the same transaction. The club after updating is returned.
+	RequestToJoinClub(id string, request *ClubJoinRequest, isFreeTier bool, outbox ...*OutboxEntry) (*Club, error)
+
+	// ApproveClubJoinRequest converts a join request with the given username into a member for
+	// the given club. The outbox entries are saved in the same transaction. The club after
+	// updating is returned.
+	ApproveClubJoinRequest(id, username, caller string, outbox ...*OutboxEntry) (*Club, error)
+
+	// RejectClubJoinRequest marks the join request with the given club id and username as
+	// rejected. The club after updating is returned. The caller must be the owner of the club.
+	RejectClubJoinRequest(id, username, caller string) (*Club, error)
+
+	// RemoveClubMember removes the given username as a member from the given club. The user
+	// cannot be the owner of the club.
+	RemoveClubMember(id string, username string) (*Club, error)
+}
+
 // Creates the given club in the database. The club id must not already exist.
 func (repo *dynamoRepository) CreateClub(club *Club) error {
 	item, err := dynamodbattribute.MarshalMap(club)
@@ -262,17 +319,13 @@ func (repo *dynamoRepository) BatchGetClubs(ids []string) ([]Club, error) {
 		return nil, errors.New(500, "Temporary server error", "More than 100 usernames passed to BatchGetClubs")
 	}
 
-	input := &dynamodb.BatchGetItemInput{
-		RequestItems: map[string]*dynamodb.KeysAndAttributes{
-			clubTable: {
-				Keys:                 []map[string]*dynamodb.AttributeValue{},
-				ProjectionExpression: aws.String("id,#name,shortDescription,description,#owner,promoCode,externalUrl,#location,memberCount,unlisted,approvalRequired,createdAt,updatedAt"),
-				ExpressionAttributeNames: map[string]*string{
-					"#name":     aws.String("name"),
-					"#owner":    aws.String("owner"),
-					"#location": aws.String("location"),
-				},
-			},
+	request := &dynamodb.KeysAndAttributes{
+		Keys:                 []map[string]*dynamodb.AttributeValue{},
+		ProjectionExpression: aws.String("id,#name,shortDescription,description,#owner,promoCode,externalUrl,#location,memberCount,unlisted,approvalRequired,createdAt,updatedAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#name":     aws.String("name"),
+			"#owner":    aws.String("owner"),
+			"#location": aws.String("location"),
 		},
 	}
 
@@ -280,18 +333,12 @@ func (repo *dynamoRepository) BatchGetClubs(ids []string) ([]Club, error) {
 		key := map[string]*dynamodb.AttributeValue{
 			"id": {S: aws.String(id)},
 		}
-		input.RequestItems[clubTable].Keys = append(input.RequestItems[clubTable].Keys, key)
+		request.Keys = append(request.Keys, key)
 	}
 
-	result, err := repo.svc.BatchGetItem(input)
-	if err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed call to BatchGetItem", err)
-	}
-	list := result.Responses[clubTable]
-
 	var clubs []Club
-	if err := dynamodbattribute.UnmarshalListOfMaps(list, &clubs); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal BatchGetItem result", err)
+	if err := repo.batchGet(clubTable, request, &clubs); err != nil {
+		return nil, err
 	}
 
 	return clubs, nil
@@ -346,8 +393,9 @@ func (repo *dynamoRepository) JoinClub(id string, username string, isFreeTier bo
 	return club, nil
 }
 
-// Adds the given join request to the given club. The club after updating is returned.
-func (repo *dynamoRepository) RequestToJoinClub(id string, request *ClubJoinRequest, isFreeTier bool) (*Club, error) {
+// Adds the given join request to the given club. The outbox entries are saved in the same
+// transaction. The club after updating is returned.
+func (repo *dynamoRepository) RequestToJoinClub(id string, request *ClubJoinRequest, isFreeTier bool, outbox ...*OutboxEntry) (*Club, error) {
 	request.Status = ClubJoinRequestStatus_Pending
 	request.CreatedAt = time.Now().Format(time.RFC3339)
 
@@ -383,7 +431,7 @@ func (repo *dynamoRepository) RequestToJoinClub(id string, request *ClubJoinRequ
 	}
 
 	club := &Club{}
-	if err := repo.updateItem(input, club); err != nil {
+	if err := repo.updateItemWithOutbox(input, outbox, club); err != nil {
 		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
 			return nil, errors.Wrap(404, "Invalid request: club does not exist, you have already requested to join or you do not have permission to request to join", "DynamoDB conditional check failed", err)
 		}
@@ -392,9 +440,9 @@ func (repo *dynamoRepository) RequestToJoinClub(id string, request *ClubJoinRequ
 	return club, nil
 }
 
-// Converts a join request with the given username into a member for the given club. The club
-// after updating is returned.
-func (repo *dynamoRepository) ApproveClubJoinRequest(id, username, caller string) (*Club, error) {
+// Converts a join request with the given username into a member for the given club. The outbox
+// entries are saved in the same transaction. The club after updating is returned.
+func (repo *dynamoRepository) ApproveClubJoinRequest(id, username, caller string, outbox ...*OutboxEntry) (*Club, error) {
 	input := &dynamodb.UpdateItemInput{
 		Key: map[string]*dynamodb.AttributeValue{
 			"id": {S: aws.String(id)},
@@ -421,7 +469,7 @@ func (repo *dynamoRepository) ApproveClubJoinRequest(id, username, caller string
 	}
 
 	club := &Club{}
-	if err := repo.updateItem(input, club); err != nil {
+	if err := repo.updateItemWithOutbox(input, outbox, club); err != nil {
 		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
 			return nil, errors.Wrap(404, "Invalid request: club not found", "DynamoDB conditional check failed", err)
 		}
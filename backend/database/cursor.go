@@ -0,0 +1,110 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+)
+
+// cursorVersion is the version prefix of the pagination cursors returned by query and scan.
+// It must be changed if the format of the cursor or its signature changes, so that old
+// cursors are rejected rather than misread.
+const cursorVersion = "v1"
+
+// cursorSecret returns the key used to sign pagination cursors. It is read from the cursorSecret
+// environment variable. If that is not set, as when running locally, a random key is generated,
+// and cursors are only valid within the current process.
+var cursorSecret = sync.OnceValue(func() []byte {
+	if secret := os.Getenv("cursorSecret"); secret != "" {
+		return []byte(secret)
+	}
+	log.Warn("cursorSecret is not set; pagination cursors will only be valid within this process")
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+})
+
+// encodeCursor returns the pagination cursor for the provided LastEvaluatedKey. scope identifies
+// the query which returned the key, and the cursor is only accepted by decodeCursor with the same
+// scope. The cursor has the form version.payload.signature, where payload is the base64-encoded key
+// and signature is an HMAC of the version, scope and payload.
+func encodeCursor(scope string, key map[string]*dynamodb.AttributeValue) (string, error) {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to marshal LastEvaluatedKey", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return cursorVersion + "." + payload + "." + signCursor(scope, payload), nil
+}
+
+// decodeCursor returns the LastEvaluatedKey of the provided cursor. A 400 error is returned if the
+// cursor is malformed, has an unsupported version or was not returned by a query with the same scope.
+func decodeCursor(scope, cursor string) (map[string]*dynamodb.AttributeValue, error) {
+	version, rest, _ := strings.Cut(cursor, ".")
+	if version != cursorVersion {
+		return nil, errors.New(400, "Invalid request: startKey is not valid", "startKey has unsupported version")
+	}
+
+	payload, signature, ok := strings.Cut(rest, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCursor(scope, payload))) {
+		return nil, errors.New(400, "Invalid request: startKey is not valid", "startKey signature does not match the query")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.Wrap(400, "Invalid request: startKey is not valid", "startKey payload could not be decoded", err)
+	}
+	var key map[string]*dynamodb.AttributeValue
+	if err := json.Unmarshal(b, &key); err != nil {
+		return nil, errors.Wrap(400, "Invalid request: startKey is not valid", "startKey could not be unmarshaled from json", err)
+	}
+	return key, nil
+}
+
+func signCursor(scope, payload string) string {
+	mac := hmac.New(sha256.New, cursorSecret())
+	mac.Write([]byte(cursorVersion + "\x00" + scope + "\x00" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+var expressionPlaceholderPattern = regexp.MustCompile(`[:#][A-Za-z0-9_]+`)
+
+// queryCursorScope returns the scope of the cursors returned by the provided query. The scope
+// includes the table, the index and the key condition along with the values it references, so a
+// cursor cannot be used to page through a different index or partition (for example, another
+// user's timeline). Filters are not included, as they do not change which items can be read.
+func queryCursorScope(input *dynamodb.QueryInput) string {
+	keyCondition := aws.StringValue(input.KeyConditionExpression)
+	parts := []string{
+		"query",
+		aws.StringValue(input.TableName),
+		aws.StringValue(input.IndexName),
+		keyCondition,
+	}
+
+	for _, placeholder := range expressionPlaceholderPattern.FindAllString(keyCondition, -1) {
+		if strings.HasPrefix(placeholder, "#") {
+			parts = append(parts, placeholder+"="+aws.StringValue(input.ExpressionAttributeNames[placeholder]))
+		} else {
+			b, _ := json.Marshal(input.ExpressionAttributeValues[placeholder])
+			parts = append(parts, placeholder+"="+string(b))
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// scanCursorScope returns the scope of the cursors returned by the provided scan.
+func scanCursorScope(input *dynamodb.ScanInput) string {
+	return strings.Join([]string{"scan", aws.StringValue(input.TableName), aws.StringValue(input.IndexName)}, "\x00")
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+func timelineQuery(owner string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(owner)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		TableName:        aws.String(timelineTable),
+	}
+}
+
+func TestCursor(t *testing.T) {
+	key := map[string]*dynamodb.AttributeValue{
+		"owner": {S: aws.String("alice")},
+		"id":    {S: aws.String("2024-01-01_abc")},
+	}
+	scope := queryCursorScope(timelineQuery("alice"))
+
+	cursor, err := encodeCursor(scope, key)
+	if err != nil {
+		t.Fatalf("encodeCursor got err %v", err)
+	}
+	if strings.Contains(cursor, "alice") {
+		t.Errorf("encodeCursor = %q, want opaque cursor", cursor)
+	}
+
+	otherIndex := timelineQuery("alice")
+	otherIndex.IndexName = aws.String("OtherIndex")
+
+	// Modifies the payload of the cursor while keeping its signature.
+	tamper := func(cursor string) string {
+		parts := strings.Split(cursor, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"owner":{"S":"bob"},"id":{"S":"x"}}`))
+		return strings.Join(parts, ".")
+	}
+
+	table := []struct {
+		name    string
+		scope   string
+		cursor  string
+		wantErr bool
+	}{
+		{
+			name:   "SameQuery",
+			scope:  queryCursorScope(timelineQuery("alice")),
+			cursor: cursor,
+		},
+		{
+			name:    "DifferentOwner",
+			scope:   queryCursorScope(timelineQuery("bob")),
+			cursor:  cursor,
+			wantErr: true,
+		},
+		{
+			name:    "DifferentIndex",
+			scope:   queryCursorScope(otherIndex),
+			cursor:  cursor,
+			wantErr: true,
+		},
+		{
+			name:    "Scan",
+			scope:   scanCursorScope(&dynamodb.ScanInput{TableName: aws.String(timelineTable)}),
+			cursor:  cursor,
+			wantErr: true,
+		},
+		{
+			name:    "TamperedPayload",
+			scope:   scope,
+			cursor:  tamper(cursor),
+			wantErr: true,
+		},
+		{
+			name:    "UnsupportedVersion",
+			scope:   scope,
+			cursor:  "v0" + strings.TrimPrefix(cursor, cursorVersion),
+			wantErr: true,
+		},
+		{
+			name:    "RawKey",
+			scope:   scope,
+			cursor:  `{"owner":{"S":"alice"},"id":{"S":"2024-01-01_abc"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeCursor(tc.scope, tc.cursor)
+			if tc.wantErr {
+				var aerr *errors.Error
+				if !errors.As(err, &aerr) || aerr.Code != 400 {
+					t.Errorf("decodeCursor got err %v, want 400", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decodeCursor got err %v, want nil", err)
+			}
+			if diff := cmp.Diff(key, got); diff != "" {
+				t.Errorf("decodeCursor mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemoryQueryCursor(t *testing.T) {
+	repo := NewMemoryRepository()
+	for i := range memoryPageSize + 1 {
+		entry := TimelineEntry{TimelineEntryKey: TimelineEntryKey{Owner: "alice", Id: fmt.Sprintf("%03d", i)}}
+		if err := repo.PutTimelineEntry(&entry); err != nil {
+			t.Fatalf("PutTimelineEntry got err %v", err)
+		}
+	}
+
+	_, aliceKey, err := repo.ListTimelineEntries("alice", "")
+	if err != nil || aliceKey == "" {
+		t.Fatalf("ListTimelineEntries got key %q, err %v, want next page", aliceKey, err)
+	}
+
+	if _, _, err := repo.ListTimelineEntries("alice", aliceKey); err != nil {
+		t.Errorf("ListTimelineEntries with own cursor got err %v, want nil", err)
+	}
+
+	if _, _, err := repo.ListNotifications("alice", aliceKey); err == nil {
+		t.Errorf("ListNotifications with timeline cursor got nil err, want 400")
+	}
+}
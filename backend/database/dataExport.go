@@ -0,0 +1,260 @@
+package database
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// DataExportStatus is the state of a DataExport.
+type DataExportStatus string
+
+const (
+	DataExportStatus_InProgress DataExportStatus = "IN_PROGRESS"
+	DataExportStatus_Complete   DataExportStatus = "COMPLETE"
+	DataExportStatus_Failed     DataExportStatus = "FAILED"
+)
+
+const (
+	// DataExportTTL is how long a completed export can be downloaded. The export bucket
+	// deletes the files after the same time.
+	DataExportTTL = 7 * 24 * time.Hour
+
+	// dataExportStaleTime is the time after which an in-progress export is assumed to have
+	// crashed, so that it can be requested again. It is longer than the Lambda timeout.
+	dataExportStaleTime = 20 * time.Minute
+)
+
+// DataExport tracks a request to export all of the data stored about a user.
+type DataExport struct {
+	// The username of the user being exported. The hash key of the table.
+	Username string `dynamodbav:"username" json:"username"`
+
+	// The state of the export.
+	Status DataExportStatus `dynamodbav:"status" json:"status"`
+
+	// The key of the zip file in the export bucket. Set once the export is complete.
+	Key string `dynamodbav:"key,omitempty" json:"-"`
+
+	// The public error message, if the export failed.
+	Error string `dynamodbav:"error,omitempty" json:"error,omitempty"`
+
+	// The time the export was requested, in time.RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The time the export was last saved, in time.RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+
+	// The time the export finished, in time.RFC3339 format.
+	CompletedAt string `dynamodbav:"completedAt,omitempty" json:"completedAt,omitempty"`
+
+	// The time the export can no longer be downloaded, in time.RFC3339 format.
+	ExpiresAt string `dynamodbav:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+
+	// The time the export record expires, in Unix seconds.
+	ExpirationTime int64 `dynamodbav:"expirationTime,omitempty" json:"-"`
+}
+
+// NewDataExport returns an in-progress DataExport for the provided username.
+func NewDataExport(username string) *DataExport {
+	now := time.Now().Format(time.RFC3339)
+	return &DataExport{
+		Username:  username,
+		Status:    DataExportStatus_InProgress,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Complete marks the export as complete with the zip file at the provided key.
+func (e *DataExport) Complete(key string) {
+	now := time.Now()
+	e.Status = DataExportStatus_Complete
+	e.Key = key
+	e.Error = ""
+	e.UpdatedAt = now.Format(time.RFC3339)
+	e.CompletedAt = e.UpdatedAt
+	e.ExpiresAt = now.Add(DataExportTTL).Format(time.RFC3339)
+	e.ExpirationTime = now.Add(DataExportTTL).Unix()
+}
+
+// Fail marks the export as failed with the public message of the provided error.
+func (e *DataExport) Fail(err error) {
+	e.Status = DataExportStatus_Failed
+	e.Error = "Temporary server error"
+	var aerr *errors.Error
+	if errors.As(err, &aerr) {
+		e.Error = aerr.PublicMessage
+	}
+	e.UpdatedAt = time.Now().Format(time.RFC3339)
+}
+
+// IsExpired returns true if the export is complete and can no longer be downloaded.
+func (e *DataExport) IsExpired() bool {
+	if e.Status != DataExportStatus_Complete {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, e.ExpiresAt)
+	return err != nil || time.Now().After(expiresAt)
+}
+
+// DataExportMetadata is the metadata of a DATA_EXPORT_READY notification.
+type DataExportMetadata struct {
+	// The time the export can no longer be downloaded, in time.RFC3339 format.
+	ExpiresAt string `dynamodbav:"expiresAt" json:"expiresAt"`
+}
+
+// DataExportRequester provides an interface for requesting and checking on data exports.
+type DataExportRequester interface {
+	UserGetter
+
+	// CreateDataExport starts exporting the data of the provided username. If an export is
+	// already in progress, the existing DataExport is returned instead.
+	CreateDataExport(username string) (*DataExport, error)
+
+	// GetDataExport returns the DataExport for the provided username.
+	GetDataExport(username string) (*DataExport, error)
+}
+
+// DataExporter provides an interface for gathering the data stored about a user.
+type DataExporter interface {
+	UserGetter
+
+	// PutDataExport saves the provided export.
+	PutDataExport(export *DataExport) error
+
+	// ListTimelineEntries returns the timeline entries owned by the provided username.
+	ListTimelineEntries(owner, startKey string) ([]*TimelineEntry, string, error)
+
+	// ListGamesByOwner returns the games owned by the provided username, without their PGNs.
+	ListGamesByOwner(isOwner bool, owner, startDate, endDate, startKey string) ([]*Game, string, error)
+
+	// GetGame returns the game with the provided cohort and id, including its PGN.
+	GetGame(cohort, id string) (*Game, error)
+
+	// ScanGameComments returns the cohort, id and comments of the games which have comments.
+	ScanGameComments(startKey string) ([]Game, string, error)
+
+	// ListFollowers returns a list of FollowerEntries where the provided username is the poster.
+	ListFollowers(username, startKey string) ([]FollowerEntry, string, error)
+
+	// ListFollowing returns a list of FollowerEntries where the provided username is the follower.
+	ListFollowing(username, startKey string) ([]FollowerEntry, string, error)
+
+	// ListNotifications returns the notifications of the provided username.
+	ListNotifications(username, startKey string) ([]Notification, string, error)
+
+	// ListExams lists the exam answers when examType is a username.
+	ListExams(examType ExamType, startKey string, out interface{}) (string, error)
+
+	// ListGraduationsByOwner returns the graduations of the provided username.
+	ListGraduationsByOwner(username, startKey string) ([]Graduation, string, error)
+
+	// ListYearReviews returns the year reviews of the provided username.
+	ListYearReviews(username, startKey string) ([]YearReview, string, error)
+
+	// ScanEventsByUser returns the events the provided username owns or participates in.
+	ScanEventsByUser(username, startKey string) ([]Event, string, error)
+
+	// PutNotification saves the provided notification.
+	PutNotification(notification *Notification) error
+}
+
+// CreateDataExport starts exporting the data of the provided username. If an export is
+// already in progress, the existing DataExport is returned instead.
+func (repo *dynamoRepository) CreateDataExport(username string) (*DataExport, error) {
+	export := NewDataExport(username)
+	item, err := dynamodbattribute.MarshalMap(export)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal data export", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(username) OR #status <> :inProgress OR #updatedAt < :stale"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status":    aws.String("status"),
+			"#updatedAt": aws.String("updatedAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":inProgress": {S: aws.String(string(DataExportStatus_InProgress))},
+			":stale":      {S: aws.String(time.Now().Add(-dataExportStaleTime).Format(time.RFC3339))},
+		},
+		TableName: aws.String(dataExportTable),
+	}
+	if _, err := repo.svc.PutItem(input); err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return repo.GetDataExport(username)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+	}
+	return export, nil
+}
+
+// GetDataExport returns the DataExport for the provided username.
+func (repo *dynamoRepository) GetDataExport(username string) (*DataExport, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		TableName: aws.String(dataExportTable),
+	}
+	export := DataExport{}
+	if err := repo.getItem(input, &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// PutDataExport saves the provided export.
+func (repo *dynamoRepository) PutDataExport(export *DataExport) error {
+	item, err := dynamodbattribute.MarshalMap(export)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal data export", err)
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(dataExportTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+}
+
+// ListYearReviews returns the year reviews of the provided username. The next start key
+// is also returned.
+func (repo *dynamoRepository) ListYearReviews(username, startKey string) ([]YearReview, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#username = :username"),
+		ExpressionAttributeNames: map[string]*string{
+			"#username": aws.String("username"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+		},
+		TableName: aws.String(yearReviewTable),
+	}
+
+	var reviews []YearReview
+	lastKey, err := repo.query(input, startKey, &reviews)
+	if err != nil {
+		return nil, "", err
+	}
+	return reviews, lastKey, nil
+}
+
+// PutNotification saves the provided notification.
+func (repo *dynamoRepository) PutNotification(notification *Notification) error {
+	item, err := dynamodbattribute.MarshalMap(notification)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal notification", err)
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(notificationTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+}
@@ -284,8 +284,9 @@ type Coaching struct {
 type EventSetter interface {
 	UserGetter
 
-	// SetEvent inserts the provided Event into the database.
-	SetEvent(event *Event) error
+	// SetEvent inserts the provided Event into the database. The outbox entries are saved
+	// in the same transaction.
+	SetEvent(event *Event, outbox ...*OutboxEntry) error
 
 	// RecordEventCreation saves statistics on the created event.
 	RecordEventCreation(event *Event) error
@@ -299,8 +300,9 @@ type EventGetter interface {
 type EventLeaver interface {
 	EventGetter
 
-	// SetEvent inserts the provided Event into the database.
-	SetEvent(event *Event) error
+	// SetEvent inserts the provided Event into the database. The outbox entries are saved
+	// in the same transaction.
+	SetEvent(event *Event, outbox ...*OutboxEntry) error
 
 	// CancelEvent marks the provided Event as canceled.
 	CancelEvent(event *Event) (*Event, error)
@@ -315,9 +317,16 @@ type EventLeaver interface {
 	RecordEventCancelation(event *Event) error
 }
 
+type EventPaymentMarker interface {
+	// MarkParticipantPaid updates the given event so that the participant with the given username is
+	// marked as paid. The participant's checkoutSession is set to the provided checkoutSession.
+	MarkParticipantPaid(eventId, participant string, checkoutSession *stripe.CheckoutSession) (*Event, error)
+}
+
 type EventDeleter interface {
 	UserGetter
 	EventGetter
+	AuditEntryPutter
 
 	// DeleteEvent deletes the event with the given id. The deleted
 	// event is returned. An error is returned if it does not exist or is booked.
@@ -334,8 +343,8 @@ type EventBooker interface {
 	// BookEvent adds the given user as a participant to the given event.
 	// The request only succeeds if the Event is not already fully booked.
 	// startTime and aType are only used if the Event is of type EventTypeAvailability
-	// and has MaxParticipants set to 1.
-	BookEvent(event *Event, user *User, startTime string, aType AvailabilityType, checkoutSession *stripe.CheckoutSession) (*Event, error)
+	// and has MaxParticipants set to 1. The outbox entries are saved in the same transaction.
+	BookEvent(event *Event, user *User, startTime string, aType AvailabilityType, checkoutSession *stripe.CheckoutSession, outbox ...*OutboxEntry) (*Event, error)
 
 	// RecordEventBooking saves statistics on an event booking.
 	RecordEventBooking(event *Event) error
@@ -356,8 +365,9 @@ type EventMessager interface {
 	CreateEventMessage(id string, message *Comment) (*Event, error)
 }
 
-// SetEvent inserts the provided Event into the database.
-func (repo *dynamoRepository) SetEvent(event *Event) error {
+// SetEvent inserts the provided Event into the database. The outbox entries are saved
+// in the same transaction.
+func (repo *dynamoRepository) SetEvent(event *Event, outbox ...*OutboxEntry) error {
 	if event.Id == "STATISTICS" {
 		return errors.New(403, "Invalid request: user does not have permission to set event statistics", "")
 	}
@@ -378,7 +388,7 @@ func (repo *dynamoRepository) SetEvent(event *Event) error {
 		TableName: aws.String(eventTable),
 	}
 
-	_, err = repo.svc.PutItem(input)
+	err = repo.putItemWithOutbox(input, outbox)
 	return errors.Wrap(500, "Temporary server error", "Failed Dynamo PutItem request", err)
 }
 
@@ -441,8 +451,8 @@ func (repo *dynamoRepository) DeleteEvent(id string) (*Event, error) {
 // BookEvent adds the given user as a participant to the given event.
 // The request only succeeds if the Event is not already fully booked.
 // startTime and aType are only used if the Event is of type EventTypeAvailability
-// and has MaxParticipants set to 1.
-func (repo *dynamoRepository) BookEvent(event *Event, user *User, startTime string, aType AvailabilityType, checkoutSession *stripe.CheckoutSession) (*Event, error) {
+// and has MaxParticipants set to 1. The outbox entries are saved in the same transaction.
+func (repo *dynamoRepository) BookEvent(event *Event, user *User, startTime string, aType AvailabilityType, checkoutSession *stripe.CheckoutSession, outbox ...*OutboxEntry) (*Event, error) {
 	if event.Id == "STATISTICS" {
 		return nil, errors.New(403, "Invalid request: event statistics cannot be booked", "")
 	}
@@ -511,18 +521,13 @@ func (repo *dynamoRepository) BookEvent(event *Event, user *User, startTime stri
 		TableName:        aws.String(eventTable),
 	}
 
-	result, err := repo.svc.UpdateItem(input)
-	if err != nil {
+	e := Event{}
+	if err := repo.updateItemWithOutbox(input, outbox, &e); err != nil {
 		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
 			return nil, errors.Wrap(400, "Invalid request: event no longer exists or is already fully booked", "DynamoDB conditional check failed", aerr)
 		}
 		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem call", err)
 	}
-
-	e := Event{}
-	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &e); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal UpdateItem result", err)
-	}
 	return &e, nil
 }
 
@@ -7,7 +7,6 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 )
 
 // The key of an item in the exams table.
@@ -122,6 +121,36 @@ type ExamAnswer struct {
 	Attempts []ExamAttempt `dynamodbav:"attempts" json:"attempts"`
 }
 
+type ExamGetter interface {
+	// GetExam returns the requested exam.
+	GetExam(examType string, id string) (*Exam, error)
+
+	// GetExamAnswer fetches the provided exam answer from the database.
+	GetExamAnswer(username, id string) (*ExamAnswer, error)
+}
+
+type ExamLister interface {
+	// ListExams returns a paginated list of exams with the provided type.
+	ListExams(examType ExamType, startKey string, out interface{}) (string, error)
+}
+
+type ExamAnswerPutter interface {
+	// PutExamAnswerSummary creates and saves an ExamAnswerSummary using the provided ExamAnswer.
+	// The updated Exam is returned. If the ExamAnswer does not require updating the Exam, then nil is returned.
+	PutExamAnswerSummary(answer *ExamAnswer, score int) (*Exam, error)
+
+	// PutExamAttempt inserts the provided exam attempt into the database. If index is non-nil,
+	// the exam attempt is assumed to already exist and overwrites the current item at the index
+	// in the attempts list. If index is nil, the attempt is appended to the existing list of attempts.
+	// The updated ExamAnswer is returned.
+	PutExamAttempt(username string, examId string, examType ExamType, attempt *ExamAttempt, index *int) (*ExamAnswer, error)
+}
+
+type ExamRatingUpdater interface {
+	// UpdateUserExamRatings batch updates the given users' exam summaries for the given exam.
+	UpdateUserExamRatings(examId string, updates []UserExamSummaryUpdate) error
+}
+
 // GetExam returns the requested exam.
 func (repo *dynamoRepository) GetExam(examType string, id string) (*Exam, error) {
 	input := &dynamodb.GetItemInput{
@@ -295,12 +324,8 @@ func (repo *dynamoRepository) UpdateUserExamRatings(examId string, updates []Use
 			})
 		}
 
-		input := &dynamodb.BatchExecuteStatementInput{Statements: statements}
-		log.Debugf("Batch execute statement input: %v", input)
-		output, err := repo.svc.BatchExecuteStatement(input)
-		log.Debugf("Batch execute statement output: %v", output)
-		if err != nil {
-			return errors.Wrap(500, "Temporary server error", "Failed BatchExecuteStatement", err)
+		if err := repo.batchExecute(statements); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 )
@@ -34,8 +35,8 @@ type FollowerEditor interface {
 	UserGetter
 
 	// CreateFollower adds a FollowerEntry for the given poster and follower. The poster's and follower's
-	// followerCount/followingCount fields are also updated.
-	CreateFollower(poster, follower *User) (*FollowerEntry, error)
+	// followerCount/followingCount fields and the outbox entries are saved in the same transaction.
+	CreateFollower(poster, follower *User, outbox ...*OutboxEntry) (*FollowerEntry, error)
 
 	// DeleteFollower removes a FollowerEntry for the given poster and follower usernames. The poster's and
 	// follower's followerCount/followingCount fields are also updated.
@@ -65,9 +66,26 @@ type FollowerLister interface {
 	ListFollowingLimit(username, startKey string, limit int) ([]FollowerEntry, string, error)
 }
 
+// FollowerReconciler provides an interface for recounting the followers of every user.
+type FollowerReconciler interface {
+	AdminUserLister
+	FollowerLister
+
+	// ScanFollowers returns a list of all FollowerEntry objects, up to 1MB of data.
+	// startKey is an optional parameter that can be used to perform pagination.
+	// The list of entries and the next start key are returned.
+	ScanFollowers(startKey string) ([]FollowerEntry, string, error)
+
+	// SetFollowCounts sets the followerCount and followingCount of the provided user, which must have
+	// been read from the database. The update fails with a 409 error if the user's counts were changed
+	// after it was read.
+	SetFollowCounts(user *User, followerCount, followingCount int) error
+}
+
 // CreateFollower adds a FollowerEntry for the given poster and follower. The poster's and follower's
-// followerCount/followingCount fields are also updated.
-func (repo *dynamoRepository) CreateFollower(poster, follower *User) (*FollowerEntry, error) {
+// followerCount/followingCount fields are updated and the outbox entries are saved in the same
+// transaction, so they are only changed if the FollowerEntry is created.
+func (repo *dynamoRepository) CreateFollower(poster, follower *User, outbox ...*OutboxEntry) (*FollowerEntry, error) {
 	followerEntry := &FollowerEntry{
 		Poster:              poster.Username,
 		PosterDisplayName:   poster.DisplayName,
@@ -81,81 +99,115 @@ func (repo *dynamoRepository) CreateFollower(poster, follower *User) (*FollowerE
 		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal user", err)
 	}
 
-	input := &dynamodb.PutItemInput{
-		Item:                item,
-		ConditionExpression: aws.String("attribute_not_exists(follower)"),
-		TableName:           aws.String(followersTable),
+	puts, err := outboxPuts(outbox)
+	if err != nil {
+		return nil, err
 	}
-	_, err = repo.svc.PutItem(input)
+
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Put: &dynamodb.Put{
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(follower)"),
+					TableName:           aws.String(followersTable),
+				},
+			},
+			{Update: followCountUpdate(poster.Username, "followerCount", 1)},
+			{Update: followCountUpdate(follower.Username, "followingCount", 1)},
+		},
+	}
+	input.TransactItems = append(input.TransactItems, puts...)
+	_, err = repo.svc.TransactWriteItems(input)
 	if err != nil {
-		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+		if transactionCancellationCode(err, 0) == conditionalCheckFailedReason {
 			// The follower relationship already exists, so we can just return like everything worked successfully
 			return followerEntry, nil
 		}
-		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
-	}
-
-	if err := repo.updateFollowCount(poster.Username, "followerCount", 1); err != nil {
-		log.Error("Failed to update followerCount: ", err)
-	}
-	if err := repo.updateFollowCount(follower.Username, "followingCount", 1); err != nil {
-		log.Error("Failed to update followingCount: ", err)
+		if followCountCancelled(err) {
+			return nil, errors.Wrap(404, "Invalid request: user does not exist", "Follow count update condition failed", err)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB TransactWriteItems call", err)
 	}
 	return followerEntry, nil
 }
 
-// updateFollowCount updates the followerCount or followingCount (as chosen by field) for the given username.
-func (repo *dynamoRepository) updateFollowCount(username, field string, incrementalCount int) error {
-	if field != "followerCount" && field != "followingCount" {
-		return errors.New(500, "Temporary server error", "field is not supported")
-	}
-
-	input := &dynamodb.UpdateItemInput{
+// followCountUpdate returns an Update which adds incrementalCount to the followerCount or
+// followingCount (as chosen by field) of the given username. The update fails if the user
+// does not exist.
+func followCountUpdate(username, field string, incrementalCount int) *dynamodb.Update {
+	return &dynamodb.Update{
 		Key: map[string]*dynamodb.AttributeValue{
 			"username": {
 				S: aws.String(username),
 			},
 		},
-		UpdateExpression: aws.String("ADD #v :v"),
+		ConditionExpression: aws.String("attribute_exists(username)"),
+		UpdateExpression:    aws.String("ADD #v :v"),
 		ExpressionAttributeNames: map[string]*string{
-			"#v": &field,
+			"#v": aws.String(field),
 		},
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
 			":v": {N: aws.String(fmt.Sprintf("%d", incrementalCount))},
 		},
-		TableName: &userTable,
+		TableName: aws.String(userTable),
 	}
-	_, err := repo.svc.UpdateItem(input)
-	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem call", err)
+}
+
+// followCountCancelled returns true if err is a canceled follower transaction where one of the
+// follow count updates failed its condition.
+func followCountCancelled(err error) bool {
+	return transactionCancellationCode(err, 1) == conditionalCheckFailedReason ||
+		transactionCancellationCode(err, 2) == conditionalCheckFailedReason
 }
 
 // DeleteFollower removes a FollowerEntry for the given poster and follower usernames. The poster's and
-// follower's followerCount/followingCount fields are also updated.
+// follower's followerCount/followingCount fields are updated in the same transaction.
 func (repo *dynamoRepository) DeleteFollower(poster, follower string) error {
-	input := &dynamodb.DeleteItemInput{
-		Key: map[string]*dynamodb.AttributeValue{
-			"poster":   {S: &poster},
-			"follower": {S: &follower},
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Delete: &dynamodb.Delete{
+					Key: map[string]*dynamodb.AttributeValue{
+						"poster":   {S: aws.String(poster)},
+						"follower": {S: aws.String(follower)},
+					},
+					ConditionExpression: aws.String("attribute_exists(follower)"),
+					TableName:           aws.String(followersTable),
+				},
+			},
+			{Update: followCountUpdate(poster, "followerCount", -1)},
+			{Update: followCountUpdate(follower, "followingCount", -1)},
 		},
-		ConditionExpression: aws.String("attribute_exists(follower)"),
-		TableName:           aws.String(followersTable),
 	}
-	_, err := repo.svc.DeleteItem(input)
+	_, err := repo.svc.TransactWriteItems(input)
 	if err != nil {
-		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+		if transactionCancellationCode(err, 0) == conditionalCheckFailedReason {
 			// The follower relationship does not exist, so we can just return like everything worked successfully
 			return nil
 		}
-		return errors.Wrap(500, "Temporary server error", "Failed DynamoDB DeleteItem call", err)
+		if followCountCancelled(err) {
+			// One of the users was deleted, so the relationship is removed without updating the counts.
+			log.Warnf("Deleting follower entry %s/%s without updating counts, as one of the users no longer exists", poster, follower)
+			return repo.deleteFollowerEntry(poster, follower)
+		}
+		return errors.Wrap(500, "Temporary server error", "Failed DynamoDB TransactWriteItems call", err)
 	}
+	return nil
+}
 
-	if err := repo.updateFollowCount(poster, "followerCount", -1); err != nil {
-		log.Error("Failed to update followerCount: ", err)
-	}
-	if err := repo.updateFollowCount(follower, "followingCount", -1); err != nil {
-		log.Error("Failed to update followingCount: ", err)
+// deleteFollowerEntry removes the FollowerEntry for the given poster and follower without updating
+// the follow counts.
+func (repo *dynamoRepository) deleteFollowerEntry(poster, follower string) error {
+	input := &dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"poster":   {S: aws.String(poster)},
+			"follower": {S: aws.String(follower)},
+		},
+		TableName: aws.String(followersTable),
 	}
-	return nil
+	_, err := repo.svc.DeleteItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB DeleteItem call", err)
 }
 
 // GetFollowerEntry returns the FollowerEntry with the provided poster and follower. If the FollowerEntry
@@ -239,3 +291,63 @@ func (repo *dynamoRepository) ListFollowingLimit(username, startKey string, limi
 	}
 	return following, lastKey, nil
 }
+
+// ScanFollowers returns a list of all FollowerEntry objects, up to 1MB of data.
+// startKey is an optional parameter that can be used to perform pagination.
+// The list of entries and the next start key are returned.
+func (repo *dynamoRepository) ScanFollowers(startKey string) ([]FollowerEntry, string, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(followersTable),
+	}
+
+	var entries []FollowerEntry
+	lastKey, err := repo.scan(input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// SetFollowCounts sets the followerCount and followingCount of the provided user, which must have
+// been read from the database. The update fails with a 409 error if the user's counts were changed
+// after it was read.
+func (repo *dynamoRepository) SetFollowCounts(user *User, followerCount, followingCount int) error {
+	condition := expression.Name("username").AttributeExists().
+		And(followCountCondition("followerCount", user.FollowerCount)).
+		And(followCountCondition("followingCount", user.FollowingCount))
+	update := expression.Set(expression.Name("followerCount"), expression.Value(followerCount)).
+		Set(expression.Name("followingCount"), expression.Value(followingCount))
+
+	expr, err := expression.NewBuilder().WithCondition(condition).WithUpdate(update).Build()
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "DynamoDB expression building error", err)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(user.Username)},
+		},
+		ConditionExpression:       expr.Condition(),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		TableName:                 aws.String(userTable),
+	}
+	if _, err := repo.svc.UpdateItem(input); err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.NewConflict("Temporary server error", "Follow counts were changed concurrently", user.Username)
+		}
+		return errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem call", err)
+	}
+	return nil
+}
+
+// followCountCondition returns a condition which checks that the provided follow count field has
+// the given value. A missing field is treated as 0.
+func followCountCondition(field string, count int) expression.ConditionBuilder {
+	condition := expression.Name(field).Equal(expression.Value(count))
+	if count == 0 {
+		condition = condition.Or(expression.AttributeNotExists(expression.Name(field)))
+	}
+	return condition
+}
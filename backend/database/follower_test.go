@@ -0,0 +1,76 @@
+package database
+
+import (
+	"testing"
+)
+
+func followCounts(t *testing.T, repo *memoryRepository, username string) [2]int {
+	t.Helper()
+	user, err := repo.GetUser(username)
+	if err != nil {
+		t.Fatalf("GetUser(%s) got err %v", username, err)
+	}
+	return [2]int{user.FollowerCount, user.FollowingCount}
+}
+
+func TestMemoryFollowerTransactions(t *testing.T) {
+	repo := NewMemoryRepository()
+	alice, _ := repo.CreateUser("alice", "alice@example.com", "Alice", nil)
+	bob, _ := repo.CreateUser("bob", "bob@example.com", "Bob", nil)
+
+	// Creating the same relationship twice only counts it once.
+	for range 2 {
+		if _, err := repo.CreateFollower(alice, bob); err != nil {
+			t.Fatalf("CreateFollower got err %v", err)
+		}
+	}
+	if got := followCounts(t, repo, "alice"); got != [2]int{1, 0} {
+		t.Errorf("alice counts after CreateFollower = %v, want [1 0]", got)
+	}
+	if got := followCounts(t, repo, "bob"); got != [2]int{0, 1} {
+		t.Errorf("bob counts after CreateFollower = %v, want [0 1]", got)
+	}
+
+	// A missing user cancels the whole transaction.
+	_, err := repo.CreateFollower(alice, &User{Username: "missing"})
+	if errorCode(err) != 404 {
+		t.Errorf("CreateFollower with missing user got err %v, want 404", err)
+	}
+	if entry, _ := repo.GetFollowerEntry("alice", "missing"); entry != nil {
+		t.Errorf("CreateFollower with missing user saved entry %v", entry)
+	}
+	if got := followCounts(t, repo, "alice"); got != [2]int{1, 0} {
+		t.Errorf("alice counts after failed CreateFollower = %v, want [1 0]", got)
+	}
+
+	// Deleting the same relationship twice only counts it once.
+	for range 2 {
+		if err := repo.DeleteFollower("alice", "bob"); err != nil {
+			t.Fatalf("DeleteFollower got err %v", err)
+		}
+	}
+	if got := followCounts(t, repo, "alice"); got != [2]int{0, 0} {
+		t.Errorf("alice counts after DeleteFollower = %v, want [0 0]", got)
+	}
+	if got := followCounts(t, repo, "bob"); got != [2]int{0, 0} {
+		t.Errorf("bob counts after DeleteFollower = %v, want [0 0]", got)
+	}
+}
+
+func TestMemorySetFollowCountsConflict(t *testing.T) {
+	repo := NewMemoryRepository()
+	alice, _ := repo.CreateUser("alice", "alice@example.com", "Alice", nil)
+	bob, _ := repo.CreateUser("bob", "bob@example.com", "Bob", nil)
+
+	if _, err := repo.CreateFollower(alice, bob); err != nil {
+		t.Fatalf("CreateFollower got err %v", err)
+	}
+
+	// alice was read before bob followed her.
+	if err := repo.SetFollowCounts(alice, 0, 0); errorCode(err) != 409 {
+		t.Errorf("SetFollowCounts with stale user got err %v, want 409", err)
+	}
+	if got := followCounts(t, repo, "alice"); got != [2]int{1, 0} {
+		t.Errorf("alice counts after conflict = %v, want [1 0]", got)
+	}
+}
@@ -0,0 +1,531 @@
+package database
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// GameStatisticsDimension is a property of a game which a user's results are grouped by.
+type GameStatisticsDimension string
+
+const (
+	GameStatisticsDimension_Color          GameStatisticsDimension = "color"
+	GameStatisticsDimension_Eco            GameStatisticsDimension = "eco"
+	GameStatisticsDimension_TimeControl    GameStatisticsDimension = "timeControl"
+	GameStatisticsDimension_OpponentRating GameStatisticsDimension = "opponentRating"
+	GameStatisticsDimension_Month          GameStatisticsDimension = "month"
+)
+
+// GameStatisticsDimensions lists every dimension of GameStatistics.
+var GameStatisticsDimensions = []GameStatisticsDimension{
+	GameStatisticsDimension_Color,
+	GameStatisticsDimension_Eco,
+	GameStatisticsDimension_TimeControl,
+	GameStatisticsDimension_OpponentRating,
+	GameStatisticsDimension_Month,
+}
+
+// gameStatisticsUnknown is the value of a dimension which cannot be determined from the game.
+const gameStatisticsUnknown = "unknown"
+
+const (
+	gameResultWin  = "win"
+	gameResultDraw = "draw"
+	gameResultLoss = "loss"
+)
+
+// GameStatistics is the cached aggregate of a user's game results. Counts are stored in a flat
+// map so that they can be incremented atomically as games are created and deleted.
+type GameStatistics struct {
+	// The username of the user. The hash key of the table.
+	Username string `dynamodbav:"username" json:"username"`
+
+	// Maps a key in the form dimension:value:result to the number of games with that result,
+	// from the perspective of the user. Ex: eco:B20-B29:win.
+	Counts map[string]int `dynamodbav:"counts" json:"-"`
+
+	// The time the statistics were last calculated from all of the user's games, in
+	// time.RFC3339 format.
+	CalculatedAt string `dynamodbav:"calculatedAt" json:"calculatedAt"`
+
+	// The time the statistics were last updated, in time.RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+
+	// The player names the statistics were calculated with. See User.PlayerNames. Each name
+	// has a GameStatisticsPlayer so that games in which the user played can be found from
+	// the games table's stream.
+	PlayerNames []string `dynamodbav:"playerNames,omitempty" json:"-"`
+}
+
+// GameStatisticsPlayer maps a player name to a user with cached game statistics.
+type GameStatisticsPlayer struct {
+	// The lowercase player name. The hash key of the table.
+	Player string `dynamodbav:"player"`
+
+	// The username of the user. The range key of the table.
+	Username string `dynamodbav:"username"`
+}
+
+// GameStatisticsPlayerChanges returns the players which must be saved and deleted when the
+// provided user's player names change from oldNames to newNames.
+func GameStatisticsPlayerChanges(username string, oldNames, newNames []string) (put, del []GameStatisticsPlayer) {
+	for _, name := range newNames {
+		if !slices.Contains(oldNames, name) {
+			put = append(put, GameStatisticsPlayer{Player: name, Username: username})
+		}
+	}
+	for _, name := range oldNames {
+		if !slices.Contains(newNames, name) {
+			del = append(del, GameStatisticsPlayer{Player: name, Username: username})
+		}
+	}
+	return put, del
+}
+
+// GameResults is the number of wins, draws and losses in a group of games.
+type GameResults struct {
+	Wins   int `json:"wins"`
+	Draws  int `json:"draws"`
+	Losses int `json:"losses"`
+}
+
+// Results returns the user's results grouped by the values of the provided dimension. Values
+// with no games are omitted.
+func (s *GameStatistics) Results(dimension GameStatisticsDimension) map[string]GameResults {
+	results := make(map[string]GameResults)
+	for key, count := range s.Counts {
+		tokens := strings.Split(key, ":")
+		if len(tokens) != 3 || tokens[0] != string(dimension) || count <= 0 {
+			continue
+		}
+
+		r := results[tokens[1]]
+		switch tokens[2] {
+		case gameResultWin:
+			r.Wins += count
+		case gameResultDraw:
+			r.Draws += count
+		case gameResultLoss:
+			r.Losses += count
+		}
+		results[tokens[1]] = r
+	}
+	return results
+}
+
+// AddGame adds the provided game to the statistics. See GameStatisticsKeys.
+func (s *GameStatistics) AddGame(game *Game, playerNames []string) {
+	if s.Counts == nil {
+		s.Counts = make(map[string]int)
+	}
+	for _, key := range GameStatisticsKeys(game, playerNames) {
+		s.Counts[key]++
+	}
+}
+
+// PlayerNames returns the lowercase names the user may appear under in the White and Black
+// fields of a game: their username, display name and the usernames of their rating systems.
+func (u *User) PlayerNames() []string {
+	var names []string
+	add := func(name string) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return
+		}
+		for _, n := range names {
+			if n == name {
+				return
+			}
+		}
+		names = append(names, name)
+	}
+
+	add(u.Username)
+	add(u.DisplayName)
+	for _, system := range ratingSystems {
+		if rating := u.Ratings[system]; rating != nil {
+			add(rating.Username)
+		}
+	}
+	return names
+}
+
+// GameStatisticsKeys returns the keys of GameStatistics.Counts which the provided game counts
+// towards. The user's color is the side whose player matches one of playerNames. No keys are
+// returned for unlisted games, games without a result and games where the user's color cannot
+// be determined.
+func GameStatisticsKeys(game *Game, playerNames []string) []string {
+	if game == nil || game.Unlisted {
+		return nil
+	}
+
+	color := gamePlayerColor(game, playerNames)
+	if color == "" {
+		return nil
+	}
+	result := gameResult(game, color)
+	if result == "" {
+		return nil
+	}
+	opponent := White
+	if color == White {
+		opponent = Black
+	}
+
+	values := map[GameStatisticsDimension]string{
+		GameStatisticsDimension_Color:          string(color),
+		GameStatisticsDimension_Eco:            ecoFamily(game.Headers["ECO"]),
+		GameStatisticsDimension_TimeControl:    timeControlCategory(game.Headers["TimeControl"]),
+		GameStatisticsDimension_OpponentRating: ratingBand(game.Headers[playerColorTag(opponent)+"Elo"]),
+		GameStatisticsDimension_Month:          gameMonth(game),
+	}
+
+	keys := make([]string, 0, len(GameStatisticsDimensions))
+	for _, d := range GameStatisticsDimensions {
+		keys = append(keys, fmt.Sprintf("%s:%s:%s", d, values[d], result))
+	}
+	return keys
+}
+
+// gamePlayerColor returns the color of the side whose player matches one of playerNames, or an
+// empty string if neither or both sides match.
+func gamePlayerColor(game *Game, playerNames []string) PlayerColor {
+	isPlayer := func(name string) bool {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, n := range playerNames {
+			if name != "" && n == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	isWhite, isBlack := isPlayer(game.White), isPlayer(game.Black)
+	switch {
+	case isWhite && !isBlack:
+		return White
+	case isBlack && !isWhite:
+		return Black
+	default:
+		return ""
+	}
+}
+
+// gameResult returns the result of the game from the perspective of the provided color, or an
+// empty string if the game has no result.
+func gameResult(game *Game, color PlayerColor) string {
+	switch game.Headers["Result"] {
+	case "1/2-1/2":
+		return gameResultDraw
+	case "1-0":
+		if color == White {
+			return gameResultWin
+		}
+		return gameResultLoss
+	case "0-1":
+		if color == Black {
+			return gameResultWin
+		}
+		return gameResultLoss
+	default:
+		return ""
+	}
+}
+
+// playerColorTag returns the prefix of the PGN tags of the provided color, such as White for WhiteElo.
+func playerColorTag(color PlayerColor) string {
+	if color == Black {
+		return "Black"
+	}
+	return "White"
+}
+
+// ecoFamily returns the range of ten ECO codes containing the provided code, such as B20-B29 for B23.
+func ecoFamily(eco string) string {
+	eco = strings.ToUpper(strings.TrimSpace(eco))
+	if len(eco) != 3 || eco[0] < 'A' || eco[0] > 'E' || eco[1] < '0' || eco[1] > '9' || eco[2] < '0' || eco[2] > '9' {
+		return gameStatisticsUnknown
+	}
+	return fmt.Sprintf("%s0-%s9", eco[:2], eco[:2])
+}
+
+// timeControlCategory returns the category of the provided PGN TimeControl tag. Only the first
+// period of the time control is used. Categories are based on the estimated game duration of
+// base + 40 * increment seconds, matching Lichess.
+func timeControlCategory(timeControl string) string {
+	timeControl = strings.TrimSpace(timeControl)
+	switch timeControl {
+	case "", "?":
+		return gameStatisticsUnknown
+	case "-":
+		return "untimed"
+	}
+
+	period := strings.TrimPrefix(strings.Split(timeControl, ":")[0], "*")
+	if _, seconds, ok := strings.Cut(period, "/"); ok {
+		period = seconds
+	}
+	baseStr, incrementStr, _ := strings.Cut(period, "+")
+	base, err := strconv.Atoi(baseStr)
+	if err != nil || base < 0 {
+		return gameStatisticsUnknown
+	}
+	increment := 0
+	if incrementStr != "" {
+		if increment, err = strconv.Atoi(incrementStr); err != nil || increment < 0 {
+			return gameStatisticsUnknown
+		}
+	}
+
+	switch duration := base + 40*increment; {
+	case duration < 180:
+		return "bullet"
+	case duration < 480:
+		return "blitz"
+	case duration < 1500:
+		return "rapid"
+	default:
+		return "classical"
+	}
+}
+
+// ratingBand returns the 100 point band containing the provided rating, such as 1500-1600 for 1550.
+func ratingBand(elo string) string {
+	rating, err := strconv.Atoi(strings.TrimSpace(elo))
+	if err != nil || rating <= 0 {
+		return gameStatisticsUnknown
+	}
+	lower := rating / 100 * 100
+	return fmt.Sprintf("%d-%d", lower, lower+100)
+}
+
+// gameMonth returns the month the game was played in 2006-01 format. If the game's date is
+// unknown, the month it was created is used instead.
+func gameMonth(game *Game) string {
+	for _, date := range []string{game.Date, strings.Split(game.Id, "_")[0]} {
+		if t, err := time.Parse("2006.01.02", date); err == nil {
+			return t.Format("2006-01")
+		}
+	}
+	return gameStatisticsUnknown
+}
+
+type GameStatisticsCalculator interface {
+	UserGetter
+	GameLister
+
+	// GetGameStatistics returns the cached game statistics of the provided user.
+	GetGameStatistics(username string) (*GameStatistics, error)
+
+	// PutGameStatistics saves the provided game statistics, replacing any existing statistics.
+	PutGameStatistics(stats *GameStatistics) error
+
+	// SetGameStatisticsPlayers updates the GameStatisticsPlayers of the provided user when
+	// their player names change from oldNames to newNames.
+	SetGameStatisticsPlayers(username string, oldNames, newNames []string) error
+}
+
+// gameStatisticsConsumer is the name of UpdateGameStatistics in the keys of the
+// IdempotencyRecords of the stream records it applies.
+const gameStatisticsConsumer = "gameStatistics"
+
+// gameStatisticsMaxUpdate is the maximum number of users in a call to UpdateGameStatistics,
+// which is the maximum number of items in a DynamoDB transaction less its IdempotencyRecord.
+const gameStatisticsMaxUpdate = 99
+
+type GameStatisticsUpdater interface {
+	// GetGameStatistics returns the cached game statistics of the provided user.
+	GetGameStatistics(username string) (*GameStatistics, error)
+
+	// ListGameStatisticsUsers returns the usernames of the users with cached game statistics
+	// which include the provided lowercase player name.
+	ListGameStatisticsUsers(player string) ([]string, error)
+
+	// UpdateGameStatistics adds the provided deltas, keyed by username and then by count key,
+	// to the counts of the users' game statistics. The updates are made in one transaction
+	// which also saves an IdempotencyRecord for the stream record with the provided event ID,
+	// so a record which was already applied is skipped. A 404 error is returned if one of the
+	// users has no statistics.
+	UpdateGameStatistics(eventID string, deltas map[string]map[string]int) error
+}
+
+// GetGameStatistics returns the cached game statistics of the provided user.
+func (repo *dynamoRepository) GetGameStatistics(username string) (*GameStatistics, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		TableName: aws.String(gameStatisticsTable),
+	}
+
+	stats := GameStatistics{}
+	if err := repo.getItem(input, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// PutGameStatistics saves the provided game statistics, replacing any existing statistics.
+func (repo *dynamoRepository) PutGameStatistics(stats *GameStatistics) error {
+	item, err := dynamodbattribute.MarshalMap(stats)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal game statistics", err)
+	}
+	if len(stats.Counts) == 0 {
+		// The counts map must exist for UpdateGameStatistics to set its keys.
+		item["counts"] = &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue)}
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(gameStatisticsTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "DynamoDB PutItem failure", err)
+}
+
+// SetGameStatisticsPlayers updates the GameStatisticsPlayers of the provided user when
+// their player names change from oldNames to newNames.
+func (repo *dynamoRepository) SetGameStatisticsPlayers(username string, oldNames, newNames []string) error {
+	put, del := GameStatisticsPlayerChanges(username, oldNames, newNames)
+
+	var reqs []*dynamodb.WriteRequest
+	for _, p := range put {
+		item, err := dynamodbattribute.MarshalMap(p)
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Unable to marshal game statistics player", err)
+		}
+		reqs = append(reqs, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+	}
+	for _, p := range del {
+		reqs = append(reqs, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{
+			Key: map[string]*dynamodb.AttributeValue{
+				"player":   {S: aws.String(p.Player)},
+				"username": {S: aws.String(p.Username)},
+			},
+		}})
+	}
+
+	for len(reqs) > 0 {
+		n := min(len(reqs), 25)
+		if err := repo.batchWrite(reqs[:n], gameStatisticsPlayersTable); err != nil {
+			return err
+		}
+		reqs = reqs[n:]
+	}
+	return nil
+}
+
+// ListGameStatisticsUsers returns the usernames of the users with cached game statistics
+// which include the provided lowercase player name.
+func (repo *dynamoRepository) ListGameStatisticsUsers(player string) ([]string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#player = :player"),
+		ExpressionAttributeNames: map[string]*string{
+			"#player": aws.String("player"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":player": {S: aws.String(player)},
+		},
+		TableName: aws.String(gameStatisticsPlayersTable),
+	}
+
+	var usernames []string
+	startKey := ""
+	for {
+		var players []GameStatisticsPlayer
+		lastKey, err := repo.query(input, startKey, &players)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range players {
+			usernames = append(usernames, p.Username)
+		}
+		if lastKey == "" {
+			return usernames, nil
+		}
+		startKey = lastKey
+	}
+}
+
+// UpdateGameStatistics adds the provided deltas, keyed by username and then by count key, to
+// the counts of the users' game statistics. The updates are made in one transaction which also
+// saves an IdempotencyRecord for the stream record with the provided event ID, so a record which
+// was already applied is skipped. A 404 error is returned if one of the users has no statistics.
+func (repo *dynamoRepository) UpdateGameStatistics(eventID string, deltas map[string]map[string]int) error {
+	if len(deltas) > gameStatisticsMaxUpdate {
+		return errors.New(500, "Temporary server error", fmt.Sprintf("UpdateGameStatistics got %d users, more than the maximum of %d", len(deltas), gameStatisticsMaxUpdate))
+	}
+
+	marker, err := streamRecordPut(gameStatisticsConsumer, eventID)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.TransactWriteItemsInput{TransactItems: []*dynamodb.TransactWriteItem{marker}}
+	updatedAt := time.Now().Format(time.RFC3339)
+
+	usernames := slices.Sorted(maps.Keys(deltas))
+	for _, username := range usernames {
+		names := map[string]*string{
+			"#username":  aws.String("username"),
+			"#counts":    aws.String("counts"),
+			"#updatedAt": aws.String("updatedAt"),
+		}
+		values := map[string]*dynamodb.AttributeValue{
+			":zero":      {N: aws.String("0")},
+			":updatedAt": {S: aws.String(updatedAt)},
+		}
+
+		var set []string
+		for key, delta := range deltas[username] {
+			if delta == 0 {
+				continue
+			}
+			i := len(set)
+			names[fmt.Sprintf("#k%d", i)] = aws.String(key)
+			values[fmt.Sprintf(":d%d", i)] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(delta))}
+			set = append(set, fmt.Sprintf("#counts.#k%d = if_not_exists(#counts.#k%d, :zero) + :d%d", i, i, i))
+		}
+		set = append(set, "#updatedAt = :updatedAt")
+
+		input.TransactItems = append(input.TransactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				ConditionExpression:       aws.String("attribute_exists(#username)"),
+				UpdateExpression:          aws.String("SET " + strings.Join(set, ", ")),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: values,
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": {S: aws.String(username)},
+				},
+				TableName: aws.String(gameStatisticsTable),
+			},
+		})
+	}
+
+	_, err = repo.svc.TransactWriteItems(input)
+	return gameStatisticsUpdateError(err, usernames)
+}
+
+// gameStatisticsUpdateError returns the error of an UpdateGameStatistics transaction which
+// updated the provided usernames after its IdempotencyRecord. Nil is returned if the
+// transaction was cancelled because the stream record was already applied.
+func gameStatisticsUpdateError(err error, usernames []string) error {
+	if err == nil || transactionCancellationCode(err, 0) == conditionalCheckFailedReason {
+		return nil
+	}
+	for i, username := range usernames {
+		if transactionCancellationCode(err, i+1) == conditionalCheckFailedReason {
+			return errors.Wrap(404, "Invalid request: game statistics not found", fmt.Sprintf("User %s has no game statistics", username), err)
+		}
+	}
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB TransactWriteItems call", err)
+}
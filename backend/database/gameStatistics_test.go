@@ -0,0 +1,146 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGameStatisticsKeys(t *testing.T) {
+	names := []string{"alice", "alice_lichess"}
+
+	table := []struct {
+		name string
+		game Game
+		want []string
+	}{
+		{
+			name: "WhiteWin",
+			game: Game{
+				Id: "2024.03.05_abc", White: "Alice", Black: "bob", Date: "2024.02.28",
+				Headers: map[string]string{"Result": "1-0", "ECO": "B23", "TimeControl": "5400+30", "BlackElo": "1549"},
+			},
+			want: []string{"color:white:win", "eco:B20-B29:win", "timeControl:classical:win", "opponentRating:1500-1600:win", "month:2024-02:win"},
+		},
+		{
+			name: "BlackWin",
+			game: Game{
+				Id: "2024.03.05_abc", White: "bob", Black: "alice_lichess", Date: "????.??.??",
+				Headers: map[string]string{"Result": "0-1", "ECO": "c65", "TimeControl": "180+2", "WhiteElo": "2010", "BlackElo": "1500"},
+			},
+			want: []string{"color:black:win", "eco:C60-C69:win", "timeControl:blitz:win", "opponentRating:2000-2100:win", "month:2024-03:win"},
+		},
+		{
+			name: "BlackLossUnknownHeaders",
+			game: Game{
+				Id: "2024.03.05_abc", White: "bob", Black: "alice", Date: "2024.03.01",
+				Headers: map[string]string{"Result": "1-0", "ECO": "?", "TimeControl": "?"},
+			},
+			want: []string{"color:black:loss", "eco:unknown:loss", "timeControl:unknown:loss", "opponentRating:unknown:loss", "month:2024-03:loss"},
+		},
+		{
+			name: "Draw",
+			game: Game{
+				Id: "2024.03.05_abc", White: "alice", Black: "bob", Date: "2024.03.01",
+				Headers: map[string]string{"Result": "1/2-1/2", "TimeControl": "-"},
+			},
+			want: []string{"color:white:draw", "eco:unknown:draw", "timeControl:untimed:draw", "opponentRating:unknown:draw", "month:2024-03:draw"},
+		},
+		{
+			name: "NoResult",
+			game: Game{Id: "2024.03.05_abc", White: "alice", Black: "bob", Headers: map[string]string{"Result": "*"}},
+		},
+		{
+			name: "Unlisted",
+			game: Game{Id: "2024.03.05_abc", White: "alice", Black: "bob", Unlisted: true, Headers: map[string]string{"Result": "1-0"}},
+		},
+		{
+			name: "NotPlayer",
+			game: Game{Id: "2024.03.05_abc", White: "carol", Black: "bob", Headers: map[string]string{"Result": "1-0"}},
+		},
+		{
+			name: "BothPlayers",
+			game: Game{Id: "2024.03.05_abc", White: "alice", Black: "alice_lichess", Headers: map[string]string{"Result": "1-0"}},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := GameStatisticsKeys(&tc.game, names)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GameStatisticsKeys mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTimeControlCategory(t *testing.T) {
+	table := map[string]string{
+		"60":              "bullet",
+		"120+1":           "bullet",
+		"180+2":           "blitz",
+		"300":             "blitz",
+		"600+5":           "rapid",
+		"900+10":          "rapid",
+		"1800":            "classical",
+		"40/5400+30:1800": "classical",
+		"*180":            "blitz",
+		"-":               "untimed",
+		"?":               "unknown",
+		"90 minutes":      "unknown",
+		"300+x":           "unknown",
+	}
+
+	for tc, want := range table {
+		if got := timeControlCategory(tc); got != want {
+			t.Errorf("timeControlCategory(%q) = %q; want %q", tc, got, want)
+		}
+	}
+}
+
+func TestGameStatisticsResults(t *testing.T) {
+	stats := &GameStatistics{Counts: map[string]int{
+		"color:white:win":   3,
+		"color:white:loss":  1,
+		"color:black:draw":  2,
+		"color:black:win":   0,
+		"month:2024-01:win": 3,
+	}}
+
+	want := map[string]GameResults{
+		"white": {Wins: 3, Losses: 1},
+		"black": {Draws: 2},
+	}
+	if diff := cmp.Diff(want, stats.Results(GameStatisticsDimension_Color)); diff != "" {
+		t.Errorf("Results mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPlayerNames(t *testing.T) {
+	user := &User{
+		Username:    "alice",
+		DisplayName: " Alice ",
+		Ratings: map[RatingSystem]*Rating{
+			Lichess:  {Username: "Alice_Lichess"},
+			Chesscom: {Username: ""},
+		},
+	}
+
+	want := []string{"alice", "alice_lichess"}
+	if diff := cmp.Diff(want, user.PlayerNames()); diff != "" {
+		t.Errorf("PlayerNames mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGameStatisticsPlayerChanges(t *testing.T) {
+	put, del := GameStatisticsPlayerChanges("alice", []string{"alice", "old_name"}, []string{"alice", "new_name"})
+
+	wantPut := []GameStatisticsPlayer{{Player: "new_name", Username: "alice"}}
+	if diff := cmp.Diff(wantPut, put); diff != "" {
+		t.Errorf("GameStatisticsPlayerChanges put mismatch (-want +got):\n%s", diff)
+	}
+	wantDel := []GameStatisticsPlayer{{Player: "old_name", Username: "alice"}}
+	if diff := cmp.Diff(wantDel, del); diff != "" {
+		t.Errorf("GameStatisticsPlayerChanges del mismatch (-want +got):\n%s", diff)
+	}
+}
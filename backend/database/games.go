@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
 )
 
 type PlayerColor string
@@ -136,6 +137,40 @@ type PositionComment struct {
 	SuggestedVariation string `dynamodbav:"suggestedVariation,omitempty" json:"suggestedVariation,omitempty"`
 }
 
+// NormalizeCommentFen returns the normalized form of a position comment's FEN, which is used
+// as the key of the game's PositionComments map. The FEN must be valid. Only its halfmove clock
+// and fullmove number are normalized, since the frontend looks comments up by the FEN returned by
+// chess.normalizedFen(), which keeps the en passant square and castling rights as they are.
+func NormalizeCommentFen(fen string) (string, error) {
+	if _, err := pgn.ParseFEN(fen); err != nil {
+		return "", err
+	}
+	fields := strings.Fields(fen)
+	if len(fields) < 4 {
+		return "", fmt.Errorf("FEN has %d fields, want at least 4", len(fields))
+	}
+	return strings.Join(fields[:4], " ") + " 0 1", nil
+}
+
+// normalizeCommentFen returns the key of a new position comment's FEN. See NormalizeCommentFen.
+func normalizeCommentFen(fen string) (string, error) {
+	normalized, err := NormalizeCommentFen(fen)
+	if err != nil {
+		return "", errors.Wrap(400, fmt.Sprintf("Invalid request: fen is invalid: %v", err), "", err)
+	}
+	return normalized, nil
+}
+
+// existingCommentFen returns the key of an existing position comment's FEN. Comments saved before
+// FENs were validated are left under their original key if their FEN is invalid, so an invalid
+// FEN is returned as is.
+func existingCommentFen(fen string) string {
+	if normalized, err := NormalizeCommentFen(fen); err == nil {
+		return normalized
+	}
+	return fen
+}
+
 type PositionCommentUpdate struct {
 	// The cohort of the game containing the comment
 	Cohort DojoCohort `json:"cohort"`
@@ -298,6 +333,31 @@ type GameGetter interface {
 	GetGame(cohort, id string) (*Game, error)
 }
 
+type GameUpdater interface {
+	// UpdateGame applies the specified update to the specified game.
+	UpdateGame(cohort, id string, update *GameUpdate) (*Game, error)
+}
+
+type GameReviewSetter interface {
+	UserGetter
+	AuditEntryPutter
+	GameGetter
+
+	// SetGameReview sets the provided game review on the provided game. The outbox entries are
+	// saved in the same transaction.
+	SetGameReview(cohort, id string, review *GameReview, outbox ...*OutboxEntry) (*Game, error)
+}
+
+type PositionCommentEditor interface {
+	// UpdateComment applies the given position comment update to the database. The game after
+	// update is returned.
+	UpdateComment(owner string, update *PositionCommentUpdate) (*Game, error)
+
+	// DeleteComment deletes the position comment indicated by the given update, including
+	// any replies to the comment. The updated game is returned.
+	DeleteComment(owner string, update *PositionCommentUpdate) (*Game, error)
+}
+
 type GameLister interface {
 	// ListGamesByCohort returns a list of Games matching the provided cohort. The PGN text is excluded and must be
 	// fetched separately with a call to GetGame.
@@ -322,9 +382,29 @@ type GameLister interface {
 	ScanCohort(cohort DojoCohort, startKey string) ([]*Game, string, error)
 }
 
+type GameReviewLister interface {
+	// ListGamesForReview returns a list of games that have been submitted for review by
+	// the senseis.
+	ListGamesForReview(startKey string) ([]Game, string, error)
+}
+
 type GameCommenter interface {
 	// PutComment puts the provided comment in the provided Game's position comments.
-	PutComment(cohort, id string, comment *PositionComment, skipMapCreation bool) (*Game, error)
+	// The outbox entries are saved in the same transaction.
+	PutComment(cohort, id string, comment *PositionComment, skipMapCreation bool, outbox ...*OutboxEntry) (*Game, error)
+}
+
+type PositionCommentMigrator interface {
+	// ScanGameComments returns the cohort, id and comments of the games which have comments.
+	// The next start key is also returned.
+	ScanGameComments(startKey string) ([]Game, string, error)
+
+	GameGetter
+
+	// SetPositionComments replaces the position comments of the provided game with comments,
+	// if its stored position comments are still equal to old. Otherwise, a 409 error whose
+	// cause is an *errors.ConflictError is returned, and the game must be read again.
+	SetPositionComments(cohort, id string, old, comments map[string]map[string]PositionComment) error
 }
 
 // GetGame returns the game object with the provided cohort and id.
@@ -706,8 +786,14 @@ func (repo *dynamoRepository) ScanCohort(cohort DojoCohort, startKey string) ([]
 
 // PutComment puts the provided comment in the provided Game's position comments.
 // If skipMapCreation is true, then the first conditional request to create the initial
-// comment map for a position is skipped.
-func (repo *dynamoRepository) PutComment(cohort, id string, comment *PositionComment, skipMapCreation bool) (*Game, error) {
+// comment map for a position is skipped. The outbox entries are saved in the same transaction.
+func (repo *dynamoRepository) PutComment(cohort, id string, comment *PositionComment, skipMapCreation bool, outbox ...*OutboxEntry) (*Game, error) {
+	fen, err := normalizeCommentFen(comment.Fen)
+	if err != nil {
+		return nil, err
+	}
+	comment.Fen = fen
+
 	item, err := dynamodbattribute.MarshalMap(comment)
 	if err != nil {
 		return nil, errors.Wrap(400, "Invalid request: comment cannot be marshaled", "", err)
@@ -750,7 +836,7 @@ func (repo *dynamoRepository) PutComment(cohort, id string, comment *PositionCom
 		}
 
 		game := Game{}
-		err = repo.updateItem(input, &game)
+		err = repo.updateItemWithOutbox(input, outbox, &game)
 		if err == nil {
 			return &game, nil
 		}
@@ -790,7 +876,7 @@ func (repo *dynamoRepository) PutComment(cohort, id string, comment *PositionCom
 	}
 
 	game := Game{}
-	err = repo.updateItem(input, &game)
+	err = repo.updateItemWithOutbox(input, outbox, &game)
 	if err != nil {
 		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
 			return nil, errors.Wrap(400, "Invalid request: game does not exist", "DynamoDB UpdateItem failure", aerr)
@@ -802,9 +888,11 @@ func (repo *dynamoRepository) PutComment(cohort, id string, comment *PositionCom
 
 // UpdateComment applies the given position comment update to the database. The game after update is returned.
 func (repo *dynamoRepository) UpdateComment(owner string, update *PositionCommentUpdate) (*Game, error) {
+	fen := existingCommentFen(update.Fen)
+
 	exprAttrNames := map[string]*string{
 		"#p":         aws.String("positionComments"),
-		"#fen":       aws.String(update.Fen),
+		"#fen":       aws.String(fen),
 		"#id":        aws.String(update.Id),
 		"#owner":     aws.String("owner"),
 		"#username":  aws.String("username"),
@@ -851,9 +939,11 @@ func (repo *dynamoRepository) UpdateComment(owner string, update *PositionCommen
 // DeleteComment deletes the position comment indicated by the given update, including
 // any replies to the comment. The updated game is returned.
 func (repo *dynamoRepository) DeleteComment(owner string, update *PositionCommentUpdate) (*Game, error) {
+	fen := existingCommentFen(update.Fen)
+
 	exprAttrNames := map[string]*string{
 		"#p":        aws.String("positionComments"),
-		"#fen":      aws.String(update.Fen),
+		"#fen":      aws.String(fen),
 		"#id":       aws.String(update.Id),
 		"#owner":    aws.String("owner"),
 		"#username": aws.String("username"),
@@ -890,6 +980,70 @@ func (repo *dynamoRepository) DeleteComment(owner string, update *PositionCommen
 	return &game, nil
 }
 
+// SetPositionComments replaces the position comments of the provided game with comments,
+// if its stored position comments are still equal to old. Otherwise, a 409 error whose
+// cause is an *errors.ConflictError is returned, and the game must be read again. This
+// prevents a migration from overwriting comments added since the game was read.
+func (repo *dynamoRepository) SetPositionComments(cohort, id string, old, comments map[string]map[string]PositionComment) error {
+	oldAv, err := dynamodbattribute.Marshal(old)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal old position comments", err)
+	}
+	fillCommentReplies(comments)
+	av, err := dynamodbattribute.Marshal(comments)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal position comments", err)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("#p = :old"),
+		UpdateExpression:    aws.String("SET #p = :p"),
+		ExpressionAttributeNames: map[string]*string{
+			"#p": aws.String("positionComments"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":old": oldAv,
+			":p":   av,
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"cohort": {
+				S: aws.String(cohort),
+			},
+			"id": {
+				S: aws.String(id),
+			},
+		},
+		TableName: aws.String(gameTable),
+	}
+
+	_, err = repo.svc.UpdateItem(input)
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.NewConflict("Invalid request: the game's comments were changed or the game was deleted", "DynamoDB UpdateItem failure", cohort+"/"+id)
+		}
+		return errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
+	}
+	return nil
+}
+
+// fillCommentReplies sets the nil replies maps of the provided comments to empty maps, so that
+// later replies can be added to them. See the note in PutComment.
+func fillCommentReplies(comments map[string]map[string]PositionComment) {
+	var fill func(map[string]PositionComment)
+	fill = func(m map[string]PositionComment) {
+		for id, c := range m {
+			if c.Replies == nil {
+				c.Replies = make(map[string]PositionComment)
+				m[id] = c
+			}
+			fill(c.Replies)
+		}
+	}
+	for _, m := range comments {
+		fill(m)
+	}
+}
+
 // UpdateGame applies the specified update to the specified game.
 func (repo *dynamoRepository) UpdateGame(cohort, id string, update *GameUpdate) (*Game, error) {
 	av, err := dynamodbattribute.MarshalMap(update)
@@ -935,8 +1089,9 @@ func (repo *dynamoRepository) UpdateGame(cohort, id string, update *GameUpdate)
 	return &game, nil
 }
 
-// Sets the provided game review on the provided game.
-func (repo *dynamoRepository) SetGameReview(cohort, id string, review *GameReview) (*Game, error) {
+// Sets the provided game review on the provided game. The outbox entries are saved in the
+// same transaction.
+func (repo *dynamoRepository) SetGameReview(cohort, id string, review *GameReview, outbox ...*OutboxEntry) (*Game, error) {
 	item, err := dynamodbattribute.MarshalMap(review)
 	if err != nil {
 		return nil, errors.Wrap(500, "Temporary server error", "Failed to marshal reviewer", err)
@@ -965,7 +1120,7 @@ func (repo *dynamoRepository) SetGameReview(cohort, id string, review *GameRevie
 	}
 
 	game := Game{}
-	err = repo.updateItem(input, &game)
+	err = repo.updateItemWithOutbox(input, outbox, &game)
 	if err != nil {
 		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
 			return nil, errors.Wrap(400, "Invalid request: game not found", "DynamoDB conditional check failed", aerr)
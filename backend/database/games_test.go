@@ -0,0 +1,171 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestPutCommentNormalizesFen(t *testing.T) {
+	repo := NewMemoryRepository()
+	if _, err := repo.BatchPutGames([]*Game{{Cohort: "1500-1600", Id: "2024.01.01_1", Owner: "alice"}}); err != nil {
+		t.Fatalf("BatchPutGames got err %v", err)
+	}
+
+	const normalized = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	for _, c := range []*PositionComment{
+		{Id: "c1", Fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 12 30", Owner: CommentOwner{Username: "alice"}},
+		{Id: "c2", Fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -", Owner: CommentOwner{Username: "bob"}},
+	} {
+		if _, err := repo.PutComment("1500-1600", "2024.01.01_1", c, false); err != nil {
+			t.Fatalf("PutComment(%s) got err %v", c.Id, err)
+		}
+	}
+
+	game, err := repo.GetGame("1500-1600", "2024.01.01_1")
+	if err != nil {
+		t.Fatalf("GetGame got err %v", err)
+	}
+	if len(game.PositionComments) != 1 || len(game.PositionComments[normalized]) != 2 {
+		t.Errorf("PositionComments got %v, want both comments under %q", game.PositionComments, normalized)
+	}
+	if got := game.PositionComments[normalized]["c1"].Fen; got != normalized {
+		t.Errorf("Comment Fen got %q, want %q", got, normalized)
+	}
+
+	update := &PositionCommentUpdate{
+		Cohort:  "1500-1600",
+		GameId:  "2024.01.01_1",
+		Id:      "c2",
+		Fen:     "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 5 9",
+		Content: "Edited",
+	}
+	if _, err := repo.UpdateComment("bob", update); err != nil {
+		t.Errorf("UpdateComment with unnormalized FEN got err %v", err)
+	}
+
+	_, err = repo.PutComment("1500-1600", "2024.01.01_1", &PositionComment{Id: "c3", Fen: "not a fen"}, false)
+	if got := errorCode(err); got != 400 {
+		t.Errorf("PutComment with invalid FEN got error code %d, want 400", got)
+	}
+}
+
+func TestNormalizeCommentFen(t *testing.T) {
+	table := []struct {
+		name string
+		fen  string
+		want string
+	}{
+		{
+			name: "Counters",
+			fen:  "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 12 30",
+			want: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		},
+		{
+			name: "NoCounters",
+			fen:  "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+			want: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		},
+		{
+			name: "EnPassant",
+			fen:  "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3",
+			want: "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		},
+		{
+			name: "Castling",
+			fen:  "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w Kq - 4 10",
+			want: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w Kq - 0 1",
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeCommentFen(tc.fen)
+			if err != nil {
+				t.Fatalf("NormalizeCommentFen got err %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("NormalizeCommentFen got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateCommentInvalidFen(t *testing.T) {
+	// Comments saved before FENs were validated may be keyed by a FEN which cannot be parsed.
+	const fen = "8/8/8/8/8/8/8/8 w - - 0 1"
+	repo := NewMemoryRepository()
+	game := &Game{
+		Cohort: "1500-1600",
+		Id:     "2024.01.01_1",
+		Owner:  "alice",
+		PositionComments: map[string]map[string]PositionComment{
+			fen: {
+				"c1": {Id: "c1", Fen: fen, Owner: CommentOwner{Username: "bob"}},
+				"c2": {Id: "c2", Fen: fen, Owner: CommentOwner{Username: "bob"}},
+			},
+		},
+	}
+	if _, err := repo.BatchPutGames([]*Game{game}); err != nil {
+		t.Fatalf("BatchPutGames got err %v", err)
+	}
+
+	update := &PositionCommentUpdate{Cohort: "1500-1600", GameId: "2024.01.01_1", Id: "c1", Fen: fen, Content: "Edited"}
+	game, err := repo.UpdateComment("bob", update)
+	if err != nil {
+		t.Fatalf("UpdateComment got err %v", err)
+	}
+	if got := game.PositionComments[fen]["c1"].Content; got != "Edited" {
+		t.Errorf("UpdateComment got content %q, want %q", got, "Edited")
+	}
+
+	update = &PositionCommentUpdate{Cohort: "1500-1600", GameId: "2024.01.01_1", Id: "c2", Fen: fen}
+	game, err = repo.DeleteComment("bob", update)
+	if err != nil {
+		t.Fatalf("DeleteComment got err %v", err)
+	}
+	if _, ok := game.PositionComments[fen]["c2"]; ok {
+		t.Errorf("DeleteComment did not delete the comment")
+	}
+}
+
+func TestSetPositionCommentsConflict(t *testing.T) {
+	repo := NewMemoryRepository()
+	if _, err := repo.BatchPutGames([]*Game{{Cohort: "1500-1600", Id: "2024.01.01_1", Owner: "alice"}}); err != nil {
+		t.Fatalf("BatchPutGames got err %v", err)
+	}
+
+	const fen = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	if _, err := repo.PutComment("1500-1600", "2024.01.01_1", &PositionComment{Id: "c1", Fen: fen}, false); err != nil {
+		t.Fatalf("PutComment(c1) got err %v", err)
+	}
+	game, err := repo.GetGame("1500-1600", "2024.01.01_1")
+	if err != nil {
+		t.Fatalf("GetGame got err %v", err)
+	}
+	read := game.PositionComments
+
+	// A comment added after the game was read must not be overwritten.
+	if _, err := repo.PutComment("1500-1600", "2024.01.01_1", &PositionComment{Id: "c2", Fen: fen}, false); err != nil {
+		t.Fatalf("PutComment(c2) got err %v", err)
+	}
+	comments := map[string]map[string]PositionComment{fen: {"c1": read[fen]["c1"]}}
+	err = repo.SetPositionComments("1500-1600", "2024.01.01_1", read, comments)
+	if got := errorCode(err); got != 409 {
+		t.Fatalf("SetPositionComments with stale comments got error code %d, want 409", got)
+	}
+
+	game, err = repo.GetGame("1500-1600", "2024.01.01_1")
+	if err != nil {
+		t.Fatalf("GetGame got err %v", err)
+	}
+	if len(game.PositionComments[fen]) != 2 {
+		t.Errorf("PositionComments got %v, want both comments", game.PositionComments)
+	}
+	if err := repo.SetPositionComments("1500-1600", "2024.01.01_1", game.PositionComments, comments); err != nil {
+		t.Errorf("SetPositionComments with current comments got err %v", err)
+	}
+
+	err = repo.SetPositionComments("1500-1600", "missing", read, comments)
+	if got := errorCode(err); got != 409 {
+		t.Errorf("SetPositionComments on a missing game got error code %d, want 409", got)
+	}
+}
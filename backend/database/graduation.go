@@ -71,6 +71,7 @@ type GraduationCreator interface {
 	UserUpdater
 	RequirementLister
 	TimelineEditor
+	RatingHistoryLister
 
 	// PutGraduation saves the provided Graduation in the database.
 	PutGraduation(graduation *Graduation) error
@@ -87,6 +88,11 @@ type GraduationLister interface {
 	ListGraduationsByDate(date, startKey string) ([]Graduation, string, error)
 }
 
+type GraduationScanner interface {
+	// ScanGraduations returns a list of all graduations in the table, paginated by the startKey.
+	ScanGraduations(startKey string) ([]Graduation, string, error)
+}
+
 // PutGraduation saves the provided Graduation in the database.
 func (repo *dynamoRepository) PutGraduation(graduation *Graduation) error {
 	item, err := dynamodbattribute.MarshalMap(graduation)
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// IdempotencyStatus is the state of the request which created an IdempotencyRecord.
+type IdempotencyStatus string
+
+const (
+	// The request is still being handled. Retries are rejected until it completes
+	// or the record expires.
+	IdempotencyStatus_InProgress IdempotencyStatus = "IN_PROGRESS"
+
+	// The request has completed and its response is saved on the record.
+	IdempotencyStatus_Complete IdempotencyStatus = "COMPLETE"
+)
+
+// IdempotencyRecord saves the response to a request made with an Idempotency-Key header,
+// so that retries of the request receive the same response instead of repeating its effects.
+type IdempotencyRecord struct {
+	// The key of the record, in the form username#idempotencyKey, so that keys chosen by
+	// different users cannot collide. Records marking applied stream records have keys in the
+	// form stream#consumer#eventID.
+	Key string `dynamodbav:"key" json:"key"`
+
+	// A hash of the route, path, query and body of the request. A retry with the same key
+	// must have the same hash.
+	RequestHash string `dynamodbav:"requestHash" json:"requestHash"`
+
+	// The state of the request.
+	Status IdempotencyStatus `dynamodbav:"status" json:"status"`
+
+	// The status code of the response. Only set if Status is IdempotencyStatus_Complete.
+	StatusCode int `dynamodbav:"statusCode,omitempty" json:"statusCode,omitempty"`
+
+	// The headers of the response. Only set if Status is IdempotencyStatus_Complete.
+	Headers map[string]string `dynamodbav:"headers,omitempty" json:"headers,omitempty"`
+
+	// The body of the response. Only set if Status is IdempotencyStatus_Complete.
+	Body string `dynamodbav:"body,omitempty" json:"body,omitempty"`
+
+	// The time the request was first received, in RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The time the record expires, in Unix seconds. Expired records are ignored and are
+	// eventually removed by the table's TTL.
+	ExpirationTime int64 `dynamodbav:"expirationTime" json:"-"`
+}
+
+// IdempotencyStore provides an interface for saving the responses to idempotent requests.
+type IdempotencyStore interface {
+	// CreateIdempotencyRecord saves the provided record if no unexpired record with the same
+	// key exists. If one does, it is returned and the provided record is not saved. Otherwise,
+	// nil is returned.
+	CreateIdempotencyRecord(record *IdempotencyRecord) (*IdempotencyRecord, error)
+
+	// PutIdempotencyRecord saves the provided record, replacing any existing record with
+	// the same key.
+	PutIdempotencyRecord(record *IdempotencyRecord) error
+
+	// DeleteIdempotencyRecord removes the record with the provided key. Deleting a record
+	// which does not exist is not an error.
+	DeleteIdempotencyRecord(key string) error
+}
+
+// CreateIdempotencyRecord saves the provided record if no unexpired record with the same
+// key exists. If one does, it is returned and the provided record is not saved. Otherwise,
+// nil is returned.
+func (repo *dynamoRepository) CreateIdempotencyRecord(record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	item, err := dynamodbattribute.MarshalMap(record)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal idempotency record", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item: item,
+		// The TTL can take days to remove expired records, so they are also overwritten here.
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #expirationTime < :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key":            aws.String("key"),
+			"#expirationTime": aws.String("expirationTime"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+		},
+		ReturnValuesOnConditionCheckFailure: aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld),
+		TableName:                           aws.String(idempotencyTable),
+	}
+	if _, err := repo.svc.PutItem(input); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			existing := IdempotencyRecord{}
+			if err := dynamodbattribute.UnmarshalMap(aerr.Item, &existing); err != nil {
+				return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal existing idempotency record", err)
+			}
+			return &existing, nil
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+	}
+	return nil, nil
+}
+
+// PutIdempotencyRecord saves the provided record, replacing any existing record with
+// the same key.
+func (repo *dynamoRepository) PutIdempotencyRecord(record *IdempotencyRecord) error {
+	item, err := dynamodbattribute.MarshalMap(record)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal idempotency record", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(idempotencyTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+}
+
+// DeleteIdempotencyRecord removes the record with the provided key. Deleting a record
+// which does not exist is not an error.
+func (repo *dynamoRepository) DeleteIdempotencyRecord(key string) error {
+	input := &dynamodb.DeleteItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {S: aws.String(key)},
+		},
+		TableName: aws.String(idempotencyTable),
+	}
+	_, err := repo.svc.DeleteItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB DeleteItem call", err)
+}
+
+// streamRecordTTL is how long the stream records applied by a consumer are remembered. DynamoDB
+// streams keep records for 24 hours, so a record cannot be delivered again after that.
+const streamRecordTTL = 48 * time.Hour
+
+// newStreamRecord returns the IdempotencyRecord which marks the stream record with the provided
+// event ID as applied by the provided consumer. Stream records are delivered again after a
+// failure, so consumers whose updates are not idempotent save it in the same transaction as their
+// updates and skip records which already have one.
+func newStreamRecord(consumer, eventID string) *IdempotencyRecord {
+	now := time.Now()
+	return &IdempotencyRecord{
+		Key:            "stream#" + consumer + "#" + eventID,
+		Status:         IdempotencyStatus_Complete,
+		CreatedAt:      now.Format(time.RFC3339),
+		ExpirationTime: now.Add(streamRecordTTL).Unix(),
+	}
+}
+
+// streamRecordPut returns a transaction item which saves the IdempotencyRecord of the provided
+// stream record. The transaction is cancelled if the record was already applied.
+func streamRecordPut(consumer, eventID string) (*dynamodb.TransactWriteItem, error) {
+	item, err := dynamodbattribute.MarshalMap(newStreamRecord(consumer, eventID))
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal idempotency record", err)
+	}
+	return &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(#key)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#key": aws.String("key"),
+			},
+			TableName: aws.String(idempotencyTable),
+		},
+	}, nil
+}
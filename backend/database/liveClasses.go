@@ -37,6 +37,11 @@ type GameReviewCohortMember struct {
 	QueueDate string `dynamodbav:"queueDate" json:"queueDate"`
 }
 
+type GameReviewCohortGetter interface {
+	// GetGameReviewCohort returns the game review cohort with the provided id.
+	GetGameReviewCohort(id string) (*GameReviewCohort, error)
+}
+
 func (repo *dynamoRepository) GetGameReviewCohort(id string) (*GameReviewCohort, error) {
 	input := dynamodb.GetItemInput{
 		Key: map[string]*dynamodb.AttributeValue{
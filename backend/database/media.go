@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -31,16 +33,25 @@ type MediaStore interface {
 	// Download fetches the file from the provided bucket and key
 	// and writes it to the given file.
 	Download(bucket, key string, file *os.File) error
+
+	// Upload saves the provided body at the provided bucket and key.
+	Upload(bucket, key, contentType string, body io.Reader) error
+
+	// PresignDownload returns a URL which downloads the file at the provided bucket
+	// and key as filename. The URL is valid for the provided duration.
+	PresignDownload(bucket, key, filename string, expires time.Duration) (string, error)
 }
 
 // s3MediaStore implements a media store using AWS S3.
 type s3MediaStore struct {
+	client     *s3.S3
 	uploader   *s3manager.Uploader
 	downloader *s3manager.Downloader
 }
 
 // S3 implements an AWS S3 media store using the default AWS session.
 var S3 = &s3MediaStore{
+	client:     s3.New(sess),
 	uploader:   s3manager.NewUploader(sess),
 	downloader: s3manager.NewDownloader(sess),
 }
@@ -100,3 +111,29 @@ func (ms *s3MediaStore) Download(bucket, key string, file *os.File) error {
 	})
 	return errors.Wrap(500, "Temporary server error", "Failed to download file", err)
 }
+
+// Upload saves the provided body at the provided bucket and key.
+func (ms *s3MediaStore) Upload(bucket, key, contentType string, body io.Reader) error {
+	_, err := ms.uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to upload file", err)
+}
+
+// PresignDownload returns a URL which downloads the file at the provided bucket
+// and key as filename. The URL is valid for the provided duration.
+func (ms *s3MediaStore) PresignDownload(bucket, key, filename string, expires time.Duration) (string, error) {
+	request, _ := ms.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket:                     aws.String(bucket),
+		Key:                        aws.String(key),
+		ResponseContentDisposition: aws.String(fmt.Sprintf("attachment; filename=%q", filename)),
+	})
+	url, err := request.Presign(expires)
+	if err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to presign download", err)
+	}
+	return url, nil
+}
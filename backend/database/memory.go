@@ -0,0 +1,612 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+)
+
+// memoryPageSize is the number of items evaluated by a single query or scan against
+// the memory repository. It plays the role of DynamoDB's 1MB page limit.
+const memoryPageSize = 100
+
+// memoryRepository implements a database using in-memory maps. It is intended for local
+// development and tests, where a real DynamoDB table is not available. Items are stored
+// in their DynamoDB attribute value form, so they go through the same marshaling as
+// they would in production.
+type memoryRepository struct {
+	mu     sync.Mutex
+	tables map[string]*memoryTable
+
+	// The file the items are loaded from before and saved to after every operation, so that
+	// they are shared between processes. Empty if the items are only kept in memory.
+	file string
+}
+
+// memoryTable holds the items of a single table, mapped by their primary key.
+type memoryTable struct {
+	// The name of the table's hash key attribute.
+	hashKey string
+
+	// The name of the table's range key attribute. Empty if the table has no range key.
+	rangeKey string
+
+	// The items in the table, mapped by the value returned from memoryTable.key.
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+// NewMemoryRepository returns an empty in-memory repository with the same tables as
+// the DynamoDB repository.
+func NewMemoryRepository() *memoryRepository {
+	repo := &memoryRepository{tables: make(map[string]*memoryTable)}
+
+	repo.addTable(userTable, "username", "")
+	repo.addTable(timelineTable, "owner", "id")
+	repo.addTable(gameTable, "cohort", "id")
+	repo.addTable(requirementTable, "status", "id")
+	repo.addTable(graduationTable, "username", "createdAt")
+	repo.addTable(eventTable, "id", "")
+	repo.addTable(courseTable, "type", "id")
+	repo.addTable(tournamentTable, "type", "startsAt")
+	repo.addTable(notificationTable, "username", "id")
+	repo.addTable(followersTable, "poster", "follower")
+	repo.addTable(newsfeedTable, "newsfeedId", "sortKey")
+	repo.addTable(yearReviewTable, "username", "period")
+	repo.addTable(clubTable, "id", "")
+	repo.addTable(examsTable, "type", "id")
+	repo.addTable(directoryTable, "owner", "id")
+	repo.addTable(liveClassesTable, "type", "id")
+	repo.addTable(auditLogTable, "target", "id")
+	repo.addTable(outboxTable, "status", "id")
+	repo.addTable(idempotencyTable, "key", "")
+	repo.addTable(accountDeletionTable, "username", "")
+	repo.addTable(dataExportTable, "username", "")
+	repo.addTable(ratingUpdateTable, "id", "")
+	repo.addTable(ratingHistoryTable, "username", "sortKey")
+	repo.addTable(ratingDistributionTable, "ratingSystem", "")
+	repo.addTable(gameStatisticsTable, "username", "")
+	repo.addTable(gameStatisticsPlayersTable, "player", "username")
+	repo.addTable(repertoireTable, "username", "sortKey")
+
+	return repo
+}
+
+func (repo *memoryRepository) addTable(name, hashKey, rangeKey string) {
+	repo.tables[name] = &memoryTable{
+		hashKey:  hashKey,
+		rangeKey: rangeKey,
+		items:    make(map[string]map[string]*dynamodb.AttributeValue),
+	}
+}
+
+// lock locks the repository and, if it has a file, loads the items saved by other processes.
+// Processes sharing a file must not use it concurrently.
+func (repo *memoryRepository) lock() {
+	repo.mu.Lock()
+	if repo.file == "" {
+		return
+	}
+
+	b, err := os.ReadFile(repo.file)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to read memory repository file %s: %v", repo.file, err)
+		return
+	}
+
+	var tables map[string]map[string]map[string]*dynamodb.AttributeValue
+	if err := json.Unmarshal(b, &tables); err != nil {
+		log.Errorf("Failed to unmarshal memory repository file %s: %v", repo.file, err)
+		return
+	}
+	for name, table := range repo.tables {
+		table.items = tables[name]
+		if table.items == nil {
+			table.items = make(map[string]map[string]*dynamodb.AttributeValue)
+		}
+	}
+}
+
+// unlock saves the items to the repository's file, if it has one, and unlocks the repository.
+func (repo *memoryRepository) unlock() {
+	defer repo.mu.Unlock()
+	if repo.file == "" {
+		return
+	}
+
+	tables := make(map[string]map[string]map[string]*dynamodb.AttributeValue, len(repo.tables))
+	for name, table := range repo.tables {
+		tables[name] = table.items
+	}
+	b, err := json.Marshal(tables)
+	if err != nil {
+		log.Errorf("Failed to marshal memory repository: %v", err)
+		return
+	}
+
+	// The file is replaced atomically so that a crash cannot leave it partially written.
+	tmp := filepath.Join(filepath.Dir(repo.file), "."+filepath.Base(repo.file)+".tmp")
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Errorf("Failed to write memory repository file %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, repo.file); err != nil {
+		log.Errorf("Failed to replace memory repository file %s: %v", repo.file, err)
+	}
+}
+
+// attributeString returns the string form of a scalar attribute value.
+func attributeString(av *dynamodb.AttributeValue) string {
+	if av == nil {
+		return ""
+	}
+	if av.S != nil {
+		return *av.S
+	}
+	if av.N != nil {
+		return *av.N
+	}
+	return ""
+}
+
+// key returns the primary key of the provided item, suitable for indexing the items map.
+func (t *memoryTable) key(item map[string]*dynamodb.AttributeValue) string {
+	if t.rangeKey == "" {
+		return attributeString(item[t.hashKey])
+	}
+	return attributeString(item[t.hashKey]) + "\x00" + attributeString(item[t.rangeKey])
+}
+
+// keyAttributes returns the primary key attributes of the provided item.
+func (t *memoryTable) keyAttributes(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{t.hashKey: item[t.hashKey]}
+	if t.rangeKey != "" {
+		key[t.rangeKey] = item[t.rangeKey]
+	}
+	return key
+}
+
+// tableKey returns the key attributes for the given hash and range values of the table.
+func (t *memoryTable) tableKey(hash, rng string) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{t.hashKey: {S: aws.String(hash)}}
+	if t.rangeKey != "" {
+		key[t.rangeKey] = &dynamodb.AttributeValue{S: aws.String(rng)}
+	}
+	return key
+}
+
+// conditionalCheckFailed returns the error DynamoDB returns when a condition expression
+// is not met, so that callers can map it to the same errors as the DynamoDB repository.
+func conditionalCheckFailed() error {
+	return &dynamodb.ConditionalCheckFailedException{Message_: aws.String("The conditional request failed")}
+}
+
+// putItem marshals the provided object and saves it in the given table, replacing any existing
+// item with the same key. The optional opts are applied to the marshaled item before saving.
+func putItem[T any](repo *memoryRepository, tableName string, object T, opts ...func(item map[string]*dynamodb.AttributeValue)) error {
+	item, err := dynamodbattribute.MarshalMap(object)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal item", err)
+	}
+	for _, opt := range opts {
+		opt(item)
+	}
+
+	repo.lock()
+	defer repo.unlock()
+
+	table := repo.tables[tableName]
+	table.items[table.key(item)] = item
+	return nil
+}
+
+// putItems saves each of the provided objects in the given table. The number of saved objects
+// is returned, matching batchWriteObjects.
+func putItems[T any](repo *memoryRepository, tableName string, objects []T) (int, error) {
+	for i, object := range objects {
+		if err := putItem(repo, tableName, object); err != nil {
+			return i, err
+		}
+	}
+	return len(objects), nil
+}
+
+// putItemConditional is like putItem, but the item is only saved if condition returns nil for
+// the existing item. The existing item is nil if it does not exist.
+func (repo *memoryRepository) putItemConditional(tableName string, item map[string]*dynamodb.AttributeValue, condition func(existing map[string]*dynamodb.AttributeValue) error) error {
+	repo.lock()
+	defer repo.unlock()
+
+	table := repo.tables[tableName]
+	key := table.key(item)
+	if condition != nil {
+		if err := condition(table.items[key]); err != nil {
+			return err
+		}
+	}
+	table.items[key] = item
+	return nil
+}
+
+// getItem unmarshals the item with the given key into the provided output value, which must be a
+// non-nil pointer. If the item does not exist, a 404 error is returned, matching dynamoRepository.getItem.
+func (repo *memoryRepository) getItem(tableName, hash, rng string, out any) error {
+	repo.lock()
+	defer repo.unlock()
+
+	table := repo.tables[tableName]
+	item, ok := table.items[table.key(table.tableKey(hash, rng))]
+	if !ok {
+		return errors.New(404, "Invalid request: resource not found", fmt.Sprintf("Memory GetItem item is nil for %s/%s in %s", hash, rng, tableName))
+	}
+
+	err := dynamodbattribute.UnmarshalMap(item, out)
+	return errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory GetItem result", err)
+}
+
+// unmarshalMemoryItem unmarshals the provided item into out, which must be a non-nil pointer.
+func unmarshalMemoryItem(item map[string]*dynamodb.AttributeValue, out any) error {
+	err := dynamodbattribute.UnmarshalMap(item, out)
+	return errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory item", err)
+}
+
+// deleteItem removes the item with the given key. The deleted item is returned, or nil if it
+// did not exist. If condition is non-nil and returns an error for the existing item, the item
+// is not deleted and the error is returned.
+func (repo *memoryRepository) deleteItem(tableName, hash, rng string, condition func(existing map[string]*dynamodb.AttributeValue) error) (map[string]*dynamodb.AttributeValue, error) {
+	repo.lock()
+	defer repo.unlock()
+
+	table := repo.tables[tableName]
+	key := table.key(table.tableKey(hash, rng))
+	item := table.items[key]
+	if condition != nil {
+		if err := condition(item); err != nil {
+			return nil, err
+		}
+	}
+	delete(table.items, key)
+	return item, nil
+}
+
+// updateAttributes applies fn to a copy of the item with the given key and saves the result.
+// If the item does not exist, fn receives an item containing only the key attributes and exists
+// is false, which mirrors DynamoDB's behavior of creating items on UpdateItem. If fn returns an
+// error, the item is left unchanged. The item after the update is returned.
+func (repo *memoryRepository) updateAttributes(tableName, hash, rng string, fn func(item map[string]*dynamodb.AttributeValue, exists bool) error) (map[string]*dynamodb.AttributeValue, error) {
+	repo.lock()
+	defer repo.unlock()
+
+	table := repo.tables[tableName]
+	keyAttrs := table.tableKey(hash, rng)
+	key := table.key(keyAttrs)
+
+	existing, exists := table.items[key]
+	item := make(map[string]*dynamodb.AttributeValue, len(existing))
+	for k, v := range existing {
+		item[k] = v
+	}
+	for k, v := range keyAttrs {
+		item[k] = v
+	}
+
+	if err := fn(item, exists); err != nil {
+		return nil, err
+	}
+	for k, v := range keyAttrs {
+		item[k] = v
+	}
+	table.items[key] = item
+	return item, nil
+}
+
+// memoryWrite is a single write of a memory transaction. fn receives a copy of the existing item
+// with the given key, or nil if it does not exist. It returns the new item, or nil to delete the
+// item. If fn returns an error, the transaction is canceled.
+type memoryWrite struct {
+	table     string
+	hash, rng string
+	fn        func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error)
+}
+
+// transactWrite applies all of the provided writes or none of them, like TransactWriteItems. If a
+// write's fn returns a ConditionalCheckFailedException, a TransactionCanceledException is returned
+// with that write's cancellation reason set, matching DynamoDB. Other errors are returned as is.
+func (repo *memoryRepository) transactWrite(writes ...memoryWrite) error {
+	repo.lock()
+	defer repo.unlock()
+
+	results := make([]map[string]*dynamodb.AttributeValue, len(writes))
+	for i, w := range writes {
+		table := repo.tables[w.table]
+		var item map[string]*dynamodb.AttributeValue
+		if existing, ok := table.items[table.key(table.tableKey(w.hash, w.rng))]; ok {
+			item = make(map[string]*dynamodb.AttributeValue, len(existing))
+			for k, v := range existing {
+				item[k] = v
+			}
+		}
+
+		result, err := w.fn(item)
+		if err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				return transactionCanceled(len(writes), i)
+			}
+			return err
+		}
+		results[i] = result
+	}
+
+	for i, w := range writes {
+		table := repo.tables[w.table]
+		key := table.key(table.tableKey(w.hash, w.rng))
+		if results[i] == nil {
+			delete(table.items, key)
+		} else {
+			table.items[key] = results[i]
+		}
+	}
+	return nil
+}
+
+// transactionCanceled returns the TransactionCanceledException of a transaction with n items
+// where the condition of the item at index failed.
+func transactionCanceled(n, index int) error {
+	reasons := make([]*dynamodb.CancellationReason, n)
+	for i := range reasons {
+		reasons[i] = &dynamodb.CancellationReason{Code: aws.String("None")}
+	}
+	reasons[index].Code = aws.String(conditionalCheckFailedReason)
+	return &dynamodb.TransactionCanceledException{
+		Message_:            aws.String("Transaction cancelled"),
+		CancellationReasons: reasons,
+	}
+}
+
+// updateItem is a typed form of updateAttributes. The item is unmarshaled into a T, passed to fn
+// and then marshaled back into the table. The optional opts are applied to the marshaled item
+// before saving. The value after the update is returned.
+func updateItem[T any](repo *memoryRepository, tableName, hash, rng string, fn func(value *T, exists bool) error, opts ...func(item map[string]*dynamodb.AttributeValue)) (*T, error) {
+	result, err := repo.updateAttributes(tableName, hash, rng, func(item map[string]*dynamodb.AttributeValue, exists bool) error {
+		var value T
+		if err := dynamodbattribute.UnmarshalMap(item, &value); err != nil {
+			return errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory item", err)
+		}
+		if err := fn(&value, exists); err != nil {
+			return err
+		}
+
+		updated, err := dynamodbattribute.MarshalMap(value)
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Unable to marshal memory item", err)
+		}
+		for _, opt := range opts {
+			opt(updated)
+		}
+
+		for k := range item {
+			delete(item, k)
+		}
+		for k, v := range updated {
+			item[k] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var value T
+	if err := dynamodbattribute.UnmarshalMap(result, &value); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory item", err)
+	}
+	return &value, nil
+}
+
+// memoryQueryInput describes a query or scan against a memoryTable. Items are decoded into a T
+// in order to evaluate the key condition, filter and sort key.
+type memoryQueryInput[T any] struct {
+	// The key attributes of the index being queried, if any. These are included in the
+	// returned lastKey in addition to the table's primary key, as DynamoDB does.
+	indexKeys []string
+
+	// Returns true if the item matches the key condition of the query. Items which do not
+	// match are not evaluated and do not count towards the limit. If nil, all items match,
+	// as in a scan.
+	match func(item *T) bool
+
+	// Returns true if the item should be included in the results. Like a DynamoDB filter
+	// expression, filtered items still count towards the limit. If nil, all items are included.
+	filter func(item *T) bool
+
+	// Returns the value used to order the results. If nil, results are ordered by primary key.
+	sortKey func(item *T) string
+
+	// Whether to return the results in descending order, equivalent to setting ScanIndexForward
+	// to false.
+	descending bool
+
+	// The maximum number of items to evaluate. If non-positive, memoryPageSize is used.
+	limit int
+
+	// The top-level attributes to include in the results. If empty, all attributes are included.
+	projection []string
+}
+
+type memoryQueryItem[T any] struct {
+	item    map[string]*dynamodb.AttributeValue
+	value   T
+	sortKey string
+	key     string
+}
+
+// query runs the given input against the given table and unmarshals the results into the provided
+// output value, which must be a non-nil pointer to a slice. startKey has the same format as the
+// startKey accepted by dynamoRepository.query, and the next startKey is returned. Cursors are bound
+// to the table and index being queried. If startKey is not a valid cursor for them, a 400 error is
+// returned.
+func query[T any](repo *memoryRepository, tableName string, input *memoryQueryInput[T], startKey string, out any) (string, error) {
+	scope := strings.Join(append([]string{"memory", tableName}, input.indexKeys...), "\x00")
+	var exclusiveStartKey map[string]*dynamodb.AttributeValue
+	if startKey != "" {
+		var err error
+		if exclusiveStartKey, err = decodeCursor(scope, startKey); err != nil {
+			return "", err
+		}
+	}
+
+	repo.lock()
+	table := repo.tables[tableName]
+
+	var candidates []memoryQueryItem[T]
+	for _, item := range table.items {
+		var value T
+		if err := dynamodbattribute.UnmarshalMap(item, &value); err != nil {
+			repo.unlock()
+			return "", errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory item", err)
+		}
+		if input.match != nil && !input.match(&value) {
+			continue
+		}
+		candidates = append(candidates, memoryQueryItem[T]{item: item, value: value, key: table.key(item)})
+	}
+	repo.unlock()
+
+	for i := range candidates {
+		candidates[i].sortKey = input.getSortKey(&candidates[i].value, candidates[i].key)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return memoryQueryLess(candidates[i].sortKey, candidates[i].key, candidates[j].sortKey, candidates[j].key, input.descending)
+	})
+
+	start := 0
+	if exclusiveStartKey != nil {
+		var startValue T
+		if err := dynamodbattribute.UnmarshalMap(exclusiveStartKey, &startValue); err != nil {
+			return "", errors.Wrap(400, "Invalid request: startKey is not valid", "startKey could not be unmarshaled", err)
+		}
+		startPrimaryKey := table.key(exclusiveStartKey)
+		startSortKey := input.getSortKey(&startValue, startPrimaryKey)
+		start = sort.Search(len(candidates), func(i int) bool {
+			return memoryQueryLess(startSortKey, startPrimaryKey, candidates[i].sortKey, candidates[i].key, input.descending)
+		})
+	}
+
+	limit := input.limit
+	if limit <= 0 {
+		limit = memoryPageSize
+	}
+	end := start + limit
+	if end > len(candidates) {
+		end = len(candidates)
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+	for _, c := range candidates[start:end] {
+		if input.filter != nil && !input.filter(&c.value) {
+			continue
+		}
+		items = append(items, project(c.item, input.projection))
+	}
+
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, out); err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory query result", err)
+	}
+
+	if end < len(candidates) {
+		last := candidates[end-1].item
+		lastEvaluatedKey := table.keyAttributes(last)
+		for _, k := range input.indexKeys {
+			lastEvaluatedKey[k] = last[k]
+		}
+		return encodeCursor(scope, lastEvaluatedKey)
+	}
+	return "", nil
+}
+
+func (input *memoryQueryInput[T]) getSortKey(value *T, primaryKey string) string {
+	if input.sortKey == nil {
+		return primaryKey
+	}
+	return input.sortKey(value)
+}
+
+// memoryQueryLess returns true if the item with sort key s1 and primary key k1 comes before
+// the item with sort key s2 and primary key k2.
+func memoryQueryLess(s1, k1, s2, k2 string, descending bool) bool {
+	if s1 == s2 {
+		s1, s2 = k1, k2
+	}
+	if descending {
+		return s1 > s2
+	}
+	return s1 < s2
+}
+
+// project returns a copy of the item containing only the given top-level attributes. If
+// projection is empty, the item is returned unchanged.
+func project(item map[string]*dynamodb.AttributeValue, projection []string) map[string]*dynamodb.AttributeValue {
+	if len(projection) == 0 {
+		return item
+	}
+
+	result := make(map[string]*dynamodb.AttributeValue, len(projection))
+	for _, attr := range projection {
+		if v, ok := item[attr]; ok {
+			result[attr] = v
+		}
+	}
+	return result
+}
+
+// parseProjection converts a DynamoDB projection expression without expression attribute
+// names into the list of top-level attributes it selects.
+func parseProjection(projectionExpression string) []string {
+	if projectionExpression == "" {
+		return nil
+	}
+
+	var projection []string
+	for _, path := range strings.Split(projectionExpression, ",") {
+		path = strings.TrimSpace(path)
+		if i := strings.IndexAny(path, ".["); i >= 0 {
+			path = path[:i]
+		}
+		if path != "" {
+			projection = append(projection, path)
+		}
+	}
+	return projection
+}
+
+// batchGet returns the items with the provided hash keys in the given table. Keys which do not
+// exist are skipped, as in a DynamoDB BatchGetItem request.
+func batchGet[T any](repo *memoryRepository, tableName string, keys []map[string]*dynamodb.AttributeValue, projection []string) ([]T, error) {
+	repo.lock()
+	table := repo.tables[tableName]
+	items := make([]map[string]*dynamodb.AttributeValue, 0, len(keys))
+	for _, k := range keys {
+		if item, ok := table.items[table.key(k)]; ok {
+			items = append(items, project(item, projection))
+		}
+	}
+	repo.unlock()
+
+	var result []T
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &result); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory BatchGetItem result", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// CreateAccountDeletion starts deleting the account with the provided username. If the
+// account is already being deleted, the existing AccountDeletion is returned instead.
+func (repo *memoryRepository) CreateAccountDeletion(username string) (*AccountDeletion, error) {
+	deletion := NewAccountDeletion(username)
+	item, err := dynamodbattribute.MarshalMap(deletion)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal account deletion", err)
+	}
+
+	err = repo.putItemConditional(accountDeletionTable, item, func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing != nil && attributeString(existing["status"]) != string(AccountDeletionStatus_Complete) {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	if err != nil {
+		return repo.GetAccountDeletion(username)
+	}
+	return deletion, nil
+}
+
+// GetAccountDeletion returns the AccountDeletion for the provided username.
+func (repo *memoryRepository) GetAccountDeletion(username string) (*AccountDeletion, error) {
+	deletion := AccountDeletion{}
+	if err := repo.getItem(accountDeletionTable, username, "", &deletion); err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// ListAccountDeletions returns the deletions which are in progress. The next start key
+// is also returned.
+func (repo *memoryRepository) ListAccountDeletions(startKey string) ([]AccountDeletion, string, error) {
+	input := &memoryQueryInput[AccountDeletion]{
+		filter: func(d *AccountDeletion) bool { return d.Status == AccountDeletionStatus_InProgress },
+	}
+
+	var deletions []AccountDeletion
+	lastKey, err := query(repo, accountDeletionTable, input, startKey, &deletions)
+	if err != nil {
+		return nil, "", err
+	}
+	return deletions, lastKey, nil
+}
+
+// PutAccountDeletion saves the provided deletion.
+func (repo *memoryRepository) PutAccountDeletion(deletion *AccountDeletion) error {
+	return putItem(repo, accountDeletionTable, deletion)
+}
+
+// deleteQueryPage deletes the items returned by a page of the provided query. The number
+// of deleted items and the next start key are returned.
+func (repo *memoryRepository) deleteQueryPage(tableName string, input *memoryQueryInput[map[string]any], startKey string) (int, string, error) {
+	var items []map[string]any
+	lastKey, err := query(repo, tableName, input, startKey, &items)
+	if err != nil {
+		return 0, "", err
+	}
+
+	table := repo.tables[tableName]
+	for i, item := range items {
+		rng, _ := item[table.rangeKey].(string)
+		if _, err := repo.deleteItem(tableName, item[table.hashKey].(string), rng, nil); err != nil {
+			return i, "", err
+		}
+	}
+	return len(items), lastKey, nil
+}
+
+// memoryPartitionQuery returns a memoryQueryInput for all items with the provided hash key.
+func memoryPartitionQuery(hashName, hashValue string) *memoryQueryInput[map[string]any] {
+	return &memoryQueryInput[map[string]any]{
+		match: func(item *map[string]any) bool { return (*item)[hashName] == hashValue },
+	}
+}
+
+// DeleteNewsfeedPage removes a page of the entries in the provided newsfeed. The number
+// of deleted entries and the next start key are returned.
+func (repo *memoryRepository) DeleteNewsfeedPage(newsfeedId, startKey string) (int, string, error) {
+	return repo.deleteQueryPage(newsfeedTable, memoryPartitionQuery("newsfeedId", newsfeedId), startKey)
+}
+
+// DeleteNewsfeedEntriesByPosterPage removes a page of the entries generated by the provided
+// poster, across all newsfeeds. The number of deleted entries and the next start key are returned.
+func (repo *memoryRepository) DeleteNewsfeedEntriesByPosterPage(poster, startKey string) (int, string, error) {
+	input := memoryPartitionQuery("poster", poster)
+	input.indexKeys = []string{"poster", "timelineId"}
+	return repo.deleteQueryPage(newsfeedTable, input, startKey)
+}
+
+// DeleteNotificationsPage removes a page of the provided username's notifications. The number
+// of deleted notifications and the next start key are returned.
+func (repo *memoryRepository) DeleteNotificationsPage(username, startKey string) (int, string, error) {
+	return repo.deleteQueryPage(notificationTable, memoryPartitionQuery("username", username), startKey)
+}
+
+// DeleteDirectoriesPage removes a page of the directories owned by the provided username. The
+// number of deleted directories and the next start key are returned.
+func (repo *memoryRepository) DeleteDirectoriesPage(owner, startKey string) (int, string, error) {
+	return repo.deleteQueryPage(directoryTable, memoryPartitionQuery("owner", owner), startKey)
+}
+
+// DeleteGraduationsPage removes a page of the provided username's graduations. The number of
+// deleted graduations and the next start key are returned.
+func (repo *memoryRepository) DeleteGraduationsPage(username, startKey string) (int, string, error) {
+	return repo.deleteQueryPage(graduationTable, memoryPartitionQuery("username", username), startKey)
+}
+
+// DeleteYearReviewsPage removes a page of the provided username's year reviews. The number of
+// deleted year reviews and the next start key are returned.
+func (repo *memoryRepository) DeleteYearReviewsPage(username, startKey string) (int, string, error) {
+	return repo.deleteQueryPage(yearReviewTable, memoryPartitionQuery("username", username), startKey)
+}
+
+// ScanClubsByUser returns the clubs the provided username owns, is a member of or has
+// requested to join. The next start key is also returned.
+func (repo *memoryRepository) ScanClubsByUser(username, startKey string) ([]Club, string, error) {
+	input := &memoryQueryInput[Club]{
+		filter: func(c *Club) bool {
+			_, isMember := c.Members[username]
+			_, hasRequest := c.JoinRequests[username]
+			return c.Owner == username || isMember || hasRequest
+		},
+	}
+
+	var clubs []Club
+	lastKey, err := query(repo, clubTable, input, startKey, &clubs)
+	if err != nil {
+		return nil, "", err
+	}
+	return clubs, lastKey, nil
+}
+
+// DeleteClub removes the club with the provided id. The club is also removed from the clubs
+// of each of its members. The deleted club is returned.
+func (repo *memoryRepository) DeleteClub(id string) (*Club, error) {
+	item, err := repo.deleteItem(clubTable, id, "", func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing == nil {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(404, "Invalid request: club not found", "Memory conditional check failed", err)
+	}
+
+	club := &Club{}
+	if err := unmarshalMemoryItem(item, club); err != nil {
+		return nil, err
+	}
+
+	for username := range club.Members {
+		_, err := repo.updateAttributes(userTable, username, "", func(item map[string]*dynamodb.AttributeValue, exists bool) error {
+			if !exists {
+				return conditionalCheckFailed()
+			}
+			updateStringSet(item, "clubs", id, false)
+			return nil
+		})
+		if err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				continue
+			}
+			return nil, errors.Wrap(500, "Temporary server error", "Failed to update club member", err)
+		}
+	}
+	return club, nil
+}
+
+// ScanEventsByUser returns the events the provided username owns or participates in.
+// The next start key is also returned.
+func (repo *memoryRepository) ScanEventsByUser(username, startKey string) ([]Event, string, error) {
+	input := &memoryQueryInput[Event]{
+		filter: func(e *Event) bool {
+			_, isParticipant := e.Participants[username]
+			return e.Id != "STATISTICS" && (e.Owner == username || isParticipant)
+		},
+	}
+
+	var events []Event
+	lastKey, err := query(repo, eventTable, input, startKey, &events)
+	if err != nil {
+		return nil, "", err
+	}
+	return events, lastKey, nil
+}
+
+// ScanGameComments returns the cohort, id, owner and comments of the games which have
+// comments. The next start key is also returned.
+func (repo *memoryRepository) ScanGameComments(startKey string) ([]Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		filter:     func(g *Game) bool { return len(g.PositionComments) > 0 || len(g.Comments) > 0 },
+		projection: []string{"cohort", "id", "owner", "positionComments", "comments"},
+	}
+
+	var games []Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// AnonymizeGameComments anonymises the provided username's comments on the provided game.
+// The owner of each comment is replaced with DeletedUsername and its content is removed.
+// Replies from other users are kept. The number of anonymised comments is returned.
+func (repo *memoryRepository) AnonymizeGameComments(game *Game, username string) (int, error) {
+	count := 0
+	_, err := updateItem(repo, gameTable, string(game.Cohort), game.Id, func(g *Game, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		for fen, comments := range g.PositionComments {
+			count += anonymizePositionComments(comments, username)
+			g.PositionComments[fen] = comments
+		}
+		for _, c := range g.Comments {
+			if c != nil && c.Owner == username {
+				c.Owner = DeletedUsername
+				c.OwnerDisplayName = DeletedDisplayName
+				c.OwnerCohort = ""
+				c.Content = deletedContent
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			// The game was deleted after it was scanned.
+			return 0, nil
+		}
+		return 0, err
+	}
+	return count, nil
+}
+
+// anonymizePositionComments anonymises the provided username's comments in the provided map,
+// including replies. The number of anonymised comments is returned.
+func anonymizePositionComments(comments map[string]PositionComment, username string) int {
+	count := 0
+	for id, c := range comments {
+		if c.Owner.Username == username {
+			c.Owner = CommentOwner{Username: DeletedUsername, DisplayName: DeletedDisplayName}
+			c.Content = deletedContent
+			c.SuggestedVariation = ""
+			count++
+		}
+		count += anonymizePositionComments(c.Replies, username)
+		comments[id] = c
+	}
+	return count
+}
+
+// DeleteExamAnswer removes the provided answer and the summary of it on the exam, so
+// that the user is no longer included in the exam's results.
+func (repo *memoryRepository) DeleteExamAnswer(answer *ExamAnswer) error {
+	_, err := updateItem(repo, examsTable, string(answer.ExamType), answer.Id, func(e *Exam, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		delete(e.Answers, string(answer.Type))
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); !ok {
+			return err
+		}
+	}
+
+	_, err = repo.deleteItem(examsTable, string(answer.Type), answer.Id, nil)
+	return err
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// PutAuditEntry saves the provided entry in the audit log. Existing entries are never
+// overwritten.
+func (repo *memoryRepository) PutAuditEntry(entry *AuditEntry) error {
+	item, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal audit entry", err)
+	}
+
+	err = repo.putItemConditional(auditLogTable, item, func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing != nil {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed memory PutItem", err)
+}
+
+// ListAuditEntriesByTarget returns the audit log entries for the provided target, newest
+// first. The next start key is also returned.
+func (repo *memoryRepository) ListAuditEntriesByTarget(target, startKey string) ([]AuditEntry, string, error) {
+	input := &memoryQueryInput[AuditEntry]{
+		match:      func(e *AuditEntry) bool { return e.Target == target },
+		sortKey:    func(e *AuditEntry) string { return e.Id },
+		descending: true,
+	}
+
+	var entries []AuditEntry
+	lastKey, err := query(repo, auditLogTable, input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// ListAuditEntriesByActor returns the audit log entries performed by the provided actor,
+// newest first. The next start key is also returned.
+func (repo *memoryRepository) ListAuditEntriesByActor(actor, startKey string) ([]AuditEntry, string, error) {
+	input := &memoryQueryInput[AuditEntry]{
+		indexKeys:  []string{"actor"},
+		match:      func(e *AuditEntry) bool { return e.Actor == actor },
+		sortKey:    func(e *AuditEntry) string { return e.Id },
+		descending: true,
+	}
+
+	var entries []AuditEntry
+	lastKey, err := query(repo, auditLogTable, input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// Creates the given club in the database. The club id must not already exist.
+func (repo *memoryRepository) CreateClub(club *Club) error {
+	item, err := dynamodbattribute.MarshalMap(club)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal club", err)
+	}
+
+	err = repo.putItemConditional(clubTable, item, func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing != nil {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Memory PutItem failure", err)
+	}
+
+	if err := repo.AddClubToUser(club.Id, club.Owner); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Applies the given update to the given club. The club after the update is returned.
+func (repo *memoryRepository) UpdateClub(id string, caller string, update *ClubUpdate) (*Club, error) {
+	update.UpdatedAt = aws.String(time.Now().Format(time.RFC3339))
+
+	av, err := dynamodbattribute.Marshal(update)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal club update", err)
+	}
+
+	item, err := repo.updateAttributes(clubTable, id, "", func(item map[string]*dynamodb.AttributeValue, exists bool) error {
+		if !exists || attributeString(item["owner"]) != caller {
+			return conditionalCheckFailed()
+		}
+		for k, v := range av.M {
+			item[k] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(404, "Invalid request: club not found", "Memory conditional check failed", err)
+	}
+
+	club := &Club{}
+	if err := unmarshalMemoryItem(item, club); err != nil {
+		return nil, err
+	}
+	return club, nil
+}
+
+// Returns a list of clubs, excluding the promo code, members and join requests. The next start key is also returned.
+func (repo *memoryRepository) ListClubs(startKey string) ([]Club, string, error) {
+	input := &memoryQueryInput[Club]{
+		filter:     func(c *Club) bool { return !c.Unlisted },
+		projection: []string{"id", "name", "description", "shortDescription", "owner", "externalUrl", "location", "memberCount", "approvalRequired", "createdAt", "updatedAt"},
+	}
+
+	var clubs []Club
+	lastKey, err := query(repo, clubTable, input, startKey, &clubs)
+	if err != nil {
+		return nil, "", err
+	}
+	return clubs, lastKey, nil
+}
+
+// Returns the club with the given id.
+func (repo *memoryRepository) GetClub(id string) (*Club, error) {
+	club := Club{}
+	if err := repo.getItem(clubTable, id, "", &club); err != nil {
+		return nil, err
+	}
+	return &club, nil
+}
+
+// Returns a list of clubs with the provided ids. Up to 100 ids can be specified at a time.
+func (repo *memoryRepository) BatchGetClubs(ids []string) ([]Club, error) {
+	if len(ids) == 0 {
+		return []Club{}, nil
+	}
+	if len(ids) > 100 {
+		return nil, errors.New(500, "Temporary server error", "More than 100 usernames passed to BatchGetClubs")
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}})
+	}
+	projection := []string{"id", "name", "shortDescription", "description", "owner", "promoCode", "externalUrl", "location", "memberCount", "unlisted", "approvalRequired", "createdAt", "updatedAt"}
+	return batchGet[Club](repo, clubTable, keys, projection)
+}
+
+// Adds the given username as a member of the given club. The club must have ApprovalRequired set to false.
+// The club after updating is returned. Also adds the club id to the given user's clubs attributes.
+func (repo *memoryRepository) JoinClub(id string, username string, isFreeTier bool) (*Club, error) {
+	club, err := updateItem(repo, clubTable, id, "", func(c *Club, exists bool) error {
+		if !exists || c.ApprovalRequired || (isFreeTier && !c.AllowFreeTier) {
+			return conditionalCheckFailed()
+		}
+		if _, ok := c.Members[username]; ok {
+			return conditionalCheckFailed()
+		}
+
+		if c.Members == nil {
+			c.Members = make(map[string]ClubMember)
+		}
+		c.Members[username] = ClubMember{Username: username, JoinedAt: time.Now().Format(time.RFC3339)}
+		c.MemberCount++
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: club not found, you are already a member or you do not have permission to join", "Memory conditional check failed", err)
+		}
+		return nil, err
+	}
+
+	if err := repo.AddClubToUser(id, username); err != nil {
+		return nil, err
+	}
+
+	return club, nil
+}
+
+// Adds the given join request to the given club. The outbox entries are saved if the request
+// is added. The club after updating is returned.
+func (repo *memoryRepository) RequestToJoinClub(id string, request *ClubJoinRequest, isFreeTier bool, outbox ...*OutboxEntry) (*Club, error) {
+	request.Status = ClubJoinRequestStatus_Pending
+	request.CreatedAt = time.Now().Format(time.RFC3339)
+
+	club, err := updateItem(repo, clubTable, id, "", func(c *Club, exists bool) error {
+		if !exists || !c.ApprovalRequired || (isFreeTier && !c.AllowFreeTier) {
+			return conditionalCheckFailed()
+		}
+		if _, ok := c.JoinRequests[request.Username]; ok {
+			return conditionalCheckFailed()
+		}
+
+		if c.JoinRequests == nil {
+			c.JoinRequests = make(map[string]ClubJoinRequest)
+		}
+		c.JoinRequests[request.Username] = *request
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: club does not exist, you have already requested to join or you do not have permission to request to join", "Memory conditional check failed", err)
+		}
+		return nil, err
+	}
+	return club, repo.putOutbox(outbox)
+}
+
+// Converts a join request with the given username into a member for the given club. The outbox
+// entries are saved if the request is approved. The club after updating is returned.
+func (repo *memoryRepository) ApproveClubJoinRequest(id, username, caller string, outbox ...*OutboxEntry) (*Club, error) {
+	club, err := updateItem(repo, clubTable, id, "", func(c *Club, exists bool) error {
+		if _, ok := c.JoinRequests[username]; !ok || c.Owner != caller {
+			return conditionalCheckFailed()
+		}
+
+		delete(c.JoinRequests, username)
+		if c.Members == nil {
+			c.Members = make(map[string]ClubMember)
+		}
+		c.Members[username] = ClubMember{Username: username, JoinedAt: time.Now().Format(time.RFC3339)}
+		c.MemberCount++
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: club not found", "Memory conditional check failed", err)
+		}
+		return nil, err
+	}
+	if err := repo.putOutbox(outbox); err != nil {
+		return nil, err
+	}
+
+	if err := repo.AddClubToUser(id, username); err != nil {
+		return nil, err
+	}
+
+	return club, nil
+}
+
+// Deletes the join request with the given username from the given club. The club after updating is returned.
+func (repo *memoryRepository) DeleteClubJoinRequest(id string, username string) (*Club, error) {
+	return updateItem(repo, clubTable, id, "", func(c *Club, _ bool) error {
+		delete(c.JoinRequests, username)
+		return nil
+	})
+}
+
+// Marks the join request with the given club id and username as rejected. The club after updating is returned.
+// The caller must be the owner of the club.
+func (repo *memoryRepository) RejectClubJoinRequest(id, username, caller string) (*Club, error) {
+	club, err := updateItem(repo, clubTable, id, "", func(c *Club, exists bool) error {
+		request, ok := c.JoinRequests[username]
+		if !ok || c.Owner != caller {
+			return conditionalCheckFailed()
+		}
+		request.Status = ClubJoinRequestStatus_Rejected
+		c.JoinRequests[username] = request
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: club not found, request no longer exists or you do not have permission to edit it", "Memory conditional check failed", err)
+		}
+		return nil, err
+	}
+	return club, nil
+}
+
+// Removes the given username as a member from the given club. The user cannot be the owner of the club.
+func (repo *memoryRepository) RemoveClubMember(id string, username string) (*Club, error) {
+	club, err := updateItem(repo, clubTable, id, "", func(c *Club, exists bool) error {
+		if _, ok := c.Members[username]; !ok || c.Owner == username {
+			return conditionalCheckFailed()
+		}
+		delete(c.Members, username)
+		c.MemberCount--
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: club or member not found", "Memory conditional check failed", err)
+		}
+		return nil, err
+	}
+
+	_, err = repo.updateAttributes(userTable, username, "", func(item map[string]*dynamodb.AttributeValue, _ bool) error {
+		updateStringSet(item, "clubs", id, false)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to update user", err)
+	}
+
+	return club, nil
+}
+
+// Adds the given club id to the given user's clubs attribute.
+func (repo *memoryRepository) AddClubToUser(clubId string, username string) error {
+	_, err := repo.updateAttributes(userTable, username, "", func(item map[string]*dynamodb.AttributeValue, _ bool) error {
+		updateStringSet(item, "clubs", clubId, true)
+		return nil
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to update user", err)
+}
+
+// updateStringSet adds or removes the given value from the string set attribute with the given
+// name, equivalent to a DynamoDB ADD or DELETE update expression. As in DynamoDB, the attribute
+// is removed if the set becomes empty.
+func updateStringSet(item map[string]*dynamodb.AttributeValue, name, value string, add bool) {
+	var values []*string
+	if av, ok := item[name]; ok {
+		for _, v := range av.SS {
+			if *v != value {
+				values = append(values, v)
+			}
+		}
+	}
+	if add {
+		values = append(values, aws.String(value))
+	}
+
+	if len(values) == 0 {
+		delete(item, name)
+	} else {
+		item[name] = &dynamodb.AttributeValue{SS: values}
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// CreateDataExport starts exporting the data of the provided username. If an export is
+// already in progress, the existing DataExport is returned instead.
+func (repo *memoryRepository) CreateDataExport(username string) (*DataExport, error) {
+	export := NewDataExport(username)
+	item, err := dynamodbattribute.MarshalMap(export)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal data export", err)
+	}
+
+	stale := time.Now().Add(-dataExportStaleTime).Format(time.RFC3339)
+	err = repo.putItemConditional(dataExportTable, item, func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing != nil &&
+			attributeString(existing["status"]) == string(DataExportStatus_InProgress) &&
+			attributeString(existing["updatedAt"]) >= stale {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	if err != nil {
+		return repo.GetDataExport(username)
+	}
+	return export, nil
+}
+
+// GetDataExport returns the DataExport for the provided username.
+func (repo *memoryRepository) GetDataExport(username string) (*DataExport, error) {
+	export := DataExport{}
+	if err := repo.getItem(dataExportTable, username, "", &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// PutDataExport saves the provided export.
+func (repo *memoryRepository) PutDataExport(export *DataExport) error {
+	return putItem(repo, dataExportTable, export)
+}
+
+// ListYearReviews returns the year reviews of the provided username. The next start key
+// is also returned.
+func (repo *memoryRepository) ListYearReviews(username, startKey string) ([]YearReview, string, error) {
+	input := &memoryQueryInput[YearReview]{
+		match: func(r *YearReview) bool { return r.Username == username },
+	}
+
+	var reviews []YearReview
+	lastKey, err := query(repo, yearReviewTable, input, startKey, &reviews)
+	if err != nil {
+		return nil, "", err
+	}
+	return reviews, lastKey, nil
+}
+
+// PutNotification saves the provided notification.
+func (repo *memoryRepository) PutNotification(notification *Notification) error {
+	return putItem(repo, notificationTable, notification)
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/stripe/stripe-go/v81"
+)
+
+// SetEvent inserts the provided Event into the database. The outbox entries are also saved.
+func (repo *memoryRepository) SetEvent(event *Event, outbox ...*OutboxEntry) error {
+	if event.Id == "STATISTICS" {
+		return errors.New(403, "Invalid request: user does not have permission to set event statistics", "")
+	}
+	if err := putItem(repo, eventTable, event); err != nil {
+		return err
+	}
+	return repo.putOutbox(outbox)
+}
+
+// GetEvent returns the event object with the provided id.
+func (repo *memoryRepository) GetEvent(id string) (*Event, error) {
+	if id == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: user does not have permission to get event statistics", "")
+	}
+
+	event := Event{}
+	if err := repo.getItem(eventTable, id, "", &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// DeleteEvent deletes the event with the given id. The deleted
+// event is returned. An error is returned if it does not exist.
+func (repo *memoryRepository) DeleteEvent(id string) (*Event, error) {
+	if id == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: user does not have permission to delete event statistics", "")
+	}
+
+	item, err := repo.deleteItem(eventTable, id, "", func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing == nil {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(404, "Invalid request: event does not exist", "Memory conditional check failed", err)
+	}
+
+	event := Event{}
+	if err := unmarshalMemoryItem(item, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// BookEvent adds the given user as a participant to the given event.
+// The request only succeeds if the Event is not already fully booked.
+// startTime and aType are only used if the Event is of type EventTypeAvailability
+// and has MaxParticipants set to 1. The outbox entries are saved if the event is booked.
+func (repo *memoryRepository) BookEvent(event *Event, user *User, startTime string, aType AvailabilityType, checkoutSession *stripe.CheckoutSession, outbox ...*OutboxEntry) (*Event, error) {
+	if event.Id == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: event statistics cannot be booked", "")
+	}
+
+	participant := &Participant{
+		Username:        user.Username,
+		DisplayName:     user.DisplayName,
+		Cohort:          user.DojoCohort,
+		PreviousCohort:  user.PreviousCohort,
+		CheckoutSession: checkoutSession,
+	}
+
+	e, err := updateItem(repo, eventTable, event.Id, "", func(e *Event, exists bool) error {
+		if !exists || e.Status != SchedulingStatus_Scheduled || len(e.Participants) >= event.MaxParticipants {
+			return conditionalCheckFailed()
+		}
+
+		if e.Participants == nil {
+			e.Participants = make(map[string]*Participant)
+		}
+		e.Participants[user.Username] = participant
+
+		if len(event.Participants) == event.MaxParticipants-1 {
+			e.Status = SchedulingStatus_Booked
+			e.DiscordMessageId = ""
+		}
+		if event.Type == EventType_Availability && event.MaxParticipants == 1 {
+			e.BookedStartTime = startTime
+			e.BookedType = aType
+		}
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: event no longer exists or is already fully booked", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return e, repo.putOutbox(outbox)
+}
+
+// Updates the given event so that the participant with the given username is marked as paid. The participant's
+// hasPaid attribute is set to true and their checkoutSession is set to the provided checkoutSession.
+func (repo *memoryRepository) MarkParticipantPaid(eventId, participant string, checkoutSession *stripe.CheckoutSession) (*Event, error) {
+	e, err := updateItem(repo, eventTable, eventId, "", func(e *Event, exists bool) error {
+		if !exists || e.Participants[participant] == nil {
+			return conditionalCheckFailed()
+		}
+		e.Participants[participant].HasPaid = true
+		e.Participants[participant].CheckoutSession = checkoutSession
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: event no longer exists or participant does not exist", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// LeaveEvent leaves the event for the given participants. If participant is nil,
+// then the person leaving is the owner. In that case, the first participant is made
+// the new owner. The updated event is returned.
+func (repo *memoryRepository) LeaveEvent(event *Event, participant *Participant, requireNoPayment bool) (*Event, error) {
+	if event.Id == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: event statistics cannot be canceled", "")
+	}
+	if len(event.Participants) == 0 {
+		return nil, errors.New(400, "Invalid request: event does not have any participants. Delete it instead", "")
+	}
+
+	newOwner := participant == nil
+	if newOwner {
+		for _, p := range event.Participants {
+			participant = p
+			break
+		}
+	}
+
+	e, err := updateItem(repo, eventTable, event.Id, "", func(e *Event, exists bool) error {
+		if !exists || e.Owner != event.Owner {
+			return conditionalCheckFailed()
+		}
+		existing := e.Participants[participant.Username]
+		if existing == nil || (requireNoPayment && existing.HasPaid) {
+			return conditionalCheckFailed()
+		}
+
+		e.Status = SchedulingStatus_Scheduled
+		e.BookedStartTime = ""
+		e.BookedType = ""
+		if newOwner {
+			e.Owner = participant.Username
+			e.OwnerDisplayName = participant.DisplayName
+			e.OwnerCohort = participant.Cohort
+			e.OwnerPreviousCohort = participant.PreviousCohort
+		}
+		delete(e.Participants, participant.Username)
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: event no longer exists or has changed. Please try again.", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// CancelEvent marks the provided Event as canceled.
+func (repo *memoryRepository) CancelEvent(event *Event) (*Event, error) {
+	if event.Id == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: event statistics cannot be canceled", "")
+	}
+
+	e, err := updateItem(repo, eventTable, event.Id, "", func(e *Event, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		e.Status = SchedulingStatus_Canceled
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: event not found.", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// ScanEvents returns a list of all Events in the database, up to one page of data.
+// startKey is an optional parameter that can be used to perform pagination.
+// The list of meetings and the next start key are returned.
+func (repo *memoryRepository) ScanEvents(public bool, startKey string) ([]*Event, string, error) {
+	input := &memoryQueryInput[Event]{
+		filter: func(e *Event) bool {
+			return e.Id != "STATISTICS" && (!public || e.Type != EventType_Availability)
+		},
+	}
+
+	var events []*Event
+	lastKey, err := query(repo, eventTable, input, startKey, &events)
+	if err != nil {
+		return nil, "", err
+	}
+	return events, lastKey, nil
+}
+
+// CreateEventMessage adds the given message to the event with the given id. The owner included in the
+// message must be a participant of the event and must have completed payment, if the event is a coaching
+// session.
+func (repo *memoryRepository) CreateEventMessage(id string, message *Comment) (*Event, error) {
+	event, err := updateItem(repo, eventTable, id, "", func(e *Event, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		if e.Owner != message.Owner {
+			p := e.Participants[message.Owner]
+			if p == nil || (e.Type == EventType_Coaching && !p.HasPaid) {
+				return conditionalCheckFailed()
+			}
+		}
+		e.Messages = append(e.Messages, *message)
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: event does not exist or you do not have permission to create a message", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetGameReviewCohort returns the game review cohort with the provided id.
+func (repo *memoryRepository) GetGameReviewCohort(id string) (*GameReviewCohort, error) {
+	var output GameReviewCohort
+	err := repo.getItem(liveClassesTable, "GAME_REVIEW_COHORT", id, &output)
+	return &output, err
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// GetGameStatistics returns the cached game statistics of the provided user.
+func (repo *memoryRepository) GetGameStatistics(username string) (*GameStatistics, error) {
+	stats := GameStatistics{}
+	if err := repo.getItem(gameStatisticsTable, username, "", &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// PutGameStatistics saves the provided game statistics, replacing any existing statistics.
+func (repo *memoryRepository) PutGameStatistics(stats *GameStatistics) error {
+	return putItem(repo, gameStatisticsTable, stats)
+}
+
+// SetGameStatisticsPlayers updates the GameStatisticsPlayers of the provided user when
+// their player names change from oldNames to newNames.
+func (repo *memoryRepository) SetGameStatisticsPlayers(username string, oldNames, newNames []string) error {
+	put, del := GameStatisticsPlayerChanges(username, oldNames, newNames)
+	if _, err := putItems(repo, gameStatisticsPlayersTable, put); err != nil {
+		return err
+	}
+	for _, p := range del {
+		if _, err := repo.deleteItem(gameStatisticsPlayersTable, p.Player, p.Username, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListGameStatisticsUsers returns the usernames of the users with cached game statistics
+// which include the provided lowercase player name.
+func (repo *memoryRepository) ListGameStatisticsUsers(player string) ([]string, error) {
+	input := &memoryQueryInput[GameStatisticsPlayer]{
+		match: func(p *GameStatisticsPlayer) bool { return p.Player == player },
+	}
+
+	var usernames []string
+	for p, err := range All(context.Background(), func(startKey string) ([]GameStatisticsPlayer, string, error) {
+		var players []GameStatisticsPlayer
+		lastKey, err := query(repo, gameStatisticsPlayersTable, input, startKey, &players)
+		return players, lastKey, err
+	}) {
+		if err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, p.Username)
+	}
+	return usernames, nil
+}
+
+// UpdateGameStatistics adds the provided deltas, keyed by username and then by count key, to
+// the counts of the users' game statistics. The updates are made in one transaction which also
+// saves an IdempotencyRecord for the stream record with the provided event ID, so a record which
+// was already applied is skipped. A 404 error is returned if one of the users has no statistics.
+func (repo *memoryRepository) UpdateGameStatistics(eventID string, deltas map[string]map[string]int) error {
+	if len(deltas) > gameStatisticsMaxUpdate {
+		return errors.New(500, "Temporary server error", fmt.Sprintf("UpdateGameStatistics got %d users, more than the maximum of %d", len(deltas), gameStatisticsMaxUpdate))
+	}
+
+	updatedAt := time.Now().Format(time.RFC3339)
+	usernames := slices.Sorted(maps.Keys(deltas))
+	writes := []memoryWrite{streamRecordWrite(gameStatisticsConsumer, eventID)}
+	for _, username := range usernames {
+		writes = append(writes, memoryWrite{
+			table: gameStatisticsTable,
+			hash:  username,
+			fn: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				if item == nil {
+					return nil, conditionalCheckFailed()
+				}
+				var stats GameStatistics
+				if err := unmarshalMemoryItem(item, &stats); err != nil {
+					return nil, err
+				}
+				if stats.Counts == nil {
+					stats.Counts = make(map[string]int)
+				}
+				for key, delta := range deltas[username] {
+					stats.Counts[key] += delta
+				}
+				stats.UpdatedAt = updatedAt
+
+				result, err := dynamodbattribute.MarshalMap(stats)
+				if err != nil {
+					return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal memory item", err)
+				}
+				return result, nil
+			},
+		})
+	}
+	return gameStatisticsUpdateError(repo.transactWrite(writes...), usernames)
+}
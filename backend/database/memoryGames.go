@@ -0,0 +1,445 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// GetGame returns the game object with the provided cohort and id.
+func (repo *memoryRepository) GetGame(cohort, id string) (*Game, error) {
+	game := Game{}
+	if err := repo.getItem(gameTable, cohort, id, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// DeleteGame removes the specified game from the database, if the game
+// is owned by the calling user.
+func (repo *memoryRepository) DeleteGame(username, cohort, id string) (*Game, error) {
+	item, err := repo.deleteItem(gameTable, cohort, id, func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing == nil || attributeString(existing["owner"]) != username {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(400, "Invalid request: game does not exist or you do not have permission to delete it", "Memory conditional check failed", err)
+	}
+
+	game := Game{}
+	if err := unmarshalMemoryItem(item, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// BatchPutGames inserts the provided list of games into the database. The number of
+// successfully inserted games is returned.
+func (repo *memoryRepository) BatchPutGames(games []*Game) (int, error) {
+	return putItems(repo, gameTable, games)
+}
+
+// inDateRange returns true if the provided game id is within the provided start and end dates,
+// using the same bounds as addDates.
+func inDateRange(id, startDate, endDate string) bool {
+	if startDate != "" && id < startDate+"_00000000-0000-0000-0000-000000000000" {
+		return false
+	}
+	if endDate != "" && id > endDate+"_ffffffff-ffff-ffff-ffff-ffffffffffff" {
+		return false
+	}
+	return true
+}
+
+// gameId returns the id of the provided game, for use as a memoryQueryInput sort key.
+func gameId(g *Game) string {
+	return g.Id
+}
+
+// ListGamesByCohort returns a list of Games matching the provided cohort. The PGN text is excluded and must be
+// fetched separately with a call to GetGame.
+func (repo *memoryRepository) ListGamesByCohort(cohort, startDate, endDate, startKey string) ([]*Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		match: func(g *Game) bool {
+			return string(g.Cohort) == cohort && inDateRange(g.Id, startDate, endDate)
+		},
+		filter: func(g *Game) bool {
+			return g.Owner != "model_games" && g.Owner != "games_to_memorize" && !g.Unlisted
+		},
+		sortKey:    gameId,
+		descending: true,
+		projection: []string{"cohort", "id", "white", "black", "date", "createdAt", "updatedAt", "publishedAt", "owner", "ownerDisplayName", "headers"},
+	}
+
+	var games []*Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ListGamesByOwner returns a list of Games matching the provided owner. The PGN text is excluded and must be
+// fetched separately with a call to GetGame. Unlisted games are not included, unless isOwner is true.
+func (repo *memoryRepository) ListGamesByOwner(isOwner bool, owner, startDate, endDate, startKey string) ([]*Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		indexKeys: []string{"owner"},
+		match: func(g *Game) bool {
+			return g.Owner == owner && inDateRange(g.Id, startDate, endDate)
+		},
+		sortKey:    gameId,
+		descending: true,
+	}
+	if !isOwner {
+		input.filter = func(g *Game) bool { return !g.Unlisted }
+	}
+
+	var games []*Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ListGamesByPlayer returns a list of Games matching the provided player. The PGN text is excluded and must
+// be fetched separately with a call to GetGame.
+func (repo *memoryRepository) ListGamesByPlayer(player string, color PlayerColor, startDate, endDate, startKey string) ([]*Game, string, error) {
+	player = strings.ToLower(strings.TrimSpace(player))
+
+	startKeys := listGamesByPlayerStartKey{}
+	if startKey != "" {
+		if err := json.Unmarshal([]byte(startKey), &startKeys); err != nil {
+			return nil, "", errors.Wrap(400, "Invalid request: startKey is not valid", "startKey could not be unmarshaled", err)
+		}
+	}
+
+	lastKeys := listGamesByPlayerStartKey{}
+	games := make([]*Game, 0)
+
+	if (color == White || color == Either) && (startKey == "" || startKeys.WhiteKey != "") {
+		whiteGames, whiteKey, err := repo.listColorGames(player, White, startDate, endDate, startKeys.WhiteKey)
+		if err != nil {
+			return nil, "", err
+		}
+		games = append(games, whiteGames...)
+		lastKeys.WhiteKey = whiteKey
+	}
+
+	if (color == Black || color == Either) && (startKey == "" || startKeys.BlackKey != "") {
+		blackGames, blackKey, err := repo.listColorGames(player, Black, startDate, endDate, startKeys.BlackKey)
+		if err != nil {
+			return nil, "", err
+		}
+		games = append(games, blackGames...)
+		lastKeys.BlackKey = blackKey
+	}
+
+	var lastKey string
+	if lastKeys.WhiteKey != "" || lastKeys.BlackKey != "" {
+		b, err := json.Marshal(&lastKeys)
+		if err != nil {
+			return nil, "", errors.Wrap(500, "Temporary server error", "Failed to marshal listGamesByPlayerStartKey", err)
+		}
+		lastKey = string(b)
+	}
+
+	sort.Sort(byDate(games))
+
+	return games, lastKey, nil
+}
+
+func (repo *memoryRepository) listColorGames(player string, color PlayerColor, startDate, endDate, startKey string) ([]*Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		indexKeys: []string{string(color)},
+		match: func(g *Game) bool {
+			value := g.White
+			if color == Black {
+				value = g.Black
+			}
+			return value == player && inDateRange(g.Id, startDate, endDate)
+		},
+		filter:     func(g *Game) bool { return !g.Unlisted },
+		sortKey:    gameId,
+		descending: true,
+	}
+
+	var games []*Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ListFeaturedGames returns a list of Games featured more recently than the provided date.
+func (repo *memoryRepository) ListFeaturedGames(date, startKey string) ([]*Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		indexKeys: []string{"isFeatured", "featuredAt"},
+		match: func(g *Game) bool {
+			return g.IsFeatured == "true" && g.FeaturedAt >= date
+		},
+		filter:  func(g *Game) bool { return !g.Unlisted },
+		sortKey: func(g *Game) string { return g.FeaturedAt },
+	}
+
+	var games []*Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ListGamesByEco returns a list of Games matching the provided ECO. The PGN text is excluded and must be
+// fetched separately with a call to GetGame.
+func (repo *memoryRepository) ListGamesByEco(eco, startDate, endDate, startKey string) ([]*Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		indexKeys: []string{"owner"},
+		match:     func(g *Game) bool { return g.Owner != "" },
+		filter: func(g *Game) bool {
+			return g.Headers["ECO"] == eco && !g.Unlisted && inDateRange(g.Id, startDate, endDate)
+		},
+	}
+
+	var games []*Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ListGamesForReview returns a list of games that have been submitted for review by
+// the senseis.
+func (repo *memoryRepository) ListGamesForReview(startKey string) ([]Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		indexKeys: []string{"reviewStatus", "reviewRequestedAt"},
+		match:     func(g *Game) bool { return g.ReviewStatus == GameReviewStatus_Pending },
+		sortKey:   func(g *Game) string { return g.ReviewRequestedAt },
+	}
+
+	var games []Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// ScanCohort returns a list of all Games in the provided cohort, including the PGN text.
+func (repo *memoryRepository) ScanCohort(cohort DojoCohort, startKey string) ([]*Game, string, error) {
+	input := &memoryQueryInput[Game]{
+		match:   func(g *Game) bool { return g.Cohort == cohort },
+		sortKey: gameId,
+	}
+
+	var games []*Game
+	lastKey, err := query(repo, gameTable, input, startKey, &games)
+	if err != nil {
+		return nil, "", err
+	}
+	return games, lastKey, nil
+}
+
+// commentReplies returns the map containing the comment with the given parent ids in the given
+// position comments map, following the replies of each parent in order. If create is true, a
+// missing replies map on a parent is created. If a parent does not exist, nil is returned.
+func commentReplies(comments map[string]PositionComment, parentIds string, create bool) map[string]PositionComment {
+	if comments == nil || parentIds == "" {
+		return comments
+	}
+
+	for _, id := range strings.Split(parentIds, ",") {
+		parent, ok := comments[id]
+		if !ok {
+			return nil
+		}
+		if parent.Replies == nil {
+			if !create {
+				return nil
+			}
+			parent.Replies = make(map[string]PositionComment)
+			comments[id] = parent
+		}
+		comments = parent.Replies
+	}
+	return comments
+}
+
+// PutComment puts the provided comment in the provided Game's position comments.
+// If skipMapCreation is true, then the comment map for the position must already exist.
+// The outbox entries are saved if the comment is added.
+func (repo *memoryRepository) PutComment(cohort, id string, comment *PositionComment, skipMapCreation bool, outbox ...*OutboxEntry) (*Game, error) {
+	fen, err := normalizeCommentFen(comment.Fen)
+	if err != nil {
+		return nil, err
+	}
+	comment.Fen = fen
+
+	game, err := updateItem(repo, gameTable, cohort, id, func(g *Game, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+
+		if g.PositionComments[comment.Fen] == nil {
+			if skipMapCreation {
+				return conditionalCheckFailed()
+			}
+			if g.PositionComments == nil {
+				g.PositionComments = make(map[string]map[string]PositionComment)
+			}
+			g.PositionComments[comment.Fen] = map[string]PositionComment{comment.Id: *comment}
+			return nil
+		}
+
+		replies := commentReplies(g.PositionComments[comment.Fen], comment.ParentIds, true)
+		if replies == nil {
+			return errors.New(500, "Temporary server error", "Memory UpdateItem failure: comment parent does not exist")
+		}
+		replies[comment.Id] = *comment
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: game does not exist", "Memory UpdateItem failure", aerr)
+		}
+		return nil, err
+	}
+	return game, repo.putOutbox(outbox)
+}
+
+// updatePositionComment applies fn to the map containing the comment indicated by the given update.
+// fn is only called if the comment exists and is owned by the given owner.
+func (repo *memoryRepository) updatePositionComment(owner string, update *PositionCommentUpdate, fn func(comments map[string]PositionComment)) (*Game, error) {
+	fen := existingCommentFen(update.Fen)
+
+	return updateItem(repo, gameTable, string(update.Cohort), update.GameId, func(g *Game, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+
+		comments := commentReplies(g.PositionComments[fen], update.ParentIds, false)
+		comment, ok := comments[update.Id]
+		if !ok || comment.Owner.Username != owner {
+			return conditionalCheckFailed()
+		}
+		fn(comments)
+		return nil
+	})
+}
+
+// UpdateComment applies the given position comment update to the database. The game after update is returned.
+func (repo *memoryRepository) UpdateComment(owner string, update *PositionCommentUpdate) (*Game, error) {
+	game, err := repo.updatePositionComment(owner, update, func(comments map[string]PositionComment) {
+		comment := comments[update.Id]
+		comment.Content = update.Content
+		comment.SuggestedVariation = update.SuggestedVariation
+		comment.UpdatedAt = time.Now().Format(time.RFC3339)
+		comments[update.Id] = comment
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: comment does not exist or you do not have permission to edit it", "Memory UpdateItem failure", aerr)
+		}
+		return nil, err
+	}
+	return game, nil
+}
+
+// DeleteComment deletes the position comment indicated by the given update, including
+// any replies to the comment. The updated game is returned.
+func (repo *memoryRepository) DeleteComment(owner string, update *PositionCommentUpdate) (*Game, error) {
+	game, err := repo.updatePositionComment(owner, update, func(comments map[string]PositionComment) {
+		delete(comments, update.Id)
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: comment does not exist or you do not have permission to delete it", "Memory UpdateItem failure", aerr)
+		}
+		return nil, err
+	}
+	return game, nil
+}
+
+// SetPositionComments replaces the position comments of the provided game with comments,
+// if its stored position comments are still equal to old. Otherwise, a 409 error whose
+// cause is an *errors.ConflictError is returned, and the game must be read again.
+func (repo *memoryRepository) SetPositionComments(cohort, id string, old, comments map[string]map[string]PositionComment) error {
+	oldAv, err := dynamodbattribute.Marshal(old)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal old position comments", err)
+	}
+	fillCommentReplies(comments)
+	av, err := dynamodbattribute.Marshal(comments)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal position comments", err)
+	}
+
+	_, err = repo.updateAttributes(gameTable, cohort, id, func(item map[string]*dynamodb.AttributeValue, exists bool) error {
+		if !exists || !reflect.DeepEqual(item["positionComments"], oldAv) {
+			return conditionalCheckFailed()
+		}
+		item["positionComments"] = av
+		return nil
+	})
+	if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+		return errors.NewConflict("Invalid request: the game's comments were changed or the game was deleted", "Memory UpdateItem failure", cohort+"/"+id)
+	}
+	return err
+}
+
+// UpdateGame applies the specified update to the specified game.
+func (repo *memoryRepository) UpdateGame(cohort, id string, update *GameUpdate) (*Game, error) {
+	av, err := dynamodbattribute.MarshalMap(update)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal user update", err)
+	}
+
+	item, err := repo.updateAttributes(gameTable, cohort, id, func(item map[string]*dynamodb.AttributeValue, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		for k, v := range av {
+			item[k] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(400, "Invalid request: game not found or you do not have permission to update it", "Memory conditional check failed", err)
+	}
+
+	game := Game{}
+	if err := unmarshalMemoryItem(item, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// Sets the provided game review on the provided game. The outbox entries are saved if the
+// review is set.
+func (repo *memoryRepository) SetGameReview(cohort, id string, review *GameReview, outbox ...*OutboxEntry) (*Game, error) {
+	game, err := updateItem(repo, gameTable, cohort, id, func(g *Game, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		g.ReviewStatus = ""
+		g.Review = review
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: game not found", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return game, repo.putOutbox(outbox)
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// CreateIdempotencyRecord saves the provided record if no unexpired record with the same
+// key exists. If one does, it is returned and the provided record is not saved. Otherwise,
+// nil is returned.
+func (repo *memoryRepository) CreateIdempotencyRecord(record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	item, err := dynamodbattribute.MarshalMap(record)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal idempotency record", err)
+	}
+
+	var existing *IdempotencyRecord
+	err = repo.putItemConditional(idempotencyTable, item, func(current map[string]*dynamodb.AttributeValue) error {
+		if current == nil {
+			return nil
+		}
+		existing = &IdempotencyRecord{}
+		if err := unmarshalMemoryItem(current, existing); err != nil {
+			return err
+		}
+		if existing.ExpirationTime < time.Now().Unix() {
+			existing = nil
+			return nil
+		}
+		return conditionalCheckFailed()
+	})
+	if existing != nil {
+		return existing, nil
+	}
+	return nil, err
+}
+
+// PutIdempotencyRecord saves the provided record, replacing any existing record with
+// the same key.
+func (repo *memoryRepository) PutIdempotencyRecord(record *IdempotencyRecord) error {
+	return putItem(repo, idempotencyTable, record)
+}
+
+// DeleteIdempotencyRecord removes the record with the provided key. Deleting a record
+// which does not exist is not an error.
+func (repo *memoryRepository) DeleteIdempotencyRecord(key string) error {
+	_, err := repo.deleteItem(idempotencyTable, key, "", nil)
+	return err
+}
+
+// streamRecordWrite returns a memoryWrite which saves the IdempotencyRecord of the provided
+// stream record. The transaction is cancelled if the record was already applied.
+func streamRecordWrite(consumer, eventID string) memoryWrite {
+	record := newStreamRecord(consumer, eventID)
+	return memoryWrite{
+		table: idempotencyTable,
+		hash:  record.Key,
+		fn: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			if item != nil {
+				return nil, conditionalCheckFailed()
+			}
+			result, err := dynamodbattribute.MarshalMap(record)
+			if err != nil {
+				return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal memory item", err)
+			}
+			return result, nil
+		},
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// outboxMemoryWrites returns the memory transaction writes which save the provided entries.
+func outboxMemoryWrites(entries []*OutboxEntry) ([]memoryWrite, error) {
+	writes := make([]memoryWrite, 0, len(entries))
+	for _, entry := range entries {
+		item, err := dynamodbattribute.MarshalMap(entry)
+		if err != nil {
+			return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal outbox entry", err)
+		}
+		writes = append(writes, memoryWrite{
+			table: outboxTable,
+			hash:  string(entry.Status),
+			rng:   entry.Id,
+			fn: func(existing map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				if existing != nil {
+					return nil, conditionalCheckFailed()
+				}
+				return item, nil
+			},
+		})
+	}
+	return writes, nil
+}
+
+// putOutbox saves the provided entries. It is called after the write which generated the
+// entries succeeds. Unlike DynamoDB, the memory repository cannot fail between the two writes,
+// so this is equivalent to saving the entries in the same transaction.
+func (repo *memoryRepository) putOutbox(entries []*OutboxEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	writes, err := outboxMemoryWrites(entries)
+	if err != nil {
+		return err
+	}
+	return repo.transactWrite(writes...)
+}
+
+// ListOutboxEntries returns the entries with the provided status, oldest first.
+// The next start key is also returned.
+func (repo *memoryRepository) ListOutboxEntries(status OutboxStatus, startKey string) ([]OutboxEntry, string, error) {
+	input := &memoryQueryInput[OutboxEntry]{
+		match:   func(e *OutboxEntry) bool { return e.Status == status },
+		sortKey: func(e *OutboxEntry) string { return e.Id },
+	}
+
+	var entries []OutboxEntry
+	lastKey, err := query(repo, outboxTable, input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// DeleteOutboxEntry removes the provided entry after it has been sent. Deleting an entry
+// which no longer exists is not an error.
+func (repo *memoryRepository) DeleteOutboxEntry(entry *OutboxEntry) error {
+	_, err := repo.deleteItem(outboxTable, string(entry.Status), entry.Id, nil)
+	return err
+}
+
+// RecordOutboxFailure increments the attempts of the provided pending entry and saves the
+// cause as its last error. Once the entry reaches MaxOutboxAttempts, it is moved to
+// OutboxStatus_Failed. The entry after updating is returned.
+func (repo *memoryRepository) RecordOutboxFailure(entry *OutboxEntry, cause error) (*OutboxEntry, error) {
+	updated := *entry
+	updated.Attempts++
+	updated.LastError = cause.Error()
+	updated.UpdatedAt = time.Now().Format(time.RFC3339)
+	if updated.Attempts >= MaxOutboxAttempts {
+		updated.Status = OutboxStatus_Failed
+	}
+
+	if err := repo.moveOutboxEntry(entry, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ReplayOutboxEntry moves the failed entry with the provided id back to OutboxStatus_Pending
+// with its attempts reset, so that the relay sends it again. The entry after updating is returned.
+func (repo *memoryRepository) ReplayOutboxEntry(id string) (*OutboxEntry, error) {
+	entry := OutboxEntry{}
+	if err := repo.getItem(outboxTable, string(OutboxStatus_Failed), id, &entry); err != nil {
+		return nil, err
+	}
+
+	updated := entry
+	updated.Status = OutboxStatus_Pending
+	updated.Attempts = 0
+	updated.UpdatedAt = time.Now().Format(time.RFC3339)
+	if err := repo.moveOutboxEntry(&entry, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// moveOutboxEntry replaces the entry from with the entry to in a single transaction. The status
+// of the entries may differ. A 409 error is returned if from was changed or removed after it was read.
+func (repo *memoryRepository) moveOutboxEntry(from, to *OutboxEntry) error {
+	item, err := dynamodbattribute.MarshalMap(to)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal outbox entry", err)
+	}
+
+	checkFrom := func(existing map[string]*dynamodb.AttributeValue) error {
+		var current OutboxEntry
+		if existing == nil {
+			return conditionalCheckFailed()
+		}
+		if err := dynamodbattribute.UnmarshalMap(existing, &current); err != nil {
+			return err
+		}
+		if current.Attempts != from.Attempts {
+			return conditionalCheckFailed()
+		}
+		return nil
+	}
+
+	writes := []memoryWrite{{
+		table: outboxTable,
+		hash:  string(to.Status),
+		rng:   to.Id,
+		fn: func(existing map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			if from.Status == to.Status {
+				if err := checkFrom(existing); err != nil {
+					return nil, err
+				}
+			} else if existing != nil {
+				return nil, conditionalCheckFailed()
+			}
+			return item, nil
+		},
+	}}
+	if from.Status != to.Status {
+		writes = append([]memoryWrite{{
+			table: outboxTable,
+			hash:  string(from.Status),
+			rng:   from.Id,
+			fn: func(existing map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				return nil, checkFrom(existing)
+			},
+		}}, writes...)
+	}
+
+	if err := repo.transactWrite(writes...); err != nil {
+		if _, ok := err.(*dynamodb.TransactionCanceledException); ok {
+			return errors.NewConflict("Invalid request: outbox entry was sent or updated by another request",
+				"Memory outbox transaction condition failed", from.Id)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package database
+
+import "context"
+
+// PutRatingDistributions saves the provided rating distributions, replacing the existing
+// distributions of their rating systems. The number of distributions saved is returned.
+func (repo *memoryRepository) PutRatingDistributions(distributions []*RatingDistribution) (int, error) {
+	return putItems(repo, ratingDistributionTable, distributions)
+}
+
+// ListRatingDistributions returns the saved distribution of each rating system.
+func (repo *memoryRepository) ListRatingDistributions() ([]RatingDistribution, error) {
+	input := &memoryQueryInput[RatingDistribution]{}
+	return Collect(context.Background(), func(startKey string) ([]RatingDistribution, string, error) {
+		var distributions []RatingDistribution
+		lastKey, err := query(repo, ratingDistributionTable, input, startKey, &distributions)
+		return distributions, lastKey, err
+	})
+}
@@ -0,0 +1,37 @@
+package database
+
+import "context"
+
+// PutRatingHistory saves the provided rating history points. The number of points
+// saved is returned.
+func (repo *memoryRepository) PutRatingHistory(points []RatingHistoryPoint) (int, error) {
+	return putItems(repo, ratingHistoryTable, dedupeRatingHistory(points))
+}
+
+// ListRatingHistory returns the rating history of the provided user and rating system
+// between startDate and endDate, inclusive, sorted by date. Dates are in time.DateOnly
+// format and either may be empty.
+func (repo *memoryRepository) ListRatingHistory(username string, system RatingSystem, startDate, endDate string) ([]RatingHistory, error) {
+	return listRatingHistory(func(kind, startDate, endDate string) ([]RatingHistoryPoint, error) {
+		lower, upper := ratingHistoryKeyRange(system, kind, startDate, endDate)
+		input := &memoryQueryInput[RatingHistoryPoint]{
+			match: func(p *RatingHistoryPoint) bool {
+				return p.Username == username && p.SortKey >= lower && p.SortKey <= upper
+			},
+			sortKey: func(p *RatingHistoryPoint) string { return p.SortKey },
+		}
+
+		var result []RatingHistoryPoint
+		for p, err := range All(context.Background(), func(startKey string) ([]RatingHistoryPoint, string, error) {
+			var points []RatingHistoryPoint
+			lastKey, err := query(repo, ratingHistoryTable, input, startKey, &points)
+			return points, lastKey, err
+		}) {
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, p)
+		}
+		return result, nil
+	}, startDate, endDate)
+}
@@ -0,0 +1,30 @@
+package database
+
+// GetRatingUpdate returns the RatingUpdate with the provided id.
+func (repo *memoryRepository) GetRatingUpdate(id string) (*RatingUpdate, error) {
+	update := RatingUpdate{}
+	if err := repo.getItem(ratingUpdateTable, id, "", &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// ListRatingUpdates returns the RatingUpdates which are in progress. The next start key
+// is also returned.
+func (repo *memoryRepository) ListRatingUpdates(startKey string) ([]RatingUpdate, string, error) {
+	input := &memoryQueryInput[RatingUpdate]{
+		filter: func(u *RatingUpdate) bool { return u.Status == RatingUpdateStatus_InProgress },
+	}
+
+	var updates []RatingUpdate
+	lastKey, err := query(repo, ratingUpdateTable, input, startKey, &updates)
+	if err != nil {
+		return nil, "", err
+	}
+	return updates, lastKey, nil
+}
+
+// PutRatingUpdate saves the provided RatingUpdate.
+func (repo *memoryRepository) PutRatingUpdate(update *RatingUpdate) error {
+	return putItem(repo, ratingUpdateTable, update)
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// ListRepertoireMoves returns the moves the provided user has played from the position
+// with the provided normalized FEN, in both colors. Moves which are no longer in any of
+// the user's games are excluded.
+func (repo *memoryRepository) ListRepertoireMoves(username, fen string) ([]RepertoireMove, error) {
+	prefix := repertoirePositionPrefix(fen)
+	input := &memoryQueryInput[RepertoireMove]{
+		match: func(m *RepertoireMove) bool {
+			return m.Username == username && strings.HasPrefix(m.SortKey, prefix)
+		},
+		filter:  func(m *RepertoireMove) bool { return m.Games > 0 },
+		sortKey: func(m *RepertoireMove) string { return m.SortKey },
+	}
+
+	var result []RepertoireMove
+	for m, err := range All(context.Background(), func(startKey string) ([]RepertoireMove, string, error) {
+		var moves []RepertoireMove
+		lastKey, err := query(repo, repertoireTable, input, startKey, &moves)
+		return moves, lastKey, err
+	}) {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// GetRepertoireGame returns the RepertoireGame of the game with the provided cohort and id
+// owned by the provided user. A 404 error is returned if the game has none.
+func (repo *memoryRepository) GetRepertoireGame(username, cohort, id string) (*RepertoireGame, error) {
+	game := RepertoireGame{}
+	if err := repo.getItem(repertoireTable, username, repertoireGameSortKey(cohort, id), &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// UpdateRepertoire adds the counts of the provided moves to the counts of the existing
+// moves in the repertoire table, creating them if necessary, and saves and deletes the
+// provided RepertoireGames. The changes are made in one transaction which also saves an
+// IdempotencyRecord for the stream record with the provided event ID, so a record which was
+// already applied is skipped. At most repertoireMaxUpdate distinct moves and games can be
+// updated at once.
+func (repo *memoryRepository) UpdateRepertoire(eventID string, moves []RepertoireMove, put, del []RepertoireGame) error {
+	moves = MergeRepertoireMoves(moves)
+	if len(moves)+len(put)+len(del) > repertoireMaxUpdate {
+		return errors.New(500, "Temporary server error", fmt.Sprintf("UpdateRepertoire got %d moves and games, more than the maximum of %d", len(moves)+len(put)+len(del), repertoireMaxUpdate))
+	}
+
+	writes := []memoryWrite{streamRecordWrite(repertoireConsumer, eventID)}
+	for _, m := range moves {
+		writes = append(writes, memoryWrite{
+			table: repertoireTable,
+			hash:  m.Username,
+			rng:   m.SortKey,
+			fn: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				updated := m
+				if item != nil {
+					var existing RepertoireMove
+					if err := dynamodbattribute.UnmarshalMap(item, &existing); err != nil {
+						return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal memory item", err)
+					}
+					updated.Games += existing.Games
+					updated.Wins += existing.Wins
+					updated.Draws += existing.Draws
+					updated.Losses += existing.Losses
+				}
+
+				result, err := dynamodbattribute.MarshalMap(updated)
+				if err != nil {
+					return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal memory item", err)
+				}
+				return result, nil
+			},
+		})
+	}
+	for _, g := range put {
+		writes = append(writes, memoryWrite{
+			table: repertoireTable,
+			hash:  g.Username,
+			rng:   g.SortKey,
+			fn: func(map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				result, err := dynamodbattribute.MarshalMap(g)
+				if err != nil {
+					return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal memory item", err)
+				}
+				return result, nil
+			},
+		})
+	}
+	for _, g := range del {
+		writes = append(writes, memoryWrite{
+			table: repertoireTable,
+			hash:  g.Username,
+			rng:   g.SortKey,
+			fn: func(map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				return nil, nil
+			},
+		})
+	}
+
+	err := repo.transactWrite(writes...)
+	if transactionCancellationCode(err, 0) == conditionalCheckFailedReason {
+		return nil
+	}
+	return err
+}
+
+// PutRepertoireMoves saves the provided moves, replacing the counts of any existing
+// moves. The number of moves saved is returned.
+func (repo *memoryRepository) PutRepertoireMoves(moves []RepertoireMove) (int, error) {
+	return putItems(repo, repertoireTable, MergeRepertoireMoves(moves))
+}
+
+// PutRepertoireGames saves the provided RepertoireGames, replacing any existing games. The
+// number of games saved is returned.
+func (repo *memoryRepository) PutRepertoireGames(games []RepertoireGame) (int, error) {
+	return putItems(repo, repertoireTable, games)
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// ListRequirements fetches a list of requirements matching the provided cohort. If scoreboardOnly is true, then
+// only requirements which should be displayed on the scoreboard will be returned. The next start key is returned
+// as well.
+func (repo *memoryRepository) ListRequirements(cohort DojoCohort, scoreboardOnly bool, startKey string) ([]*Requirement, string, error) {
+	if !scoreboardOnly {
+		return repo.ScanRequirements(cohort, startKey)
+	}
+
+	input := &memoryQueryInput[Requirement]{
+		match: func(r *Requirement) bool { return r.Status == Active },
+		filter: func(r *Requirement) bool {
+			_, ok := r.Counts[cohort]
+			return r.ScoreboardDisplay != Hidden && ok
+		},
+	}
+
+	var requirements []*Requirement
+	lastKey, err := query(repo, requirementTable, input, startKey, &requirements)
+	if err != nil {
+		return nil, "", err
+	}
+	return requirements, lastKey, nil
+}
+
+// ScanRequirements returns a list of requirements matching the provided cohort. Archived requirements and requirements
+// hidden from the scoreboard are returned.
+func (repo *memoryRepository) ScanRequirements(cohort DojoCohort, startKey string) ([]*Requirement, string, error) {
+	input := &memoryQueryInput[Requirement]{}
+	if cohort != "" {
+		input.filter = func(r *Requirement) bool {
+			_, ok := r.Counts[cohort]
+			return ok
+		}
+	}
+
+	var requirements []*Requirement
+	lastKey, err := query(repo, requirementTable, input, startKey, &requirements)
+	if err != nil {
+		return nil, "", err
+	}
+	return requirements, lastKey, nil
+}
+
+// GetRequirement returns the requirement with the provided id.
+func (repo *memoryRepository) GetRequirement(id string) (*Requirement, error) {
+	requirement := Requirement{}
+	if err := repo.getItem(requirementTable, string(Active), id, &requirement); err != nil {
+		return nil, err
+	}
+	return &requirement, nil
+}
+
+// SetRequirement saves the provided requirement in the database.
+func (repo *memoryRepository) SetRequirement(requirement *Requirement) error {
+	if err := ValidatePositions("positions", requirement.Positions); err != nil {
+		return err
+	}
+	return putItem(repo, requirementTable, requirement)
+}
+
+// PutGraduation saves the provided Graduation in the database.
+func (repo *memoryRepository) PutGraduation(graduation *Graduation) error {
+	return putItem(repo, graduationTable, graduation)
+}
+
+// ListGraduationsByCohort returns a list of graduations matching the provided cohort.
+func (repo *memoryRepository) ListGraduationsByCohort(cohort DojoCohort, startKey string) ([]Graduation, string, error) {
+	return repo.listGraduations(&memoryQueryInput[Graduation]{
+		indexKeys: []string{"previousCohort"},
+		match:     func(g *Graduation) bool { return g.PreviousCohort == cohort },
+	}, startKey)
+}
+
+// ListGraduationsByOwner returns a list of graduations matching the provided username.
+func (repo *memoryRepository) ListGraduationsByOwner(username, startKey string) ([]Graduation, string, error) {
+	return repo.listGraduations(&memoryQueryInput[Graduation]{
+		match:   func(g *Graduation) bool { return g.Username == username },
+		sortKey: func(g *Graduation) string { return g.CreatedAt },
+	}, startKey)
+}
+
+// ListGraduationsByDate returns a list of graduations more recent than the provided date.
+func (repo *memoryRepository) ListGraduationsByDate(date, startKey string) ([]Graduation, string, error) {
+	return repo.listGraduations(&memoryQueryInput[Graduation]{
+		indexKeys: []string{"type"},
+		match:     func(g *Graduation) bool { return g.Type == "GRADUATION" && g.CreatedAt >= date },
+		sortKey:   func(g *Graduation) string { return g.CreatedAt },
+	}, startKey)
+}
+
+// ScanGraduations returns a list of all graduations in the table, paginated by the startKey.
+func (repo *memoryRepository) ScanGraduations(startKey string) ([]Graduation, string, error) {
+	return repo.listGraduations(&memoryQueryInput[Graduation]{}, startKey)
+}
+
+func (repo *memoryRepository) listGraduations(input *memoryQueryInput[Graduation], startKey string) ([]Graduation, string, error) {
+	var graduations []Graduation
+	lastKey, err := query(repo, graduationTable, input, startKey, &graduations)
+	if err != nil {
+		return nil, "", err
+	}
+	return graduations, lastKey, nil
+}
+
+// GetCourse returns the course with the provided type and id.
+func (repo *memoryRepository) GetCourse(courseType, id string) (*Course, error) {
+	course := Course{}
+	if err := repo.getItem(courseTable, courseType, id, &course); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// ListCourses returns a list of courses with the provided type.
+func (repo *memoryRepository) ListCourses(courseType, startKey string) ([]Course, string, error) {
+	input := &memoryQueryInput[Course]{
+		match: func(c *Course) bool { return string(c.Type) == courseType },
+	}
+
+	var courses []Course
+	lastKey, err := query(repo, courseTable, input, startKey, &courses)
+	if err != nil {
+		return nil, "", err
+	}
+	return courses, lastKey, nil
+}
+
+// ScanCourses returns a list of all courses.
+func (repo *memoryRepository) ScanCourses(startKey string) ([]Course, string, error) {
+	var courses []Course
+	lastKey, err := query(repo, courseTable, &memoryQueryInput[Course]{}, startKey, &courses)
+	if err != nil {
+		return nil, "", err
+	}
+	return courses, lastKey, nil
+}
+
+// SetCourse saves the provided course to the database. If the course already exists, the
+// existing owner must match the provided course's owner.
+func (repo *memoryRepository) SetCourse(course *Course) error {
+	item, err := dynamodbattribute.MarshalMap(course)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal course", err)
+	}
+
+	err = repo.putItemConditional(courseTable, item, func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing != nil && attributeString(existing["owner"]) != course.Owner {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed memory PutItem", err)
+}
+
+// GetExam returns the requested exam.
+func (repo *memoryRepository) GetExam(examType string, id string) (*Exam, error) {
+	exam := Exam{}
+	err := repo.getItem(examsTable, examType, id, &exam)
+	return &exam, err
+}
+
+// ListExams returns a paginated list of exams with the provided type.
+func (repo *memoryRepository) ListExams(examType ExamType, startKey string, out interface{}) (string, error) {
+	input := &memoryQueryInput[ExamTableKey]{
+		match: func(k *ExamTableKey) bool { return k.Type == examType },
+	}
+	return query(repo, examsTable, input, startKey, out)
+}
+
+// PutExamAnswerSummary creates and saves an ExamAnswerSummary using the provided ExamAnswer.
+// The updated Exam is returned. If the ExamAnswer does not require updating the Exam, then nil is returned.
+func (repo *memoryRepository) PutExamAnswerSummary(answer *ExamAnswer, score int) (*Exam, error) {
+	if len(answer.Attempts) != 1 || answer.Attempts[0].InProgress {
+		// We've either already saved the first attempt on the Exam or the attempt is still on-going
+		return nil, nil
+	}
+
+	summary := ExamAnswerSummary{
+		Cohort:    answer.Attempts[0].Cohort,
+		Rating:    answer.Attempts[0].Rating,
+		Score:     score,
+		CreatedAt: answer.Attempts[0].CreatedAt,
+	}
+
+	exam, err := updateItem(repo, examsTable, string(answer.ExamType), answer.Id, func(e *Exam, exists bool) error {
+		if _, ok := e.Answers[string(answer.Type)]; !exists || e.Answers == nil || ok {
+			return conditionalCheckFailed()
+		}
+		e.Answers[string(answer.Type)] = summary
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: exam not found or you have already taken it", "Memory conditional check failed", err)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed memory UpdateItem", err)
+	}
+	return exam, nil
+}
+
+// PutExamAttempt inserts the provided exam attempt into the database. If index is non-nil,
+// the exam attempt is assumed to already exist and overwrites the current item at the index
+// in the attempts list. If index is nil, the attempt is appended to the existing list of attempts.
+// The updated ExamAnswer is returned.
+func (repo *memoryRepository) PutExamAttempt(username string, examId string, examType ExamType, attempt *ExamAttempt, index *int) (*ExamAnswer, error) {
+	answer, err := updateItem(repo, examsTable, username, examId, func(a *ExamAnswer, _ bool) error {
+		if index == nil {
+			a.ExamType = examType
+			a.Attempts = append(a.Attempts, *attempt)
+			return nil
+		}
+		if *index < 0 || *index >= len(a.Attempts) {
+			return errors.New(500, "Temporary server error", fmt.Sprintf("Exam answer does not have attempt %d", *index))
+		}
+		a.Attempts[*index] = *attempt
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed updateItem call", err)
+	}
+	return answer, nil
+}
+
+// GetExamAnswer fetches the provided exam answer from the database.
+func (repo *memoryRepository) GetExamAnswer(username, id string) (*ExamAnswer, error) {
+	answer := ExamAnswer{}
+	if err := repo.getItem(examsTable, username, id, &answer); err != nil {
+		return nil, err
+	}
+	return &answer, nil
+}
+
+// PutYearReviews inserts the provided YearReviews into the database. The number of
+// successfully inserted reviews is returned.
+func (repo *memoryRepository) PutYearReviews(reviews []*YearReview) (int, error) {
+	return putItems(repo, yearReviewTable, reviews)
+}
+
+// GetYearReview returns the YearReview for the given username and year.
+func (repo *memoryRepository) GetYearReview(username, year string) (*YearReview, error) {
+	review := YearReview{}
+	if err := repo.getItem(yearReviewTable, username, year, &review); err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// addNestedNumber adds delta to the number attribute at the given path of map attributes,
+// creating any missing maps along the way and treating a missing number as 0.
+func addNestedNumber(item map[string]*dynamodb.AttributeValue, path []string, delta int) {
+	for _, name := range path[:len(path)-1] {
+		av, ok := item[name]
+		if !ok || av.M == nil {
+			av = &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue)}
+			item[name] = av
+		} else {
+			copied := make(map[string]*dynamodb.AttributeValue, len(av.M))
+			for k, v := range av.M {
+				copied[k] = v
+			}
+			av = &dynamodb.AttributeValue{M: copied}
+			item[name] = av
+		}
+		item = av.M
+	}
+	addNumber(item, path[len(path)-1], delta)
+}
+
+// GetUserStatistics returns the user statistics from the database.
+func (repo *memoryRepository) GetUserStatistics() (*UserStatistics, error) {
+	userStats := UserStatistics{}
+	if err := repo.getItem(userTable, "STATISTICS", "", &userStats); err != nil {
+		return nil, err
+	}
+	return &userStats, nil
+}
+
+// SetUserStatistics inserts the provided user statistics into the database.
+func (repo *memoryRepository) SetUserStatistics(stats *UserStatistics) error {
+	item, err := dynamodbattribute.MarshalMap(stats)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal users stats", err)
+	}
+	item["username"] = &dynamodb.AttributeValue{S: aws.String("STATISTICS")}
+	item["dojoCohort"] = &dynamodb.AttributeValue{S: aws.String("STATISTICS")}
+
+	return repo.putItemConditional(userTable, item, nil)
+}
+
+// GetEventStatistics gets the event statistics from the database.
+func (repo *memoryRepository) GetEventStatistics() (*EventStatistics, error) {
+	eventStats := EventStatistics{}
+	if err := repo.getItem(eventTable, "STATISTICS", "", &eventStats); err != nil {
+		return nil, err
+	}
+	return &eventStats, nil
+}
+
+// updateEventStatistics applies fn to the event statistics record, creating it if necessary.
+func (repo *memoryRepository) updateEventStatistics(fn func(item map[string]*dynamodb.AttributeValue)) error {
+	_, err := repo.updateAttributes(eventTable, "STATISTICS", "", func(item map[string]*dynamodb.AttributeValue, _ bool) error {
+		fn(item)
+		return nil
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to update event statistics record", err)
+}
+
+// RecordEventCreation saves statistics on the created event.
+func (repo *memoryRepository) RecordEventCreation(event *Event) error {
+	return repo.updateEventStatistics(func(item map[string]*dynamodb.AttributeValue) {
+		if event.Type == EventType_Dojo {
+			addNestedNumber(item, []string{"created", string(EventType_Dojo)}, 1)
+			return
+		}
+
+		addNestedNumber(item, []string{"created", string(EventType_Availability)}, 1)
+		addNestedNumber(item, []string{"ownerCohorts", string(event.OwnerCohort)}, 1)
+		for _, c := range event.Cohorts {
+			addNestedNumber(item, []string{"bookableCohorts", string(c)}, 1)
+		}
+		for _, t := range event.Types {
+			addNestedNumber(item, []string{"availabilityTypes", string(t)}, 1)
+		}
+		addNestedNumber(item, []string{"availabilityMaxParticipants", fmt.Sprint(event.MaxParticipants)}, 1)
+	})
+}
+
+// RecordEventBooking saves statistics on an event booking.
+func (repo *memoryRepository) RecordEventBooking(event *Event) error {
+	if event.Type == EventType_Dojo {
+		return nil
+	}
+	return repo.updateEventStatistics(func(item map[string]*dynamodb.AttributeValue) {
+		addNumber(item, "availabilitiesBooked", 1)
+	})
+}
+
+// RecordEventDeletion saves statistics on the deleted event.
+func (repo *memoryRepository) RecordEventDeletion(event *Event) error {
+	if event.Type == EventType_Dojo {
+		return nil
+	}
+	return repo.updateEventStatistics(func(item map[string]*dynamodb.AttributeValue) {
+		addNumber(item, "availabilitiesDeleted", 1)
+	})
+}
+
+// RecordEventCancelation saves statistics on the canceled event.
+func (repo *memoryRepository) RecordEventCancelation(event *Event) error {
+	if event.Type == EventType_Dojo {
+		return nil
+	}
+	return repo.updateEventStatistics(func(item map[string]*dynamodb.AttributeValue) {
+		addNumber(item, "availabilitiesCanceled", 1)
+	})
+}
+
+// RecordSubscriptionCancelation adds 1 cancelation to the user statistics for
+// the given cohort.
+func (repo *memoryRepository) RecordSubscriptionCancelation(cohort DojoCohort) error {
+	return repo.addUserStatistic(cohort, "subscriptionCancelations")
+}
+
+// RecordFreeTierConversion adds 1 conversion to the user statistics for
+// the given cohort.
+func (repo *memoryRepository) RecordFreeTierConversion(cohort DojoCohort) error {
+	return repo.addUserStatistic(cohort, "freeTierConversions")
+}
+
+// addUserStatistic adds 1 to the given field of the user statistics for the given cohort.
+func (repo *memoryRepository) addUserStatistic(cohort DojoCohort, field string) error {
+	_, err := repo.updateAttributes(userTable, "STATISTICS", "", func(item map[string]*dynamodb.AttributeValue, _ bool) error {
+		addNestedNumber(item, []string{"cohorts", string(cohort), field}, 1)
+		return nil
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to update user statistics record", err)
+}
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// PutTimelineEntry saves the provided TimelineEntry into the database.
+func (repo *memoryRepository) PutTimelineEntry(entry *TimelineEntry) error {
+	return putItem(repo, timelineTable, entry)
+}
+
+// PutTimelineEntries inserts the provided TimelineEntries into the database. The number of
+// successfully inserted entries is returned.
+func (repo *memoryRepository) PutTimelineEntries(entries []*TimelineEntry) (int, error) {
+	return putItems(repo, timelineTable, entries)
+}
+
+// GetTimelineEntry returns the TimelineEntry with the provided owner and id.
+func (repo *memoryRepository) GetTimelineEntry(owner string, id string) (*TimelineEntry, error) {
+	entry := TimelineEntry{}
+	if err := repo.getItem(timelineTable, owner, id, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListTimelineEntries returns a list of TimelineEntries with the provided owner,
+// up to one page of data. startKey can be passed to perform pagination.
+func (repo *memoryRepository) ListTimelineEntries(owner string, startKey string) ([]*TimelineEntry, string, error) {
+	return repo.listTimelineEntriesWithLimit(owner, startKey, -1)
+}
+
+// listTimelineEntriesWithLimit returns a list of TimelineEntries with the provided owner,
+// up to the number of items specified by limit. If limit is <= 0, then up to one page of data
+// is returned. startKey can be passed to perform pagination.
+func (repo *memoryRepository) listTimelineEntriesWithLimit(owner, startKey string, limit int) ([]*TimelineEntry, string, error) {
+	input := &memoryQueryInput[TimelineEntry]{
+		match:      func(e *TimelineEntry) bool { return e.Owner == owner },
+		sortKey:    func(e *TimelineEntry) string { return e.Id },
+		descending: true,
+		limit:      limit,
+	}
+
+	var entries []*TimelineEntry
+	lastKey, err := query(repo, timelineTable, input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// DeleteTimelineEntries deleted the provided TimelineEntries from the database. The number of
+// successfully deleted entries is returned.
+func (repo *memoryRepository) DeleteTimelineEntries(entries []*TimelineEntry) (int, error) {
+	for i, e := range entries {
+		if _, err := repo.deleteItem(timelineTable, e.Owner, e.Id, nil); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// BatchGetTimelineEntries returns the TimelineEntries with the provided keys.
+func (repo *memoryRepository) BatchGetTimelineEntries(entries map[string]TimelineEntryKey) ([]TimelineEntry, error) {
+	if len(entries) == 0 {
+		return []TimelineEntry{}, nil
+	}
+	if len(entries) > 100 {
+		return nil, errors.New(500, "Temporary server error", "More than 100 items in BatchGetTimelineEntries request")
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(entries))
+	for _, e := range entries {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{
+			"owner": {S: aws.String(e.Owner)},
+			"id":    {S: aws.String(e.Id)},
+		})
+	}
+	return batchGet[TimelineEntry](repo, timelineTable, keys, nil)
+}
+
+// CreateTimelineComment appends the provided comment to the TimelineEntry with the provided owner and id.
+// The outbox entries are saved if the comment is created.
+func (repo *memoryRepository) CreateTimelineComment(owner, id string, comment *Comment, outbox ...*OutboxEntry) (*TimelineEntry, error) {
+	entry, err := updateItem(repo, timelineTable, owner, id, func(e *TimelineEntry, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		e.Comments = append(e.Comments, *comment)
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: timeline entry not found", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return entry, repo.putOutbox(outbox)
+}
+
+// SetTimelineReaction sets the given reaction on the provided TimelineEntry. The outbox entries
+// are saved if the reaction is set.
+func (repo *memoryRepository) SetTimelineReaction(owner, id string, reaction *Reaction, outbox ...*OutboxEntry) (*TimelineEntry, error) {
+	entry, err := updateItem(repo, timelineTable, owner, id, func(e *TimelineEntry, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		if e.Reactions == nil {
+			e.Reactions = make(map[string]Reaction)
+		}
+		e.Reactions[reaction.Username] = *reaction
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Invalid request: timeline entry not found", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return entry, repo.putOutbox(outbox)
+}
+
+// PutNewsfeedEntries inserts the provided NewsfeedEntries into the database. The number of
+// successfully inserted entries is returned.
+func (repo *memoryRepository) PutNewsfeedEntries(entries []NewsfeedEntry) (int, error) {
+	return putItems(repo, newsfeedTable, entries)
+}
+
+// ListNewsfeedEntries returns a list of NewsfeedEntries for the provided news feed ID. Usually
+// the ID will be a user's username, but it could also be a cohort or the special value `ALL_USERS`.
+// The optional parameter lastFetch can be used to limit how many results are returned.
+func (repo *memoryRepository) ListNewsfeedEntries(newsfeedId, startKey, lastFetch string, limit int64) ([]NewsfeedEntry, string, error) {
+	input := &memoryQueryInput[NewsfeedEntry]{
+		match: func(e *NewsfeedEntry) bool {
+			return e.NewsfeedId == newsfeedId && (lastFetch == "" || e.SortKey >= lastFetch)
+		},
+		sortKey:    func(e *NewsfeedEntry) string { return e.SortKey },
+		descending: true,
+		limit:      int(limit),
+	}
+
+	var entries []NewsfeedEntry
+	lastKey, err := query(repo, newsfeedTable, input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// DeleteNewsfeedEntries deletes the NewsfeedEntries with the provided poster and timelineId.
+// The number of successfully deleted entries is returned.
+func (repo *memoryRepository) DeleteNewsfeedEntries(poster, timelineId string) (int, error) {
+	return repo.deleteNewsfeedEntriesByQuery(&memoryQueryInput[NewsfeedEntry]{
+		indexKeys: []string{"poster", "timelineId"},
+		match: func(e *NewsfeedEntry) bool {
+			return e.Poster == poster && e.TimelineId == timelineId
+		},
+	})
+}
+
+// RemovePosterFromNewsfeed deletes any entries from the provided newsfeed that were posted by the given poster.
+func (repo *memoryRepository) RemovePosterFromNewsfeed(newsfeedId, poster string) (int, error) {
+	return repo.deleteNewsfeedEntriesByQuery(&memoryQueryInput[NewsfeedEntry]{
+		match:  func(e *NewsfeedEntry) bool { return e.NewsfeedId == newsfeedId },
+		filter: func(e *NewsfeedEntry) bool { return e.Poster == poster },
+	})
+}
+
+// deleteNewsfeedEntriesByQuery deletes all NewsfeedEntries returned by the given query input.
+func (repo *memoryRepository) deleteNewsfeedEntriesByQuery(input *memoryQueryInput[NewsfeedEntry]) (int, error) {
+	var entries []NewsfeedEntry
+	var startKey string
+	var err error
+
+	for ok := true; ok; ok = startKey != "" {
+		var page []NewsfeedEntry
+		startKey, err = query(repo, newsfeedTable, input, startKey, &page)
+		if err != nil {
+			return 0, errors.Wrap(500, "Temporary server error", "Failed to list newsfeed entries", err)
+		}
+		entries = append(entries, page...)
+	}
+
+	for i, e := range entries {
+		if _, err := repo.deleteItem(newsfeedTable, e.NewsfeedId, e.SortKey, nil); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// InsertPosterIntoNewsfeed inserts up to the latest 25 timeline entries from the given poster into the given newsfeed.
+// The number of successfully inserted entries is returned.
+func (repo *memoryRepository) InsertPosterIntoNewsfeed(newsfeedId, poster string) (int, error) {
+	timelineEntries, _, err := repo.listTimelineEntriesWithLimit(poster, "", 25)
+	if err != nil || len(timelineEntries) == 0 {
+		return 0, err
+	}
+
+	entries := make([]NewsfeedEntry, 0, len(timelineEntries))
+	for _, te := range timelineEntries {
+		entries = append(entries, NewsfeedEntry{
+			NewsfeedId: newsfeedId,
+			SortKey:    fmt.Sprintf("%s_%s", te.CreatedAt, te.Id),
+			CreatedAt:  te.CreatedAt,
+			Poster:     poster,
+			TimelineId: te.Id,
+		})
+	}
+	return repo.PutNewsfeedEntries(entries)
+}
+
+// ListNotifications returns a list of notifications for the provided username.
+func (repo *memoryRepository) ListNotifications(username string, startKey string) ([]Notification, string, error) {
+	input := &memoryQueryInput[Notification]{
+		match:      func(n *Notification) bool { return n.Username == username },
+		sortKey:    func(n *Notification) string { return n.Id },
+		descending: true,
+	}
+
+	var notifications []Notification
+	lastKey, err := query(repo, notificationTable, input, startKey, &notifications)
+	if err != nil {
+		return nil, "", err
+	}
+	return notifications, lastKey, nil
+}
+
+// DeleteNotification removes the notification with the specified key from the database.
+func (repo *memoryRepository) DeleteNotification(username, id string) error {
+	_, err := repo.deleteItem(notificationTable, username, id, nil)
+	return err
+}
@@ -0,0 +1,279 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// SetLeaderboard inserts the provided leaderboard into the database.
+func (repo *memoryRepository) SetLeaderboard(leaderboard Leaderboard) error {
+	return putItem(repo, tournamentTable, leaderboard)
+}
+
+// GetLeaderboard fetches the leaderboard with the provided values.
+func (repo *memoryRepository) GetLeaderboard(site LeaderboardSite, timePeriod, tournamentType, timeControl, startsAt string) (*Leaderboard, error) {
+	var sitePrefix string
+	if site == LeaderboardSite_Chesscom {
+		sitePrefix = "_CHESSCOM"
+	}
+
+	timePeriod = strings.ToUpper(timePeriod)
+	tournamentType = strings.ToUpper(tournamentType)
+	timeControl = strings.ToUpper(timeControl)
+
+	leaderboardType := fmt.Sprintf("LEADERBOARD%s_%s_%s_%s", sitePrefix, timePeriod, tournamentType, timeControl)
+	leaderboard := Leaderboard{
+		Type:        LeaderboardType(leaderboardType),
+		StartsAt:    startsAt,
+		Site:        site,
+		TimeControl: timeControl,
+	}
+	err := repo.getItem(tournamentTable, leaderboardType, startsAt, &leaderboard)
+	return &leaderboard, err
+}
+
+// SetOpenClassical inserts the provided OpenClassical into the database.
+func (repo *memoryRepository) SetOpenClassical(openClassical *OpenClassical) error {
+	openClassical.Type = LeaderboardType_OpenClassical
+	return putItem(repo, tournamentTable, openClassical)
+}
+
+// GetOpenClassical returns the open classical for the provided startsAt period.
+func (repo *memoryRepository) GetOpenClassical(startsAt string) (*OpenClassical, error) {
+	openClassical := OpenClassical{}
+	err := repo.getItem(tournamentTable, string(LeaderboardType_OpenClassical), startsAt, &openClassical)
+	return &openClassical, err
+}
+
+// updateOpenClassical applies fn to the open classical with the given startsAt period. As in
+// DynamoDB, the open classical is created if it does not exist.
+func (repo *memoryRepository) updateOpenClassical(startsAt string, fn func(oc *OpenClassical) error) (*OpenClassical, error) {
+	return updateItem(repo, tournamentTable, string(LeaderboardType_OpenClassical), startsAt, func(oc *OpenClassical, _ bool) error {
+		return fn(oc)
+	})
+}
+
+// updateSection applies fn to the section with the given key in the provided open classical. An
+// error is returned if the section does not exist, as DynamoDB does for an invalid document path.
+func updateSection(oc *OpenClassical, key string, fn func(section *OpenClassicalSection) error) error {
+	section, ok := oc.Sections[key]
+	if !ok {
+		return errors.New(500, "Temporary server error", fmt.Sprintf("Open classical section %q does not exist", key))
+	}
+	if err := fn(&section); err != nil {
+		return err
+	}
+	oc.Sections[key] = section
+	return nil
+}
+
+// UpdateOpenClassicalRegistration adds the provided player to the given open classical. If the player
+// already exists in a different section, they are removed.
+func (repo *memoryRepository) UpdateOpenClassicalRegistration(openClassical *OpenClassical, player *OpenClassicalPlayer) (*OpenClassical, error) {
+	sectionKey := fmt.Sprintf("%s_%s", player.Region, player.Section)
+
+	result, err := repo.updateOpenClassical(openClassical.StartsAt, func(oc *OpenClassical) error {
+		if !oc.AcceptingRegistrations {
+			return conditionalCheckFailed()
+		}
+
+		for key := range oc.Sections {
+			if key == sectionKey {
+				continue
+			}
+			if err := updateSection(oc, key, func(section *OpenClassicalSection) error {
+				delete(section.Players, player.Username)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		return updateSection(oc, sectionKey, func(section *OpenClassicalSection) error {
+			if section.Players == nil {
+				section.Players = make(map[string]OpenClassicalPlayer)
+			}
+			section.Players[player.Username] = *player
+			return nil
+		})
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "Registration for this tournament has already closed", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListPreviousOpenClassicals returns a list of OpenClassicals whose name is not CURRENT.
+// The list is sorted in descending order by name.
+func (repo *memoryRepository) ListPreviousOpenClassicals(startKey string) ([]OpenClassical, string, error) {
+	input := &memoryQueryInput[OpenClassical]{
+		indexKeys: []string{"name"},
+		match: func(oc *OpenClassical) bool {
+			return oc.Type == LeaderboardType_OpenClassical && oc.Name != ""
+		},
+		sortKey:    func(oc *OpenClassical) string { return oc.Name },
+		descending: true,
+	}
+
+	var openClassicals []OpenClassical
+	lastKey, err := query(repo, tournamentTable, input, startKey, &openClassicals)
+	if err != nil {
+		return nil, "", err
+	}
+	return openClassicals, lastKey, nil
+}
+
+// pairingAt returns the pairing with the given round and index in the provided section, or
+// nil if it does not exist.
+func pairingAt(section *OpenClassicalSection, round, index int) *OpenClassicalPairing {
+	if round < 0 || round >= len(section.Rounds) {
+		return nil
+	}
+	pairings := section.Rounds[round].Pairings
+	if index < 0 || index >= len(pairings) {
+		return nil
+	}
+	return &pairings[index]
+}
+
+// Sets the pairing on the current open classical to match the given update. The update succeeds
+// only if the pairing is not already marked as verified.
+func (repo *memoryRepository) UpdateOpenClassicalResult(update *OpenClassicalPairingUpdate) (*OpenClassical, error) {
+	sectionKey := fmt.Sprintf("%s_%s", update.Region, update.Section)
+
+	result, err := repo.updateOpenClassical(CurrentLeaderboard, func(oc *OpenClassical) error {
+		if _, ok := oc.Sections[sectionKey]; !ok {
+			return conditionalCheckFailed()
+		}
+		return updateSection(oc, sectionKey, func(section *OpenClassicalSection) error {
+			pairing := pairingAt(section, update.Round, update.PairingIndex)
+			if pairing == nil || (!update.OverwriteVerified && pairing.Verified) {
+				return conditionalCheckFailed()
+			}
+			*pairing = *update.Pairing
+			return nil
+		})
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(400, "This pairing does not exist or its result has already been verified. Contact the TD to change it.", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// Sets the pairing emails sent flag to true for all sections in the current open classical.
+// Round is a 1-based index.
+func (repo *memoryRepository) SetPairingEmailsSent(openClassical *OpenClassical, round int) (*OpenClassical, error) {
+	return repo.updateOpenClassical(openClassical.StartsAt, func(oc *OpenClassical) error {
+		for key := range openClassical.Sections {
+			err := updateSection(oc, key, func(section *OpenClassicalSection) error {
+				if round < 1 || round > len(section.Rounds) {
+					return errors.New(500, "Temporary server error", fmt.Sprintf("Open classical section %q does not have round %d", key, round))
+				}
+				section.Rounds[round-1].PairingEmailsSent = true
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Bans the given player in the current open classical.
+func (repo *memoryRepository) BanPlayer(player *OpenClassicalPlayer) (*OpenClassical, error) {
+	return repo.updateOpenClassical(CurrentLeaderboard, func(oc *OpenClassical) error {
+		if oc.BannedPlayers == nil {
+			oc.BannedPlayers = make(map[string]OpenClassicalPlayer)
+		}
+		oc.BannedPlayers[player.Username] = *player
+
+		return updateSection(oc, fmt.Sprintf("%s_%s", player.Region, player.Section), func(section *OpenClassicalSection) error {
+			if section.Players == nil {
+				section.Players = make(map[string]OpenClassicalPlayer)
+			}
+			section.Players[player.Username] = *player
+			return nil
+		})
+	})
+}
+
+// Unbans the given player in the current open classical.
+func (repo *memoryRepository) UnbanPlayer(username string) (*OpenClassical, error) {
+	return repo.updateOpenClassical(CurrentLeaderboard, func(oc *OpenClassical) error {
+		delete(oc.BannedPlayers, username)
+		return nil
+	})
+}
+
+// Sets a player in the current open classical. The player must already exist in the given
+// region and section.
+func (repo *memoryRepository) SetPlayer(player *OpenClassicalPlayer) (*OpenClassical, error) {
+	sectionKey := fmt.Sprintf("%s_%s", player.Region, player.Section)
+
+	result, err := repo.updateOpenClassical(CurrentLeaderboard, func(oc *OpenClassical) error {
+		if _, ok := oc.Sections[sectionKey].Players[player.Username]; !ok {
+			return conditionalCheckFailed()
+		}
+		return updateSection(oc, sectionKey, func(section *OpenClassicalSection) error {
+			section.Players[player.Username] = *player
+			return nil
+		})
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: player does not exist", "Memory conditional check failed", err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// Closes registrations for the current open classical.
+func (repo *memoryRepository) OpenClassicalCloseRegistrations() (*OpenClassical, error) {
+	return repo.updateOpenClassical(CurrentLeaderboard, func(oc *OpenClassical) error {
+		oc.AcceptingRegistrations = false
+		oc.StartMonth = time.Now().Format("2006-01")
+		oc.RegistrationClose = ""
+		return nil
+	})
+}
+
+// Adds a new round to the given region and section of the current open classical, using the
+// provided pairings.
+func (repo *memoryRepository) OpenClassicalAddRound(region, section string, pairings []OpenClassicalPairing) (*OpenClassical, error) {
+	round := OpenClassicalRound{
+		PairingEmailsSent: false,
+		Pairings:          pairings,
+	}
+
+	return repo.updateOpenClassical(CurrentLeaderboard, func(oc *OpenClassical) error {
+		return updateSection(oc, fmt.Sprintf("%s_%s", region, section), func(s *OpenClassicalSection) error {
+			s.Rounds = append(s.Rounds, round)
+			return nil
+		})
+	})
+}
+
+// Sets the pairings in the given round for current open classical.
+func (repo *memoryRepository) OpenClassicalSetRound(region, section string, round int, pairings []OpenClassicalPairing) (*OpenClassical, error) {
+	return repo.updateOpenClassical(CurrentLeaderboard, func(oc *OpenClassical) error {
+		return updateSection(oc, fmt.Sprintf("%s_%s", region, section), func(s *OpenClassicalSection) error {
+			if round < 0 || round >= len(s.Rounds) {
+				return errors.New(500, "Temporary server error", fmt.Sprintf("Open classical section does not have round %d", round))
+			}
+			s.Rounds[round].Pairings = pairings
+			return nil
+		})
+	})
+}
@@ -0,0 +1,744 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+)
+
+// CreateUser creates a new User object with the provided information.
+func (repo *memoryRepository) CreateUser(username, email, name string, paymentInfo *PaymentInfo) (*User, error) {
+	subscriptionStatus := SubscriptionStatus_NotSubscribed
+	subscriptionTier := SubscriptionTier_Free
+	if paymentInfo != nil {
+		subscriptionStatus = SubscriptionStatus_Subscribed
+		subscriptionTier = SubscriptionTier_Basic
+	}
+
+	user := &User{
+		Username:           username,
+		Email:              email,
+		WixEmail:           email,
+		Name:               name,
+		CreatedAt:          time.Now().Format(time.RFC3339),
+		DojoCohort:         NoCohort,
+		PaymentInfo:        paymentInfo,
+		SubscriptionStatus: subscriptionStatus,
+		SubscriptionTier:   subscriptionTier,
+	}
+
+	err := repo.SetUserConditional(user, aws.String("attribute_not_exists(username)"))
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := defaultDirectories(user)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if err := repo.putItemConditional(directoryTable, item, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// SetUserConditional saves the provided User object in the database using an optional condition statement.
+// Only the conditions used by the DynamoDB repository (attribute_exists(username) and
+// attribute_not_exists(username)) are supported.
+func (repo *memoryRepository) SetUserConditional(user *User, condition *string) error {
+	var check func(existing map[string]*dynamodb.AttributeValue) error
+	if condition != nil {
+		check = func(existing map[string]*dynamodb.AttributeValue) error {
+			switch strings.TrimSpace(*condition) {
+			case "attribute_exists(username)":
+				if existing == nil {
+					return errors.Wrap(500, "Temporary server error", "Memory PutItem failure", conditionalCheckFailed())
+				}
+			case "attribute_not_exists(username)":
+				if existing != nil {
+					return errors.Wrap(500, "Temporary server error", "Memory PutItem failure", conditionalCheckFailed())
+				}
+			default:
+				return errors.New(500, "Temporary server error", fmt.Sprintf("Unsupported memory condition expression %q", *condition))
+			}
+			return nil
+		}
+	}
+	return repo.setUser(user, check)
+}
+
+// setUser saves the provided User object if condition returns nil for the existing item.
+func (repo *memoryRepository) setUser(user *User, condition func(existing map[string]*dynamodb.AttributeValue) error) error {
+	if user.Username == "STATISTICS" {
+		return errors.New(403, "Invalid request: cannot use username `STATISTICS`", "")
+	}
+
+	user.UpdatedAt = time.Now().Format(time.RFC3339)
+	user.Version++
+	item, err := dynamodbattribute.MarshalMap(user)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal user", err)
+	}
+	return repo.putItemConditional(userTable, item, condition)
+}
+
+// UpdateUser applies the specified update to the user with the provided username.
+// The outbox entries are saved if the update succeeds.
+func (repo *memoryRepository) UpdateUser(username string, update *UserUpdate, outbox ...*OutboxEntry) (*User, error) {
+	if username == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: cannot update username `STATISTICS`", "")
+	}
+
+	update.UpdatedAt = aws.String(time.Now().Format(time.RFC3339))
+
+	encoder := dynamodbattribute.NewEncoder()
+	encoder.NullEmptyString = false
+	encoder.EnableEmptyCollections = true
+	av, err := encoder.Encode(update)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal user update", err)
+	}
+
+	item, err := repo.updateAttributes(userTable, username, "", func(item map[string]*dynamodb.AttributeValue, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+
+		var version int
+		if v := item["version"]; v != nil {
+			if err := dynamodbattribute.Unmarshal(v, &version); err != nil {
+				return err
+			}
+		}
+		if update.ExpectedVersion != nil && *update.ExpectedVersion != version {
+			return errors.NewConflict("Invalid request: your profile was changed by another request. Refresh and try again.",
+				fmt.Sprintf("User version is %d, not %d", version, *update.ExpectedVersion), username)
+		}
+
+		for k, v := range av.M {
+			item[k] = v
+		}
+		item["version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(version + 1))}
+		return nil
+	})
+	if err != nil {
+		if _, ok := errors.AsConflict(err); ok {
+			return nil, err
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Memory UpdateItem failure", err)
+	}
+
+	user := User{}
+	if err := dynamodbattribute.UnmarshalMap(item, &user); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal UpdateItem result", err)
+	}
+	return &user, repo.putOutbox(outbox)
+}
+
+// UpdateUserProgress sets the given progress entry in the user's progress map.
+func (repo *memoryRepository) UpdateUserProgress(username string, progressEntry *RequirementProgress) (*User, error) {
+	if username == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: cannot update username `STATISTICS`", "")
+	}
+
+	user, err := updateItem(repo, userTable, username, "", func(user *User, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		if user.Progress == nil {
+			user.Progress = make(map[string]*RequirementProgress)
+		}
+		user.Progress[progressEntry.RequirementId] = progressEntry
+		user.UpdatedAt = time.Now().Format(time.RFC3339)
+		user.Version++
+		return nil
+	})
+	if err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: user does not exist", "Memory conditional check failed", aerr)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUser returns the User object with the provided username.
+func (repo *memoryRepository) GetUser(username string) (*User, error) {
+	if username == "STATISTICS" {
+		return nil, errors.New(403, "Invalid request: cannot get username `STATISTICS`", "")
+	}
+
+	user := User{}
+	if err := repo.getItem(userTable, username, "", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByDiscordId returns the User object with the provided Discord ID.
+func (repo *memoryRepository) GetUserByDiscordId(discordId string) (*User, error) {
+	input := &memoryQueryInput[User]{
+		indexKeys: []string{"discordId"},
+		match:     func(u *User) bool { return u.DiscordId != "" && u.DiscordId == discordId },
+	}
+
+	var results []*User
+	if _, err := query(repo, userTable, input, "", &results); err != nil {
+		return nil, err
+	}
+
+	if len(results) < 1 {
+		return nil, errors.New(404, fmt.Sprintf("Discord ID %q not found", discordId), "")
+	}
+	return results[0], nil
+}
+
+// ListUsersByCohort returns a list of Users in the provided cohort, up to one page of data.
+// startKey is an optional parameter that can be used to perform pagination. Only active
+// users are returned (updated within the past month).
+// The list of users and the next start key are returned.
+func (repo *memoryRepository) ListUsersByCohort(cohort DojoCohort, startKey string) ([]*User, string, error) {
+	if cohort == "STATISTICS" {
+		return nil, "", errors.New(403, "Invalid request: cannot get cohort `STATISTICS`", "")
+	}
+
+	monthAgo := time.Now().Add(ONE_MONTH_AGO).Format(time.RFC3339)
+	input := &memoryQueryInput[User]{
+		indexKeys: []string{"dojoCohort"},
+		match:     func(u *User) bool { return u.DojoCohort == cohort },
+		filter:    func(u *User) bool { return u.UpdatedAt >= monthAgo },
+	}
+
+	var users []*User
+	lastKey, err := query(repo, userTable, input, startKey, &users)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, lastKey, nil
+}
+
+// ScanUsers returns a list of all Users in the database, up to one page of data.
+// startKey is an optional parameter that can be used to perform pagination.
+// The list of users and the next start key are returned.
+func (repo *memoryRepository) ScanUsers(startKey string) ([]*User, string, error) {
+	input := &memoryQueryInput[User]{
+		filter: func(u *User) bool { return u.Username != "STATISTICS" },
+	}
+
+	var users []*User
+	lastKey, err := query(repo, userTable, input, startKey, &users)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, lastKey, nil
+}
+
+// ListUserRatings returns a list of Users matching the provided cohort, up to one page of data.
+// Only the fields necessary for the rating/statistics update are returned.
+// startkey is an optional parameter that can be used to perform pagination.
+// The list of users and the next start key are returned.
+func (repo *memoryRepository) ListUserRatings(cohort DojoCohort, startKey string) ([]*User, string, error) {
+	input := &memoryQueryInput[User]{
+		indexKeys:  []string{"dojoCohort"},
+		match:      func(u *User) bool { return u.DojoCohort == cohort },
+		projection: parseProjection(ratingsProjection),
+	}
+
+	var users []*User
+	lastKey, err := query(repo, userTable, input, startKey, &users)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, lastKey, nil
+}
+
+// UpdateUserRatings sets the ratings, legacy rating histories and Lichess ban status of the provided
+// users. Each user is only updated if its version has not changed since it was read. If some users have
+// changed or do not exist, the returned error's cause is an *errors.ConflictError containing their
+// usernames.
+func (repo *memoryRepository) UpdateUserRatings(users []*User) error {
+	if len(users) > 25 {
+		return errors.New(500, "Temporary server error", "UpdateUserRatings has max limit of 25 users")
+	}
+
+	return repo.versionedUpdateUsers(users, func(existing, user *User) {
+		existing.Ratings = user.Ratings
+		existing.RatingHistories = user.RatingHistories
+		existing.LichessBan = user.LichessBan
+	})
+}
+
+// UpdateUserTimes sets the totalDojoScore and minutesSpent fields on the provided users. Each user
+// is only updated if its version has not changed since it was read. If some users have changed or
+// do not exist, the returned error's cause is an *errors.ConflictError containing their usernames.
+func (repo *memoryRepository) UpdateUserTimes(users []*User) error {
+	if len(users) > 25 {
+		return errors.New(500, "Temporary server error", "UpdateUserTimes has max limit of 25 users")
+	}
+
+	return repo.versionedUpdateUsers(users, func(existing, user *User) {
+		existing.TotalDojoScore = user.TotalDojoScore
+		existing.MinutesSpent = user.MinutesSpent
+	})
+}
+
+// versionedUpdateUsers applies fn to each of the provided users whose version matches the version
+// in the database, and increments their version. Like a PartiQL BatchExecuteStatement with a version
+// condition, a user which does not match does not cause the other updates to fail. Instead, the
+// returned error contains a ConflictError with the usernames which did not match.
+func (repo *memoryRepository) versionedUpdateUsers(users []*User, fn func(existing, user *User)) error {
+	var conflicts []string
+	for _, user := range users {
+		_, err := updateItem(repo, userTable, user.Username, "", func(existing *User, exists bool) error {
+			if !exists || existing.Version != user.Version {
+				return conditionalCheckFailed()
+			}
+			fn(existing, user)
+			existing.Version++
+			return nil
+		})
+		if err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				conflicts = append(conflicts, user.Username)
+				continue
+			}
+			return errors.Wrap(500, "Temporary server error", "Failed memory batch update", err)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return errors.NewConflict("Temporary server error", "Users were modified concurrently", conflicts...)
+	}
+	return nil
+}
+
+// UpdateUserSubscriptionStatuses sets the subscriptionStatus and subscriptionTier fields on the
+// provided users. Users which do not exist are skipped.
+func (repo *memoryRepository) UpdateUserSubscriptionStatuses(users []*User) error {
+	if len(users) > 25 {
+		return errors.New(500, "Temporary server error", "UpdateUserSubscriptionStatuses has max limit of 25 users")
+	}
+
+	return repo.batchUpdateUsers(users, func(existing, user *User) {
+		existing.SubscriptionStatus = user.GetSubscriptionStatus()
+		existing.SubscriptionTier = user.GetSubscriptionTier()
+	})
+}
+
+// batchUpdateUsers applies fn to each of the provided users which exists in the database. Like a
+// PartiQL BatchExecuteStatement, a missing user does not cause the other updates to fail.
+func (repo *memoryRepository) batchUpdateUsers(users []*User, fn func(existing, user *User)) error {
+	for _, user := range users {
+		_, err := updateItem(repo, userTable, user.Username, "", func(existing *User, exists bool) error {
+			if !exists {
+				return conditionalCheckFailed()
+			}
+			fn(existing, user)
+			return nil
+		})
+		if err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				log.Debugf("Skipping update for missing user %q", user.Username)
+				continue
+			}
+			return errors.Wrap(500, "Temporary server error", "Failed memory batch update", err)
+		}
+	}
+	return nil
+}
+
+// RecordGameCreation updates the given user to increase their game creation stats.
+func (repo *memoryRepository) RecordGameCreation(user *User, amount int) error {
+	if user.GamesCreated == nil {
+		user.GamesCreated = make(map[DojoCohort]int)
+	}
+
+	count := user.GamesCreated[user.DojoCohort]
+	user.GamesCreated[user.DojoCohort] = count + amount
+	return repo.SetUserConditional(user, nil)
+}
+
+// DeleteUser deletes the user with the given username
+func (repo *memoryRepository) DeleteUser(username string) error {
+	if username == "STATISTICS" {
+		return errors.New(403, "Invalid request: cannot delete username `STATISTICS`", "")
+	}
+
+	_, err := repo.deleteItem(userTable, username, "", func(existing map[string]*dynamodb.AttributeValue) error {
+		if existing == nil {
+			return conditionalCheckFailed()
+		}
+		return nil
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed memory DeleteItem", err)
+}
+
+// SearchUsers returns a list of users whose SearchKey field matches the provided query string and fields.
+// fields should be an array like ["display", "discord", "chesscom"] etc. The special value "all" can be
+// provided to indicate all fields are a match.
+func (repo *memoryRepository) SearchUsers(search string, fields []string, startKey string) ([]*User, string, error) {
+	search = strings.ToLower(search)
+
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(field)
+		if field == "all" {
+			values = append(values, search)
+		} else {
+			values = append(values, fmt.Sprintf("%s:%s", field, search))
+		}
+	}
+
+	input := &memoryQueryInput[User]{
+		indexKeys: []string{"searchKey"},
+		filter: func(u *User) bool {
+			for _, v := range values {
+				if strings.Contains(u.SearchKey, v) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+
+	var users []*User
+	lastKey, err := query(repo, userTable, input, startKey, &users)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, lastKey, nil
+}
+
+// BatchGetUsers returns a list of users with the provided usernames.
+func (repo *memoryRepository) BatchGetUsers(usernames []string) ([]*User, error) {
+	return repo.BatchGetUsersProjection(usernames, "")
+}
+
+// BatchGetUsersProjection returns a list of users with the provided usernames and the provided
+// projection expression. Expression attribute names are not supported in the projection expression.
+func (repo *memoryRepository) BatchGetUsersProjection(usernames []string, projectionExpression string) ([]*User, error) {
+	if len(usernames) == 0 {
+		return []*User{}, nil
+	}
+	if len(usernames) > 100 {
+		return nil, errors.New(500, "Temporary server error", "More than 100 items in BatchGetUsers request")
+	}
+
+	return batchGet[*User](repo, userTable, usernameKeys(usernames), parseProjection(projectionExpression))
+}
+
+// usernameKeys returns the users table keys for the provided usernames.
+func usernameKeys(usernames []string) []map[string]*dynamodb.AttributeValue {
+	keys := make([]map[string]*dynamodb.AttributeValue, 0, len(usernames))
+	for _, u := range usernames {
+		keys = append(keys, map[string]*dynamodb.AttributeValue{"username": {S: aws.String(u)}})
+	}
+	return keys
+}
+
+// UpdateUserExamRatings batch updates the given users' exam summaries for the given exam.
+func (repo *memoryRepository) UpdateUserExamRatings(examId string, updates []UserExamSummaryUpdate) error {
+	for _, update := range updates {
+		_, err := updateItem(repo, userTable, update.Username, "", func(user *User, exists bool) error {
+			if !exists {
+				return conditionalCheckFailed()
+			}
+			if user.Exams == nil {
+				user.Exams = make(map[string]UserExamSummary)
+			}
+			user.Exams[examId] = update.Summary
+			return nil
+		})
+		if err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				continue
+			}
+			return errors.Wrap(500, "Temporary server error", "Failed memory batch update", err)
+		}
+	}
+	return nil
+}
+
+// addNumber adds delta to the number attribute with the given name, treating a missing
+// attribute as 0. This is equivalent to a DynamoDB ADD update expression.
+func addNumber(item map[string]*dynamodb.AttributeValue, name string, delta int) {
+	var value int
+	if av, ok := item[name]; ok && av.N != nil {
+		value, _ = strconv.Atoi(*av.N)
+	}
+	item[name] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(value + delta))}
+}
+
+// CreateFollower adds a FollowerEntry for the given poster and follower. The poster's and follower's
+// followerCount/followingCount fields and the outbox entries are saved in the same transaction.
+func (repo *memoryRepository) CreateFollower(poster, follower *User, outbox ...*OutboxEntry) (*FollowerEntry, error) {
+	followerEntry := &FollowerEntry{
+		Poster:              poster.Username,
+		PosterDisplayName:   poster.DisplayName,
+		Follower:            follower.Username,
+		FollowerDisplayName: follower.DisplayName,
+		CreatedAt:           time.Now().Format(time.RFC3339),
+	}
+
+	item, err := dynamodbattribute.MarshalMap(followerEntry)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal user", err)
+	}
+
+	writes := []memoryWrite{
+		{
+			table: followersTable,
+			hash:  poster.Username,
+			rng:   follower.Username,
+			fn: func(existing map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				if existing != nil {
+					return nil, conditionalCheckFailed()
+				}
+				return item, nil
+			},
+		},
+		followCountWrite(poster.Username, "followerCount", 1),
+		followCountWrite(follower.Username, "followingCount", 1),
+	}
+	outboxWrites, err := outboxMemoryWrites(outbox)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.transactWrite(append(writes, outboxWrites...)...)
+	if err != nil {
+		if transactionCancellationCode(err, 0) == conditionalCheckFailedReason {
+			// The follower relationship already exists, so we can just return like everything worked successfully
+			return followerEntry, nil
+		}
+		if followCountCancelled(err) {
+			return nil, errors.Wrap(404, "Invalid request: user does not exist", "Follow count update condition failed", err)
+		}
+		return nil, err
+	}
+	return followerEntry, nil
+}
+
+// followCountWrite returns a memoryWrite which adds incrementalCount to the followerCount or
+// followingCount (as chosen by field) of the given username. The write fails if the user does
+// not exist.
+func followCountWrite(username, field string, incrementalCount int) memoryWrite {
+	return memoryWrite{
+		table: userTable,
+		hash:  username,
+		fn: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+			if item == nil {
+				return nil, conditionalCheckFailed()
+			}
+			addNumber(item, field, incrementalCount)
+			return item, nil
+		},
+	}
+}
+
+// DeleteFollower removes a FollowerEntry for the given poster and follower usernames. The poster's and
+// follower's followerCount/followingCount fields are updated in the same transaction.
+func (repo *memoryRepository) DeleteFollower(poster, follower string) error {
+	err := repo.transactWrite(
+		memoryWrite{
+			table: followersTable,
+			hash:  poster,
+			rng:   follower,
+			fn: func(existing map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+				if existing == nil {
+					return nil, conditionalCheckFailed()
+				}
+				return nil, nil
+			},
+		},
+		followCountWrite(poster, "followerCount", -1),
+		followCountWrite(follower, "followingCount", -1),
+	)
+	if err != nil {
+		if transactionCancellationCode(err, 0) == conditionalCheckFailedReason {
+			// The follower relationship does not exist, so we can just return like everything worked successfully
+			return nil
+		}
+		if followCountCancelled(err) {
+			// One of the users was deleted, so the relationship is removed without updating the counts.
+			log.Warnf("Deleting follower entry %s/%s without updating counts, as one of the users no longer exists", poster, follower)
+			_, err = repo.deleteItem(followersTable, poster, follower, nil)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetFollowerEntry returns the FollowerEntry with the provided poster and follower. If the FollowerEntry
+// does not exist, a nil FollowerEntry and nil error is returned.
+func (repo *memoryRepository) GetFollowerEntry(poster, follower string) (*FollowerEntry, error) {
+	entry := &FollowerEntry{}
+	if err := repo.getItem(followersTable, poster, follower, entry); err != nil {
+		if aerr, ok := err.(*errors.Error); ok && aerr.Code == 404 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListFollowers returns a list of FollowerEntry objects where the given username is the Poster.
+// The next start key is also returned.
+func (repo *memoryRepository) ListFollowers(username, startKey string) ([]FollowerEntry, string, error) {
+	input := &memoryQueryInput[FollowerEntry]{
+		match: func(e *FollowerEntry) bool { return e.Poster == username },
+	}
+
+	var followers []FollowerEntry
+	lastKey, err := query(repo, followersTable, input, startKey, &followers)
+	if err != nil {
+		return nil, "", err
+	}
+	return followers, lastKey, nil
+}
+
+// ListFollowing returns a list of FollowerEntry objects where the given username is the Follower.
+// The next start key is also returned. This is equivalent to ListFollowingLimit(username, startKey, -1).
+func (repo *memoryRepository) ListFollowing(username, startKey string) ([]FollowerEntry, string, error) {
+	return repo.ListFollowingLimit(username, startKey, -1)
+}
+
+// ListFollowingLimit returns a list of FollowerEntry objects where the given username is the Follower.
+// The next start key is also returned. If limit is positive, it is used as the upper bound on the
+// number of results returned. If non-positive, it is ignored.
+func (repo *memoryRepository) ListFollowingLimit(username, startKey string, limit int) ([]FollowerEntry, string, error) {
+	input := &memoryQueryInput[FollowerEntry]{
+		indexKeys: []string{"follower"},
+		match:     func(e *FollowerEntry) bool { return e.Follower == username },
+		sortKey:   func(e *FollowerEntry) string { return e.Poster },
+		limit:     limit,
+	}
+
+	var following []FollowerEntry
+	lastKey, err := query(repo, followersTable, input, startKey, &following)
+	if err != nil {
+		return nil, "", err
+	}
+	return following, lastKey, nil
+}
+
+// ListScoreboardSummaries returns a list of ScoreboardSummaries for all active users in the SUBSCRIBED tier.
+// Up to one page of data is included at a time. startKey is an optional parameter users to perform pagination.
+func (repo *memoryRepository) ListScoreboardSummaries(startKey string) ([]ScoreboardSummary, string, error) {
+	monthAgo := time.Now().Add(ONE_MONTH_AGO).Format(time.RFC3339)
+
+	input := &memoryQueryInput[User]{
+		indexKeys: []string{"subscriptionStatus"},
+		match:     func(u *User) bool { return u.SubscriptionStatus == SubscriptionStatus_Subscribed },
+		filter:    func(u *User) bool { return u.UpdatedAt >= monthAgo && u.DojoCohort != NoCohort },
+	}
+
+	var summaries []ScoreboardSummary
+	lastKey, err := query(repo, userTable, input, startKey, &summaries)
+	if err != nil {
+		return nil, "", err
+	}
+	return summaries, lastKey, nil
+}
+
+// GetScoreboardSummaries returns a list of ScoreboardSummaries matching the provided usernames.
+// Up to 100 usernames can be specified at a time.
+func (repo *memoryRepository) GetScoreboardSummaries(usernames []string) ([]ScoreboardSummary, error) {
+	if len(usernames) == 0 {
+		return []ScoreboardSummary{}, nil
+	}
+	if len(usernames) > 100 {
+		return nil, errors.New(500, "Temporary server error", "More than 100 usernames passed to GetScoreboardSummaries")
+	}
+
+	return batchGet[ScoreboardSummary](repo, userTable, usernameKeys(usernames), parseProjection(scoreboardSummaryProjection))
+}
+
+// GetCohort returns a list of Users in the provided cohort, up to one page of data.
+// startKey is an optional parameter that can be used to perform pagination. Free-tier and inactive
+// users are excluded. The list of users and the next start key are returned.
+func (repo *memoryRepository) GetCohort(cohort, startKey string) ([]User, string, error) {
+	monthAgo := time.Now().Add(ONE_MONTH_AGO).Format(time.RFC3339)
+	input := &memoryQueryInput[User]{
+		indexKeys: []string{"dojoCohort"},
+		match:     func(u *User) bool { return string(u.DojoCohort) == cohort },
+		filter: func(u *User) bool {
+			return u.UpdatedAt >= monthAgo && u.SubscriptionStatus == SubscriptionStatus_Subscribed
+		},
+	}
+
+	var users []User
+	lastKey, err := query(repo, userTable, input, startKey, &users)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, lastKey, nil
+}
+
+// SetUserRoles sets the roles of the user with the provided username. The roles must not
+// contain duplicates. The deprecated IsAdmin, IsCalendarAdmin and IsTournamentAdmin fields
+// are updated to match, and the user's version is incremented. The updated user is returned.
+func (repo *memoryRepository) SetUserRoles(username string, roles []Role) (*User, error) {
+	if err := checkDuplicateRoles(roles); err != nil {
+		return nil, err
+	}
+
+	user, err := updateItem(repo, userTable, username, "", func(u *User, exists bool) error {
+		if !exists {
+			return conditionalCheckFailed()
+		}
+		u.SetRoles(roles)
+		u.Version++
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: user not found", "Memory conditional check failed", err)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Memory UpdateItem failure", err)
+	}
+	return user, nil
+}
+
+// ScanFollowers returns a list of all FollowerEntry objects, up to one page of data.
+// startKey is an optional parameter that can be used to perform pagination.
+// The list of entries and the next start key are returned.
+func (repo *memoryRepository) ScanFollowers(startKey string) ([]FollowerEntry, string, error) {
+	var entries []FollowerEntry
+	lastKey, err := query(repo, followersTable, &memoryQueryInput[FollowerEntry]{}, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// SetFollowCounts sets the followerCount and followingCount of the provided user, which must have
+// been read from the database. The update fails with a 409 error if the user's counts were changed
+// after it was read.
+func (repo *memoryRepository) SetFollowCounts(user *User, followerCount, followingCount int) error {
+	_, err := updateItem(repo, userTable, user.Username, "", func(existing *User, exists bool) error {
+		if !exists || existing.FollowerCount != user.FollowerCount || existing.FollowingCount != user.FollowingCount {
+			return conditionalCheckFailed()
+		}
+		existing.FollowerCount = followerCount
+		existing.FollowingCount = followingCount
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return errors.NewConflict("Temporary server error", "Follow counts were changed concurrently", user.Username)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+func errorCode(err error) int {
+	var aerr *errors.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code
+	}
+	return 0
+}
+
+func TestMemoryGetItem(t *testing.T) {
+	repo := NewMemoryRepository()
+	if err := repo.SetUserConditional(&User{Username: "alice", DojoCohort: "1400-1500"}, nil); err != nil {
+		t.Fatalf("SetUserConditional: %v", err)
+	}
+
+	table := []struct {
+		name     string
+		username string
+		wantCode int
+	}{
+		{name: "Exists", username: "alice"},
+		{name: "Missing", username: "bob", wantCode: 404},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := repo.GetUser(tc.username)
+			if got := errorCode(err); got != tc.wantCode {
+				t.Fatalf("GetUser(%q) error code = %d; want %d", tc.username, got, tc.wantCode)
+			}
+			if err == nil && user.Username != tc.username {
+				t.Errorf("GetUser(%q) username = %q", tc.username, user.Username)
+			}
+		})
+	}
+}
+
+func TestMemoryQueryPagination(t *testing.T) {
+	repo := NewMemoryRepository()
+	total := memoryPageSize + 25
+	for i := 0; i < total; i++ {
+		user := &User{Username: fmt.Sprintf("user%03d", i), DojoCohort: "1400-1500"}
+		if err := repo.SetUserConditional(user, nil); err != nil {
+			t.Fatalf("SetUserConditional: %v", err)
+		}
+	}
+	if err := repo.SetUserConditional(&User{Username: "other", DojoCohort: "1500-1600"}, nil); err != nil {
+		t.Fatalf("SetUserConditional: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	pages := 0
+	startKey := ""
+	for ok := true; ok; ok = startKey != "" {
+		users, lastKey, err := repo.ListUsersByCohort("1400-1500", startKey)
+		if err != nil {
+			t.Fatalf("ListUsersByCohort: %v", err)
+		}
+		for _, u := range users {
+			if seen[u.Username] {
+				t.Errorf("ListUsersByCohort returned %q twice", u.Username)
+			}
+			seen[u.Username] = true
+		}
+		startKey = lastKey
+		pages++
+	}
+
+	if len(seen) != total {
+		t.Errorf("ListUsersByCohort returned %d users; want %d", len(seen), total)
+	}
+	if pages != 2 {
+		t.Errorf("ListUsersByCohort returned %d pages; want 2", pages)
+	}
+}
+
+func TestMemoryQueryInvalidStartKey(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	_, _, err := repo.ListUsersByCohort("1400-1500", "not a start key")
+	if got := errorCode(err); got != 400 {
+		t.Errorf("ListUsersByCohort error code = %d; want 400", got)
+	}
+}
+
+func TestMemoryRepositoryFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.json")
+	first := NewMemoryRepository()
+	first.file = file
+	second := NewMemoryRepository()
+	second.file = file
+
+	if err := first.SetUserConditional(&User{Username: "alice", DojoCohort: "1400-1500"}, nil); err != nil {
+		t.Fatalf("SetUserConditional: %v", err)
+	}
+	user, err := second.GetUser("alice")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user.DojoCohort != "1400-1500" {
+		t.Errorf("GetUser cohort = %q; want 1400-1500", user.DojoCohort)
+	}
+
+	if err := second.DeleteUser("alice"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := first.GetUser("alice"); errorCode(err) != 404 {
+		t.Errorf("GetUser after delete error code = %d; want 404", errorCode(err))
+	}
+}
+
+func TestRepository(t *testing.T) {
+	if _, ok := Repository[UserGetter](DynamoDB).(*dynamoRepository); !ok {
+		t.Errorf("Repository without %s did not return DynamoDB", MemoryDatabaseFileEnv)
+	}
+
+	t.Setenv(MemoryDatabaseFileEnv, filepath.Join(t.TempDir(), "db.json"))
+	repo, ok := Repository[UserGetter](DynamoDB).(*memoryRepository)
+	if !ok {
+		t.Fatalf("Repository with %s did not return a memory repository", MemoryDatabaseFileEnv)
+	}
+	if other := Repository[GameLister](DynamoDB); other != GameLister(repo) {
+		t.Errorf("Repository returned different memory repositories")
+	}
+}
+
+func TestMemoryRepositoryInterfaces(t *testing.T) {
+	// Repository panics if the memory repository does not implement a handler's interface.
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[UserSummaryBatchGetter](),
+		reflect.TypeFor[DiscordUserGetter](),
+		reflect.TypeFor[UserSubscriptionChecker](),
+		reflect.TypeFor[UserTimeUpdater](),
+		reflect.TypeFor[UserStatisticsUpdater](),
+		reflect.TypeFor[NotificationLister](),
+		reflect.TypeFor[NotificationDeleter](),
+		reflect.TypeFor[EventPaymentMarker](),
+		reflect.TypeFor[GameUpdater](),
+		reflect.TypeFor[GameReviewSetter](),
+		reflect.TypeFor[PositionCommentEditor](),
+		reflect.TypeFor[GameReviewCohortGetter](),
+		reflect.TypeFor[NewsfeedLister](),
+		reflect.TypeFor[NewsfeedInserter](),
+		reflect.TypeFor[NewsfeedDeleter](),
+		reflect.TypeFor[LeaderboardSetter](),
+		reflect.TypeFor[OpenClassicalLister](),
+		reflect.TypeFor[OpenClassicalRegistrar](),
+		reflect.TypeFor[OpenClassicalEditor](),
+		reflect.TypeFor[YearReviewGetter](),
+		reflect.TypeFor[YearReviewCalculator](),
+		reflect.TypeFor[ClubLister](),
+		reflect.TypeFor[ClubEditor](),
+		reflect.TypeFor[ClubMemberEditor](),
+		reflect.TypeFor[ExamGetter](),
+		reflect.TypeFor[ExamLister](),
+		reflect.TypeFor[ExamAnswerPutter](),
+		reflect.TypeFor[ExamRatingUpdater](),
+	} {
+		if !reflect.TypeFor[*memoryRepository]().Implements(typ) {
+			t.Errorf("memoryRepository does not implement %v", typ)
+		}
+	}
+}
@@ -39,6 +39,39 @@ type NewsfeedEntry struct {
 	TimelineId string `dynamodbav:"timelineId" json:"timelineId"`
 }
 
+type NewsfeedLister interface {
+	UserUpdater
+	TimelineBatchGetter
+
+	// ListNewsfeedEntries returns a list of NewsfeedEntries for the provided news feed ID. Usually
+	// the ID will be a user's username, but it could also be a cohort or the special value `ALL_USERS`.
+	// The optional parameter lastFetch can be used to limit how many results are returned.
+	ListNewsfeedEntries(newsfeedId, startKey, lastFetch string, limit int64) ([]NewsfeedEntry, string, error)
+}
+
+type NewsfeedInserter interface {
+	UserGetter
+	FollowerLister
+
+	// PutNewsfeedEntries inserts the provided NewsfeedEntries into the database. The number of
+	// successfully inserted entries is returned.
+	PutNewsfeedEntries(entries []NewsfeedEntry) (int, error)
+
+	// InsertPosterIntoNewsfeed inserts up to the latest 25 timeline entries from the given poster into
+	// the given newsfeed. The number of successfully inserted entries is returned.
+	InsertPosterIntoNewsfeed(newsfeedId, poster string) (int, error)
+}
+
+type NewsfeedDeleter interface {
+	// DeleteNewsfeedEntries deletes the NewsfeedEntries with the provided poster and timelineId.
+	// The number of successfully deleted entries is returned.
+	DeleteNewsfeedEntries(poster, timelineId string) (int, error)
+
+	// RemovePosterFromNewsfeed deletes any entries from the provided newsfeed that were posted by
+	// the given poster.
+	RemovePosterFromNewsfeed(newsfeedId, poster string) (int, error)
+}
+
 // PutNewsfeedEntries inserts the provided NewsfeedEntries into the database. The number of
 // successfully inserted entries is returned.
 func (repo *dynamoRepository) PutNewsfeedEntries(entries []NewsfeedEntry) (int, error) {
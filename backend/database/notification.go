@@ -1,11 +1,8 @@
 package database
 
 import (
-	"encoding/json"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 )
 
@@ -47,6 +44,9 @@ const (
 
 	// Notifications generated by a user creating a subscription
 	NotificationType_SubscriptionCreated NotificationType = "SUBSCRIPTION_CREATED"
+
+	// Notifications generated by a completed data export
+	NotificationType_DataExportReady NotificationType = "DATA_EXPORT_READY"
 )
 
 // Data for a notification
@@ -90,6 +90,9 @@ type Notification struct {
 
 	// Metadata for round robin start notifications
 	RoundRobinStartMetadata *RoundRobinStartMetadata `dynamodbav:"roundRobinStartMetadata,omitempty" json:"roundRobinStartMetadata,omitempty"`
+
+	// Metadata for data export notifications
+	DataExportMetadata *DataExportMetadata `dynamodbav:"dataExportMetadata,omitempty" json:"dataExportMetadata,omitempty"`
 }
 
 // Metadata for a game comment notification.
@@ -190,7 +193,9 @@ type RoundRobinStartMetadata struct {
 	Name string `dynamodbav:"name" json:"name"`
 }
 
-func SendEventBookedNotification(event *Event) error {
+// NewEventBookedEvent returns the outbox entry notifying the owner of the provided event
+// that it was booked.
+func NewEventBookedEvent(event *Event) *OutboxEntry {
 	e := struct {
 		Type    string `json:"type"`
 		Id      string `json:"id"`
@@ -202,10 +207,12 @@ func SendEventBookedNotification(event *Event) error {
 		Owner:   event.Owner,
 		IsGroup: event.MaxParticipants != 1,
 	}
-	return sendSqsEvent(e)
+	return newOutboxEntry(NotificationType_EventBooked, e)
 }
 
-func SendGameCommentEvent(game *Game, comment *PositionComment) error {
+// NewGameCommentEvent returns the outbox entry notifying the owner of the provided game
+// of the provided comment.
+func NewGameCommentEvent(cohort, id string, comment *PositionComment) *OutboxEntry {
 	event := struct {
 		Type string `json:"type"`
 		Game struct {
@@ -222,8 +229,8 @@ func SendGameCommentEvent(game *Game, comment *PositionComment) error {
 			Cohort string "json:\"cohort\""
 			Id     string "json:\"id\""
 		}{
-			Cohort: string(game.Cohort),
-			Id:     game.Id,
+			Cohort: cohort,
+			Id:     id,
 		},
 		Comment: struct {
 			Fen string "json:\"fen\""
@@ -233,10 +240,12 @@ func SendGameCommentEvent(game *Game, comment *PositionComment) error {
 			Id:  comment.Id,
 		},
 	}
-	return sendSqsEvent(event)
+	return newOutboxEntry(NotificationType_GameComment, event)
 }
 
-func SendGameReviewCompleteEvent(game *Game) error {
+// NewGameReviewCompleteEvent returns the outbox entry notifying the owner of the provided
+// game that its review is complete.
+func NewGameReviewCompleteEvent(cohort, id string) *OutboxEntry {
 	event := struct {
 		Type string `json:"type"`
 		Game struct {
@@ -249,37 +258,40 @@ func SendGameReviewCompleteEvent(game *Game) error {
 			Cohort string "json:\"cohort\""
 			Id     string "json:\"id\""
 		}{
-			Cohort: string(game.Cohort),
-			Id:     game.Id,
+			Cohort: cohort,
+			Id:     id,
 		},
 	}
-	return sendSqsEvent(event)
+	return newOutboxEntry(NotificationType_GameReviewComplete, event)
 }
 
-func SendFollowerEvent(f *FollowerEntry, cohort DojoCohort) error {
-	type follower struct {
+// NewFollowerEvent returns the outbox entry notifying poster that follower started following them.
+func NewFollowerEvent(poster, follower *User) *OutboxEntry {
+	type followerData struct {
 		Username    string `json:"username"`
 		DisplayName string `json:"displayName"`
 		Cohort      string `json:"cohort"`
 	}
 
 	event := struct {
-		Type     string   `json:"type"`
-		Username string   `json:"username"`
-		Follower follower `json:"follower"`
+		Type     string       `json:"type"`
+		Username string       `json:"username"`
+		Follower followerData `json:"follower"`
 	}{
 		Type:     string(NotificationType_NewFollower),
-		Username: f.Poster,
-		Follower: follower{
-			Username:    f.Follower,
-			DisplayName: f.FollowerDisplayName,
-			Cohort:      string(cohort),
+		Username: poster.Username,
+		Follower: followerData{
+			Username:    follower.Username,
+			DisplayName: follower.DisplayName,
+			Cohort:      string(follower.DojoCohort),
 		},
 	}
-	return sendSqsEvent(event)
+	return newOutboxEntry(NotificationType_NewFollower, event)
 }
 
-func SendTimelineCommentEvent(e *TimelineEntry, c *Comment) error {
+// NewTimelineCommentEvent returns the outbox entry notifying the owner of the provided
+// timeline entry of the provided comment.
+func NewTimelineCommentEvent(owner, id string, c *Comment) *OutboxEntry {
 	event := struct {
 		Type      string `json:"type"`
 		Owner     string `json:"owner"`
@@ -287,27 +299,31 @@ func SendTimelineCommentEvent(e *TimelineEntry, c *Comment) error {
 		CommentId string `json:"commentId"`
 	}{
 		Type:      string(NotificationType_TimelineComment),
-		Owner:     e.Owner,
-		Id:        e.Id,
+		Owner:     owner,
+		Id:        id,
 		CommentId: c.Id,
 	}
-	return sendSqsEvent(event)
+	return newOutboxEntry(NotificationType_TimelineComment, event)
 }
 
-func SendTimelineReactionEvent(e *TimelineEntry) error {
+// NewTimelineReactionEvent returns the outbox entry notifying the owner of the provided
+// timeline entry of a new reaction.
+func NewTimelineReactionEvent(owner, id string) *OutboxEntry {
 	event := struct {
 		Type  string `json:"type"`
 		Owner string `json:"owner"`
 		Id    string `json:"id"`
 	}{
 		Type:  string(NotificationType_TimelineReaction),
-		Owner: e.Owner,
-		Id:    e.Id,
+		Owner: owner,
+		Id:    id,
 	}
-	return sendSqsEvent(event)
+	return newOutboxEntry(NotificationType_TimelineReaction, event)
 }
 
-func SendClubJoinRequestEvent(club *Club) error {
+// NewClubJoinRequestEvent returns the outbox entry notifying the owner of the provided
+// club of a new join request.
+func NewClubJoinRequestEvent(club *Club) *OutboxEntry {
 	event := struct {
 		Type  string `json:"type"`
 		Id    string `json:"id"`
@@ -319,10 +335,12 @@ func SendClubJoinRequestEvent(club *Club) error {
 		Name:  club.Name,
 		Owner: club.Owner,
 	}
-	return sendSqsEvent(event)
+	return newOutboxEntry(NotificationType_NewClubJoinRequest, event)
 }
 
-func SendClubJoinRequestApprovedEvent(club *Club, username string) error {
+// NewClubJoinRequestApprovedEvent returns the outbox entry notifying username that their
+// request to join the provided club was approved.
+func NewClubJoinRequestApprovedEvent(club *Club, username string) *OutboxEntry {
 	event := struct {
 		Type     string `json:"type"`
 		Id       string `json:"id"`
@@ -334,10 +352,11 @@ func SendClubJoinRequestApprovedEvent(club *Club, username string) error {
 		Name:     club.Name,
 		Username: username,
 	}
-	return sendSqsEvent(event)
+	return newOutboxEntry(NotificationType_ClubJoinRequestApproved, event)
 }
 
-func SendCalendarInviteEvent(event *Event) error {
+// NewCalendarInviteEvent returns the outbox entry notifying the invitees of the provided event.
+func NewCalendarInviteEvent(event *Event) *OutboxEntry {
 	e := struct {
 		Type string `json:"type"`
 		Id   string `json:"id"`
@@ -345,10 +364,12 @@ func SendCalendarInviteEvent(event *Event) error {
 		Type: string(NotificationType_CalendarInvite),
 		Id:   event.Id,
 	}
-	return sendSqsEvent(e)
+	return newOutboxEntry(NotificationType_CalendarInvite, e)
 }
 
-func SendSubscriptionCreatedEvent(username string) error {
+// NewSubscriptionCreatedEvent returns the outbox entry notifying username that their
+// subscription was created.
+func NewSubscriptionCreatedEvent(username string) *OutboxEntry {
 	e := struct {
 		Type     string `json:"type"`
 		Username string `json:"username"`
@@ -356,19 +377,17 @@ func SendSubscriptionCreatedEvent(username string) error {
 		Type:     string(NotificationType_SubscriptionCreated),
 		Username: username,
 	}
-	return sendSqsEvent(e)
+	return newOutboxEntry(NotificationType_SubscriptionCreated, e)
 }
 
-func sendSqsEvent(event any) error {
-	body, err := json.Marshal(event)
-	if err != nil {
-		return errors.Wrap(500, "Temporary server error", "Failed to marshal notification event", err)
-	}
-	_, err = sqsService.SendMessage(&sqs.SendMessageInput{
-		MessageBody: aws.String(string(body)),
-		QueueUrl:    aws.String(sqsUrl),
-	})
-	return errors.Wrap(500, "Temporary server error", "Failed to send SQS message", err)
+type NotificationLister interface {
+	// ListNotifications returns a list of notifications for the provided username.
+	ListNotifications(username string, startKey string) ([]Notification, string, error)
+}
+
+type NotificationDeleter interface {
+	// DeleteNotification removes the notification with the specified key from the database.
+	DeleteNotification(username, id string) error
 }
 
 // ListNotifications returns a list of notifications for the provided username.
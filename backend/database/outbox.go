@@ -0,0 +1,359 @@
+package database
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// OutboxStatus is the delivery status of an OutboxEntry.
+type OutboxStatus string
+
+const (
+	// The entry is waiting to be sent to SQS by the relay.
+	OutboxStatus_Pending OutboxStatus = "PENDING"
+
+	// The relay failed to send the entry MaxOutboxAttempts times. The entry is not retried
+	// until it is replayed by an admin.
+	OutboxStatus_Failed OutboxStatus = "FAILED"
+)
+
+// MaxOutboxAttempts is the number of times the relay tries to send an OutboxEntry before
+// marking it as failed.
+const MaxOutboxAttempts = 5
+
+// OutboxEntry is a notification event waiting to be sent to the notification event queue.
+// Entries are saved in the same transaction as the change which generated them, so that the
+// event is sent if and only if the change is committed. The relay deletes entries once they
+// are sent, so events are delivered at least once.
+type OutboxEntry struct {
+	// The delivery status of the entry.
+	Status OutboxStatus `dynamodbav:"status" json:"status"`
+
+	// The sort key of the entry, in the form createdAt_uuid, so that entries are ordered
+	// by time within a status.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The type of the notification event.
+	Type NotificationType `dynamodbav:"type" json:"type"`
+
+	// The JSON body of the SQS message.
+	Body string `dynamodbav:"body" json:"body"`
+
+	// The number of failed attempts to send the entry.
+	Attempts int `dynamodbav:"attempts" json:"attempts"`
+
+	// The error returned by the most recent failed attempt.
+	LastError string `dynamodbav:"lastError,omitempty" json:"lastError,omitempty"`
+
+	// The time the entry was created, in RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The time the entry was last updated, in RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+}
+
+// newOutboxEntry returns a pending OutboxEntry of the provided type whose body is the JSON
+// encoding of event.
+func newOutboxEntry(eventType NotificationType, event any) *OutboxEntry {
+	// The events contain only strings and bools, so marshaling cannot fail.
+	body, _ := json.Marshal(event)
+	createdAt := time.Now().Format(time.RFC3339)
+	return &OutboxEntry{
+		Status:    OutboxStatus_Pending,
+		Id:        createdAt + "_" + uuid.NewString(),
+		Type:      eventType,
+		Body:      string(body),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+// SendOutboxEntry sends the body of the provided entry to the notification event queue.
+func SendOutboxEntry(entry *OutboxEntry) error {
+	_, err := sqsService.SendMessage(&sqs.SendMessageInput{
+		MessageBody: aws.String(entry.Body),
+		QueueUrl:    aws.String(sqsUrl),
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to send SQS message", err)
+}
+
+// OutboxRelayer provides an interface for draining the outbox.
+type OutboxRelayer interface {
+	// ListOutboxEntries returns the entries with the provided status, oldest first.
+	// The next start key is also returned.
+	ListOutboxEntries(status OutboxStatus, startKey string) ([]OutboxEntry, string, error)
+
+	// DeleteOutboxEntry removes the provided entry after it has been sent. Deleting an entry
+	// which no longer exists is not an error.
+	DeleteOutboxEntry(entry *OutboxEntry) error
+
+	// RecordOutboxFailure increments the attempts of the provided pending entry and saves the
+	// cause as its last error. Once the entry reaches MaxOutboxAttempts, it is moved to
+	// OutboxStatus_Failed. The entry after updating is returned.
+	RecordOutboxFailure(entry *OutboxEntry, cause error) (*OutboxEntry, error)
+}
+
+// OutboxReplayer provides an interface for inspecting and replaying stuck outbox entries.
+type OutboxReplayer interface {
+	UserGetter
+	AuditEntryPutter
+
+	// ListOutboxEntries returns the entries with the provided status, oldest first.
+	// The next start key is also returned.
+	ListOutboxEntries(status OutboxStatus, startKey string) ([]OutboxEntry, string, error)
+
+	// ReplayOutboxEntry moves the failed entry with the provided id back to OutboxStatus_Pending
+	// with its attempts reset, so that the relay sends it again. The entry after updating is returned.
+	ReplayOutboxEntry(id string) (*OutboxEntry, error)
+}
+
+// outboxPuts returns the transaction items which save the provided entries.
+func outboxPuts(entries []*OutboxEntry) ([]*dynamodb.TransactWriteItem, error) {
+	items := make([]*dynamodb.TransactWriteItem, 0, len(entries))
+	for _, entry := range entries {
+		item, err := dynamodbattribute.MarshalMap(entry)
+		if err != nil {
+			return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal outbox entry", err)
+		}
+		items = append(items, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(id)"),
+				TableName:           aws.String(outboxTable),
+			},
+		})
+	}
+	return items, nil
+}
+
+// transactWithOutbox runs a transaction containing the provided item followed by the provided
+// outbox entries. If the condition of the item fails, a ConditionalCheckFailedException is
+// returned, as it would be for a single-item request.
+func (repo *dynamoRepository) transactWithOutbox(item *dynamodb.TransactWriteItem, entries []*OutboxEntry) error {
+	puts, err := outboxPuts(entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.svc.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]*dynamodb.TransactWriteItem{item}, puts...),
+	})
+	var canceled *dynamodb.TransactionCanceledException
+	if transactionCancellationCode(err, 0) == conditionalCheckFailedReason && errors.As(err, &canceled) {
+		reason := canceled.CancellationReasons[0]
+		return &dynamodb.ConditionalCheckFailedException{Message_: reason.Message, Item: reason.Item}
+	}
+	return err
+}
+
+// updateItemWithOutbox applies the provided UpdateItemInput and saves the provided outbox entries
+// in a single transaction. The item after updating is unmarshaled into out. Errors are returned
+// unwrapped, as in updateItem. If there are no entries, updateItem is used instead.
+func (repo *dynamoRepository) updateItemWithOutbox(input *dynamodb.UpdateItemInput, entries []*OutboxEntry, out any) error {
+	if len(entries) == 0 {
+		return repo.updateItem(input, out)
+	}
+
+	err := repo.transactWithOutbox(&dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			Key:                                 input.Key,
+			ConditionExpression:                 input.ConditionExpression,
+			UpdateExpression:                    input.UpdateExpression,
+			ExpressionAttributeNames:            input.ExpressionAttributeNames,
+			ExpressionAttributeValues:           input.ExpressionAttributeValues,
+			ReturnValuesOnConditionCheckFailure: input.ReturnValuesOnConditionCheckFailure,
+			TableName:                           input.TableName,
+		},
+	}, entries)
+	if err != nil {
+		return err
+	}
+
+	// Transactions cannot return the updated item, so it is read back consistently.
+	result, err := repo.svc.GetItem(&dynamodb.GetItemInput{
+		Key:            input.Key,
+		ConsistentRead: aws.Bool(true),
+		TableName:      input.TableName,
+	})
+	if err != nil {
+		return err
+	}
+	return dynamodbattribute.UnmarshalMap(result.Item, out)
+}
+
+// putItemWithOutbox saves the provided PutItemInput and outbox entries in a single transaction.
+// Errors are returned unwrapped, as by PutItem. If there are no entries, PutItem is used instead.
+func (repo *dynamoRepository) putItemWithOutbox(input *dynamodb.PutItemInput, entries []*OutboxEntry) error {
+	if len(entries) == 0 {
+		_, err := repo.svc.PutItem(input)
+		return err
+	}
+
+	return repo.transactWithOutbox(&dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			Item:                      input.Item,
+			ConditionExpression:       input.ConditionExpression,
+			ExpressionAttributeNames:  input.ExpressionAttributeNames,
+			ExpressionAttributeValues: input.ExpressionAttributeValues,
+			TableName:                 input.TableName,
+		},
+	}, entries)
+}
+
+// ListOutboxEntries returns the entries with the provided status, oldest first.
+// The next start key is also returned.
+func (repo *dynamoRepository) ListOutboxEntries(status OutboxStatus, startKey string) ([]OutboxEntry, string, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {S: aws.String(string(status))},
+		},
+		TableName: aws.String(outboxTable),
+	}
+
+	var entries []OutboxEntry
+	lastKey, err := repo.query(input, startKey, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, lastKey, nil
+}
+
+// outboxKey returns the DynamoDB key of the outbox entry with the provided status and id.
+func outboxKey(status OutboxStatus, id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"status": {S: aws.String(string(status))},
+		"id":     {S: aws.String(id)},
+	}
+}
+
+// DeleteOutboxEntry removes the provided entry after it has been sent. Deleting an entry
+// which no longer exists is not an error.
+func (repo *dynamoRepository) DeleteOutboxEntry(entry *OutboxEntry) error {
+	input := &dynamodb.DeleteItemInput{
+		Key:       outboxKey(entry.Status, entry.Id),
+		TableName: aws.String(outboxTable),
+	}
+	_, err := repo.svc.DeleteItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB DeleteItem call", err)
+}
+
+// RecordOutboxFailure increments the attempts of the provided pending entry and saves the
+// cause as its last error. Once the entry reaches MaxOutboxAttempts, it is moved to
+// OutboxStatus_Failed. The entry after updating is returned.
+func (repo *dynamoRepository) RecordOutboxFailure(entry *OutboxEntry, cause error) (*OutboxEntry, error) {
+	updated := *entry
+	updated.Attempts++
+	updated.LastError = cause.Error()
+	updated.UpdatedAt = time.Now().Format(time.RFC3339)
+
+	if updated.Attempts < MaxOutboxAttempts {
+		input := &dynamodb.UpdateItemInput{
+			Key:                 outboxKey(entry.Status, entry.Id),
+			ConditionExpression: aws.String("attribute_exists(id) AND #attempts = :attempts"),
+			UpdateExpression:    aws.String("SET #attempts = :newAttempts, #lastError = :lastError, #updatedAt = :updatedAt"),
+			ExpressionAttributeNames: map[string]*string{
+				"#attempts":  aws.String("attempts"),
+				"#lastError": aws.String("lastError"),
+				"#updatedAt": aws.String("updatedAt"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":attempts":    {N: aws.String(strconv.Itoa(entry.Attempts))},
+				":newAttempts": {N: aws.String(strconv.Itoa(updated.Attempts))},
+				":lastError":   {S: aws.String(updated.LastError)},
+				":updatedAt":   {S: aws.String(updated.UpdatedAt)},
+			},
+			TableName: aws.String(outboxTable),
+		}
+		if _, err := repo.svc.UpdateItem(input); err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+				return nil, errors.NewConflict("Invalid request: outbox entry was sent or updated by another request",
+					"DynamoDB conditional check failed", entry.Id)
+			}
+			return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem call", err)
+		}
+		return &updated, nil
+	}
+
+	updated.Status = OutboxStatus_Failed
+	if err := repo.moveOutboxEntry(entry, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ReplayOutboxEntry moves the failed entry with the provided id back to OutboxStatus_Pending
+// with its attempts reset, so that the relay sends it again. The entry after updating is returned.
+func (repo *dynamoRepository) ReplayOutboxEntry(id string) (*OutboxEntry, error) {
+	entry := OutboxEntry{}
+	err := repo.getItem(&dynamodb.GetItemInput{
+		Key:       outboxKey(OutboxStatus_Failed, id),
+		TableName: aws.String(outboxTable),
+	}, &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := entry
+	updated.Status = OutboxStatus_Pending
+	updated.Attempts = 0
+	updated.UpdatedAt = time.Now().Format(time.RFC3339)
+	if err := repo.moveOutboxEntry(&entry, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// moveOutboxEntry replaces the entry from with the entry to, which has a different status, in a
+// single transaction. A 409 error is returned if from was changed or removed after it was read.
+func (repo *dynamoRepository) moveOutboxEntry(from, to *OutboxEntry) error {
+	item, err := dynamodbattribute.MarshalMap(to)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal outbox entry", err)
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Delete: &dynamodb.Delete{
+					Key:                 outboxKey(from.Status, from.Id),
+					ConditionExpression: aws.String("attribute_exists(id) AND #attempts = :attempts"),
+					ExpressionAttributeNames: map[string]*string{
+						"#attempts": aws.String("attempts"),
+					},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":attempts": {N: aws.String(strconv.Itoa(from.Attempts))},
+					},
+					TableName: aws.String(outboxTable),
+				},
+			},
+			{
+				Put: &dynamodb.Put{
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(id)"),
+					TableName:           aws.String(outboxTable),
+				},
+			},
+		},
+	}
+	if _, err := repo.svc.TransactWriteItems(input); err != nil {
+		if transactionCancellationCode(err, 0) == conditionalCheckFailedReason ||
+			transactionCancellationCode(err, 1) == conditionalCheckFailedReason {
+			return errors.NewConflict("Invalid request: outbox entry was sent or updated by another request",
+				"Outbox entry transaction condition failed", from.Id)
+		}
+		return errors.Wrap(500, "Temporary server error", "Failed DynamoDB TransactWriteItems call", err)
+	}
+	return nil
+}
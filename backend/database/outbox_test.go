@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+)
+
+func outboxEntries(t *testing.T, repo *memoryRepository, status OutboxStatus) []OutboxEntry {
+	t.Helper()
+	entries, _, err := repo.ListOutboxEntries(status, "")
+	if err != nil {
+		t.Fatalf("ListOutboxEntries(%s) got err %v", status, err)
+	}
+	return entries
+}
+
+func TestMemoryOutboxSavedWithWrite(t *testing.T) {
+	repo := NewMemoryRepository()
+	alice, _ := repo.CreateUser("alice", "alice@example.com", "Alice", nil)
+	bob, _ := repo.CreateUser("bob", "bob@example.com", "Bob", nil)
+
+	// A failed write does not save its outbox entries.
+	missing := &User{Username: "missing"}
+	if _, err := repo.CreateFollower(alice, missing, NewFollowerEvent(alice, missing)); errorCode(err) != 404 {
+		t.Fatalf("CreateFollower with missing user got err %v, want 404", err)
+	}
+	if got := outboxEntries(t, repo, OutboxStatus_Pending); len(got) != 0 {
+		t.Errorf("CreateFollower with missing user saved outbox entries %v", got)
+	}
+
+	event := NewFollowerEvent(alice, bob)
+	if _, err := repo.CreateFollower(alice, bob, event); err != nil {
+		t.Fatalf("CreateFollower got err %v", err)
+	}
+	got := outboxEntries(t, repo, OutboxStatus_Pending)
+	if len(got) != 1 || got[0].Id != event.Id || got[0].Body != event.Body {
+		t.Errorf("CreateFollower saved outbox entries %v, want [%v]", got, *event)
+	}
+}
+
+func TestMemoryRecordOutboxFailure(t *testing.T) {
+	repo := NewMemoryRepository()
+	entry := NewSubscriptionCreatedEvent("alice")
+	if err := repo.putOutbox([]*OutboxEntry{entry}); err != nil {
+		t.Fatalf("putOutbox got err %v", err)
+	}
+
+	cause := fmt.Errorf("queue unavailable")
+	for i := 1; i < MaxOutboxAttempts; i++ {
+		updated, err := repo.RecordOutboxFailure(entry, cause)
+		if err != nil {
+			t.Fatalf("RecordOutboxFailure attempt %d got err %v", i, err)
+		}
+		if updated.Status != OutboxStatus_Pending || updated.Attempts != i || updated.LastError != cause.Error() {
+			t.Fatalf("RecordOutboxFailure attempt %d got %+v, want pending with %d attempts", i, updated, i)
+		}
+
+		// Recording a failure against a stale copy of the entry is a conflict.
+		if _, err := repo.RecordOutboxFailure(entry, cause); errorCode(err) != 409 {
+			t.Errorf("RecordOutboxFailure with stale entry got err %v, want 409", err)
+		}
+		entry = updated
+	}
+
+	updated, err := repo.RecordOutboxFailure(entry, cause)
+	if err != nil {
+		t.Fatalf("RecordOutboxFailure final attempt got err %v", err)
+	}
+	if updated.Status != OutboxStatus_Failed || updated.Attempts != MaxOutboxAttempts {
+		t.Errorf("RecordOutboxFailure final attempt got %+v, want failed", updated)
+	}
+	if got := outboxEntries(t, repo, OutboxStatus_Pending); len(got) != 0 {
+		t.Errorf("Pending entries after final attempt = %v, want none", got)
+	}
+	if got := outboxEntries(t, repo, OutboxStatus_Failed); len(got) != 1 {
+		t.Errorf("Failed entries after final attempt = %v, want 1", got)
+	}
+
+	replayed, err := repo.ReplayOutboxEntry(entry.Id)
+	if err != nil {
+		t.Fatalf("ReplayOutboxEntry got err %v", err)
+	}
+	if replayed.Status != OutboxStatus_Pending || replayed.Attempts != 0 {
+		t.Errorf("ReplayOutboxEntry got %+v, want pending with 0 attempts", replayed)
+	}
+	if got := outboxEntries(t, repo, OutboxStatus_Failed); len(got) != 0 {
+		t.Errorf("Failed entries after replay = %v, want none", got)
+	}
+
+	// The entry is no longer failed, so it cannot be replayed again.
+	if _, err := repo.ReplayOutboxEntry(entry.Id); errorCode(err) != 404 {
+		t.Errorf("ReplayOutboxEntry of pending entry got err %v, want 404", err)
+	}
+}
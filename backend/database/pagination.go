@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"iter"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// PageFetcher fetches a single page of items starting at the provided start key. It returns the
+// items and the start key of the next page, which is empty if there are no more pages. Most
+// repository list and scan methods can be adapted to a PageFetcher with a closure, for example:
+//
+//	func(startKey string) ([]*User, string, error) {
+//		return repository.ListUserRatings(cohort, startKey)
+//	}
+type PageFetcher[T any] func(startKey string) ([]T, string, error)
+
+// All returns an iterator over every item returned by fetch, across all pages. Pages are fetched
+// lazily as the iterator is consumed, so breaking out of the loop stops further requests.
+//
+// If fetch returns an error, or ctx is done before the next page is fetched, the iterator yields
+// the error with the zero value of T and stops.
+func All[T any](ctx context.Context, fetch PageFetcher[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		startKey := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, errors.Wrap(500, "Temporary server error", "Context done before fetching next page", err))
+				return
+			}
+
+			items, lastKey, err := fetch(startKey)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if lastKey == "" {
+				return
+			}
+			startKey = lastKey
+		}
+	}
+}
+
+// Collect returns every item returned by fetch, across all pages. If an error occurs, the items
+// read before the error are returned along with it.
+func Collect[T any](ctx context.Context, fetch PageFetcher[T]) ([]T, error) {
+	var result []T
+	for item, err := range All(ctx, fetch) {
+		if err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// fakePages returns a PageFetcher which serves the provided pages, keyed by their index, and
+// records the start keys it was called with.
+func fakePages(pages [][]int, calls *[]string) PageFetcher[int] {
+	return func(startKey string) ([]int, string, error) {
+		*calls = append(*calls, startKey)
+		i := 0
+		if startKey != "" {
+			fmt.Sscan(startKey, &i)
+		}
+		lastKey := ""
+		if i+1 < len(pages) {
+			lastKey = fmt.Sprint(i + 1)
+		}
+		return pages[i], lastKey, nil
+	}
+}
+
+func TestAll(t *testing.T) {
+	t.Run("AllPages", func(t *testing.T) {
+		var calls []string
+		var got []int
+		for item, err := range All(context.Background(), fakePages([][]int{{1, 2}, {}, {3}}, &calls)) {
+			if err != nil {
+				t.Fatalf("All got err %v, want nil", err)
+			}
+			got = append(got, item)
+		}
+
+		if diff := cmp.Diff([]int{1, 2, 3}, got); diff != "" {
+			t.Errorf("All items mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"", "1", "2"}, calls); diff != "" {
+			t.Errorf("All start keys mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("EarlyTermination", func(t *testing.T) {
+		var calls []string
+		for item := range All(context.Background(), fakePages([][]int{{1, 2}, {3}}, &calls)) {
+			if item == 2 {
+				break
+			}
+		}
+
+		if len(calls) != 1 {
+			t.Errorf("All fetched %d pages, want 1", len(calls))
+		}
+	})
+
+	t.Run("FetchError", func(t *testing.T) {
+		cause := errors.New(500, "Temporary server error", "")
+		fetch := func(startKey string) ([]int, string, error) {
+			if startKey == "" {
+				return []int{1}, "next", nil
+			}
+			return nil, "", cause
+		}
+
+		got, err := Collect(context.Background(), fetch)
+		if err != cause {
+			t.Errorf("Collect got err %v, want %v", err, cause)
+		}
+		if diff := cmp.Diff([]int{1}, got); diff != "" {
+			t.Errorf("Collect items mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var calls []string
+		var got []int
+		var gotErr error
+		for item, err := range All(ctx, fakePages([][]int{{1}, {2}}, &calls)) {
+			if err != nil {
+				gotErr = err
+				break
+			}
+			got = append(got, item)
+			cancel()
+		}
+
+		if !errors.Is(gotErr, context.Canceled) {
+			t.Errorf("All got err %v, want context.Canceled", gotErr)
+		}
+		if diff := cmp.Diff([]int{1}, got); diff != "" {
+			t.Errorf("All items mismatch (-want +got):\n%s", diff)
+		}
+		if len(calls) != 1 {
+			t.Errorf("All fetched %d pages, want 1", len(calls))
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// RatingDistribution is the number of users with each current rating in a rating system. Each
+// user is counted in the rating system they prefer. The distributions are calculated daily by the
+// user statistics update, so that cohort boundaries can be compared without reading every user.
+type RatingDistribution struct {
+	// The rating system of the distribution. The hash key of the table.
+	RatingSystem RatingSystem `dynamodbav:"ratingSystem" json:"ratingSystem"`
+
+	// The number of users keyed by their current rating. DynamoDB map keys must be strings,
+	// so the ratings are in strconv.Itoa format.
+	Counts map[string]int `dynamodbav:"counts" json:"counts"`
+
+	// The time the distribution was calculated, in time.RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+}
+
+// NewRatingDistributions returns an empty distribution for each rating system, so that saving
+// them replaces the distributions of rating systems which no longer have users.
+func NewRatingDistributions() map[RatingSystem]*RatingDistribution {
+	now := time.Now().Format(time.RFC3339)
+	distributions := make(map[RatingSystem]*RatingDistribution, len(ratingSystems))
+	for _, rs := range ratingSystems {
+		distributions[rs] = &RatingDistribution{RatingSystem: rs, Counts: make(map[string]int), UpdatedAt: now}
+	}
+	return distributions
+}
+
+// AddRatingDistribution counts the provided user's current rating in their preferred rating
+// system. Users without a positive rating in their preferred rating system are skipped.
+func AddRatingDistribution(distributions map[RatingSystem]*RatingDistribution, user *User) {
+	distribution := distributions[user.RatingSystem]
+	rating := user.Ratings[user.RatingSystem]
+	if distribution == nil || rating == nil || rating.CurrentRating <= 0 {
+		return
+	}
+	distribution.Counts[strconv.Itoa(rating.CurrentRating)]++
+}
+
+type RatingDistributionWriter interface {
+	// PutRatingDistributions saves the provided rating distributions, replacing the existing
+	// distributions of their rating systems. The number of distributions saved is returned.
+	PutRatingDistributions(distributions []*RatingDistribution) (int, error)
+}
+
+type RatingDistributionLister interface {
+	// ListRatingDistributions returns the saved distribution of each rating system.
+	ListRatingDistributions() ([]RatingDistribution, error)
+}
+
+// PutRatingDistributions saves the provided rating distributions, replacing the existing
+// distributions of their rating systems. The number of distributions saved is returned.
+func (repo *dynamoRepository) PutRatingDistributions(distributions []*RatingDistribution) (int, error) {
+	return batchWriteObjects(repo, distributions, ratingDistributionTable)
+}
+
+// ListRatingDistributions returns the saved distribution of each rating system.
+func (repo *dynamoRepository) ListRatingDistributions() ([]RatingDistribution, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(ratingDistributionTable),
+	}
+
+	var result []RatingDistribution
+	startKey := ""
+	for {
+		var distributions []RatingDistribution
+		lastKey, err := repo.scan(input, startKey, &distributions)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, distributions...)
+		if lastKey == "" {
+			return result, nil
+		}
+		startKey = lastKey
+	}
+}
@@ -0,0 +1,268 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// RatingHistoryDailyTTL is how long daily rating history points are kept. Older history
+	// only has one point per week.
+	RatingHistoryDailyTTL = 90 * 24 * time.Hour
+
+	ratingHistoryDaily  = "D"
+	ratingHistoryWeekly = "W"
+)
+
+// RatingHistoryPoint is a single point in the rating history table. Each point is saved twice:
+// as a daily point, which expires after RatingHistoryDailyTTL, and as the weekly point of its
+// week, which is overwritten by later points in the same week and never expires.
+type RatingHistoryPoint struct {
+	// The username of the user. The hash key of the table.
+	Username string `dynamodbav:"username" json:"-"`
+
+	// The range key of the table, in the form ratingSystem#D#date for daily points and
+	// ratingSystem#W#weekStartDate for weekly points. Dates are in time.DateOnly format.
+	SortKey string `dynamodbav:"sortKey" json:"-"`
+
+	// The rating system of the point.
+	RatingSystem RatingSystem `dynamodbav:"ratingSystem" json:"ratingSystem"`
+
+	// The time of the rating, in time.RFC3339 format.
+	Date string `dynamodbav:"date" json:"date"`
+
+	// The rating the user had at the given date.
+	Rating int `dynamodbav:"rating" json:"rating"`
+
+	// The time the daily point expires, in Unix seconds. Not set on weekly points.
+	ExpirationTime int64 `dynamodbav:"expirationTime,omitempty" json:"-"`
+}
+
+// NewRatingHistoryPoints returns the daily and weekly points recording that the provided user
+// had the provided rating at time t.
+func NewRatingHistoryPoints(username string, system RatingSystem, t time.Time, rating int) []RatingHistoryPoint {
+	t = t.UTC()
+	weekStart := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	return []RatingHistoryPoint{
+		{
+			Username:       username,
+			SortKey:        ratingHistorySortKey(system, ratingHistoryDaily, t.Format(time.DateOnly)),
+			RatingSystem:   system,
+			Date:           t.Format(time.RFC3339),
+			Rating:         rating,
+			ExpirationTime: t.Add(RatingHistoryDailyTTL).Unix(),
+		},
+		{
+			Username:     username,
+			SortKey:      ratingHistorySortKey(system, ratingHistoryWeekly, weekStart.Format(time.DateOnly)),
+			RatingSystem: system,
+			Date:         t.Format(time.RFC3339),
+			Rating:       rating,
+		},
+	}
+}
+
+// ratingHistorySortKey returns the sort key of a rating history point.
+func ratingHistorySortKey(system RatingSystem, kind, date string) string {
+	return string(system) + "#" + kind + "#" + date
+}
+
+// ratingHistoryKeyRange returns the bounds of the sort keys of the provided kind of points
+// between startDate and endDate. Weekly points are keyed by the start of their week, so the
+// lower bound of weekly points is a week earlier.
+func ratingHistoryKeyRange(system RatingSystem, kind, startDate, endDate string) (string, string) {
+	if kind == ratingHistoryWeekly && startDate != "" {
+		if t, err := time.Parse(time.DateOnly, startDate[:min(len(startDate), len(time.DateOnly))]); err == nil {
+			startDate = t.AddDate(0, 0, -7).Format(time.DateOnly)
+		}
+	}
+	upper := "~"
+	if endDate != "" {
+		upper = endDate + "~"
+	}
+	return ratingHistorySortKey(system, kind, startDate), ratingHistorySortKey(system, kind, upper)
+}
+
+// dedupeRatingHistory returns the provided points with only the last point of each key, since a
+// batch write cannot contain the same key twice.
+func dedupeRatingHistory(points []RatingHistoryPoint) []RatingHistoryPoint {
+	index := make(map[string]int, len(points))
+	result := make([]RatingHistoryPoint, 0, len(points))
+	for _, p := range points {
+		key := p.Username + "|" + p.SortKey
+		if i, ok := index[key]; ok {
+			result[i] = p
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, p)
+	}
+	return result
+}
+
+// mergeRatingHistory combines the daily and weekly points of a rating system into a single
+// history sorted by date. Daily points are used from cutoff onwards, and weekly points before
+// it. Points outside of startDate and endDate are removed.
+func mergeRatingHistory(daily, weekly []RatingHistoryPoint, cutoff, startDate, endDate string) []RatingHistory {
+	var result []RatingHistory
+	add := func(p RatingHistoryPoint) {
+		if p.Date < startDate || (endDate != "" && p.Date[:min(len(p.Date), len(time.DateOnly))] > endDate) {
+			return
+		}
+		result = append(result, RatingHistory{Date: p.Date, Rating: p.Rating})
+	}
+
+	for _, p := range weekly {
+		if p.Date < cutoff {
+			add(p)
+		}
+	}
+	for _, p := range daily {
+		if p.Date >= cutoff {
+			add(p)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+	return result
+}
+
+// listRatingHistory returns the history between startDate and endDate, using fetch to get the
+// daily and weekly points. Only the kinds of points which can be in the merged history are fetched.
+func listRatingHistory(fetch func(kind, startDate, endDate string) ([]RatingHistoryPoint, error), startDate, endDate string) ([]RatingHistory, error) {
+	cutoff := ratingHistoryCutoff()
+
+	var daily, weekly []RatingHistoryPoint
+	var err error
+	if endDate == "" || endDate >= cutoff {
+		if daily, err = fetch(ratingHistoryDaily, max(startDate, cutoff), endDate); err != nil {
+			return nil, err
+		}
+	}
+	if startDate < cutoff {
+		if weekly, err = fetch(ratingHistoryWeekly, startDate, endDate); err != nil {
+			return nil, err
+		}
+	}
+	return mergeRatingHistory(daily, weekly, cutoff, startDate, endDate), nil
+}
+
+// ratingHistoryCutoff returns the date before which only weekly points are used.
+func ratingHistoryCutoff() string {
+	return time.Now().Add(-RatingHistoryDailyTTL).UTC().Format(time.DateOnly)
+}
+
+type RatingHistoryWriter interface {
+	// PutRatingHistory saves the provided rating history points. The number of points
+	// saved is returned.
+	PutRatingHistory(points []RatingHistoryPoint) (int, error)
+}
+
+type RatingHistoryLister interface {
+	// ListRatingHistory returns the rating history of the provided user and rating system
+	// between startDate and endDate, inclusive, sorted by date. Dates are in time.DateOnly
+	// format and either may be empty.
+	ListRatingHistory(username string, system RatingSystem, startDate, endDate string) ([]RatingHistory, error)
+}
+
+// GetRatingHistories returns the rating history since startDate of each of the provided user's
+// rating systems. Points in the user's legacy RatingHistories which are older than the rating
+// history table's points are included, so that unmigrated history is not lost.
+func GetRatingHistories(repo RatingHistoryLister, user *User, startDate string) (map[RatingSystem][]RatingHistory, error) {
+	result := make(map[RatingSystem][]RatingHistory)
+	for system := range user.Ratings {
+		history, err := GetRatingHistory(repo, user, system, startDate, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(history) > 0 {
+			result[system] = history
+		}
+	}
+	return result, nil
+}
+
+// GetRatingHistory returns the rating history between startDate and endDate, inclusive, of the
+// provided user in a single rating system, including legacy points as described in
+// GetRatingHistories. Dates are in time.DateOnly format and either may be empty.
+func GetRatingHistory(repo RatingHistoryLister, user *User, system RatingSystem, startDate, endDate string) ([]RatingHistory, error) {
+	history, err := repo.ListRatingHistory(user.Username, system, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy []RatingHistory
+	for _, item := range user.RatingHistories[system] {
+		if item.Date < startDate || (endDate != "" && item.Date[:min(len(item.Date), len(time.DateOnly))] > endDate) {
+			continue
+		}
+		if len(history) == 0 || item.Date < history[0].Date {
+			legacy = append(legacy, item)
+		}
+	}
+	return append(legacy, history...), nil
+}
+
+type RatingHistoryGetter interface {
+	UserGetter
+	RatingHistoryLister
+}
+
+type RatingForecaster interface {
+	UserGetter
+	RatingHistoryLister
+	UserStatisticsGetter
+}
+
+// PutRatingHistory saves the provided rating history points. The number of points
+// saved is returned.
+func (repo *dynamoRepository) PutRatingHistory(points []RatingHistoryPoint) (int, error) {
+	return batchWriteObjects(repo, dedupeRatingHistory(points), ratingHistoryTable)
+}
+
+// ListRatingHistory returns the rating history of the provided user and rating system
+// between startDate and endDate, inclusive, sorted by date. Dates are in time.DateOnly
+// format and either may be empty.
+func (repo *dynamoRepository) ListRatingHistory(username string, system RatingSystem, startDate, endDate string) ([]RatingHistory, error) {
+	return listRatingHistory(func(kind, startDate, endDate string) ([]RatingHistoryPoint, error) {
+		return repo.listRatingHistoryPoints(username, system, kind, startDate, endDate)
+	}, startDate, endDate)
+}
+
+// listRatingHistoryPoints returns all points of the provided kind between startDate and endDate.
+func (repo *dynamoRepository) listRatingHistoryPoints(username string, system RatingSystem, kind, startDate, endDate string) ([]RatingHistoryPoint, error) {
+	lower, upper := ratingHistoryKeyRange(system, kind, startDate, endDate)
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#username = :username AND #sortKey BETWEEN :lower AND :upper"),
+		ExpressionAttributeNames: map[string]*string{
+			"#username": aws.String("username"),
+			"#sortKey":  aws.String("sortKey"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+			":lower":    {S: aws.String(lower)},
+			":upper":    {S: aws.String(upper)},
+		},
+		TableName: aws.String(ratingHistoryTable),
+	}
+
+	var result []RatingHistoryPoint
+	startKey := ""
+	for {
+		var points []RatingHistoryPoint
+		lastKey, err := repo.query(input, startKey, &points)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, points...)
+		if lastKey == "" {
+			return result, nil
+		}
+		startKey = lastKey
+	}
+}
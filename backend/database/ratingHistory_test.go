@@ -0,0 +1,139 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoryListRatingHistory(t *testing.T) {
+	repo := NewMemoryRepository()
+	today := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	daysAgo := func(days int) time.Time { return today.AddDate(0, 0, -days) }
+
+	var points []RatingHistoryPoint
+	for _, p := range []struct {
+		t      time.Time
+		rating int
+	}{
+		{daysAgo(200), 1400},
+		{daysAgo(100), 1450},
+		{daysAgo(10), 1500},
+		{daysAgo(2), 1510},
+		{daysAgo(2).Add(time.Hour), 1520},
+	} {
+		points = append(points, NewRatingHistoryPoints("alice", Chesscom, p.t, p.rating)...)
+	}
+	points = append(points, NewRatingHistoryPoints("alice", Lichess, daysAgo(2), 1900)...)
+	points = append(points, NewRatingHistoryPoints("bob", Chesscom, daysAgo(2), 1000)...)
+	if _, err := repo.PutRatingHistory(points); err != nil {
+		t.Fatalf("PutRatingHistory got err %v", err)
+	}
+
+	point := func(days int, rating int) RatingHistory {
+		t := daysAgo(days)
+		if days == 2 {
+			t = t.Add(time.Hour)
+		}
+		return RatingHistory{Date: t.Format(time.RFC3339), Rating: rating}
+	}
+	date := func(days int) string { return daysAgo(days).Format(time.DateOnly) }
+
+	table := []struct {
+		name      string
+		startDate string
+		endDate   string
+		want      []RatingHistory
+	}{
+		{
+			name: "All",
+			want: []RatingHistory{point(200, 1400), point(100, 1450), point(10, 1500), point(2, 1520)},
+		},
+		{
+			name:      "DailyOnly",
+			startDate: date(50),
+			want:      []RatingHistory{point(10, 1500), point(2, 1520)},
+		},
+		{
+			name:    "WeeklyOnly",
+			endDate: date(100),
+			want:    []RatingHistory{point(200, 1400), point(100, 1450)},
+		},
+		{
+			name:      "AcrossCutoff",
+			startDate: date(150),
+			endDate:   date(5),
+			want:      []RatingHistory{point(100, 1450), point(10, 1500)},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := repo.ListRatingHistory("alice", Chesscom, tc.startDate, tc.endDate)
+			if err != nil {
+				t.Fatalf("ListRatingHistory got err %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ListRatingHistory mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetRatingHistories(t *testing.T) {
+	repo := NewMemoryRepository()
+	now := time.Now().UTC().Truncate(time.Second)
+	if _, err := repo.PutRatingHistory(NewRatingHistoryPoints("alice", Chesscom, now, 1500)); err != nil {
+		t.Fatalf("PutRatingHistory got err %v", err)
+	}
+
+	legacy := RatingHistory{Date: "2023-01-02T00:00:00Z", Rating: 1400}
+	user := &User{
+		Username: "alice",
+		Ratings: map[RatingSystem]*Rating{
+			Chesscom: {CurrentRating: 1500},
+			Fide:     {CurrentRating: 1600},
+			Uscf:     {},
+		},
+		RatingHistories: map[RatingSystem][]RatingHistory{
+			Chesscom: {legacy},
+			Fide:     {{Date: "2023-01-02T00:00:00Z", Rating: 1600}},
+		},
+	}
+
+	got, err := GetRatingHistories(repo, user, "")
+	if err != nil {
+		t.Fatalf("GetRatingHistories got err %v", err)
+	}
+	want := map[RatingSystem][]RatingHistory{
+		Chesscom: {legacy, {Date: now.Format(time.RFC3339), Rating: 1500}},
+		Fide:     {{Date: "2023-01-02T00:00:00Z", Rating: 1600}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetRatingHistories mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetRatingHistoryDates(t *testing.T) {
+	repo := NewMemoryRepository()
+	user := &User{
+		Username: "alice",
+		RatingHistories: map[RatingSystem][]RatingHistory{
+			Chesscom: {
+				{Date: "2023-01-02T00:00:00Z", Rating: 1400},
+				{Date: "2023-01-09T00:00:00Z", Rating: 1410},
+				{Date: "2023-01-16T00:00:00Z", Rating: 1420},
+			},
+		},
+	}
+
+	got, err := GetRatingHistory(repo, user, Chesscom, "2023-01-05", "2023-01-09")
+	if err != nil {
+		t.Fatalf("GetRatingHistory got err %v", err)
+	}
+	want := []RatingHistory{{Date: "2023-01-09T00:00:00Z", Rating: 1410}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetRatingHistory mismatch (-want +got):\n%s", diff)
+	}
+}
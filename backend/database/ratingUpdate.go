@@ -0,0 +1,195 @@
+package database
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// RatingUpdateStatus is the state of a RatingUpdate.
+type RatingUpdateStatus string
+
+const (
+	RatingUpdateStatus_InProgress RatingUpdateStatus = "IN_PROGRESS"
+	RatingUpdateStatus_Complete   RatingUpdateStatus = "COMPLETE"
+)
+
+// RatingUpdateMetrics counts the rating fetches of a single rating system during a RatingUpdate.
+type RatingUpdateMetrics struct {
+	// The number of ratings fetched successfully.
+	Fetched int `dynamodbav:"fetched" json:"fetched"`
+
+	// The number of fetched ratings which differed from the saved rating.
+	Changed int `dynamodbav:"changed" json:"changed"`
+
+	// The number of ratings which could not be fetched.
+	Failed int `dynamodbav:"failed" json:"failed"`
+
+	// The number of ratings not fetched because the provider's circuit breaker was open
+	// or the run was out of time.
+	Skipped int `dynamodbav:"skipped" json:"skipped"`
+}
+
+// RatingUpdate is the checkpoint of a scheduled run of the rating update. It is saved after
+// each page of users, so that a run which times out can be resumed from the last page.
+type RatingUpdate struct {
+	// The id of the scheduled event which started the run. The hash key of the table.
+	Id string `dynamodbav:"id" json:"id"`
+
+	// The state of the run.
+	Status RatingUpdateStatus `dynamodbav:"status" json:"status"`
+
+	// The cohorts to update, in order.
+	Cohorts []DojoCohort `dynamodbav:"cohorts" json:"cohorts"`
+
+	// The index in Cohorts of the cohort currently being updated.
+	CohortIndex int `dynamodbav:"cohortIndex" json:"cohortIndex"`
+
+	// The start key of the next page of users in the current cohort.
+	StartKey string `dynamodbav:"startKey,omitempty" json:"-"`
+
+	// The number of users processed so far.
+	Users int `dynamodbav:"users" json:"users"`
+
+	// The fetch counts of each rating system so far.
+	Metrics map[RatingSystem]*RatingUpdateMetrics `dynamodbav:"metrics" json:"metrics"`
+
+	// The time the run started, in time.RFC3339 format.
+	CreatedAt string `dynamodbav:"createdAt" json:"createdAt"`
+
+	// The time the run was last saved, in time.RFC3339 format.
+	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
+
+	// The time the run finished, in time.RFC3339 format.
+	CompletedAt string `dynamodbav:"completedAt,omitempty" json:"completedAt,omitempty"`
+}
+
+// NewRatingUpdate returns a RatingUpdate with the provided id, starting at the first cohort.
+func NewRatingUpdate(id string, cohorts []DojoCohort) *RatingUpdate {
+	now := time.Now().Format(time.RFC3339)
+	u := &RatingUpdate{
+		Id:        id,
+		Status:    RatingUpdateStatus_InProgress,
+		Cohorts:   cohorts,
+		Metrics:   make(map[RatingSystem]*RatingUpdateMetrics),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if len(cohorts) == 0 {
+		u.Status = RatingUpdateStatus_Complete
+		u.CompletedAt = now
+	}
+	return u
+}
+
+// Cohort returns the cohort currently being updated.
+func (u *RatingUpdate) Cohort() DojoCohort {
+	if u.CohortIndex >= len(u.Cohorts) {
+		return NoCohort
+	}
+	return u.Cohorts[u.CohortIndex]
+}
+
+// Metric returns the metrics of the provided rating system, creating them if necessary.
+func (u *RatingUpdate) Metric(system RatingSystem) *RatingUpdateMetrics {
+	if u.Metrics == nil {
+		u.Metrics = make(map[RatingSystem]*RatingUpdateMetrics)
+	}
+	m, ok := u.Metrics[system]
+	if !ok {
+		m = &RatingUpdateMetrics{}
+		u.Metrics[system] = m
+	}
+	return m
+}
+
+// Advance records that a page of count users was processed. If nextKey is empty, the cohort
+// is finished and the run moves to the next cohort, or is marked complete if there are no
+// more cohorts.
+func (u *RatingUpdate) Advance(count int, nextKey string) {
+	u.Users += count
+	u.StartKey = nextKey
+	u.UpdatedAt = time.Now().Format(time.RFC3339)
+	if nextKey != "" {
+		return
+	}
+
+	u.CohortIndex++
+	if u.CohortIndex >= len(u.Cohorts) {
+		u.Status = RatingUpdateStatus_Complete
+		u.CompletedAt = u.UpdatedAt
+	}
+}
+
+// RatingUpdater provides an interface for the scheduled rating update.
+type RatingUpdater interface {
+	UserBatchGetter
+	UserRatingLister
+	RatingHistoryWriter
+
+	// UpdateUserRatings sets the ratings, legacy rating histories and Lichess ban status of the provided users.
+	UpdateUserRatings(users []*User) error
+
+	// GetRatingUpdate returns the RatingUpdate with the provided id.
+	GetRatingUpdate(id string) (*RatingUpdate, error)
+
+	// ListRatingUpdates returns the RatingUpdates which are in progress.
+	ListRatingUpdates(startKey string) ([]RatingUpdate, string, error)
+
+	// PutRatingUpdate saves the provided RatingUpdate.
+	PutRatingUpdate(update *RatingUpdate) error
+}
+
+// GetRatingUpdate returns the RatingUpdate with the provided id.
+func (repo *dynamoRepository) GetRatingUpdate(id string) (*RatingUpdate, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		TableName: aws.String(ratingUpdateTable),
+	}
+	update := RatingUpdate{}
+	if err := repo.getItem(input, &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// ListRatingUpdates returns the RatingUpdates which are in progress. The next start key
+// is also returned.
+func (repo *dynamoRepository) ListRatingUpdates(startKey string) ([]RatingUpdate, string, error) {
+	input := &dynamodb.ScanInput{
+		FilterExpression: aws.String("#status = :inProgress"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":inProgress": {S: aws.String(string(RatingUpdateStatus_InProgress))},
+		},
+		TableName: aws.String(ratingUpdateTable),
+	}
+
+	var updates []RatingUpdate
+	lastKey, err := repo.scan(input, startKey, &updates)
+	if err != nil {
+		return nil, "", err
+	}
+	return updates, lastKey, nil
+}
+
+// PutRatingUpdate saves the provided RatingUpdate.
+func (repo *dynamoRepository) PutRatingUpdate(update *RatingUpdate) error {
+	item, err := dynamodbattribute.MarshalMap(update)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal rating update", err)
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(ratingUpdateTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "Failed DynamoDB PutItem call", err)
+}
@@ -1,28 +0,0 @@
-package database
-
-var ratingBoundaries = map[RatingSystem][]int{
-	Chesscom: {550, 650, 750, 850, 950, 1050, 1150, 1250, 1350, 1450, 1550, 1650, 1750, 1850, 1950, 2050, 2165, 2275, 2360, 2425, 2485, 2550},
-	Lichess:  {1250, 1310, 1370, 1435, 1500, 1550, 1600, 1665, 1730, 1795, 1850, 1910, 1970, 2030, 2090, 2150, 2225, 2310, 2370, 2410, 2440, 2470},
-	Uscf:     {350, 460, 570, 680, 790, 900, 1010, 1120, 1230, 1330, 1420, 1510, 1600, 1675, 1750, 1825, 1930, 2055, 2185, 2290, 2395, 2500},
-	Fide:     {1400, 1400, 1400, 1400, 1400, 1400, 1400, 1450, 1500, 1550, 1600, 1650, 1700, 1750, 1800, 1850, 1910, 2000, 2100, 2200, 2300, 2400},
-	Ecf:      {400, 625, 850, 1000, 1130, 1210, 1270, 1325, 1390, 1455, 1535, 1595, 1665, 1735, 1805, 1875, 1955, 2065, 2165, 2260, 2360, 2460},
-	Cfc:      {350, 460, 570, 680, 780, 880, 980, 1090, 1200, 1300, 1390, 1480, 1570, 1645, 1730, 1825, 1925, 2060, 2185, 2290, 2395, 2500},
-	Dwz:      {450, 540, 630, 725, 815, 920, 1025, 1110, 1185, 1260, 1335, 1410, 1480, 1560, 1640, 1720, 1815, 1940, 2070, 2185, 2285, 2385},
-	Knsb:     {400, 600, 800, 1000, 1140, 1280, 1400, 1450, 1500, 1550, 1600, 1650, 1700, 1750, 1800, 1850, 1910, 2000, 2100, 2200, 2300, 2400},
-	Acf:      {300, 395, 490, 585, 680, 775, 870, 990, 1100, 1210, 1320, 1415, 1510, 1605, 1700, 1790, 1900, 2000, 2105, 2215, 2330, 2450},
-}
-
-func getCohort(ratingSystem RatingSystem, currentRating int) DojoCohort {
-	boundaries := ratingBoundaries[ratingSystem]
-	if boundaries == nil {
-		return NoCohort
-	}
-
-	for i := 0; i < len(boundaries); i++ {
-		if currentRating < boundaries[i] {
-			return Cohorts[i]
-		}
-	}
-
-	return "2400+"
-}
@@ -0,0 +1,376 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+// RepertoireMaxPly is the number of plies of each game's main line which are added to the
+// user's repertoire.
+const RepertoireMaxPly = 30
+
+// repertoireMaxUpdate is the maximum number of moves and games in a call to UpdateRepertoire,
+// which is the maximum number of items in a DynamoDB transaction less its IdempotencyRecord. The
+// moves of the old and new versions of a game are at most 2 * RepertoireMaxPly.
+const repertoireMaxUpdate = 99
+
+// repertoireConsumer is the name of UpdateRepertoire in the keys of the IdempotencyRecords of
+// the stream records it applies.
+const repertoireConsumer = "repertoire"
+
+// RepertoireMove is the aggregate of the games in which a move was played from a position,
+// grouped by the color the user played.
+type RepertoireMove struct {
+	// The username of the user. The hash key of the table.
+	Username string `dynamodbav:"username" json:"-"`
+
+	// The range key of the table, in the form fen|color|san.
+	SortKey string `dynamodbav:"sortKey" json:"-"`
+
+	// The normalized FEN of the position before the move.
+	Fen string `dynamodbav:"fen" json:"fen"`
+
+	// The color the user played in the games.
+	Color PlayerColor `dynamodbav:"color" json:"color"`
+
+	// The move in standard algebraic notation.
+	San string `dynamodbav:"san" json:"san"`
+
+	// The number of games in which the move was played.
+	Games int `dynamodbav:"games" json:"games"`
+
+	// The number of those games which the user won, drew and lost. Games without a result
+	// only count towards Games.
+	Wins   int `dynamodbav:"wins" json:"wins"`
+	Draws  int `dynamodbav:"draws" json:"draws"`
+	Losses int `dynamodbav:"losses" json:"losses"`
+}
+
+// repertoireSortKey returns the sort key of a repertoire move.
+func repertoireSortKey(fen string, color PlayerColor, san string) string {
+	return fen + "|" + string(color) + "|" + san
+}
+
+// repertoirePositionPrefix returns the prefix of the sort keys of the moves from the provided
+// normalized FEN.
+func repertoirePositionPrefix(fen string) string {
+	return fen + "|"
+}
+
+// RepertoireGame records the color a game was added to its owner's repertoire with, so that
+// its moves are removed with the same color even if the owner's player names have changed.
+type RepertoireGame struct {
+	// The username of the game's owner. The hash key of the table.
+	Username string `dynamodbav:"username"`
+
+	// The range key of the table, in the form game|cohort|id. FENs cannot start with a g, so
+	// RepertoireGames are never returned with the moves of a position.
+	SortKey string `dynamodbav:"sortKey"`
+
+	// The color of the moves added for the game. Empty if no moves were added.
+	Color PlayerColor `dynamodbav:"color"`
+}
+
+// NewRepertoireGame returns the RepertoireGame of the provided game.
+func NewRepertoireGame(game *Game, color PlayerColor) RepertoireGame {
+	return RepertoireGame{Username: game.Owner, SortKey: repertoireGameSortKey(string(game.Cohort), game.Id), Color: color}
+}
+
+// repertoireGameSortKey returns the sort key of a RepertoireGame.
+func repertoireGameSortKey(cohort, id string) string {
+	return "game|" + cohort + "|" + id
+}
+
+// RepertoireColor returns the color of the moves of the provided game which count towards its
+// owner's repertoire. This is the side whose player matches one of playerNames, or the game's
+// orientation if neither or both sides match. An empty color is returned if the game has no PGN
+// or the color cannot be determined.
+func RepertoireColor(game *Game, playerNames []string) PlayerColor {
+	if game == nil || game.Pgn == "" {
+		return ""
+	}
+	if color := gamePlayerColor(game, playerNames); color != "" {
+		return color
+	}
+	switch PlayerColor(game.Orientation) {
+	case White, Black:
+		return PlayerColor(game.Orientation)
+	}
+	return ""
+}
+
+// RepertoireMoves returns the moves of the provided game which count towards its owner's
+// repertoire when they play the provided color. See RepertoireColor. Each move of the first
+// RepertoireMaxPly plies of the main line is returned once, even if the position is repeated.
+// No moves are returned if the game has no PGN or the color is empty.
+func RepertoireMoves(game *Game, color PlayerColor) ([]RepertoireMove, error) {
+	if game == nil || game.Pgn == "" || color == "" {
+		return nil, nil
+	}
+
+	g, err := pgn.ParseGame(game.Pgn)
+	if err != nil {
+		return nil, errors.Wrap(400, "Invalid request: pgn is invalid", "Failed to parse game PGN", err)
+	}
+
+	move := RepertoireMove{Username: game.Owner, Color: color, Games: 1}
+	switch gameResult(game, color) {
+	case gameResultWin:
+		move.Wins = 1
+	case gameResultDraw:
+		move.Draws = 1
+	case gameResultLoss:
+		move.Losses = 1
+	}
+
+	var moves []RepertoireMove
+	seen := make(map[string]bool)
+	position := g.Start()
+	for i, node := range g.Moves {
+		if i >= RepertoireMaxPly || node.Move.IsNull() {
+			break
+		}
+
+		move.Fen = position.NormalizedFEN()
+		move.San = node.SAN
+		move.SortKey = repertoireSortKey(move.Fen, color, move.San)
+		if !seen[move.SortKey] {
+			seen[move.SortKey] = true
+			moves = append(moves, move)
+		}
+		position = node.Position()
+	}
+	return moves, nil
+}
+
+// MergeRepertoireMoves returns the provided moves with the counts of moves with the same
+// username and sort key summed. Moves whose counts are all zero are removed. The result is
+// sorted by username and sort key.
+func MergeRepertoireMoves(moves []RepertoireMove) []RepertoireMove {
+	index := make(map[string]int, len(moves))
+	var result []RepertoireMove
+	for _, m := range moves {
+		key := m.Username + "|" + m.SortKey
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(result)
+			result = append(result, m)
+			continue
+		}
+		result[i].Games += m.Games
+		result[i].Wins += m.Wins
+		result[i].Draws += m.Draws
+		result[i].Losses += m.Losses
+	}
+
+	merged := result[:0]
+	for _, m := range result {
+		if m.Games != 0 || m.Wins != 0 || m.Draws != 0 || m.Losses != 0 {
+			merged = append(merged, m)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Username != merged[j].Username {
+			return merged[i].Username < merged[j].Username
+		}
+		return merged[i].SortKey < merged[j].SortKey
+	})
+	return merged
+}
+
+type RepertoireGetter interface {
+	// ListRepertoireMoves returns the moves the provided user has played from the position
+	// with the provided normalized FEN, in both colors. Moves which are no longer in any of
+	// the user's games are excluded.
+	ListRepertoireMoves(username, fen string) ([]RepertoireMove, error)
+}
+
+type RepertoireUpdater interface {
+	UserGetter
+
+	// GetRepertoireGame returns the RepertoireGame of the game with the provided cohort and id
+	// owned by the provided user. A 404 error is returned if the game has none.
+	GetRepertoireGame(username, cohort, id string) (*RepertoireGame, error)
+
+	// UpdateRepertoire adds the counts of the provided moves to the counts of the existing
+	// moves in the repertoire table, creating them if necessary, and saves and deletes the
+	// provided RepertoireGames. The changes are made in one transaction which also saves an
+	// IdempotencyRecord for the stream record with the provided event ID, so a record which was
+	// already applied is skipped. At most repertoireMaxUpdate distinct moves and games can be
+	// updated at once.
+	UpdateRepertoire(eventID string, moves []RepertoireMove, put, del []RepertoireGame) error
+}
+
+type GameStreamProcessor interface {
+	GameStatisticsUpdater
+	RepertoireUpdater
+}
+
+type RepertoireWriter interface {
+	AdminUserLister
+	GameLister
+	GameGetter
+
+	// PutRepertoireMoves saves the provided moves, replacing the counts of any existing
+	// moves. The number of moves saved is returned.
+	PutRepertoireMoves(moves []RepertoireMove) (int, error)
+
+	// PutRepertoireGames saves the provided RepertoireGames, replacing any existing games. The
+	// number of games saved is returned.
+	PutRepertoireGames(games []RepertoireGame) (int, error)
+}
+
+// ListRepertoireMoves returns the moves the provided user has played from the position
+// with the provided normalized FEN, in both colors. Moves which are no longer in any of
+// the user's games are excluded.
+func (repo *dynamoRepository) ListRepertoireMoves(username, fen string) ([]RepertoireMove, error) {
+	input := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#username = :username AND begins_with(#sortKey, :prefix)"),
+		FilterExpression:       aws.String("#games > :zero"),
+		ExpressionAttributeNames: map[string]*string{
+			"#username": aws.String("username"),
+			"#sortKey":  aws.String("sortKey"),
+			"#games":    aws.String("games"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":username": {S: aws.String(username)},
+			":prefix":   {S: aws.String(repertoirePositionPrefix(fen))},
+			":zero":     {N: aws.String("0")},
+		},
+		TableName: aws.String(repertoireTable),
+	}
+
+	var result []RepertoireMove
+	startKey := ""
+	for {
+		var moves []RepertoireMove
+		lastKey, err := repo.query(input, startKey, &moves)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, moves...)
+		if lastKey == "" {
+			return result, nil
+		}
+		startKey = lastKey
+	}
+}
+
+// GetRepertoireGame returns the RepertoireGame of the game with the provided cohort and id
+// owned by the provided user. A 404 error is returned if the game has none.
+func (repo *dynamoRepository) GetRepertoireGame(username, cohort, id string) (*RepertoireGame, error) {
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+			"sortKey":  {S: aws.String(repertoireGameSortKey(cohort, id))},
+		},
+		TableName: aws.String(repertoireTable),
+	}
+
+	game := RepertoireGame{}
+	if err := repo.getItem(input, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// UpdateRepertoire adds the counts of the provided moves to the counts of the existing
+// moves in the repertoire table, creating them if necessary, and saves and deletes the
+// provided RepertoireGames. The changes are made in one transaction which also saves an
+// IdempotencyRecord for the stream record with the provided event ID, so a record which was
+// already applied is skipped. At most repertoireMaxUpdate distinct moves and games can be
+// updated at once.
+func (repo *dynamoRepository) UpdateRepertoire(eventID string, moves []RepertoireMove, put, del []RepertoireGame) error {
+	moves = MergeRepertoireMoves(moves)
+	if len(moves)+len(put)+len(del) == 0 {
+		return nil
+	}
+	if len(moves)+len(put)+len(del) > repertoireMaxUpdate {
+		return errors.New(500, "Temporary server error", fmt.Sprintf("UpdateRepertoire got %d moves and games, more than the maximum of %d", len(moves)+len(put)+len(del), repertoireMaxUpdate))
+	}
+
+	marker, err := streamRecordPut(repertoireConsumer, eventID)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.TransactWriteItemsInput{TransactItems: []*dynamodb.TransactWriteItem{marker}}
+	for _, m := range moves {
+		input.TransactItems = append(input.TransactItems, &dynamodb.TransactWriteItem{
+			Update: &dynamodb.Update{
+				UpdateExpression: aws.String("SET #fen = :fen, #color = :color, #san = :san ADD #games :games, #wins :wins, #draws :draws, #losses :losses"),
+				ExpressionAttributeNames: map[string]*string{
+					"#fen":    aws.String("fen"),
+					"#color":  aws.String("color"),
+					"#san":    aws.String("san"),
+					"#games":  aws.String("games"),
+					"#wins":   aws.String("wins"),
+					"#draws":  aws.String("draws"),
+					"#losses": aws.String("losses"),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":fen":    {S: aws.String(m.Fen)},
+					":color":  {S: aws.String(string(m.Color))},
+					":san":    {S: aws.String(m.San)},
+					":games":  {N: aws.String(strconv.Itoa(m.Games))},
+					":wins":   {N: aws.String(strconv.Itoa(m.Wins))},
+					":draws":  {N: aws.String(strconv.Itoa(m.Draws))},
+					":losses": {N: aws.String(strconv.Itoa(m.Losses))},
+				},
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": {S: aws.String(m.Username)},
+					"sortKey":  {S: aws.String(m.SortKey)},
+				},
+				TableName: aws.String(repertoireTable),
+			},
+		})
+	}
+
+	for _, g := range put {
+		item, err := dynamodbattribute.MarshalMap(g)
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Unable to marshal repertoire game", err)
+		}
+		input.TransactItems = append(input.TransactItems, &dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{
+				Item:      item,
+				TableName: aws.String(repertoireTable),
+			},
+		})
+	}
+	for _, g := range del {
+		input.TransactItems = append(input.TransactItems, &dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{
+				Key: map[string]*dynamodb.AttributeValue{
+					"username": {S: aws.String(g.Username)},
+					"sortKey":  {S: aws.String(g.SortKey)},
+				},
+				TableName: aws.String(repertoireTable),
+			},
+		})
+	}
+
+	_, err = repo.svc.TransactWriteItems(input)
+	if err != nil && transactionCancellationCode(err, 0) != conditionalCheckFailedReason {
+		return errors.Wrap(500, "Temporary server error", "Failed DynamoDB TransactWriteItems call", err)
+	}
+	return nil
+}
+
+// PutRepertoireMoves saves the provided moves, replacing the counts of any existing
+// moves. The number of moves saved is returned.
+func (repo *dynamoRepository) PutRepertoireMoves(moves []RepertoireMove) (int, error) {
+	return batchWriteObjects(repo, MergeRepertoireMoves(moves), repertoireTable)
+}
+
+// PutRepertoireGames saves the provided RepertoireGames, replacing any existing games. The
+// number of games saved is returned.
+func (repo *dynamoRepository) PutRepertoireGames(games []RepertoireGame) (int, error) {
+	return batchWriteObjects(repo, games, repertoireTable)
+}
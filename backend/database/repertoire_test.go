@@ -0,0 +1,126 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+// The normalized FENs of the positions reached in the tests.
+const (
+	afterE4        = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+	afterNf3       = "rnbqkbnr/pppppppp/8/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 0 1"
+	afterNf3Nf6    = "rnbqkb1r/pppppppp/5n2/8/8/5N2/PPPPPPPP/RNBQKB1R w KQkq - 0 1"
+	afterNf3Nf6Ng1 = "rnbqkb1r/pppppppp/5n2/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1"
+)
+
+func TestRepertoireMoves(t *testing.T) {
+	names := []string{"alice"}
+
+	table := []struct {
+		name string
+		game Game
+		want []RepertoireMove
+	}{
+		{
+			name: "WhiteWin",
+			game: Game{
+				Owner: "alice", White: "Alice", Black: "bob",
+				Headers: map[string]string{"Result": "1-0"},
+				Pgn:     "[White \"Alice\"]\n[Black \"bob\"]\n[Result \"1-0\"]\n\n1. e4 e5 1-0",
+			},
+			want: []RepertoireMove{
+				{Username: "alice", SortKey: pgn.StartingFEN + "|white|e4", Fen: pgn.StartingFEN, Color: White, San: "e4", Games: 1, Wins: 1},
+				{Username: "alice", SortKey: afterE4 + "|white|e5", Fen: afterE4, Color: White, San: "e5", Games: 1, Wins: 1},
+			},
+		},
+		{
+			name: "RepeatedPosition",
+			game: Game{
+				Owner: "alice", White: "bob", Black: "alice",
+				Headers: map[string]string{"Result": "1-0"},
+				Pgn:     "1. Nf3 Nf6 2. Ng1 Ng8 3. Nf3 1-0",
+			},
+			want: []RepertoireMove{
+				{Username: "alice", SortKey: pgn.StartingFEN + "|black|Nf3", Fen: pgn.StartingFEN, Color: Black, San: "Nf3", Games: 1, Losses: 1},
+				{Username: "alice", SortKey: afterNf3 + "|black|Nf6", Fen: afterNf3, Color: Black, San: "Nf6", Games: 1, Losses: 1},
+				{Username: "alice", SortKey: afterNf3Nf6 + "|black|Ng1", Fen: afterNf3Nf6, Color: Black, San: "Ng1", Games: 1, Losses: 1},
+				{Username: "alice", SortKey: afterNf3Nf6Ng1 + "|black|Ng8", Fen: afterNf3Nf6Ng1, Color: Black, San: "Ng8", Games: 1, Losses: 1},
+			},
+		},
+		{
+			name: "OrientationWithoutResult",
+			game: Game{
+				Owner: "alice", White: "carol", Black: "bob", Orientation: "black",
+				Pgn: "1. e4 *",
+			},
+			want: []RepertoireMove{
+				{Username: "alice", SortKey: pgn.StartingFEN + "|black|e4", Fen: pgn.StartingFEN, Color: Black, San: "e4", Games: 1},
+			},
+		},
+		{
+			name: "UnknownColor",
+			game: Game{Owner: "alice", White: "carol", Black: "bob", Pgn: "1. e4 *"},
+		},
+		{
+			name: "NoPgn",
+			game: Game{Owner: "alice", White: "alice", Black: "bob"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RepertoireMoves(&tc.game, RepertoireColor(&tc.game, names))
+			if err != nil {
+				t.Fatalf("RepertoireMoves got err %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("RepertoireMoves mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRepertoireMovesMaxPly(t *testing.T) {
+	game := &Game{
+		Owner: "alice", White: "alice", Black: "bob",
+		Pgn: "1. Nf3 Nf6 2. Ng1 Ng8 3. Nf3 Nf6 4. Ng1 Ng8 5. Nf3 Nf6 6. Ng1 Ng8 7. Nf3 Nf6 8. Ng1 Ng8 " +
+			"9. Nf3 Nf6 10. Ng1 Ng8 11. Nf3 Nf6 12. Ng1 Ng8 13. Nf3 Nf6 14. Ng1 Ng8 15. Nf3 Nf6 16. e4 *",
+	}
+
+	moves, err := RepertoireMoves(game, White)
+	if err != nil {
+		t.Fatalf("RepertoireMoves got err %v", err)
+	}
+	for _, m := range moves {
+		if m.San == "e4" {
+			t.Errorf("RepertoireMoves included a move after ply %d", RepertoireMaxPly)
+		}
+	}
+}
+
+func TestRepertoireMovesInvalidPgn(t *testing.T) {
+	game := &Game{Owner: "alice", White: "alice", Black: "bob", Pgn: "1. e5 *"}
+	if _, err := RepertoireMoves(game, White); err == nil {
+		t.Errorf("RepertoireMoves got nil err for an illegal move")
+	}
+}
+
+func TestMergeRepertoireMoves(t *testing.T) {
+	moves := []RepertoireMove{
+		{Username: "bob", SortKey: "b", Games: 1, Wins: 1},
+		{Username: "alice", SortKey: "b", Games: 1, Draws: 1},
+		{Username: "alice", SortKey: "a", Games: 1, Losses: 1},
+		{Username: "alice", SortKey: "b", Games: 1, Wins: 1},
+		{Username: "bob", SortKey: "b", Games: -1, Wins: -1},
+	}
+
+	want := []RepertoireMove{
+		{Username: "alice", SortKey: "a", Games: 1, Losses: 1},
+		{Username: "alice", SortKey: "b", Games: 2, Wins: 1, Draws: 1},
+	}
+	if diff := cmp.Diff(want, MergeRepertoireMoves(moves)); diff != "" {
+		t.Errorf("MergeRepertoireMoves mismatch (-want +got):\n%s", diff)
+	}
+}
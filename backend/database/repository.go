@@ -1,22 +1,27 @@
 package database
 
 import (
-	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"os"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/sqs"
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 )
 
 // dynamoRepository implements a database using AWS DynamoDB.
 type dynamoRepository struct {
-	svc *dynamodb.DynamoDB
+	svc dynamodbiface.DynamoDBAPI
 }
 
 var sess = session.Must(session.NewSession())
@@ -26,6 +31,35 @@ var DynamoDB = &dynamoRepository{
 	svc: dynamodb.New(sess),
 }
 
+// MemoryDatabaseFileEnv is the environment variable which makes Repository return a memory
+// repository instead of DynamoDB. Its value is the file where the memory repository's items are
+// saved, so that they are shared between the handlers run by the local gateway.
+const MemoryDatabaseFileEnv = "memoryDatabaseFile"
+
+// memoryDatabase returns the memory repository used by Repository, creating it on first use so
+// that every handler in the process shares it.
+var memoryDatabase = sync.OnceValue(func() *memoryRepository {
+	repo := NewMemoryRepository()
+	repo.file = os.Getenv(MemoryDatabaseFileEnv)
+	return repo
+})
+
+// Repository returns the provided DynamoDB repository as T, or a memory repository if the
+// MemoryDatabaseFileEnv environment variable is set. T must be an interface, which is normally
+// implemented by both repositories. Handlers use it to declare their repository:
+//
+//	var repository = database.Repository[database.UserGetter](database.DynamoDB)
+func Repository[T any](dynamo T) T {
+	if os.Getenv(MemoryDatabaseFileEnv) == "" {
+		return dynamo
+	}
+	repo, ok := any(memoryDatabase()).(T)
+	if !ok {
+		panic(fmt.Sprintf("database: the memory repository does not implement %v", reflect.TypeFor[T]()))
+	}
+	return repo
+}
+
 var sqsService = sqs.New(sess)
 var sqsUrl = os.Getenv("notificationEventSqsUrl")
 
@@ -46,6 +80,17 @@ var clubTable = stage + "-clubs"
 var examsTable = stage + "-exams"
 var directoryTable = stage + "-directories"
 var liveClassesTable = stage + "-live-classes"
+var auditLogTable = stage + "-audit-log"
+var outboxTable = stage + "-notification-outbox"
+var idempotencyTable = stage + "-idempotency-keys"
+var accountDeletionTable = stage + "-account-deletions"
+var dataExportTable = stage + "-data-exports"
+var ratingUpdateTable = stage + "-rating-updates"
+var ratingHistoryTable = stage + "-rating-history"
+var ratingDistributionTable = stage + "-rating-distributions"
+var gameStatisticsTable = stage + "-game-statistics"
+var gameStatisticsPlayersTable = stage + "-game-statistics-players"
+var repertoireTable = stage + "-repertoires"
 
 const gameTableOwnerIndex = "OwnerIdx"
 const gameTableWhiteIndex = "WhiteIndex"
@@ -57,6 +102,8 @@ const tournamentTableOpenClassicalIndex = "OpenClassicalIndex"
 
 const graduationTableCohortIndex = "CohortIndex"
 
+const auditLogTableActorIndex = "ActorIndex"
+
 // getItem handles sending a DynamoDB GetItem request and unmarshals the result into the provided output
 // value, which must be a non-nil pointer. If the result of the GetItem request is nil, then
 // a 404 error is returned. All other errors result in a 500 error.
@@ -76,14 +123,15 @@ func (repo *dynamoRepository) getItem(input *dynamodb.GetItemInput, out any) err
 
 // query handles sending a DynamoDB Query request and unmarshals the result into the provided output value,
 // which must be a non-nil pointer to a slice. startKey is an optional parameter that can be used to perform
-// pagination. The next startKey is returned. If startKey cannot be unmarshalled, a 400 error is returned.
-// All other errors result in a 500.
+// pagination. The next startKey is returned as a signed cursor, which is only accepted by a query on the same
+// table, index and key condition. If startKey is not such a cursor, a 400 error is returned. All other errors
+// result in a 500.
 func (repo *dynamoRepository) query(input *dynamodb.QueryInput, startKey string, out interface{}) (string, error) {
+	scope := queryCursorScope(input)
 	if startKey != "" {
-		var exclusiveStartKey map[string]*dynamodb.AttributeValue
-		err := json.Unmarshal([]byte(startKey), &exclusiveStartKey)
+		exclusiveStartKey, err := decodeCursor(scope, startKey)
 		if err != nil {
-			return "", errors.Wrap(400, "Invalid request: startKey is not valid", "startKey could not be unmarshaled from json", err)
+			return "", err
 		}
 		input.SetExclusiveStartKey(exclusiveStartKey)
 	}
@@ -97,27 +145,22 @@ func (repo *dynamoRepository) query(input *dynamodb.QueryInput, startKey string,
 		return "", errors.Wrap(500, "Temporary server error", "Failed to unmarshal Query result", err)
 	}
 
-	var lastKey string
 	if len(result.LastEvaluatedKey) > 0 {
-		b, err := json.Marshal(result.LastEvaluatedKey)
-		if err != nil {
-			return "", errors.Wrap(500, "Temporary server error", "Failed to marshal Query LastEvaluatedKey", err)
-		}
-		lastKey = string(b)
+		return encodeCursor(scope, result.LastEvaluatedKey)
 	}
-	return lastKey, nil
+	return "", nil
 }
 
 // scan handles sending a DynamoDB Scan request and unmarshals the result into the provided output value, which
 // must be a non-nil pointer to a slice. startKey is an optional parameter that can be used to perform pagination.
-// The next startKey is returned. If startKey cannot be unmarshalled, a 400 error is returned. All other errors
-// result in a 500.
+// The next startKey is returned as a signed cursor, which is only accepted by a scan of the same table and index.
+// If startKey is not such a cursor, a 400 error is returned. All other errors result in a 500.
 func (repo *dynamoRepository) scan(input *dynamodb.ScanInput, startKey string, out interface{}) (string, error) {
+	scope := scanCursorScope(input)
 	if startKey != "" {
-		var exclusiveStartKey map[string]*dynamodb.AttributeValue
-		err := json.Unmarshal([]byte(startKey), &exclusiveStartKey)
+		exclusiveStartKey, err := decodeCursor(scope, startKey)
 		if err != nil {
-			return "", errors.Wrap(400, "Invalid request: startKey is not valid", "startKey could not be unmarshaled from json", err)
+			return "", err
 		}
 		input.SetExclusiveStartKey(exclusiveStartKey)
 	}
@@ -131,22 +174,130 @@ func (repo *dynamoRepository) scan(input *dynamodb.ScanInput, startKey string, o
 		return "", errors.Wrap(500, "Temporary server error", "Failed to unmarshal Scan result", err)
 	}
 
-	var lastKey string
 	if len(result.LastEvaluatedKey) > 0 {
-		b, err := json.Marshal(result.LastEvaluatedKey)
-		if err != nil {
-			return "", errors.Wrap(500, "Temporary server error", "Failed to marshal Scan LastEvaluatedKey", err)
-		}
-		lastKey = string(b)
+		return encodeCursor(scope, result.LastEvaluatedKey)
 	}
-	return lastKey, nil
+	return "", nil
+}
+
+// batchMaxRetries is the number of times the unprocessed items of a batch request are retried
+// before giving up.
+const batchMaxRetries = 8
+
+// batchBaseDelay and batchMaxDelay bound the exponential backoff between batch retries.
+const batchBaseDelay = 50 * time.Millisecond
+const batchMaxDelay = 5 * time.Second
+
+// sleep pauses between batch retries. It is replaced in tests.
+var sleep = time.Sleep
+
+// batchBackoff waits before the provided retry of a batch request, which is 0 for the first retry.
+// The wait is chosen uniformly at random up to an exponentially increasing limit ("full jitter"),
+// so that concurrent jobs which were throttled together do not retry together.
+func batchBackoff(retry int) {
+	limit := batchMaxDelay
+	if retry < 20 && batchBaseDelay<<retry < batchMaxDelay {
+		limit = batchBaseDelay << retry
+	}
+	sleep(rand.N(limit))
+}
+
+// UnprocessedWritesError is the cause of the error returned by a batch write when some of its
+// write requests were not applied.
+type UnprocessedWritesError struct {
+	// The table the requests were sent to.
+	TableName string
+
+	// The write requests which were not applied.
+	Requests []*dynamodb.WriteRequest
+
+	// The error returned by DynamoDB, if any. If nil, the requests were still unprocessed
+	// after batchMaxRetries retries.
+	Err error
+}
+
+func (e *UnprocessedWritesError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d write requests to %s failed: %v", len(e.Requests), e.TableName, e.Err)
+	}
+	return fmt.Sprintf("%d write requests to %s were unprocessed after %d retries", len(e.Requests), e.TableName, batchMaxRetries)
+}
+
+func (e *UnprocessedWritesError) Unwrap() error {
+	return e.Err
+}
+
+// UnprocessedKeysError is the cause of the error returned by a batch get when some of its
+// keys were not read.
+type UnprocessedKeysError struct {
+	// The table the keys belong to.
+	TableName string
+
+	// The keys which were not read.
+	Keys []map[string]*dynamodb.AttributeValue
+
+	// The error returned by DynamoDB, if any. If nil, the keys were still unprocessed
+	// after batchMaxRetries retries.
+	Err error
+}
+
+func (e *UnprocessedKeysError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d keys from %s failed: %v", len(e.Keys), e.TableName, e.Err)
+	}
+	return fmt.Sprintf("%d keys from %s were unprocessed after %d retries", len(e.Keys), e.TableName, batchMaxRetries)
+}
+
+func (e *UnprocessedKeysError) Unwrap() error {
+	return e.Err
+}
+
+// FailedStatementsError is the cause of the error returned by a batch of PartiQL statements when
+// some of the statements failed.
+type FailedStatementsError struct {
+	// The statements which failed.
+	Statements []*dynamodb.BatchStatementRequest
+
+	// The error of each statement in Statements. Nil if Err is set.
+	Errors []*dynamodb.BatchStatementError
+
+	// The error returned by DynamoDB for the request as a whole, if any.
+	Err error
+}
+
+func (e *FailedStatementsError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d statements failed: %v", len(e.Statements), e.Err)
+	}
+	return fmt.Sprintf("%d statements failed: %v", len(e.Statements), e.Errors)
+}
+
+func (e *FailedStatementsError) Unwrap() error {
+	return e.Err
 }
 
 // batchWriteObjects inserts the provided objects into the provided table. The number of successfully inserted
-// objects is returned.
+// objects is returned. If some objects could not be inserted, the remaining objects are still attempted and
+// the returned error's cause is an *UnprocessedWritesError containing every failed request.
 func batchWriteObjects[T any](repo *dynamoRepository, objects []T, tableName string, opts ...func(object T, item map[string]*dynamodb.AttributeValue)) (int, error) {
 	var putRequests []*dynamodb.WriteRequest
 	updated := 0
+	failed := &UnprocessedWritesError{TableName: tableName}
+
+	write := func() {
+		err := repo.batchWrite(putRequests, tableName)
+		updated += len(putRequests)
+
+		var uerr *UnprocessedWritesError
+		if errors.As(err, &uerr) {
+			updated -= len(uerr.Requests)
+			failed.Requests = append(failed.Requests, uerr.Requests...)
+			if failed.Err == nil {
+				failed.Err = uerr.Err
+			}
+		}
+		putRequests = nil
+	}
 
 	for _, e := range objects {
 		item, err := dynamodbattribute.MarshalMap(e)
@@ -166,40 +317,154 @@ func batchWriteObjects[T any](repo *dynamoRepository, objects []T, tableName str
 		putRequests = append(putRequests, req)
 
 		if len(putRequests) == 25 {
-			if err := repo.batchWrite(putRequests, tableName); err != nil {
-				return updated, err
-			}
-			updated += 25
-			putRequests = nil
+			write()
 		}
 	}
 
 	if len(putRequests) > 0 {
-		if err := repo.batchWrite(putRequests, tableName); err != nil {
-			return updated, err
-		}
-		updated += len(putRequests)
+		write()
 	}
 
+	if len(failed.Requests) > 0 {
+		return updated, errors.Wrap(500, "Temporary server error", "DynamoDB BatchWriteItem failed to process all items", failed)
+	}
 	return updated, nil
 }
 
 // batchWrite handles sending a DynamoDB BatchWriteItem request using the provided slice of WriteRequests.
-// The WriteRequests are mapped to the provided table name.
+// The WriteRequests are mapped to the provided table name. Unprocessed items are retried with exponential
+// backoff. If some requests are still unprocessed after batchMaxRetries retries, or DynamoDB returns an
+// error, the returned error's cause is an *UnprocessedWritesError containing the requests which were not applied.
 func (repo *dynamoRepository) batchWrite(reqs []*dynamodb.WriteRequest, tableName string) error {
-	input := &dynamodb.BatchWriteItemInput{
-		RequestItems: map[string][]*dynamodb.WriteRequest{
-			tableName: reqs,
-		},
-		ReturnConsumedCapacity: aws.String("NONE"),
+	for retry := 0; ; retry++ {
+		input := &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{
+				tableName: reqs,
+			},
+			ReturnConsumedCapacity: aws.String("NONE"),
+		}
+
+		output, err := repo.svc.BatchWriteItem(input)
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Failed DynamoDB BatchWriteItem",
+				&UnprocessedWritesError{TableName: tableName, Requests: reqs, Err: err})
+		}
+
+		reqs = output.UnprocessedItems[tableName]
+		if len(reqs) == 0 {
+			return nil
+		}
+		if retry == batchMaxRetries {
+			return errors.Wrap(500, "Temporary server error", "DynamoDB BatchWriteItem failed to process all items",
+				&UnprocessedWritesError{TableName: tableName, Requests: reqs})
+		}
+
+		log.Debugf("Retrying %d unprocessed BatchWriteItem requests to %s", len(reqs), tableName)
+		batchBackoff(retry)
 	}
+}
 
-	output, err := repo.svc.BatchWriteItem(input)
-	if err != nil {
-		return errors.Wrap(500, "Temporary server error", "Failed DynamoDB BatchWriteItem", err)
+// batchGet handles sending DynamoDB BatchGetItem requests for the provided keys and attributes of the provided
+// table. The results are unmarshaled into the provided output value, which must be a non-nil pointer to a slice.
+// Unprocessed keys are retried with exponential backoff. If some keys are still unprocessed after batchMaxRetries
+// retries, or DynamoDB returns an error, the items which were read are still unmarshaled into out, and the returned
+// error's cause is an *UnprocessedKeysError containing the keys which were not read.
+func (repo *dynamoRepository) batchGet(tableName string, request *dynamodb.KeysAndAttributes, out any) error {
+	var items []map[string]*dynamodb.AttributeValue
+	var failed *UnprocessedKeysError
+
+	for retry := 0; ; retry++ {
+		input := &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]*dynamodb.KeysAndAttributes{
+				tableName: request,
+			},
+		}
+
+		output, err := repo.svc.BatchGetItem(input)
+		if err != nil {
+			failed = &UnprocessedKeysError{TableName: tableName, Keys: request.Keys, Err: err}
+			break
+		}
+		items = append(items, output.Responses[tableName]...)
+
+		request = output.UnprocessedKeys[tableName]
+		if request == nil || len(request.Keys) == 0 {
+			break
+		}
+		if retry == batchMaxRetries {
+			failed = &UnprocessedKeysError{TableName: tableName, Keys: request.Keys}
+			break
+		}
+
+		log.Debugf("Retrying %d unprocessed BatchGetItem keys from %s", len(request.Keys), tableName)
+		batchBackoff(retry)
 	}
-	if len(output.UnprocessedItems) > 0 {
-		return errors.New(500, "Temporary server error", fmt.Sprintf("DynamoDB BatchWriteItem failed to process (%+v): %+v", reqs, output))
+
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, out); err != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed to unmarshal BatchGetItem result", err)
+	}
+	if failed != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed call to BatchGetItem", failed)
+	}
+	return nil
+}
+
+// retryableStatementErrors are the BatchStatementError codes which are retried by batchExecute.
+var retryableStatementErrors = map[string]bool{
+	dynamodb.BatchStatementErrorCodeEnumProvisionedThroughputExceeded: true,
+	dynamodb.BatchStatementErrorCodeEnumRequestLimitExceeded:          true,
+	dynamodb.BatchStatementErrorCodeEnumThrottlingError:               true,
+	dynamodb.BatchStatementErrorCodeEnumInternalServerError:           true,
+	dynamodb.BatchStatementErrorCodeEnumTransactionConflict:           true,
+}
+
+// batchExecute handles sending a DynamoDB BatchExecuteStatement request using the provided statements.
+// Statements which fail with a throttling or other transient error are retried with exponential backoff.
+// If some statements fail with any other error, or still fail after batchMaxRetries retries, the returned
+// error's cause is a *FailedStatementsError containing the failed statements and their errors.
+func (repo *dynamoRepository) batchExecute(statements []*dynamodb.BatchStatementRequest) error {
+	failed := &FailedStatementsError{}
+
+	for retry := 0; len(statements) > 0; retry++ {
+		input := &dynamodb.BatchExecuteStatementInput{Statements: statements}
+		log.Debugf("Batch execute statement input: %v", input)
+		output, err := repo.svc.BatchExecuteStatement(input)
+		log.Debugf("Batch execute statement output: %v", output)
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Failed BatchExecuteStatement",
+				&FailedStatementsError{Statements: append(failed.Statements, statements...), Err: err})
+		}
+
+		var retryable []*dynamodb.BatchStatementRequest
+		var retryableErrors []*dynamodb.BatchStatementError
+		for i, response := range output.Responses {
+			if response.Error == nil {
+				continue
+			}
+			if retryableStatementErrors[aws.StringValue(response.Error.Code)] {
+				retryable = append(retryable, statements[i])
+				retryableErrors = append(retryableErrors, response.Error)
+			} else {
+				failed.Statements = append(failed.Statements, statements[i])
+				failed.Errors = append(failed.Errors, response.Error)
+			}
+		}
+
+		if len(retryable) > 0 && retry == batchMaxRetries {
+			failed.Statements = append(failed.Statements, retryable...)
+			failed.Errors = append(failed.Errors, retryableErrors...)
+			break
+		}
+
+		statements = retryable
+		if len(statements) > 0 {
+			log.Debugf("Retrying %d failed BatchExecuteStatement statements", len(statements))
+			batchBackoff(retry)
+		}
+	}
+
+	if len(failed.Statements) > 0 {
+		return errors.Wrap(500, "Temporary server error", "BatchExecuteStatement failed to process all statements", failed)
 	}
 	return nil
 }
@@ -213,3 +478,17 @@ func (repo *dynamoRepository) updateItem(input *dynamodb.UpdateItemInput, out in
 	}
 	return dynamodbattribute.UnmarshalMap(result.Attributes, out)
 }
+
+// conditionalCheckFailedReason is the cancellation reason code of a transaction item whose
+// condition expression failed.
+const conditionalCheckFailedReason = "ConditionalCheckFailed"
+
+// transactionCancellationCode returns the cancellation reason code of the item at index i if err
+// is a TransactionCanceledException. An empty string is returned otherwise.
+func transactionCancellationCode(err error, i int) string {
+	var canceled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceled) || i >= len(canceled.CancellationReasons) {
+		return ""
+	}
+	return aws.StringValue(canceled.CancellationReasons[i].Code)
+}
@@ -0,0 +1,338 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// fakeBatchClient implements the batch DynamoDB calls by returning the configured outputs in order.
+type fakeBatchClient struct {
+	dynamodbiface.DynamoDBAPI
+
+	writeInputs   []*dynamodb.BatchWriteItemInput
+	writeOutputs  []func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error)
+	getInputs     []*dynamodb.BatchGetItemInput
+	getOutputs    []func(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error)
+	executeInputs []*dynamodb.BatchExecuteStatementInput
+	executeOutput func(input *dynamodb.BatchExecuteStatementInput) (*dynamodb.BatchExecuteStatementOutput, error)
+}
+
+func (c *fakeBatchClient) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	c.writeInputs = append(c.writeInputs, input)
+	if len(c.writeOutputs) == 0 {
+		return &dynamodb.BatchWriteItemOutput{}, nil
+	}
+	f := c.writeOutputs[0]
+	c.writeOutputs = c.writeOutputs[1:]
+	return f(input)
+}
+
+func (c *fakeBatchClient) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	c.getInputs = append(c.getInputs, input)
+	f := c.getOutputs[0]
+	if len(c.getOutputs) > 1 {
+		c.getOutputs = c.getOutputs[1:]
+	}
+	return f(input)
+}
+
+func (c *fakeBatchClient) BatchExecuteStatement(input *dynamodb.BatchExecuteStatementInput) (*dynamodb.BatchExecuteStatementOutput, error) {
+	c.executeInputs = append(c.executeInputs, input)
+	return c.executeOutput(input)
+}
+
+func newFakeBatchRepository(t *testing.T) (*dynamoRepository, *fakeBatchClient) {
+	originalSleep := sleep
+	sleep = func(time.Duration) {}
+	t.Cleanup(func() { sleep = originalSleep })
+
+	client := &fakeBatchClient{}
+	return &dynamoRepository{svc: client}, client
+}
+
+func putRequest(id string) *dynamodb.WriteRequest {
+	return &dynamodb.WriteRequest{
+		PutRequest: &dynamodb.PutRequest{
+			Item: map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}},
+		},
+	}
+}
+
+func requestIds(reqs []*dynamodb.WriteRequest) []string {
+	var ids []string
+	for _, r := range reqs {
+		ids = append(ids, aws.StringValue(r.PutRequest.Item["id"].S))
+	}
+	return ids
+}
+
+func keyIds(keys []map[string]*dynamodb.AttributeValue) []string {
+	var ids []string
+	for _, k := range keys {
+		ids = append(ids, aws.StringValue(k["id"].S))
+	}
+	return ids
+}
+
+// unprocess returns a BatchWriteItem output which leaves the requests with the provided ids unprocessed.
+func unprocess(ids ...string) func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+		output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}
+		for table, reqs := range input.RequestItems {
+			for _, r := range reqs {
+				for _, id := range ids {
+					if aws.StringValue(r.PutRequest.Item["id"].S) == id {
+						output.UnprocessedItems[table] = append(output.UnprocessedItems[table], r)
+					}
+				}
+			}
+		}
+		return output, nil
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func TestBatchWrite(t *testing.T) {
+	t.Run("RetriesUnprocessed", func(t *testing.T) {
+		repo, client := newFakeBatchRepository(t)
+		client.writeOutputs = append(client.writeOutputs, unprocess("b", "c"), unprocess("c"))
+
+		err := repo.batchWrite([]*dynamodb.WriteRequest{putRequest("a"), putRequest("b"), putRequest("c")}, "table")
+		if err != nil {
+			t.Fatalf("batchWrite got err %v, want nil", err)
+		}
+		if len(client.writeInputs) != 3 {
+			t.Fatalf("batchWrite made %d calls, want 3", len(client.writeInputs))
+		}
+		if got := requestIds(client.writeInputs[1].RequestItems["table"]); !equalStrings(got, []string{"b", "c"}) {
+			t.Errorf("batchWrite retried %v, want [b c]", got)
+		}
+		if got := requestIds(client.writeInputs[2].RequestItems["table"]); !equalStrings(got, []string{"c"}) {
+			t.Errorf("batchWrite retried %v, want [c]", got)
+		}
+	})
+
+	t.Run("RetriesExhausted", func(t *testing.T) {
+		repo, client := newFakeBatchRepository(t)
+		for range batchMaxRetries + 1 {
+			client.writeOutputs = append(client.writeOutputs, unprocess("b"))
+		}
+
+		err := repo.batchWrite([]*dynamodb.WriteRequest{putRequest("a"), putRequest("b")}, "table")
+		var uerr *UnprocessedWritesError
+		if !errors.As(err, &uerr) {
+			t.Fatalf("batchWrite got err %v, want UnprocessedWritesError", err)
+		}
+		if got := requestIds(uerr.Requests); !equalStrings(got, []string{"b"}) {
+			t.Errorf("batchWrite failed requests = %v, want [b]", got)
+		}
+		if len(client.writeInputs) != batchMaxRetries+1 {
+			t.Errorf("batchWrite made %d calls, want %d", len(client.writeInputs), batchMaxRetries+1)
+		}
+	})
+
+	t.Run("ApiError", func(t *testing.T) {
+		repo, client := newFakeBatchRepository(t)
+		cause := fmt.Errorf("validation error")
+		client.writeOutputs = append(client.writeOutputs, unprocess("b"),
+			func(*dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) { return nil, cause })
+
+		err := repo.batchWrite([]*dynamodb.WriteRequest{putRequest("a"), putRequest("b")}, "table")
+		var uerr *UnprocessedWritesError
+		if !errors.As(err, &uerr) {
+			t.Fatalf("batchWrite got err %v, want UnprocessedWritesError", err)
+		}
+		if got := requestIds(uerr.Requests); !equalStrings(got, []string{"b"}) {
+			t.Errorf("batchWrite failed requests = %v, want [b]", got)
+		}
+		if uerr.Err != cause {
+			t.Errorf("batchWrite cause = %v, want %v", uerr.Err, cause)
+		}
+	})
+}
+
+func TestBatchWriteObjects(t *testing.T) {
+	repo, client := newFakeBatchRepository(t)
+
+	var objects []map[string]string
+	for i := range 30 {
+		objects = append(objects, map[string]string{"id": fmt.Sprint(i)})
+	}
+
+	// The first chunk leaves 3 unprocessed until retries are exhausted; the second chunk succeeds.
+	for range batchMaxRetries + 1 {
+		client.writeOutputs = append(client.writeOutputs, unprocess("3", "7", "11"))
+	}
+
+	updated, err := batchWriteObjects(repo, objects, "table")
+	if updated != 27 {
+		t.Errorf("batchWriteObjects updated = %d, want 27", updated)
+	}
+	var uerr *UnprocessedWritesError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("batchWriteObjects got err %v, want UnprocessedWritesError", err)
+	}
+	if got := requestIds(uerr.Requests); !equalStrings(got, []string{"3", "7", "11"}) {
+		t.Errorf("batchWriteObjects failed requests = %v, want [3 7 11]", got)
+	}
+	if got := requestIds(client.writeInputs[len(client.writeInputs)-1].RequestItems["table"]); len(got) != 5 {
+		t.Errorf("batchWriteObjects last chunk = %v, want 5 requests", got)
+	}
+}
+
+func TestBatchGet(t *testing.T) {
+	key := func(id string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}}
+	}
+
+	// respond returns the first processed keys as items and leaves the rest unprocessed.
+	respond := func(processed int) func(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+		return func(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+			request := input.RequestItems["table"]
+			processed := min(processed, len(request.Keys))
+			output := &dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]*dynamodb.AttributeValue{
+					"table": request.Keys[:processed],
+				},
+			}
+			if processed < len(request.Keys) {
+				output.UnprocessedKeys = map[string]*dynamodb.KeysAndAttributes{
+					"table": {Keys: request.Keys[processed:], ProjectionExpression: request.ProjectionExpression},
+				}
+			}
+			return output, nil
+		}
+	}
+
+	type item struct {
+		Id string `dynamodbav:"id"`
+	}
+
+	t.Run("RetriesUnprocessed", func(t *testing.T) {
+		repo, client := newFakeBatchRepository(t)
+		client.getOutputs = append(client.getOutputs, respond(1))
+
+		var items []item
+		err := repo.batchGet("table", &dynamodb.KeysAndAttributes{
+			Keys:                 []map[string]*dynamodb.AttributeValue{key("a"), key("b"), key("c")},
+			ProjectionExpression: aws.String("id"),
+		}, &items)
+		if err != nil {
+			t.Fatalf("batchGet got err %v, want nil", err)
+		}
+		if fmt.Sprint(items) != "[{a} {b} {c}]" {
+			t.Errorf("batchGet items = %v, want [{a} {b} {c}]", items)
+		}
+		if len(client.getInputs) != 3 {
+			t.Errorf("batchGet made %d calls, want 3", len(client.getInputs))
+		}
+		if got := aws.StringValue(client.getInputs[2].RequestItems["table"].ProjectionExpression); got != "id" {
+			t.Errorf("batchGet retry projection = %q, want id", got)
+		}
+	})
+
+	t.Run("RetriesExhausted", func(t *testing.T) {
+		repo, client := newFakeBatchRepository(t)
+		client.getOutputs = append(client.getOutputs, respond(1), respond(0))
+
+		var items []item
+		err := repo.batchGet("table", &dynamodb.KeysAndAttributes{
+			Keys: []map[string]*dynamodb.AttributeValue{key("a"), key("b"), key("c")},
+		}, &items)
+		var uerr *UnprocessedKeysError
+		if !errors.As(err, &uerr) {
+			t.Fatalf("batchGet got err %v, want UnprocessedKeysError", err)
+		}
+		if got := keyIds(uerr.Keys); !equalStrings(got, []string{"b", "c"}) {
+			t.Errorf("batchGet failed keys = %v, want [b c]", got)
+		}
+		if fmt.Sprint(items) != "[{a}]" {
+			t.Errorf("batchGet items = %v, want [{a}]", items)
+		}
+		if len(client.getInputs) != batchMaxRetries+1 {
+			t.Errorf("batchGet made %d calls, want %d", len(client.getInputs), batchMaxRetries+1)
+		}
+	})
+}
+
+func TestBatchExecute(t *testing.T) {
+	statement := func(s string) *dynamodb.BatchStatementRequest {
+		return &dynamodb.BatchStatementRequest{Statement: aws.String(s)}
+	}
+	statementStrings := func(statements []*dynamodb.BatchStatementRequest) []string {
+		var result []string
+		for _, s := range statements {
+			result = append(result, aws.StringValue(s.Statement))
+		}
+		return result
+	}
+
+	// respond fails each statement with the error code in codes, while any other statement succeeds.
+	respond := func(codes map[string]string) func(input *dynamodb.BatchExecuteStatementInput) (*dynamodb.BatchExecuteStatementOutput, error) {
+		return func(input *dynamodb.BatchExecuteStatementInput) (*dynamodb.BatchExecuteStatementOutput, error) {
+			output := &dynamodb.BatchExecuteStatementOutput{}
+			for _, s := range input.Statements {
+				response := &dynamodb.BatchStatementResponse{}
+				if code, ok := codes[aws.StringValue(s.Statement)]; ok {
+					response.Error = &dynamodb.BatchStatementError{Code: aws.String(code)}
+				}
+				output.Responses = append(output.Responses, response)
+			}
+			return output, nil
+		}
+	}
+
+	t.Run("RetriesThrottled", func(t *testing.T) {
+		repo, client := newFakeBatchRepository(t)
+		calls := 0
+		client.executeOutput = func(input *dynamodb.BatchExecuteStatementInput) (*dynamodb.BatchExecuteStatementOutput, error) {
+			calls++
+			if calls == 1 {
+				return respond(map[string]string{"b": dynamodb.BatchStatementErrorCodeEnumThrottlingError})(input)
+			}
+			return respond(nil)(input)
+		}
+
+		if err := repo.batchExecute([]*dynamodb.BatchStatementRequest{statement("a"), statement("b")}); err != nil {
+			t.Fatalf("batchExecute got err %v, want nil", err)
+		}
+		if len(client.executeInputs) != 2 {
+			t.Fatalf("batchExecute made %d calls, want 2", len(client.executeInputs))
+		}
+		if got := statementStrings(client.executeInputs[1].Statements); !equalStrings(got, []string{"b"}) {
+			t.Errorf("batchExecute retried %v, want [b]", got)
+		}
+	})
+
+	t.Run("ReportsFailed", func(t *testing.T) {
+		repo, client := newFakeBatchRepository(t)
+		client.executeOutput = respond(map[string]string{
+			"b": dynamodb.BatchStatementErrorCodeEnumConditionalCheckFailed,
+			"c": dynamodb.BatchStatementErrorCodeEnumProvisionedThroughputExceeded,
+		})
+
+		err := repo.batchExecute([]*dynamodb.BatchStatementRequest{statement("a"), statement("b"), statement("c")})
+		var ferr *FailedStatementsError
+		if !errors.As(err, &ferr) {
+			t.Fatalf("batchExecute got err %v, want FailedStatementsError", err)
+		}
+		if got := statementStrings(ferr.Statements); !equalStrings(got, []string{"b", "c"}) {
+			t.Errorf("batchExecute failed statements = %v, want [b c]", got)
+		}
+		if len(ferr.Errors) != 2 || aws.StringValue(ferr.Errors[0].Code) != dynamodb.BatchStatementErrorCodeEnumConditionalCheckFailed {
+			t.Errorf("batchExecute errors = %v, want ConditionalCheckFailed first", ferr.Errors)
+		}
+		if len(client.executeInputs) != batchMaxRetries+1 {
+			t.Errorf("batchExecute made %d calls, want %d", len(client.executeInputs), batchMaxRetries+1)
+		}
+	})
+}
@@ -1,11 +1,15 @@
 package database
 
 import (
+	"fmt"
 	"math"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
 )
 
 type RequirementStatus string
@@ -149,6 +153,20 @@ type Position struct {
 	Result string `dynamodbav:"result" json:"result"`
 }
 
+// ValidatePositions returns a 400 error if any of the provided positions has an invalid FEN.
+// field is the name of the positions in the request, which is used in the error message.
+func ValidatePositions(field string, positions []*Position) error {
+	for i, p := range positions {
+		if p == nil {
+			continue
+		}
+		if _, err := pgn.ParseFEN(p.Fen); err != nil {
+			return errors.Wrap(400, fmt.Sprintf("Invalid request: %s[%d].fen is invalid: %v", field, i, err), "", err)
+		}
+	}
+	return nil
+}
+
 type Requirement struct {
 	// Uniquely identifies a requirement. The sort key for the table.
 	Id string `dynamodbav:"id" json:"id"`
@@ -469,6 +487,26 @@ func (repo *dynamoRepository) GetRequirement(id string) (*Requirement, error) {
 	return &requirement, nil
 }
 
+// SetRequirement saves the provided requirement in the database. The requirement's positions
+// must have valid FENs.
+func (repo *dynamoRepository) SetRequirement(requirement *Requirement) error {
+	if err := ValidatePositions("positions", requirement.Positions); err != nil {
+		return err
+	}
+
+	item, err := dynamodbattribute.MarshalMap(requirement)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Unable to marshal requirement", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:      item,
+		TableName: aws.String(requirementTable),
+	}
+	_, err = repo.svc.PutItem(input)
+	return errors.Wrap(500, "Temporary server error", "DynamoDB PutItem failure", err)
+}
+
 // IsDeletedRequirement returns true if the given id is the id of a deleted requirement.
 func IsDeletedRequirement(id string) bool {
 	for _, req := range deletedRequirements {
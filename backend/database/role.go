@@ -0,0 +1,204 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// Role is a named set of permissions which can be assigned to a user.
+type Role string
+
+const (
+	// Admins have every permission.
+	Role_Admin Role = "ADMIN"
+
+	// Tournament directors manage the Open Classical.
+	Role_TournamentDirector Role = "TOURNAMENT_DIRECTOR"
+
+	// Calendar editors manage Dojo events on the calendar.
+	Role_CalendarEditor Role = "CALENDAR_EDITOR"
+
+	// Game reviewers review games submitted for review.
+	Role_GameReviewer Role = "GAME_REVIEWER"
+
+	// Club moderators can edit any club.
+	Role_ClubModerator Role = "CLUB_MODERATOR"
+
+	// Support staff can view the details of any event in order to help users.
+	Role_Support Role = "SUPPORT"
+)
+
+// Permission is a single action which requires a role.
+type Permission string
+
+const (
+	// Assign roles to users.
+	Permission_ManageRoles Permission = "MANAGE_ROLES"
+
+	// Register, pair, ban and verify results for players in the Open Classical.
+	Permission_ManageTournaments Permission = "MANAGE_TOURNAMENTS"
+
+	// Create, edit and delete Dojo events.
+	Permission_ManageCalendar Permission = "MANAGE_CALENDAR"
+
+	// Create and edit live class events.
+	Permission_ManageLiveClasses Permission = "MANAGE_LIVE_CLASSES"
+
+	// View every event, including the location and messages of events the user is not in.
+	Permission_ViewAllEvents Permission = "VIEW_ALL_EVENTS"
+
+	// Update the review status of games submitted for review.
+	Permission_ReviewGames Permission = "REVIEW_GAMES"
+
+	// Edit clubs owned by other users.
+	Permission_ModerateClubs Permission = "MODERATE_CLUBS"
+
+	// View the audit log of admin and moderator actions.
+	Permission_ViewAuditLog Permission = "VIEW_AUDIT_LOG"
+
+	// View and replay notification events which failed to send.
+	Permission_ManageOutbox Permission = "MANAGE_OUTBOX"
+
+	// Preview changes to the cohort rating boundaries.
+	Permission_ManageCohorts Permission = "MANAGE_COHORTS"
+)
+
+// rolePermissions maps each role other than Role_Admin to its permissions.
+var rolePermissions = map[Role][]Permission{
+	Role_TournamentDirector: {Permission_ManageTournaments, Permission_ViewAuditLog},
+	Role_CalendarEditor:     {Permission_ManageCalendar, Permission_ViewAllEvents},
+	Role_GameReviewer:       {Permission_ReviewGames},
+	Role_ClubModerator:      {Permission_ModerateClubs},
+	Role_Support:            {Permission_ViewAllEvents, Permission_ViewAuditLog},
+}
+
+// IsValidRole returns true if the provided role exists.
+func IsValidRole(role Role) bool {
+	if role == Role_Admin {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasRole returns true if the user has been assigned the provided role.
+func (u *User) HasRole(role Role) bool {
+	if u == nil {
+		return false
+	}
+	return slices.Contains(u.Roles, role)
+}
+
+// HasPermission returns true if any of the user's roles grants the provided permission. The
+// roles equivalent to the deprecated IsAdmin, IsCalendarAdmin and IsTournamentAdmin fields are
+// also checked, so that users who have not been migrated to roles keep their permissions.
+func (u *User) HasPermission(permission Permission) bool {
+	if u == nil {
+		return false
+	}
+	for _, role := range slices.Concat(u.Roles, u.LegacyRoles()) {
+		if role == Role_Admin || slices.Contains(rolePermissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRoles sets the user's roles. The deprecated IsAdmin, IsCalendarAdmin and
+// IsTournamentAdmin fields are updated to match.
+func (u *User) SetRoles(roles []Role) {
+	u.Roles = roles
+	u.IsAdmin = u.HasRole(Role_Admin)
+	u.IsCalendarAdmin = u.HasRole(Role_CalendarEditor)
+	u.IsTournamentAdmin = u.HasRole(Role_TournamentDirector)
+}
+
+// LegacyRoles returns the roles equivalent to the user's deprecated IsAdmin, IsCalendarAdmin
+// and IsTournamentAdmin fields.
+func (u *User) LegacyRoles() []Role {
+	var roles []Role
+	if u.IsAdmin {
+		roles = append(roles, Role_Admin)
+	}
+	if u.IsCalendarAdmin {
+		roles = append(roles, Role_CalendarEditor)
+	}
+	if u.IsTournamentAdmin {
+		roles = append(roles, Role_TournamentDirector)
+	}
+	return roles
+}
+
+// checkDuplicateRoles returns a 400 error if the provided roles contain the same role more than once.
+func checkDuplicateRoles(roles []Role) error {
+	for i, role := range roles {
+		if slices.Contains(roles[:i], role) {
+			return errors.New(400, fmt.Sprintf("Invalid request: role `%s` is duplicated", role), "")
+		}
+	}
+	return nil
+}
+
+type UserRoleSetter interface {
+	UserGetter
+	AuditEntryPutter
+
+	// SetUserRoles sets the roles of the user with the provided username. The roles must
+	// not contain duplicates. The updated user is returned.
+	SetUserRoles(username string, roles []Role) (*User, error)
+}
+
+// SetUserRoles sets the roles of the user with the provided username. The roles must not
+// contain duplicates. The deprecated IsAdmin, IsCalendarAdmin and IsTournamentAdmin fields
+// are updated to match, and the user's version is incremented. The updated user is returned.
+func (repo *dynamoRepository) SetUserRoles(username string, roles []Role) (*User, error) {
+	if err := checkDuplicateRoles(roles); err != nil {
+		return nil, err
+	}
+
+	u := &User{}
+	u.SetRoles(roles)
+
+	rolesAttribute, err := dynamodbattribute.Marshal(u.Roles)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Unable to marshal roles", err)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(username)"),
+		UpdateExpression:    aws.String("SET #roles = :roles, #isAdmin = :isAdmin, #isCalendarAdmin = :isCalendarAdmin, #isTournamentAdmin = :isTournamentAdmin ADD #version :one"),
+		ExpressionAttributeNames: map[string]*string{
+			"#roles":             aws.String("roles"),
+			"#isAdmin":           aws.String("isAdmin"),
+			"#isCalendarAdmin":   aws.String("isCalendarAdmin"),
+			"#isTournamentAdmin": aws.String("isTournamentAdmin"),
+			"#version":           aws.String("version"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":roles":             rolesAttribute,
+			":isAdmin":           {BOOL: aws.Bool(u.IsAdmin)},
+			":isCalendarAdmin":   {BOOL: aws.Bool(u.IsCalendarAdmin)},
+			":isTournamentAdmin": {BOOL: aws.Bool(u.IsTournamentAdmin)},
+			":one":               {N: aws.String("1")},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"username": {S: aws.String(username)},
+		},
+		ReturnValues: aws.String("ALL_NEW"),
+		TableName:    aws.String(userTable),
+	}
+
+	user := &User{}
+	if err := repo.updateItem(input, user); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
+			return nil, errors.Wrap(404, "Invalid request: user not found", "DynamoDB conditional check failed", aerr)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "Failed DynamoDB UpdateItem", err)
+	}
+	return user, nil
+}
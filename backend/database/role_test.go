@@ -0,0 +1,68 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHasPermission(t *testing.T) {
+	table := []struct {
+		name       string
+		user       *User
+		permission Permission
+		want       bool
+	}{
+		{name: "NilUser", permission: Permission_ViewAllEvents},
+		{name: "NoRoles", user: &User{}, permission: Permission_ViewAllEvents},
+		{name: "LegacyAdmin", user: &User{IsAdmin: true}, permission: Permission_ManageRoles, want: true},
+		{name: "LegacyCalendarAdmin", user: &User{IsCalendarAdmin: true}, permission: Permission_ViewAllEvents, want: true},
+		{name: "LegacyTournamentAdmin", user: &User{IsTournamentAdmin: true}, permission: Permission_ManageCalendar},
+		{name: "Admin", user: &User{Roles: []Role{Role_Admin}}, permission: Permission_ManageRoles, want: true},
+		{name: "Granted", user: &User{Roles: []Role{Role_CalendarEditor}}, permission: Permission_ViewAllEvents, want: true},
+		{name: "NotGranted", user: &User{Roles: []Role{Role_CalendarEditor}}, permission: Permission_ManageTournaments},
+		{name: "SecondRole", user: &User{Roles: []Role{Role_Support, Role_GameReviewer}}, permission: Permission_ReviewGames, want: true},
+		{name: "UnknownRole", user: &User{Roles: []Role{"UNKNOWN"}}, permission: Permission_ViewAllEvents},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.user.HasPermission(tc.permission); got != tc.want {
+				t.Errorf("HasPermission(%s) = %t; want %t", tc.permission, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMemorySetUserRoles(t *testing.T) {
+	repo := NewMemoryRepository()
+	if err := repo.SetUserConditional(&User{Username: "alice", IsCalendarAdmin: true}, nil); err != nil {
+		t.Fatalf("SetUserConditional: %v", err)
+	}
+	before, err := repo.GetUser("alice")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+
+	user, err := repo.SetUserRoles("alice", []Role{Role_Admin, Role_TournamentDirector})
+	if err != nil {
+		t.Fatalf("SetUserRoles: %v", err)
+	}
+	if diff := cmp.Diff([]Role{Role_Admin, Role_TournamentDirector}, user.Roles); diff != "" {
+		t.Errorf("SetUserRoles roles mismatch (-want +got):\n%s", diff)
+	}
+	if !user.IsAdmin || user.IsCalendarAdmin || !user.IsTournamentAdmin {
+		t.Errorf("SetUserRoles flags = (%t, %t, %t); want (true, false, true)", user.IsAdmin, user.IsCalendarAdmin, user.IsTournamentAdmin)
+	}
+	if user.Version != before.Version+1 {
+		t.Errorf("SetUserRoles version = %d; want %d", user.Version, before.Version+1)
+	}
+
+	if _, err := repo.SetUserRoles("alice", []Role{Role_Support, Role_Support}); errorCode(err) != 400 {
+		t.Errorf("SetUserRoles with duplicate roles err = %v; want 400", err)
+	}
+
+	if _, err := repo.SetUserRoles("bob", []Role{Role_Support}); errorCode(err) != 404 {
+		t.Errorf("SetUserRoles(bob) err = %v; want 404", err)
+	}
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 )
 
@@ -98,31 +97,21 @@ func (repo *dynamoRepository) GetScoreboardSummaries(usernames []string) ([]Scor
 		return nil, errors.New(500, "Temporary server error", "More than 100 usernames passed to GetScoreboardSummaries")
 	}
 
-	input := &dynamodb.BatchGetItemInput{
-		RequestItems: map[string]*dynamodb.KeysAndAttributes{
-			userTable: {
-				Keys:                 []map[string]*dynamodb.AttributeValue{},
-				ProjectionExpression: aws.String(scoreboardSummaryProjection),
-			},
-		},
+	request := &dynamodb.KeysAndAttributes{
+		Keys:                 []map[string]*dynamodb.AttributeValue{},
+		ProjectionExpression: aws.String(scoreboardSummaryProjection),
 	}
 
 	for _, u := range usernames {
 		key := map[string]*dynamodb.AttributeValue{
 			"username": {S: aws.String(u)},
 		}
-		input.RequestItems[userTable].Keys = append(input.RequestItems[userTable].Keys, key)
-	}
-
-	result, err := repo.svc.BatchGetItem(input)
-	if err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed call to BatchGetItem", err)
+		request.Keys = append(request.Keys, key)
 	}
-	list := result.Responses[userTable]
 
 	var summaries []ScoreboardSummary
-	if err := dynamodbattribute.UnmarshalListOfMaps(list, &summaries); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal BatchGetItem result", err)
+	if err := repo.batchGet(userTable, request, &summaries); err != nil {
+		return nil, err
 	}
 
 	return summaries, nil
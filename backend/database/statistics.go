@@ -112,6 +112,17 @@ type UserStatisticsGetter interface {
 	GetUserStatistics() (*UserStatistics, error)
 }
 
+type UserStatisticsUpdater interface {
+	UserStatisticsGetter
+	UserRatingLister
+	RequirementScanner
+	GraduationScanner
+	RatingDistributionWriter
+
+	// SetUserStatistics inserts the provided user statistics into the database.
+	SetUserStatistics(stats *UserStatistics) error
+}
+
 // NewUserStatistics returns a blank UserStatistics object with all values
 // set to zero.
 func NewUserStatistics() *UserStatistics {
@@ -156,6 +156,12 @@ type TimelineGetter interface {
 	GetTimelineEntry(owner, id string) (*TimelineEntry, error)
 }
 
+type TimelineBatchGetter interface {
+	// BatchGetTimelineEntries returns the TimelineEntries with the provided keys. Up to 100 keys
+	// can be specified at a time.
+	BatchGetTimelineEntries(entries map[string]TimelineEntryKey) ([]TimelineEntry, error)
+}
+
 type TimelineLister interface {
 	// ListTimelineEntries returns a list of TimelineEntries with the provided owner,
 	// up to 1MB of data. startKey can be passed to perform pagination.
@@ -165,14 +171,17 @@ type TimelineLister interface {
 type TimelineCommenter interface {
 	UserGetter
 
-	CreateTimelineComment(owner, id string, comment *Comment) (*TimelineEntry, error)
+	// CreateTimelineComment appends the given comment to the provided TimelineEntry. The outbox
+	// entries are saved in the same transaction.
+	CreateTimelineComment(owner, id string, comment *Comment, outbox ...*OutboxEntry) (*TimelineEntry, error)
 }
 
 type TimelineReactor interface {
 	UserGetter
 
-	// SetTimelineReaction sets the given reaction on the provided TimelineEntry.
-	SetTimelineReaction(owner, id string, reaction *Reaction) (*TimelineEntry, error)
+	// SetTimelineReaction sets the given reaction on the provided TimelineEntry. The outbox
+	// entries are saved in the same transaction.
+	SetTimelineReaction(owner, id string, reaction *Reaction, outbox ...*OutboxEntry) (*TimelineEntry, error)
 }
 
 // PutTimelineEntry saves the provided TimelineEntry into the database.
@@ -301,12 +310,8 @@ func (repo *dynamoRepository) BatchGetTimelineEntries(entries map[string]Timelin
 		return nil, errors.New(500, "Temporary server error", "More than 100 items in BatchGetTimelineEntries request")
 	}
 
-	input := &dynamodb.BatchGetItemInput{
-		RequestItems: map[string]*dynamodb.KeysAndAttributes{
-			timelineTable: {
-				Keys: []map[string]*dynamodb.AttributeValue{},
-			},
-		},
+	request := &dynamodb.KeysAndAttributes{
+		Keys: []map[string]*dynamodb.AttributeValue{},
 	}
 
 	for _, e := range entries {
@@ -314,24 +319,20 @@ func (repo *dynamoRepository) BatchGetTimelineEntries(entries map[string]Timelin
 			"owner": {S: aws.String(e.Owner)},
 			"id":    {S: aws.String(e.Id)},
 		}
-		input.RequestItems[timelineTable].Keys = append(input.RequestItems[timelineTable].Keys, key)
+		request.Keys = append(request.Keys, key)
 	}
 
-	result, err := repo.svc.BatchGetItem(input)
-	if err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed call to BatchGetItem", err)
-	}
-	list := result.Responses[timelineTable]
-
 	var resultEntries []TimelineEntry
-	if err := dynamodbattribute.UnmarshalListOfMaps(list, &resultEntries); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal BatchGetItem result", err)
+	if err := repo.batchGet(timelineTable, request, &resultEntries); err != nil {
+		return nil, err
 	}
 
 	return resultEntries, nil
 }
 
-func (repo *dynamoRepository) CreateTimelineComment(owner, id string, comment *Comment) (*TimelineEntry, error) {
+// CreateTimelineComment appends the given comment to the provided TimelineEntry. The outbox
+// entries are saved in the same transaction.
+func (repo *dynamoRepository) CreateTimelineComment(owner, id string, comment *Comment, outbox ...*OutboxEntry) (*TimelineEntry, error) {
 	item, err := dynamodbattribute.MarshalMap(comment)
 	if err != nil {
 		return nil, errors.Wrap(400, "Invalid request: comment cannot be marshaled", "", err)
@@ -360,23 +361,19 @@ func (repo *dynamoRepository) CreateTimelineComment(owner, id string, comment *C
 		TableName:    aws.String(timelineTable),
 	}
 
-	result, err := repo.svc.UpdateItem(input)
-	if err != nil {
+	entry := TimelineEntry{}
+	if err := repo.updateItemWithOutbox(input, outbox, &entry); err != nil {
 		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
 			return nil, errors.Wrap(400, "Invalid request: timeline entry not found", "DynamoDB conditional check failed", aerr)
 		}
 		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
 	}
-
-	entry := TimelineEntry{}
-	if err = dynamodbattribute.UnmarshalMap(result.Attributes, &entry); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal CreateTimelineComment result", err)
-	}
 	return &entry, nil
 }
 
-// SetTimelineReaction sets the given reaction on the provided TimelineEntry.
-func (repo *dynamoRepository) SetTimelineReaction(owner, id string, reaction *Reaction) (*TimelineEntry, error) {
+// SetTimelineReaction sets the given reaction on the provided TimelineEntry. The outbox
+// entries are saved in the same transaction.
+func (repo *dynamoRepository) SetTimelineReaction(owner, id string, reaction *Reaction, outbox ...*OutboxEntry) (*TimelineEntry, error) {
 	item, err := dynamodbattribute.MarshalMap(reaction)
 	if err != nil {
 		return nil, errors.Wrap(400, "Invalid request: reaction cannot be marshaled", "", err)
@@ -401,17 +398,12 @@ func (repo *dynamoRepository) SetTimelineReaction(owner, id string, reaction *Re
 		TableName:    aws.String(timelineTable),
 	}
 
-	result, err := repo.svc.UpdateItem(input)
-	if err != nil {
+	entry := TimelineEntry{}
+	if err := repo.updateItemWithOutbox(input, outbox, &entry); err != nil {
 		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok {
 			return nil, errors.Wrap(400, "Invalid request: timeline entry not found", "DynamoDB conditional check failed", aerr)
 		}
 		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
 	}
-
-	entry := TimelineEntry{}
-	if err = dynamodbattribute.UnmarshalMap(result.Attributes, &entry); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal SetTimelineReaction result", err)
-	}
 	return &entry, nil
 }
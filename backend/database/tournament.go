@@ -91,6 +91,79 @@ type Leaderboard struct {
 	Players []LeaderboardPlayer `dynamodbav:"players" json:"players"`
 }
 
+type LeaderboardGetter interface {
+	// GetLeaderboard fetches the leaderboard with the provided values.
+	GetLeaderboard(site LeaderboardSite, timePeriod, tournamentType, timeControl, startsAt string) (*Leaderboard, error)
+}
+
+type LeaderboardSetter interface {
+	LeaderboardGetter
+
+	// SetLeaderboard inserts the provided leaderboard into the database.
+	SetLeaderboard(leaderboard Leaderboard) error
+}
+
+type OpenClassicalGetter interface {
+	// GetOpenClassical returns the open classical for the provided startsAt period.
+	GetOpenClassical(startsAt string) (*OpenClassical, error)
+}
+
+type OpenClassicalLister interface {
+	// ListPreviousOpenClassicals returns a list of OpenClassicals whose name is not CURRENT.
+	// The list is sorted in descending order by name.
+	ListPreviousOpenClassicals(startKey string) ([]OpenClassical, string, error)
+}
+
+type OpenClassicalRegistrar interface {
+	UserGetter
+	OpenClassicalGetter
+
+	// UpdateOpenClassicalRegistration adds the provided player to the given open classical. If the player
+	// already exists in a different section, they are removed.
+	UpdateOpenClassicalRegistration(openClassical *OpenClassical, player *OpenClassicalPlayer) (*OpenClassical, error)
+}
+
+type OpenClassicalResultSubmitter interface {
+	OpenClassicalGetter
+
+	// UpdateOpenClassicalResult sets the pairing on the current open classical to match the given
+	// update. The update succeeds only if the pairing is not already marked as verified.
+	UpdateOpenClassicalResult(update *OpenClassicalPairingUpdate) (*OpenClassical, error)
+}
+
+type OpenClassicalEditor interface {
+	UserGetter
+	AuditEntryPutter
+	OpenClassicalResultSubmitter
+
+	// SetOpenClassical inserts the provided OpenClassical into the database.
+	SetOpenClassical(openClassical *OpenClassical) error
+
+	// SetPairingEmailsSent sets the pairing emails sent flag to true for all sections in the current
+	// open classical. Round is a 1-based index.
+	SetPairingEmailsSent(openClassical *OpenClassical, round int) (*OpenClassical, error)
+
+	// BanPlayer bans the given player in the current open classical.
+	BanPlayer(player *OpenClassicalPlayer) (*OpenClassical, error)
+
+	// UnbanPlayer unbans the given player in the current open classical.
+	UnbanPlayer(username string) (*OpenClassical, error)
+
+	// SetPlayer sets a player in the current open classical. The player must already exist in the
+	// given region and section.
+	SetPlayer(player *OpenClassicalPlayer) (*OpenClassical, error)
+
+	// OpenClassicalCloseRegistrations closes registrations for the current open classical.
+	OpenClassicalCloseRegistrations() (*OpenClassical, error)
+
+	// OpenClassicalAddRound adds a new round to the given region and section of the current open
+	// classical, using the provided pairings.
+	OpenClassicalAddRound(region, section string, pairings []OpenClassicalPairing) (*OpenClassical, error)
+
+	// OpenClassicalSetRound sets the pairings in the given round for current open classical.
+	OpenClassicalSetRound(region, section string, round int, pairings []OpenClassicalPairing) (*OpenClassical, error)
+}
+
 // SetLeaderboard inserts the provided leaderboard into the database.
 func (repo *dynamoRepository) SetLeaderboard(leaderboard Leaderboard) error {
 	item, err := dynamodbattribute.MarshalMap(leaderboard)
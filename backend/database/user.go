@@ -156,6 +156,24 @@ type Rating struct {
 
 	// Whether the user's rating is provisional
 	IsProvisional bool `dynamodbav:"isProvisional,omitempty" json:"isProvisional,omitempty"`
+
+	// Whether the user has proven that they own the account with Username. Reset
+	// whenever Username changes.
+	Verified bool `dynamodbav:"verified,omitempty" json:"verified,omitempty"`
+}
+
+// RatingVerification is a token issued to a user to prove that they own an account in
+// a rating system. The user places the token in the account's profile, and the server
+// checks for it.
+type RatingVerification struct {
+	// The username of the account being verified.
+	Username string `dynamodbav:"username" json:"username"`
+
+	// The token which must be placed in the account's profile.
+	Token string `dynamodbav:"token" json:"token"`
+
+	// The time the token expires, in time.RFC3339 format.
+	ExpiresAt string `dynamodbav:"expiresAt" json:"expiresAt"`
 }
 
 type RatingHistory struct {
@@ -205,7 +223,14 @@ type User struct {
 	// The user's ratings in each rating system
 	Ratings map[RatingSystem]*Rating `dynamodbav:"ratings" json:"ratings"`
 
+	// The pending verifications of the user's ratings, keyed by rating system.
+	RatingVerifications map[RatingSystem]*RatingVerification `dynamodbav:"ratingVerifications,omitempty" json:"-"`
+
 	// A map from a rating system to a slice of RatingHistory objects for that rating system.
+	//
+	// Deprecated: rating history is saved in the rating history table. This field is still
+	// written by the rating update for clients which read it, and is only read by the backend
+	// for users whose history has not been migrated. Use GetRatingHistories instead.
 	RatingHistories map[RatingSystem][]RatingHistory `dynamodbav:"ratingHistories" json:"ratingHistories"`
 
 	// The user's Dojo cohort
@@ -217,13 +242,23 @@ type User struct {
 	// The number of games the user has created
 	GamesCreated map[DojoCohort]int `dynamodbav:"gamesCreated" json:"gamesCreated"`
 
-	// Whether the user is an admin or not
+	// The roles assigned to the user. Use HasPermission to check whether the user
+	// can perform an action.
+	Roles []Role `dynamodbav:"roles,omitempty" json:"roles,omitempty"`
+
+	// Whether the user has Role_Admin.
+	//
+	// Deprecated: kept in sync with Roles by SetRoles for existing clients. Use HasPermission instead.
 	IsAdmin bool `dynamodbav:"isAdmin" json:"isAdmin"`
 
-	// Whether the user has admin privileges for the calendar
+	// Whether the user has Role_CalendarEditor.
+	//
+	// Deprecated: kept in sync with Roles by SetRoles for existing clients. Use HasPermission instead.
 	IsCalendarAdmin bool `dynamodbav:"isCalendarAdmin" json:"isCalendarAdmin"`
 
-	// Whether the user has admin privileges for tournaments
+	// Whether the user has Role_TournamentDirector.
+	//
+	// Deprecated: kept in sync with Roles by SetRoles for existing clients. Use HasPermission instead.
 	IsTournamentAdmin bool `dynamodbav:"isTournamentAdmin" json:"isTournamentAdmin"`
 
 	// Whether the user is a beta tester or not
@@ -250,6 +285,11 @@ type User struct {
 	// When the user was most recently updated (not including nightly rating updates)
 	UpdatedAt string `dynamodbav:"updatedAt" json:"updatedAt"`
 
+	// Incremented each time the user is saved or their profile, progress, ratings or times are
+	// updated. Used to detect concurrent modifications of the user. Users created before this
+	// field was added have version 0 until their next update.
+	Version int `dynamodbav:"version" json:"version"`
+
 	// Whether to enable light mode on the site
 	EnableLightMode bool `dynamodbav:"enableLightMode" json:"enableLightMode"`
 
@@ -620,6 +660,16 @@ func (u *User) GetRatingChange() int {
 	return current - start
 }
 
+// IsRatingVerified returns true if the user has verified that they own the provided
+// username in the provided rating system. Usernames are compared case-insensitively.
+func (u *User) IsRatingVerified(rs RatingSystem, username string) bool {
+	if u == nil || u.Ratings == nil || u.Ratings[rs] == nil {
+		return false
+	}
+	rating := u.Ratings[rs]
+	return rating.Verified && strings.EqualFold(strings.TrimSpace(rating.Username), strings.TrimSpace(username))
+}
+
 func (u *User) getDisplayName() string {
 	if u == nil {
 		return ""
@@ -666,13 +716,6 @@ func (u *User) GetSubscriptionTier() SubscriptionTier {
 	return u.SubscriptionTier
 }
 
-func (u *User) GetIsCalendarAdmin() bool {
-	if u == nil {
-		return false
-	}
-	return u.IsAdmin || u.IsCalendarAdmin
-}
-
 // UserUpdate contains pointers to fields included in the update of a user record. If a field
 // should not be updated in a particular request, then it is set to nil.
 // Some fields from the User type are removed as they cannot be updated. Other fields
@@ -699,6 +742,10 @@ type UserUpdate struct {
 	// The user's ratings in each rating system
 	Ratings *map[RatingSystem]*Rating `dynamodbav:"ratings,omitempty" json:"ratings,omitempty"`
 
+	// The pending verifications of the user's ratings.
+	// Cannot be manually passed by the user. The user should instead call the user/ratings/verification functions
+	RatingVerifications *map[RatingSystem]*RatingVerification `dynamodbav:"ratingVerifications,omitempty" json:"-"`
+
 	// The user's Dojo cohort
 	DojoCohort *DojoCohort `dynamodbav:"dojoCohort,omitempty" json:"dojoCohort,omitempty"`
 
@@ -722,6 +769,10 @@ type UserUpdate struct {
 	// Cannot be manually passed by the user and is updated automatically by the server
 	UpdatedAt *string `dynamodbav:"updatedAt,omitempty" json:"-"`
 
+	// The version of the user this update was based on. If set, the update fails with a 409
+	// error if the user has been modified since. Not saved in the database.
+	ExpectedVersion *int `dynamodbav:"-" json:"version,omitempty"`
+
 	// Maps requirement ids to RequirementProgress objects.
 	// Cannot be manually passed by the user. The user should instead call the user/progress/timeline function
 	Progress *map[string]*RequirementProgress `dynamodbav:"progress,omitempty" json:"-"`
@@ -816,24 +867,6 @@ type UserUpdate struct {
 	TimerStartedAt *string `dynamodbav:"timerStartedAt,omitempty" json:"timerStartedAt,omitempty"`
 }
 
-// AutopickCohort sets the UserUpdate's dojoCohort field based on the values of the ratingSystem
-// and current rating fields. The chosen cohort is returned.
-func (u *UserUpdate) AutopickCohort() DojoCohort {
-	if u == nil || u.RatingSystem == nil || u.Ratings == nil {
-		return NoCohort
-	}
-
-	rating, ok := (*u.Ratings)[*u.RatingSystem]
-	if !ok {
-		return NoCohort
-	}
-
-	currentRating := rating.CurrentRating
-	cohort := getCohort(*u.RatingSystem, currentRating)
-	u.DojoCohort = &cohort
-	return cohort
-}
-
 func (u *UserUpdate) getDisplayName() string {
 	if u == nil {
 		return ""
@@ -890,6 +923,11 @@ type UserGetter interface {
 	GetUser(username string) (*User, error)
 }
 
+type UserBatchGetter interface {
+	// BatchGetUsers returns a list of users with the provided usernames.
+	BatchGetUsers(usernames []string) ([]*User, error)
+}
+
 type UserLister interface {
 	// ListUsersByCohort returns a list of Users in the provided cohort, up to 1MB of data.
 	// startKey is an optional parameter that can be used to perform pagination.
@@ -902,11 +940,23 @@ type UserLister interface {
 	SearchUsers(query string, fields []string, startKey string) ([]*User, string, error)
 }
 
+type UserRatingLister interface {
+	// ListUserRatings returns a list of Users matching the provided cohort, up to 1MB of data.
+	// Only the fields necessary for the rating/statistics update are returned.
+	ListUserRatings(cohort DojoCohort, startKey string) ([]*User, string, error)
+}
+
+type CohortPreviewer interface {
+	UserGetter
+	RatingDistributionLister
+}
+
 type UserUpdater interface {
 	UserGetter
 
 	// UpdateUser applies the specified update to the user with the provided username.
-	UpdateUser(username string, update *UserUpdate) (*User, error)
+	// The outbox entries are saved in the same transaction.
+	UpdateUser(username string, update *UserUpdate, outbox ...*OutboxEntry) (*User, error)
 
 	// RecordSubscriptionCancelation adds 1 cancelation to the user statistics for
 	// the given cohort.
@@ -937,6 +987,37 @@ type AdminUserLister interface {
 	ScanUsers(startKey string) ([]*User, string, error)
 }
 
+type UserSummaryBatchGetter interface {
+	// BatchGetUsersProjection returns a list of users with the provided usernames and the provided
+	// projection expression.
+	BatchGetUsersProjection(usernames []string, projectionExpression string) ([]*User, error)
+}
+
+type DiscordUserGetter interface {
+	// GetUserByDiscordId returns the User object with the provided Discord ID.
+	GetUserByDiscordId(discordId string) (*User, error)
+}
+
+type UserSubscriptionChecker interface {
+	UserRatingLister
+
+	// UpdateUserSubscriptionStatuses saves the subscription status and tier of the provided users.
+	// Up to 25 users can be updated at a time.
+	UpdateUserSubscriptionStatuses(users []*User) error
+}
+
+type UserTimeUpdater interface {
+	UserBatchGetter
+	UserRatingLister
+	RequirementScanner
+	TimelineLister
+
+	// UpdateUserTimes updates the minutesSpent field on the provided users. Each user is only
+	// updated if its version has not changed since it was read. If some users have changed, the
+	// returned error's cause is an *errors.ConflictError containing their usernames.
+	UpdateUserTimes(users []*User) error
+}
+
 // CreateUser creates a new User object with the provided information.
 func (repo *dynamoRepository) CreateUser(username, email, name string, paymentInfo *PaymentInfo) (*User, error) {
 	subscriptionStatus := SubscriptionStatus_NotSubscribed
@@ -978,6 +1059,7 @@ func (repo *dynamoRepository) SetUserConditional(user *User, condition *string)
 	}
 
 	user.UpdatedAt = time.Now().Format(time.RFC3339)
+	user.Version++
 	item, err := dynamodbattribute.MarshalMap(user)
 	if err != nil {
 		return errors.Wrap(500, "Temporary server error", "Unable to marshal user", err)
@@ -1011,6 +1093,26 @@ func (repo *dynamoRepository) SetUserConditional(user *User, condition *string)
 // createDefaultDirectories creates the default directories that a user should have when
 // they first start using the Dojo.
 func (repo *dynamoRepository) createDefaultDirectories(user *User) error {
+	items, err := defaultDirectories(user)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		input := &dynamodb.PutItemInput{
+			Item:      item,
+			TableName: aws.String(directoryTable),
+		}
+		if _, err := repo.svc.PutItem(input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultDirectories returns the marshaled directories that a user should have when
+// they first start using the Dojo.
+func defaultDirectories(user *User) ([]map[string]*dynamodb.AttributeValue, error) {
 	type directoryItemMetadata struct {
 		CreatedAt   string `dynamodbav:"createdAt"`
 		UpdatedAt   string `dynamodbav:"updatedAt"`
@@ -1061,18 +1163,9 @@ func (repo *dynamoRepository) createDefaultDirectories(user *User) error {
 		},
 		ItemIds: []string{"mygames"},
 	}
-	item, err := dynamodbattribute.MarshalMap(home)
+	homeItem, err := dynamodbattribute.MarshalMap(home)
 	if err != nil {
-		return err
-	}
-
-	input := &dynamodb.PutItemInput{
-		Item:      item,
-		TableName: aws.String(directoryTable),
-	}
-	_, err = repo.svc.PutItem(input)
-	if err != nil {
-		return err
+		return nil, err
 	}
 
 	mygames := directory{
@@ -1085,22 +1178,19 @@ func (repo *dynamoRepository) createDefaultDirectories(user *User) error {
 		CreatedAt:   user.CreatedAt,
 		UpdatedAt:   user.UpdatedAt,
 	}
-	item, err = dynamodbattribute.MarshalMap(mygames)
+	mygamesItem, err := dynamodbattribute.MarshalMap(mygames)
 	if err != nil {
-		return err
-	}
-	item["items"] = &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue)}
-	item["itemIds"] = &dynamodb.AttributeValue{L: make([]*dynamodb.AttributeValue, 0)}
-	input = &dynamodb.PutItemInput{
-		Item:      item,
-		TableName: aws.String(directoryTable),
+		return nil, err
 	}
-	_, err = repo.svc.PutItem(input)
-	return err
+	mygamesItem["items"] = &dynamodb.AttributeValue{M: make(map[string]*dynamodb.AttributeValue)}
+	mygamesItem["itemIds"] = &dynamodb.AttributeValue{L: make([]*dynamodb.AttributeValue, 0)}
+
+	return []map[string]*dynamodb.AttributeValue{homeItem, mygamesItem}, nil
 }
 
 // UpdateUser applies the specified update to the user with the provided username.
-func (repo *dynamoRepository) UpdateUser(username string, update *UserUpdate) (*User, error) {
+// The outbox entries are saved in the same transaction.
+func (repo *dynamoRepository) UpdateUser(username string, update *UserUpdate, outbox ...*OutboxEntry) (*User, error) {
 	if username == "STATISTICS" {
 		return nil, errors.New(403, "Invalid request: cannot update username `STATISTICS`", "")
 	}
@@ -1119,8 +1209,14 @@ func (repo *dynamoRepository) UpdateUser(username string, update *UserUpdate) (*
 	for k, v := range av.M {
 		builder = builder.Set(expression.Name(k), expression.Value(v))
 	}
+	builder = builder.Add(expression.Name("version"), expression.Value(1))
 
-	expr, err := expression.NewBuilder().WithUpdate(builder).Build()
+	condition := expression.AttributeExists(expression.Name("username"))
+	if update.ExpectedVersion != nil {
+		condition = condition.And(userVersionCondition(*update.ExpectedVersion))
+	}
+
+	expr, err := expression.NewBuilder().WithUpdate(builder).WithCondition(condition).Build()
 	if err != nil {
 		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB expression building error", err)
 	}
@@ -1131,21 +1227,21 @@ func (repo *dynamoRepository) UpdateUser(username string, update *UserUpdate) (*
 				S: aws.String(username),
 			},
 		},
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		UpdateExpression:          expr.Update(),
-		ConditionExpression:       aws.String("attribute_exists(username)"),
-		TableName:                 aws.String(userTable),
-		ReturnValues:              aws.String("ALL_NEW"),
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		UpdateExpression:                    expr.Update(),
+		ConditionExpression:                 expr.Condition(),
+		TableName:                           aws.String(userTable),
+		ReturnValues:                        aws.String("ALL_NEW"),
+		ReturnValuesOnConditionCheckFailure: aws.String(dynamodb.ReturnValuesOnConditionCheckFailureAllOld),
 	}
-	result, err := repo.svc.UpdateItem(input)
-	if err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
-	}
-
 	user := User{}
-	if err := dynamodbattribute.UnmarshalMap(result.Attributes, &user); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal UpdateItem result", err)
+	if err := repo.updateItemWithOutbox(input, outbox, &user); err != nil {
+		if aerr, ok := err.(*dynamodb.ConditionalCheckFailedException); ok && len(aerr.Item) > 0 {
+			return nil, errors.NewConflict("Invalid request: your profile was changed by another request. Refresh and try again.",
+				fmt.Sprintf("User version is not %d", *update.ExpectedVersion), username)
+		}
+		return nil, errors.Wrap(500, "Temporary server error", "DynamoDB UpdateItem failure", err)
 	}
 	return &user, nil
 }
@@ -1168,15 +1264,17 @@ func (repo *dynamoRepository) UpdateUserProgress(username string, progressEntry
 				S: aws.String(username),
 			},
 		},
-		UpdateExpression: aws.String("SET #p.#id = :p, #u = :u"),
+		UpdateExpression: aws.String("SET #p.#id = :p, #u = :u ADD #v :one"),
 		ExpressionAttributeNames: map[string]*string{
 			"#p":  aws.String("progress"),
 			"#id": aws.String(progressEntry.RequirementId),
 			"#u":  aws.String("updatedAt"),
+			"#v":  aws.String("version"),
 		},
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":p": pav,
-			":u": {S: aws.String(updatedAt)},
+			":p":   pav,
+			":u":   {S: aws.String(updatedAt)},
+			":one": {N: aws.String("1")},
 		},
 		ConditionExpression: aws.String("attribute_exists(username)"),
 		ReturnValues:        aws.String("ALL_NEW"),
@@ -1297,7 +1395,7 @@ func (repo *dynamoRepository) ScanUsers(startKey string) ([]*User, string, error
 	return users, lastKey, nil
 }
 
-const ratingsProjection = "username, dojoCohort, subscriptionStatus, paymentInfo, wixEmail, updatedAt, progress, minutesSpent, ratingSystem, ratings, ratingHistories, lichessBan"
+const ratingsProjection = "username, dojoCohort, subscriptionStatus, paymentInfo, wixEmail, updatedAt, version, progress, minutesSpent, ratingSystem, ratings, ratingHistories, lichessBan"
 
 // ListUserRatings returns a list of Users matching the provided cohort, up to 1MB of data.
 // Only the fields necessary for the rating/statistics update are returned.
@@ -1325,6 +1423,10 @@ func (repo *dynamoRepository) ListUserRatings(cohort DojoCohort, startKey string
 	return users, lastKey, nil
 }
 
+// UpdateUserRatings sets the ratings, legacy rating histories and Lichess ban status of the provided
+// users. Each user is only updated if its version has not changed since it was read. If some users have
+// changed, the returned error's cause is an *errors.ConflictError containing their usernames, and
+// the update can be retried with RetryUserConflicts.
 func (repo *dynamoRepository) UpdateUserRatings(users []*User) error {
 	if len(users) > 25 {
 		return errors.New(500, "Temporary server error", "UpdateUserRatings has max limit of 25 users")
@@ -1332,28 +1434,21 @@ func (repo *dynamoRepository) UpdateUserRatings(users []*User) error {
 
 	statements := make([]*dynamodb.BatchStatementRequest, 0, len(users))
 	for _, user := range users {
-		params, err := dynamodbattribute.MarshalList([]any{user.Ratings, user.RatingHistories, user.LichessBan, user.Username})
+		params, err := dynamodbattribute.MarshalList([]any{user.Ratings, user.RatingHistories, user.LichessBan, user.Version + 1, user.Username, user.Version})
 		if err != nil {
 			return errors.Wrap(500, "Temporary server error", "Failed to marshal user.Ratings", err)
 		}
 
 		statement := &dynamodb.BatchStatementRequest{
 			Statement: aws.String(fmt.Sprintf(
-				"UPDATE \"%s\" SET ratings=? SET ratingHistories=? SET lichessBan=? WHERE username=?", userTable,
+				"UPDATE \"%s\" SET ratings=? SET ratingHistories=? SET lichessBan=? SET version=? WHERE username=? AND %s", userTable, userVersionStatementCondition(user),
 			)),
 			Parameters: params,
 		}
 		statements = append(statements, statement)
 	}
 
-	input := &dynamodb.BatchExecuteStatementInput{
-		Statements: statements,
-	}
-	log.Debugf("Batch execute statement input: %v", input)
-	output, err := repo.svc.BatchExecuteStatement(input)
-	log.Debugf("Batch execute statement output: %v", output)
-
-	return errors.Wrap(500, "Temporary server error", "Failed BatchExecuteStatement", err)
+	return userVersionConflicts(repo.batchExecute(statements), statements, users)
 }
 
 const (
@@ -1375,7 +1470,10 @@ const (
 	AllCohortsNonDojo     = "ALL_COHORTS_NON_DOJO"
 )
 
-// UpdateUserTimes uses DynamoDB PartiQL to update the minutesSpent field on the provided users
+// UpdateUserTimes uses DynamoDB PartiQL to update the minutesSpent field on the provided users.
+// Each user is only updated if its version has not changed since it was read. If some users have
+// changed, the returned error's cause is an *errors.ConflictError containing their usernames, and
+// the update can be retried with RetryUserConflicts.
 func (repo *dynamoRepository) UpdateUserTimes(users []*User) error {
 	if len(users) > 25 {
 		return errors.New(500, "Temporary server error", "UpdateUserTimes has max limit of 25 users")
@@ -1384,14 +1482,14 @@ func (repo *dynamoRepository) UpdateUserTimes(users []*User) error {
 	var sb strings.Builder
 	statements := make([]*dynamodb.BatchStatementRequest, 0, len(users))
 	for _, user := range users {
-		params, err := dynamodbattribute.MarshalList([]interface{}{user.TotalDojoScore, user.MinutesSpent})
+		params, err := dynamodbattribute.MarshalList([]interface{}{user.TotalDojoScore, user.MinutesSpent, user.Version + 1, user.Username, user.Version})
 		if err != nil {
 			return errors.Wrap(500, "Temporary server error", "Failed to marshal user.MinutesSpent", err)
 		}
 
 		sb.WriteString(fmt.Sprintf("UPDATE \"%s\"", userTable))
-		sb.WriteString(" SET totalDojoScore=? SET minutesSpent=?")
-		sb.WriteString(fmt.Sprintf(" WHERE username='%s'", user.Username))
+		sb.WriteString(" SET totalDojoScore=? SET minutesSpent=? SET version=?")
+		sb.WriteString(" WHERE username=? AND " + userVersionStatementCondition(user))
 
 		statement := &dynamodb.BatchStatementRequest{
 			Statement:  aws.String(sb.String()),
@@ -1402,14 +1500,87 @@ func (repo *dynamoRepository) UpdateUserTimes(users []*User) error {
 		sb.Reset()
 	}
 
-	input := &dynamodb.BatchExecuteStatementInput{
-		Statements: statements,
+	return userVersionConflicts(repo.batchExecute(statements), statements, users)
+}
+
+// userVersionCondition returns a condition which checks that a user's version is the provided
+// version. Users created before versions were added are treated as version 0.
+func userVersionCondition(version int) expression.ConditionBuilder {
+	condition := expression.Name("version").Equal(expression.Value(version))
+	if version == 0 {
+		condition = condition.Or(expression.AttributeNotExists(expression.Name("version")))
+	}
+	return condition
+}
+
+// userVersionStatementCondition returns the PartiQL equivalent of userVersionCondition for the
+// provided user. The version is passed as the statement's last parameter.
+func userVersionStatementCondition(user *User) string {
+	if user.Version == 0 {
+		return "(version=? OR version IS MISSING)"
+	}
+	return "version=?"
+}
+
+// userVersionConflicts converts the provided error, returned by batchExecute for statements which
+// update the corresponding users, into a 409 error if all of the failed statements failed because
+// the user's version had changed. Otherwise, err is returned unchanged.
+func userVersionConflicts(err error, statements []*dynamodb.BatchStatementRequest, users []*User) error {
+	var failed *FailedStatementsError
+	if !errors.As(err, &failed) || failed.Err != nil {
+		return err
+	}
+
+	usernames := make(map[*dynamodb.BatchStatementRequest]string, len(statements))
+	for i, statement := range statements {
+		usernames[statement] = users[i].Username
 	}
-	log.Debugf("Batch execute statement input: %v", input)
-	output, err := repo.svc.BatchExecuteStatement(input)
-	log.Debugf("Batch execute statement output: %v", output)
 
-	return errors.Wrap(500, "Temporary server error", "Failed BatchExecuteStatement", err)
+	conflicts := make([]string, 0, len(failed.Statements))
+	for i, statement := range failed.Statements {
+		if aws.StringValue(failed.Errors[i].Code) != dynamodb.BatchStatementErrorCodeEnumConditionalCheckFailed {
+			return err
+		}
+		conflicts = append(conflicts, usernames[statement])
+	}
+	return errors.NewConflict("Temporary server error", "Users were modified concurrently", conflicts...)
+}
+
+// userConflictRetries is the number of times RetryUserConflicts retries users which were modified
+// concurrently.
+const userConflictRetries = 3
+
+// RetryUserConflicts calls update with the provided users, which must have been read from the database.
+// If update fails with a 409 error because some of the users were modified after they were read, those
+// users are read again and merge is called with each of them. merge should reapply the caller's changes
+// to the latest version of the user and return false if the user no longer needs to be updated. update is
+// then called again with the merged users, up to userConflictRetries times. Users which were deleted are
+// skipped.
+func RetryUserConflicts(repo UserBatchGetter, users []*User, update func(users []*User) error, merge func(latest *User) bool) error {
+	for retry := 0; ; retry++ {
+		err := update(users)
+		conflict, ok := errors.AsConflict(err)
+		if !ok || retry == userConflictRetries {
+			return err
+		}
+
+		log.Debugf("Retrying %d users modified concurrently: %v", len(conflict.Ids), conflict.Ids)
+		latest, err := repo.BatchGetUsers(conflict.Ids)
+		if err != nil {
+			return err
+		}
+
+		var merged []*User
+		for _, user := range latest {
+			if merge(user) {
+				merged = append(merged, user)
+			}
+		}
+		if len(merged) == 0 {
+			return nil
+		}
+		users = merged
+	}
 }
 
 func (repo *dynamoRepository) UpdateUserSubscriptionStatuses(users []*User) error {
@@ -1438,14 +1609,7 @@ func (repo *dynamoRepository) UpdateUserSubscriptionStatuses(users []*User) erro
 		sb.Reset()
 	}
 
-	input := &dynamodb.BatchExecuteStatementInput{
-		Statements: statements,
-	}
-	log.Debugf("Batch execute statement input: %v", input)
-	output, err := repo.svc.BatchExecuteStatement(input)
-	log.Debugf("Batch execute statement output: %v", output)
-
-	return errors.Wrap(500, "Temporary server error", "Failed BatchExecuteStatement", err)
+	return repo.batchExecute(statements)
 }
 
 // RecordGameCreation updates the given user to increase their game creation stats.
@@ -1535,34 +1699,24 @@ func (repo *dynamoRepository) BatchGetUsersProjection(usernames []string, projec
 		return nil, errors.New(500, "Temporary server error", "More than 100 items in BatchGetUsers request")
 	}
 
-	input := &dynamodb.BatchGetItemInput{
-		RequestItems: map[string]*dynamodb.KeysAndAttributes{
-			userTable: {
-				Keys: []map[string]*dynamodb.AttributeValue{},
-			},
-		},
+	request := &dynamodb.KeysAndAttributes{
+		Keys: []map[string]*dynamodb.AttributeValue{},
 	}
 
 	if projectionExpression != "" {
-		input.RequestItems[userTable].ProjectionExpression = &projectionExpression
+		request.ProjectionExpression = &projectionExpression
 	}
 
 	for _, u := range usernames {
 		key := map[string]*dynamodb.AttributeValue{
 			"username": {S: aws.String(u)},
 		}
-		input.RequestItems[userTable].Keys = append(input.RequestItems[userTable].Keys, key)
+		request.Keys = append(request.Keys, key)
 	}
 
-	result, err := repo.svc.BatchGetItem(input)
-	if err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed call to BatchGetItem", err)
-	}
-	list := result.Responses[userTable]
-
 	var resultEntries []*User
-	if err := dynamodbattribute.UnmarshalListOfMaps(list, &resultEntries); err != nil {
-		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal BatchGetItem result", err)
+	if err := repo.batchGet(userTable, request, &resultEntries); err != nil {
+		return nil, err
 	}
 	return resultEntries, nil
 }
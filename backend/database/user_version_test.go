@@ -0,0 +1,151 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+func TestMemoryUpdateUserVersion(t *testing.T) {
+	repo := NewMemoryRepository()
+	user, err := repo.CreateUser("alice", "alice@example.com", "Alice", nil)
+	if err != nil {
+		t.Fatalf("CreateUser got err %v", err)
+	}
+
+	updated, err := repo.UpdateUser("alice", &UserUpdate{Bio: aws.String("first"), ExpectedVersion: aws.Int(user.Version)})
+	if err != nil {
+		t.Fatalf("UpdateUser with current version got err %v", err)
+	}
+	if updated.Version != user.Version+1 {
+		t.Errorf("UpdateUser got version %d, want %d", updated.Version, user.Version+1)
+	}
+
+	_, err = repo.UpdateUser("alice", &UserUpdate{Bio: aws.String("stale"), ExpectedVersion: aws.Int(user.Version)})
+	conflict, ok := errors.AsConflict(err)
+	if !ok || errorCode(err) != 409 {
+		t.Fatalf("UpdateUser with stale version got err %v, want 409 conflict", err)
+	}
+	if diff := cmp.Diff([]string{"alice"}, conflict.Ids); diff != "" {
+		t.Errorf("UpdateUser conflict ids mismatch (-want +got):\n%s", diff)
+	}
+
+	// Updates without an expected version are not checked.
+	if _, err := repo.UpdateUser("alice", &UserUpdate{Bio: aws.String("unchecked")}); err != nil {
+		t.Errorf("UpdateUser without version got err %v", err)
+	}
+}
+
+func TestMemoryUpdateUserRatingsConflict(t *testing.T) {
+	repo := NewMemoryRepository()
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := repo.CreateUser(username, username+"@example.com", username, nil); err != nil {
+			t.Fatalf("CreateUser got err %v", err)
+		}
+	}
+
+	users, err := repo.BatchGetUsers([]string{"alice", "bob"})
+	if err != nil {
+		t.Fatalf("BatchGetUsers got err %v", err)
+	}
+	if _, err := repo.UpdateUser("bob", &UserUpdate{Bio: aws.String("changed")}); err != nil {
+		t.Fatalf("UpdateUser got err %v", err)
+	}
+
+	for _, user := range users {
+		user.LichessBan = user.Username
+	}
+	err = repo.UpdateUserRatings(users)
+	conflict, ok := errors.AsConflict(err)
+	if !ok {
+		t.Fatalf("UpdateUserRatings got err %v, want conflict", err)
+	}
+	if diff := cmp.Diff([]string{"bob"}, conflict.Ids); diff != "" {
+		t.Errorf("UpdateUserRatings conflict ids mismatch (-want +got):\n%s", diff)
+	}
+
+	alice, _ := repo.GetUser("alice")
+	bob, _ := repo.GetUser("bob")
+	if alice.LichessBan != "alice" {
+		t.Errorf("UpdateUserRatings did not update non-conflicting user")
+	}
+	if bob.LichessBan != "" || bob.Bio != "changed" {
+		t.Errorf("UpdateUserRatings overwrote conflicting user: %+v", bob)
+	}
+}
+
+func TestRetryUserConflicts(t *testing.T) {
+	repo := NewMemoryRepository()
+	if _, err := repo.CreateUser("alice", "alice@example.com", "Alice", nil); err != nil {
+		t.Fatalf("CreateUser got err %v", err)
+	}
+
+	users, err := repo.BatchGetUsers([]string{"alice"})
+	if err != nil {
+		t.Fatalf("BatchGetUsers got err %v", err)
+	}
+	if _, err := repo.UpdateUser("alice", &UserUpdate{Bio: aws.String("changed")}); err != nil {
+		t.Fatalf("UpdateUser got err %v", err)
+	}
+
+	users[0].TotalDojoScore = 10
+	var merged []string
+	merge := func(latest *User) bool {
+		merged = append(merged, latest.Username)
+		latest.TotalDojoScore = 10
+		return true
+	}
+	if err := RetryUserConflicts(repo, users, repo.UpdateUserTimes, merge); err != nil {
+		t.Fatalf("RetryUserConflicts got err %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"alice"}, merged); diff != "" {
+		t.Errorf("RetryUserConflicts merged users mismatch (-want +got):\n%s", diff)
+	}
+	alice, _ := repo.GetUser("alice")
+	if alice.TotalDojoScore != 10 || alice.Bio != "changed" {
+		t.Errorf("RetryUserConflicts got user %+v, want merged score and bio", alice)
+	}
+}
+
+func TestUserVersionConflicts(t *testing.T) {
+	users := []*User{{Username: "alice"}, {Username: "bob"}}
+	statements := []*dynamodb.BatchStatementRequest{{Statement: aws.String("alice")}, {Statement: aws.String("bob")}}
+	conditionFailed := &dynamodb.BatchStatementError{Code: aws.String(dynamodb.BatchStatementErrorCodeEnumConditionalCheckFailed)}
+	internalError := &dynamodb.BatchStatementError{Code: aws.String(dynamodb.BatchStatementErrorCodeEnumInternalServerError)}
+
+	t.Run("ConditionFailed", func(t *testing.T) {
+		err := userVersionConflicts(&FailedStatementsError{
+			Statements: statements[1:],
+			Errors:     []*dynamodb.BatchStatementError{conditionFailed},
+		}, statements, users)
+
+		conflict, ok := errors.AsConflict(err)
+		if !ok {
+			t.Fatalf("userVersionConflicts got err %v, want conflict", err)
+		}
+		if diff := cmp.Diff([]string{"bob"}, conflict.Ids); diff != "" {
+			t.Errorf("userVersionConflicts ids mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("OtherError", func(t *testing.T) {
+		failed := &FailedStatementsError{
+			Statements: statements,
+			Errors:     []*dynamodb.BatchStatementError{conditionFailed, internalError},
+		}
+		err := userVersionConflicts(failed, statements, users)
+		if _, ok := errors.AsConflict(err); ok || err != failed {
+			t.Errorf("userVersionConflicts got err %v, want %v", err, failed)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		if err := userVersionConflicts(nil, statements, users); err != nil {
+			t.Errorf("userVersionConflicts got err %v, want nil", err)
+		}
+	})
+}
@@ -77,6 +77,24 @@ type YearReview struct {
 	Total YearReviewData `dynamodbav:"total" json:"total"`
 }
 
+type YearReviewGetter interface {
+	// GetYearReview returns the year review of the provided user and year.
+	GetYearReview(username, year string) (*YearReview, error)
+}
+
+type YearReviewCalculator interface {
+	AdminUserLister
+	RequirementScanner
+	TimelineLister
+	GraduationLister
+	GameLister
+	RatingHistoryLister
+
+	// PutYearReviews inserts the provided year reviews into the database. The number of
+	// successfully inserted reviews is returned.
+	PutYearReviews(reviews []*YearReview) (int, error)
+}
+
 func (repo *dynamoRepository) PutYearReviews(reviews []*YearReview) (int, error) {
 	return batchWriteObjects(repo, reviews, yearReviewTable)
 }
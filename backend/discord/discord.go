@@ -12,7 +12,7 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.UserGetter = database.DynamoDB
+var repository = database.Repository[database.UserGetter](database.DynamoDB)
 
 var frontendHost = os.Getenv("frontendHost")
 var authToken = os.Getenv("discordAuth")
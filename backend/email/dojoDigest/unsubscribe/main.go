@@ -27,13 +27,11 @@ type UnsubscribeRequest struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	request := UnsubscribeRequest{}
 
 	if _, ok := event.QueryStringParameters["email"]; ok {
@@ -62,7 +60,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	}
 
 	if err := os.Remove("/tmp/serviceAccountKey.json"); err != nil {
-		log.Errorf("Failed to rmeove JSON file: %v", err)
+		logger.Errorf("Failed to rmeove JSON file: %v", err)
 	}
 
 	return api.Success(nil), nil
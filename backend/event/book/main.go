@@ -17,7 +17,7 @@ import (
 	"github.com/stripe/stripe-go/v81"
 )
 
-var repository database.EventBooker = database.DynamoDB
+var repository = database.Repository[database.EventBooker](database.DynamoDB)
 
 type BookEventRequest struct {
 	StartTime string                    `json:"startTime"`
@@ -72,9 +72,7 @@ func checkCohort(event *database.Event, cohort database.DojoCohort) error {
 
 // Handler implements the BookAvailability endpoint.
 func Handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(request)
 	if info.Username == "" {
 		err := errors.New(400, "Invalid request: username is required", "")
@@ -139,25 +137,22 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 		}
 	}
 
-	newEvent, err := repository.BookEvent(originalEvent, user, body.StartTime, body.Type, checkoutSession)
+	newEvent, err := repository.BookEvent(originalEvent, user, body.StartTime, body.Type, checkoutSession,
+		database.NewEventBookedEvent(originalEvent))
 	if err != nil {
 		return api.Failure(err), nil
 	}
 
 	if err := repository.RecordEventBooking(newEvent); err != nil {
-		log.Error("Failed RecordEventBooking: ", err)
-	}
-
-	if err := database.SendEventBookedNotification(newEvent); err != nil {
-		log.Error("Failed SendBookingNotification: ", err)
+		logger.Errorf("Failed RecordEventBooking: %v", err)
 	}
 
 	if newEvent.Status == database.SchedulingStatus_Booked {
 		if err := discord.DeleteEventNotification(originalEvent); err != nil {
-			log.Error("Failed to delete Discord message: ", err)
+			logger.Errorf("Failed to delete Discord message: %v", err)
 		}
 	} else if _, err := discord.SendEventNotification(newEvent); err != nil {
-		log.Error("Failed SendEventNotification: ", err)
+		logger.Errorf("Failed SendEventNotification: %v", err)
 	}
 
 	var checkoutUrl string
@@ -169,5 +164,5 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Handle(Handler, api.Idempotent(database.Repository[database.IdempotencyStore](database.DynamoDB))))
 }
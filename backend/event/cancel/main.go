@@ -16,7 +16,7 @@ import (
 	payment "github.com/jackstenglein/chess-dojo-scheduler/backend/paymentService"
 )
 
-var repository database.EventLeaver = database.DynamoDB
+var repository = database.Repository[database.EventLeaver](database.DynamoDB)
 var frontendHost = os.Getenv("frontendHost")
 
 const newOwnerPrefix = "Hello, the owner of your upcoming meeting has canceled, and you have been made the new owner of the meeting. "
@@ -25,13 +25,11 @@ const sameOwnerPrefix = "Hello, a member of your upcoming meeting has canceled.
 const withParticipantsSuffix = "There are still %d participants in the meeting. View it [here](%s)."
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(request)
 	if info.Username == "" {
 		err := errors.New(403, "Invalid request: not authenticated", "Username from Cognito token was empty")
@@ -53,12 +51,12 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 
 	if event.Type == database.EventType_Coaching {
 		if info.Username == event.Owner {
-			newEvent, err = cancelCoachingSession(event)
+			newEvent, err = cancelCoachingSession(ctx, event)
 		} else {
-			newEvent, err = leaveCoachingSession(info.Username, event)
+			newEvent, err = leaveCoachingSession(ctx, info.Username, event)
 		}
 	} else if event.Type == database.EventType_Availability {
-		newEvent, err = leaveAvailability(info.Username, event)
+		newEvent, err = leaveAvailability(ctx, info.Username, event)
 	} else {
 		err = errors.New(400, "Invalid request: this event type is not supported", "")
 	}
@@ -68,14 +66,14 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 	}
 
 	if msgId, err := discord.SendEventNotification(newEvent); err != nil {
-		log.Error("Failed SendEventNotification: ", err)
+		logger.Errorf("Failed SendEventNotification: %v", err)
 	} else if newEvent.DiscordMessageId != msgId {
 		// We have to save the event a second time in order to avoid first
 		// sending the Discord notification and then failing to save the event.
 		// If this save fails, we just log the error and return success since it is non-critical.
 		newEvent.DiscordMessageId = msgId
 		if err := repository.SetEvent(newEvent); err != nil {
-			log.Error("Failed to set event.DiscordMessageId: ", err)
+			logger.Errorf("Failed to set event.DiscordMessageId: %v", err)
 		}
 	}
 
@@ -83,7 +81,7 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 }
 
 // Leaves a regular availability.
-func leaveAvailability(username string, event *database.Event) (*database.Event, error) {
+func leaveAvailability(ctx context.Context, username string, event *database.Event) (*database.Event, error) {
 	if len(event.Participants) == 0 {
 		err := errors.New(400, "Invalid request: nobody has booked this availability. Delete it instead", "")
 		return nil, err
@@ -99,13 +97,14 @@ func leaveAvailability(username string, event *database.Event) (*database.Event,
 	if err != nil {
 		return nil, err
 	}
-	sendNotification(event, newEvent)
+	sendNotification(ctx, event, newEvent)
 	return newEvent, nil
 }
 
 // Handles a coach canceling a session that has been booked. Any users who have paid are issued
 // a full refund.
-func cancelCoachingSession(event *database.Event) (*database.Event, error) {
+func cancelCoachingSession(ctx context.Context, event *database.Event) (*database.Event, error) {
+	logger := log.FromContext(ctx)
 	newEvent, err := repository.CancelEvent(event)
 	if err != nil {
 		return nil, err
@@ -114,7 +113,7 @@ func cancelCoachingSession(event *database.Event) (*database.Event, error) {
 	for _, p := range newEvent.Participants {
 		_, err := payment.CreateEventRefund(event, p, 100)
 		if err != nil {
-			log.Errorf("Failed to create refund: %v", err)
+			logger.Errorf("Failed to create refund: %v", err)
 		}
 	}
 
@@ -123,7 +122,7 @@ func cancelCoachingSession(event *database.Event) (*database.Event, error) {
 
 // Handles a user leaving a coaching session that they have booked. The user may need a refund
 // depending on whether they have already paid and how far in advance they are canceling.
-func leaveCoachingSession(username string, event *database.Event) (*database.Event, error) {
+func leaveCoachingSession(ctx context.Context, username string, event *database.Event) (*database.Event, error) {
 	participant := event.Participants[username]
 	if participant == nil {
 		err := errors.New(403, "Invalid request: user is not a participant in this meeting", "")
@@ -154,11 +153,12 @@ func leaveCoachingSession(username string, event *database.Event) (*database.Eve
 		return nil, err
 	}
 
-	sendNotification(event, newEvent)
+	sendNotification(ctx, event, newEvent)
 	return newEvent, nil
 }
 
-func sendNotification(event, newEvent *database.Event) {
+func sendNotification(ctx context.Context, event, newEvent *database.Event) {
+	logger := log.FromContext(ctx)
 	var msg string
 
 	if newEvent.Owner != event.Owner {
@@ -176,6 +176,6 @@ func sendNotification(event, newEvent *database.Event) {
 	}
 
 	if err := discord.SendCancellationNotification(newEvent.Owner, msg); err != nil {
-		log.Error("Failed SendCancellationNotification: ", err)
+		logger.Errorf("Failed SendCancellationNotification: %v", err)
 	}
 }
@@ -6,24 +6,20 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.EventGetter = database.DynamoDB
+var repository = database.Repository[database.EventGetter](database.DynamoDB)
 
 type EventCheckoutResponse struct {
 	Url string `json:"url"`
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Handle(handler, api.Idempotent(database.Repository[database.IdempotencyStore](database.DynamoDB))))
 }
 
 func handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
 	info := api.GetUserInfo(request)
 	if info.Username == "" {
 		err := errors.New(403, "Invalid request: not authenticated", "")
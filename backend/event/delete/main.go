@@ -12,12 +12,10 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/discord"
 )
 
-var repository database.EventDeleter = database.DynamoDB
+var repository = database.Repository[database.EventDeleter](database.DynamoDB)
 
 func Handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(request)
 	if info.Username == "" {
 		err := errors.New(403, "Invalid request: not authenticated", "Username from Cognito token was empty")
@@ -40,7 +38,7 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 		if err != nil {
 			return api.Failure(err), nil
 		}
-		if !user.IsAdmin && !user.IsCalendarAdmin {
+		if !user.HasPermission(database.Permission_ManageCalendar) {
 			err := errors.New(403, "You do not have permission to delete dojo events", "")
 			return api.Failure(err), nil
 		}
@@ -59,21 +57,25 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
+	if event.Type == database.EventType_Dojo || event.Type == database.EventType_LigaTournament {
+		api.Audit(repository, request, database.AuditAction_DeleteEvent, database.EventAuditTarget(id), event, nil)
+	}
+
 	if err = repository.RecordEventDeletion(event); err != nil {
-		log.Error("Failed RecordEventDeletion: ", err)
+		logger.Errorf("Failed RecordEventDeletion: %v", err)
 	}
 
 	if err = discord.DeleteEventNotification(event); err != nil {
-		log.Error("Failed discord.DeleteMessage: ", err)
+		logger.Errorf("Failed discord.DeleteMessage: %v", err)
 	}
 
 	if err = discord.DeleteEvents(event.PrivateDiscordEventId, event.PublicDiscordEventId); err != nil {
-		log.Error("Failed discord.DeleteEvents: ", err)
+		logger.Errorf("Failed discord.DeleteEvents: %v", err)
 	}
 
 	return api.Success(nil), nil
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
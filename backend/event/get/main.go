@@ -7,20 +7,22 @@ import (
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+type EventGetter interface {
+	database.UserGetter
+	database.EventGetter
+	database.GameReviewCohortGetter
+}
+
+var repository = database.Repository[EventGetter](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
 	info := api.GetUserInfo(request)
 	if info.Username == "" {
 		err := errors.New(403, "Invalid request: not authenticated", "Username from Cognito token was empty")
@@ -97,7 +99,7 @@ func getGameReviewEvent(userInfo *api.UserInfo, event *database.Event) api.Respo
 	if err != nil {
 		return api.Failure(err)
 	}
-	if user.IsAdmin {
+	if user.HasPermission(database.Permission_ViewAllEvents) {
 		return api.Success(&event)
 	}
 
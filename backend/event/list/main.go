@@ -12,7 +12,12 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+type EventLister interface {
+	database.UserGetter
+	database.EventLister
+}
+
+var repository = database.Repository[EventLister](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 type ListEventsResponse struct {
@@ -21,23 +26,18 @@ type ListEventsResponse struct {
 }
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(request)
 	var user *database.User
 	var err error
 	if info.Username != "" {
 		user, err = repository.GetUser(info.Username)
 		if err != nil {
-			log.Errorf("Failed to get user: %v", err)
+			logger.Errorf("Failed to get user: %v", err)
 		}
 	}
 
@@ -67,7 +67,7 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 
 // Returns true if the event should be removed from the list for the given user.
 func shouldRemoveEvent(event *database.Event, user *database.User) bool {
-	if user.GetIsCalendarAdmin() {
+	if user.HasPermission(database.Permission_ViewAllEvents) {
 		return false
 	}
 
@@ -157,7 +157,7 @@ func shouldRemoveGameReview(event *database.Event, user *database.User) bool {
 
 // Returns true if the event details (location, messages, etc) should be hidden.
 func shouldHideEventDetails(event *database.Event, user *database.User) bool {
-	if user.GetIsCalendarAdmin() {
+	if user.HasPermission(database.Permission_ViewAllEvents) {
 		return false
 	}
 
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -16,45 +15,49 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/discord"
 )
 
-var repository database.EventSetter = database.DynamoDB
+var repository = database.Repository[database.EventSetter](database.DynamoDB)
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Handle(Handler,
+		requireUsername,
+		api.RequireUser(repository),
+		api.DecodeJSON[database.Event](),
+	))
 }
 
-func Handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
-	info := api.GetUserInfo(request)
-	if info.Username == "" {
-		err := errors.New(403, "Invalid request: username is required", "")
-		return api.Failure(err), nil
+// requireUsername returns a 403 error for requests without a username, which this endpoint
+// has always returned, instead of the 400 returned by api.RequireUser.
+func requireUsername(next api.Handler) api.Handler {
+	return func(ctx context.Context, request api.Request) (api.Response, error) {
+		if api.GetUserInfo(request).Username == "" {
+			return api.Response{}, errors.New(403, "Invalid request: username is required", "")
+		}
+		return next(ctx, request)
 	}
+}
 
-	event := &database.Event{}
-	if err := json.Unmarshal([]byte(request.Body), event); err != nil {
-		err := errors.Wrap(400, "Invalid request: body format is invalid", "Unable to unmarshal body", err)
-		return api.Failure(err), nil
-	}
+func Handler(ctx context.Context, request api.Request) (api.Response, error) {
+	user := api.UserFromContext(ctx)
+	event := api.Body[database.Event](ctx)
 
 	switch event.Type {
 	case database.EventType_Availability:
-		return handleAvailability(info, event), nil
+		return handleAvailability(ctx, user, event), nil
 	case database.EventType_Dojo:
-		return handleDojoEvent(info, event), nil
+		return handleDojoEvent(ctx, user, event), nil
 	case database.EventType_Coaching:
-		return handleCoachingEvent(info, event), nil
+		return handleCoachingEvent(ctx, user, event), nil
 	case database.EventType_LectureTier, database.EventType_GameReviewTier:
-		return handleLiveClass(info, event), nil
+		return handleLiveClass(user, event), nil
 	}
 
 	err := errors.New(400, fmt.Sprintf("Invalid request: event type `%s` is not supported", event.Type), "")
 	return api.Failure(err), nil
 }
 
-func handleAvailability(info *api.UserInfo, event *database.Event) api.Response {
-	if event.Owner != info.Username {
+func handleAvailability(ctx context.Context, user *database.User, event *database.Event) api.Response {
+	logger := log.FromContext(ctx)
+	if event.Owner != user.Username {
 		err := errors.New(403, "Invalid request: username does not match availability owner", "")
 		return api.Failure(err)
 	}
@@ -98,7 +101,7 @@ func handleAvailability(info *api.UserInfo, event *database.Event) api.Response
 	if event.Id == "" {
 		event.Id = uuid.New().String()
 		if err := repository.RecordEventCreation(event); err != nil {
-			log.Error("Failed RecordEventCreation: ", err)
+			logger.Errorf("Failed RecordEventCreation: %v", err)
 		}
 	}
 
@@ -106,37 +109,33 @@ func handleAvailability(info *api.UserInfo, event *database.Event) api.Response
 		event.Location = "Discord"
 	}
 
-	if err := repository.SetEvent(event); err != nil {
-		return api.Failure(err)
+	var outbox []*database.OutboxEntry
+	if len(event.Invited) > 0 {
+		outbox = append(outbox, database.NewCalendarInviteEvent(event))
 	}
 
-	if len(event.Invited) > 0 {
-		if err := database.SendCalendarInviteEvent(event); err != nil {
-			log.Error("Failed to send calendar invite notification: ", err)
-		}
+	if err := repository.SetEvent(event, outbox...); err != nil {
+		return api.Failure(err)
 	}
 
 	if msgId, err := discord.SendAvailabilityNotification(event); err != nil {
-		log.Error("Failed SendAvailabilityNotification: ", err)
+		logger.Errorf("Failed SendAvailabilityNotification: %v", err)
 	} else if event.DiscordMessageId != msgId {
 		// We have to save the event a second time in order to avoid first
 		// sending the Discord notification and then failing to save the event.
 		// If this save fails, we just log the error and return success since it is non-critical.
 		event.DiscordMessageId = msgId
 		if err := repository.SetEvent(event); err != nil {
-			log.Error("Failed to set event.DiscordMessageId: ", err)
+			logger.Errorf("Failed to set event.DiscordMessageId: %v", err)
 		}
 	}
 
 	return api.Success(event)
 }
 
-func handleDojoEvent(info *api.UserInfo, event *database.Event) api.Response {
-	user, err := repository.GetUser(info.Username)
-	if err != nil {
-		return api.Failure(err)
-	}
-	if !user.IsAdmin && !user.IsCalendarAdmin {
+func handleDojoEvent(ctx context.Context, user *database.User, event *database.Event) api.Response {
+	logger := log.FromContext(ctx)
+	if !user.HasPermission(database.Permission_ManageCalendar) {
 		err := errors.New(403, "You do not have permission to create Dojo events", "")
 		return api.Failure(err)
 	}
@@ -162,7 +161,7 @@ func handleDojoEvent(info *api.UserInfo, event *database.Event) api.Response {
 	if event.Id == "" {
 		event.Id = uuid.New().String()
 		if err := repository.RecordEventCreation(event); err != nil {
-			log.Error("Failed RecordEventCreation: ", err)
+			logger.Errorf("Failed RecordEventCreation: %v", err)
 		}
 	}
 
@@ -185,26 +184,22 @@ func handleDojoEvent(info *api.UserInfo, event *database.Event) api.Response {
 	}
 
 	if privateEventId, publicEventId, err := discord.SetEvent(event); err != nil {
-		log.Error("Failed SendAvailabilityNotification: ", err)
+		logger.Errorf("Failed SendAvailabilityNotification: %v", err)
 	} else if event.PrivateDiscordEventId != privateEventId || event.PublicDiscordEventId != publicEventId {
 		// We have to save the event a second time in order to avoid first
 		// pushing the Discord event and then failing to save the event in our database.
 		// If this save fails, we just log the error and return success since it is non-critical.
 		event.PrivateDiscordEventId, event.PublicDiscordEventId = privateEventId, publicEventId
 		if err := repository.SetEvent(event); err != nil {
-			log.Error("Failed to set event.DiscordEventIds: ", err)
+			logger.Errorf("Failed to set event.DiscordEventIds: %v", err)
 		}
 	}
 
 	return api.Success(event)
 }
 
-func handleCoachingEvent(info *api.UserInfo, event *database.Event) api.Response {
-	user, err := repository.GetUser(info.Username)
-	if err != nil {
-		return api.Failure(err)
-	}
-
+func handleCoachingEvent(ctx context.Context, user *database.User, event *database.Event) api.Response {
+	logger := log.FromContext(ctx)
 	if !user.IsCoach {
 		err := errors.New(403, "You must be a coach to create Coaching events", "")
 		return api.Failure(err)
@@ -272,26 +267,22 @@ func handleCoachingEvent(info *api.UserInfo, event *database.Event) api.Response
 	}
 
 	if msgId, err := discord.SendCoachingNotification(event); err != nil {
-		log.Error("Failed SendCoachingNotification: ", err)
+		logger.Errorf("Failed SendCoachingNotification: %v", err)
 	} else if event.DiscordMessageId != msgId {
 		// We have to save the event a second time in order to avoid first
 		// sending the Discord notification and then failing to save the event.
 		// If this save fails, we just log the error and return success since it is non-critical.
 		event.DiscordMessageId = msgId
 		if err := repository.SetEvent(event); err != nil {
-			log.Error("Failed to set event.DiscordMessageId: ", err)
+			logger.Errorf("Failed to set event.DiscordMessageId: %v", err)
 		}
 	}
 
 	return api.Success(event)
 }
 
-func handleLiveClass(info *api.UserInfo, event *database.Event) api.Response {
-	user, err := repository.GetUser(info.Username)
-	if err != nil {
-		return api.Failure(err)
-	}
-	if !user.IsAdmin {
+func handleLiveClass(user *database.User, event *database.Event) api.Response {
+	if !user.HasPermission(database.Permission_ManageLiveClasses) {
 		err := errors.New(403, "You do not have permission to create live class events", "")
 		return api.Failure(err)
 	}
@@ -8,11 +8,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ExamLister](database.DynamoDB)
 
 type ListAnswersResponse struct {
 	Answers          []database.ExamAnswer `json:"answers"`
@@ -20,13 +19,10 @@ type ListAnswersResponse struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Debugf("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
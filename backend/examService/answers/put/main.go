@@ -5,16 +5,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ExamAnswerPutter](database.DynamoDB)
 
 type PutExamAttemptRequest struct {
 	ExamType   database.ExamType    `json:"examType"`
@@ -30,13 +31,10 @@ type PutExamAnswerResponse struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Debugf("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
@@ -59,6 +57,14 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if request.Attempt.Rating == 0 {
 		return api.Failure(errors.New(400, "Invalid request: attempt.rating is required", "")), nil
 	}
+	for i, answer := range request.Attempt.Answers {
+		if answer.Pgn == "" {
+			continue
+		}
+		if err := pgn.Validate(answer.Pgn); err != nil {
+			return api.Failure(errors.Wrap(400, fmt.Sprintf("Invalid request: attempt.answers[%d].pgn is invalid: %v", i, err), "", err)), nil
+		}
+	}
 
 	request.Attempt.CreatedAt = time.Now().Format(time.RFC3339)
 
@@ -8,11 +8,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ExamLister](database.DynamoDB)
 
 type ListExamsResponse struct {
 	Exams            []database.Exam `json:"exams"`
@@ -20,13 +19,10 @@ type ListExamsResponse struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Debugf("Event: %#v", event)
-
 	examType := event.QueryStringParameters["type"]
 	if examType == "" {
 		return api.Failure(errors.New(400, "Invalid request: type is required", "")), nil
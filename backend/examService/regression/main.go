@@ -17,7 +17,7 @@ import (
 	"github.com/sajari/regression"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.ExamRatingUpdater](database.DynamoDB)
 
 func main() {
 	lambda.Start(handler)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
@@ -11,20 +12,17 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
 )
 
-var repository database.GameCommenter = database.DynamoDB
+var repository = database.Repository[database.GameCommenter](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	cohort, ok := event.PathParameters["cohort"]
 	if !ok {
 		err := errors.New(400, "Invalid request: cohort is required", "")
@@ -53,15 +51,11 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		existingComments = true
 	}
 
-	game, err := repository.PutComment(cohort, id, &comment, existingComments)
+	game, err := repository.PutComment(cohort, id, &comment, existingComments, database.NewGameCommentEvent(cohort, id, &comment))
 	if err != nil {
 		return api.Failure(err), nil
 	}
 
-	if err := database.SendGameCommentEvent(game, &comment); err != nil {
-		log.Error("Failed to send game comment notification event:", err)
-	}
-
 	if strings.HasPrefix(event.RawPath, "/game/v2/") {
 		response := struct {
 			Game    database.Game            `json:"game"`
@@ -97,6 +91,11 @@ func getComment(event api.Request) (database.PositionComment, error) {
 	if comment.Fen == "" {
 		return comment, errors.New(400, "Invalid request: fen is required", "")
 	}
+	fen, err := database.NormalizeCommentFen(comment.Fen)
+	if err != nil {
+		return comment, errors.Wrap(400, fmt.Sprintf("Invalid request: fen is invalid: %v", err), "", err)
+	}
+	comment.Fen = fen
 
 	if comment.Ply < 0 {
 		return comment, errors.New(400, "Invalid request: ply must be non-negative", "")
@@ -106,6 +105,12 @@ func getComment(event api.Request) (database.PositionComment, error) {
 		return comment, errors.New(400, "Invalid request: one of content and suggestedVariation must be non-empty", "")
 	}
 
+	if comment.SuggestedVariation != "" {
+		if _, err := pgn.ParseMoves(comment.Fen, comment.SuggestedVariation); err != nil {
+			return comment, errors.Wrap(400, fmt.Sprintf("Invalid request: suggestedVariation is invalid: %v", err), "", err)
+		}
+	}
+
 	comment.Id = uuid.NewString()
 	comment.CreatedAt = time.Now().Format(time.RFC3339)
 	comment.UpdatedAt = comment.CreatedAt
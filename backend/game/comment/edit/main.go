@@ -3,24 +3,22 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.PositionCommentEditor](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
@@ -60,6 +58,11 @@ func getUpdate(event api.Request) (database.PositionCommentUpdate, error) {
 	if update.Content == "" && update.SuggestedVariation == "" {
 		return update, errors.New(400, "Invalid request: one of content and suggestedVariation must not be empty", "")
 	}
+	if update.SuggestedVariation != "" {
+		if _, err := pgn.ParseMoves(update.Fen, update.SuggestedVariation); err != nil {
+			return update, errors.Wrap(400, fmt.Sprintf("Invalid request: suggestedVariation is invalid: %v", err), "", err)
+		}
+	}
 
 	return update, nil
 }
@@ -7,16 +7,12 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.GameDeleter = database.DynamoDB
+var repository = database.Repository[database.GameDeleter](database.DynamoDB)
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 
 	cohort, _ := event.PathParameters["cohort"]
@@ -46,5 +42,5 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
@@ -9,24 +9,17 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.GameGetter = database.DynamoDB
+var repository = database.Repository[database.GameGetter](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	cohort, ok := event.PathParameters["cohort"]
 	if !ok {
 		err := errors.New(400, "Invalid request: cohort is required", "")
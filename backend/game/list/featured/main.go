@@ -7,11 +7,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.GameLister = database.DynamoDB
+var repository = database.Repository[database.GameLister](database.DynamoDB)
 
 type ListGamesResponse struct {
 	Games            []*database.Game `json:"games"`
@@ -19,9 +18,6 @@ type ListGamesResponse struct {
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	startKey, _ := event.QueryStringParameters["startKey"]
 	monthAgo := time.Now().Add(database.ONE_MONTH_AGO).Format(time.RFC3339)
 
@@ -37,5 +33,5 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
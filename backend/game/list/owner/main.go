@@ -8,11 +8,10 @@ import (
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.GameLister = database.DynamoDB
+var repository = database.Repository[database.GameLister](database.DynamoDB)
 
 type ListGamesResponse struct {
 	Games            []*database.Game `json:"games"`
@@ -20,9 +19,6 @@ type ListGamesResponse struct {
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 
 	owner, ownerSpecified := event.QueryStringParameters["owner"]
@@ -65,5 +61,5 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
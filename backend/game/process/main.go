@@ -0,0 +1,112 @@
+// Implements a Lambda handler which keeps the data derived from games up to date. It is the
+// games table stream's only Go consumer, since DynamoDB throttles streams read by more than two
+// consumers, and passes the old and new images of each game to every updater:
+//   - updateStatistics keeps the cached game statistics up to date.
+//   - updateRepertoire keeps the repertoire table up to date.
+//
+// Each updater saves a record's changes in one transaction which also records that the record
+// was applied, so a record which is retried after one updater failed is not applied twice by
+// the updaters which succeeded.
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.GameStreamProcessor](database.DynamoDB)
+
+func main() {
+	lambda.Start(Handler)
+}
+
+func Handler(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	log.Infof("Processing %d records", len(event.Records))
+
+	c := newCache()
+	for _, record := range event.Records {
+		if err := processRecord(record, c); err != nil {
+			log.Errorf("Failed to process record %s: %v", record.Change.SequenceNumber, err)
+			// Lambda retries the batch from the first failed record, so the records after it
+			// are left for the retry.
+			return events.DynamoDBEventResponse{
+				BatchItemFailures: []events.DynamoDBBatchItemFailure{{ItemIdentifier: record.Change.SequenceNumber}},
+			}, nil
+		}
+	}
+
+	return events.DynamoDBEventResponse{}, nil
+}
+
+// cache saves the lookups made while processing a batch of records.
+type cache struct {
+	// Maps a lowercase player name to the users with cached statistics which include it.
+	statisticsUsers map[string][]string
+
+	// Maps a username to the player names their statistics were calculated with. Nil if
+	// the user has no statistics.
+	statisticsNames map[string][]string
+
+	// Maps a username to the user's current player names. Nil if the user does not exist.
+	playerNames map[string][]string
+}
+
+// newCache returns an empty cache.
+func newCache() *cache {
+	return &cache{
+		statisticsUsers: make(map[string][]string),
+		statisticsNames: make(map[string][]string),
+		playerNames:     make(map[string][]string),
+	}
+}
+
+// processRecord passes the old and new images of the game in the provided record to each
+// updater.
+func processRecord(record events.DynamoDBEventRecord, c *cache) error {
+	var oldGame, newGame *database.Game
+	if len(record.Change.OldImage) > 0 {
+		oldGame = &database.Game{}
+		if err := unmarshalStreamImage(record.Change.OldImage, oldGame); err != nil {
+			return err
+		}
+	}
+	if len(record.Change.NewImage) > 0 {
+		newGame = &database.Game{}
+		if err := unmarshalStreamImage(record.Change.NewImage, newGame); err != nil {
+			return err
+		}
+	}
+
+	if err := updateStatistics(record.EventID, oldGame, newGame, c); err != nil {
+		return err
+	}
+	return updateRepertoire(record.EventID, oldGame, newGame, c)
+}
+
+// unmarshalStreamImage converts events.DynamoDBAttributeValue to struct
+func unmarshalStreamImage(attribute map[string]events.DynamoDBAttributeValue, out any) error {
+	dbAttrMap := make(map[string]*dynamodb.AttributeValue)
+
+	for k, v := range attribute {
+		var dbAttr dynamodb.AttributeValue
+		bytes, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(bytes, &dbAttr); err != nil {
+			return err
+		}
+		dbAttrMap[k] = &dbAttr
+	}
+
+	err := dynamodbattribute.UnmarshalMap(dbAttrMap, out)
+	return errors.Wrap(500, "Temporary server error", "Failed to unmarshal stream image", err)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+func TestHandlerFailure(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	names := []string{"alice"}
+	if err := repo.PutGameStatistics(&database.GameStatistics{Username: "alice", PlayerNames: names}); err != nil {
+		t.Fatalf("PutGameStatistics got err %v", err)
+	}
+	if err := repo.SetGameStatisticsPlayers("alice", nil, names); err != nil {
+		t.Fatalf("SetGameStatisticsPlayers got err %v", err)
+	}
+
+	first := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "bob",
+		Orientation: "white", Headers: map[string]string{"Result": "1-0"}, Pgn: "1. e4 e5 1-0"}
+	second := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_2", Owner: "alice", White: "alice", Black: "bob",
+		Orientation: "white", Headers: map[string]string{"Result": "0-1"}, Pgn: "1. d4 d5 0-1"}
+	invalid := newRecord(t, "2", "INSERT", nil, nil)
+	invalid.Change.NewImage = map[string]events.DynamoDBAttributeValue{"headers": events.NewStringAttribute("1-0")}
+
+	event := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		newRecord(t, "1", "INSERT", nil, first),
+		invalid,
+		newRecord(t, "3", "INSERT", nil, second),
+	}}
+	resp, err := Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	want := []events.DynamoDBBatchItemFailure{{ItemIdentifier: "2"}}
+	if diff := cmp.Diff(want, resp.BatchItemFailures); diff != "" {
+		t.Errorf("Handler failures mismatch (-want +got):\n%s", diff)
+	}
+
+	stats, err := repo.GetGameStatistics("alice")
+	if err != nil {
+		t.Fatalf("GetGameStatistics got err %v", err)
+	}
+	wantResults := map[string]database.GameResults{"white": {Wins: 1}}
+	if diff := cmp.Diff(wantResults, stats.Results(database.GameStatisticsDimension_Color)); diff != "" {
+		t.Errorf("Results mismatch (-want +got):\n%s", diff)
+	}
+
+	moves, err := repo.ListRepertoireMoves("alice", pgn.StartingFEN)
+	if err != nil {
+		t.Fatalf("ListRepertoireMoves got err %v", err)
+	}
+	wantMoves := []database.RepertoireMove{{Fen: pgn.StartingFEN, Color: database.White, San: "e4", Games: 1, Wins: 1}}
+	if diff := cmp.Diff(wantMoves, moves, cmpopts.IgnoreFields(database.RepertoireMove{}, "Username", "SortKey")); diff != "" {
+		t.Errorf("ListRepertoireMoves mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// newRecord returns a stream record changing oldGame into newGame.
+func newRecord(t *testing.T, sequence, eventName string, oldGame, newGame *database.Game) events.DynamoDBEventRecord {
+	t.Helper()
+	return events.DynamoDBEventRecord{
+		EventID:   "event-" + sequence,
+		EventName: eventName,
+		Change: events.DynamoDBStreamRecord{
+			SequenceNumber: sequence,
+			OldImage:       streamImage(t, oldGame),
+			NewImage:       streamImage(t, newGame),
+		},
+	}
+}
+
+// streamImage converts the provided game to a stream image.
+func streamImage(t *testing.T, game *database.Game) map[string]events.DynamoDBAttributeValue {
+	t.Helper()
+	if game == nil {
+		return nil
+	}
+
+	item, err := dynamodbattribute.MarshalMap(game)
+	if err != nil {
+		t.Fatalf("MarshalMap got err %v", err)
+	}
+	image := make(map[string]events.DynamoDBAttributeValue, len(item))
+	for k, v := range item {
+		image[k] = streamAttribute(v)
+	}
+	return image
+}
+
+// streamAttribute converts the provided attribute value to a stream attribute value.
+func streamAttribute(av *dynamodb.AttributeValue) events.DynamoDBAttributeValue {
+	switch {
+	case av.S != nil:
+		return events.NewStringAttribute(*av.S)
+	case av.N != nil:
+		return events.NewNumberAttribute(*av.N)
+	case av.BOOL != nil:
+		return events.NewBooleanAttribute(*av.BOOL)
+	case av.SS != nil:
+		return events.NewStringSetAttribute(aws.StringValueSlice(av.SS))
+	case av.M != nil:
+		m := make(map[string]events.DynamoDBAttributeValue, len(av.M))
+		for k, v := range av.M {
+			m[k] = streamAttribute(v)
+		}
+		return events.NewMapAttribute(m)
+	case av.L != nil:
+		l := make([]events.DynamoDBAttributeValue, 0, len(av.L))
+		for _, v := range av.L {
+			l = append(l, streamAttribute(v))
+		}
+		return events.NewListAttribute(l)
+	default:
+		return events.NewNullAttribute()
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// updateRepertoire applies the difference between the moves of the provided old and new images
+// of a game to the repertoire of the game's owner. The color each game was added with is saved,
+// so that its moves are removed with the same color even if the owner's player names change.
+func updateRepertoire(eventID string, oldGame, newGame *database.Game, c *cache) error {
+	var moves []database.RepertoireMove
+	var put, del []database.RepertoireGame
+
+	if oldGame != nil && oldGame.Owner != "" {
+		color, err := oldColor(oldGame, c)
+		if err != nil {
+			return err
+		}
+		moves = appendMoves(moves, oldGame, color, -1)
+		if newGame == nil || newGame.Owner != oldGame.Owner {
+			del = append(del, database.NewRepertoireGame(oldGame, color))
+		}
+	}
+
+	if newGame != nil && newGame.Owner != "" {
+		playerNames, err := c.getPlayerNames(newGame.Owner)
+		if err != nil {
+			return err
+		}
+		color := database.RepertoireColor(newGame, playerNames)
+		moves = appendMoves(moves, newGame, color, 1)
+		put = append(put, database.NewRepertoireGame(newGame, color))
+	}
+
+	return repository.UpdateRepertoire(eventID, moves, put, del)
+}
+
+// oldColor returns the color the provided game was added to its owner's repertoire with. Games
+// added before their colors were saved use the owner's current player names.
+func oldColor(game *database.Game, c *cache) (database.PlayerColor, error) {
+	saved, err := repository.GetRepertoireGame(game.Owner, string(game.Cohort), game.Id)
+	if err == nil {
+		return saved.Color, nil
+	}
+	var apiErr *errors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+		return "", err
+	}
+
+	playerNames, err := c.getPlayerNames(game.Owner)
+	if err != nil {
+		return "", err
+	}
+	return database.RepertoireColor(game, playerNames), nil
+}
+
+// getPlayerNames returns the current player names of the provided user, or nil if the user does
+// not exist.
+func (c *cache) getPlayerNames(username string) ([]string, error) {
+	if playerNames, ok := c.playerNames[username]; ok {
+		return playerNames, nil
+	}
+
+	user, err := repository.GetUser(username)
+	if err != nil {
+		var apiErr *errors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+			return nil, err
+		}
+		c.playerNames[username] = nil
+		return nil, nil
+	}
+	c.playerNames[username] = user.PlayerNames()
+	return c.playerNames[username], nil
+}
+
+// appendMoves appends the moves of the provided game, played as the provided color, to moves with
+// their counts multiplied by delta.
+func appendMoves(moves []database.RepertoireMove, game *database.Game, color database.PlayerColor, delta int) []database.RepertoireMove {
+	gameMoves, err := database.RepertoireMoves(game, color)
+	if err != nil {
+		// The PGN will not become valid on retry, so the game is skipped.
+		log.Warnf("Skipping game %s/%s: %v", game.Cohort, game.Id, err)
+		return moves
+	}
+	for _, m := range gameMoves {
+		m.Games *= delta
+		m.Wins *= delta
+		m.Draws *= delta
+		m.Losses *= delta
+		moves = append(moves, m)
+	}
+	return moves
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+func TestUpdateRepertoire(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	if err := repo.SetUserConditional(&database.User{Username: "alice"}, nil); err != nil {
+		t.Fatalf("SetUserConditional got err %v", err)
+	}
+
+	win := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "bob",
+		Headers: map[string]string{"Result": "1-0"}, Pgn: "1. e4 e5 1-0"}
+	draw := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "bob",
+		Headers: map[string]string{"Result": "1/2-1/2"}, Pgn: "1. e4 c5 1/2-1/2"}
+	deleted := &database.Game{Cohort: "1500-1600", Id: "2024.01.04_2", Owner: "alice", White: "alice", Black: "carol",
+		Headers: map[string]string{"Result": "0-1"}, Pgn: "1. d4 d5 0-1"}
+	invalid := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_3", Owner: "alice", White: "alice", Black: "bob",
+		Pgn: "1. e5 *"}
+
+	event := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		newRecord(t, "1", "INSERT", nil, deleted),
+		newRecord(t, "2", "INSERT", nil, win),
+		newRecord(t, "3", "MODIFY", win, draw),
+		newRecord(t, "4", "REMOVE", deleted, nil),
+		newRecord(t, "5", "INSERT", nil, invalid),
+	}}
+	resp, err := Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if len(resp.BatchItemFailures) != 0 {
+		t.Errorf("Handler got failures %v", resp.BatchItemFailures)
+	}
+
+	got, err := repo.ListRepertoireMoves("alice", pgn.StartingFEN)
+	if err != nil {
+		t.Fatalf("ListRepertoireMoves got err %v", err)
+	}
+	want := []database.RepertoireMove{
+		{Fen: pgn.StartingFEN, Color: database.White, San: "e4", Games: 1, Draws: 1},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(database.RepertoireMove{}, "Username", "SortKey")); diff != "" {
+		t.Errorf("ListRepertoireMoves mismatch (-want +got):\n%s", diff)
+	}
+
+	// Records delivered again after a failure are not counted twice.
+	if _, err := Handler(context.Background(), event); err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	again, err := repo.ListRepertoireMoves("alice", pgn.StartingFEN)
+	if err != nil {
+		t.Fatalf("ListRepertoireMoves got err %v", err)
+	}
+	if diff := cmp.Diff(got, again); diff != "" {
+		t.Errorf("Handler applied records twice (-first +second):\n%s", diff)
+	}
+}
+
+func TestUpdateRepertoirePlayerNamesChanged(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	user := &database.User{Username: "alice", DisplayName: "Ally"}
+	if err := repo.SetUserConditional(user, nil); err != nil {
+		t.Fatalf("SetUserConditional got err %v", err)
+	}
+
+	game := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "Ally", Black: "bob",
+		Orientation: "black", Headers: map[string]string{"Result": "1-0"}, Pgn: "1. e4 e5 1-0"}
+	event := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{newRecord(t, "1", "INSERT", nil, game)}}
+	if _, err := Handler(context.Background(), event); err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+
+	// The game is removed with the color it was added with, not the side matching the new
+	// display name.
+	user.DisplayName = "Zed"
+	if err := repo.SetUserConditional(user, nil); err != nil {
+		t.Fatalf("SetUserConditional got err %v", err)
+	}
+	event = events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{newRecord(t, "2", "REMOVE", game, nil)}}
+	resp, err := Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if len(resp.BatchItemFailures) != 0 {
+		t.Errorf("Handler got failures %v", resp.BatchItemFailures)
+	}
+
+	got, err := repo.ListRepertoireMoves("alice", pgn.StartingFEN)
+	if err != nil {
+		t.Fatalf("ListRepertoireMoves got err %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ListRepertoireMoves got %v, want no moves", got)
+	}
+	if _, err := repo.GetRepertoireGame("alice", "1500-1600", "2024.01.05_1"); err == nil {
+		t.Errorf("GetRepertoireGame got nil err for a removed game")
+	}
+}
+
+func TestProcessRecordMalformedPgn(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	game := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "bob",
+		Orientation: "white", Pgn: "1. e4 e5 2. Nf3 Nc6 ]"}
+
+	// The PGN cannot be parsed, so the game is skipped instead of failing the record.
+	if err := processRecord(newRecord(t, "1", "INSERT", nil, game), newCache()); err != nil {
+		t.Fatalf("processRecord got err %v", err)
+	}
+	got, err := repo.ListRepertoireMoves("alice", pgn.StartingFEN)
+	if err != nil {
+		t.Fatalf("ListRepertoireMoves got err %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ListRepertoireMoves got %v, want no moves", got)
+	}
+}
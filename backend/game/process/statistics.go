@@ -0,0 +1,105 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// getStatisticsUsers returns the usernames of the users with cached statistics which include the
+// provided player name.
+func (c *cache) getStatisticsUsers(player string) ([]string, error) {
+	player = strings.ToLower(strings.TrimSpace(player))
+	if player == "" {
+		return nil, nil
+	}
+	if users, ok := c.statisticsUsers[player]; ok {
+		return users, nil
+	}
+
+	users, err := repository.ListGameStatisticsUsers(player)
+	if err != nil {
+		return nil, err
+	}
+	c.statisticsUsers[player] = users
+	return users, nil
+}
+
+// getStatisticsNames returns the player names the provided user's statistics were calculated with. Nil
+// is returned if the user has no statistics.
+func (c *cache) getStatisticsNames(username string) ([]string, error) {
+	if names, ok := c.statisticsNames[username]; ok {
+		return names, nil
+	}
+
+	stats, err := repository.GetGameStatistics(username)
+	if err != nil {
+		var apiErr *errors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+			return nil, err
+		}
+		c.statisticsNames[username] = nil
+		return nil, nil
+	}
+	c.statisticsNames[username] = stats.PlayerNames
+	return stats.PlayerNames, nil
+}
+
+// updateStatistics applies the difference between the provided old and new images of a game to
+// the statistics of every user whose player names include the game's white or black player.
+// Users without cached statistics are skipped, since their statistics are calculated from all of
+// their games when first read.
+func updateStatistics(eventID string, oldGame, newGame *database.Game, c *cache) error {
+	deltas := make(map[string]map[string]int)
+	for _, change := range []struct {
+		game  *database.Game
+		delta int
+	}{{oldGame, -1}, {newGame, 1}} {
+		if change.game == nil {
+			continue
+		}
+
+		var usernames []string
+		for _, player := range []string{change.game.White, change.game.Black} {
+			users, err := c.getStatisticsUsers(player)
+			if err != nil {
+				return err
+			}
+			for _, u := range users {
+				if !slices.Contains(usernames, u) {
+					usernames = append(usernames, u)
+				}
+			}
+		}
+
+		for _, username := range usernames {
+			names, err := c.getStatisticsNames(username)
+			if err != nil {
+				return err
+			}
+			if names == nil {
+				continue
+			}
+			if deltas[username] == nil {
+				deltas[username] = make(map[string]int)
+			}
+			for _, key := range database.GameStatisticsKeys(change.game, names) {
+				deltas[username][key] += change.delta
+			}
+		}
+	}
+
+	for username, d := range deltas {
+		maps.DeleteFunc(d, func(_ string, delta int) bool { return delta == 0 })
+		if len(d) == 0 {
+			delete(deltas, username)
+		}
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+	return repository.UpdateGameStatistics(eventID, deltas)
+}
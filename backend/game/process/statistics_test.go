@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestUpdateStatistics(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	names := []string{"alice", "alice_li"}
+	err := repo.PutGameStatistics(&database.GameStatistics{
+		Username:    "alice",
+		Counts:      map[string]int{"color:white:win": 2},
+		PlayerNames: names,
+	})
+	if err != nil {
+		t.Fatalf("PutGameStatistics got err %v", err)
+	}
+	if err := repo.SetGameStatisticsPlayers("alice", nil, names); err != nil {
+		t.Fatalf("SetGameStatisticsPlayers got err %v", err)
+	}
+
+	win := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "carol",
+		Headers: map[string]string{"Result": "1-0", "ECO": "C50"}}
+	draw := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "carol",
+		Headers: map[string]string{"Result": "1/2-1/2", "ECO": "C50"}}
+	deleted := &database.Game{Cohort: "1500-1600", Id: "2024.01.04_2", Owner: "alice", White: "alice", Black: "dave",
+		Headers: map[string]string{"Result": "1-0", "ECO": "A10"}}
+	other := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_3", Owner: "bob", White: "bob", Black: "carol",
+		Headers: map[string]string{"Result": "1-0"}}
+	played := &database.Game{Cohort: "1500-1600", Id: "2024.01.05_4", Owner: "bob", White: "bob", Black: "Alice_Li",
+		Headers: map[string]string{"Result": "1-0"}}
+
+	event := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		newRecord(t, "1", "INSERT", nil, win),
+		newRecord(t, "2", "MODIFY", win, draw),
+		newRecord(t, "3", "REMOVE", deleted, nil),
+		newRecord(t, "4", "INSERT", nil, other),
+		newRecord(t, "5", "INSERT", nil, played),
+	}}
+	resp, err := Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if len(resp.BatchItemFailures) != 0 {
+		t.Errorf("Handler got failures %v", resp.BatchItemFailures)
+	}
+
+	stats, err := repo.GetGameStatistics("alice")
+	if err != nil {
+		t.Fatalf("GetGameStatistics got err %v", err)
+	}
+	want := map[string]database.GameResults{"white": {Wins: 1, Draws: 1}, "black": {Losses: 1}}
+	if diff := cmp.Diff(want, stats.Results(database.GameStatisticsDimension_Color)); diff != "" {
+		t.Errorf("Results mismatch (-want +got):\n%s", diff)
+	}
+	want = map[string]database.GameResults{"C50-C59": {Draws: 1}, "unknown": {Losses: 1}}
+	if diff := cmp.Diff(want, stats.Results(database.GameStatisticsDimension_Eco)); diff != "" {
+		t.Errorf("Results mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := repo.GetGameStatistics("bob"); err == nil {
+		t.Errorf("Handler created statistics for a user without cached statistics")
+	}
+
+	// Records delivered again after a failure are not counted twice.
+	if _, err := Handler(context.Background(), event); err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	again, err := repo.GetGameStatistics("alice")
+	if err != nil {
+		t.Fatalf("GetGameStatistics got err %v", err)
+	}
+	if diff := cmp.Diff(stats.Counts, again.Counts); diff != "" {
+		t.Errorf("Handler applied records twice (-first +second):\n%s", diff)
+	}
+}
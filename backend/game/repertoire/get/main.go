@@ -0,0 +1,119 @@
+// Implements a Lambda handler which returns the moves the caller has played from a position in
+// the games they own, split by the color the caller played. The repertoire is kept up to date
+// by the process handler as the caller's games change.
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+var repository = database.Repository[database.RepertoireGetter](database.DynamoDB)
+
+type RepertoireResponse struct {
+	// The normalized FEN of the position.
+	Fen string `json:"fen"`
+
+	// The moves played from the position in games where the user played white and black.
+	White RepertoireColor `json:"white"`
+	Black RepertoireColor `json:"black"`
+}
+
+type RepertoireColor struct {
+	// The number of games which continued from the position, and the user's results in them.
+	Games  int `json:"games"`
+	Wins   int `json:"wins"`
+	Draws  int `json:"draws"`
+	Losses int `json:"losses"`
+
+	// The moves played from the position, sorted by number of games descending.
+	Moves []RepertoireMove `json:"moves"`
+}
+
+type RepertoireMove struct {
+	// The move in standard algebraic notation.
+	San string `json:"san"`
+
+	// The number of games in which the move was played, and the user's results in them.
+	Games  int `json:"games"`
+	Wins   int `json:"wins"`
+	Draws  int `json:"draws"`
+	Losses int `json:"losses"`
+
+	// The user's score after the move, from 0 to 1, counting draws as half a point. Games
+	// without a result are not included. Zero if no game after the move has a result.
+	Score float64 `json:"score"`
+}
+
+// Handler returns the caller's repertoire from the position in the fen query parameter. The
+// starting position is used if fen is not provided.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	info := api.GetUserInfo(event)
+	if info.Username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	fen := event.QueryStringParameters["fen"]
+	if fen == "" {
+		fen = pgn.StartingFEN
+	}
+	fen, err := pgn.NormalizeFEN(fen)
+	if err != nil {
+		return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: fen is invalid: %v", err), "")), nil
+	}
+
+	moves, err := repository.ListRepertoireMoves(info.Username, fen)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	return api.Success(newResponse(fen, moves)), nil
+}
+
+// newResponse returns the response for the provided moves from the position with the
+// provided FEN.
+func newResponse(fen string, moves []database.RepertoireMove) *RepertoireResponse {
+	resp := &RepertoireResponse{
+		Fen:   fen,
+		White: RepertoireColor{Moves: []RepertoireMove{}},
+		Black: RepertoireColor{Moves: []RepertoireMove{}},
+	}
+
+	for _, m := range moves {
+		c := &resp.White
+		if m.Color == database.Black {
+			c = &resp.Black
+		}
+
+		c.Games += m.Games
+		c.Wins += m.Wins
+		c.Draws += m.Draws
+		c.Losses += m.Losses
+
+		move := RepertoireMove{San: m.San, Games: m.Games, Wins: m.Wins, Draws: m.Draws, Losses: m.Losses}
+		if decided := m.Wins + m.Draws + m.Losses; decided > 0 {
+			move.Score = (float64(m.Wins) + float64(m.Draws)/2) / float64(decided)
+		}
+		c.Moves = append(c.Moves, move)
+	}
+
+	for _, c := range []*RepertoireColor{&resp.White, &resp.Black} {
+		sort.SliceStable(c.Moves, func(i, j int) bool {
+			if c.Moves[i].Games != c.Moves[j].Games {
+				return c.Moves[i].Games > c.Moves[j].Games
+			}
+			return c.Moves[i].San < c.Moves[j].San
+		})
+	}
+	return resp
+}
+
+func main() {
+	lambda.Start(api.Logged(Handler))
+}
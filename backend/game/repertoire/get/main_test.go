@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+const afterE4 = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"
+
+func TestHandler(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	names := []string{"alice"}
+	var moves []database.RepertoireMove
+	for _, game := range []*database.Game{
+		{Owner: "alice", White: "alice", Black: "bob", Headers: map[string]string{"Result": "1-0"}, Pgn: "1. e4 e5 1-0"},
+		{Owner: "alice", White: "alice", Black: "bob", Headers: map[string]string{"Result": "1/2-1/2"}, Pgn: "1. e4 c5 1/2-1/2"},
+		{Owner: "alice", White: "alice", Black: "bob", Headers: map[string]string{"Result": "0-1"}, Pgn: "1. d4 d5 0-1"},
+		{Owner: "alice", White: "bob", Black: "alice", Headers: map[string]string{"Result": "1-0"}, Pgn: "1. e4 e5 1-0"},
+		{Owner: "alice", White: "bob", Black: "alice", Pgn: "1. e4 c5 *"},
+		{Owner: "bob", White: "bob", Black: "alice", Headers: map[string]string{"Result": "1-0"}, Pgn: "1. e4 e6 1-0"},
+	} {
+		gameMoves, err := database.RepertoireMoves(game, database.RepertoireColor(game, names))
+		if err != nil {
+			t.Fatalf("RepertoireMoves got err %v", err)
+		}
+		moves = append(moves, gameMoves...)
+	}
+	if err := repo.UpdateRepertoire("event-1", moves, nil, nil); err != nil {
+		t.Fatalf("UpdateRepertoire got err %v", err)
+	}
+
+	table := []struct {
+		name string
+		fen  string
+		want *RepertoireResponse
+	}{
+		{
+			name: "StartingPosition",
+			want: &RepertoireResponse{
+				Fen: pgn.StartingFEN,
+				White: RepertoireColor{Games: 3, Wins: 1, Draws: 1, Losses: 1, Moves: []RepertoireMove{
+					{San: "e4", Games: 2, Wins: 1, Draws: 1, Score: 0.75},
+					{San: "d4", Games: 1, Losses: 1},
+				}},
+				Black: RepertoireColor{Games: 2, Losses: 1, Moves: []RepertoireMove{
+					{San: "e4", Games: 2, Losses: 1},
+				}},
+			},
+		},
+		{
+			name: "NormalizesFen",
+			fen:  "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			want: &RepertoireResponse{
+				Fen: afterE4,
+				White: RepertoireColor{Games: 2, Wins: 1, Draws: 1, Moves: []RepertoireMove{
+					{San: "c5", Games: 1, Draws: 1, Score: 0.5},
+					{San: "e5", Games: 1, Wins: 1, Score: 1},
+				}},
+				Black: RepertoireColor{Games: 2, Losses: 1, Moves: []RepertoireMove{
+					{San: "c5", Games: 1},
+					{San: "e5", Games: 1, Losses: 1},
+				}},
+			},
+		},
+		{
+			name: "UnknownPosition",
+			fen:  "8/8/8/8/8/8/8/K6k w - - 0 1",
+			want: &RepertoireResponse{
+				Fen:   "8/8/8/8/8/8/8/K6k w - - 0 1",
+				White: RepertoireColor{Moves: []RepertoireMove{}},
+				Black: RepertoireColor{Moves: []RepertoireMove{}},
+			},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := Handler(context.Background(), newRequest("alice", tc.fen))
+			if err != nil {
+				t.Fatalf("Handler got err %v", err)
+			}
+			if resp.StatusCode != 200 {
+				t.Fatalf("Handler got status %d: %s", resp.StatusCode, resp.Body)
+			}
+
+			var got RepertoireResponse
+			if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+				t.Fatalf("Unmarshal response got err %v", err)
+			}
+			if diff := cmp.Diff(tc.want, &got); diff != "" {
+				t.Errorf("Handler mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandlerInvalidRequest(t *testing.T) {
+	repository = database.NewMemoryRepository()
+	defer func() { repository = database.DynamoDB }()
+
+	table := []struct {
+		name     string
+		username string
+		fen      string
+	}{
+		{name: "NoUsername", username: ""},
+		{name: "InvalidFen", username: "alice", fen: "not a fen"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := Handler(context.Background(), newRequest(tc.username, tc.fen))
+			if err != nil {
+				t.Fatalf("Handler got err %v", err)
+			}
+			if resp.StatusCode != 400 {
+				t.Errorf("Handler got status %d, want 400", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func newRequest(username, fen string) api.Request {
+	event := api.Request{QueryStringParameters: map[string]string{"fen": fen}}
+	if username != "" {
+		event.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+			JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+				Claims: map[string]string{"cognito:username": username},
+			},
+		}
+	}
+	return event
+}
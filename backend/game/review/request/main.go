@@ -10,12 +10,16 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 	payment "github.com/jackstenglein/chess-dojo-scheduler/backend/paymentService"
 )
 
-var repository = database.DynamoDB
+type GameReviewRequester interface {
+	database.UserGetter
+	database.GameGetter
+}
+
+var repository = database.Repository[GameReviewRequester](database.DynamoDB)
 
 type ReviewRequest struct {
 	Cohort string                  `json:"cohort"`
@@ -28,13 +32,10 @@ type ReviewResponse struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Handle(handler, api.Idempotent(database.Repository[database.IdempotencyStore](database.DynamoDB))))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Debugf("Event: %#v", event)
-
 	request := ReviewRequest{}
 	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
 		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
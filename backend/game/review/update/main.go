@@ -1,5 +1,5 @@
 // Implements a lambda handler which marks a game as reviewed or unreviewed.
-// The caller must be an admin.
+// The caller must have the REVIEW_GAMES permission.
 package main
 
 import (
@@ -10,11 +10,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.GameReviewSetter](database.DynamoDB)
 
 type Request struct {
 	Cohort string               `json:"cohort"`
@@ -23,13 +22,10 @@ type Request struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Debugf("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
@@ -39,8 +35,8 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	if !user.IsAdmin {
-		return api.Failure(errors.New(403, "Invalid request: you must be an admin to call this function", "")), nil
+	if !user.HasPermission(database.Permission_ReviewGames) {
+		return api.Failure(errors.New(403, "Invalid request: you do not have permission to review games", "")), nil
 	}
 
 	request := Request{}
@@ -65,14 +61,18 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		Cohort:      user.DojoCohort,
 	}
 
-	game, err := repository.SetGameReview(request.Cohort, request.Id, request.Review)
+	before, err := repository.GetGame(request.Cohort, request.Id)
 	if err != nil {
 		return api.Failure(err), nil
 	}
 
-	if err := database.SendGameReviewCompleteEvent(game); err != nil {
-		log.Errorf("Failed to create notification: %v", err)
+	game, err := repository.SetGameReview(request.Cohort, request.Id, request.Review,
+		database.NewGameReviewCompleteEvent(request.Cohort, request.Id))
+	if err != nil {
+		return api.Failure(err), nil
 	}
 
+	api.Audit(repository, event, database.AuditAction_ReviewGame, database.GameAuditTarget(request.Cohort, request.Id), before.Review, game.Review)
+
 	return api.Success(game), nil
 }
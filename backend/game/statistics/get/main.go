@@ -0,0 +1,157 @@
+// Implements a Lambda handler which returns a user's game results grouped by color, ECO family,
+// time control, opponent rating and month. The statistics include the games the user owns and the
+// games owned by other users in which one of the user's player names played. They are cached and
+// kept up to date by the process handler as games change, and are recalculated from all of the
+// user's games if they are missing or were last calculated more than maxStatisticsAge ago.
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// The maximum time since the statistics were calculated from all of the user's games before
+// they are recalculated.
+const maxStatisticsAge = 7 * 24 * time.Hour
+
+var repository = database.Repository[database.GameStatisticsCalculator](database.DynamoDB)
+
+type GameStatisticsResponse struct {
+	// The username of the user.
+	Username string `json:"username"`
+
+	// The user's results across all games.
+	Total database.GameResults `json:"total"`
+
+	// The user's results grouped by the user's color, ECO family, time control category,
+	// opponent rating band and month.
+	ByColor          map[string]database.GameResults `json:"byColor"`
+	ByEco            map[string]database.GameResults `json:"byEco"`
+	ByTimeControl    map[string]database.GameResults `json:"byTimeControl"`
+	ByOpponentRating map[string]database.GameResults `json:"byOpponentRating"`
+	ByMonth          map[string]database.GameResults `json:"byMonth"`
+
+	// The time the statistics were last calculated from all of the user's games and the time
+	// they were last updated, in time.RFC3339 format.
+	CalculatedAt string `json:"calculatedAt"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// Handler returns the game statistics of the user in the username path parameter.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	username, _ := event.PathParameters["username"]
+	if username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	now := time.Now().UTC()
+	stats, err := repository.GetGameStatistics(username)
+	if err != nil {
+		var apiErr *errors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+			return api.Failure(err), nil
+		}
+	}
+
+	if stats == nil || isStale(stats, now) {
+		user, err := repository.GetUser(username)
+		if err != nil {
+			return api.Failure(err), nil
+		}
+
+		var oldNames []string
+		if stats != nil {
+			oldNames = stats.PlayerNames
+		}
+		if stats, err = calculate(ctx, user, now); err != nil {
+			return api.Failure(err), nil
+		}
+		if err := repository.PutGameStatistics(stats); err != nil {
+			return api.Failure(err), nil
+		}
+		if err := repository.SetGameStatisticsPlayers(username, oldNames, stats.PlayerNames); err != nil {
+			return api.Failure(err), nil
+		}
+	}
+
+	return api.Success(newResponse(stats)), nil
+}
+
+// isStale returns true if the provided statistics were calculated more than maxStatisticsAge
+// before now.
+func isStale(stats *database.GameStatistics, now time.Time) bool {
+	calculatedAt, err := time.Parse(time.RFC3339, stats.CalculatedAt)
+	return err != nil || now.Sub(calculatedAt) > maxStatisticsAge
+}
+
+// calculate returns the statistics of the games owned by the provided user and the games in
+// which one of the user's player names played.
+func calculate(ctx context.Context, user *database.User, now time.Time) (*database.GameStatistics, error) {
+	names := user.PlayerNames()
+	stats := &database.GameStatistics{
+		Username:     user.Username,
+		Counts:       make(map[string]int),
+		CalculatedAt: now.Format(time.RFC3339),
+		UpdatedAt:    now.Format(time.RFC3339),
+		PlayerNames:  names,
+	}
+
+	seen := make(map[string]bool)
+	add := func(games func(startKey string) ([]*database.Game, string, error)) error {
+		for g, err := range database.All(ctx, games) {
+			if err != nil {
+				return err
+			}
+			key := string(g.Cohort) + "/" + g.Id
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			stats.AddGame(g, names)
+		}
+		return nil
+	}
+
+	if err := add(func(startKey string) ([]*database.Game, string, error) {
+		return repository.ListGamesByOwner(true, user.Username, "", "", startKey)
+	}); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err := add(func(startKey string) ([]*database.Game, string, error) {
+			return repository.ListGamesByPlayer(name, database.Either, "", "", startKey)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// newResponse returns the response for the provided statistics.
+func newResponse(stats *database.GameStatistics) *GameStatisticsResponse {
+	resp := &GameStatisticsResponse{
+		Username:         stats.Username,
+		ByColor:          stats.Results(database.GameStatisticsDimension_Color),
+		ByEco:            stats.Results(database.GameStatisticsDimension_Eco),
+		ByTimeControl:    stats.Results(database.GameStatisticsDimension_TimeControl),
+		ByOpponentRating: stats.Results(database.GameStatisticsDimension_OpponentRating),
+		ByMonth:          stats.Results(database.GameStatisticsDimension_Month),
+		CalculatedAt:     stats.CalculatedAt,
+		UpdatedAt:        stats.UpdatedAt,
+	}
+	for _, r := range resp.ByColor {
+		resp.Total.Wins += r.Wins
+		resp.Total.Draws += r.Draws
+		resp.Total.Losses += r.Losses
+	}
+	return resp
+}
+
+func main() {
+	lambda.Start(api.Logged(Handler))
+}
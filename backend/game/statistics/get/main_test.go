@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestHandler(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	err := repo.SetUserConditional(&database.User{
+		Username:    "alice",
+		DisplayName: "Alice",
+		Ratings:     map[database.RatingSystem]*database.Rating{database.Lichess: {Username: "alice_li"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("SetUserConditional got err %v", err)
+	}
+
+	_, err = repo.BatchPutGames([]*database.Game{
+		{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "bob", Date: "2024.01.05",
+			Headers: map[string]string{"Result": "1-0", "ECO": "C50", "TimeControl": "600+5", "BlackElo": "1520"}},
+		{Cohort: "1500-1600", Id: "2024.02.05_2", Owner: "alice", White: "carol", Black: "alice", Date: "2024.02.05",
+			Headers: map[string]string{"Result": "1/2-1/2", "ECO": "B12", "TimeControl": "180+2", "WhiteElo": "1610"}},
+		{Cohort: "1500-1600", Id: "2024.02.06_3", Owner: "alice", White: "alice", Black: "dave", Unlisted: true,
+			Headers: map[string]string{"Result": "0-1"}},
+		{Cohort: "1600-1700", Id: "2024.02.07_4", Owner: "bob", White: "bob", Black: "alice_li", Date: "2024.02.07",
+			Headers: map[string]string{"Result": "1-0", "ECO": "C55", "TimeControl": "5400+30", "WhiteElo": "1700"}},
+		{Cohort: "1600-1700", Id: "2024.02.08_5", Owner: "bob", White: "bob", Black: "erin",
+			Headers: map[string]string{"Result": "1-0"}},
+	})
+	if err != nil {
+		t.Fatalf("BatchPutGames got err %v", err)
+	}
+
+	resp := getStatistics(t, "alice")
+	want := &GameStatisticsResponse{
+		Username: "alice",
+		Total:    database.GameResults{Wins: 1, Draws: 1, Losses: 1},
+		ByColor: map[string]database.GameResults{
+			"white": {Wins: 1},
+			"black": {Draws: 1, Losses: 1},
+		},
+		ByEco: map[string]database.GameResults{
+			"C50-C59": {Wins: 1, Losses: 1},
+			"B10-B19": {Draws: 1},
+		},
+		ByTimeControl: map[string]database.GameResults{
+			"rapid":     {Wins: 1},
+			"blitz":     {Draws: 1},
+			"classical": {Losses: 1},
+		},
+		ByOpponentRating: map[string]database.GameResults{
+			"1500-1600": {Wins: 1},
+			"1600-1700": {Draws: 1},
+			"1700-1800": {Losses: 1},
+		},
+		ByMonth: map[string]database.GameResults{
+			"2024-01": {Wins: 1},
+			"2024-02": {Draws: 1, Losses: 1},
+		},
+	}
+	if diff := cmp.Diff(want, resp, ignoreTimes); diff != "" {
+		t.Errorf("Handler mismatch (-want +got):\n%s", diff)
+	}
+
+	stats, err := repo.GetGameStatistics("alice")
+	if err != nil {
+		t.Fatalf("GetGameStatistics got err %v", err)
+	}
+	if stats.CalculatedAt == "" {
+		t.Errorf("Handler did not cache the statistics")
+	}
+
+	for _, player := range []string{"alice", "alice_li"} {
+		users, err := repo.ListGameStatisticsUsers(player)
+		if err != nil {
+			t.Fatalf("ListGameStatisticsUsers got err %v", err)
+		}
+		if diff := cmp.Diff([]string{"alice"}, users); diff != "" {
+			t.Errorf("ListGameStatisticsUsers(%q) mismatch (-want +got):\n%s", player, diff)
+		}
+	}
+}
+
+func TestHandlerCache(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	if err := repo.SetUserConditional(&database.User{Username: "alice"}, nil); err != nil {
+		t.Fatalf("SetUserConditional got err %v", err)
+	}
+	_, err := repo.BatchPutGames([]*database.Game{
+		{Cohort: "1500-1600", Id: "2024.01.05_1", Owner: "alice", White: "alice", Black: "bob", Headers: map[string]string{"Result": "1-0"}},
+	})
+	if err != nil {
+		t.Fatalf("BatchPutGames got err %v", err)
+	}
+
+	now := time.Now().UTC()
+	for _, tc := range []struct {
+		name         string
+		calculatedAt time.Time
+		wantWins     int
+	}{
+		{name: "Fresh", calculatedAt: now.Add(-time.Hour), wantWins: 5},
+		{name: "Stale", calculatedAt: now.Add(-maxStatisticsAge - time.Hour), wantWins: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := repo.PutGameStatistics(&database.GameStatistics{
+				Username:     "alice",
+				Counts:       map[string]int{"color:white:win": 5},
+				CalculatedAt: tc.calculatedAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				t.Fatalf("PutGameStatistics got err %v", err)
+			}
+
+			if got := getStatistics(t, "alice").Total.Wins; got != tc.wantWins {
+				t.Errorf("Handler got %d wins, want %d", got, tc.wantWins)
+			}
+		})
+	}
+}
+
+var ignoreTimes = cmp.FilterPath(func(p cmp.Path) bool {
+	name := p.Last().String()
+	return name == ".CalculatedAt" || name == ".UpdatedAt"
+}, cmp.Ignore())
+
+func getStatistics(t *testing.T, username string) *GameStatisticsResponse {
+	t.Helper()
+	event := api.Request{PathParameters: map[string]string{"username": username}}
+	resp, err := Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Handler got status %d: %s", resp.StatusCode, resp.Body)
+	}
+
+	var got GameStatisticsResponse
+	if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+		t.Fatalf("Unmarshal response got err %v", err)
+	}
+	return &got
+}
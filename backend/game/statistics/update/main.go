@@ -21,7 +21,7 @@ var stage = os.Getenv("stage")
 
 type Event events.CloudWatchEvent
 
-var repository database.GameLister = database.DynamoDB
+var repository = database.Repository[database.GameLister](database.DynamoDB)
 
 func getZipFile() (*os.File, *zip.Writer, io.Writer, error) {
 	date := time.Now().Format(time.DateOnly)
@@ -55,8 +55,9 @@ func processGames(w io.Writer, games []*database.Game) error {
 	return nil
 }
 
-func uploadFile(archive *os.File) error {
-	log.Info("Uploading file")
+func uploadFile(ctx context.Context, archive *os.File) error {
+	logger := log.FromContext(ctx)
+	logger.Info("Uploading file")
 	archive.Seek(0, 0)
 	uploader := s3manager.NewUploader(session.Must(session.NewSession()))
 	_, err := uploader.Upload(&s3manager.UploadInput{
@@ -68,12 +69,13 @@ func uploadFile(archive *os.File) error {
 }
 
 func Handler(ctx context.Context, event Event) (Event, error) {
-	log.Infof("Event: %#v", event)
-	log.SetRequestId(event.ID)
+	logger := log.Default().With(log.RequestId(event.ID))
+	ctx = log.NewContext(ctx, logger)
+	logger.Infof("Event: %#v", event)
 
 	archive, zipWriter, w, err := getZipFile()
 	if err != nil {
-		log.Errorf("Failed to create pgn file: %v", err)
+		logger.Errorf("Failed to create pgn file: %v", err)
 		return event, err
 	}
 	defer archive.Close()
@@ -85,13 +87,13 @@ func Handler(ctx context.Context, event Event) (Event, error) {
 		for ok := true; ok; ok = startKey != "" {
 			games, startKey, err = repository.ScanCohort(cohort, startKey)
 			if err != nil {
-				log.Errorf("Failed to scan games: %v", err)
+				logger.Errorf("Failed to scan games: %v", err)
 				return event, err
 			}
 
-			log.Infof("Processing %d games", len(games))
+			logger.Infof("Processing %d games", len(games))
 			if err := processGames(w, games); err != nil {
-				log.Errorf("Failed to process games: %v", err)
+				logger.Errorf("Failed to process games: %v", err)
 				return event, err
 			}
 		}
@@ -99,11 +101,11 @@ func Handler(ctx context.Context, event Event) (Event, error) {
 
 	zipWriter.Close()
 
-	if err := uploadFile(archive); err != nil {
-		log.Errorf("Failed to upload pgn file: %v", err)
+	if err := uploadFile(ctx, archive); err != nil {
+		logger.Errorf("Failed to upload pgn file: %v", err)
 		return event, err
 	}
-	log.Info("File uploaded")
+	logger.Info("File uploaded")
 	return event, nil
 }
 
@@ -55,4 +55,5 @@ require (
 	golang.org/x/crypto v0.47.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
 	google.golang.org/api v0.262.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -18,7 +18,7 @@ import (
 
 type Event events.CloudWatchEvent
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.GraduationLister](database.DynamoDB)
 var graduationsChannelId = os.Getenv("discordGraduationsChannelId")
 var frontendHost = os.Getenv("frontendHost")
 
@@ -55,12 +55,13 @@ func main() {
 }
 
 func handler(ctx context.Context, event Event) (Event, error) {
-	log.SetRequestId(event.ID)
-	log.Infof("Event: %#v", event)
+	logger := log.Default().With(log.RequestId(event.ID))
+	ctx = log.NewContext(ctx, logger)
+	logger.Infof("Event: %#v", event)
 
 	cohorts, grads, err := getData(event.ID)
 	if err != nil {
-		log.Errorf("Failed to get graduations: %v", err)
+		logger.Errorf("Failed to get graduations: %v", err)
 		return event, err
 	}
 
@@ -83,7 +84,7 @@ func handler(ctx context.Context, event Event) (Event, error) {
 		for _, grad := range cohortGrads {
 			discordId, err := discord.GetDiscordIdByCognitoUsername(nil, grad.Username)
 			if err != nil {
-				log.Errorf("Failed to get Discord ID: %v", err)
+				logger.Errorf("Failed to get Discord ID: %v", err)
 			}
 			if discordId == "" {
 				sb.WriteString(fmt.Sprintf("%s %s", discord.MessageEmojiDojo, grad.DisplayName))
@@ -95,10 +96,10 @@ func handler(ctx context.Context, event Event) (Event, error) {
 		}
 
 		if sb.Len() >= 1000 {
-			log.Infof("Sending message to ID %s: %s", graduationsChannelId, sb.String())
+			logger.Infof("Sending message to ID %s: %s", graduationsChannelId, sb.String())
 			_, err = discord.SendMessageInChannel(sb.String(), graduationsChannelId)
 			if err != nil {
-				log.Errorf("Failed to post message in Discord: %v", err)
+				logger.Errorf("Failed to post message in Discord: %v", err)
 				return event, err
 			}
 			sb.Reset()
@@ -107,14 +108,14 @@ func handler(ctx context.Context, event Event) (Event, error) {
 	}
 
 	if !hasGrads {
-		log.Info("No Grads")
+		logger.Info("No Grads")
 		return event, nil
 	}
 
-	log.Infof("Sending message to ID %s: %s", graduationsChannelId, sb.String())
+	logger.Infof("Sending message to ID %s: %s", graduationsChannelId, sb.String())
 	_, err = discord.SendMessageInChannel(sb.String(), graduationsChannelId)
 	if err != nil {
-		log.Errorf("Failed to post message in Discord: %v", err)
+		logger.Errorf("Failed to post message in Discord: %v", err)
 		return event, err
 	}
 	return event, nil
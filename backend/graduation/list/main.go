@@ -8,11 +8,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.GraduationLister = database.DynamoDB
+var repository = database.Repository[database.GraduationLister](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 type ListGraduationsResponse struct {
@@ -61,9 +60,6 @@ func byDateHandler(event api.Request) (api.Response, error) {
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	if _, ok := event.PathParameters["cohort"]; ok {
 		return byCohortHandler(event)
 	}
@@ -76,8 +72,5 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 }
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda/messages"
+)
+
+// functionTimeout is the maximum duration of a single invocation, matching the
+// API Gateway integration timeout.
+const functionTimeout = 30 * time.Second
+
+// function is a Go Lambda function which is built and started on its first invocation.
+// The function runs as a child process in the Lambda RPC mode, which aws-lambda-go
+// enables when the _LAMBDA_SERVER_PORT environment variable is set.
+type function struct {
+	// The name of the function, unique across all services.
+	Name string
+
+	// The directory containing the function's main package.
+	Dir string
+
+	// The environment variables of the function, in KEY=value form.
+	Env []string
+
+	mu     sync.Mutex
+	client *rpc.Client
+}
+
+// functionRunner builds and starts functions, and stops them when its context is canceled.
+type functionRunner struct {
+	// The context of the child processes.
+	ctx context.Context
+
+	// The directory where function binaries are built.
+	binDir string
+
+	// The environment variables added to every function, in KEY=value form.
+	env []string
+
+	// Whether only one function is invoked at a time. Functions sharing a memory database
+	// file must not use it concurrently.
+	serial bool
+
+	mu sync.Mutex
+}
+
+// Invoke sends the provided payload to the function and returns the response payload.
+// The function is built and started if it is not already running.
+func (r *functionRunner) Invoke(f *function, requestId string, payload []byte) ([]byte, error) {
+	if r.serial {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+	}
+
+	client, err := r.start(f)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(functionTimeout)
+	request := &messages.InvokeRequest{
+		Payload:   payload,
+		RequestId: requestId,
+		Deadline: messages.InvokeRequest_Timestamp{
+			Seconds: deadline.Unix(),
+			Nanos:   int64(deadline.Nanosecond()),
+		},
+		InvokedFunctionArn: fmt.Sprintf("arn:aws:lambda:us-east-1:000000000000:function:%s", f.Name),
+	}
+
+	var response messages.InvokeResponse
+	call := client.Go("Function.Invoke", request, &response, nil)
+	select {
+	case <-call.Done:
+	case <-time.After(functionTimeout):
+		return nil, fmt.Errorf("%s timed out after %s", f.Name, functionTimeout)
+	}
+
+	if call.Error != nil {
+		// The process most likely crashed, so start it again on the next invocation.
+		f.mu.Lock()
+		if f.client == client {
+			f.client = nil
+		}
+		f.mu.Unlock()
+		return nil, fmt.Errorf("%s: %w", f.Name, call.Error)
+	}
+	if response.Error != nil {
+		b, _ := json.Marshal(response.Error)
+		return nil, fmt.Errorf("%s returned error: %s", f.Name, b)
+	}
+	return response.Payload, nil
+}
+
+// start builds and starts the provided function if it is not already running, and returns
+// an RPC client connected to it.
+func (r *functionRunner) start(f *function) (*rpc.Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.client != nil {
+		return f.client, nil
+	}
+
+	bin := filepath.Join(r.binDir, f.Name)
+	log.Printf("Building %s from %s", f.Name, f.Dir)
+	build := exec.CommandContext(r.ctx, "go", "build", "-o", bin, ".")
+	build.Dir = f.Dir
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return nil, fmt.Errorf("failed to build %s: %w", f.Name, err)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(r.ctx, bin)
+	cmd.Env = slices.Concat(f.Env, os.Environ(), r.env, []string{
+		"_LAMBDA_SERVER_PORT=" + strconv.Itoa(port),
+		"AWS_LAMBDA_FUNCTION_NAME=" + f.Name,
+	})
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", f.Name, err)
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil && r.ctx.Err() == nil {
+			log.Printf("%s exited: %v", f.Name, err)
+		}
+	}()
+
+	client, err := dial(port)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("failed to connect to %s: %w", f.Name, err)
+	}
+
+	log.Printf("Started %s on port %d", f.Name, port)
+	f.client = client
+	return client, nil
+}
+
+// freePort returns a TCP port on localhost that is not currently in use.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// dial connects to the function RPC server on the provided port, retrying until the
+// function has started listening.
+func dial(port int) (*rpc.Client, error) {
+	addr := net.JoinHostPort("localhost", strconv.Itoa(port))
+	deadline := time.Now().Add(10 * time.Second)
+
+	for {
+		client, err := rpc.Dial("tcp", addr)
+		if err == nil {
+			var response messages.PingResponse
+			if err = client.Call("Function.Ping", &messages.PingRequest{}, &response); err == nil {
+				return client, nil
+			}
+			client.Close()
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+)
+
+// The CORS configuration of the HttpApi in root/serverless.yml.
+const (
+	corsAllowHeaders  = "Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,X-Amz-User-Agent,X-Amzn-Trace-Id,Idempotency-Key"
+	corsAllowMethods  = "OPTIONS,GET,POST,PUT,DELETE"
+	corsExposeHeaders = "Idempotent-Replayed"
+)
+
+// gateway serves API Gateway routes by invoking their Lambda functions.
+type gateway struct {
+	routes     []*route
+	runner     *functionRunner
+	authorizer *stubAuthorizer
+}
+
+// ServeHTTP invokes the function of the route matching the request.
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		setCorsHeaders(w.Header())
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	rt, params := findRoute(g.routes, r.Method, r.URL.Path)
+	if rt == nil {
+		writeMessage(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	start := time.Now()
+	requestId := fmt.Sprintf("local-%d", start.UnixNano())
+
+	var claims map[string]string
+	if rt.Authorized {
+		var ok bool
+		if claims, ok = g.authorizer.Claims(r); !ok {
+			writeMessage(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+	}
+
+	request, err := newRequest(r, rt, params, claims, requestId)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		log.Printf("Failed to marshal request for %s: %v", rt.RouteKey(), err)
+		writeMessage(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	payload, err = g.runner.Invoke(rt.Function, requestId, payload)
+	if err != nil {
+		log.Printf("%s failed: %v", rt.RouteKey(), err)
+		writeMessage(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	var response api.Response
+	if err := json.Unmarshal(payload, &response); err != nil {
+		log.Printf("%s returned invalid response: %v", rt.RouteKey(), err)
+		writeMessage(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	writeResponse(w, response)
+	log.Printf("%s %s -> %d (%s)", r.Method, r.URL.Path, response.StatusCode, time.Since(start).Round(time.Millisecond))
+}
+
+// newRequest converts the provided HTTP request into the API Gateway request sent to the
+// route's function. Claims are passed to the function as the JWT authorizer claims if non-nil.
+func newRequest(r *http.Request, rt *route, params, claims map[string]string, requestId string) (api.Request, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return api.Request{}, err
+	}
+
+	request := api.Request{
+		Version:        "2.0",
+		RouteKey:       rt.RouteKey(),
+		RawPath:        r.URL.Path,
+		RawQueryString: r.URL.RawQuery,
+		Headers:        make(map[string]string),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:   rt.RouteKey(),
+			AccountID:  "000000000000",
+			Stage:      "$default",
+			RequestID:  requestId,
+			DomainName: r.Host,
+			Time:       time.Now().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:  time.Now().UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIP(r),
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+		if name == "cookie" {
+			for _, v := range values {
+				request.Cookies = append(request.Cookies, strings.Split(v, "; ")...)
+			}
+			continue
+		}
+		request.Headers[name] = strings.Join(values, ",")
+	}
+
+	if query := r.URL.Query(); len(query) > 0 {
+		request.QueryStringParameters = make(map[string]string, len(query))
+		for name, values := range query {
+			request.QueryStringParameters[name] = strings.Join(values, ",")
+		}
+	}
+
+	if len(params) > 0 {
+		request.PathParameters = params
+	}
+
+	if claims != nil {
+		request.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+			JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+				Claims: claims,
+			},
+		}
+	}
+
+	if utf8.Valid(body) {
+		request.Body = string(body)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+
+	return request, nil
+}
+
+// sourceIP returns the IP address of the client that sent the request.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// setCorsHeaders sets the CORS headers configured on the HttpApi.
+func setCorsHeaders(header http.Header) {
+	header.Set("Access-Control-Allow-Origin", "*")
+	header.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+	header.Set("Access-Control-Allow-Methods", corsAllowMethods)
+	header.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+}
+
+// writeResponse writes the provided API Gateway response to w.
+func writeResponse(w http.ResponseWriter, response api.Response) {
+	header := w.Header()
+	for name, value := range response.Headers {
+		header.Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		for _, v := range values {
+			header.Add(name, v)
+		}
+	}
+	setCorsHeaders(header)
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			writeMessage(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		body = decoded
+	}
+
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
+}
+
+// writeMessage writes an API Gateway style error response, such as {"message":"Unauthorized"}.
+func writeMessage(w http.ResponseWriter, statusCode int, message string) {
+	setCorsHeaders(w.Header())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,88 @@
+// Command localGateway serves the Go API handlers over HTTP on a local machine, so that the
+// frontend can be developed without deploying the backend.
+//
+// The routes are read from the serverless.yml file of each service. Each handler is built and
+// started on its first request, and is then invoked the same way API Gateway invokes it in
+// production. Run it from the backend directory:
+//
+//	go run ./localGateway -user myusername
+//
+// With the -memory flag, the handlers use an in-memory database saved in the given file instead
+// of DynamoDB, and are invoked one at a time:
+//
+//	go run ./localGateway -user myusername -memory local.db.json
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "The address to listen on")
+	root := flag.String("root", ".", "The backend directory containing the service serverless.yml files")
+	stage := flag.String("stage", "dev", "The stage used to resolve serverless.yml variables")
+	username := flag.String("user", "", "The username used for authorized requests without an Authorization header")
+	email := flag.String("email", "", "The email used for authorized requests without an Authorization header")
+	name := flag.String("name", "", "The name used for authorized requests without an Authorization header")
+	memory := flag.String("memory", "", "The file where the in-memory database is saved. If set, handlers use it instead of DynamoDB")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rootDir, err := filepath.Abs(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	routes, err := loadRoutes(rootDir, *stage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(routes) == 0 {
+		log.Fatalf("No routes found in %s", rootDir)
+	}
+
+	binDir, err := os.MkdirTemp("", "localGateway")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(binDir)
+
+	runner := &functionRunner{ctx: ctx, binDir: binDir}
+	if *memory != "" {
+		memoryFile, err := filepath.Abs(*memory)
+		if err != nil {
+			log.Fatal(err)
+		}
+		runner.env = []string{database.MemoryDatabaseFileEnv + "=" + memoryFile}
+		runner.serial = true
+		log.Printf("Using the in-memory database saved in %s", memoryFile)
+	}
+
+	g := &gateway{
+		routes:     routes,
+		runner:     runner,
+		authorizer: newStubAuthorizer(*username, *email, *name),
+	}
+	server := &http.Server{Addr: *addr, Handler: g}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("Serving %d routes on http://%s", len(routes), *addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+)
+
+// Segment kinds, in increasing order of precedence.
+const (
+	greedySegment = iota
+	parameterSegment
+	literalSegment
+)
+
+// segment is a single segment of an API Gateway route path.
+type segment struct {
+	kind int
+
+	// The literal value of the segment, or the name of the path parameter.
+	value string
+}
+
+// parsePath splits the provided API Gateway route path into segments.
+func parsePath(path string) []segment {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	segments := make([]segment, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case strings.HasPrefix(p, "{") && strings.HasSuffix(p, "+}"):
+			segments = append(segments, segment{kind: greedySegment, value: p[1 : len(p)-2]})
+		case strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}"):
+			segments = append(segments, segment{kind: parameterSegment, value: p[1 : len(p)-1]})
+		default:
+			segments = append(segments, segment{kind: literalSegment, value: p})
+		}
+	}
+	return segments
+}
+
+// match returns the path parameters of the provided request path if it matches the
+// segments. The returned bool is false if the path does not match.
+func match(segments []segment, path string) (map[string]string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	params := make(map[string]string)
+
+	for i, s := range segments {
+		if i >= len(parts) || parts[i] == "" {
+			return nil, false
+		}
+
+		switch s.kind {
+		case greedySegment:
+			params[s.value] = strings.Join(parts[i:], "/")
+			return params, true
+		case parameterSegment:
+			params[s.value] = parts[i]
+		case literalSegment:
+			if parts[i] != s.value {
+				return nil, false
+			}
+		}
+	}
+
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// moreSpecific returns true if route a takes precedence over route b when both match
+// the same request. As in API Gateway, literal segments take precedence over path
+// parameters, which take precedence over greedy path parameters, and routes with a
+// method take precedence over ANY routes.
+func moreSpecific(a, b *route) bool {
+	as, bs := a.segments, b.segments
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i].kind != bs[i].kind {
+			return as[i].kind > bs[i].kind
+		}
+	}
+	if len(as) != len(bs) {
+		return len(as) > len(bs)
+	}
+	return a.Method != "" && b.Method == ""
+}
+
+// findRoute returns the route matching the provided method and path, along with the
+// path parameters. The returned route is nil if no route matches.
+func findRoute(routes []*route, method, path string) (*route, map[string]string) {
+	var best *route
+	var bestParams map[string]string
+
+	for _, rt := range routes {
+		if rt.Method != "" && rt.Method != method {
+			continue
+		}
+		params, ok := match(rt.segments, path)
+		if !ok {
+			continue
+		}
+		if best == nil || moreSpecific(rt, best) {
+			best, bestParams = rt, params
+		}
+	}
+	return best, bestParams
+}
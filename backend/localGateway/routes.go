@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serverlessConfig contains the parts of a service's serverless.yml file used by the gateway.
+type serverlessConfig struct {
+	Provider struct {
+		Region      string         `yaml:"region"`
+		Environment map[string]any `yaml:"environment"`
+	} `yaml:"provider"`
+
+	Functions map[string]serverlessFunction `yaml:"functions"`
+}
+
+// serverlessFunction is a single function in a serverless.yml file.
+type serverlessFunction struct {
+	// The handler of the function, relative to the service directory. For Go
+	// functions, this is the path to the main.go file.
+	Handler string `yaml:"handler"`
+
+	// The environment variables specific to this function.
+	Environment map[string]any `yaml:"environment"`
+
+	// The events that trigger the function.
+	Events serverlessEvents `yaml:"events"`
+}
+
+// serverlessEvents is the list of events of a function. Events which are defined through
+// a variable instead of a list are ignored.
+type serverlessEvents []struct {
+	HttpApi *httpApiEvent `yaml:"httpApi"`
+}
+
+func (e *serverlessEvents) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	type plain serverlessEvents
+	return node.Decode((*plain)(e))
+}
+
+// httpApiEvent is an API Gateway HTTP API event. It can be written either as
+// a map with path/method keys or as a string of the form `METHOD /path`.
+type httpApiEvent struct {
+	Path       string `yaml:"path"`
+	Method     string `yaml:"method"`
+	Authorizer any    `yaml:"authorizer"`
+}
+
+func (e *httpApiEvent) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		method, path, ok := strings.Cut(node.Value, " ")
+		if !ok {
+			method, path = "*", node.Value
+		}
+		e.Method, e.Path = method, path
+		return nil
+	}
+
+	type plain httpApiEvent
+	return node.Decode((*plain)(e))
+}
+
+// route is a single API Gateway route served by a Go Lambda function.
+type route struct {
+	// The HTTP method of the route, in upper case. Empty if the route matches any method.
+	Method string
+
+	// The API Gateway path of the route, such as /event/{id}/book.
+	Path string
+
+	// Whether the route uses the JWT authorizer.
+	Authorized bool
+
+	// The function serving the route.
+	Function *function
+
+	// The segments of Path.
+	segments []segment
+}
+
+// RouteKey returns the API Gateway route key, such as `PUT /event/{id}/book`.
+func (r *route) RouteKey() string {
+	if r.Method == "" {
+		return "ANY " + r.Path
+	}
+	return r.Method + " " + r.Path
+}
+
+// loadRoutes parses every serverless.yml file in the direct subdirectories of root and returns
+// the routes served by Go functions. TypeScript functions are skipped.
+func loadRoutes(root, stage string) ([]*route, error) {
+	files, err := filepath.Glob(filepath.Join(root, "*", "serverless.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []*route
+	for _, file := range files {
+		serviceRoutes, err := loadServiceRoutes(file, stage)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, serviceRoutes...)
+	}
+	return routes, nil
+}
+
+// loadServiceRoutes returns the routes served by Go functions in the provided serverless.yml file.
+func loadServiceRoutes(file, stage string) ([]*route, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var config serverlessConfig
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	serviceDir := filepath.Dir(file)
+	providerEnv := resolveEnvironment(serviceDir, stage, config.Provider.Environment)
+	if config.Provider.Region != "" {
+		// Lambda sets AWS_REGION to the region of the function.
+		providerEnv = append(providerEnv, "AWS_REGION="+config.Provider.Region)
+	}
+
+	var routes []*route
+	for name, fn := range config.Functions {
+		if !strings.HasSuffix(fn.Handler, ".go") {
+			continue
+		}
+
+		f := &function{
+			Name: fmt.Sprintf("%s-%s", filepath.Base(serviceDir), name),
+			Dir:  filepath.Join(serviceDir, filepath.Dir(fn.Handler)),
+			Env:  slices.Concat(providerEnv, resolveEnvironment(serviceDir, stage, fn.Environment)),
+		}
+
+		for _, event := range fn.Events {
+			if event.HttpApi == nil {
+				continue
+			}
+
+			method := strings.ToUpper(event.HttpApi.Method)
+			if method == "*" || method == "ANY" {
+				method = ""
+			}
+			routes = append(routes, &route{
+				Method:     method,
+				Path:       event.HttpApi.Path,
+				Authorized: event.HttpApi.Authorizer != nil,
+				Function:   f,
+				segments:   parsePath(event.HttpApi.Path),
+			})
+		}
+	}
+	return routes, nil
+}
+
+// variablePattern matches a single Serverless variable that does not contain another variable.
+var variablePattern = regexp.MustCompile(`\$\{([^${}]+)\}`)
+
+// filePattern matches the contents of a Serverless file variable, such as file(../config.yml):key.
+var filePattern = regexp.MustCompile(`^file\(([^)]+)\):(.+)$`)
+
+// resolveEnvironment returns the provided environment in KEY=value form. Serverless variables
+// for the stage, environment variables and YAML files are resolved. Variables which can only be
+// resolved by a deployment (params, CloudFormation references, SSM, etc) are skipped.
+func resolveEnvironment(serviceDir, stage string, environment map[string]any) []string {
+	var env []string
+	for key, value := range environment {
+		s, ok := value.(string)
+		if !ok {
+			s = fmt.Sprint(value)
+		}
+
+		resolved, ok := resolveVariables(serviceDir, stage, s)
+		if ok {
+			env = append(env, key+"="+resolved)
+		}
+	}
+	return env
+}
+
+// resolveVariables resolves the Serverless variables in the provided value. The returned
+// bool is false if any variable could not be resolved.
+func resolveVariables(serviceDir, stage, value string) (string, bool) {
+	for {
+		match := variablePattern.FindStringSubmatchIndex(value)
+		if match == nil {
+			return value, true
+		}
+
+		resolved, ok := resolveVariable(serviceDir, stage, value[match[2]:match[3]])
+		if !ok {
+			return "", false
+		}
+		value = value[:match[0]] + resolved + value[match[1]:]
+	}
+}
+
+// resolveVariable resolves a single Serverless variable, without the surrounding ${}.
+func resolveVariable(serviceDir, stage, variable string) (string, bool) {
+	if variable == "sls:stage" || variable == "opt:stage" {
+		return stage, true
+	}
+	if name, ok := strings.CutPrefix(variable, "env:"); ok {
+		return os.LookupEnv(name)
+	}
+
+	match := filePattern.FindStringSubmatch(variable)
+	if match == nil {
+		return "", false
+	}
+
+	b, err := os.ReadFile(filepath.Join(serviceDir, match[1]))
+	if err != nil {
+		return "", false
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal(b, &values); err != nil {
+		return "", false
+	}
+
+	value, ok := values[match[2]]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
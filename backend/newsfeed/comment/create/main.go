@@ -10,16 +10,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.TimelineCommenter = database.DynamoDB
+var repository = database.Repository[database.TimelineCommenter](database.DynamoDB)
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	owner := event.PathParameters["owner"]
 	if owner == "" {
 		err := errors.New(400, "Invalid request: owner is required", "")
@@ -55,18 +51,14 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	comment.CreatedAt = time.Now().Format(time.RFC3339)
 	comment.UpdatedAt = comment.CreatedAt
 
-	entry, err := repository.CreateTimelineComment(owner, id, &comment)
+	entry, err := repository.CreateTimelineComment(owner, id, &comment, database.NewTimelineCommentEvent(owner, id, &comment))
 	if err != nil {
 		return api.Failure(err), nil
 	}
 
-	if err := database.SendTimelineCommentEvent(entry, &comment); err != nil {
-		log.Error("Failed to create notification event: ", err)
-	}
-
 	return api.Success(entry), nil
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
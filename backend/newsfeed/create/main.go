@@ -13,13 +13,10 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.NewsfeedInserter](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
 	lambda.Start(handler)
 }
 
@@ -10,7 +10,7 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.NewsfeedDeleter](database.DynamoDB)
 
 func main() {
 	lambda.Start(handler)
@@ -18,7 +18,7 @@ import (
 
 const limit = 25
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.NewsfeedLister](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 type ListNewsfeedResponse struct {
@@ -28,18 +28,13 @@ type ListNewsfeedResponse struct {
 }
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(event)
-	lastFetch := getLastFetched(info.Username, event)
+	lastFetch := getLastFetched(ctx, info.Username, event)
 
 	newsfeedIdsStr := event.QueryStringParameters["newsfeedIds"]
 	if newsfeedIdsStr == "" {
@@ -76,12 +71,12 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 			continue
 		}
 
-		if err := fetchEntries(newsfeedId, startKeys[newsfeedId], lastFetch, timelineEntries, lastKeys); err != nil {
+		if err := fetchEntries(ctx, newsfeedId, startKeys[newsfeedId], lastFetch, timelineEntries, lastKeys); err != nil {
 			return api.Failure(err), nil
 		}
 	}
 
-	log.Debugf("Fetching timeline entries: %#v", timelineEntries)
+	logger.Debugf("Fetching timeline entries: %#v", timelineEntries)
 	resultEntries, err := repository.BatchGetTimelineEntries(timelineEntries)
 	if err != nil {
 		return api.Failure(err), nil
@@ -93,7 +88,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		}
 		_, err := repository.UpdateUser(info.Username, update)
 		if err != nil {
-			log.Error("Failed to update last fetched newsfeed: ", err)
+			logger.Errorf("Failed to update last fetched newsfeed: %v", err)
 		}
 	}
 
@@ -108,14 +103,15 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 // has never fetched their newsfeed or they last fetched it more than 1 week ago, then
 // a time 1 week ago is returned. If the event contains the skipLastFetched query parameter,
 // then an empty string is returned.
-func getLastFetched(username string, event api.Request) string {
+func getLastFetched(ctx context.Context, username string, event api.Request) string {
+	logger := log.FromContext(ctx)
 	if username == "" || event.QueryStringParameters["skipLastFetched"] != "" {
 		return ""
 	}
 
 	user, err := repository.GetUser(username)
 	if err != nil {
-		log.Error("Failed to fetch user: ", err)
+		logger.Errorf("Failed to fetch user: %v", err)
 		return ""
 	}
 
@@ -127,7 +123,7 @@ func getLastFetched(username string, event api.Request) string {
 
 	t, err := time.Parse(time.RFC3339, user.LastFetchedNewsfeed)
 	if err != nil {
-		log.Error("Failed to parse user's lastFetched time: ", err)
+		logger.Errorf("Failed to parse user's lastFetched time: %v", err)
 		return weekAgo.Format(time.RFC3339)
 	}
 	t = t.Add(-24 * time.Hour)
@@ -139,12 +135,13 @@ func getLastFetched(username string, event api.Request) string {
 	return t.Format(time.RFC3339)
 }
 
-func fetchEntries(newsfeedId, startKey, lastFetch string, entryMap map[string]database.TimelineEntryKey, lastKeys map[string]string) error {
+func fetchEntries(ctx context.Context, newsfeedId, startKey, lastFetch string, entryMap map[string]database.TimelineEntryKey, lastKeys map[string]string) error {
+	logger := log.FromContext(ctx)
 	newsfeedEntries, last, err := repository.ListNewsfeedEntries(newsfeedId, startKey, lastFetch, limit)
 	if err != nil {
 		return err
 	}
-	log.Debugf("Got %d entries for id %q: %v", len(newsfeedEntries), newsfeedId, newsfeedEntries)
+	logger.Debugf("Got %d entries for id %q: %v", len(newsfeedEntries), newsfeedId, newsfeedEntries)
 
 	if last != "" {
 		lastKeys[newsfeedId] = last
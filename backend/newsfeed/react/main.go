@@ -8,16 +8,12 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.TimelineReactor = database.DynamoDB
+var repository = database.Repository[database.TimelineReactor](database.DynamoDB)
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	owner := event.PathParameters["owner"]
 	if owner == "" {
 		err := errors.New(400, "Invalid request: owner is required", "")
@@ -46,20 +42,19 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	reaction.Cohort = reactor.DojoCohort
 	reaction.UpdatedAt = time.Now().Format(time.RFC3339)
 
-	entry, err := repository.SetTimelineReaction(owner, id, &reaction)
-	if err != nil {
-		return api.Failure(err), nil
+	var outbox []*database.OutboxEntry
+	if owner != reactor.Username {
+		outbox = append(outbox, database.NewTimelineReactionEvent(owner, id))
 	}
 
-	if entry.Owner != reactor.Username {
-		if err := database.SendTimelineReactionEvent(entry); err != nil {
-			log.Error("Failed to create notification: ", err)
-		}
+	entry, err := repository.SetTimelineReaction(owner, id, &reaction, outbox...)
+	if err != nil {
+		return api.Failure(err), nil
 	}
 
 	return api.Success(entry), nil
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
@@ -0,0 +1,51 @@
+// Implements a Lambda handler which lists the notification events in the outbox with
+// the provided status, oldest first. The status query parameter defaults to FAILED,
+// which lists the entries that must be replayed. PENDING lists the entries waiting to
+// be sent or retried.
+//
+// The caller must have the MANAGE_OUTBOX permission.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.OutboxReplayer](database.DynamoDB)
+
+type ListOutboxEntriesResponse struct {
+	Entries []database.OutboxEntry `json:"entries"`
+	LastKey string                 `json:"lastEvaluatedKey,omitempty"`
+}
+
+func main() {
+	lambda.Start(api.Handle(handler,
+		api.RequireUser(repository),
+		api.RequirePermission(database.Permission_ManageOutbox),
+	))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	status := database.OutboxStatus(event.QueryStringParameters["status"])
+	switch status {
+	case "":
+		status = database.OutboxStatus_Failed
+	case database.OutboxStatus_Failed, database.OutboxStatus_Pending:
+	default:
+		return api.Response{}, errors.New(400, fmt.Sprintf("Invalid request: status `%s` is not supported", status), "")
+	}
+
+	entries, lastKey, err := repository.ListOutboxEntries(status, event.QueryStringParameters["startKey"])
+	if err != nil {
+		return api.Response{}, err
+	}
+	return api.Success(&ListOutboxEntriesResponse{
+		Entries: entries,
+		LastKey: lastKey,
+	}), nil
+}
@@ -0,0 +1,136 @@
+// Implements a Lambda handler which sends the notification events in the outbox to the
+// notification event queue. Sent entries are deleted from the outbox. Entries which fail
+// to send are retried until they reach database.MaxOutboxAttempts, after which they are
+// marked as failed and must be replayed by an admin.
+//
+// The handler is triggered by the outbox table's stream, so that new entries are sent as
+// soon as they are written, and on a schedule, which retries pending entries whose earlier
+// attempts failed. Scheduled invocations have no stream records.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.OutboxRelayer](database.DynamoDB)
+
+// send sends an entry to the notification event queue. It is replaced in tests.
+var send = database.SendOutboxEntry
+
+// retryDelay is the time since a pending entry was last updated before the scheduled run
+// retries it. Newer entries are left to the stream invocation which is already sending them.
+const retryDelay = 2 * time.Minute
+
+// RelayResult summarizes a run of the handler.
+type RelayResult struct {
+	// The number of entries sent to the queue.
+	Sent int `json:"sent"`
+
+	// The number of entries which failed to send.
+	Failed int `json:"failed"`
+}
+
+func main() {
+	lambda.Start(Handler)
+}
+
+func Handler(ctx context.Context, event events.DynamoDBEvent) (*RelayResult, error) {
+	if len(event.Records) == 0 {
+		return retryPending(ctx, time.Now().Add(-retryDelay))
+	}
+
+	result := &RelayResult{}
+	for _, record := range event.Records {
+		if record.EventName != "INSERT" {
+			continue
+		}
+
+		entry := database.OutboxEntry{}
+		if err := unmarshalStreamImage(record.Change.NewImage, &entry); err != nil {
+			log.Errorf("Failed to unmarshal outbox entry: %v", err)
+			continue
+		}
+		if entry.Status != database.OutboxStatus_Pending {
+			continue
+		}
+		relay(&entry, result)
+	}
+	return result, nil
+}
+
+// retryPending sends the pending entries which were last updated before the provided time.
+func retryPending(ctx context.Context, before time.Time) (*RelayResult, error) {
+	result := &RelayResult{}
+	fetch := func(startKey string) ([]database.OutboxEntry, string, error) {
+		return repository.ListOutboxEntries(database.OutboxStatus_Pending, startKey)
+	}
+
+	for entry, err := range database.All(ctx, fetch) {
+		if err != nil {
+			return result, err
+		}
+
+		updatedAt, err := time.Parse(time.RFC3339, entry.UpdatedAt)
+		if err == nil && updatedAt.After(before) {
+			continue
+		}
+		relay(&entry, result)
+	}
+
+	log.Infof("Retried pending outbox entries: %+v", result)
+	return result, nil
+}
+
+// relay sends the provided entry and then deletes it from the outbox. If sending fails,
+// the failure is recorded on the entry instead.
+func relay(entry *database.OutboxEntry, result *RelayResult) {
+	if err := send(entry); err != nil {
+		result.Failed++
+		updated, recordErr := repository.RecordOutboxFailure(entry, err)
+		if recordErr != nil {
+			log.Errorf("Failed to record failure of outbox entry %s: %v", entry.Id, recordErr)
+		} else if updated.Status == database.OutboxStatus_Failed {
+			log.Errorf("Outbox entry %s failed after %d attempts: %v", entry.Id, updated.Attempts, err)
+		} else {
+			log.Warnf("Failed to send outbox entry %s: %v", entry.Id, err)
+		}
+		return
+	}
+
+	result.Sent++
+	if err := repository.DeleteOutboxEntry(entry); err != nil {
+		// The entry will be sent again by the next scheduled run, which consumers tolerate
+		// since delivery is at least once.
+		log.Errorf("Failed to delete sent outbox entry %s: %v", entry.Id, err)
+	}
+}
+
+// unmarshalStreamImage converts events.DynamoDBAttributeValue to struct
+func unmarshalStreamImage(attribute map[string]events.DynamoDBAttributeValue, out any) error {
+	dbAttrMap := make(map[string]*dynamodb.AttributeValue)
+
+	for k, v := range attribute {
+		var dbAttr dynamodb.AttributeValue
+		bytes, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(bytes, &dbAttr); err != nil {
+			return err
+		}
+		dbAttrMap[k] = &dbAttr
+	}
+
+	err := dynamodbattribute.UnmarshalMap(dbAttrMap, out)
+	return errors.Wrap(500, "Temporary server error", "Failed to unmarshal stream image", err)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestRetryPending(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() {
+		repository = database.DynamoDB
+		send = database.SendOutboxEntry
+	}()
+
+	alice, _ := repo.CreateUser("alice", "alice@example.com", "Alice", nil)
+	bob, _ := repo.CreateUser("bob", "bob@example.com", "Bob", nil)
+	carol, _ := repo.CreateUser("carol", "carol@example.com", "Carol", nil)
+	sent := database.NewFollowerEvent(alice, bob)
+	failed := database.NewFollowerEvent(alice, carol)
+	if _, err := repo.CreateFollower(alice, bob, sent); err != nil {
+		t.Fatalf("CreateFollower got err %v", err)
+	}
+	if _, err := repo.CreateFollower(alice, carol, failed); err != nil {
+		t.Fatalf("CreateFollower got err %v", err)
+	}
+
+	var bodies []string
+	send = func(entry *database.OutboxEntry) error {
+		if entry.Id == failed.Id {
+			return fmt.Errorf("queue unavailable")
+		}
+		bodies = append(bodies, entry.Body)
+		return nil
+	}
+
+	// Entries updated after the cutoff are left for the stream invocation.
+	result, err := retryPending(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("retryPending got err %v", err)
+	}
+	if *result != (RelayResult{}) {
+		t.Errorf("retryPending before cutoff got %+v, want no entries relayed", result)
+	}
+
+	result, err = retryPending(context.Background(), time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("retryPending got err %v", err)
+	}
+	if *result != (RelayResult{Sent: 1, Failed: 1}) {
+		t.Errorf("retryPending got %+v, want 1 sent and 1 failed", result)
+	}
+	if len(bodies) != 1 || bodies[0] != sent.Body {
+		t.Errorf("retryPending sent %v, want [%s]", bodies, sent.Body)
+	}
+
+	pending, _, err := repo.ListOutboxEntries(database.OutboxStatus_Pending, "")
+	if err != nil {
+		t.Fatalf("ListOutboxEntries got err %v", err)
+	}
+	if len(pending) != 1 || pending[0].Id != failed.Id || pending[0].Attempts != 1 {
+		t.Errorf("Pending entries after retryPending = %+v, want only the failed entry with 1 attempt", pending)
+	}
+}
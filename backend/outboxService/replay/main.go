@@ -0,0 +1,38 @@
+// Implements a Lambda handler which replays a failed notification event. The entry is
+// moved back to PENDING with its attempts reset, which triggers the relay to send it again.
+//
+// The caller must have the MANAGE_OUTBOX permission.
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.OutboxReplayer](database.DynamoDB)
+
+func main() {
+	lambda.Start(api.Handle(handler,
+		api.RequireUser(repository),
+		api.RequirePermission(database.Permission_ManageOutbox),
+	))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	id := event.PathParameters["id"]
+	if id == "" {
+		return api.Response{}, errors.New(400, "Invalid request: id is required", "")
+	}
+
+	entry, err := repository.ReplayOutboxEntry(id)
+	if err != nil {
+		return api.Response{}, err
+	}
+
+	api.Audit(repository, event, database.AuditAction_ReplayOutbox, database.OutboxAuditTarget(id), nil, entry)
+	return api.Success(entry), nil
+}
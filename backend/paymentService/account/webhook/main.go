@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/davecgh/go-spew/spew"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
@@ -15,7 +14,7 @@ import (
 	"github.com/stripe/stripe-go/v81/webhook"
 )
 
-var repository database.UserUpdater = database.DynamoDB
+var repository = database.Repository[database.UserUpdater](database.DynamoDB)
 var endpointSecret = ""
 
 func init() {
@@ -33,14 +32,12 @@ func init() {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 // Responds to Stripe webhook events.
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	signatureHeader, ok := event.Headers["stripe-signature"]
 	if !ok {
 		err := errors.New(400, "Invalid request: missing stripe signature", "")
@@ -53,15 +50,14 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
-	str := spew.Sdump(stripeEvent)
-	log.Debugf("Stripe Event: %s", str)
+	logger.Debugf("Stripe event %s with type %s", stripeEvent.ID, stripeEvent.Type)
 
 	switch stripeEvent.Type {
 	case "account.updated":
 		return handleAccountUpdated(&stripeEvent), nil
 
 	default:
-		log.Debugf("Unhandled event type: %s", stripeEvent.Type)
+		logger.Debugf("Unhandled event type: %s", stripeEvent.Type)
 	}
 
 	return api.Success(nil), nil
@@ -8,22 +8,18 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 	payment "github.com/jackstenglein/chess-dojo-scheduler/backend/paymentService"
 	"github.com/stripe/stripe-go/v81"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.UserUpdater](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	var checkoutIds map[string]string
 	if err := json.Unmarshal([]byte(event.Body), &checkoutIds); err != nil {
 		return api.Failure(errors.Wrap(400, "Invalid request: body could not be unmarshalled", "", err)), nil
@@ -8,7 +8,6 @@ import (
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/davecgh/go-spew/spew"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/analytics"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
@@ -21,7 +20,14 @@ import (
 	"github.com/stripe/stripe-go/v81/webhook"
 )
 
-var repository = database.DynamoDB
+type PaymentRecorder interface {
+	database.UserUpdater
+	database.EventLeaver
+	database.EventPaymentMarker
+	database.GameUpdater
+}
+
+var repository = database.Repository[PaymentRecorder](database.DynamoDB)
 var endpointSecret = ""
 
 func init() {
@@ -39,14 +45,12 @@ func init() {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 // handler responds to Stripe webhook events.
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	signatureHeader, ok := event.Headers["stripe-signature"]
 	if !ok {
 		err := errors.New(400, "Invalid request: missing stripe signature", "")
@@ -59,46 +63,45 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
-	str := spew.Sdump(stripeEvent)
-	log.Debugf("Stripe Event: %s", str)
+	logger.Debugf("Stripe event %s with type %s", stripeEvent.ID, stripeEvent.Type)
 
 	switch stripeEvent.Type {
 	case "checkout.session.completed":
-		return handleCheckoutSessionCompleted(&stripeEvent), nil
+		return handleCheckoutSessionCompleted(ctx, &stripeEvent), nil
 
 	case "checkout.session.expired":
-		return handleCheckoutSessionExpired(&stripeEvent), nil
+		return handleCheckoutSessionExpired(ctx, &stripeEvent), nil
 
 	case "customer.subscription.deleted":
-		return handleSubscriptionDeletion(&stripeEvent), nil
+		return handleSubscriptionDeletion(ctx, &stripeEvent), nil
 
 	case "customer.subscription.updated":
-		return handleSubscriptionUpdated(&stripeEvent), nil
+		return handleSubscriptionUpdated(ctx, &stripeEvent), nil
 
 	default:
-		log.Debugf("Unhandled event type: %s", stripeEvent.Type)
+		logger.Debugf("Unhandled event type: %s", stripeEvent.Type)
 	}
 
 	return api.Success(nil), nil
 }
 
 // Responds to Stripe checkout.session.completed events.
-func handleCheckoutSessionCompleted(event *stripe.Event) api.Response {
+func handleCheckoutSessionCompleted(ctx context.Context, event *stripe.Event) api.Response {
+	logger := log.FromContext(ctx)
 	var checkoutSession stripe.CheckoutSession
 	if err := json.Unmarshal(event.Data.Raw, &checkoutSession); err != nil {
 		err := errors.Wrap(400, "Invalid request: unable to unmarshal event data", "", err)
 		return api.Failure(err)
 	}
 
-	str := spew.Sdump(checkoutSession)
-	log.Debugf("Got checkout session: %s", str)
+	logger.Debugf("Got checkout session %s with metadata %v", checkoutSession.ID, checkoutSession.Metadata)
 
 	checkoutType := checkoutSession.Metadata["type"]
 	switch checkoutType {
 	case string(payment.CheckoutSessionType_Course):
 		return handleCoursePurchase(checkoutSession.ClientReferenceID, strings.Split(checkoutSession.Metadata["courseIds"], ","))
 	case string(payment.CheckoutSessionType_Subscription):
-		return handleSubscriptionPurchase(&checkoutSession)
+		return handleSubscriptionPurchase(ctx, &checkoutSession)
 	case string(payment.CheckoutSessionType_Coaching):
 		return handleCoachingPurchase(&checkoutSession)
 	case string(payment.CheckoutSessionType_GameReview):
@@ -138,7 +141,8 @@ func handleCoursePurchase(username string, courseIds []string) api.Response {
 }
 
 // Handles saving a subscription purchase on the user in the checkout session.
-func handleSubscriptionPurchase(checkoutSession *stripe.CheckoutSession) api.Response {
+func handleSubscriptionPurchase(ctx context.Context, checkoutSession *stripe.CheckoutSession) api.Response {
+	logger := log.FromContext(ctx)
 	if checkoutSession.ClientReferenceID == "" {
 		return api.Failure(errors.New(400, "Invalid request: no clientReferenceId included", ""))
 	}
@@ -159,16 +163,13 @@ func handleSubscriptionPurchase(checkoutSession *stripe.CheckoutSession) api.Res
 		SubscriptionTier:   stripe.String(string(tier)),
 	}
 
-	user, err := repository.UpdateUser(checkoutSession.ClientReferenceID, &update)
+	user, err := repository.UpdateUser(checkoutSession.ClientReferenceID, &update,
+		database.NewSubscriptionCreatedEvent(checkoutSession.ClientReferenceID))
 	if err != nil {
 		return api.Failure(err)
 	}
 	if err := discord.SetCohortRole(user); err != nil {
-		log.Errorf("Failed to set Discord roles: %v", err)
-	}
-
-	if err := database.SendSubscriptionCreatedEvent(user.Username); err != nil {
-		log.Errorf("Failed to send subscription created notification: %v", err)
+		logger.Errorf("Failed to set Discord roles: %v", err)
 	}
 
 	analytics.PurchaseEvent(user, checkoutSession)
@@ -217,13 +218,14 @@ func handleGameReviewPurchase(checkoutSession *stripe.CheckoutSession) api.Respo
 }
 
 // Handles a Stripe checkout session expiring.
-func handleCheckoutSessionExpired(event *stripe.Event) api.Response {
+func handleCheckoutSessionExpired(ctx context.Context, event *stripe.Event) api.Response {
+	logger := log.FromContext(ctx)
 	var checkoutSession stripe.CheckoutSession
 	if err := json.Unmarshal(event.Data.Raw, &checkoutSession); err != nil {
 		err := errors.Wrap(400, "Invalid request: unable to unmarshall event data", "", err)
 		return api.Failure(err)
 	}
-	log.Debugf("Checkout session expired: %s", spew.Sdump(checkoutSession))
+	logger.Debugf("Checkout session %s expired with metadata %v", checkoutSession.ID, checkoutSession.Metadata)
 
 	checkoutType := checkoutSession.Metadata["type"]
 	switch checkoutType {
@@ -231,7 +233,7 @@ func handleCheckoutSessionExpired(event *stripe.Event) api.Response {
 		return handleCoachingSessionExpired(&checkoutSession)
 	}
 
-	log.Debugf("Unhandled checkout session type: %s", checkoutType)
+	logger.Debugf("Unhandled checkout session type: %s", checkoutType)
 	return api.Success(nil)
 }
 
@@ -270,15 +272,15 @@ func handleCoachingSessionExpired(checkoutSession *stripe.CheckoutSession) api.R
 }
 
 // Handles deleting a subscription on the user in the subscription metadata.
-func handleSubscriptionDeletion(event *stripe.Event) api.Response {
+func handleSubscriptionDeletion(ctx context.Context, event *stripe.Event) api.Response {
+	logger := log.FromContext(ctx)
 	var subscription stripe.Subscription
 	if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
 		err := errors.Wrap(400, "Invalid request: unable to unmarshal event data", "", err)
 		return api.Failure(err)
 	}
 
-	str := spew.Sdump(subscription)
-	log.Debugf("Got subscription: %s", str)
+	logger.Debugf("Got subscription %s with status %s and metadata %v", subscription.ID, subscription.Status, subscription.Metadata)
 
 	username := subscription.Metadata["username"]
 	if username == "" {
@@ -301,24 +303,24 @@ func handleSubscriptionDeletion(event *stripe.Event) api.Response {
 		return api.Failure(err)
 	}
 	if err := discord.SetCohortRole(user); err != nil {
-		log.Errorf("Failed to set Discord roles: %v", err)
+		logger.Errorf("Failed to set Discord roles: %v", err)
 	}
 	return api.Success(nil)
 }
 
 // Handles updating a subscription on the user in the subscription metadata.
-func handleSubscriptionUpdated(event *stripe.Event) api.Response {
+func handleSubscriptionUpdated(ctx context.Context, event *stripe.Event) api.Response {
+	logger := log.FromContext(ctx)
 	var subscription stripe.Subscription
 	if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
 		err := errors.Wrap(400, "Invalid request: unable to unmarshal event data", "", err)
 		return api.Failure(err)
 	}
 
-	str := spew.Sdump(subscription)
-	log.Debugf("Got subscription: %s", str)
+	logger.Debugf("Got subscription %s with status %s and metadata %v", subscription.ID, subscription.Status, subscription.Metadata)
 
 	if subscription.Status != "active" {
-		log.Infof("Subscription has status %q, so no action is necessary", subscription.Status)
+		logger.Infof("Subscription has status %q, so no action is necessary", subscription.Status)
 		return api.Success(nil)
 	}
 
@@ -347,7 +349,7 @@ func handleSubscriptionUpdated(event *stripe.Event) api.Response {
 		return api.Failure(err)
 	}
 	if err := discord.SetCohortRole(user); err != nil {
-		log.Errorf("Failed to set Discord roles: %v", err)
+		logger.Errorf("Failed to set Discord roles: %v", err)
 	}
 
 	return api.Success(nil)
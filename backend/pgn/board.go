@@ -0,0 +1,457 @@
+package pgn
+
+// Color is the color of a piece or of the side to move.
+type Color uint8
+
+const (
+	White Color = iota
+	Black
+)
+
+// Other returns the opposite color.
+func (c Color) Other() Color {
+	return c ^ 1
+}
+
+// String returns white or black.
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+// PieceType is the type of a piece, regardless of its color.
+type PieceType uint8
+
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+// Piece is a colored piece on the board. The zero value is an empty square.
+type Piece uint8
+
+const NoPiece Piece = 0
+
+// NewPiece returns the piece with the provided color and type.
+func NewPiece(c Color, t PieceType) Piece {
+	return Piece(uint8(t) | uint8(c)<<3)
+}
+
+// Type returns the piece's type.
+func (p Piece) Type() PieceType {
+	return PieceType(p & 7)
+}
+
+// Color returns the piece's color. The color of NoPiece is White.
+func (p Piece) Color() Color {
+	return Color(p >> 3)
+}
+
+// Square is an index into the board, from 0 (a1) to 63 (h8).
+type Square int8
+
+const NoSquare Square = -1
+
+// NewSquare returns the square on the provided file and rank, both from 0 to 7.
+func NewSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+// File returns the square's file, from 0 (a) to 7 (h).
+func (s Square) File() int {
+	return int(s) % 8
+}
+
+// Rank returns the square's rank, from 0 (1) to 7 (8).
+func (s Square) Rank() int {
+	return int(s) / 8
+}
+
+// String returns the square in algebraic notation, such as e4.
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+// parseSquare returns the square with the provided algebraic name, or NoSquare if the
+// name is invalid.
+func parseSquare(s string) Square {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return NoSquare
+	}
+	return NewSquare(int(s[0]-'a'), int(s[1]-'1'))
+}
+
+// CastlingRights is a bit set of the castling moves which are still available.
+type CastlingRights uint8
+
+const (
+	WhiteKingside CastlingRights = 1 << iota
+	WhiteQueenside
+	BlackKingside
+	BlackQueenside
+)
+
+// Move is a single move on the board. Null moves have From and To set to NoSquare.
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+}
+
+// NullMove passes the turn to the other side without moving a piece.
+var NullMove = Move{From: NoSquare, To: NoSquare}
+
+// IsNull returns true if the move is a null move.
+func (m Move) IsNull() bool {
+	return m.From == NoSquare
+}
+
+// Position is a chess position, including the state needed to generate legal moves.
+type Position struct {
+	board     [64]Piece
+	turn      Color
+	castling  CastlingRights
+	enPassant Square
+
+	// The number of plies since the last capture or pawn move.
+	halfmoveClock int
+
+	// The number of the current full move, starting at 1 and incremented after Black moves.
+	fullmoveNumber int
+}
+
+// Piece returns the piece on the provided square.
+func (p *Position) Piece(s Square) Piece {
+	return p.board[s]
+}
+
+// Turn returns the color to move.
+func (p *Position) Turn() Color {
+	return p.turn
+}
+
+// FullmoveNumber returns the number of the current full move.
+func (p *Position) FullmoveNumber() int {
+	return p.fullmoveNumber
+}
+
+var (
+	knightOffsets = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [8][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	bishopDirs    = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirs      = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+)
+
+// offset returns the square df files and dr ranks away from s, or NoSquare if it is off the board.
+func offset(s Square, df, dr int) Square {
+	f, r := s.File()+df, s.Rank()+dr
+	if f < 0 || f > 7 || r < 0 || r > 7 {
+		return NoSquare
+	}
+	return NewSquare(f, r)
+}
+
+// kingSquare returns the square of the provided color's king, or NoSquare if it has none.
+func (p *Position) kingSquare(c Color) Square {
+	king := NewPiece(c, King)
+	for s := Square(0); s < 64; s++ {
+		if p.board[s] == king {
+			return s
+		}
+	}
+	return NoSquare
+}
+
+// isAttacked returns true if the provided square is attacked by a piece of the provided color.
+func (p *Position) isAttacked(s Square, by Color) bool {
+	pawnRank := -1
+	if by == Black {
+		pawnRank = 1
+	}
+	for _, df := range []int{-1, 1} {
+		if t := offset(s, df, pawnRank); t != NoSquare && p.board[t] == NewPiece(by, Pawn) {
+			return true
+		}
+	}
+
+	for _, o := range knightOffsets {
+		if t := offset(s, o[0], o[1]); t != NoSquare && p.board[t] == NewPiece(by, Knight) {
+			return true
+		}
+	}
+	for _, o := range kingOffsets {
+		if t := offset(s, o[0], o[1]); t != NoSquare && p.board[t] == NewPiece(by, King) {
+			return true
+		}
+	}
+
+	if p.slidingAttack(s, by, bishopDirs[:], Bishop) || p.slidingAttack(s, by, rookDirs[:], Rook) {
+		return true
+	}
+	return false
+}
+
+// slidingAttack returns true if the provided square is attacked along the provided directions by
+// a queen or a piece of the provided type and color.
+func (p *Position) slidingAttack(s Square, by Color, dirs [][2]int, t PieceType) bool {
+	for _, d := range dirs {
+		for u := offset(s, d[0], d[1]); u != NoSquare; u = offset(u, d[0], d[1]) {
+			piece := p.board[u]
+			if piece == NoPiece {
+				continue
+			}
+			if piece.Color() == by && (piece.Type() == t || piece.Type() == Queen) {
+				return true
+			}
+			break
+		}
+	}
+	return false
+}
+
+// InCheck returns true if the side to move is in check.
+func (p *Position) InCheck() bool {
+	k := p.kingSquare(p.turn)
+	return k != NoSquare && p.isAttacked(k, p.turn.Other())
+}
+
+// LegalMoves returns every legal move in the position.
+func (p *Position) LegalMoves() []Move {
+	var moves []Move
+	for _, m := range p.pseudoLegalMoves() {
+		next := p.apply(m)
+		if k := next.kingSquare(p.turn); k == NoSquare || !next.isAttacked(k, p.turn.Other()) {
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+// pseudoLegalMoves returns every move in the position, including those which leave the side
+// to move in check. Castling moves are only returned if the king does not pass through check.
+func (p *Position) pseudoLegalMoves() []Move {
+	var moves []Move
+	for s := Square(0); s < 64; s++ {
+		piece := p.board[s]
+		if piece == NoPiece || piece.Color() != p.turn {
+			continue
+		}
+
+		switch piece.Type() {
+		case Pawn:
+			moves = p.appendPawnMoves(moves, s)
+		case Knight:
+			moves = p.appendStepMoves(moves, s, knightOffsets[:])
+		case Bishop:
+			moves = p.appendSlidingMoves(moves, s, bishopDirs[:])
+		case Rook:
+			moves = p.appendSlidingMoves(moves, s, rookDirs[:])
+		case Queen:
+			moves = p.appendSlidingMoves(moves, s, bishopDirs[:])
+			moves = p.appendSlidingMoves(moves, s, rookDirs[:])
+		case King:
+			moves = p.appendStepMoves(moves, s, kingOffsets[:])
+			moves = p.appendCastlingMoves(moves, s)
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendPawnMoves(moves []Move, s Square) []Move {
+	dir, startRank, lastRank := 1, 1, 7
+	if p.turn == Black {
+		dir, startRank, lastRank = -1, 6, 0
+	}
+
+	add := func(to Square) {
+		if to.Rank() == lastRank {
+			for _, t := range []PieceType{Queen, Rook, Bishop, Knight} {
+				moves = append(moves, Move{From: s, To: to, Promotion: t})
+			}
+		} else {
+			moves = append(moves, Move{From: s, To: to})
+		}
+	}
+
+	if one := offset(s, 0, dir); one != NoSquare && p.board[one] == NoPiece {
+		add(one)
+		if two := offset(s, 0, 2*dir); s.Rank() == startRank && p.board[two] == NoPiece {
+			add(two)
+		}
+	}
+	for _, df := range []int{-1, 1} {
+		to := offset(s, df, dir)
+		if to == NoSquare {
+			continue
+		}
+		if target := p.board[to]; (target != NoPiece && target.Color() != p.turn) || to == p.enPassant {
+			add(to)
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendStepMoves(moves []Move, s Square, offsets [][2]int) []Move {
+	for _, o := range offsets {
+		to := offset(s, o[0], o[1])
+		if to == NoSquare {
+			continue
+		}
+		if target := p.board[to]; target == NoPiece || target.Color() != p.turn {
+			moves = append(moves, Move{From: s, To: to})
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendSlidingMoves(moves []Move, s Square, dirs [][2]int) []Move {
+	for _, d := range dirs {
+		for to := offset(s, d[0], d[1]); to != NoSquare; to = offset(to, d[0], d[1]) {
+			target := p.board[to]
+			if target == NoPiece || target.Color() != p.turn {
+				moves = append(moves, Move{From: s, To: to})
+			}
+			if target != NoPiece {
+				break
+			}
+		}
+	}
+	return moves
+}
+
+func (p *Position) appendCastlingMoves(moves []Move, s Square) []Move {
+	rank, kingside, queenside := 0, WhiteKingside, WhiteQueenside
+	if p.turn == Black {
+		rank, kingside, queenside = 7, BlackKingside, BlackQueenside
+	}
+	if s != NewSquare(4, rank) || p.isAttacked(s, p.turn.Other()) {
+		return moves
+	}
+
+	rook := NewPiece(p.turn, Rook)
+	if p.castling&kingside != 0 && p.board[NewSquare(7, rank)] == rook &&
+		p.isEmptyAndSafe(rank, 5, 6) {
+		moves = append(moves, Move{From: s, To: NewSquare(6, rank)})
+	}
+	if p.castling&queenside != 0 && p.board[NewSquare(0, rank)] == rook &&
+		p.board[NewSquare(1, rank)] == NoPiece && p.isEmptyAndSafe(rank, 3, 2) {
+		moves = append(moves, Move{From: s, To: NewSquare(2, rank)})
+	}
+	return moves
+}
+
+// isEmptyAndSafe returns true if the squares on the provided rank and files are empty and not
+// attacked by the side not to move.
+func (p *Position) isEmptyAndSafe(rank int, files ...int) bool {
+	for _, f := range files {
+		s := NewSquare(f, rank)
+		if p.board[s] != NoPiece || p.isAttacked(s, p.turn.Other()) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLegal returns true if the provided move is legal in the position.
+func (p *Position) isLegal(m Move) bool {
+	if m.IsNull() {
+		return !p.InCheck()
+	}
+	for _, legal := range p.LegalMoves() {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the position after the provided move, which must be pseudo-legal.
+func (p *Position) apply(m Move) *Position {
+	next := *p
+	next.turn = p.turn.Other()
+	next.enPassant = NoSquare
+	if p.turn == Black {
+		next.fullmoveNumber++
+	}
+	if m.IsNull() {
+		next.halfmoveClock++
+		return &next
+	}
+
+	piece := p.board[m.From]
+	captured := p.board[m.To]
+	next.board[m.From] = NoPiece
+	next.board[m.To] = piece
+
+	switch piece.Type() {
+	case Pawn:
+		if m.To == p.enPassant {
+			next.board[NewSquare(m.To.File(), m.From.Rank())] = NoPiece
+		}
+		if d := m.To.Rank() - m.From.Rank(); d == 2 || d == -2 {
+			next.enPassant = NewSquare(m.From.File(), (m.From.Rank()+m.To.Rank())/2)
+		}
+		if m.Promotion != NoPieceType {
+			next.board[m.To] = NewPiece(p.turn, m.Promotion)
+		}
+	case King:
+		if d := m.To.File() - m.From.File(); d == 2 || d == -2 {
+			rookFrom, rookTo := NewSquare(7, m.From.Rank()), NewSquare(5, m.From.Rank())
+			if d < 0 {
+				rookFrom, rookTo = NewSquare(0, m.From.Rank()), NewSquare(3, m.From.Rank())
+			}
+			next.board[rookTo] = next.board[rookFrom]
+			next.board[rookFrom] = NoPiece
+		}
+	}
+
+	if piece.Type() == Pawn || captured != NoPiece {
+		next.halfmoveClock = 0
+	} else {
+		next.halfmoveClock++
+	}
+	next.castling &^= castlingMask(m.From) | castlingMask(m.To)
+	return &next
+}
+
+// castlingMask returns the castling rights lost when a piece moves from or to the provided square.
+func castlingMask(s Square) CastlingRights {
+	switch s {
+	case NewSquare(4, 0):
+		return WhiteKingside | WhiteQueenside
+	case NewSquare(7, 0):
+		return WhiteKingside
+	case NewSquare(0, 0):
+		return WhiteQueenside
+	case NewSquare(4, 7):
+		return BlackKingside | BlackQueenside
+	case NewSquare(7, 7):
+		return BlackKingside
+	case NewSquare(0, 7):
+		return BlackQueenside
+	}
+	return 0
+}
+
+// Play returns the position after the provided move. It returns an error if the move is illegal.
+func (p *Position) Play(m Move) (*Position, error) {
+	if !p.isLegal(m) {
+		return nil, errorf("illegal move %s%s", m.From, m.To)
+	}
+	return p.apply(m), nil
+}
+
+// IsCheckmate returns true if the side to move is in check and has no legal moves.
+func (p *Position) IsCheckmate() bool {
+	return p.InCheck() && len(p.LegalMoves()) == 0
+}
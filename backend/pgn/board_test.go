@@ -0,0 +1,194 @@
+package pgn
+
+import (
+	"testing"
+)
+
+func perft(p *Position, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	moves := p.LegalMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	count := 0
+	for _, m := range moves {
+		count += perft(p.apply(m), depth-1)
+	}
+	return count
+}
+
+func TestPerft(t *testing.T) {
+	table := []struct {
+		name  string
+		fen   string
+		depth int
+		want  int
+	}{
+		{name: "Start", fen: StartingFEN, depth: 3, want: 8902},
+		{name: "Kiwipete", fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", depth: 2, want: 2039},
+		{name: "Endgame", fen: "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", depth: 4, want: 43238},
+		{name: "Promotions", fen: "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", depth: 3, want: 9467},
+		{name: "Talkchess", fen: "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", depth: 2, want: 1486},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseFEN(tc.fen)
+			if err != nil {
+				t.Fatalf("ParseFEN got err %v", err)
+			}
+			if got := perft(p, tc.depth); got != tc.want {
+				t.Errorf("perft(%d) got %d, want %d", tc.depth, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFEN(t *testing.T) {
+	table := []struct {
+		name    string
+		fen     string
+		want    string
+		wantErr bool
+	}{
+		{name: "Start", fen: StartingFEN, want: StartingFEN},
+		{name: "FourFields", fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3", want: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{name: "StaleCastlingRights", fen: "4k3/8/8/8/8/8/8/4K2R w KQkq - 3 40", want: "4k3/8/8/8/8/8/8/4K2R w K - 3 40"},
+		{name: "FiveFields", fen: "4k3/8/8/8/8/8/8/4K3 w - - 0", wantErr: true},
+		{name: "SevenRanks", fen: "4k3/8/8/8/8/8/4K3 w - - 0 1", wantErr: true},
+		{name: "LongRank", fen: "4k3/8/8/8/8/8/8/4K3p w - - 0 1", wantErr: true},
+		{name: "ShortRank", fen: "4k3/8/8/8/8/8/8/4K2 w - - 0 1", wantErr: true},
+		{name: "InvalidPiece", fen: "4k3/8/8/8/8/8/8/4K2X w - - 0 1", wantErr: true},
+		{name: "InvalidTurn", fen: "4k3/8/8/8/8/8/8/4K3 x - - 0 1", wantErr: true},
+		{name: "NoKing", fen: "8/8/8/8/8/8/8/4K3 w - - 0 1", wantErr: true},
+		{name: "TwoKings", fen: "4k3/8/8/8/8/8/8/3KK3 w - - 0 1", wantErr: true},
+		{name: "PawnOnFirstRank", fen: "4k3/8/8/8/8/8/8/P3K3 w - - 0 1", wantErr: true},
+		{name: "NinePawns", fen: "4k3/8/8/8/8/P7/PPPPPPPP/4K3 w - - 0 1", wantErr: true},
+		{name: "SeventeenPieces", fen: "rnbqkbnr/pppppppp/8/8/8/N7/PPPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: true},
+		{name: "TooManyPromotions", fen: "4k3/8/8/8/8/QQQQ4/PPPPPPPP/4K3 w - - 0 1", wantErr: true},
+		{name: "Promotions", fen: "4k3/8/8/8/8/QQQQ4/PPPP4/4K3 w - - 0 1", want: "4k3/8/8/8/8/QQQQ4/PPPP4/4K3 w - - 0 1"},
+		{name: "KingCapturable", fen: "4k3/8/8/8/8/8/8/4R1K1 w - - 0 1", wantErr: true},
+		{name: "InvalidEnPassant", fen: "4k3/8/8/8/8/8/8/4K3 w - e6 0 1", wantErr: true},
+		{name: "InvalidFullmove", fen: "4k3/8/8/8/8/8/8/4K3 w - - 0 0", wantErr: true},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseFEN(tc.fen)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseFEN got err %v, want error %t", err, tc.wantErr)
+			}
+			if err == nil && p.FEN() != tc.want {
+				t.Errorf("FEN got %q, want %q", p.FEN(), tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFEN(t *testing.T) {
+	table := []struct {
+		name    string
+		fen     string
+		want    string
+		wantErr bool
+	}{
+		{name: "Start", fen: StartingFEN, want: StartingFEN},
+		{name: "Clocks", fen: "4k3/8/8/8/8/8/8/4K3 b - - 12 57", want: "4k3/8/8/8/8/8/8/4K3 b - - 0 1"},
+		{name: "FourFields", fen: "4k3/8/8/8/8/8/8/4K3 w - -", want: "4k3/8/8/8/8/8/8/4K3 w - - 0 1"},
+		{name: "CastlingOrder", fen: "r3k2r/8/8/8/8/8/8/R3K2R w qkQK - 0 1", want: "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1"},
+		{name: "StaleCastlingRights", fen: "4k3/8/8/8/8/8/8/4K2R w KQkq - 3 40", want: "4k3/8/8/8/8/8/8/4K2R w K - 0 1"},
+		{name: "EnPassantWithoutCapture", fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", want: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"},
+		{name: "EnPassantCapture", fen: "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3", want: "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{name: "EnPassantPinned", fen: "8/8/8/KPp4r/8/8/8/6k1 w - c6 0 1", want: "8/8/8/KPp4r/8/8/8/6k1 w - - 0 1"},
+		{name: "Invalid", fen: "4k3/8/8/8/8/8/8/4K3 w - e6 0 1", wantErr: true},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeFEN(tc.fen)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NormalizeFEN got err %v, want error %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("NormalizeFEN got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSAN(t *testing.T) {
+	table := []struct {
+		name string
+		fen  string
+		move string
+		want string
+	}{
+		{name: "Pawn", fen: StartingFEN, move: "e4", want: "e4"},
+		{name: "Knight", fen: StartingFEN, move: "Nf3", want: "Nf3"},
+		{name: "Coordinate", fen: StartingFEN, move: "g1f3", want: "Nf3"},
+		{name: "CoordinateDash", fen: StartingFEN, move: "e2-e4", want: "e4"},
+		{name: "Annotated", fen: StartingFEN, move: "e4!?", want: "e4"},
+		{name: "CastleZeros", fen: "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", move: "0-0-0", want: "O-O-O"},
+		{name: "CastleCheck", fen: "5k2/8/8/8/8/8/8/4K2R w K - 0 1", move: "O-O", want: "O-O+"},
+		{name: "FileDisambiguation", fen: "4k3/8/8/8/8/8/8/R4RK1 w - - 0 1", move: "Rad1", want: "Rad1"},
+		{name: "RookRankDisambiguation", fen: "4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", move: "R1a4", want: "R1a4"},
+		{name: "SquareDisambiguation", fen: "4k3/8/8/8/8/Q1Q5/8/Q3K3 w - - 0 1", move: "Qa3b2", want: "Qa3b2"},
+		{name: "OverDisambiguated", fen: "4k3/8/8/8/8/8/8/4K1N1 w - - 0 1", move: "Ng1f3", want: "Nf3"},
+		{name: "Capture", fen: "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", move: "exd5", want: "exd5"},
+		{name: "EnPassant", fen: "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", move: "exd6e.p.", want: "exd6"},
+		{name: "Promotion", fen: "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1", move: "e8=Q", want: "e8=Q"},
+		{name: "PromotionWithoutEquals", fen: "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1", move: "e8N+", want: "e8=N+"},
+		{name: "Mate", fen: "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1", move: "Ra8", want: "Ra8#"},
+		{name: "Null", fen: StartingFEN, move: "--", want: "--"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseFEN(tc.fen)
+			if err != nil {
+				t.Fatalf("ParseFEN got err %v", err)
+			}
+			m, err := p.ParseSAN(tc.move)
+			if err != nil {
+				t.Fatalf("ParseSAN(%q) got err %v", tc.move, err)
+			}
+			if got := p.SAN(m); got != tc.want {
+				t.Errorf("SAN got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	table := []struct {
+		name string
+		fen  string
+		move string
+	}{
+		{name: "Illegal", fen: StartingFEN, move: "e5"},
+		{name: "Ambiguous", fen: "4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", move: "Ra4"},
+		{name: "Invalid", fen: StartingFEN, move: "Zz9"},
+		{name: "CastleThroughCheck", fen: "4kr2/8/8/8/8/8/8/4K2R w K - 0 1", move: "O-O"},
+		{name: "CastleWithoutRights", fen: "4k3/8/8/8/8/8/8/4K2R w - - 0 1", move: "O-O"},
+		{name: "IntoCheck", fen: "4k3/8/8/8/8/8/4r3/4K3 w - - 0 1", move: "Kd2"},
+		{name: "Pinned", fen: "4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1", move: "Nf4"},
+		{name: "NullInCheck", fen: "4k3/8/8/8/8/8/4r3/4K3 w - - 0 1", move: "--"},
+		{name: "PromoteToKing", fen: "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1", move: "e8=K"},
+		{name: "MissingPromotion", fen: "8/4P1k1/8/8/8/8/8/4K3 w - - 0 1", move: "e8"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParseFEN(tc.fen)
+			if err != nil {
+				t.Fatalf("ParseFEN got err %v", err)
+			}
+			if m, err := p.ParseSAN(tc.move); err == nil {
+				t.Errorf("ParseSAN(%q) got move %v, want error", tc.move, m)
+			}
+		})
+	}
+}
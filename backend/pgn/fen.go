@@ -0,0 +1,292 @@
+package pgn
+
+import (
+	"strconv"
+	"strings"
+)
+
+// StartingFEN is the FEN of the standard starting position.
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var pieceLetters = map[byte]PieceType{
+	'p': Pawn,
+	'n': Knight,
+	'b': Bishop,
+	'r': Rook,
+	'q': Queen,
+	'k': King,
+}
+
+// letter returns the FEN letter of the piece.
+func (p Piece) letter() byte {
+	for l, t := range pieceLetters {
+		if t == p.Type() {
+			if p.Color() == White {
+				return l - 'a' + 'A'
+			}
+			return l
+		}
+	}
+	return '?'
+}
+
+// ParseFEN returns the position described by the provided FEN. The halfmove clock and fullmove
+// number may be omitted, in which case they default to 0 and 1. An error is returned if the FEN
+// is malformed or describes an impossible position.
+func ParseFEN(fen string) (*Position, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, errorf("FEN must have 4 or 6 fields, got %d", len(fields))
+	}
+
+	p := &Position{enPassant: NoSquare, fullmoveNumber: 1}
+	if err := p.parsePlacement(fields[0]); err != nil {
+		return nil, err
+	}
+
+	switch fields[1] {
+	case "w":
+		p.turn = White
+	case "b":
+		p.turn = Black
+	default:
+		return nil, errorf("FEN has invalid side to move %q", fields[1])
+	}
+
+	if err := p.parseCastling(fields[2]); err != nil {
+		return nil, err
+	}
+	if err := p.parseEnPassant(fields[3]); err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 6 {
+		halfmove, err := strconv.Atoi(fields[4])
+		if err != nil || halfmove < 0 {
+			return nil, errorf("FEN has invalid halfmove clock %q", fields[4])
+		}
+		fullmove, err := strconv.Atoi(fields[5])
+		if err != nil || fullmove < 1 {
+			return nil, errorf("FEN has invalid fullmove number %q", fields[5])
+		}
+		p.halfmoveClock, p.fullmoveNumber = halfmove, fullmove
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Position) parsePlacement(placement string) error {
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return errorf("FEN must have 8 ranks, got %d", len(ranks))
+	}
+
+	for i, rank := range ranks {
+		r, f := 7-i, 0
+		for j := 0; j < len(rank); j++ {
+			c := rank[j]
+			switch {
+			case c >= '1' && c <= '8':
+				f += int(c - '0')
+			case pieceLetters[c|0x20] != NoPieceType:
+				if f > 7 {
+					return errorf("FEN rank %d has more than 8 squares", r+1)
+				}
+				color := White
+				if c >= 'a' {
+					color = Black
+				}
+				p.board[NewSquare(f, r)] = NewPiece(color, pieceLetters[c|0x20])
+				f++
+			default:
+				return errorf("FEN has invalid character %q", c)
+			}
+		}
+		if f != 8 {
+			return errorf("FEN rank %d has %d squares, want 8", r+1, f)
+		}
+	}
+	return nil
+}
+
+// parseCastling sets the position's castling rights. Rights whose king or rook are not on their
+// starting squares are ignored, since many FENs in the wild include them.
+func (p *Position) parseCastling(castling string) error {
+	if castling == "-" {
+		return nil
+	}
+	for _, c := range castling {
+		var right CastlingRights
+		var color Color
+		var rookFile int
+		switch c {
+		case 'K':
+			right, color, rookFile = WhiteKingside, White, 7
+		case 'Q':
+			right, color, rookFile = WhiteQueenside, White, 0
+		case 'k':
+			right, color, rookFile = BlackKingside, Black, 7
+		case 'q':
+			right, color, rookFile = BlackQueenside, Black, 0
+		default:
+			return errorf("FEN has invalid castling rights %q", castling)
+		}
+
+		rank := 0
+		if color == Black {
+			rank = 7
+		}
+		if p.board[NewSquare(4, rank)] == NewPiece(color, King) && p.board[NewSquare(rookFile, rank)] == NewPiece(color, Rook) {
+			p.castling |= right
+		}
+	}
+	return nil
+}
+
+func (p *Position) parseEnPassant(enPassant string) error {
+	if enPassant == "-" {
+		return nil
+	}
+
+	s := parseSquare(enPassant)
+	rank, dir := 5, -1
+	if p.turn == Black {
+		rank, dir = 2, 1
+	}
+	if s == NoSquare || s.Rank() != rank {
+		return errorf("FEN has invalid en passant square %q", enPassant)
+	}
+	if p.board[s] != NoPiece || p.board[offset(s, 0, -dir)] != NoPiece ||
+		p.board[offset(s, 0, dir)] != NewPiece(p.turn.Other(), Pawn) {
+		return errorf("FEN has en passant square %q but no pawn just moved two squares", enPassant)
+	}
+	p.enPassant = s
+	return nil
+}
+
+// validate returns an error if the position is impossible: each side must have exactly one king,
+// at most 16 pieces and 8 pawns, no more promoted pieces than missing pawns, no pawns on the first
+// or last rank, and the side not to move cannot be in check.
+func (p *Position) validate() error {
+	var counts [2][King + 1]int
+	for s := Square(0); s < 64; s++ {
+		piece := p.board[s]
+		if piece == NoPiece {
+			continue
+		}
+		counts[piece.Color()][piece.Type()]++
+		if piece.Type() == Pawn && (s.Rank() == 0 || s.Rank() == 7) {
+			return errorf("FEN has a pawn on %s", s)
+		}
+	}
+	if counts[White][King] != 1 || counts[Black][King] != 1 {
+		return errorf("FEN must have exactly one king of each color")
+	}
+
+	for _, color := range []Color{White, Black} {
+		c := counts[color]
+		total := 0
+		for _, n := range c {
+			total += n
+		}
+		if total > 16 {
+			return errorf("FEN has %d %s pieces, want at most 16", total, color)
+		}
+		if c[Pawn] > 8 {
+			return errorf("FEN has %d %s pawns, want at most 8", c[Pawn], color)
+		}
+		promoted := max(c[Knight]-2, 0) + max(c[Bishop]-2, 0) + max(c[Rook]-2, 0) + max(c[Queen]-1, 0)
+		if promoted > 8-c[Pawn] {
+			return errorf("FEN has too many %s pieces for %d pawns", color, c[Pawn])
+		}
+	}
+
+	if p.isAttacked(p.kingSquare(p.turn.Other()), p.turn) {
+		return errorf("FEN has the side not to move in check")
+	}
+	return nil
+}
+
+// NormalizeFEN returns the normalized form of the provided FEN, or an error if it is malformed
+// or describes an impossible position. Two FENs of the same position always normalize to the
+// same string: castling rights are listed in KQkq order and only if the king and rook are on
+// their starting squares, the en passant square is kept only if an en passant capture is legal,
+// and the halfmove clock and fullmove number are reset to 0 and 1.
+func NormalizeFEN(fen string) (string, error) {
+	p, err := ParseFEN(fen)
+	if err != nil {
+		return "", err
+	}
+	return p.NormalizedFEN(), nil
+}
+
+// NormalizedFEN returns the normalized FEN of the position. See NormalizeFEN.
+func (p *Position) NormalizedFEN() string {
+	n := *p
+	n.halfmoveClock, n.fullmoveNumber = 0, 1
+	if n.enPassant != NoSquare && !n.canCaptureEnPassant() {
+		n.enPassant = NoSquare
+	}
+	return n.FEN()
+}
+
+// canCaptureEnPassant returns true if the side to move has a legal en passant capture.
+func (p *Position) canCaptureEnPassant() bool {
+	for _, m := range p.LegalMoves() {
+		if m.To == p.enPassant && p.board[m.From].Type() == Pawn {
+			return true
+		}
+	}
+	return false
+}
+
+// FEN returns the FEN of the position.
+func (p *Position) FEN() string {
+	var sb strings.Builder
+	for r := 7; r >= 0; r-- {
+		empty := 0
+		for f := 0; f < 8; f++ {
+			piece := p.board[NewSquare(f, r)]
+			if piece == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(piece.letter())
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if r > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	if p.turn == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	castling := ""
+	for i, c := range "KQkq" {
+		if p.castling&(1<<i) != 0 {
+			castling += string(c)
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	sb.WriteString(castling)
+
+	sb.WriteByte(' ')
+	sb.WriteString(p.enPassant.String())
+	sb.WriteString(" " + strconv.Itoa(p.halfmoveClock) + " " + strconv.Itoa(p.fullmoveNumber))
+	return sb.String()
+}
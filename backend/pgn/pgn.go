@@ -0,0 +1,694 @@
+// Package pgn parses, validates and normalizes PGNs using a legal-move chess board.
+//
+// The parser accepts the PGN export format along with the deviations commonly produced by
+// chess sites and GUIs: missing tags and results, move numbers without spaces, symbolic
+// annotations, coordinate notation and null moves. Every move, including moves in variations,
+// is checked for legality.
+package pgn
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxVariationDepth is the maximum nesting depth of variations.
+const maxVariationDepth = 64
+
+// Error is returned when a PGN or FEN is invalid.
+type Error struct {
+	// The reason the PGN or FEN is invalid.
+	Reason string
+
+	// The move at which the error occurred, such as 12... for Black's 12th move. Empty if the
+	// error did not occur at a move.
+	Move string
+
+	// The 1-based index of the game in which the error occurred. 0 if the error did not occur
+	// in a game.
+	Game int
+}
+
+func (e *Error) Error() string {
+	msg := e.Reason
+	if e.Move != "" {
+		msg = fmt.Sprintf("%s at move %s", msg, e.Move)
+	}
+	if e.Game > 1 {
+		msg = fmt.Sprintf("game %d: %s", e.Game, msg)
+	}
+	return msg
+}
+
+func errorf(format string, args ...any) *Error {
+	return &Error{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Tag is a single tag pair of a PGN, such as [White "Magnus Carlsen"].
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Game is a single parsed PGN.
+type Game struct {
+	// The tag pairs of the game, in the order they appeared.
+	Tags []Tag
+
+	// The comment before the first move of the game.
+	Comment string
+
+	// The main line of the game.
+	Moves []*Node
+
+	// The game termination marker: 1-0, 0-1, 1/2-1/2 or *. Empty if the movetext did not have one.
+	Result string
+
+	start *Position
+}
+
+// Tag returns the value of the tag with the provided name, or the empty string if the game
+// does not have the tag.
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// Start returns the starting position of the game.
+func (g *Game) Start() *Position {
+	return g.start
+}
+
+// Node is a single move in a game, along with its annotations and alternatives.
+type Node struct {
+	// The move played.
+	Move Move
+
+	// The move in standard algebraic notation.
+	SAN string
+
+	// The numeric annotation glyphs of the move. Symbolic annotations such as !? are
+	// converted to their numeric equivalents.
+	NAGs []int
+
+	// The comment before the move. Only set on the first move of a variation.
+	CommentBefore string
+
+	// The comment after the move.
+	Comment string
+
+	// The alternatives to this move, each starting from the position before the move.
+	Variations [][]*Node
+
+	position *Position
+}
+
+// Position returns the position after the move.
+func (n *Node) Position() *Position {
+	return n.position
+}
+
+// FEN returns the FEN of the position after the move.
+func (n *Node) FEN() string {
+	return n.position.FEN()
+}
+
+// number returns the move number of the move, such as 12. or 12... for Black's 12th move.
+func (n *Node) number() string {
+	if n.position.turn == Black {
+		return strconv.Itoa(n.position.fullmoveNumber) + "."
+	}
+	return strconv.Itoa(n.position.fullmoveNumber-1) + "..."
+}
+
+var commandRegex = regexp.MustCompile(`\[%(\w+)\s+([^\]]*)\]`)
+
+// Command returns the value of the provided embedded command in the move's comment, such as
+// [%clk 1:02:03]. It returns false if the comment does not have the command.
+func (n *Node) Command(name string) (string, bool) {
+	for _, match := range commandRegex.FindAllStringSubmatch(n.Comment, -1) {
+		if match[1] == name {
+			return strings.TrimSpace(match[2]), true
+		}
+	}
+	return "", false
+}
+
+// Clock returns the clock time remaining after the move from the move's [%clk] command. It
+// returns false if the move does not have a valid clock command.
+func (n *Node) Clock() (time.Duration, bool) {
+	value, ok := n.Command("clk")
+	if !ok {
+		return 0, false
+	}
+	return parseClock(value)
+}
+
+// parseClock returns the duration of a clock command value in the format h:mm:ss, with optional
+// fractional seconds.
+func parseClock(value string) (time.Duration, bool) {
+	tokens := strings.Split(value, ":")
+	if len(tokens) < 2 || len(tokens) > 3 {
+		return 0, false
+	}
+
+	minutes := 0
+	for _, token := range tokens[:len(tokens)-1] {
+		n, err := strconv.Atoi(token)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		minutes = minutes*60 + n
+	}
+	seconds, err := strconv.ParseFloat(tokens[len(tokens)-1], 64)
+	if err != nil || seconds < 0 || seconds >= 60 {
+		return 0, false
+	}
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second)), true
+}
+
+// Parse returns every game in the provided PGN text. An error is returned if the text has no
+// games or if any game is malformed or has an illegal move.
+func Parse(text string) ([]*Game, error) {
+	return parse(text, nil)
+}
+
+// ParseGame returns the single game in the provided PGN text.
+func ParseGame(text string) (*Game, error) {
+	games, err := parse(text, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(games) != 1 {
+		return nil, errorf("PGN must have exactly 1 game, got %d", len(games))
+	}
+	return games[0], nil
+}
+
+// ParseMoves returns the game made of the provided movetext, played from the position with the
+// provided FEN. Move numbers in the movetext are ignored.
+func ParseMoves(fen, movetext string) (*Game, error) {
+	start, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	games, err := parse(movetext, start)
+	if err != nil {
+		return nil, err
+	}
+	if len(games) != 1 {
+		return nil, errorf("movetext must have exactly 1 game, got %d", len(games))
+	}
+	return games[0], nil
+}
+
+// Validate returns an error if the provided PGN text is invalid.
+func Validate(text string) error {
+	_, err := Parse(text)
+	return err
+}
+
+// Normalize returns the provided PGN text in the PGN export format.
+func Normalize(text string) (string, error) {
+	games, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	result := make([]string, 0, len(games))
+	for _, g := range games {
+		result = append(result, g.String())
+	}
+	return strings.Join(result, "\n\n"), nil
+}
+
+// parse returns the games in the provided text. If start is not nil, it is used as the starting
+// position of games without a FEN tag.
+func parse(text string, start *Position) ([]*Game, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	var games []*Game
+	for p.peek().kind != tokenEOF {
+		g, err := p.parseGame(start)
+		if err != nil {
+			if e, ok := err.(*Error); ok {
+				e.Game = len(games) + 1
+			}
+			return nil, err
+		}
+		games = append(games, g)
+	}
+
+	if len(games) == 0 {
+		return nil, errorf("PGN is empty")
+	}
+	return games, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenTag
+	tokenComment
+	tokenOpen
+	tokenClose
+	tokenNAG
+	tokenMove
+	tokenResult
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	name  string
+	nag   int
+}
+
+var symbolicNAGs = map[string]int{
+	"!":   1,
+	"?":   2,
+	"!!":  3,
+	"??":  4,
+	"!?":  5,
+	"?!":  6,
+	"□":   7,
+	"=":   10,
+	"∞":   13,
+	"+=":  14,
+	"⩲":   14,
+	"=+":  15,
+	"⩱":   15,
+	"+/-": 16,
+	"±":   16,
+	"-/+": 17,
+	"∓":   17,
+	"+-":  18,
+	"-+":  19,
+}
+
+var moveNumberRegex = regexp.MustCompile(`^[0-9]+(\.|…)+`)
+
+// tokenize splits the provided PGN text into tokens.
+func tokenize(text string) ([]token, error) {
+	text = strings.TrimPrefix(text, "\uFEFF")
+
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+
+		case c == '%' && (i == 0 || text[i-1] == '\n'):
+			i = skipLine(text, i)
+
+		case c == ';':
+			end := skipLine(text, i)
+			tokens = append(tokens, token{kind: tokenComment, value: strings.TrimSpace(text[i+1 : end])})
+			i = end
+
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, errorf("comment is not closed")
+			}
+			tokens = append(tokens, token{kind: tokenComment, value: strings.TrimSpace(text[i+1 : i+end])})
+			i += end + 1
+
+		case c == '[':
+			tag, end, err := parseTag(text, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tag)
+			i = end
+
+		case c == ']' || c == '}':
+			return nil, errorf("unexpected %c", c)
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose})
+			i++
+
+		case c == '$':
+			end := i + 1
+			for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+				end++
+			}
+			nag, err := strconv.Atoi(text[i+1 : end])
+			if err != nil || nag > 255 {
+				return nil, errorf("invalid annotation %q", text[i:end])
+			}
+			tokens = append(tokens, token{kind: tokenNAG, nag: nag})
+			i = end
+
+		default:
+			end := i
+			for end < len(text) && !strings.ContainsRune(" \t\r\n{}()[];$", rune(text[end])) {
+				end++
+			}
+			tokens = appendWord(tokens, text[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// skipLine returns the index of the end of the line containing index i.
+func skipLine(text string, i int) int {
+	if end := strings.IndexByte(text[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(text)
+}
+
+// parseTag returns the tag pair starting at index i and the index after its closing bracket.
+func parseTag(text string, i int) (token, int, error) {
+	j := i + 1
+	for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+		j++
+	}
+	nameStart := j
+	for j < len(text) && text[j] != ' ' && text[j] != '\t' && text[j] != '"' && text[j] != ']' {
+		j++
+	}
+	name := text[nameStart:j]
+	for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+		j++
+	}
+	if name == "" || j >= len(text) || text[j] != '"' {
+		return token{}, 0, errorf("invalid tag %q", strings.SplitN(text[i:], "\n", 2)[0])
+	}
+
+	var value strings.Builder
+	for j++; j < len(text) && text[j] != '"'; j++ {
+		if text[j] == '\\' && j+1 < len(text) {
+			j++
+		} else if text[j] == '\n' {
+			break
+		}
+		value.WriteByte(text[j])
+	}
+	if j >= len(text) || text[j] != '"' {
+		return token{}, 0, errorf("tag %s is not closed", name)
+	}
+
+	j++
+	for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+		j++
+	}
+	if j >= len(text) || text[j] != ']' {
+		return token{}, 0, errorf("tag %s is not closed", name)
+	}
+	return token{kind: tokenTag, name: name, value: value.String()}, j + 1, nil
+}
+
+// appendWord appends the tokens in the provided word of movetext, which may combine a move
+// number, a move and symbolic annotations, such as 12.Nf3!?.
+func appendWord(tokens []token, word string) []token {
+	switch word {
+	case "1-0", "0-1", "1/2-1/2", "*":
+		return append(tokens, token{kind: tokenResult, value: word})
+	case "½-½":
+		return append(tokens, token{kind: tokenResult, value: "1/2-1/2"})
+	case "e.p.":
+		return tokens
+	}
+	if nag, ok := symbolicNAGs[word]; ok {
+		return append(tokens, token{kind: tokenNAG, nag: nag})
+	}
+
+	if _, err := strconv.Atoi(word); err == nil {
+		return tokens
+	}
+	if loc := moveNumberRegex.FindStringIndex(word); loc != nil {
+		if word = word[loc[1]:]; word == "" {
+			return tokens
+		}
+	}
+
+	move := strings.TrimRight(word, "!?")
+	tokens = append(tokens, token{kind: tokenMove, value: move})
+	if suffix := word[len(move):]; suffix != "" {
+		if nag, ok := symbolicNAGs[suffix]; ok {
+			tokens = append(tokens, token{kind: tokenNAG, nag: nag})
+		} else {
+			tokens[len(tokens)-1].value = word
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	if p.i >= len(p.tokens) {
+		return token{kind: tokenEOF}
+	}
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.i < len(p.tokens) {
+		p.i++
+	}
+	return t
+}
+
+// parseGame parses the next game in the token stream.
+func (p *parser) parseGame(start *Position) (*Game, error) {
+	g := &Game{start: start}
+	for p.peek().kind == tokenTag {
+		t := p.next()
+		g.Tags = append(g.Tags, Tag{Name: t.name, Value: t.value})
+	}
+
+	if variant := strings.ToLower(g.Tag("Variant")); variant != "" && variant != "standard" && variant != "from position" {
+		return nil, errorf("variant %s is not supported", g.Tag("Variant"))
+	}
+	if fen := g.Tag("FEN"); fen != "" {
+		pos, err := ParseFEN(fen)
+		if err != nil {
+			return nil, errorf("invalid FEN tag: %s", err.Error())
+		}
+		g.start = pos
+	}
+	if g.start == nil {
+		g.start, _ = ParseFEN(StartingFEN)
+	}
+
+	moves, comment, err := p.parseLine(g, g.start, 0)
+	if err != nil {
+		return nil, err
+	}
+	g.Moves, g.Comment = moves, comment
+	return g, nil
+}
+
+// parseLine parses a line of moves played from the provided position, until the end of the
+// game or, if depth is greater than 0, the end of the variation. The comment before the first
+// move is returned separately.
+func (p *parser) parseLine(g *Game, pos *Position, depth int) ([]*Node, string, error) {
+	if depth > maxVariationDepth {
+		return nil, "", errorf("variations are nested more than %d deep", maxVariationDepth)
+	}
+
+	var nodes []*Node
+	var before string
+	prev := pos
+	for {
+		var last *Node
+		if len(nodes) > 0 {
+			last = nodes[len(nodes)-1]
+		}
+
+		t := p.peek()
+		switch t.kind {
+		case tokenEOF, tokenTag:
+			if depth > 0 {
+				return nil, "", errorf("variation is not closed")
+			}
+			return nodes, before, nil
+
+		case tokenResult:
+			if depth > 0 {
+				return nil, "", errorf("variation is not closed")
+			}
+			p.next()
+			g.Result = t.value
+			return nodes, before, nil
+
+		case tokenClose:
+			if depth == 0 {
+				return nil, "", errorf("unexpected )")
+			}
+			p.next()
+			return nodes, before, nil
+
+		case tokenComment:
+			p.next()
+			if last == nil {
+				before = joinComments(before, t.value)
+			} else {
+				last.Comment = joinComments(last.Comment, t.value)
+				if _, ok := last.Command("clk"); ok {
+					if _, ok := last.Clock(); !ok {
+						return nil, "", &Error{Reason: "invalid clock annotation", Move: last.number()}
+					}
+				}
+			}
+
+		case tokenNAG:
+			p.next()
+			if last != nil {
+				last.NAGs = append(last.NAGs, t.nag)
+			}
+
+		case tokenOpen:
+			p.next()
+			if last == nil {
+				return nil, "", errorf("variation before the first move")
+			}
+			variation, _, err := p.parseLine(g, prev, depth+1)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(variation) > 0 {
+				last.Variations = append(last.Variations, variation)
+			}
+
+		case tokenMove:
+			p.next()
+			current := pos
+			if last != nil {
+				current = last.position
+			}
+
+			m, err := current.ParseSAN(t.value)
+			if err != nil {
+				if e, ok := err.(*Error); ok {
+					e.Move = moveNumber(current)
+				}
+				return nil, "", err
+			}
+			node := &Node{Move: m, SAN: current.SAN(m), position: current.apply(m)}
+			if len(nodes) == 0 && depth > 0 {
+				node.CommentBefore, before = before, ""
+			}
+			prev = current
+			nodes = append(nodes, node)
+		}
+	}
+}
+
+// moveNumber returns the number of the next move in the provided position.
+func moveNumber(p *Position) string {
+	if p.turn == White {
+		return strconv.Itoa(p.fullmoveNumber) + "."
+	}
+	return strconv.Itoa(p.fullmoveNumber) + "..."
+}
+
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + " " + b
+}
+
+// String returns the game in the PGN export format.
+func (g *Game) String() string {
+	var sb strings.Builder
+	for _, t := range g.Tags {
+		value := strings.ReplaceAll(strings.ReplaceAll(t.Value, `\`, `\\`), `"`, `\"`)
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", t.Name, value)
+	}
+	if len(g.Tags) > 0 {
+		sb.WriteByte('\n')
+	}
+
+	var words []string
+	if g.Comment != "" {
+		words = append(words, "{ "+g.Comment+" }")
+	}
+	words = appendLine(words, g.Moves)
+
+	result := g.Result
+	if result == "" {
+		result = g.Tag("Result")
+	}
+	if result == "" {
+		result = "*"
+	}
+	words = append(words, result)
+
+	line := 0
+	for i, w := range words {
+		if i > 0 {
+			if line+1+len(w) > 80 {
+				sb.WriteByte('\n')
+				line = 0
+			} else {
+				sb.WriteByte(' ')
+				line++
+			}
+		}
+		sb.WriteString(w)
+		line += len(w)
+	}
+	return sb.String()
+}
+
+// appendLine appends the words of the provided line to words.
+func appendLine(words []string, line []*Node) []string {
+	needNumber := true
+	for _, n := range line {
+		if n.CommentBefore != "" {
+			words = append(words, "{ "+n.CommentBefore+" }")
+		}
+		if number := n.number(); needNumber || !strings.HasSuffix(number, "...") {
+			words = append(words, number+" "+n.SAN)
+		} else {
+			words = append(words, n.SAN)
+		}
+		needNumber = false
+
+		for _, nag := range n.NAGs {
+			words = append(words, "$"+strconv.Itoa(nag))
+		}
+		if n.Comment != "" {
+			words = append(words, "{ "+n.Comment+" }")
+			needNumber = true
+		}
+		for _, v := range n.Variations {
+			variation := appendLine(nil, v)
+			variation[0] = "(" + variation[0]
+			variation[len(variation)-1] += ")"
+			words = append(words, variation...)
+			needNumber = true
+		}
+	}
+	return words
+}
@@ -0,0 +1,200 @@
+package pgn
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const annotatedPgn = `[Event "Dojo Training"]
+[White "Alice \"The Rook\""]
+[Black "Bob"]
+[Result "1-0"]
+
+{Opening comment} 1.e4 {[%clk 0:05:00]} 1...e5 {[%clk 0:04:58]} 2. Nf3!? Nc6 $6
+(2... d6 {Philidor} 3. d4 (3. Bc4) 3... exd4) (2... Nf6) 3. Bb5 a6 4. Ba4 Nf6
+5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7 1-0`
+
+func TestParse(t *testing.T) {
+	games, err := Parse(annotatedPgn)
+	if err != nil {
+		t.Fatalf("Parse got err %v", err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("Parse got %d games, want 1", len(games))
+	}
+
+	g := games[0]
+	if got := g.Tag("White"); got != `Alice "The Rook"` {
+		t.Errorf("Tag(White) got %q, want %q", got, `Alice "The Rook"`)
+	}
+	if g.Comment != "Opening comment" {
+		t.Errorf("Comment got %q, want %q", g.Comment, "Opening comment")
+	}
+	if g.Result != "1-0" {
+		t.Errorf("Result got %q, want 1-0", g.Result)
+	}
+	if len(g.Moves) != 20 {
+		t.Errorf("Parse got %d moves, want 20", len(g.Moves))
+	}
+
+	if clock, ok := g.Moves[1].Clock(); !ok || clock != 4*time.Minute+58*time.Second {
+		t.Errorf("Clock got %v, %t, want 4m58s", clock, ok)
+	}
+	if !cmp.Equal(g.Moves[2].NAGs, []int{5}) || !cmp.Equal(g.Moves[3].NAGs, []int{6}) {
+		t.Errorf("NAGs got %v and %v, want [5] and [6]", g.Moves[2].NAGs, g.Moves[3].NAGs)
+	}
+
+	variations := g.Moves[3].Variations
+	if len(variations) != 2 {
+		t.Fatalf("Parse got %d variations, want 2", len(variations))
+	}
+	if got := variations[0][1].Variations[0][0].SAN; got != "Bc4" {
+		t.Errorf("Nested variation got %s, want Bc4", got)
+	}
+
+	wantFEN := "r1bq1rk1/2pnbppp/p2p1n2/1p2p3/3PP3/1BP2N1P/PP3PP1/RNBQR1K1 w - - 1 11"
+	if got := g.Moves[len(g.Moves)-1].FEN(); got != wantFEN {
+		t.Errorf("FEN got %q, want %q", got, wantFEN)
+	}
+}
+
+func TestParseMultipleGames(t *testing.T) {
+	games, err := Parse("[Event \"A\"]\n\n1. e4 *\n\n[Event \"B\"]\n\n1. d4 d5 1/2-1/2\n\n1. c4 0-1")
+	if err != nil {
+		t.Fatalf("Parse got err %v", err)
+	}
+
+	var got []string
+	for _, g := range games {
+		got = append(got, g.Tag("Event")+":"+g.Result)
+	}
+	if want := []string{"A:*", "B:1/2-1/2", ":0-1"}; !cmp.Equal(want, got) {
+		t.Errorf("Parse got games %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	table := []struct {
+		name string
+		pgn  string
+		want string
+	}{
+		{name: "Empty", pgn: "  \n", want: "PGN is empty"},
+		{name: "IllegalMove", pgn: "1. e4 e5 2. Nf3 Nf6 3. Ke3", want: "illegal move Ke3 at move 3."},
+		{name: "IllegalBlackMove", pgn: "1. e4 e5 2. Nf3 Ke6", want: "illegal move Ke6 at move 2..."},
+		{name: "IllegalVariationMove", pgn: "1. e4 e5 (1... e4) 2. Nf3", want: "illegal move e4 at move 1..."},
+		{name: "SecondGame", pgn: "1. e4 *\n\n1. e5 *", want: "game 2: illegal move e5 at move 1."},
+		{name: "UnclosedComment", pgn: "1. e4 {comment", want: "comment is not closed"},
+		{name: "UnclosedVariation", pgn: "1. e4 (1. d4 d5", want: "variation is not closed"},
+		{name: "UnexpectedClose", pgn: "1. e4 ) e5", want: "unexpected )"},
+		{name: "UnexpectedBracket", pgn: "]", want: "unexpected ]"},
+		{name: "UnexpectedBracketAfterWord", pgn: "x]", want: "unexpected ]"},
+		{name: "UnexpectedBracketInMovetext", pgn: "a] 1. e4", want: "unexpected ]"},
+		{name: "UnexpectedBrace", pgn: "1. e4 } e5", want: "unexpected }"},
+		{name: "VariationBeforeMove", pgn: "(1. d4) 1. e4", want: "variation before the first move"},
+		{name: "InvalidTag", pgn: "[Event Dojo]\n\n1. e4", want: `invalid tag "[Event Dojo]"`},
+		{name: "UnclosedTag", pgn: "[Event \"Dojo\"\n\n1. e4", want: "tag Event is not closed"},
+		{name: "InvalidFEN", pgn: "[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n\n*", want: "invalid FEN tag: FEN must have exactly one king of each color"},
+		{name: "Variant", pgn: "[Variant \"Crazyhouse\"]\n\n1. e4", want: "variant Crazyhouse is not supported"},
+		{name: "InvalidClock", pgn: "1. e4 {[%clk 5 minutes]}", want: "invalid clock annotation at move 1."},
+		{name: "InvalidNAG", pgn: "1. e4 $999", want: `invalid annotation "$999"`},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.pgn)
+			if err == nil {
+				t.Fatalf("Parse got nil err, want %q", tc.want)
+			}
+			if err.Error() != tc.want {
+				t.Errorf("Parse got err %q, want %q", err.Error(), tc.want)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(annotatedPgn)
+	f.Add("[Event \"A\"]\n\n1. e4 *\n\n[Event \"B\"]\n\n1. d4 d5 1/2-1/2")
+	f.Add("1. e4 e5 2. Nf3 Nc6 ]")
+	f.Add("1. e4 } {")
+	f.Add("1. e4 (1. d4 (1. c4)) $1 ; comment\n%escape\n*")
+
+	f.Fuzz(func(t *testing.T, pgn string) {
+		// Parse must return, with or without an error, for any input.
+		Parse(pgn)
+	})
+}
+
+func TestParseFromPosition(t *testing.T) {
+	pgn := `[SetUp "1"]
+[FEN "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"]
+
+3. Bb5 a6 4. Ba4 *`
+
+	g, err := ParseGame(pgn)
+	if err != nil {
+		t.Fatalf("ParseGame got err %v", err)
+	}
+	if len(g.Moves) != 3 {
+		t.Errorf("ParseGame got %d moves, want 3", len(g.Moves))
+	}
+	if got := g.Start().FullmoveNumber(); got != 3 {
+		t.Errorf("Start got fullmove number %d, want 3", got)
+	}
+}
+
+func TestParseMoves(t *testing.T) {
+	fen := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 1"
+
+	g, err := ParseMoves(fen, "3. Bb5 a6 (3... Nf6 4. O-O) 4. Ba4")
+	if err != nil {
+		t.Fatalf("ParseMoves got err %v", err)
+	}
+	if len(g.Moves) != 3 {
+		t.Errorf("ParseMoves got %d moves, want 3", len(g.Moves))
+	}
+
+	if _, err := ParseMoves(fen, "3. Bb5 Ke6"); err == nil {
+		t.Errorf("ParseMoves got nil err for illegal move")
+	}
+	if _, err := ParseMoves("not a fen", "1. e4"); err == nil {
+		t.Errorf("ParseMoves got nil err for invalid FEN")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	input := "[White \"Alice\"]\n[Result \"*\"]\n\n" +
+		"1.e4 {Best by test} e5 2.Ng1-f3 Nc6!? (2...Nf6 3.Nxe5) 3.Bb5 a6 4.Bxc6 dxc6 5.0-0 f6 6.d4 exd4 7.Nxd4 c5 8.Nb3 Qxd1 9.Rxd1"
+
+	want := `[White "Alice"]
+[Result "*"]
+
+1. e4 { Best by test } 1... e5 2. Nf3 Nc6 $5 (2... Nf6 3. Nxe5) 3. Bb5 a6
+4. Bxc6 dxc6 5. O-O f6 6. d4 exd4 7. Nxd4 c5 8. Nb3 Qxd1 9. Rxd1 *`
+
+	got, err := Normalize(input)
+	if err != nil {
+		t.Fatalf("Normalize got err %v", err)
+	}
+	if got != want {
+		t.Errorf("Normalize mismatch (-want +got):\n%s", cmp.Diff(want, got))
+	}
+
+	again, err := Normalize(got)
+	if err != nil {
+		t.Fatalf("Normalize of normalized PGN got err %v", err)
+	}
+	if again != got {
+		t.Errorf("Normalize is not idempotent (-first +second):\n%s", cmp.Diff(got, again))
+	}
+
+	for _, line := range strings.Split(got, "\n") {
+		if len(line) > 80 {
+			t.Errorf("Normalize got line of length %d, want <= 80", len(line))
+		}
+	}
+}
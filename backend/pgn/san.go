@@ -0,0 +1,202 @@
+package pgn
+
+import "strings"
+
+var sanPieces = map[byte]PieceType{
+	'N': Knight,
+	'B': Bishop,
+	'R': Rook,
+	'Q': Queen,
+	'K': King,
+}
+
+// ParseSAN returns the legal move described by the provided move in standard algebraic
+// notation. Common variations are accepted: 0-0 for O-O, promotions without =, trailing check,
+// mate and annotation symbols, and coordinate notation such as e2e4. Null moves are written
+// as -- or Z0.
+func (p *Position) ParseSAN(san string) (Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	s = strings.TrimSuffix(s, "e.p.")
+
+	if s == "--" || s == "Z0" {
+		if p.InCheck() {
+			return Move{}, errorf("null move %s while in check", san)
+		}
+		return NullMove, nil
+	}
+
+	switch strings.ReplaceAll(s, "0", "O") {
+	case "O-O":
+		return p.matchCastle(san, 6)
+	case "O-O-O":
+		return p.matchCastle(san, 2)
+	}
+
+	if m, ok := p.parseCoordinate(s); ok {
+		return m, nil
+	}
+
+	piece := Pawn
+	if len(s) > 0 {
+		if t, ok := sanPieces[s[0]]; ok {
+			piece = t
+			s = s[1:]
+		}
+	}
+
+	promotion := NoPieceType
+	if piece == Pawn && len(s) > 0 {
+		if t, ok := sanPieces[s[len(s)-1]]; ok && t != King {
+			promotion = t
+			s = strings.TrimSuffix(s[:len(s)-1], "=")
+		}
+	}
+
+	if len(s) < 2 {
+		return Move{}, errorf("invalid move %s", san)
+	}
+	to := parseSquare(s[len(s)-2:])
+	if to == NoSquare {
+		return Move{}, errorf("invalid move %s", san)
+	}
+
+	fromFile, fromRank := -1, -1
+	for _, c := range strings.TrimSuffix(strings.TrimSuffix(s[:len(s)-2], "-"), "x") {
+		switch {
+		case c >= 'a' && c <= 'h' && fromFile == -1 && fromRank == -1:
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8' && fromRank == -1:
+			fromRank = int(c - '1')
+		default:
+			return Move{}, errorf("invalid move %s", san)
+		}
+	}
+
+	var matches []Move
+	for _, m := range p.LegalMoves() {
+		if m.To != to || p.board[m.From].Type() != piece || m.Promotion != promotion {
+			continue
+		}
+		if (fromFile != -1 && m.From.File() != fromFile) || (fromRank != -1 && m.From.Rank() != fromRank) {
+			continue
+		}
+		if piece == King && (m.To.File()-m.From.File() == 2 || m.From.File()-m.To.File() == 2) {
+			continue
+		}
+		matches = append(matches, m)
+	}
+
+	switch len(matches) {
+	case 0:
+		return Move{}, errorf("illegal move %s", san)
+	case 1:
+		return matches[0], nil
+	default:
+		return Move{}, errorf("ambiguous move %s", san)
+	}
+}
+
+// matchCastle returns the legal castling move of the side to move whose king lands on the
+// provided file.
+func (p *Position) matchCastle(san string, file int) (Move, error) {
+	rank := 0
+	if p.turn == Black {
+		rank = 7
+	}
+	m := Move{From: NewSquare(4, rank), To: NewSquare(file, rank)}
+	if p.board[m.From] != NewPiece(p.turn, King) || !p.isLegal(m) {
+		return Move{}, errorf("illegal move %s", san)
+	}
+	return m, nil
+}
+
+// parseCoordinate returns the legal move described in coordinate notation, such as e2e4,
+// e2-e4 or e7e8q. It returns false if the move is not in coordinate notation or is illegal.
+func (p *Position) parseCoordinate(s string) (Move, bool) {
+	s = strings.Replace(strings.Replace(s, "-", "", 1), "x", "", 1)
+	if len(s) != 4 && len(s) != 5 {
+		return Move{}, false
+	}
+
+	m := Move{From: parseSquare(s[:2]), To: parseSquare(s[2:4])}
+	if m.From == NoSquare || m.To == NoSquare {
+		return Move{}, false
+	}
+	if len(s) == 5 {
+		t := pieceLetters[s[4]|0x20]
+		if t == NoPieceType || t == Pawn || t == King {
+			return Move{}, false
+		}
+		m.Promotion = t
+	}
+	return m, p.isLegal(m)
+}
+
+// SAN returns the provided legal move in standard algebraic notation, including a + or # suffix
+// for check or checkmate.
+func (p *Position) SAN(m Move) string {
+	if m.IsNull() {
+		return "--"
+	}
+
+	piece := p.board[m.From]
+	var sb strings.Builder
+	switch {
+	case piece.Type() == King && m.To.File()-m.From.File() == 2:
+		sb.WriteString("O-O")
+	case piece.Type() == King && m.From.File()-m.To.File() == 2:
+		sb.WriteString("O-O-O")
+	default:
+		capture := p.board[m.To] != NoPiece || (piece.Type() == Pawn && m.To == p.enPassant)
+		if piece.Type() == Pawn {
+			if capture {
+				sb.WriteByte(byte('a' + m.From.File()))
+			}
+		} else {
+			sb.WriteByte(piece.letter() &^ 0x20)
+			sb.WriteString(p.disambiguation(m))
+		}
+		if capture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(m.To.String())
+		if m.Promotion != NoPieceType {
+			sb.WriteByte('=')
+			sb.WriteByte(NewPiece(White, m.Promotion).letter())
+		}
+	}
+
+	next := p.apply(m)
+	if next.IsCheckmate() {
+		sb.WriteByte('#')
+	} else if next.InCheck() {
+		sb.WriteByte('+')
+	}
+	return sb.String()
+}
+
+// disambiguation returns the file, rank or square of the moving piece needed to distinguish
+// the move from other legal moves of the same piece type to the same square.
+func (p *Position) disambiguation(m Move) string {
+	piece := p.board[m.From]
+	sameFile, sameRank, ambiguous := false, false, false
+	for _, other := range p.LegalMoves() {
+		if other.To != m.To || other.From == m.From || p.board[other.From] != piece {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || other.From.File() == m.From.File()
+		sameRank = sameRank || other.From.Rank() == m.From.Rank()
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return m.From.String()[:1]
+	case !sameRank:
+		return m.From.String()[1:]
+	default:
+		return m.From.String()
+	}
+}
@@ -7,11 +7,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.RequirementLister = database.DynamoDB
+var repository = database.Repository[database.RequirementLister](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 type ListRequirementsResponse struct {
@@ -20,9 +19,6 @@ type ListRequirementsResponse struct {
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	startKey := event.QueryStringParameters["startKey"]
 	cohort := event.PathParameters["cohort"]
 
@@ -52,8 +48,5 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 }
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
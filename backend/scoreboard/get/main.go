@@ -8,7 +8,6 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
@@ -17,7 +16,7 @@ const (
 	requestType_Following = "following"
 )
 
-var repository database.ScoreboardSummaryLister = database.DynamoDB
+var repository = database.Repository[database.ScoreboardSummaryLister](database.DynamoDB)
 var stage = os.Getenv("stage")
 
 type GetScoreboardResponse struct {
@@ -26,16 +25,10 @@ type GetScoreboardResponse struct {
 }
 
 func main() {
-	if stage == "prod" {
-		log.SetLevel(log.InfoLevel)
-	}
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	startKey := event.QueryStringParameters["startKey"]
 	requestType := event.PathParameters["type"]
 
@@ -0,0 +1,83 @@
+// Builds the repertoire of each user from the games they own. Existing moves
+// in the repertoire table are overwritten with the counts calculated from the
+// user's games, so the script can safely be run more than once. Moves which
+// are no longer in any of a user's games are not removed. The color each game
+// was counted with is also saved, so that the stream processor removes its
+// moves with the same color. Games changed while a user's repertoire is being
+// built may not be counted correctly.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository database.RepertoireWriter = database.DynamoDB
+
+func main() {
+	var users []*database.User
+	var startKey string
+	var err error
+
+	built := 0
+	failed := 0
+
+	for ok := true; ok; ok = startKey != "" {
+		fmt.Println("StartKey: ", startKey)
+		users, startKey, err = repository.ScanUsers(startKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, u := range users {
+			count, err := buildRepertoire(u)
+			built += count
+			if err != nil {
+				failed += 1
+				fmt.Printf("Failed to build repertoire of user %s: %v\n", u.Username, err)
+			}
+		}
+	}
+
+	fmt.Printf("Success: %d moves saved, %d users failed\n", built, failed)
+}
+
+// buildRepertoire saves the repertoire of the provided user and returns the number of moves saved.
+func buildRepertoire(user *database.User) (int, error) {
+	names := user.PlayerNames()
+
+	var moves []database.RepertoireMove
+	var games []database.RepertoireGame
+	for g, err := range database.All(context.Background(), func(startKey string) ([]*database.Game, string, error) {
+		return repository.ListGamesByOwner(true, user.Username, "", "", startKey)
+	}) {
+		if err != nil {
+			return 0, err
+		}
+
+		// ListGamesByOwner does not include the PGN.
+		game, err := repository.GetGame(string(g.Cohort), g.Id)
+		if err != nil {
+			return 0, err
+		}
+		color := database.RepertoireColor(game, names)
+		games = append(games, database.NewRepertoireGame(game, color))
+		gameMoves, err := database.RepertoireMoves(game, color)
+		if err != nil {
+			fmt.Printf("Skipping game %s/%s: %v\n", g.Cohort, g.Id, err)
+			continue
+		}
+		moves = append(moves, gameMoves...)
+	}
+
+	if _, err := repository.PutRepertoireGames(games); err != nil {
+		return 0, err
+	}
+	if len(moves) == 0 {
+		return 0, nil
+	}
+	return repository.PutRepertoireMoves(moves)
+}
@@ -0,0 +1,63 @@
+// Migrates the deprecated RatingHistories field of each user into the
+// rating history table. The daily points of ratings older than the daily TTL
+// are already expired, so only their weekly points are kept. The user's
+// RatingHistories are not modified, so the script can safely be run more
+// than once.
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.DynamoDB
+
+func main() {
+	var users []*database.User
+	var startKey string
+	var err error
+
+	migrated := 0
+	failed := 0
+
+	for ok := true; ok; ok = startKey != "" {
+		fmt.Println("StartKey: ", startKey)
+		users, startKey, err = repository.ScanUsers(startKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, u := range users {
+			count, err := migrateUser(u)
+			migrated += count
+			if err != nil {
+				failed += 1
+				fmt.Printf("Failed to migrate user %s: %v\n", u.Username, err)
+			}
+		}
+	}
+
+	fmt.Printf("Success: %d points migrated, %d users failed\n", migrated, failed)
+}
+
+func migrateUser(user *database.User) (int, error) {
+	var points []database.RatingHistoryPoint
+	for system, history := range user.RatingHistories {
+		for _, item := range history {
+			date, err := time.Parse(time.RFC3339, item.Date)
+			if err != nil {
+				fmt.Printf("Skipping invalid date %q for user %s\n", item.Date, user.Username)
+				continue
+			}
+			points = append(points, database.NewRatingHistoryPoints(user.Username, system, date, item.Rating)...)
+		}
+	}
+
+	if len(points) == 0 {
+		return 0, nil
+	}
+	return repository.PutRatingHistory(points)
+}
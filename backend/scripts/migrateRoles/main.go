@@ -0,0 +1,57 @@
+// Migrates the deprecated IsAdmin, IsCalendarAdmin and IsTournamentAdmin
+// fields of each user into the equivalent roles. Users who already have
+// roles are skipped.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.DynamoDB
+
+func main() {
+	var users []*database.User
+	var startKey string
+	var err error
+
+	updated := 0
+	failed := 0
+
+	for ok := true; ok; ok = startKey != "" {
+		fmt.Println("StartKey: ", startKey)
+		users, startKey, err = repository.ScanUsers(startKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, u := range users {
+			success, err := updateUser(u)
+			if success {
+				updated += 1
+			}
+			if err != nil {
+				failed += 1
+				fmt.Printf("Failed to update user %s: %v\n", u.Username, err)
+			}
+		}
+	}
+
+	fmt.Printf("Success: %d updated, %d failed\n", updated, failed)
+}
+
+func updateUser(user *database.User) (bool, error) {
+	if len(user.Roles) > 0 {
+		return false, nil
+	}
+
+	roles := user.LegacyRoles()
+	if len(roles) == 0 {
+		return false, nil
+	}
+
+	_, err := repository.SetUserRoles(user.Username, roles)
+	return err == nil, err
+}
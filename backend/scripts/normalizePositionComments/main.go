@@ -0,0 +1,131 @@
+// Re-keys the PositionComments of each game under the normalized FEN of the
+// position, merging the comments of positions which were saved with different
+// move counters. Like the frontend's chess.normalizedFen(), only the counters are
+// normalized, so the keys match the FENs the frontend looks comments up by. The
+// Fen field of each comment and its replies is also normalized. Comments whose
+// FEN cannot be parsed are left under their original key and reported, and can
+// still be edited and deleted by that key. Games which are already normalized are not
+// written, so the script can safely be run more than once. A game is only
+// written if its comments have not changed since it was read. Otherwise, it is
+// read again and retried, so comments added during the migration are kept.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// maxAttempts is the number of times a game is written before it is reported as failed,
+// if its comments keep changing.
+const maxAttempts = 5
+
+var repository database.PositionCommentMigrator = database.DynamoDB
+
+func main() {
+	var games []database.Game
+	var startKey string
+	var err error
+
+	migrated := 0
+	failed := 0
+
+	for ok := true; ok; ok = startKey != "" {
+		fmt.Println("StartKey: ", startKey)
+		games, startKey, err = repository.ScanGameComments(startKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, g := range games {
+			changed, err := migrateGame(&g)
+			if err != nil {
+				failed += 1
+				fmt.Printf("Failed to migrate game %s/%s: %v\n", g.Cohort, g.Id, err)
+				continue
+			}
+			if changed {
+				migrated += 1
+			}
+		}
+	}
+
+	fmt.Printf("Success: %d games migrated, %d games failed\n", migrated, failed)
+}
+
+// migrateGame normalizes the comments of the provided game and returns whether it was written.
+// If the game's comments were changed since it was read, the game is read again and retried.
+func migrateGame(game *database.Game) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		comments, changed := normalizeComments(game)
+		if !changed {
+			return false, nil
+		}
+
+		err := repository.SetPositionComments(string(game.Cohort), game.Id, game.PositionComments, comments)
+		if _, ok := errors.AsConflict(err); !ok || attempt == maxAttempts {
+			return err == nil, err
+		}
+
+		fmt.Printf("Comments of game %s/%s changed during the migration, retrying\n", game.Cohort, game.Id)
+		latest, err := repository.GetGame(string(game.Cohort), game.Id)
+		if err != nil {
+			var apiErr *errors.Error
+			if errors.As(err, &apiErr) && apiErr.Code == 404 {
+				fmt.Printf("Skipping deleted game %s/%s\n", game.Cohort, game.Id)
+				return false, nil
+			}
+			return false, err
+		}
+		game = latest
+	}
+}
+
+// normalizeComments returns the provided game's position comments keyed by normalized FEN,
+// and whether they differ from the game's current comments. The game is not modified.
+func normalizeComments(game *database.Game) (map[string]map[string]database.PositionComment, bool) {
+	result := make(map[string]map[string]database.PositionComment, len(game.PositionComments))
+	changed := false
+
+	for fen, comments := range game.PositionComments {
+		normalized, err := database.NormalizeCommentFen(fen)
+		if err != nil {
+			fmt.Printf("Skipping invalid FEN %q in game %s/%s: %v\n", fen, game.Cohort, game.Id, err)
+			normalized = fen
+		}
+		if normalized != fen {
+			changed = true
+		}
+
+		if result[normalized] == nil {
+			result[normalized] = make(map[string]database.PositionComment, len(comments))
+		}
+		for id, c := range comments {
+			c, commentChanged := withFen(c, normalized)
+			changed = changed || commentChanged
+			result[normalized][id] = c
+		}
+	}
+	return result, changed
+}
+
+// withFen returns a copy of the provided comment and its replies with their Fen set to fen,
+// and whether any comment was changed. The provided comment is not modified.
+func withFen(comment database.PositionComment, fen string) (database.PositionComment, bool) {
+	changed := comment.Fen != fen
+	comment.Fen = fen
+	if comment.Replies == nil {
+		return comment, changed
+	}
+
+	replies := make(map[string]database.PositionComment, len(comment.Replies))
+	for id, reply := range comment.Replies {
+		reply, replyChanged := withFen(reply, fen)
+		changed = changed || replyChanged
+		replies[id] = reply
+	}
+	comment.Replies = replies
+	return comment, changed
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
@@ -22,7 +21,7 @@ const lichessSwissPrefix = "https://lichess.org/swiss/"
 const chesscomArenaPrefix = "https://www.chess.com/tournament/live/arena/"
 const chesscomSwissPrefix = "https://www.chess.com/tournament/live/"
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.EventSetter](database.DynamoDB)
 var botAccessToken = os.Getenv("botAccessToken")
 
 type CreateTournamentsRequest struct {
@@ -251,13 +250,10 @@ func (cr ChesscomResponse) ToEvent() (*database.Event, error) {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
 	auth := request.Headers["authorization"]
 	if auth != fmt.Sprintf("Basic %s", botAccessToken) {
 		err := errors.New(401, "Authorization header is invalid", "")
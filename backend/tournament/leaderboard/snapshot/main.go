@@ -15,15 +15,16 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.LeaderboardSetter](database.DynamoDB)
 
 func main() {
 	lambda.Start(Handler)
 }
 
 func Handler(ctx context.Context, event events.CloudWatchEvent) (events.CloudWatchEvent, error) {
-	log.SetRequestId(event.ID)
-	log.Infof("Event: %#v", event)
+	logger := log.Default().With(log.RequestId(event.ID))
+	ctx = log.NewContext(ctx, logger)
+	logger.Infof("Event: %#v", event)
 
 	now := time.Now()
 	month := now.Add(-24 * time.Hour).Format("2006-01")
@@ -32,16 +33,16 @@ func Handler(ctx context.Context, event events.CloudWatchEvent) (events.CloudWat
 	for _, site := range database.LeaderboardSites {
 		for _, name := range database.LeaderboardNames {
 			for _, timeControl := range database.TimeControls {
-				log.Debugf("Snapshotting monthly leaderboard (site, month, name, tc): (%s, %s, %s, %s)", site, month, name, timeControl)
+				logger.Debugf("Snapshotting monthly leaderboard (site, month, name, tc): (%s, %s, %s, %s)", site, month, name, timeControl)
 				if err := snapshotLeaderboard(site, "MONTHLY", month, name, timeControl); err != nil {
-					log.Errorf("Failed to snapshot monthly leaderboard: %v", err)
+					logger.Errorf("Failed to snapshot monthly leaderboard: %v", err)
 					return event, err
 				}
 
 				if now.YearDay() == 1 {
-					log.Debugf("Snapshotting yearly leaderboard (site, year, name, tc): (%s, %s, %s, %s)", site, year, name, timeControl)
+					logger.Debugf("Snapshotting yearly leaderboard (site, year, name, tc): (%s, %s, %s, %s)", site, year, name, timeControl)
 					if err := snapshotLeaderboard(site, "YEARLY", year, name, timeControl); err != nil {
-						log.Errorf("Failed to snapshot yearly leaderboard: %v", err)
+						logger.Errorf("Failed to snapshot yearly leaderboard: %v", err)
 						return event, err
 					}
 				}
@@ -50,7 +51,7 @@ func Handler(ctx context.Context, event events.CloudWatchEvent) (events.CloudWat
 	}
 
 	if err := resetMongo(ctx); err != nil {
-		log.Errorf("Failed to reset Mongo scores: %v", err)
+		logger.Errorf("Failed to reset Mongo scores: %v", err)
 		return event, err
 	}
 
@@ -94,6 +95,7 @@ func snapshotLeaderboard(site database.LeaderboardSite, timeframe, startsAt stri
 
 // resetMongo resets all the scores in the Dojo Discord's MongoDB to 0.
 func resetMongo(ctx context.Context) error {
+	logger := log.FromContext(ctx)
 	mongoConnectionString := os.Getenv("mongoConnectionString")
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoConnectionString))
 	if err != nil {
@@ -132,14 +134,14 @@ func resetMongo(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	log.Debugf("Successfully updated Lichess collection: %#v", result)
+	logger.Debugf("Successfully updated Lichess collection: %#v", result)
 
 	collection = client.Database("Lisebot-database").Collection("chesscom-players")
 	result, err = collection.UpdateMany(ctx, filter, update)
 	if err != nil {
 		return err
 	}
-	log.Debugf("Successfully updated Chesscom collection: %#v", result)
+	logger.Debugf("Successfully updated Chesscom collection: %#v", result)
 
 	return nil
 }
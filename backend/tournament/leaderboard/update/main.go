@@ -14,18 +14,16 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.LeaderboardSetter](database.DynamoDB)
 
 var botAccessToken = os.Getenv("botAccessToken")
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, request api.Request) (api.Response, error) {
-	log.SetRequestId(request.RequestContext.RequestID)
-	log.Infof("Request: %#v", request)
-
+	logger := log.FromContext(ctx)
 	auth := request.Headers["authorization"]
 	if auth != fmt.Sprintf("Basic %s", botAccessToken) {
 		err := errors.New(401, "Authorization header is invalid", "")
@@ -37,7 +35,7 @@ func Handler(ctx context.Context, request api.Request) (api.Response, error) {
 		return api.Failure(errors.Wrap(400, "Invalid request: unable to unmarshal request body", "", err)), nil
 	}
 
-	log.Debugf("Request leaderboard: %#v", leaderboardReq)
+	logger.Debugf("Request leaderboard: %#v", leaderboardReq)
 	tournamentType, err := getTournamentType(leaderboardReq)
 	if err != nil {
 		return api.Failure(err), nil
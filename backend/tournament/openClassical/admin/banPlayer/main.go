@@ -8,17 +8,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 
 type BanPlayerRequest struct {
 	// The username of the player to ban
@@ -32,42 +30,29 @@ type BanPlayerRequest struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Handle(handler,
+		api.RequireUser(repository),
+		api.RequirePermission(database.Permission_ManageTournaments),
+		api.DecodeJSON[BanPlayerRequest](),
+		api.Validate(validateRequest),
+	))
 }
 
-func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
-	request := BanPlayerRequest{}
-	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
-		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
-	}
-
+func validateRequest(request *BanPlayerRequest) error {
 	if request.Username == "" {
-		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+		return errors.New(400, "Invalid request: username is required", "")
 	}
 	if request.Region == "" {
-		return api.Failure(errors.New(400, "Invalid request: region is required", "")), nil
+		return errors.New(400, "Invalid request: region is required", "")
 	}
 	if request.Section == "" {
-		return api.Failure(errors.New(400, "Invalid request: section is required", "")), nil
-	}
-
-	info := api.GetUserInfo(event)
-	if info.Username == "" {
-		err := errors.New(400, "Invalid request: username is required", "")
-		return api.Failure(err), nil
+		return errors.New(400, "Invalid request: section is required", "")
 	}
+	return nil
+}
 
-	user, err := repository.GetUser(info.Username)
-	if err != nil {
-		return api.Failure(err), nil
-	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
-		err := errors.New(403, "Invalid request: you are not a tournament admin", "")
-		return api.Failure(err), nil
-	}
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	request := api.Body[BanPlayerRequest](ctx)
 
 	openClassical, err := repository.GetOpenClassical(database.CurrentLeaderboard)
 	if err != nil {
@@ -94,6 +79,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		}
 	}
 
+	before := player
 	player.Status = database.OpenClassicalPlayerStatus_Banned
 	player.LastActiveRound = lastActiveRound
 
@@ -101,5 +87,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
+
+	api.Audit(repository, event, database.AuditAction_BanPlayer, database.UserAuditTarget(request.Username), before, player)
 	return api.Success(openClassical), nil
 }
@@ -8,24 +8,20 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 
 type CompleteTournamentRequest struct {
 	NextStartDate string `json:"nextStartDate"`
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	request := CompleteTournamentRequest{}
 	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
 		return api.Failure(errors.New(400, "Invalid request: failed to unmarshal body", "")), nil
@@ -43,7 +39,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
+	if !user.HasPermission(database.Permission_ManageTournaments) {
 		return api.Failure(errors.New(403, "Invalid request: you are not a tournament admin", "")), nil
 	}
 
@@ -55,6 +51,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(errors.New(400, "Invalid request: the tournament is still accepting registrations", "")), nil
 	}
 
+	archivedAs := openClassical.StartMonth
 	openClassical.StartsAt = openClassical.StartMonth
 	openClassical.Name = openClassical.StartsAt
 
@@ -77,5 +74,10 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err := repository.SetOpenClassical(openClassical); err != nil {
 		return api.Failure(err), nil
 	}
+
+	api.Audit(repository, event, database.AuditAction_CompleteTournament, database.OpenClassicalAuditTarget("", ""), nil, map[string]string{
+		"archivedAs":        archivedAs,
+		"registrationClose": request.NextStartDate,
+	})
 	return api.Success(openClassical), nil
 }
@@ -31,17 +31,15 @@ type EmailPairingsResponse struct {
 	EmailsSent int `json:"emailsSent"`
 }
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 var Ses = ses.New(session.Must(session.NewSession()))
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	var request EmailPairingsRequest
 	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
 		err = errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)
@@ -58,7 +56,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
+	if !user.HasPermission(database.Permission_ManageTournaments) {
 		err := errors.New(403, "Invalid request: you are not a tournament admin", "")
 		return api.Failure(err), nil
 	}
@@ -85,27 +83,32 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	for name, section := range openClassical.Sections {
 		round := section.Rounds[request.Round-1]
 		if round.PairingEmailsSent {
-			log.Infof("Skipping section %s because PairingEmailsSent is already true", name)
+			logger.Infof("Skipping section %s because PairingEmailsSent is already true", name)
 			continue
 		}
 
 		for _, pairing := range round.Pairings {
-			emailsSent += sendPairingEmail(&section, &pairing, request.Round)
+			emailsSent += sendPairingEmail(ctx, &section, &pairing, request.Round)
 		}
 	}
 
+	api.Audit(repository, event, database.AuditAction_EmailPairings, database.OpenClassicalAuditTarget("", ""), nil, map[string]int{
+		"round":      request.Round,
+		"emailsSent": emailsSent,
+	})
 	return api.Success(EmailPairingsResponse{OpenClassical: result, EmailsSent: emailsSent}), nil
 }
 
-func sendPairingEmail(section *database.OpenClassicalSection, pairing *database.OpenClassicalPairing, round int) int {
+func sendPairingEmail(ctx context.Context, section *database.OpenClassicalSection, pairing *database.OpenClassicalPairing, round int) int {
+	logger := log.FromContext(ctx)
 	white, ok := section.Players[pairing.White.Username]
 	if !ok {
-		log.Debugf("Skipping pairing because white player not found: %v", pairing)
+		logger.Debugf("Skipping pairing because white player not found: %v", pairing)
 		return 0
 	}
 	black, ok := section.Players[pairing.Black.Username]
 	if !ok {
-		log.Debugf("Skipping pairing because black player not found: %v", pairing)
+		logger.Debugf("Skipping pairing because black player not found: %v", pairing)
 		return 0
 	}
 
@@ -160,7 +163,7 @@ func sendPairingEmail(section *database.OpenClassicalSection, pairing *database.
 
 	templateDataStr, err := json.Marshal(templateData)
 	if err != nil {
-		log.Errorf("Failed to marshal template data: %v", err)
+		logger.Errorf("Failed to marshal template data: %v", err)
 		return 0
 	}
 
@@ -173,7 +176,7 @@ func sendPairingEmail(section *database.OpenClassicalSection, pairing *database.
 		TemplateData: aws.String(string(templateDataStr)),
 	}
 	if _, err := Ses.SendTemplatedEmail(input); err != nil {
-		log.Errorf("Failed to send templated email: %v", err)
+		logger.Errorf("Failed to send templated email: %v", err)
 		return 0
 	}
 
@@ -9,20 +9,16 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	region := event.QueryStringParameters["region"]
 	sectionName := event.QueryStringParameters["section"]
 	if region == "" || sectionName == "" {
@@ -40,7 +36,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
+	if !user.HasPermission(database.Permission_ManageTournaments) {
 		err := errors.New(403, "Invalid request: you are not a tournament admin", "")
 		return api.Failure(err), nil
 	}
@@ -1,20 +1,18 @@
 // Implements a Lambda handler that allows the caller to set the pairings
 // for a round of the open classical.
 //
-// The caller must be an admin or tournament admin.
+// The caller must have the MANAGE_TOURNAMENTS permission.
 package main
 
 import (
 	"context"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
@@ -29,85 +27,85 @@ type SetPairingsRequest struct {
 	CsvData            string `json:"csvData"`
 }
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Handle(handler,
+		api.RequireUser(repository),
+		api.RequirePermission(database.Permission_ManageTournaments),
+		api.DecodeJSON[SetPairingsRequest](),
+		api.Validate(validateRequest),
+	))
 }
 
-func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
-	info := api.GetUserInfo(event)
-	if info.Username == "" {
-		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+func validateRequest(request *SetPairingsRequest) error {
+	if request.CloseRegistrations {
+		return nil
 	}
-
-	user, err := repository.GetUser(info.Username)
-	if err != nil {
-		return api.Failure(err), nil
+	if request.Region == "" {
+		return errors.New(400, "Invalid request: region is required", "")
+	}
+	if request.Section == "" {
+		return errors.New(400, "Invalid request: section is required", "")
 	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
-		return api.Failure(errors.New(403, "Invalid request: you are not a tournament admin", "")), nil
+	if request.Round < MIN_ROUND || request.Round > MAX_ROUND {
+		return errors.New(400, fmt.Sprintf("Invalid request: round must be between %d and %d", MIN_ROUND, MAX_ROUND), "")
 	}
-
-	request := SetPairingsRequest{}
-	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
-		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
+	if request.CsvData == "" {
+		return errors.New(400, "Invalid request: csvData is required", "")
 	}
+	return nil
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	request := api.Body[SetPairingsRequest](ctx)
 
+	var openClassical *database.OpenClassical
+	var err error
 	if request.CloseRegistrations {
-		return handleCloseRegistrations(), nil
+		openClassical, err = repository.OpenClassicalCloseRegistrations()
+		if err == nil {
+			api.Audit(repository, event, database.AuditAction_CloseRegistrations, database.OpenClassicalAuditTarget("", ""), nil, nil)
+		}
+	} else {
+		openClassical, err = setPairings(event, request)
 	}
 
-	return handlePairings(request), nil
-}
-
-func handleCloseRegistrations() api.Response {
-	openClassical, err := repository.OpenClassicalCloseRegistrations()
 	if err != nil {
-		return api.Failure(err)
+		return api.Failure(err), nil
 	}
-	return api.Success(openClassical)
+	return api.Success(openClassical), nil
 }
 
-func handlePairings(request SetPairingsRequest) api.Response {
-	if request.Region == "" {
-		return api.Failure(errors.New(400, "Invalid request: region is required", ""))
-	}
-	if request.Section == "" {
-		return api.Failure(errors.New(400, "Invalid request: section is required", ""))
-	}
-	if request.Round < MIN_ROUND || request.Round > MAX_ROUND {
-		return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: round must be between %d and %d", MIN_ROUND, MAX_ROUND), ""))
-	}
-	if request.CsvData == "" {
-		return api.Failure(errors.New(400, "Invalid request: csvData is required", ""))
-	}
-
+func setPairings(event api.Request, request *SetPairingsRequest) (*database.OpenClassical, error) {
 	openClassical, err := repository.GetOpenClassical(database.CurrentLeaderboard)
 	if err != nil {
-		return api.Failure(err)
+		return nil, err
 	}
 
 	pairings, err := getPairings(request, openClassical)
 	if err != nil {
-		return api.Failure(err)
+		return nil, err
 	}
 
 	sectionName := fmt.Sprintf("%s_%s", request.Region, request.Section)
 	section := openClassical.Sections[sectionName]
+
+	var before []database.OpenClassicalPairing
 	if request.Round-1 >= len(section.Rounds) {
 		openClassical, err = repository.OpenClassicalAddRound(request.Region, request.Section, pairings)
 	} else {
+		before = section.Rounds[request.Round-1].Pairings
 		openClassical, err = repository.OpenClassicalSetRound(request.Region, request.Section, request.Round-1, pairings)
 	}
-
 	if err != nil {
-		return api.Failure(err)
+		return nil, err
 	}
-	return api.Success(openClassical)
+
+	round := fmt.Sprintf("round%d", request.Round)
+	api.Audit(repository, event, database.AuditAction_SetPairings, database.OpenClassicalAuditTarget(request.Region, request.Section),
+		map[string]any{round: before}, map[string]any{round: pairings})
+	return openClassical, nil
 }
 
 const whiteTitleIndex = 2
@@ -118,7 +116,7 @@ const blackIndex = 8
 const blackRatingIndex = 9
 const noOpponent = "No Opponent"
 
-func getPairings(request SetPairingsRequest, openClassical *database.OpenClassical) ([]database.OpenClassicalPairing, error) {
+func getPairings(request *SetPairingsRequest, openClassical *database.OpenClassical) ([]database.OpenClassicalPairing, error) {
 	sectionName := fmt.Sprintf("%s_%s", request.Region, request.Section)
 	section, ok := openClassical.Sections[sectionName]
 	if !ok {
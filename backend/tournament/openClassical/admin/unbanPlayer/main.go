@@ -10,11 +10,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 
 type UnbanPlayerRequest struct {
 	// The username of the player to unban
@@ -22,13 +21,10 @@ type UnbanPlayerRequest struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	request := UnbanPlayerRequest{}
 	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
 		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
@@ -48,14 +44,25 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
+	if !user.HasPermission(database.Permission_ManageTournaments) {
 		err := errors.New(403, "Invalid request: you are not a tournament admin", "")
 		return api.Failure(err), nil
 	}
 
-	openClassical, err := repository.UnbanPlayer(request.Username)
+	openClassical, err := repository.GetOpenClassical(database.CurrentLeaderboard)
 	if err != nil {
 		return api.Failure(err), nil
 	}
+	var before *database.OpenClassicalPlayer
+	if player, ok := openClassical.BannedPlayers[request.Username]; ok {
+		before = &player
+	}
+
+	openClassical, err = repository.UnbanPlayer(request.Username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	api.Audit(repository, event, database.AuditAction_UnbanPlayer, database.UserAuditTarget(request.Username), before, nil)
 	return api.Success(openClassical), nil
 }
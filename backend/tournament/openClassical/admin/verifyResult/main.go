@@ -1,7 +1,7 @@
 // This package implements a Lambda handler which sets the result of a pairing
 // in the current round of the current open classical and marks it as verified.
 //
-// The caller must an admin or tournament admin.
+// The caller must have the MANAGE_TOURNAMENTS permission.
 package main
 
 import (
@@ -13,11 +13,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 
 type VerifyResultRequest struct {
 	// The region the pairing is in
@@ -40,13 +39,10 @@ type VerifyResultRequest struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	request := &VerifyResultRequest{}
 	if err := json.Unmarshal([]byte(event.Body), request); err != nil {
 		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
@@ -80,7 +76,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
+	if !user.HasPermission(database.Permission_ManageTournaments) {
 		err := errors.New(403, "Invalid request: you are not a tournament admin", "")
 		return api.Failure(err), nil
 	}
@@ -95,11 +91,18 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
+	before := openClassical.Sections[fmt.Sprintf("%s_%s", update.Region, update.Section)].Rounds[update.Round].Pairings[update.PairingIndex]
+
 	openClassical, err = repository.UpdateOpenClassicalResult(update)
 	if err != nil {
 		return api.Failure(err), nil
 	}
 
+	for _, username := range []string{update.Pairing.White.Username, update.Pairing.Black.Username} {
+		if username != "" {
+			api.Audit(repository, event, database.AuditAction_VerifyResult, database.UserAuditTarget(username), before, update.Pairing)
+		}
+	}
 	return api.Success(openClassical), nil
 }
 
@@ -1,7 +1,7 @@
 // This package implements a Lambda handler that withdraws a player from the current
 // open classical.
 //
-// The caller must be an admin or tournament admin.
+// The caller must have the MANAGE_TOURNAMENTS permission.
 package main
 
 import (
@@ -12,11 +12,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalEditor](database.DynamoDB)
 
 type WithdrawPlayerRequest struct {
 	// The Dojo username of the player to withdraw
@@ -30,13 +29,10 @@ type WithdrawPlayerRequest struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	request := WithdrawPlayerRequest{}
 	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
 		return api.Failure(errors.Wrap(400, "Invalid request: failed to unmarshal body", "", err)), nil
@@ -62,7 +58,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	if !user.IsAdmin && !user.IsTournamentAdmin {
+	if !user.HasPermission(database.Permission_ManageTournaments) {
 		err := errors.New(403, "Invalid request: you are not a tournament admin", "")
 		return api.Failure(err), nil
 	}
@@ -92,6 +88,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 		}
 	}
 
+	before := player
 	player.Status = database.OpenClassicalPlayerStatus_Withdrawn
 	player.LastActiveRound = lastActiveRound
 
@@ -99,5 +96,7 @@ func handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err != nil {
 		return api.Failure(err), nil
 	}
+
+	api.Audit(repository, event, database.AuditAction_WithdrawPlayer, database.UserAuditTarget(request.Username), before, player)
 	return api.Success(openClassical), nil
 }
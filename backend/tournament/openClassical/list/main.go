@@ -5,11 +5,10 @@ import (
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalLister](database.DynamoDB)
 
 type ListPreviousOpenClassicalsResponse struct {
 	OpenClassicals   []database.OpenClassical `json:"openClassicals"`
@@ -17,13 +16,10 @@ type ListPreviousOpenClassicalsResponse struct {
 }
 
 func main() {
-	lambda.Start(handler)
+	lambda.Start(api.Logged(handler))
 }
 
 func handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	startKey := event.QueryStringParameters["startKey"]
 	openClassicals, lastKey, err := repository.ListPreviousOpenClassicals(startKey)
 	if err != nil {
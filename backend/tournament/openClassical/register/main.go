@@ -4,19 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"slices"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/discord"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/user/ratings"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalRegistrar](database.DynamoDB)
+
+// requireVerifiedRatings is true if players must verify their Lichess account before registering.
+var requireVerifiedRatings = os.Getenv("requireVerifiedRatings") == "true"
 
 const maxByeLength = 7
 
@@ -47,13 +50,10 @@ type RegisterRequest struct {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Handle(Handler, api.Idempotent(database.Repository[database.IdempotencyStore](database.DynamoDB))))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(403, "Invalid request: user is not signed in", "")), nil
@@ -78,6 +78,11 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err := checkRequest(request); err != nil {
 		return api.Failure(err), nil
 	}
+	if requireVerifiedRatings {
+		if err := ratings.CheckVerified(user, database.Lichess, request.LichessUsername); err != nil {
+			return api.Failure(err), nil
+		}
+	}
 
 	openClassical, err := repository.GetOpenClassical(database.CurrentLeaderboard)
 	if err != nil {
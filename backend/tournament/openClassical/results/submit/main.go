@@ -14,7 +14,7 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.OpenClassicalResultSubmitter](database.DynamoDB)
 
 type SubmitResultsRequest struct {
 	Region          string `json:"region"`
@@ -53,13 +53,10 @@ type ChesscomGameResponse struct {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(403, "Invalid request: not signed in", "")), nil
@@ -71,7 +68,7 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(err), nil
 	}
 
-	if err := getGameUrl(request); err != nil {
+	if err := getGameUrl(ctx, request); err != nil {
 		return api.Failure(err), nil
 	}
 
@@ -151,23 +148,24 @@ func getPairingUpdate(openClassical *database.OpenClassical, request *SubmitResu
 	return nil, errors.New(400, fmt.Sprintf("Invalid request: round %d does not contain a pairing for %s (white) vs %s (black)", roundIdx+1, request.White, request.Black), "")
 }
 
-func getGameUrl(request *SubmitResultsRequest) error {
+func getGameUrl(ctx context.Context, request *SubmitResultsRequest) error {
 	if request.GameUrl == "" {
 		return nil
 	}
 
 	if strings.HasPrefix(request.GameUrl, "https://lichess.org/") {
-		return getLichessGame(request)
+		return getLichessGame(ctx, request)
 	}
 
 	if strings.HasPrefix(request.GameUrl, "https://www.chess.com/") {
-		return getChesscomGame(request)
+		return getChesscomGame(ctx, request)
 	}
 
 	return nil
 }
 
-func getLichessGame(request *SubmitResultsRequest) error {
+func getLichessGame(ctx context.Context, request *SubmitResultsRequest) error {
+	logger := log.FromContext(ctx)
 	gameId := strings.TrimPrefix(request.GameUrl, "https://lichess.org/")
 	gameId, _, _ = strings.Cut(gameId, "/")
 	if gameId == "" {
@@ -178,20 +176,20 @@ func getLichessGame(request *SubmitResultsRequest) error {
 		return nil
 	}
 
-	log.Debugf("Fetching Lichess game with ID %q\n", gameId)
+	logger.Debugf("Fetching Lichess game with ID %q\n", gameId)
 	resp, err := http.Get(fmt.Sprintf("https://lichess.org/api/game/%s", gameId))
 	if err != nil {
-		log.Errorf("Failed to get Lichess game: %v", err)
+		logger.Errorf("Failed to get Lichess game: %v", err)
 		return nil
 	}
 	if resp.StatusCode != 200 {
-		log.Errorf("Lichess game returned status %d", resp.StatusCode)
+		logger.Errorf("Lichess game returned status %d", resp.StatusCode)
 		return nil
 	}
 
 	var game LichessGameResponse
 	if err = json.NewDecoder(resp.Body).Decode(&game); err != nil {
-		log.Errorf("Failed to unmarshal Lichess response: %v", err)
+		logger.Errorf("Failed to unmarshal Lichess response: %v", err)
 		return nil
 	}
 
@@ -224,27 +222,28 @@ func getLichessGame(request *SubmitResultsRequest) error {
 	return nil
 }
 
-func getChesscomGame(request *SubmitResultsRequest) error {
+func getChesscomGame(ctx context.Context, request *SubmitResultsRequest) error {
+	logger := log.FromContext(ctx)
 	gameId := strings.TrimPrefix(request.GameUrl, "https://www.chess.com/game/live/")
 	gameId = strings.TrimPrefix(gameId, "https://www.chess.com/live/game/")
 	if gameId == "" {
 		return nil
 	}
 
-	log.Debugf("Fetching Chesscom game with ID %q\n", gameId)
+	logger.Debugf("Fetching Chesscom game with ID %q\n", gameId)
 	resp, err := http.Get(fmt.Sprintf("https://www.chess.com/callback/live/game/%s", gameId))
 	if err != nil {
-		log.Errorf("Failed to get Chesscom game: %v", err)
+		logger.Errorf("Failed to get Chesscom game: %v", err)
 		return nil
 	}
 	if resp.StatusCode != 200 {
-		log.Errorf("Chesscom game returned status %d", resp.StatusCode)
+		logger.Errorf("Chesscom game returned status %d", resp.StatusCode)
 		return nil
 	}
 
 	var game ChesscomGameResponse
 	if err = json.NewDecoder(resp.Body).Decode(&game); err != nil {
-		log.Errorf("Failed to unmarshal Chesscom response: %v", err)
+		logger.Errorf("Failed to unmarshal Chesscom response: %v", err)
 		return nil
 	}
 
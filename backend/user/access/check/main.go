@@ -12,18 +12,16 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/user/access"
 )
 
-var repository database.UserUpdater = database.DynamoDB
+var repository = database.Repository[database.UserUpdater](database.DynamoDB)
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	if strings.Contains(event.RawPath, "/v2") {
-		return handlerV2(event), nil
+		return handlerV2(ctx, event), nil
 	}
 
 	info := api.GetUserInfo(event)
@@ -52,7 +50,7 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 			SubscriptionTier:   aws.String(string(subscriptionTier)),
 		})
 		if err != nil {
-			log.Error("Failed UpdateUser: ", err)
+			logger.Errorf("Failed UpdateUser: %v", err)
 		}
 
 		switch user.SubscriptionStatus {
@@ -62,7 +60,7 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 			err = repository.RecordFreeTierConversion(user.DojoCohort)
 		}
 		if err != nil {
-			log.Error("Failed to update statistics: ", err)
+			logger.Errorf("Failed to update statistics: %v", err)
 		}
 	}
 
@@ -73,7 +71,8 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	return api.Success(nil), nil
 }
 
-func handlerV2(event api.Request) api.Response {
+func handlerV2(ctx context.Context, event api.Request) api.Response {
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(event)
 	user, err := repository.GetUser(info.Username)
 	if err != nil {
@@ -103,7 +102,7 @@ func handlerV2(event api.Request) api.Response {
 			SubscriptionTier:   aws.String(string(subscriptionTier)),
 		})
 		if err != nil {
-			log.Error("Failed UpdateUser: ", err)
+			logger.Errorf("Failed UpdateUser: %v", err)
 		}
 
 		if user.IsSubscribed() {
@@ -113,7 +112,7 @@ func handlerV2(event api.Request) api.Response {
 		}
 
 		if err != nil {
-			log.Error("Failed to update statistics: ", err)
+			logger.Errorf("Failed to update statistics: %v", err)
 		}
 	}
 
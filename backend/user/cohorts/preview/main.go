@@ -0,0 +1,37 @@
+// Implements a Lambda handler which previews how many users would change cohort if the
+// cohort rating boundaries were replaced by the table in the request body. The preview is
+// calculated from the rating distributions saved by the daily user statistics update, so it
+// does not include rating changes since then. Nothing is saved. New tables are published by
+// adding them to the cohorts package.
+//
+// The caller must have the MANAGE_COHORTS permission.
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/cohorts"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.CohortPreviewer](database.DynamoDB)
+
+func main() {
+	lambda.Start(api.Handle(handler,
+		api.RequireUser(repository),
+		api.RequirePermission(database.Permission_ManageCohorts),
+		api.DecodeJSON[cohorts.Table](),
+		api.Validate((*cohorts.Table).Validate),
+	))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	proposed := api.Body[cohorts.Table](ctx)
+	distributions, err := repository.ListRatingDistributions()
+	if err != nil {
+		return api.Response{}, err
+	}
+	return api.Success(cohorts.PreviewTable(distributions, cohorts.At(proposed.EffectiveDate), proposed)), nil
+}
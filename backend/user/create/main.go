@@ -15,7 +15,7 @@ import (
 
 type Event events.CognitoEventUserPoolsPostConfirmation
 
-var repository database.UserCreator = database.DynamoDB
+var repository = database.Repository[database.UserCreator](database.DynamoDB)
 
 const triggerSource = "PostConfirmation_ConfirmSignUp"
 
@@ -0,0 +1,31 @@
+// Implements a Lambda handler which returns the progress of deleting the caller's account.
+// The caller's user may already have been deleted, so only their token is checked.
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.AccountDeletionRequester](database.DynamoDB)
+
+func main() {
+	lambda.Start(api.Handle(handler))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	username := api.GetUserInfo(event).Username
+	if username == "" {
+		return api.Response{}, errors.New(400, "Invalid request: username is required", "")
+	}
+
+	deletion, err := repository.GetAccountDeletion(username)
+	if err != nil {
+		return api.Response{}, err
+	}
+	return api.Success(deletion), nil
+}
@@ -0,0 +1,33 @@
+// Implements a Lambda handler which starts deleting the caller's account. The user's
+// records are deleted or anonymised in the background by the deletion run handler, and
+// the progress can be checked with the deletion get handler. If the account is already
+// being deleted, the existing deletion is returned.
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type AccountDeletionRequester interface {
+	database.UserGetter
+	database.AccountDeletionRequester
+}
+
+var repository = database.Repository[AccountDeletionRequester](database.DynamoDB)
+
+func main() {
+	lambda.Start(api.Handle(handler, api.RequireUser(repository)))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	user := api.UserFromContext(ctx)
+	deletion, err := repository.CreateAccountDeletion(user.Username)
+	if err != nil {
+		return api.Response{}, err
+	}
+	return api.Success(deletion), nil
+}
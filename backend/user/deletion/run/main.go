@@ -0,0 +1,387 @@
+// Implements a Lambda handler which runs account deletions. Each deletion runs the steps in
+// database.AccountDeletionSteps one page at a time, deleting the user's records or anonymising
+// those which other users rely on, such as comments on their games. The deletion is saved after
+// each page, so that it resumes from where it stopped if the handler fails or times out.
+//
+// The handler is triggered by the account deletions table's stream, so that new deletions start
+// as soon as they are requested, and on a schedule, which resumes deletions which did not finish.
+// Scheduled invocations have no stream records.
+//
+// The audit log is not modified, as it must be retained for moderation.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.AccountDeleter](database.DynamoDB)
+
+const (
+	// resumeDelay is the time since a deletion was last saved before the scheduled run
+	// resumes it. Newer deletions are left to the invocation which is already running them.
+	resumeDelay = 2 * time.Minute
+
+	// deadlineMargin is the time left before the Lambda deadline at which a deletion stops
+	// running pages, so that the last page can be saved.
+	deadlineMargin = 30 * time.Second
+)
+
+// RunResult summarizes a run of the handler.
+type RunResult struct {
+	// The number of deletions which finished.
+	Completed int `json:"completed"`
+
+	// The number of deletions which are still in progress.
+	InProgress int `json:"inProgress"`
+}
+
+// stepFunc runs one page of a step for the provided username. It returns the number of
+// records deleted or anonymised and the start key of the next page.
+type stepFunc func(username, startKey string) (int, string, error)
+
+var steps = map[database.AccountDeletionStep]stepFunc{
+	database.AccountDeletionStep_Followers:     deleteFollowers,
+	database.AccountDeletionStep_Following:     deleteFollowing,
+	database.AccountDeletionStep_Clubs:         leaveClubs,
+	database.AccountDeletionStep_Events:        leaveEvents,
+	database.AccountDeletionStep_Timeline:      deleteTimeline,
+	database.AccountDeletionStep_Newsfeed:      deleteNewsfeed,
+	database.AccountDeletionStep_NewsfeedPosts: deleteNewsfeedPosts,
+	database.AccountDeletionStep_Games:         deleteGames,
+	database.AccountDeletionStep_Comments:      anonymizeComments,
+	database.AccountDeletionStep_Notifications: deleteNotifications,
+	database.AccountDeletionStep_Exams:         deleteExams,
+	database.AccountDeletionStep_Directories:   deleteDirectories,
+	database.AccountDeletionStep_Graduations:   deleteGraduations,
+	database.AccountDeletionStep_YearReviews:   deleteYearReviews,
+	database.AccountDeletionStep_User:          deleteUser,
+}
+
+func main() {
+	lambda.Start(Handler)
+}
+
+func Handler(ctx context.Context, event events.DynamoDBEvent) (*RunResult, error) {
+	if len(event.Records) == 0 {
+		return resumeStale(ctx, time.Now().Add(-resumeDelay))
+	}
+
+	result := &RunResult{}
+	for _, record := range event.Records {
+		if !isNewDeletion(record) {
+			continue
+		}
+
+		deletion := database.AccountDeletion{}
+		if err := unmarshalStreamImage(record.Change.NewImage, &deletion); err != nil {
+			log.Errorf("Failed to unmarshal account deletion: %v", err)
+			continue
+		}
+		run(ctx, &deletion, result)
+	}
+	return result, nil
+}
+
+// isNewDeletion returns true if the provided stream record is a newly requested deletion.
+// Records written by the handler itself while saving progress are ignored.
+func isNewDeletion(record events.DynamoDBEventRecord) bool {
+	switch record.EventName {
+	case "INSERT":
+		return true
+	case "MODIFY":
+		// A deletion is requested again after an earlier one completed, such as when the
+		// user signed up again with the same username.
+		oldStatus := record.Change.OldImage["status"]
+		newStatus := record.Change.NewImage["status"]
+		return oldStatus.DataType() == events.DataTypeString &&
+			oldStatus.String() == string(database.AccountDeletionStatus_Complete) &&
+			newStatus.DataType() == events.DataTypeString &&
+			newStatus.String() == string(database.AccountDeletionStatus_InProgress)
+	}
+	return false
+}
+
+// resumeStale runs the in-progress deletions which were last saved before the provided time.
+func resumeStale(ctx context.Context, before time.Time) (*RunResult, error) {
+	result := &RunResult{}
+	for deletion, err := range database.All(ctx, repository.ListAccountDeletions) {
+		if err != nil {
+			return result, err
+		}
+
+		updatedAt, err := time.Parse(time.RFC3339, deletion.UpdatedAt)
+		if err == nil && updatedAt.After(before) {
+			continue
+		}
+		run(ctx, &deletion, result)
+	}
+
+	log.Infof("Resumed account deletions: %+v", result)
+	return result, nil
+}
+
+// run runs pages of the provided deletion until it is complete, a page fails or the
+// context's deadline is close.
+func run(ctx context.Context, deletion *database.AccountDeletion, result *RunResult) {
+	for deletion.Status == database.AccountDeletionStatus_InProgress && !nearDeadline(ctx) {
+		step, ok := steps[deletion.Step]
+		if !ok {
+			log.Errorf("Account deletion of %s has unknown step %q", deletion.Username, deletion.Step)
+			break
+		}
+
+		count, nextKey, err := step(deletion.Username, deletion.StartKey)
+		if err != nil {
+			// The page is retried by the next scheduled run. Records deleted before the
+			// failure are not returned by the page again.
+			log.Errorf("Account deletion of %s failed on step %s: %v", deletion.Username, deletion.Step, err)
+			deletion.LastError = err.Error()
+			deletion.UpdatedAt = time.Now().Format(time.RFC3339)
+			save(deletion)
+			break
+		}
+
+		deletion.Advance(count, nextKey)
+		if !save(deletion) {
+			break
+		}
+	}
+
+	if deletion.Status == database.AccountDeletionStatus_Complete {
+		log.Infof("Completed account deletion of %s: %v", deletion.Username, deletion.Progress)
+		result.Completed++
+	} else {
+		result.InProgress++
+	}
+}
+
+// save saves the provided deletion and returns true if it succeeded.
+func save(deletion *database.AccountDeletion) bool {
+	if err := repository.PutAccountDeletion(deletion); err != nil {
+		log.Errorf("Failed to save account deletion of %s: %v", deletion.Username, err)
+		return false
+	}
+	return true
+}
+
+// nearDeadline returns true if the context's deadline is within deadlineMargin.
+func nearDeadline(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < deadlineMargin
+}
+
+// deleteFollowers removes a page of the users following username. The follower counts
+// of the followers are updated.
+func deleteFollowers(username, startKey string) (int, string, error) {
+	entries, lastKey, err := repository.ListFollowers(username, startKey)
+	if err != nil {
+		return 0, "", err
+	}
+	for i, entry := range entries {
+		if err := repository.DeleteFollower(username, entry.Follower); err != nil {
+			return i, "", err
+		}
+	}
+	return len(entries), lastKey, nil
+}
+
+// deleteFollowing removes a page of the users followed by username. The follower counts
+// of the followed users are updated.
+func deleteFollowing(username, startKey string) (int, string, error) {
+	entries, lastKey, err := repository.ListFollowing(username, startKey)
+	if err != nil {
+		return 0, "", err
+	}
+	for i, entry := range entries {
+		if err := repository.DeleteFollower(entry.Poster, username); err != nil {
+			return i, "", err
+		}
+	}
+	return len(entries), lastKey, nil
+}
+
+// leaveClubs deletes a page of the clubs owned by username, and removes username from
+// the members and join requests of the other clubs.
+func leaveClubs(username, startKey string) (int, string, error) {
+	clubs, lastKey, err := repository.ScanClubsByUser(username, startKey)
+	if err != nil {
+		return 0, "", err
+	}
+
+	for i, club := range clubs {
+		if club.Owner == username {
+			_, err = repository.DeleteClub(club.Id)
+		} else {
+			if _, ok := club.Members[username]; ok {
+				_, err = repository.RemoveClubMember(club.Id, username)
+			}
+			if _, ok := club.JoinRequests[username]; ok && err == nil {
+				_, err = repository.DeleteClubJoinRequest(club.Id, username)
+			}
+		}
+		if err != nil {
+			return i, "", err
+		}
+	}
+	return len(clubs), lastKey, nil
+}
+
+// leaveEvents removes username from a page of the events they own or participate in. Owned
+// events with participants are transferred to a participant, and other owned events are deleted.
+func leaveEvents(username, startKey string) (int, string, error) {
+	userEvents, lastKey, err := repository.ScanEventsByUser(username, startKey)
+	if err != nil {
+		return 0, "", err
+	}
+
+	for i, event := range userEvents {
+		switch {
+		case event.Owner == username && len(event.Participants) == 0:
+			_, err = repository.DeleteEvent(event.Id)
+		case event.Owner == username:
+			_, err = repository.LeaveEvent(&event, nil, false)
+		default:
+			_, err = repository.LeaveEvent(&event, event.Participants[username], false)
+		}
+		if err != nil {
+			return i, "", err
+		}
+	}
+	return len(userEvents), lastKey, nil
+}
+
+// deleteTimeline removes a page of username's timeline entries.
+func deleteTimeline(username, startKey string) (int, string, error) {
+	entries, lastKey, err := repository.ListTimelineEntries(username, startKey)
+	if err != nil {
+		return 0, "", err
+	}
+	count, err := repository.DeleteTimelineEntries(entries)
+	return count, lastKey, err
+}
+
+// deleteNewsfeed removes a page of the entries in username's newsfeed.
+func deleteNewsfeed(username, startKey string) (int, string, error) {
+	return repository.DeleteNewsfeedPage(username, startKey)
+}
+
+// deleteNewsfeedPosts removes a page of username's posts from other users' newsfeeds.
+func deleteNewsfeedPosts(username, startKey string) (int, string, error) {
+	return repository.DeleteNewsfeedEntriesByPosterPage(username, startKey)
+}
+
+// deleteGames removes a page of username's games.
+func deleteGames(username, startKey string) (int, string, error) {
+	games, lastKey, err := repository.ListGamesByOwner(true, username, "", "", startKey)
+	if err != nil {
+		return 0, "", err
+	}
+	for i, game := range games {
+		if _, err := repository.DeleteGame(username, string(game.Cohort), game.Id); err != nil {
+			return i, "", err
+		}
+	}
+	return len(games), lastKey, nil
+}
+
+// anonymizeComments anonymises username's comments on a page of the games with comments.
+// The comments are kept, as other users' replies rely on them.
+func anonymizeComments(username, startKey string) (int, string, error) {
+	games, lastKey, err := repository.ScanGameComments(startKey)
+	if err != nil {
+		return 0, "", err
+	}
+
+	total := 0
+	for _, game := range games {
+		count, err := repository.AnonymizeGameComments(&game, username)
+		total += count
+		if err != nil {
+			return total, "", err
+		}
+	}
+	return total, lastKey, nil
+}
+
+// deleteNotifications removes a page of username's notifications.
+func deleteNotifications(username, startKey string) (int, string, error) {
+	return repository.DeleteNotificationsPage(username, startKey)
+}
+
+// deleteExams removes a page of username's exam answers.
+func deleteExams(username, startKey string) (int, string, error) {
+	var answers []database.ExamAnswer
+	lastKey, err := repository.ListExams(database.ExamType(username), startKey, &answers)
+	if err != nil {
+		return 0, "", err
+	}
+	for i := range answers {
+		if err := repository.DeleteExamAnswer(&answers[i]); err != nil {
+			return i, "", err
+		}
+	}
+	return len(answers), lastKey, nil
+}
+
+// deleteDirectories removes a page of username's directories.
+func deleteDirectories(username, startKey string) (int, string, error) {
+	return repository.DeleteDirectoriesPage(username, startKey)
+}
+
+// deleteGraduations removes a page of username's graduations.
+func deleteGraduations(username, startKey string) (int, string, error) {
+	return repository.DeleteGraduationsPage(username, startKey)
+}
+
+// deleteYearReviews removes a page of username's year reviews.
+func deleteYearReviews(username, startKey string) (int, string, error) {
+	return repository.DeleteYearReviewsPage(username, startKey)
+}
+
+// deleteUser removes username's user. It does nothing if the user was already deleted.
+func deleteUser(username, startKey string) (int, string, error) {
+	if _, err := repository.GetUser(username); err != nil {
+		var apiErr *errors.Error
+		if errors.As(err, &apiErr) && apiErr.Code == 404 {
+			return 0, "", nil
+		}
+		return 0, "", err
+	}
+	if err := repository.DeleteUser(username); err != nil {
+		return 0, "", err
+	}
+	return 1, "", nil
+}
+
+// unmarshalStreamImage converts events.DynamoDBAttributeValue to struct
+func unmarshalStreamImage(attribute map[string]events.DynamoDBAttributeValue, out any) error {
+	dbAttrMap := make(map[string]*dynamodb.AttributeValue)
+
+	for k, v := range attribute {
+		var dbAttr dynamodb.AttributeValue
+		bytes, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(bytes, &dbAttr); err != nil {
+			return err
+		}
+		dbAttrMap[k] = &dbAttr
+	}
+
+	err := dynamodbattribute.UnmarshalMap(dbAttrMap, out)
+	return errors.Wrap(500, "Temporary server error", "Failed to unmarshal stream image", err)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+func TestHandler(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	users := make(map[string]*database.User)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		user, err := repo.CreateUser(username, username+"@example.com", username, nil)
+		if err != nil {
+			t.Fatalf("CreateUser got err %v", err)
+		}
+		users[username] = user
+	}
+
+	// bob follows alice, and alice follows carol.
+	for _, pair := range [][2]string{{"alice", "bob"}, {"carol", "alice"}} {
+		if _, err := repo.CreateFollower(users[pair[0]], users[pair[1]]); err != nil {
+			t.Fatalf("CreateFollower got err %v", err)
+		}
+	}
+
+	for _, club := range []*database.Club{{Id: "aliceClub", Owner: "alice"}, {Id: "bobClub", Owner: "bob"}} {
+		if err := repo.CreateClub(club); err != nil {
+			t.Fatalf("CreateClub got err %v", err)
+		}
+	}
+	if _, err := repo.JoinClub("aliceClub", "bob", false); err != nil {
+		t.Fatalf("JoinClub got err %v", err)
+	}
+	if _, err := repo.JoinClub("bobClub", "alice", false); err != nil {
+		t.Fatalf("JoinClub got err %v", err)
+	}
+
+	for _, event := range []*database.Event{
+		{Id: "empty", Owner: "alice"},
+		{Id: "owned", Owner: "alice", Participants: map[string]*database.Participant{"carol": {Username: "carol"}}},
+		{Id: "joined", Owner: "bob", Participants: map[string]*database.Participant{"alice": {Username: "alice"}}},
+	} {
+		if err := repo.SetEvent(event); err != nil {
+			t.Fatalf("SetEvent got err %v", err)
+		}
+	}
+
+	entry := &database.TimelineEntry{TimelineEntryKey: database.TimelineEntryKey{Owner: "alice", Id: "2024-01-01_1"}}
+	if err := repo.PutTimelineEntry(entry); err != nil {
+		t.Fatalf("PutTimelineEntry got err %v", err)
+	}
+	_, err := repo.PutNewsfeedEntries([]database.NewsfeedEntry{
+		{NewsfeedId: "alice", SortKey: "1", Poster: "carol", TimelineId: "carol_1"},
+		{NewsfeedId: "bob", SortKey: "1", Poster: "alice", TimelineId: "alice_1"},
+		{NewsfeedId: "bob", SortKey: "2", Poster: "carol", TimelineId: "carol_2"},
+	})
+	if err != nil {
+		t.Fatalf("PutNewsfeedEntries got err %v", err)
+	}
+
+	_, err = repo.BatchPutGames([]*database.Game{
+		{Cohort: "1500-1600", Id: "2024.01.01_1", Owner: "alice"},
+		{Cohort: "1500-1600", Id: "2024.01.01_2", Owner: "bob"},
+	})
+	if err != nil {
+		t.Fatalf("BatchPutGames got err %v", err)
+	}
+	comment := &database.PositionComment{
+		Id:                 "c1",
+		Fen:                pgn.StartingFEN,
+		Owner:              database.CommentOwner{Username: "alice", DisplayName: "alice"},
+		Content:            "Nice move",
+		SuggestedVariation: "1. e4",
+	}
+	if _, err := repo.PutComment("1500-1600", "2024.01.01_2", comment, false); err != nil {
+		t.Fatalf("PutComment got err %v", err)
+	}
+	reply := &database.PositionComment{
+		Id:        "c2",
+		Fen:       pgn.StartingFEN,
+		Owner:     database.CommentOwner{Username: "bob", DisplayName: "bob"},
+		Content:   "Thanks",
+		ParentIds: "c1",
+	}
+	if _, err := repo.PutComment("1500-1600", "2024.01.01_2", reply, false); err != nil {
+		t.Fatalf("PutComment got err %v", err)
+	}
+
+	if _, err := repo.CreateAccountDeletion("alice"); err != nil {
+		t.Fatalf("CreateAccountDeletion got err %v", err)
+	}
+
+	event := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{{
+		EventName: "INSERT",
+		Change: events.DynamoDBStreamRecord{NewImage: map[string]events.DynamoDBAttributeValue{
+			"username":   events.NewStringAttribute("alice"),
+			"status":     events.NewStringAttribute(string(database.AccountDeletionStatus_InProgress)),
+			"step":       events.NewStringAttribute(string(database.AccountDeletionSteps[0])),
+			"totalSteps": events.NewNumberAttribute("15"),
+		}},
+	}}}
+	result, err := Handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if diff := cmp.Diff(&RunResult{Completed: 1}, result); diff != "" {
+		t.Errorf("Handler result mismatch (-want +got):\n%s", diff)
+	}
+
+	deletion, err := repo.GetAccountDeletion("alice")
+	if err != nil {
+		t.Fatalf("GetAccountDeletion got err %v", err)
+	}
+	if deletion.Status != database.AccountDeletionStatus_Complete {
+		t.Errorf("Deletion got status %s, want %s", deletion.Status, database.AccountDeletionStatus_Complete)
+	}
+	wantProgress := map[database.AccountDeletionStep]int{
+		database.AccountDeletionStep_Followers:     1,
+		database.AccountDeletionStep_Following:     1,
+		database.AccountDeletionStep_Clubs:         2,
+		database.AccountDeletionStep_Events:        3,
+		database.AccountDeletionStep_Timeline:      1,
+		database.AccountDeletionStep_Newsfeed:      1,
+		database.AccountDeletionStep_NewsfeedPosts: 1,
+		database.AccountDeletionStep_Games:         1,
+		database.AccountDeletionStep_Directories:   2,
+		database.AccountDeletionStep_Comments:      1,
+		database.AccountDeletionStep_User:          1,
+	}
+	for step, count := range deletion.Progress {
+		if count == 0 {
+			delete(deletion.Progress, step)
+		}
+	}
+	if diff := cmp.Diff(wantProgress, deletion.Progress); diff != "" {
+		t.Errorf("Deletion progress mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := repo.GetUser("alice"); err == nil {
+		t.Errorf("GetUser(alice) got nil err, want user to be deleted")
+	}
+	bob, _ := repo.GetUser("bob")
+	carol, _ := repo.GetUser("carol")
+	if bob.FollowingCount != 0 || carol.FollowerCount != 0 {
+		t.Errorf("Follow counts got bob following %d and carol followers %d, want 0", bob.FollowingCount, carol.FollowerCount)
+	}
+	if len(bob.Clubs) != 1 || bob.Clubs[0] != "bobClub" {
+		t.Errorf("bob.Clubs got %v, want [bobClub]", bob.Clubs)
+	}
+
+	if _, err := repo.GetClub("aliceClub"); err == nil {
+		t.Errorf("GetClub(aliceClub) got nil err, want club to be deleted")
+	}
+	bobClub, _ := repo.GetClub("bobClub")
+	if _, ok := bobClub.Members["alice"]; ok {
+		t.Errorf("bobClub.Members got alice, want alice removed")
+	}
+
+	if _, err := repo.GetEvent("empty"); err == nil {
+		t.Errorf("GetEvent(empty) got nil err, want event to be deleted")
+	}
+	owned, _ := repo.GetEvent("owned")
+	if owned.Owner != "carol" || len(owned.Participants) != 0 {
+		t.Errorf("owned event got owner %s and participants %v, want owner carol and no participants", owned.Owner, owned.Participants)
+	}
+	joined, _ := repo.GetEvent("joined")
+	if len(joined.Participants) != 0 {
+		t.Errorf("joined event got participants %v, want none", joined.Participants)
+	}
+
+	entries, _, _ := repo.ListTimelineEntries("alice", "")
+	if len(entries) != 0 {
+		t.Errorf("ListTimelineEntries got %d entries, want 0", len(entries))
+	}
+	newsfeed, _, _ := repo.ListNewsfeedEntries("bob", "", "", 10)
+	if len(newsfeed) != 1 || newsfeed[0].Poster != "carol" {
+		t.Errorf("bob's newsfeed got %v, want only carol's entry", newsfeed)
+	}
+
+	if _, err := repo.GetGame("1500-1600", "2024.01.01_1"); err == nil {
+		t.Errorf("GetGame got nil err, want alice's game to be deleted")
+	}
+	game, err := repo.GetGame("1500-1600", "2024.01.01_2")
+	if err != nil {
+		t.Fatalf("GetGame got err %v", err)
+	}
+	got := game.PositionComments[pgn.StartingFEN]["c1"]
+	if got.Owner.Username != database.DeletedUsername || got.Content != "[deleted]" || got.SuggestedVariation != "" {
+		t.Errorf("Comment got owner %s, content %q and variation %q, want anonymised comment", got.Owner.Username, got.Content, got.SuggestedVariation)
+	}
+	if got.Replies["c2"].Content != "Thanks" {
+		t.Errorf("Reply got content %q, want reply to be kept", got.Replies["c2"].Content)
+	}
+}
+
+func TestIsNewDeletion(t *testing.T) {
+	status := func(s database.AccountDeletionStatus) map[string]events.DynamoDBAttributeValue {
+		return map[string]events.DynamoDBAttributeValue{"status": events.NewStringAttribute(string(s))}
+	}
+
+	table := []struct {
+		name   string
+		record events.DynamoDBEventRecord
+		want   bool
+	}{
+		{
+			name:   "Insert",
+			record: events.DynamoDBEventRecord{EventName: "INSERT", Change: events.DynamoDBStreamRecord{NewImage: status(database.AccountDeletionStatus_InProgress)}},
+			want:   true,
+		},
+		{
+			name: "Progress",
+			record: events.DynamoDBEventRecord{EventName: "MODIFY", Change: events.DynamoDBStreamRecord{
+				OldImage: status(database.AccountDeletionStatus_InProgress),
+				NewImage: status(database.AccountDeletionStatus_InProgress),
+			}},
+		},
+		{
+			name: "Completed",
+			record: events.DynamoDBEventRecord{EventName: "MODIFY", Change: events.DynamoDBStreamRecord{
+				OldImage: status(database.AccountDeletionStatus_InProgress),
+				NewImage: status(database.AccountDeletionStatus_Complete),
+			}},
+		},
+		{
+			name: "RequestedAgain",
+			record: events.DynamoDBEventRecord{EventName: "MODIFY", Change: events.DynamoDBStreamRecord{
+				OldImage: status(database.AccountDeletionStatus_Complete),
+				NewImage: status(database.AccountDeletionStatus_InProgress),
+			}},
+			want: true,
+		},
+		{
+			name:   "Remove",
+			record: events.DynamoDBEventRecord{EventName: "REMOVE"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isNewDeletion(tc.record); got != tc.want {
+				t.Errorf("isNewDeletion got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
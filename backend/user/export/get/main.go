@@ -0,0 +1,48 @@
+// Implements a Lambda handler which returns the caller's most recent data export. If the
+// export is complete and has not expired, the response includes a short-lived download URL.
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.DataExportRequester](database.DynamoDB)
+var mediaStore database.MediaStore = database.S3
+var bucket = os.Getenv("dataExportsBucket")
+
+// downloadTTL is how long the returned download URL is valid.
+const downloadTTL = 15 * time.Minute
+
+type GetDataExportResponse struct {
+	*database.DataExport
+
+	// A URL which downloads the export. Only set if the export is complete and has not expired.
+	DownloadUrl string `json:"downloadUrl,omitempty"`
+}
+
+func main() {
+	lambda.Start(api.Handle(handler, api.RequireUser(repository)))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	user := api.UserFromContext(ctx)
+	export, err := repository.GetDataExport(user.Username)
+	if err != nil {
+		return api.Response{}, err
+	}
+
+	response := GetDataExportResponse{DataExport: export}
+	if export.Status == database.DataExportStatus_Complete && !export.IsExpired() {
+		response.DownloadUrl, err = mediaStore.PresignDownload(bucket, export.Key, "chessdojo-data-export.zip", downloadTTL)
+		if err != nil {
+			return api.Response{}, err
+		}
+	}
+	return api.Success(response), nil
+}
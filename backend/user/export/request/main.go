@@ -0,0 +1,28 @@
+// Implements a Lambda handler which starts exporting the data stored about the caller.
+// The export is built in the background by the export run handler, and can be downloaded
+// using the export get handler once it is complete. If an export is already in progress,
+// the existing export is returned.
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.DataExportRequester](database.DynamoDB)
+
+func main() {
+	lambda.Start(api.Handle(handler, api.RequireUser(repository)))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	user := api.UserFromContext(ctx)
+	export, err := repository.CreateDataExport(user.Username)
+	if err != nil {
+		return api.Response{}, err
+	}
+	return api.Success(export), nil
+}
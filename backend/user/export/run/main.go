@@ -0,0 +1,372 @@
+// Implements a Lambda handler which builds data exports. The handler gathers everything
+// stored about the user into a zip file of JSON files, with the PGN of each of their games
+// in a separate file. The zip file is uploaded to the data exports bucket, and the user is
+// notified on the site and by email that it can be downloaded until it expires.
+//
+// The handler is triggered by the data exports table's stream when an export is requested.
+// Records are saved using the names of the database attributes, so fields which are not
+// returned by the API, such as the user's email and payment info, are included.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.DataExporter](database.DynamoDB)
+var mediaStore database.MediaStore = database.S3
+var bucket = os.Getenv("dataExportsBucket")
+var frontendHost = os.Getenv("frontendHost")
+
+var sesInstance = ses.New(session.Must(session.NewSession()))
+
+// sendEmail sends a plain text email. It is replaced in tests.
+var sendEmail = func(to, subject, body string) error {
+	_, err := sesInstance.SendEmail(&ses.SendEmailInput{
+		Destination: &ses.Destination{
+			ToAddresses: []*string{aws.String(to)},
+		},
+		Message: &ses.Message{
+			Body: &ses.Body{
+				Text: &ses.Content{
+					Charset: aws.String("UTF-8"),
+					Data:    aws.String(body),
+				},
+			},
+			Subject: &ses.Content{
+				Charset: aws.String("UTF-8"),
+				Data:    aws.String(subject),
+			},
+		},
+		Source: aws.String("ChessDojo <no-reply@mail.chessdojo.club>"),
+	})
+	return errors.Wrap(500, "Temporary server error", "Failed to send email", err)
+}
+
+// AuthoredComment is a comment the user wrote on a game.
+type AuthoredComment struct {
+	Cohort  database.DojoCohort `json:"cohort"`
+	GameId  string              `json:"gameId"`
+	Comment any                 `json:"comment"`
+}
+
+func main() {
+	lambda.Start(Handler)
+}
+
+func Handler(ctx context.Context, event events.DynamoDBEvent) error {
+	for _, record := range event.Records {
+		if record.EventName != "INSERT" && record.EventName != "MODIFY" {
+			continue
+		}
+
+		export := database.DataExport{}
+		if err := unmarshalStreamImage(record.Change.NewImage, &export); err != nil {
+			log.Errorf("Failed to unmarshal data export: %v", err)
+			continue
+		}
+		if export.Status != database.DataExportStatus_InProgress {
+			// The record was saved by this handler when it finished.
+			continue
+		}
+		run(ctx, &export)
+	}
+	return nil
+}
+
+// run builds and uploads the provided export, and notifies the user when it is complete.
+// Failures are saved on the export, so that the user can request it again.
+func run(ctx context.Context, export *database.DataExport) {
+	user, err := repository.GetUser(export.Username)
+	if err == nil {
+		err = build(ctx, user, export)
+	}
+	if err != nil {
+		log.Errorf("Failed to export data of %s: %v", export.Username, err)
+		export.Fail(err)
+		if err := repository.PutDataExport(export); err != nil {
+			log.Errorf("Failed to save data export of %s: %v", export.Username, err)
+		}
+		return
+	}
+
+	if err := repository.PutDataExport(export); err != nil {
+		log.Errorf("Failed to save data export of %s: %v", export.Username, err)
+		return
+	}
+	notify(user, export)
+}
+
+// build writes the zip file of the user's data, uploads it and marks the export as complete.
+func build(ctx context.Context, user *database.User, export *database.DataExport) error {
+	username := user.Username
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	if err := writeJSON(zw, "user.json", user); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name  string
+		fetch func() (any, error)
+	}{
+		{"timeline.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]*database.TimelineEntry, string, error) {
+				return repository.ListTimelineEntries(username, startKey)
+			})
+		}},
+		{"followers.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]database.FollowerEntry, string, error) {
+				return repository.ListFollowers(username, startKey)
+			})
+		}},
+		{"following.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]database.FollowerEntry, string, error) {
+				return repository.ListFollowing(username, startKey)
+			})
+		}},
+		{"notifications.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]database.Notification, string, error) {
+				return repository.ListNotifications(username, startKey)
+			})
+		}},
+		{"exams.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]database.ExamAnswer, string, error) {
+				var answers []database.ExamAnswer
+				lastKey, err := repository.ListExams(database.ExamType(username), startKey, &answers)
+				return answers, lastKey, err
+			})
+		}},
+		{"graduations.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]database.Graduation, string, error) {
+				return repository.ListGraduationsByOwner(username, startKey)
+			})
+		}},
+		{"yearReviews.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]database.YearReview, string, error) {
+				return repository.ListYearReviews(username, startKey)
+			})
+		}},
+		{"events.json", func() (any, error) {
+			return collect(ctx, func(startKey string) ([]database.Event, string, error) {
+				return repository.ScanEventsByUser(username, startKey)
+			})
+		}},
+		{"comments.json", func() (any, error) {
+			return authoredComments(ctx, username)
+		}},
+	}
+	for _, file := range files {
+		records, err := file.fetch()
+		if err != nil {
+			return err
+		}
+		if err := writeJSON(zw, file.name, records); err != nil {
+			return err
+		}
+	}
+
+	if err := writeGames(ctx, zw, username); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed to close zip file", err)
+	}
+
+	key := fmt.Sprintf("%s/%s.zip", username, time.Now().Format("2006-01-02T15-04-05"))
+	if err := mediaStore.Upload(bucket, key, "application/zip", buf); err != nil {
+		return err
+	}
+	export.Complete(key)
+	return nil
+}
+
+// collect returns all the items returned by fetch.
+func collect[T any](ctx context.Context, fetch database.PageFetcher[T]) ([]T, error) {
+	items := []T{}
+	for item, err := range database.All(ctx, fetch) {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// writeGames writes games.json, which contains the user's games without their PGNs, and the
+// PGN of each game to games/<id>.pgn.
+func writeGames(ctx context.Context, zw *zip.Writer, username string) error {
+	summaries, err := collect(ctx, func(startKey string) ([]*database.Game, string, error) {
+		return repository.ListGamesByOwner(true, username, "", "", startKey)
+	})
+	if err != nil {
+		return err
+	}
+
+	games := make([]any, 0, len(summaries))
+	for _, summary := range summaries {
+		game, err := repository.GetGame(string(summary.Cohort), summary.Id)
+		if err != nil {
+			return err
+		}
+
+		w, err := zw.Create("games/" + fileName(game.Id) + ".pgn")
+		if err != nil {
+			return errors.Wrap(500, "Temporary server error", "Failed to create PGN file", err)
+		}
+		if _, err := w.Write([]byte(game.Pgn)); err != nil {
+			return errors.Wrap(500, "Temporary server error", "Failed to write PGN file", err)
+		}
+
+		game.Pgn = ""
+		value, err := exportValue(game)
+		if err != nil {
+			return err
+		}
+		if m, ok := value.(map[string]any); ok {
+			delete(m, "pgn")
+		}
+		games = append(games, value)
+	}
+	return writeJSON(zw, "games.json", games)
+}
+
+// authoredComments returns the comments written by username on any game.
+func authoredComments(ctx context.Context, username string) ([]AuthoredComment, error) {
+	comments := []AuthoredComment{}
+	for game, err := range database.All(ctx, repository.ScanGameComments) {
+		if err != nil {
+			return nil, err
+		}
+
+		var authored []any
+		for _, positionComments := range game.PositionComments {
+			authored = appendPositionComments(authored, positionComments, username)
+		}
+		for _, c := range game.Comments {
+			if c != nil && c.Owner == username {
+				authored = append(authored, c)
+			}
+		}
+		for _, c := range authored {
+			comments = append(comments, AuthoredComment{Cohort: game.Cohort, GameId: game.Id, Comment: c})
+		}
+	}
+	return comments, nil
+}
+
+// appendPositionComments appends the comments in the provided map written by username,
+// including replies, to authored. Replies are exported as separate comments.
+func appendPositionComments(authored []any, comments map[string]database.PositionComment, username string) []any {
+	for _, c := range comments {
+		if c.Owner.Username == username {
+			comment := c
+			comment.Replies = nil
+			authored = append(authored, comment)
+		}
+		authored = appendPositionComments(authored, c.Replies, username)
+	}
+	return authored
+}
+
+// writeJSON writes the provided value to the zip file as indented JSON. The value is
+// converted using its database attribute names, so that hidden fields are included.
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	value, err := exportValue(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed to marshal "+name, err)
+	}
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return errors.Wrap(500, "Temporary server error", "Failed to create "+name, err)
+	}
+	_, err = w.Write(data)
+	return errors.Wrap(500, "Temporary server error", "Failed to write "+name, err)
+}
+
+// exportValue converts the provided value to generic maps and slices keyed by its
+// database attribute names.
+func exportValue(v any) (any, error) {
+	av, err := dynamodbattribute.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to marshal export value", err)
+	}
+	var value any
+	if err := dynamodbattribute.Unmarshal(av, &value); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to unmarshal export value", err)
+	}
+	return value, nil
+}
+
+// fileName returns the provided id with characters which are not allowed in file names replaced.
+func fileName(id string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(id)
+}
+
+// notify tells the user that their export can be downloaded. Failures are logged, as the
+// export can still be downloaded from the site.
+func notify(user *database.User, export *database.DataExport) {
+	notification := &database.Notification{
+		Username:           user.Username,
+		Id:                 string(database.NotificationType_DataExportReady),
+		Type:               database.NotificationType_DataExportReady,
+		UpdatedAt:          export.CompletedAt,
+		Count:              1,
+		DataExportMetadata: &database.DataExportMetadata{ExpiresAt: export.ExpiresAt},
+	}
+	if err := repository.PutNotification(notification); err != nil {
+		log.Errorf("Failed to save data export notification for %s: %v", user.Username, err)
+	}
+
+	if user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("Your ChessDojo data export is ready. You can download it from %s/profile/export until %s.",
+		frontendHost, export.ExpiresAt)
+	if err := sendEmail(user.Email, "Your ChessDojo data export is ready", body); err != nil {
+		log.Errorf("Failed to send data export email to %s: %v", user.Username, err)
+	}
+}
+
+// unmarshalStreamImage converts events.DynamoDBAttributeValue to struct
+func unmarshalStreamImage(attribute map[string]events.DynamoDBAttributeValue, out any) error {
+	dbAttrMap := make(map[string]*dynamodb.AttributeValue)
+
+	for k, v := range attribute {
+		var dbAttr dynamodb.AttributeValue
+		bytes, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(bytes, &dbAttr); err != nil {
+			return err
+		}
+		dbAttrMap[k] = &dbAttr
+	}
+
+	err := dynamodbattribute.UnmarshalMap(dbAttrMap, out)
+	return errors.Wrap(500, "Temporary server error", "Failed to unmarshal stream image", err)
+}
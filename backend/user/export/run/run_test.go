@@ -0,0 +1,166 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/pgn"
+)
+
+// testMediaStore saves uploaded files in memory.
+type testMediaStore struct {
+	files map[string][]byte
+}
+
+func (s *testMediaStore) UploadImage(key, imageData string) error       { return nil }
+func (s *testMediaStore) CopyImageFromURL(url, key string) error        { return nil }
+func (s *testMediaStore) DeleteImage(key string) error                  { return nil }
+func (s *testMediaStore) Download(bucket, key string, f *os.File) error { return nil }
+
+func (s *testMediaStore) Upload(bucket, key, contentType string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	s.files[key] = data
+	return err
+}
+
+func (s *testMediaStore) PresignDownload(bucket, key, filename string, expires time.Duration) (string, error) {
+	return "https://example.com/" + key, nil
+}
+
+func TestHandler(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	store := &testMediaStore{files: make(map[string][]byte)}
+	var emails []string
+	repository, mediaStore = repo, store
+	sendEmail = func(to, subject, body string) error {
+		emails = append(emails, to)
+		return nil
+	}
+	defer func() { repository, mediaStore = database.DynamoDB, database.S3 }()
+
+	alice, err := repo.CreateUser("alice", "alice@example.com", "Alice", nil)
+	if err != nil {
+		t.Fatalf("CreateUser got err %v", err)
+	}
+	bob, err := repo.CreateUser("bob", "bob@example.com", "Bob", nil)
+	if err != nil {
+		t.Fatalf("CreateUser got err %v", err)
+	}
+	if _, err := repo.CreateFollower(alice, bob); err != nil {
+		t.Fatalf("CreateFollower got err %v", err)
+	}
+
+	_, err = repo.BatchPutGames([]*database.Game{
+		{Cohort: "1500-1600", Id: "2024.01.01_1", Owner: "alice", Pgn: "1. e4 e5 *"},
+		{Cohort: "1500-1600", Id: "2024.01.01_2", Owner: "bob", Pgn: "1. d4 d5 *"},
+	})
+	if err != nil {
+		t.Fatalf("BatchPutGames got err %v", err)
+	}
+	for _, c := range []*database.PositionComment{
+		{Id: "c1", Fen: pgn.StartingFEN, Owner: database.CommentOwner{Username: "bob"}, Content: "Question?"},
+		{Id: "c2", Fen: pgn.StartingFEN, Owner: database.CommentOwner{Username: "alice"}, Content: "Answer", ParentIds: "c1"},
+	} {
+		if _, err := repo.PutComment("1500-1600", "2024.01.01_2", c, false); err != nil {
+			t.Fatalf("PutComment got err %v", err)
+		}
+	}
+
+	if _, err := repo.CreateDataExport("alice"); err != nil {
+		t.Fatalf("CreateDataExport got err %v", err)
+	}
+	event := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{{
+		EventName: "INSERT",
+		Change: events.DynamoDBStreamRecord{NewImage: map[string]events.DynamoDBAttributeValue{
+			"username": events.NewStringAttribute("alice"),
+			"status":   events.NewStringAttribute(string(database.DataExportStatus_InProgress)),
+		}},
+	}}}
+	if err := Handler(context.Background(), event); err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+
+	export, err := repo.GetDataExport("alice")
+	if err != nil {
+		t.Fatalf("GetDataExport got err %v", err)
+	}
+	if export.Status != database.DataExportStatus_Complete {
+		t.Fatalf("Export got status %s (error %q), want %s", export.Status, export.Error, database.DataExportStatus_Complete)
+	}
+
+	data, ok := store.files[export.Key]
+	if !ok {
+		t.Fatalf("Export key %q was not uploaded", export.Key)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader got err %v", err)
+	}
+	files := make(map[string]string)
+	var names []string
+	for _, f := range zr.File {
+		r, _ := f.Open()
+		content, _ := io.ReadAll(r)
+		files[f.Name] = string(content)
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	wantNames := []string{
+		"comments.json", "events.json", "exams.json", "followers.json", "following.json",
+		"games.json", "games/2024.01.01_1.pgn", "graduations.json", "notifications.json",
+		"timeline.json", "user.json", "yearReviews.json",
+	}
+	if diff := cmp.Diff(wantNames, names); diff != "" {
+		t.Errorf("Zip files mismatch (-want +got):\n%s", diff)
+	}
+
+	var user map[string]any
+	if err := json.Unmarshal([]byte(files["user.json"]), &user); err != nil {
+		t.Fatalf("Unmarshal user.json got err %v", err)
+	}
+	if user["email"] != "alice@example.com" {
+		t.Errorf("user.json got email %v, want hidden field to be included", user["email"])
+	}
+
+	if got := files["games/2024.01.01_1.pgn"]; got != "1. e4 e5 *" {
+		t.Errorf("PGN file got %q, want %q", got, "1. e4 e5 *")
+	}
+
+	var comments []AuthoredComment
+	if err := json.Unmarshal([]byte(files["comments.json"]), &comments); err != nil {
+		t.Fatalf("Unmarshal comments.json got err %v", err)
+	}
+	if len(comments) != 1 || comments[0].GameId != "2024.01.01_2" {
+		t.Errorf("comments.json got %+v, want alice's reply", comments)
+	}
+
+	var followers []map[string]any
+	if err := json.Unmarshal([]byte(files["followers.json"]), &followers); err != nil {
+		t.Fatalf("Unmarshal followers.json got err %v", err)
+	}
+	if len(followers) != 1 || followers[0]["follower"] != "bob" {
+		t.Errorf("followers.json got %v, want bob", followers)
+	}
+
+	notifications, _, err := repo.ListNotifications("alice", "")
+	if err != nil {
+		t.Fatalf("ListNotifications got err %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].Type != database.NotificationType_DataExportReady {
+		t.Errorf("ListNotifications got %+v, want data export notification", notifications)
+	}
+	if diff := cmp.Diff([]string{"alice@example.com"}, emails); diff != "" {
+		t.Errorf("Emails mismatch (-want +got):\n%s", diff)
+	}
+}
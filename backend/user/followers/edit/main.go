@@ -7,11 +7,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.FollowerEditor = database.DynamoDB
+var repository = database.Repository[database.FollowerEditor](database.DynamoDB)
 
 type EditFollowerRequest struct {
 	Poster string `json:"poster"`
@@ -19,13 +18,10 @@ type EditFollowerRequest struct {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		err := errors.New(400, "Invalid request: username is required", "")
@@ -69,15 +65,10 @@ func handleFollow(posterUsername, followerUsername string) api.Response {
 		return api.Failure(err)
 	}
 
-	entry, err := repository.CreateFollower(poster, follower)
+	entry, err := repository.CreateFollower(poster, follower, database.NewFollowerEvent(poster, follower))
 	if err != nil {
 		return api.Failure(err)
 	}
-
-	if err := database.SendFollowerEvent(entry, follower.DojoCohort); err != nil {
-		log.Error("Failed to create notification: ", err)
-	}
-
 	return api.Success(entry)
 }
 
@@ -5,15 +5,13 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.FollowerLister = database.DynamoDB
+var repository = database.Repository[database.FollowerLister](database.DynamoDB)
 
 type ListFollowersResponse struct {
 	Followers []database.FollowerEntry `json:"followers"`
@@ -21,13 +19,10 @@ type ListFollowersResponse struct {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
-func Handler(ctx context.Context, event events.APIGatewayV2HTTPRequest) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	username := event.PathParameters["username"]
 	if username == "" {
 		err := errors.New(400, "Invalid request: username is required", "")
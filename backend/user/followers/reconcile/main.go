@@ -0,0 +1,126 @@
+// Implements a Lambda handler which recounts the followers of every user from the
+// Followers table and repairs any user whose followerCount or followingCount does
+// not match. The counts from the scan of the Followers table may be outdated by the
+// time each user is read, so the followers of a user whose counts do not match are
+// recounted after the user is read again, and only those counts are saved.
+//
+// The handler has no API route. It is run by an admin with:
+//
+//	serverless invoke -f reconcileFollowers
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.FollowerReconciler](database.DynamoDB)
+
+// ReconcileResult summarizes a run of the handler.
+type ReconcileResult struct {
+	// The number of users checked.
+	Users int `json:"users"`
+
+	// The number of users whose counts were repaired.
+	Repaired int `json:"repaired"`
+
+	// The number of users which were skipped because their counts changed while the
+	// handler was running. They are repaired by the next run if still incorrect.
+	Conflicts int `json:"conflicts"`
+}
+
+func main() {
+	lambda.Start(Handler)
+}
+
+func Handler(ctx context.Context) (*ReconcileResult, error) {
+	followerCounts, followingCounts, err := countFollowers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReconcileResult{}
+	for user, err := range database.All(ctx, repository.ScanUsers) {
+		if err != nil {
+			return result, err
+		}
+		result.Users++
+
+		if user.FollowerCount == followerCounts[user.Username] && user.FollowingCount == followingCounts[user.Username] {
+			continue
+		}
+
+		// The user is read before recounting, so that a follow or unfollow after the recount
+		// changes the user's counts and fails SetFollowCounts.
+		user, err = repository.GetUser(user.Username)
+		if err != nil {
+			return result, err
+		}
+		followerCount, followingCount, err := countUserFollowers(ctx, user.Username)
+		if err != nil {
+			return result, err
+		}
+		if user.FollowerCount == followerCount && user.FollowingCount == followingCount {
+			continue
+		}
+
+		log.Infof("Repairing %s: followerCount %d => %d, followingCount %d => %d",
+			user.Username, user.FollowerCount, followerCount, user.FollowingCount, followingCount)
+		if err := repository.SetFollowCounts(user, followerCount, followingCount); err != nil {
+			if _, ok := errors.AsConflict(err); ok {
+				log.Warnf("Skipping %s, as its counts were changed concurrently", user.Username)
+				result.Conflicts++
+				continue
+			}
+			return result, err
+		}
+		result.Repaired++
+	}
+
+	log.Infof("Result: %+v", result)
+	return result, nil
+}
+
+// countFollowers returns the number of followers and the number of followed users of each
+// username in the Followers table.
+func countFollowers(ctx context.Context) (map[string]int, map[string]int, error) {
+	followerCounts := make(map[string]int)
+	followingCounts := make(map[string]int)
+	for entry, err := range database.All(ctx, repository.ScanFollowers) {
+		if err != nil {
+			return nil, nil, err
+		}
+		followerCounts[entry.Poster]++
+		followingCounts[entry.Follower]++
+	}
+	return followerCounts, followingCounts, nil
+}
+
+// countUserFollowers returns the number of followers and the number of followed users of
+// the provided username in the Followers table.
+func countUserFollowers(ctx context.Context, username string) (int, int, error) {
+	followerCount := 0
+	for _, err := range database.All(ctx, func(startKey string) ([]database.FollowerEntry, string, error) {
+		return repository.ListFollowers(username, startKey)
+	}) {
+		if err != nil {
+			return 0, 0, err
+		}
+		followerCount++
+	}
+
+	followingCount := 0
+	for _, err := range database.All(ctx, func(startKey string) ([]database.FollowerEntry, string, error) {
+		return repository.ListFollowing(username, startKey)
+	}) {
+		if err != nil {
+			return 0, 0, err
+		}
+		followingCount++
+	}
+	return followerCount, followingCount, nil
+}
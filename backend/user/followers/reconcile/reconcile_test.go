@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestHandler(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	users := make(map[string]*database.User)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		user, err := repo.CreateUser(username, username+"@example.com", username, nil)
+		if err != nil {
+			t.Fatalf("CreateUser got err %v", err)
+		}
+		users[username] = user
+	}
+
+	for _, pair := range [][2]string{{"alice", "bob"}, {"alice", "carol"}, {"bob", "carol"}} {
+		if _, err := repo.CreateFollower(users[pair[0]], users[pair[1]]); err != nil {
+			t.Fatalf("CreateFollower got err %v", err)
+		}
+	}
+
+	// Simulate drift from before follower writes were transactional.
+	alice, _ := repo.GetUser("alice")
+	if err := repo.SetFollowCounts(alice, 5, 3); err != nil {
+		t.Fatalf("SetFollowCounts got err %v", err)
+	}
+
+	result, err := Handler(context.Background())
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if diff := cmp.Diff(&ReconcileResult{Users: 3, Repaired: 1}, result); diff != "" {
+		t.Errorf("Handler result mismatch (-want +got):\n%s", diff)
+	}
+
+	want := map[string][2]int{"alice": {2, 0}, "bob": {1, 1}, "carol": {0, 2}}
+	for username, counts := range want {
+		user, err := repo.GetUser(username)
+		if err != nil {
+			t.Fatalf("GetUser got err %v", err)
+		}
+		if got := [2]int{user.FollowerCount, user.FollowingCount}; got != counts {
+			t.Errorf("%s got counts %v, want %v", username, got, counts)
+		}
+	}
+}
+
+// racingRepository creates a follower after the Followers table is scanned, as if a user
+// followed another while the handler was running.
+type racingRepository struct {
+	database.FollowerReconciler
+	follow func()
+}
+
+func (r *racingRepository) ScanFollowers(startKey string) ([]database.FollowerEntry, string, error) {
+	entries, lastKey, err := r.FollowerReconciler.ScanFollowers(startKey)
+	if err == nil && lastKey == "" && r.follow != nil {
+		r.follow()
+		r.follow = nil
+	}
+	return entries, lastKey, err
+}
+
+func TestHandlerConcurrentFollow(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	defer func() { repository = database.DynamoDB }()
+
+	users := make(map[string]*database.User)
+	for _, username := range []string{"alice", "bob"} {
+		user, err := repo.CreateUser(username, username+"@example.com", username, nil)
+		if err != nil {
+			t.Fatalf("CreateUser got err %v", err)
+		}
+		users[username] = user
+	}
+
+	repository = &racingRepository{FollowerReconciler: repo, follow: func() {
+		if _, err := repo.CreateFollower(users["alice"], users["bob"]); err != nil {
+			t.Fatalf("CreateFollower got err %v", err)
+		}
+	}}
+
+	result, err := Handler(context.Background())
+	if err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if diff := cmp.Diff(&ReconcileResult{Users: 2}, result); diff != "" {
+		t.Errorf("Handler result mismatch (-want +got):\n%s", diff)
+	}
+
+	want := map[string][2]int{"alice": {1, 0}, "bob": {0, 1}}
+	for username, counts := range want {
+		user, err := repo.GetUser(username)
+		if err != nil {
+			t.Fatalf("GetUser got err %v", err)
+		}
+		if got := [2]int{user.FollowerCount, user.FollowingCount}; got != counts {
+			t.Errorf("%s got counts %v, want %v", username, got, counts)
+		}
+	}
+}
@@ -15,7 +15,7 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/discord"
 )
 
-var repository database.GraduationCreator = database.DynamoDB
+var repository = database.Repository[database.GraduationCreator](database.DynamoDB)
 
 type GraduationRequest struct {
 	Comments string `json:"comments"`
@@ -27,8 +27,7 @@ type GraduationResponse struct {
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
@@ -49,15 +48,11 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 		return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: cohort `%s` cannot graduate", user.DojoCohort), "")), nil
 	}
 
-	var requirements []*database.Requirement
-	var startKey string
-	for ok := true; ok; ok = (startKey != "") {
-		reqs, lastKey, err := repository.ListRequirements(user.DojoCohort, true, startKey)
-		if err != nil {
-			return api.Failure(err), nil
-		}
-		requirements = append(requirements, reqs...)
-		startKey = lastKey
+	requirements, err := database.Collect(ctx, func(startKey string) ([]*database.Requirement, string, error) {
+		return repository.ListRequirements(user.DojoCohort, true, startKey)
+	})
+	if err != nil {
+		return api.Failure(err), nil
 	}
 
 	startedAt := user.LastGraduatedAt
@@ -77,19 +72,11 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	dojoTime := user.TimeSpentOnReqs(requirements)
 	nonDojoTime := totalTime - dojoTime
 
-	log.Debugf("Total Time: %d, Dojo Time: %d, NonDojo Time: %d", totalTime, dojoTime, nonDojoTime)
+	logger.Debugf("Total Time: %d, Dojo Time: %d, NonDojo Time: %d", totalTime, dojoTime, nonDojoTime)
 
-	ratingHistories := make(map[database.RatingSystem][]database.RatingHistory)
-	for rs, history := range user.RatingHistories {
-		for _, item := range history {
-			if item.Date >= createdAt {
-				ratingHistories[rs] = append(ratingHistories[rs], item)
-			}
-		}
-	}
-
-	if len(ratingHistories) == 0 {
-		ratingHistories = user.RatingHistories
+	ratingHistories, err := database.GetRatingHistories(repository, user, "")
+	if err != nil {
+		return api.Failure(err), nil
 	}
 
 	graduation := database.Graduation{
@@ -137,7 +124,7 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 		},
 	}
 	if err := repository.PutTimelineEntry(&timelineEntry); err != nil {
-		log.Debugf("Failed to create timeline entry: %v", err)
+		logger.Debugf("Failed to create timeline entry: %v", err)
 	}
 
 	update := database.UserUpdate{
@@ -153,12 +140,12 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	}
 
 	if err := discord.SetCohortRole(user); err != nil {
-		log.Errorf("Failed to update Discord role: %v", err)
+		logger.Errorf("Failed to update Discord role: %v", err)
 	}
 
 	return api.Success(&GraduationResponse{Graduation: &graduation, UserUpdate: user}), nil
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
@@ -7,11 +7,10 @@ import (
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.UserLister = database.DynamoDB
+var repository = database.Repository[database.UserLister](database.DynamoDB)
 
 type ListUsersResponse struct {
 	Users            []*database.User `json:"users"`
@@ -19,9 +18,6 @@ type ListUsersResponse struct {
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	cohort, _ := event.PathParameters["cohort"]
 	if cohort == "" {
 		return api.Failure(errors.New(400, "Invalid request: cohort is required", "")), nil
@@ -40,5 +36,5 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
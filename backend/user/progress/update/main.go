@@ -15,7 +15,7 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.UserProgressUpdater = database.DynamoDB
+var repository = database.Repository[database.UserProgressUpdater](database.DynamoDB)
 
 type ProgressUpdateRequest struct {
 	RequirementId           string              `json:"requirementId"`
@@ -35,26 +35,23 @@ type ProgressUpdateResponse struct {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
 	}
 
 	if strings.HasSuffix(event.RawPath, "/v3") {
-		return handlerV3(info, event)
+		return handlerV3(ctx, info, event)
 	}
 
 	return api.Failure(errors.New(400, "You are using an outdated version of the website. Please refresh and try again", "")), nil
 }
 
-func handlerV3(info *api.UserInfo, event api.Request) (api.Response, error) {
+func handlerV3(ctx context.Context, info *api.UserInfo, event api.Request) (api.Response, error) {
 	request := &ProgressUpdateRequest{}
 	if err := json.Unmarshal([]byte(event.Body), request); err != nil {
 		return api.Failure(errors.Wrap(400, "Invalid request: unable to unmarshal request body", "", err)), nil
@@ -73,22 +70,23 @@ func handlerV3(info *api.UserInfo, event api.Request) (api.Response, error) {
 
 	for _, t := range user.CustomTasks {
 		if t.Id == request.RequirementId {
-			return handleTask(event, request, user, t)
+			return handleTask(ctx, event, request, user, t)
 		}
 	}
 
-	return handleDefaultTask(event, request, user)
+	return handleDefaultTask(ctx, event, request, user)
 }
 
-func handleDefaultTask(event api.Request, request *ProgressUpdateRequest, user *database.User) (api.Response, error) {
+func handleDefaultTask(ctx context.Context, event api.Request, request *ProgressUpdateRequest, user *database.User) (api.Response, error) {
 	requirement, err := repository.GetRequirement(request.RequirementId)
 	if err != nil {
 		return api.Failure(err), nil
 	}
-	return handleTask(event, request, user, requirement)
+	return handleTask(ctx, event, request, user, requirement)
 }
 
-func handleTask(event api.Request, request *ProgressUpdateRequest, user *database.User, task database.Task) (api.Response, error) {
+func handleTask(ctx context.Context, event api.Request, request *ProgressUpdateRequest, user *database.User, task database.Task) (api.Response, error) {
+	logger := log.FromContext(ctx)
 	totalCount, ok := task.GetCounts()[request.Cohort]
 	if !ok {
 		return api.Failure(errors.New(400, fmt.Sprintf("Invalid request: cohort `%s` does not apply to this requirement", request.Cohort), "")), nil
@@ -121,7 +119,7 @@ func handleTask(event api.Request, request *ProgressUpdateRequest, user *databas
 	if request.Date != "" {
 		d, err := time.Parse(time.RFC3339, request.Date)
 		if err != nil {
-			log.Errorf("Failed to parse request.Date: %v", err)
+			logger.Errorf("Failed to parse request.Date: %v", err)
 		} else {
 			date = d
 		}
@@ -0,0 +1,377 @@
+// Implements a Lambda handler which fits a linear trend to a user's rating history in their
+// preferred rating system and projects when they will reach the next cohort and a target rating.
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/cohorts"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/sajari/regression"
+)
+
+const (
+	// The amount of rating history used to fit the trend.
+	forecastWindow = 180 * 24 * time.Hour
+
+	// The maximum amount of time the trend is projected into the future.
+	forecastHorizon = 2 * 365 * 24 * time.Hour
+
+	// The number of weekly points included in the response's projection.
+	projectionWeeks = 12
+
+	// The minimum number of history points and the minimum number of days they must span
+	// for a trend to be fit.
+	minTrendPoints = 4
+	minTrendDays   = 14
+
+	// The z-score of the confidence bands, for a 95% prediction interval.
+	confidenceZ = 1.96
+
+	day = 24 * time.Hour
+)
+
+var repository = database.Repository[database.RatingForecaster](database.DynamoDB)
+
+type Trend struct {
+	// The number of history points used to fit the trend.
+	Points int `json:"points"`
+
+	// The slope of the trend, in rating points per week.
+	RatingPerWeek float64 `json:"ratingPerWeek"`
+
+	// The standard deviation of the history points around the trend.
+	StandardError float64 `json:"standardError"`
+}
+
+type ProjectionPoint struct {
+	// The date of the point, in time.DateOnly format.
+	Date string `json:"date"`
+
+	// The projected rating on the given date.
+	Rating int `json:"rating"`
+
+	// The lower and upper bounds of the confidence band on the given date.
+	Low  int `json:"low"`
+	High int `json:"high"`
+}
+
+type CohortProjection struct {
+	// The next cohort after the user's current rating.
+	Cohort database.DojoCohort `json:"cohort"`
+
+	// The rating at which the user joins the next cohort.
+	Rating int `json:"rating"`
+
+	// The dates, in time.DateOnly format, at which the trend and the upper and lower bounds of
+	// its confidence band cross Rating. Dates beyond the forecast horizon are left empty.
+	Date         string `json:"date,omitempty"`
+	EarliestDate string `json:"earliestDate,omitempty"`
+	LatestDate   string `json:"latestDate,omitempty"`
+}
+
+type Goal struct {
+	// The rating the user wants to reach.
+	TargetRating int `json:"targetRating"`
+
+	// The date, in time.DateOnly format, by which the user wants to reach TargetRating.
+	TargetDate string `json:"targetDate"`
+
+	// The projected rating and its confidence band on TargetDate. Not set if no trend was fit.
+	Projection *ProjectionPoint `json:"projection,omitempty"`
+
+	// The average rating gained per hour of study by active users in the user's cohort.
+	CohortRatingPerHour float64 `json:"cohortRatingPerHour"`
+
+	// The minutes per week the user must study to reach TargetRating by TargetDate at
+	// CohortRatingPerHour. Not set if the cohort has not gained rating.
+	WeeklyMinutes *int `json:"weeklyMinutes,omitempty"`
+}
+
+type Forecast struct {
+	// The user's preferred rating system.
+	RatingSystem database.RatingSystem `json:"ratingSystem"`
+
+	// The user's current rating in RatingSystem.
+	CurrentRating int `json:"currentRating"`
+
+	// The trend fit to the user's rating history. Not set if the user does not have enough history.
+	Trend *Trend `json:"trend,omitempty"`
+
+	// The weekly projection of the trend.
+	Projection []ProjectionPoint `json:"projection,omitempty"`
+
+	// The projection of when the user reaches the next cohort. Not set if there is no
+	// trend, the user is in the last cohort or the rating system has no cohort boundaries.
+	NextCohort *CohortProjection `json:"nextCohort,omitempty"`
+
+	// The goal requested by the targetRating and targetDate query parameters.
+	Goal *Goal `json:"goal,omitempty"`
+}
+
+// Handler returns the rating forecast of the user in the username path parameter. The optional
+// targetRating and targetDate query parameters request the weekly study time needed to reach the
+// target rating by the target date.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	username, _ := event.PathParameters["username"]
+	if username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	now := time.Now().UTC()
+	goal, err := parseGoal(event.QueryStringParameters, now)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	user, err := repository.GetUser(username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	rating := user.Ratings[user.RatingSystem]
+	if rating == nil || rating.CurrentRating <= 0 {
+		return api.Failure(errors.New(400, "Invalid request: user does not have a rating in their preferred rating system", "")), nil
+	}
+
+	history, err := database.GetRatingHistory(repository, user, user.RatingSystem, now.Add(-forecastWindow).Format(time.DateOnly), "")
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	var stats *database.UserStatistics
+	if goal != nil {
+		if stats, err = repository.GetUserStatistics(); err != nil {
+			return api.Failure(err), nil
+		}
+	}
+
+	return api.Success(forecast(user, history, stats, goal, now)), nil
+}
+
+// parseGoal returns the Goal requested by the targetRating and targetDate query parameters, or
+// nil if neither is set.
+func parseGoal(params map[string]string, now time.Time) (*Goal, error) {
+	targetRating, _ := params["targetRating"]
+	targetDate, _ := params["targetDate"]
+	if targetRating == "" && targetDate == "" {
+		return nil, nil
+	}
+	if targetRating == "" || targetDate == "" {
+		return nil, errors.New(400, "Invalid request: targetRating and targetDate must be set together", "")
+	}
+
+	rating, err := strconv.Atoi(targetRating)
+	if err != nil || rating <= 0 {
+		return nil, errors.New(400, "Invalid request: targetRating must be a positive integer", "")
+	}
+	date, err := time.Parse(time.DateOnly, targetDate)
+	if err != nil {
+		return nil, errors.Wrap(400, "Invalid request: targetDate must be in the format YYYY-MM-DD", "", err)
+	}
+	if !date.After(now) {
+		return nil, errors.New(400, "Invalid request: targetDate must be in the future", "")
+	}
+	return &Goal{TargetRating: rating, TargetDate: targetDate}, nil
+}
+
+// forecast returns the Forecast of the provided user from their history in their preferred
+// rating system. stats is only read if goal is not nil.
+func forecast(user *database.User, history []database.RatingHistory, stats *database.UserStatistics, goal *Goal, now time.Time) *Forecast {
+	currentRating := user.Ratings[user.RatingSystem].CurrentRating
+	result := &Forecast{
+		RatingSystem:  user.RatingSystem,
+		CurrentRating: currentRating,
+		Goal:          goal,
+	}
+
+	fit := fitTrend(history, now)
+	if fit != nil {
+		result.Trend = &Trend{
+			Points:        fit.n,
+			RatingPerWeek: fit.slope * 7,
+			StandardError: fit.stdErr,
+		}
+		for week := 1; week <= projectionWeeks; week++ {
+			result.Projection = append(result.Projection, fit.project(now.AddDate(0, 0, 7*week)))
+		}
+		result.NextCohort = projectNextCohort(fit, user.RatingSystem, currentRating, now)
+	}
+
+	if goal != nil {
+		date, _ := time.Parse(time.DateOnly, goal.TargetDate)
+		if fit != nil {
+			p := fit.project(date)
+			goal.Projection = &p
+		}
+		goal.CohortRatingPerHour = cohortRatingPerHour(stats, user.DojoCohort)
+		goal.WeeklyMinutes = weeklyMinutes(currentRating, goal.TargetRating, goal.CohortRatingPerHour, date.Sub(now))
+	}
+	return result
+}
+
+// trend is a linear fit of rating against days since the fit's origin.
+type trend struct {
+	origin    time.Time
+	n         int
+	intercept float64
+	slope     float64
+	stdErr    float64
+	meanX     float64
+	sxx       float64
+}
+
+// fitTrend returns the linear fit of the history points since forecastWindow before now. It
+// returns nil if there are too few points or they span too few days.
+func fitTrend(history []database.RatingHistory, now time.Time) *trend {
+	origin := now.Add(-forecastWindow)
+	var xs, ys []float64
+	for _, h := range history {
+		date, err := time.Parse(time.RFC3339, h.Date)
+		if err != nil || date.Before(origin) || h.Rating <= 0 {
+			continue
+		}
+		xs = append(xs, date.Sub(origin).Hours()/24)
+		ys = append(ys, float64(h.Rating))
+	}
+	if len(xs) < minTrendPoints {
+		return nil
+	}
+
+	minX, maxX := xs[0], xs[0]
+	for _, x := range xs {
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+	}
+	if maxX-minX < minTrendDays {
+		return nil
+	}
+
+	r := new(regression.Regression)
+	r.SetObserved("Rating")
+	r.SetVar(0, "Days")
+	for i := range xs {
+		r.Train(regression.DataPoint(ys[i], []float64{xs[i]}))
+	}
+	if err := r.Run(); err != nil {
+		log.Errorf("Failed to fit rating trend: %v", err)
+		return nil
+	}
+
+	t := &trend{origin: origin, n: len(xs), intercept: r.Coeff(0), slope: r.Coeff(1)}
+	for _, x := range xs {
+		t.meanX += x / float64(len(xs))
+	}
+
+	var sse float64
+	for i, x := range xs {
+		residual := ys[i] - t.predict(x)
+		sse += residual * residual
+		t.sxx += (x - t.meanX) * (x - t.meanX)
+	}
+	t.stdErr = math.Sqrt(sse / float64(len(xs)-2))
+	return t
+}
+
+// predict returns the trend's rating x days after its origin.
+func (t *trend) predict(x float64) float64 {
+	return t.intercept + t.slope*x
+}
+
+// band returns the half-width of the trend's prediction interval x days after its origin.
+func (t *trend) band(x float64) float64 {
+	n := float64(t.n)
+	return confidenceZ * t.stdErr * math.Sqrt(1+1/n+(x-t.meanX)*(x-t.meanX)/t.sxx)
+}
+
+// project returns the trend's projected rating and confidence band on the provided date.
+func (t *trend) project(date time.Time) ProjectionPoint {
+	x := date.Sub(t.origin).Hours() / 24
+	rating, band := t.predict(x), t.band(x)
+	return ProjectionPoint{
+		Date:   date.Format(time.DateOnly),
+		Rating: int(math.Round(rating)),
+		Low:    int(math.Round(rating - band)),
+		High:   int(math.Round(rating + band)),
+	}
+}
+
+// projectNextCohort returns when the trend crosses the boundary of the cohort after the
+// provided rating. It returns nil if the rating is in the last cohort or the rating system
+// has no boundaries.
+func projectNextCohort(t *trend, system database.RatingSystem, rating int, now time.Time) *CohortProjection {
+	table := cohorts.Current()
+	boundaries := table.Boundaries[system]
+	current := table.GetCohort(system, rating)
+	if boundaries == nil || current == database.NoCohort {
+		return nil
+	}
+
+	next := current.GetNextCohort()
+	if next == database.NoCohort {
+		return nil
+	}
+
+	result := &CohortProjection{Cohort: next}
+	for i, c := range database.Cohorts {
+		if c == next {
+			result.Rating = boundaries[i-1]
+			break
+		}
+	}
+
+	for d := now; !d.After(now.Add(forecastHorizon)); d = d.Add(day) {
+		p := t.project(d)
+		if result.EarliestDate == "" && p.High >= result.Rating {
+			result.EarliestDate = p.Date
+		}
+		if result.Date == "" && p.Rating >= result.Rating {
+			result.Date = p.Date
+		}
+		if p.Low >= result.Rating {
+			result.LatestDate = p.Date
+			break
+		}
+	}
+	return result
+}
+
+// cohortRatingPerHour returns the average rating gained per hour of study by active users
+// in the provided cohort.
+func cohortRatingPerHour(stats *database.UserStatistics, cohort database.DojoCohort) float64 {
+	if stats == nil || stats.Cohorts[cohort] == nil {
+		return 0
+	}
+	s := stats.Cohorts[cohort]
+	if s.ActiveMinutesSpent <= 0 {
+		return 0
+	}
+	return 60 * float64(s.ActiveRatingChanges) / float64(s.ActiveMinutesSpent)
+}
+
+// weeklyMinutes returns the minutes per week needed to gain the difference between current
+// and target in the provided duration at the provided rating per hour. It returns nil if the
+// rating per hour is not positive.
+func weeklyMinutes(current, target int, ratingPerHour float64, duration time.Duration) *int {
+	if target <= current {
+		minutes := 0
+		return &minutes
+	}
+	if ratingPerHour <= 0 {
+		return nil
+	}
+
+	weeks := math.Max(duration.Hours()/24/7, 1.0/7)
+	minutes := int(math.Ceil(float64(target-current) / ratingPerHour * 60 / weeks))
+	return &minutes
+}
+
+func main() {
+	lambda.Start(api.Logged(Handler))
+}
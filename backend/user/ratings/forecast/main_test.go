@@ -0,0 +1,227 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var now = time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+
+// linearHistory returns weekly history points over the 98 days before now, gaining one rating
+// point per day and ending at the provided rating. Every other point is offset by noise.
+func linearHistory(rating, noise int) []database.RatingHistory {
+	var history []database.RatingHistory
+	for d := 98; d >= 0; d -= 7 {
+		offset := noise
+		if (d/7)%2 == 0 {
+			offset = -noise
+		}
+		history = append(history, database.RatingHistory{
+			Date:   now.AddDate(0, 0, -d).Format(time.RFC3339),
+			Rating: rating - d + offset,
+		})
+	}
+	return history
+}
+
+func TestForecast(t *testing.T) {
+	user := &database.User{
+		Username:     "alice",
+		DojoCohort:   "1400-1500",
+		RatingSystem: database.Chesscom,
+		Ratings: map[database.RatingSystem]*database.Rating{
+			database.Chesscom: {CurrentRating: 1700},
+		},
+	}
+	stats := database.NewUserStatistics()
+	stats.Cohorts["1400-1500"].ActiveRatingChanges = 100
+	stats.Cohorts["1400-1500"].ActiveMinutesSpent = 6000
+
+	goal := &Goal{TargetRating: 1800, TargetDate: now.AddDate(0, 0, 70).Format(time.DateOnly)}
+	got := forecast(user, linearHistory(1700, 0), stats, goal, now)
+
+	if got.Trend == nil {
+		t.Fatalf("forecast got nil trend")
+	}
+	if got.Trend.Points != 15 {
+		t.Errorf("forecast got %d trend points, want 15", got.Trend.Points)
+	}
+	if diff := got.Trend.RatingPerWeek - 7; diff > 0.001 || diff < -0.001 {
+		t.Errorf("forecast got %f rating per week, want 7", got.Trend.RatingPerWeek)
+	}
+	if len(got.Projection) != projectionWeeks {
+		t.Errorf("forecast got %d projection points, want %d", len(got.Projection), projectionWeeks)
+	}
+
+	wantNext := &CohortProjection{
+		Cohort:       "1500-1600",
+		Rating:       1750,
+		Date:         "2024-07-23",
+		EarliestDate: "2024-07-23",
+		LatestDate:   "2024-07-23",
+	}
+	if diff := cmp.Diff(wantNext, got.NextCohort); diff != "" {
+		t.Errorf("forecast next cohort mismatch (-want +got):\n%s", diff)
+	}
+
+	wantGoal := &Goal{
+		TargetRating:        1800,
+		TargetDate:          "2024-08-12",
+		Projection:          &ProjectionPoint{Date: "2024-08-12", Rating: 1770, Low: 1770, High: 1770},
+		CohortRatingPerHour: 1,
+		WeeklyMinutes:       func() *int { m := 600; return &m }(),
+	}
+	if diff := cmp.Diff(wantGoal, got.Goal); diff != "" {
+		t.Errorf("forecast goal mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestForecastConfidenceBands(t *testing.T) {
+	user := &database.User{
+		RatingSystem: database.Chesscom,
+		Ratings: map[database.RatingSystem]*database.Rating{
+			database.Chesscom: {CurrentRating: 1700},
+		},
+	}
+
+	got := forecast(user, linearHistory(1700, 20), nil, nil, now)
+	if got.Trend == nil || got.NextCohort == nil {
+		t.Fatalf("forecast got trend %v and next cohort %v, want both set", got.Trend, got.NextCohort)
+	}
+	if got.Trend.StandardError <= 0 {
+		t.Errorf("forecast got standard error %f, want > 0", got.Trend.StandardError)
+	}
+	for _, p := range got.Projection {
+		if p.Low >= p.Rating || p.High <= p.Rating {
+			t.Errorf("forecast got projection %+v, want low < rating < high", p)
+		}
+	}
+
+	next := got.NextCohort
+	if next.EarliestDate == "" || next.Date == "" || next.LatestDate == "" {
+		t.Fatalf("forecast got next cohort %+v, want all dates set", next)
+	}
+	if next.EarliestDate >= next.Date || next.Date >= next.LatestDate {
+		t.Errorf("forecast got next cohort %+v, want earliestDate < date < latestDate", next)
+	}
+}
+
+func TestForecastWithoutTrend(t *testing.T) {
+	user := &database.User{
+		RatingSystem: database.Custom,
+		Ratings: map[database.RatingSystem]*database.Rating{
+			database.Custom: {CurrentRating: 1700},
+		},
+	}
+
+	table := []struct {
+		name    string
+		history []database.RatingHistory
+	}{
+		{name: "NoHistory"},
+		{name: "TooFewPoints", history: linearHistory(1700, 0)[12:]},
+		{
+			name: "TooFewDays",
+			history: []database.RatingHistory{
+				{Date: now.AddDate(0, 0, -3).Format(time.RFC3339), Rating: 1690},
+				{Date: now.AddDate(0, 0, -2).Format(time.RFC3339), Rating: 1695},
+				{Date: now.AddDate(0, 0, -1).Format(time.RFC3339), Rating: 1698},
+				{Date: now.Format(time.RFC3339), Rating: 1700},
+			},
+		},
+		{
+			name: "OutsideWindow",
+			history: []database.RatingHistory{
+				{Date: "2023-01-01T00:00:00Z", Rating: 1500},
+				{Date: "2023-02-01T00:00:00Z", Rating: 1550},
+				{Date: "2023-03-01T00:00:00Z", Rating: 1600},
+				{Date: "2023-04-01T00:00:00Z", Rating: 1650},
+			},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := forecast(user, tc.history, nil, nil, now)
+			want := &Forecast{RatingSystem: database.Custom, CurrentRating: 1700}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("forecast mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseGoal(t *testing.T) {
+	table := []struct {
+		name    string
+		params  map[string]string
+		want    *Goal
+		wantErr bool
+	}{
+		{name: "NoGoal"},
+		{
+			name:   "Valid",
+			params: map[string]string{"targetRating": "1800", "targetDate": "2024-12-31"},
+			want:   &Goal{TargetRating: 1800, TargetDate: "2024-12-31"},
+		},
+		{
+			name:    "MissingDate",
+			params:  map[string]string{"targetRating": "1800"},
+			wantErr: true,
+		},
+		{
+			name:    "InvalidRating",
+			params:  map[string]string{"targetRating": "-5", "targetDate": "2024-12-31"},
+			wantErr: true,
+		},
+		{
+			name:    "InvalidDate",
+			params:  map[string]string{"targetRating": "1800", "targetDate": "12/31/2024"},
+			wantErr: true,
+		},
+		{
+			name:    "PastDate",
+			params:  map[string]string{"targetRating": "1800", "targetDate": "2024-01-01"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseGoal(tc.params, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseGoal got err %v, want error %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseGoal mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWeeklyMinutes(t *testing.T) {
+	table := []struct {
+		name          string
+		current       int
+		target        int
+		ratingPerHour float64
+		duration      time.Duration
+		want          *int
+	}{
+		{name: "AlreadyReached", current: 1800, target: 1700, ratingPerHour: 1, duration: 7 * day, want: func() *int { m := 0; return &m }()},
+		{name: "NoCohortGain", current: 1700, target: 1800, ratingPerHour: 0, duration: 7 * day},
+		{name: "OneWeek", current: 1700, target: 1710, ratingPerHour: 2, duration: 7 * day, want: func() *int { m := 300; return &m }()},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := weeklyMinutes(tc.current, tc.target, tc.ratingPerHour, tc.duration)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("weeklyMinutes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
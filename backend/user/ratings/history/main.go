@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+var repository = database.Repository[database.RatingHistoryGetter](database.DynamoDB)
+
+type ListRatingHistoryResponse struct {
+	History []database.RatingHistory `json:"history"`
+}
+
+// Handler returns the rating history of a user in a single rating system. The optional
+// startDate and endDate query parameters limit the history to the given dates, inclusive. The
+// history of users who have not been migrated to the rating history table is read from the user.
+func Handler(ctx context.Context, event api.Request) (api.Response, error) {
+	username, _ := event.PathParameters["username"]
+	if username == "" {
+		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
+	}
+
+	system := database.RatingSystem(event.QueryStringParameters["system"])
+	if system == "" {
+		return api.Failure(errors.New(400, "Invalid request: system is required", "")), nil
+	}
+
+	startDate, _ := event.QueryStringParameters["startDate"]
+	endDate, _ := event.QueryStringParameters["endDate"]
+	for _, date := range []string{startDate, endDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return api.Failure(errors.Wrap(400, "Invalid request: dates must be in the format YYYY-MM-DD", "", err)), nil
+		}
+	}
+	if startDate != "" && endDate != "" && startDate > endDate {
+		return api.Failure(errors.New(400, "Invalid request: startDate must not be after endDate", "")), nil
+	}
+
+	user, err := repository.GetUser(username)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+
+	history, err := database.GetRatingHistory(repository, user, system, startDate, endDate)
+	if err != nil {
+		return api.Failure(err), nil
+	}
+	if history == nil {
+		history = []database.RatingHistory{}
+	}
+	return api.Success(&ListRatingHistoryResponse{History: history}), nil
+}
+
+func main() {
+	lambda.Start(api.Logged(Handler))
+}
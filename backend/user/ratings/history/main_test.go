@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestHandler(t *testing.T) {
+	repo := database.NewMemoryRepository()
+	repository = repo
+	defer func() { repository = database.DynamoDB }()
+
+	user, err := repo.CreateUser("alice", "alice@example.com", "alice", nil)
+	if err != nil {
+		t.Fatalf("CreateUser got err %v", err)
+	}
+	user.RatingHistories = map[database.RatingSystem][]database.RatingHistory{
+		database.Chesscom: {
+			{Date: "2023-01-02T00:00:00Z", Rating: 1400},
+			{Date: "2023-01-09T00:00:00Z", Rating: 1410},
+		},
+	}
+	if err := repo.SetUserConditional(user, nil); err != nil {
+		t.Fatalf("SetUserConditional got err %v", err)
+	}
+
+	table := []struct {
+		name       string
+		username   string
+		query      map[string]string
+		wantStatus int
+		want       []database.RatingHistory
+	}{
+		{
+			name:       "UnmigratedUser",
+			username:   "alice",
+			query:      map[string]string{"system": "CHESSCOM", "endDate": "2023-01-05"},
+			wantStatus: 200,
+			want:       []database.RatingHistory{{Date: "2023-01-02T00:00:00Z", Rating: 1400}},
+		},
+		{
+			name:       "NoHistory",
+			username:   "alice",
+			query:      map[string]string{"system": "FIDE"},
+			wantStatus: 200,
+			want:       []database.RatingHistory{},
+		},
+		{
+			name:       "UnknownUser",
+			username:   "bob",
+			query:      map[string]string{"system": "CHESSCOM"},
+			wantStatus: 404,
+		},
+		{
+			name:       "InvalidDates",
+			username:   "alice",
+			query:      map[string]string{"system": "CHESSCOM", "startDate": "2023-01-09", "endDate": "2023-01-02"},
+			wantStatus: 400,
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			event := api.Request{
+				PathParameters:        map[string]string{"username": tc.username},
+				QueryStringParameters: tc.query,
+			}
+			resp, err := Handler(context.Background(), event)
+			if err != nil {
+				t.Fatalf("Handler got err %v", err)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("Handler got status %d, want %d: %s", resp.StatusCode, tc.wantStatus, resp.Body)
+			}
+			if tc.wantStatus != 200 {
+				return
+			}
+
+			var got ListRatingHistoryResponse
+			if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+				t.Fatalf("Unmarshal response got err %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got.History); diff != "" {
+				t.Errorf("Handler mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package ratings
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+// RateLimiter is a token bucket which limits the rate of requests made to a provider.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter which allows rate requests per second on average,
+// and up to burst requests at once.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed or ctx is done. If ctx is done first, its error
+// is returned and the request is not counted.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// CircuitBreaker stops requests to a provider after it fails repeatedly. Once threshold
+// consecutive requests have failed, the breaker opens and requests are not allowed until
+// cooldown has passed. A single request is then allowed through; if it succeeds the breaker
+// closes, and otherwise it stays open for another cooldown. It is safe for concurrent use.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker with the provided threshold and cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow returns true if a request may be made to the provider.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	// Allow one trial request, and keep the breaker open for everyone else until it finishes.
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// Record records the result of a request allowed by Allow. Errors with a 4xx code, such as
+// an unknown username, mean that the provider is working and are not counted as failures.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !isProviderFailure(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures == b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// IsOpen returns true if the breaker is currently rejecting requests.
+func (b *CircuitBreaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
+
+// isProviderFailure returns true if err means the provider did not respond correctly.
+func isProviderFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var aerr *errors.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code >= 500
+	}
+	return true
+}
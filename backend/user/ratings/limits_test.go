@@ -0,0 +1,64 @@
+package ratings
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(100, 2)
+
+	start := time.Now()
+	for range 4 {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait got err %v", err)
+		}
+	}
+	// The burst of 2 is immediate, and the next 2 requests wait 10ms each.
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("4 requests took %s, want at least 15ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slow := NewRateLimiter(0.001, 1)
+	slow.Wait(context.Background())
+	if err := slow.Wait(ctx); err == nil {
+		t.Errorf("Wait with cancelled context got nil err")
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+	failure := errors.New(500, "Temporary server error", "")
+	notFound := errors.New(404, "Invalid request: player not found", "")
+
+	breaker.Record(failure)
+	breaker.Record(notFound)
+	breaker.Record(failure)
+	if !breaker.Allow() {
+		t.Fatalf("Allow got false after a success reset the failures")
+	}
+
+	breaker.Record(fmt.Errorf("connection reset"))
+	if breaker.Allow() {
+		t.Fatalf("Allow got true after %d consecutive failures", 2)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatalf("Allow got false after the cooldown, want a trial request")
+	}
+	if breaker.Allow() {
+		t.Fatalf("Allow got true during the trial request")
+	}
+
+	breaker.Record(nil)
+	if !breaker.Allow() || breaker.IsOpen() {
+		t.Errorf("Breaker got open after a successful trial request")
+	}
+}
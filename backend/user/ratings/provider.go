@@ -0,0 +1,99 @@
+package ratings
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// RatingProvider fetches ratings from a single rating system.
+type RatingProvider interface {
+	// System returns the rating system the provider fetches.
+	System() database.RatingSystem
+
+	// Name returns the display name of the rating system.
+	Name() string
+
+	// Fetch returns the current rating of the provided username/id.
+	Fetch(username string) (*database.Rating, error)
+
+	// ValidateUsername returns an error if the provided username/id cannot exist
+	// in the rating system.
+	ValidateUsername(username string) error
+
+	// ProfileURL returns the URL of the provided username/id's profile page.
+	ProfileURL(username string) string
+}
+
+var providers = make(map[database.RatingSystem]RatingProvider)
+
+// Register adds the provided RatingProvider to the registry. It panics if a
+// provider is already registered for the same rating system.
+func Register(p RatingProvider) {
+	if _, ok := providers[p.System()]; ok {
+		panic(fmt.Sprintf("ratings: provider already registered for %s", p.System()))
+	}
+	providers[p.System()] = p
+}
+
+// GetProvider returns the RatingProvider registered for the provided rating system.
+func GetProvider(system database.RatingSystem) (RatingProvider, bool) {
+	p, ok := providers[system]
+	return p, ok
+}
+
+// Providers returns all registered RatingProviders, sorted by rating system.
+func Providers() []RatingProvider {
+	result := make([]RatingProvider, 0, len(providers))
+	for _, p := range providers {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].System() < result[j].System()
+	})
+	return result
+}
+
+// SetTransport sets the transport used for all requests made by the providers.
+// A nil transport resets it to http.DefaultTransport.
+func SetTransport(transport http.RoundTripper) {
+	client.Transport = transport
+}
+
+// provider is a RatingProvider which validates usernames with a regular expression
+// and fetches ratings with a RatingFetchFunc.
+type provider struct {
+	system     database.RatingSystem
+	name       string
+	username   *regexp.Regexp
+	profileURL string
+	fetch      RatingFetchFunc
+}
+
+func (p *provider) System() database.RatingSystem {
+	return p.system
+}
+
+func (p *provider) Name() string {
+	return p.name
+}
+
+func (p *provider) Fetch(username string) (*database.Rating, error) {
+	return p.fetch(username)
+}
+
+func (p *provider) ValidateUsername(username string) error {
+	if !p.username.MatchString(strings.TrimSpace(username)) {
+		return errors.New(400, fmt.Sprintf("Invalid request: `%s` is not a valid %s username or ID", username, p.name), "")
+	}
+	return nil
+}
+
+func (p *provider) ProfileURL(username string) string {
+	return fmt.Sprintf(p.profileURL, strings.TrimSpace(username))
+}
@@ -88,16 +88,76 @@ type UscfResponse struct {
 
 type RatingFetchFunc func(username string) (*database.Rating, error)
 
-var RatingFetchFuncs map[database.RatingSystem]RatingFetchFunc = map[database.RatingSystem]RatingFetchFunc{
-	database.Chesscom: FetchChesscomRating,
-	database.Lichess:  FetchLichessRating,
-	database.Fide:     FetchFideRating,
-	database.Uscf:     FetchUscfRating,
-	database.Ecf:      FetchEcfRating,
-	database.Cfc:      FetchCfcRating,
-	database.Dwz:      FetchDwzRating,
-	database.Acf:      FetchAcfRating,
-	database.Knsb:     FetchKnsbRating,
+func init() {
+	Register(&bioProvider{
+		provider: provider{
+			system:     database.Chesscom,
+			name:       "Chess.com",
+			username:   regexp.MustCompile(`^[A-Za-z0-9_-]{3,25}$`),
+			profileURL: "https://www.chess.com/member/%s",
+			fetch:      FetchChesscomRating,
+		},
+		bio: FetchChesscomBio,
+	})
+	Register(&bioProvider{
+		provider: provider{
+			system:     database.Lichess,
+			name:       "Lichess",
+			username:   regexp.MustCompile(`^[A-Za-z0-9_-]{2,30}$`),
+			profileURL: "https://lichess.org/@/%s",
+			fetch:      FetchLichessRating,
+		},
+		bio: FetchLichessBio,
+	})
+	Register(&provider{
+		system:     database.Fide,
+		name:       "FIDE",
+		username:   regexp.MustCompile(`^\d{1,10}$`),
+		profileURL: "https://ratings.fide.com/profile/%s",
+		fetch:      FetchFideRating,
+	})
+	Register(&provider{
+		system:     database.Uscf,
+		name:       "USCF",
+		username:   regexp.MustCompile(`^\d{8}$`),
+		profileURL: "https://ratings.uschess.org/player/%s",
+		fetch:      FetchUscfRating,
+	})
+	Register(&provider{
+		system:     database.Ecf,
+		name:       "ECF",
+		username:   regexp.MustCompile(`^\d{6}[A-Za-z]$`),
+		profileURL: "https://rating.englishchess.org.uk/v2/new/player.php?ECF_code=%s",
+		fetch:      FetchEcfRating,
+	})
+	Register(&provider{
+		system:     database.Cfc,
+		name:       "CFC",
+		username:   regexp.MustCompile(`^\d{1,7}$`),
+		profileURL: "https://www.chess.ca/en/ratings/p/?id=%s",
+		fetch:      FetchCfcRating,
+	})
+	Register(&provider{
+		system:     database.Dwz,
+		name:       "DWZ",
+		username:   regexp.MustCompile(`^\d{1,10}$`),
+		profileURL: "https://www.schachbund.de/spieler.html?pkz=%s",
+		fetch:      FetchDwzRating,
+	})
+	Register(&provider{
+		system:     database.Acf,
+		name:       "ACF",
+		username:   regexp.MustCompile(`^\d{1,10}$`),
+		profileURL: "https://sachess.org.au/ratings/player?id=%s",
+		fetch:      FetchAcfRating,
+	})
+	Register(&provider{
+		system:     database.Knsb,
+		name:       "KNSB",
+		username:   regexp.MustCompile(`^\d{1,10}$`),
+		profileURL: "https://ratingviewer.nl/list/latest/players/%s",
+		fetch:      FetchKnsbRating,
+	})
 }
 
 func FetchChesscomRating(chesscomUsername string) (*database.Rating, error) {
@@ -331,7 +391,7 @@ func FetchDwzRating(dwzId string) (*database.Rating, error) {
 
 	rating, err := strconv.Atoi(tokens[ratingIndex])
 	if err != nil {
-		return nil, errors.Wrap(400, fmt.Sprintf("Invalid request: DWZ API returned rating `%s` which cannot be converted to integer", tokens[14]), "", err)
+		return nil, errors.Wrap(400, fmt.Sprintf("Invalid request: DWZ API returned rating `%s` which cannot be converted to integer", tokens[ratingIndex]), "", err)
 	}
 	return &database.Rating{CurrentRating: rating}, nil
 }
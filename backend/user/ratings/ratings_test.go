@@ -0,0 +1,242 @@
+package ratings
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// fixture is a recorded response returned for requests whose URL starts with prefix.
+type fixture struct {
+	prefix string
+	file   string
+	status int
+}
+
+// fixtureTransport replays recorded responses from the testdata directory, so that
+// the providers can be tested without network access.
+type fixtureTransport []fixture
+
+func (t fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, f := range t {
+		if !strings.HasPrefix(req.URL.String(), f.prefix) {
+			continue
+		}
+
+		body := []byte{}
+		if f.file != "" {
+			var err error
+			if body, err = os.ReadFile(filepath.Join("testdata", f.file)); err != nil {
+				return nil, err
+			}
+		}
+		status := f.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no fixture recorded for %s", req.URL)
+}
+
+var fixtures = fixtureTransport{
+	{prefix: "https://api.chess.com/pub/player/dojotester/stats", file: "chesscom.json"},
+	{prefix: "https://api.chess.com/pub/player/dojotester", file: "chesscom_profile.json"},
+	{prefix: "https://api.chess.com/pub/player/", status: http.StatusNotFound},
+	{prefix: "https://lichess.org/api/user/dojotester", file: "lichess.json"},
+	{prefix: "https://lichess.org/api/users", file: "lichess_bulk.json"},
+	{prefix: "https://ratings.fide.com/profile/1503014", file: "fide.html"},
+	{prefix: "https://ratings.fide.com/profile/", file: "fide_unrated.html"},
+	{prefix: "https://ratings-api.uschess.org/api/v1/members/12345678", file: "uscf.json"},
+	{prefix: "https://ratings-api.uschess.org/api/v1/members/", file: "uscf_unrated.json"},
+	{prefix: "https://rating.englishchess.org.uk/v2/new/api.php?v2/ratings/S/123456A/", file: "ecf.json"},
+	{prefix: "https://server.chess.ca/api/player/v1/123456", file: "cfc.json"},
+	{prefix: "https://www.schachbund.de/php/dewis/spieler.php?pkz=10123456", file: "dwz.txt"},
+	{prefix: "https://sachess.org.au/ratings/player?id=3141", file: "acf.html"},
+	{prefix: "https://ratingviewer.nl/rating-lists/index.json", file: "knsb_lists.json"},
+	{prefix: "https://ratingviewer.nl/metrics/ratingList/8123456/411.json", file: "knsb.json"},
+}
+
+func TestProviders(t *testing.T) {
+	SetTransport(fixtures)
+	defer SetTransport(nil)
+
+	table := []struct {
+		system   database.RatingSystem
+		username string
+		want     *database.Rating
+		wantErr  bool
+	}{
+		{
+			system:   database.Chesscom,
+			username: "dojotester",
+			want:     &database.Rating{CurrentRating: 1432, Deviation: 45, NumGames: 232},
+		},
+		{
+			system:   database.Chesscom,
+			username: "nobody",
+			wantErr:  true,
+		},
+		{
+			system:   database.Lichess,
+			username: "dojotester",
+			want:     &database.Rating{CurrentRating: 1934, Deviation: 62, NumGames: 37, IsProvisional: true},
+		},
+		{
+			system:   database.Fide,
+			username: "1503014",
+			want:     &database.Rating{CurrentRating: 1987},
+		},
+		{
+			system:   database.Fide,
+			username: "1",
+			want:     &database.Rating{CurrentRating: 0},
+		},
+		{
+			system:   database.Uscf,
+			username: "12345678",
+			want:     &database.Rating{CurrentRating: 1605, NumGames: 18, IsProvisional: true},
+		},
+		{
+			system:   database.Uscf,
+			username: "12345679",
+			wantErr:  true,
+		},
+		{
+			system:   database.Ecf,
+			username: "123456A",
+			want:     &database.Rating{CurrentRating: 1745},
+		},
+		{
+			system:   database.Cfc,
+			username: "123456",
+			want:     &database.Rating{CurrentRating: 1812},
+		},
+		{
+			system:   database.Dwz,
+			username: "10123456",
+			want:     &database.Rating{CurrentRating: 1723},
+		},
+		{
+			system:   database.Acf,
+			username: "3141",
+			want:     &database.Rating{CurrentRating: 1654},
+		},
+		{
+			system:   database.Knsb,
+			username: "8123456",
+			want:     &database.Rating{CurrentRating: 1876, NumGames: 24},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(fmt.Sprintf("%s/%s", tc.system, tc.username), func(t *testing.T) {
+			provider, ok := GetProvider(tc.system)
+			if !ok {
+				t.Fatalf("GetProvider(%s) found no provider", tc.system)
+			}
+
+			got, err := provider.Fetch(tc.username)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Fetch got rating %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch got err %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Fetch mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchBulkLichessRatings(t *testing.T) {
+	SetTransport(fixtures)
+	defer SetTransport(nil)
+
+	got, err := FetchBulkLichessRatings([]string{"DojoTester", "Cheater"})
+	if err != nil {
+		t.Fatalf("FetchBulkLichessRatings got err %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("FetchBulkLichessRatings got %d results, want 2", len(got))
+	}
+	if got["dojotester"].Performances.Classical.Rating != 1934 {
+		t.Errorf("dojotester got rating %d, want 1934", got["dojotester"].Performances.Classical.Rating)
+	}
+	if !got["cheater"].TosViolation {
+		t.Errorf("cheater got tosViolation false, want true")
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	table := []struct {
+		system   database.RatingSystem
+		username string
+		wantErr  bool
+	}{
+		{system: database.Chesscom, username: "dojo_tester-1"},
+		{system: database.Chesscom, username: "ab", wantErr: true},
+		{system: database.Chesscom, username: "dojo/../tester", wantErr: true},
+		{system: database.Lichess, username: " DojoTester "},
+		{system: database.Lichess, username: "dojo tester", wantErr: true},
+		{system: database.Fide, username: "1503014"},
+		{system: database.Fide, username: "GM1503014", wantErr: true},
+		{system: database.Uscf, username: "12345678"},
+		{system: database.Uscf, username: "1234", wantErr: true},
+		{system: database.Ecf, username: "123456a"},
+		{system: database.Ecf, username: "123456", wantErr: true},
+		{system: database.Cfc, username: "123456"},
+		{system: database.Dwz, username: "10123456"},
+		{system: database.Acf, username: "3141"},
+		{system: database.Knsb, username: "8123456"},
+		{system: database.Knsb, username: "8123456?x=1", wantErr: true},
+	}
+
+	for _, tc := range table {
+		t.Run(fmt.Sprintf("%s/%s", tc.system, tc.username), func(t *testing.T) {
+			provider, _ := GetProvider(tc.system)
+			err := provider.ValidateUsername(tc.username)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateUsername got err %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvidersRegistered(t *testing.T) {
+	var got []database.RatingSystem
+	for _, p := range Providers() {
+		got = append(got, p.System())
+		if p.Name() == "" {
+			t.Errorf("%s provider has no name", p.System())
+		}
+		if url := p.ProfileURL("dojotester"); !strings.HasPrefix(url, "https://") || !strings.Contains(url, "dojotester") {
+			t.Errorf("%s provider got profile URL %q", p.System(), url)
+		}
+	}
+
+	want := []database.RatingSystem{
+		database.Acf, database.Cfc, database.Chesscom, database.Dwz, database.Ecf,
+		database.Fide, database.Knsb, database.Lichess, database.Uscf,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Providers mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"maps"
+	"math"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/user/ratings"
@@ -17,24 +21,250 @@ import (
 
 type Event events.CloudWatchEvent
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.RatingUpdater](database.DynamoDB)
+
+var fetchBulkLichessRatings = ratings.FetchBulkLichessRatings
 
 var now = time.Now()
 
+const (
+	// workerCount is the number of users whose ratings are fetched concurrently.
+	workerCount = 10
+
+	// breakerThreshold is the number of consecutive failures after which a provider is skipped.
+	breakerThreshold = 5
+
+	// breakerCooldown is how long a provider is skipped before it is tried again.
+	breakerCooldown = 2 * time.Minute
+
+	// deadlineMargin is the time left before the Lambda timeout at which a run stops fetching
+	// ratings and saves its checkpoint.
+	deadlineMargin = time.Minute
+
+	// resumeDelay is the time after its last save at which an in-progress run is assumed to have
+	// timed out. It is longer than the Lambda timeout, so that a run is never resumed while the
+	// invocation which started it is still going.
+	resumeDelay = 16 * time.Minute
+
+	// defaultRateLimit is the requests per second allowed to providers not in rateLimits.
+	defaultRateLimit = 2
+)
+
+// rateLimits are the requests per second allowed to each provider. Lichess ratings are
+// fetched in bulk once per page, so they are not limited. The limits are kept by the pipeline
+// of a single invocation, so they only hold because one invocation runs at a time. See
+// activeRun.
+var rateLimits = map[database.RatingSystem]float64{
+	database.Chesscom: 5,
+	database.Uscf:     5,
+	database.Ecf:      3,
+	database.Cfc:      3,
+	database.Fide:     2,
+	database.Dwz:      2,
+	database.Knsb:     2,
+	database.Acf:      1,
+}
+
 type isBannedFunc func(username string) bool
 
-func updateRating(rating *database.Rating, systemName string, fetcher ratings.RatingFetchFunc) bool {
-	rating.Username = strings.TrimSpace(rating.Username)
-	if rating.Username == "" {
-		return false
+// fetchResult is the rating fetched for a user's username in a rating system.
+type fetchResult struct {
+	username string
+	rating   *database.Rating
+}
+
+// pipeline fetches the ratings of pages of users concurrently. Requests to each provider are
+// rate limited, and a provider which fails repeatedly is skipped by its circuit breaker. The
+// limiters and breakers are shared by all pages and runs of an invocation.
+type pipeline struct {
+	fetchers map[database.RatingSystem]ratings.RatingFetchFunc
+	limiters map[database.RatingSystem]*ratings.RateLimiter
+	breakers map[database.RatingSystem]*ratings.CircuitBreaker
+
+	// mu guards the metrics of the RatingUpdate being processed.
+	mu sync.Mutex
+}
+
+// newPipeline returns a pipeline which fetches ratings from the provided providers.
+func newPipeline(providers []ratings.RatingProvider) *pipeline {
+	p := &pipeline{
+		fetchers: make(map[database.RatingSystem]ratings.RatingFetchFunc),
+		limiters: make(map[database.RatingSystem]*ratings.RateLimiter),
+		breakers: make(map[database.RatingSystem]*ratings.CircuitBreaker),
 	}
+	for _, provider := range providers {
+		system := provider.System()
+		p.fetchers[system] = provider.Fetch
+		p.breakers[system] = ratings.NewCircuitBreaker(breakerThreshold, breakerCooldown)
+		if system == database.Lichess {
+			continue
+		}
+
+		rate, ok := rateLimits[system]
+		if !ok {
+			rate = defaultRateLimit
+		}
+		p.limiters[system] = ratings.NewRateLimiter(rate, int(math.Ceil(rate)))
+	}
+	return p
+}
 
-	data, err := fetcher(rating.Username)
+// count applies fn to the metrics of the provided rating system.
+func (p *pipeline) count(update *database.RatingUpdate, system database.RatingSystem, fn func(m *database.RatingUpdateMetrics)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(update.Metric(system))
+}
+
+// fetchUser fetches the current ratings of the provided user. The results are keyed by
+// rating system.
+func (p *pipeline) fetchUser(ctx context.Context, user *database.User, fetchers map[database.RatingSystem]ratings.RatingFetchFunc, update *database.RatingUpdate) map[database.RatingSystem]fetchResult {
+	logger := log.FromContext(ctx)
+	results := make(map[database.RatingSystem]fetchResult)
+	for system, rating := range user.Ratings {
+		username := strings.TrimSpace(rating.Username)
+		fetch, ok := fetchers[system]
+		if !ok || system.IsCustom() || username == "" {
+			continue
+		}
+
+		breaker := p.breakers[system]
+		if ctx.Err() != nil || (breaker != nil && !breaker.Allow()) {
+			p.count(update, system, func(m *database.RatingUpdateMetrics) { m.Skipped++ })
+			continue
+		}
+		if limiter := p.limiters[system]; limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				p.count(update, system, func(m *database.RatingUpdateMetrics) { m.Skipped++ })
+				continue
+			}
+		}
+
+		data, err := fetch(username)
+		if breaker != nil {
+			breaker.Record(err)
+		}
+		if err != nil {
+			logger.Errorf("Failed to get %s rating for %q: %v", system, username, err)
+			p.count(update, system, func(m *database.RatingUpdateMetrics) { m.Failed++ })
+			continue
+		}
+
+		p.count(update, system, func(m *database.RatingUpdateMetrics) { m.Fetched++ })
+		results[system] = fetchResult{username: username, rating: data}
+	}
+	return results
+}
+
+// processPage fetches the ratings of the provided users and saves the users whose ratings
+// changed. It returns false if ctx was done before all ratings were fetched, in which case
+// the page should be processed again.
+func (p *pipeline) processPage(ctx context.Context, users []*database.User, update *database.RatingUpdate) bool {
+	logger := log.FromContext(ctx)
+	if len(users) == 0 {
+		return true
+	}
+
+	lichessUsernames := make([]string, 0, len(users))
+	for _, user := range users {
+		if lichess := user.Ratings[database.Lichess]; lichess != nil {
+			if lichessUsername := strings.TrimSpace(lichess.Username); lichessUsername != "" {
+				lichessUsernames = append(lichessUsernames, lichessUsername)
+			}
+		}
+	}
+	lichessRatings, err := fetchBulkLichessRatings(lichessUsernames)
 	if err != nil {
-		log.Errorf("Failed to get %s rating for %q: %v", systemName, rating.Username, err)
-		return false
+		logger.Errorf("Failed to fetch bulk Lichess ratings: %v", err)
+	}
+
+	fetchLichessRating := func(username string) (*database.Rating, error) {
+		if rating, ok := lichessRatings[strings.ToLower(username)]; !ok {
+			return nil, errors.New(404, "Invalid request: no Lichess rating found in bulk response", "")
+		} else {
+			return &database.Rating{
+				CurrentRating: rating.Performances.Classical.Rating,
+				Deviation:     rating.Performances.Classical.Deviation,
+				NumGames:      rating.Performances.Classical.NumGames,
+				IsProvisional: rating.Performances.Classical.IsProvisional,
+			}, nil
+		}
+	}
+
+	isBannedLichess := func(username string) bool {
+		if result, ok := lichessRatings[strings.ToLower(username)]; !ok {
+			return false
+		} else {
+			return result.TosViolation
+		}
+	}
+
+	fetchers := maps.Clone(p.fetchers)
+	if _, ok := fetchers[database.Lichess]; ok {
+		fetchers[database.Lichess] = fetchLichessRating
+	}
+
+	results := make(map[string]map[database.RatingSystem]fetchResult, len(users))
+	var resultsMu sync.Mutex
+	jobs := make(chan *database.User)
+	var wg sync.WaitGroup
+	for range min(workerCount, len(users)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for user := range jobs {
+				r := p.fetchUser(ctx, user, fetchers, update)
+				resultsMu.Lock()
+				results[user.Username] = r
+				resultsMu.Unlock()
+			}
+		}()
+	}
+	for _, user := range users {
+		jobs <- user
+	}
+	close(jobs)
+	wg.Wait()
+
+	var changed []*database.User
+	var history []database.RatingHistoryPoint
+	for _, user := range users {
+		history = append(history, ratingHistoryPoints(user, results[user.Username])...)
+		if applyRatingUpdates(user, results[user.Username], isBannedLichess, update) {
+			changed = append(changed, user)
+		}
 	}
+	saveRatings(ctx, changed, results, isBannedLichess)
+	if _, err := repository.PutRatingHistory(history); err != nil {
+		logger.Errorf("Failed to save rating history: %v", err)
+	}
+	return ctx.Err() == nil
+}
+
+// ratingHistoryPoints returns the rating history points to save for the provided user. A point
+// is saved when a fetched rating has changed, and every Monday for each positive rating, so that
+// every week has a point even if the rating is stable. It must be called before the results are
+// applied to the user.
+func ratingHistoryPoints(user *database.User, results map[database.RatingSystem]fetchResult) []database.RatingHistoryPoint {
+	var points []database.RatingHistoryPoint
+	for system, rating := range user.Ratings {
+		current := rating.CurrentRating
+		changed := false
+		if result, ok := results[system]; ok && result.username == strings.TrimSpace(rating.Username) {
+			changed = result.rating.CurrentRating != current
+			current = result.rating.CurrentRating
+		}
 
+		if current > 0 && (changed || now.Weekday() == time.Monday) {
+			points = append(points, database.NewRatingHistoryPoints(user.Username, system, now, current)...)
+		}
+	}
+	return points
+}
+
+// updateRating sets the provided rating to the fetched data. It returns true if the
+// rating was changed.
+func updateRating(rating *database.Rating, data *database.Rating) bool {
 	shouldUpdate := false
 
 	if data.CurrentRating != rating.CurrentRating || data.Deviation != rating.Deviation || data.NumGames != rating.NumGames || data.IsProvisional != rating.IsProvisional {
@@ -53,12 +283,25 @@ func updateRating(rating *database.Rating, systemName string, fetcher ratings.Ra
 	return shouldUpdate
 }
 
-func updateIfNecessary(user *database.User, queuedUpdates []*database.User, ratingFetchFuncs map[database.RatingSystem]ratings.RatingFetchFunc, isBannedLichess isBannedFunc) (*database.User, []*database.User) {
+// applyRatingUpdates sets the user's current ratings to the fetched results, and updates the
+// user's legacy rating histories and Lichess ban status. If update is not nil, its changed counts
+// are incremented. It returns true if the user was changed.
+func applyRatingUpdates(user *database.User, results map[database.RatingSystem]fetchResult, isBannedLichess isBannedFunc, update *database.RatingUpdate) bool {
 	shouldUpdate := false
 
 	for system, rating := range user.Ratings {
-		if system != database.Custom && system != database.Custom2 && system != database.Custom3 {
-			shouldUpdate = updateRating(rating, string(system), ratingFetchFuncs[system]) || shouldUpdate
+		if !system.IsCustom() {
+			rating.Username = strings.TrimSpace(rating.Username)
+		}
+
+		// The result is ignored if the user changed their username since it was fetched.
+		if result, ok := results[system]; ok && result.username == rating.Username {
+			if updateRating(rating, result.rating) {
+				shouldUpdate = true
+				if update != nil {
+					update.Metric(system).Changed++
+				}
+			}
 		}
 
 		if system == database.Lichess && isBannedLichess(rating.Username) {
@@ -83,119 +326,179 @@ func updateIfNecessary(user *database.User, queuedUpdates []*database.User, rati
 		}
 	}
 
-	if shouldUpdate {
-		queuedUpdates = append(queuedUpdates, user)
-		if len(queuedUpdates) == 25 {
-			if err := repository.UpdateUserRatings(queuedUpdates); err != nil {
-				log.Error(err)
-			} else {
-				log.Infof("Updated %d users", len(queuedUpdates))
-			}
-			queuedUpdates = nil
-		}
-	}
-
-	return user, queuedUpdates
+	return shouldUpdate
 }
 
-func updateUsers(users []*database.User) {
-	if len(users) == 0 {
-		return
+// saveRatings saves the ratings of the provided users in batches. If some users were modified
+// after they were read, their latest version is read again and the fetched ratings are reapplied
+// to it, so that changes made by the user in the meantime are not overwritten.
+func saveRatings(ctx context.Context, users []*database.User, results map[string]map[database.RatingSystem]fetchResult, isBannedLichess isBannedFunc) {
+	logger := log.FromContext(ctx)
+	merge := func(latest *database.User) bool {
+		return applyRatingUpdates(latest, results[latest.Username], isBannedLichess, nil)
 	}
 
-	lichessUsernames := make([]string, 0, len(users))
-	for _, user := range users {
-		if lichess := user.Ratings[database.Lichess]; lichess != nil {
-			if lichessUsername := strings.TrimSpace(lichess.Username); lichessUsername != "" {
-				lichessUsernames = append(lichessUsernames, lichessUsername)
-			}
+	for start := 0; start < len(users); start += 25 {
+		batch := users[start:min(start+25, len(users))]
+		if err := database.RetryUserConflicts(repository, batch, repository.UpdateUserRatings, merge); err != nil {
+			logger.Errorf("Failed to save ratings: %v", err)
+		} else {
+			logger.Infof("Updated %d users", len(batch))
 		}
 	}
-	lichessRatings, err := ratings.FetchBulkLichessRatings(lichessUsernames)
-	if err != nil {
-		log.Error(err)
-	}
+}
 
-	fetchLichessRating := func(username string) (*database.Rating, error) {
-		if rating, ok := lichessRatings[strings.ToLower(username)]; !ok {
-			return nil, errors.New("no Lichess rating found in cache")
-		} else {
-			return &database.Rating{
-				CurrentRating: rating.Performances.Classical.Rating,
-				Deviation:     rating.Performances.Classical.Deviation,
-				NumGames:      rating.Performances.Classical.NumGames,
-				IsProvisional: rating.Performances.Classical.IsProvisional,
-			}, nil
+// run processes pages of users for the provided update until it is complete, a page fails
+// or the context's deadline is close. The update is saved after each page, so that a run
+// which stops early can be resumed from the page it stopped at.
+func run(ctx context.Context, p *pipeline, update *database.RatingUpdate) error {
+	logger := log.FromContext(ctx)
+	for update.Status == database.RatingUpdateStatus_InProgress {
+		if nearDeadline(ctx) {
+			logger.Infof("Stopping rating update %s near deadline at cohort %s", update.Id, update.Cohort())
+			return nil
 		}
-	}
 
-	isBannedLichess := func(username string) bool {
-		if result, ok := lichessRatings[strings.ToLower(username)]; !ok {
-			return false
+		users, lastKey, err := repository.ListUserRatings(update.Cohort(), update.StartKey)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Processing %d users in cohort %s", len(users), update.Cohort())
+
+		pageCtx, cancel := withDeadlineMargin(ctx)
+		complete := p.processPage(pageCtx, users, update)
+		cancel()
+
+		if complete {
+			update.Advance(len(users), lastKey)
 		} else {
-			return result.TosViolation
+			update.UpdatedAt = time.Now().Format(time.RFC3339)
+		}
+		if err := repository.PutRatingUpdate(update); err != nil {
+			return err
 		}
 	}
 
-	ratingFetchFuncs := map[database.RatingSystem]ratings.RatingFetchFunc{
-		database.Chesscom: ratings.FetchChesscomRating,
-		database.Lichess:  fetchLichessRating,
-		database.Fide:     ratings.FetchFideRating,
-		database.Uscf:     ratings.FetchUscfRating,
-		database.Ecf:      ratings.FetchEcfRating,
-		database.Cfc:      ratings.FetchCfcRating,
-		database.Dwz:      ratings.FetchDwzRating,
-		database.Acf:      ratings.FetchAcfRating,
-		database.Knsb:     ratings.FetchKnsbRating,
+	logger.Info("Finished rating update",
+		log.String("id", update.Id),
+		log.Int("users", update.Users),
+		log.Any("metrics", update.Metrics),
+	)
+	return nil
+}
+
+// activeRun returns true if an in-progress update was saved after the provided time, in
+// which case another invocation is still running it.
+func activeRun(ctx context.Context, after time.Time) (bool, error) {
+	logger := log.FromContext(ctx)
+	for update, err := range database.All(ctx, repository.ListRatingUpdates) {
+		if err != nil {
+			return false, err
+		}
+		updatedAt, err := time.Parse(time.RFC3339, update.UpdatedAt)
+		if err == nil && updatedAt.After(after) {
+			logger.Infof("Rating update %s is running at cohort %s", update.Id, update.Cohort())
+			return true, nil
+		}
 	}
+	return false, nil
+}
 
-	var queuedUpdates []*database.User
-	for _, user := range users {
-		_, queuedUpdates = updateIfNecessary(user, queuedUpdates, ratingFetchFuncs, isBannedLichess)
+// resumeStale runs the in-progress updates which were last saved before the provided time.
+// Nothing is resumed while another invocation is running an update, so that the providers'
+// rate limits are not exceeded.
+func resumeStale(ctx context.Context, p *pipeline, before time.Time) error {
+	logger := log.FromContext(ctx)
+	if active, err := activeRun(ctx, before); err != nil || active {
+		return err
 	}
 
-	if len(queuedUpdates) > 0 {
-		if err := repository.UpdateUserRatings(queuedUpdates); err != nil {
-			log.Error(err)
-		} else {
-			log.Infof("Updated %d users", len(queuedUpdates))
+	for update, err := range database.All(ctx, repository.ListRatingUpdates) {
+		if err != nil {
+			return err
+		}
+
+		updatedAt, err := time.Parse(time.RFC3339, update.UpdatedAt)
+		if err == nil && updatedAt.After(before) {
+			continue
+		}
+
+		logger.Infof("Resuming rating update %s at cohort %s", update.Id, update.Cohort())
+		if err := run(ctx, p, &update); err != nil {
+			return err
+		}
+		if nearDeadline(ctx) {
+			return nil
 		}
 	}
+	return nil
+}
+
+// nearDeadline returns true if the context's deadline is within deadlineMargin.
+func nearDeadline(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < deadlineMargin
+}
+
+// withDeadlineMargin returns a context which is done deadlineMargin before ctx's deadline.
+func withDeadlineMargin(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(ctx, deadline.Add(-deadlineMargin))
+	}
+	return context.WithCancel(ctx)
 }
 
 type RatingUpdateRequest struct {
 	Cohorts []database.DojoCohort `json:"cohorts"`
+
+	// Resume is set by the scheduled event which resumes runs that timed out. Cohorts
+	// is ignored when it is set.
+	Resume bool `json:"resume"`
 }
 
 func Handler(ctx context.Context, event Event) (Event, error) {
-	log.Infof("Event: %#v", event)
-	log.SetRequestId(event.ID)
+	logger := log.Default().With(log.RequestId(event.ID))
+	ctx = log.NewContext(ctx, logger)
 
 	var req RatingUpdateRequest
 	err := json.Unmarshal(event.Detail, &req)
 	if err != nil {
-		log.Errorf("Failed to unmarshal request:%v ", err)
+		logger.Errorf("Failed to unmarshal request: %v", err)
 		return event, err
 	}
-	log.Infof("Request: %+v", req)
+	logger.Infof("Request: %+v", req)
 
-	var startKey string
-	for _, cohort := range req.Cohorts {
-		log.Debugf("Processing cohort %s", cohort)
+	now = time.Now()
+	p := newPipeline(ratings.Providers())
+	if req.Resume {
+		return event, resumeStale(ctx, p, time.Now().Add(-resumeDelay))
+	}
 
-		var users []*database.User
-		startKey = ""
-		for ok := true; ok; ok = startKey != "" {
-			users, startKey, err = repository.ListUserRatings(cohort, startKey)
-			if err != nil {
-				log.Errorf("Failed to scan users: %v", err)
-				return event, err
-			}
-			log.Infof("Processing %d users", len(users))
-			updateUsers(users)
-		}
+	if event.ID == "" {
+		err := fmt.Errorf("event id is required to checkpoint the rating update")
+		logger.Error(err.Error())
+		return event, err
+	}
+	if active, err := activeRun(ctx, time.Now().Add(-resumeDelay)); err != nil {
+		logger.Errorf("Failed to list rating updates: %v", err)
+		return event, err
+	} else if active {
+		logger.Infof("Skipping rating update %s, as another update is running", event.ID)
+		return event, nil
 	}
 
+	update := database.NewRatingUpdate(event.ID, req.Cohorts)
+	if err := repository.PutRatingUpdate(update); err != nil {
+		logger.Errorf("Failed to save rating update: %v", err)
+		return event, err
+	}
+	if err := run(ctx, p, update); err != nil {
+		logger.Errorf("Failed to run rating update: %v", err)
+		return event, err
+	}
 	return event, nil
 }
 
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/user/ratings"
+)
+
+// testProvider returns ratings from a map, and a 500 error for unknown usernames.
+type testProvider struct {
+	system  database.RatingSystem
+	ratings map[string]int
+}
+
+func (p *testProvider) System() database.RatingSystem { return p.system }
+func (p *testProvider) Name() string                  { return string(p.system) }
+func (p *testProvider) ValidateUsername(string) error { return nil }
+func (p *testProvider) ProfileURL(string) string      { return "" }
+
+func (p *testProvider) Fetch(username string) (*database.Rating, error) {
+	rating, ok := p.ratings[username]
+	if !ok {
+		return nil, errors.New(500, "Temporary server error", "provider is down")
+	}
+	return &database.Rating{CurrentRating: rating}, nil
+}
+
+// testRepository is the memory repository methods used by the tests.
+type testRepository interface {
+	database.RatingUpdater
+	database.RatingHistoryLister
+	CreateUser(username, email, name string, paymentInfo *database.PaymentInfo) (*database.User, error)
+	SetUserConditional(user *database.User, condition *string) error
+}
+
+func setupTest(t *testing.T) (testRepository, *pipeline) {
+	t.Helper()
+	repo := database.NewMemoryRepository()
+	repository = repo
+	fetchBulkLichessRatings = func(usernames []string) (map[string]ratings.LichessResponse, error) {
+		result := make(map[string]ratings.LichessResponse)
+		var r ratings.LichessResponse
+		r.Performances.Classical.Rating = 1900
+		result["bob-lichess"] = r
+		return result, nil
+	}
+	t.Cleanup(func() {
+		repository = database.DynamoDB
+		fetchBulkLichessRatings = ratings.FetchBulkLichessRatings
+	})
+
+	users := []struct {
+		username string
+		ratings  map[database.RatingSystem]*database.Rating
+	}{
+		{
+			username: "alice",
+			ratings: map[database.RatingSystem]*database.Rating{
+				database.Chesscom: {Username: " alice-chesscom ", CurrentRating: 1500, StartRating: 1400},
+				database.Fide:     {Username: "1", CurrentRating: 1700, StartRating: 1700},
+			},
+		},
+		{
+			username: "bob",
+			ratings: map[database.RatingSystem]*database.Rating{
+				database.Chesscom: {Username: "bob-chesscom", CurrentRating: 1600, StartRating: 1600},
+				database.Lichess:  {Username: "bob-lichess", CurrentRating: 1850, StartRating: 1800},
+				database.Custom:   {Username: "custom", CurrentRating: 1234},
+			},
+		},
+		{
+			username: "carol",
+			ratings: map[database.RatingSystem]*database.Rating{
+				database.Chesscom: {Username: "unknown"},
+			},
+		},
+	}
+	for _, u := range users {
+		user, err := repo.CreateUser(u.username, u.username+"@example.com", u.username, nil)
+		if err != nil {
+			t.Fatalf("CreateUser got err %v", err)
+		}
+		user.DojoCohort = "1500-1600"
+		user.Ratings = u.ratings
+		if err := repo.SetUserConditional(user, nil); err != nil {
+			t.Fatalf("SetUserConditional got err %v", err)
+		}
+	}
+
+	p := newPipeline([]ratings.RatingProvider{
+		&testProvider{system: database.Chesscom, ratings: map[string]int{"alice-chesscom": 1550, "bob-chesscom": 1600}},
+		&testProvider{system: database.Fide, ratings: map[string]int{"1": 1750}},
+		&testProvider{system: database.Lichess},
+	})
+	return repo, p
+}
+
+func TestRun(t *testing.T) {
+	repo, p := setupTest(t)
+	now = time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC) // A Tuesday
+
+	update := database.NewRatingUpdate("test", []database.DojoCohort{"1500-1600"})
+	if err := run(context.Background(), p, update); err != nil {
+		t.Fatalf("run got err %v", err)
+	}
+
+	if update.Status != database.RatingUpdateStatus_Complete {
+		t.Errorf("RatingUpdate got status %s, want %s", update.Status, database.RatingUpdateStatus_Complete)
+	}
+	wantMetrics := map[database.RatingSystem]*database.RatingUpdateMetrics{
+		database.Chesscom: {Fetched: 2, Changed: 1, Failed: 1},
+		database.Fide:     {Fetched: 1, Changed: 1},
+		database.Lichess:  {Fetched: 1, Changed: 1},
+	}
+	if diff := cmp.Diff(wantMetrics, update.Metrics); diff != "" {
+		t.Errorf("RatingUpdate metrics mismatch (-want +got):\n%s", diff)
+	}
+
+	users, err := repo.BatchGetUsers([]string{"alice", "bob"})
+	if err != nil {
+		t.Fatalf("BatchGetUsers got err %v", err)
+	}
+	got := make(map[string]map[database.RatingSystem]int)
+	for _, user := range users {
+		got[user.Username] = make(map[database.RatingSystem]int)
+		for system, rating := range user.Ratings {
+			got[user.Username][system] = rating.CurrentRating
+		}
+	}
+	want := map[string]map[database.RatingSystem]int{
+		"alice": {database.Chesscom: 1550, database.Fide: 1750},
+		"bob":   {database.Chesscom: 1600, database.Lichess: 1900, database.Custom: 1234},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Ratings mismatch (-want +got):\n%s", diff)
+	}
+
+	// Only changed ratings are saved in the history, since the run was not on a Monday.
+	gotHistory := make(map[string]map[database.RatingSystem]int)
+	for username, systems := range want {
+		gotHistory[username] = make(map[database.RatingSystem]int)
+		for system := range systems {
+			history, err := repo.ListRatingHistory(username, system, "", "")
+			if err != nil {
+				t.Fatalf("ListRatingHistory got err %v", err)
+			}
+			if len(history) > 0 {
+				gotHistory[username][system] = history[len(history)-1].Rating
+			}
+		}
+	}
+	wantHistory := map[string]map[database.RatingSystem]int{
+		"alice": {database.Chesscom: 1550, database.Fide: 1750},
+		"bob":   {database.Lichess: 1900},
+	}
+	if diff := cmp.Diff(wantHistory, gotHistory); diff != "" {
+		t.Errorf("Rating history mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunLegacyHistory(t *testing.T) {
+	repo, p := setupTest(t)
+	now = time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC) // A Monday
+
+	update := database.NewRatingUpdate("test", []database.DojoCohort{"1500-1600"})
+	if err := run(context.Background(), p, update); err != nil {
+		t.Fatalf("run got err %v", err)
+	}
+
+	users, err := repo.BatchGetUsers([]string{"alice", "bob"})
+	if err != nil {
+		t.Fatalf("BatchGetUsers got err %v", err)
+	}
+	got := make(map[string]map[database.RatingSystem][]database.RatingHistory)
+	for _, user := range users {
+		got[user.Username] = user.RatingHistories
+	}
+
+	date := now.Format(time.RFC3339)
+	want := map[string]map[database.RatingSystem][]database.RatingHistory{
+		"alice": {
+			database.Chesscom: {{Date: date, Rating: 1550}},
+			database.Fide:     {{Date: date, Rating: 1750}},
+		},
+		"bob": {
+			database.Chesscom: {{Date: date, Rating: 1600}},
+			database.Lichess:  {{Date: date, Rating: 1900}},
+			database.Custom:   {{Date: date, Rating: 1234}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RatingHistories mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRatingHistoryPoints(t *testing.T) {
+	user := &database.User{
+		Username: "alice",
+		Ratings: map[database.RatingSystem]*database.Rating{
+			database.Chesscom: {Username: "alice", CurrentRating: 1500},
+			database.Fide:     {Username: "1", CurrentRating: 1700},
+			database.Custom:   {CurrentRating: 1234},
+			database.Uscf:     {Username: "2"},
+		},
+	}
+	results := map[database.RatingSystem]fetchResult{
+		database.Chesscom: {username: "alice", rating: &database.Rating{CurrentRating: 1550}},
+		database.Fide:     {username: "1", rating: &database.Rating{CurrentRating: 1700}},
+	}
+
+	table := []struct {
+		name string
+		now  time.Time
+		want map[database.RatingSystem]int
+	}{
+		{
+			name: "Tuesday",
+			now:  time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC),
+			want: map[database.RatingSystem]int{database.Chesscom: 1550},
+		},
+		{
+			name: "Monday",
+			now:  time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC),
+			want: map[database.RatingSystem]int{database.Chesscom: 1550, database.Fide: 1700, database.Custom: 1234},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			now = tc.now
+			got := make(map[database.RatingSystem]int)
+			for _, p := range ratingHistoryPoints(user, results) {
+				got[p.RatingSystem] = p.Rating
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ratingHistoryPoints mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResumeStale(t *testing.T) {
+	repo, p := setupTest(t)
+
+	update := database.NewRatingUpdate("test", []database.DojoCohort{"1500-1600"})
+	if err := repo.PutRatingUpdate(update); err != nil {
+		t.Fatalf("PutRatingUpdate got err %v", err)
+	}
+
+	// A run which starts too close to its deadline saves nothing and is left in progress.
+	ctx, cancel := context.WithTimeout(context.Background(), deadlineMargin/2)
+	defer cancel()
+	if err := run(ctx, p, update); err != nil {
+		t.Fatalf("run got err %v", err)
+	}
+	if got, _ := repo.GetRatingUpdate("test"); got.Status != database.RatingUpdateStatus_InProgress {
+		t.Fatalf("RatingUpdate got status %s, want %s", got.Status, database.RatingUpdateStatus_InProgress)
+	}
+
+	// Runs saved after before are still going and are not resumed.
+	if err := resumeStale(context.Background(), p, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("resumeStale got err %v", err)
+	}
+	if got, _ := repo.GetRatingUpdate("test"); got.Status != database.RatingUpdateStatus_InProgress {
+		t.Fatalf("RatingUpdate got status %s, want recent run not to be resumed", got.Status)
+	}
+
+	if err := resumeStale(context.Background(), p, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("resumeStale got err %v", err)
+	}
+	got, _ := repo.GetRatingUpdate("test")
+	if got.Status != database.RatingUpdateStatus_Complete || got.Users != 3 {
+		t.Errorf("RatingUpdate got status %s and %d users, want %s and 3", got.Status, got.Users, database.RatingUpdateStatus_Complete)
+	}
+}
+
+func TestResumeStaleActiveRun(t *testing.T) {
+	repo, p := setupTest(t)
+
+	stale := database.NewRatingUpdate("stale", []database.DojoCohort{"1500-1600"})
+	stale.UpdatedAt = time.Now().Add(-2 * resumeDelay).Format(time.RFC3339)
+	active := database.NewRatingUpdate("active", []database.DojoCohort{"1500-1600"})
+	for _, update := range []*database.RatingUpdate{stale, active} {
+		if err := repo.PutRatingUpdate(update); err != nil {
+			t.Fatalf("PutRatingUpdate got err %v", err)
+		}
+	}
+
+	// The stale run is not resumed while another invocation is running the active run, since
+	// the two invocations would each use the providers' full rate limits.
+	if err := resumeStale(context.Background(), p, time.Now().Add(-resumeDelay)); err != nil {
+		t.Fatalf("resumeStale got err %v", err)
+	}
+	if got, _ := repo.GetRatingUpdate("stale"); got.Status != database.RatingUpdateStatus_InProgress || got.Users != 0 {
+		t.Errorf("RatingUpdate got status %s and %d users, want stale run not to be resumed", got.Status, got.Users)
+	}
+
+	// A new run is not started either.
+	event := Event{ID: "new", Detail: []byte(`{"cohorts": ["1500-1600"]}`)}
+	if _, err := Handler(context.Background(), event); err != nil {
+		t.Fatalf("Handler got err %v", err)
+	}
+	if _, err := repo.GetRatingUpdate("new"); err == nil {
+		t.Errorf("Handler started a run while another run was active")
+	}
+}
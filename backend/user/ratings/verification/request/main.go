@@ -0,0 +1,73 @@
+// Implements a Lambda handler which issues a token that the caller places in the profile of
+// their account in a rating system, to prove that they own it. The verify handler then checks
+// the profile for the token. Requesting a new token replaces the previous one.
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/user/ratings"
+)
+
+var repository = database.Repository[database.UserUpdater](database.DynamoDB)
+
+type RequestVerificationResponse struct {
+	*database.RatingVerification
+
+	// The URL of the profile page where the token should be placed.
+	ProfileURL string `json:"profileUrl"`
+}
+
+func main() {
+	lambda.Start(api.Handle(handler, api.RequireUser(repository)))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	user := api.UserFromContext(ctx)
+	system := database.RatingSystem(event.PathParameters["system"])
+
+	provider, ok := ratings.GetBioProvider(system)
+	if !ok {
+		return api.Response{}, errors.New(400, fmt.Sprintf("Invalid request: rating system `%s` does not support verification", system), "")
+	}
+
+	rating := user.Ratings[system]
+	if rating == nil || strings.TrimSpace(rating.Username) == "" {
+		return api.Response{}, errors.New(400, fmt.Sprintf("Invalid request: you have not set a %s username", provider.Name()), "")
+	}
+	if rating.Verified {
+		return api.Response{}, errors.New(400, fmt.Sprintf("Invalid request: your %s account is already verified", provider.Name()), "")
+	}
+
+	verification, err := ratings.NewVerification(rating.Username)
+	if err != nil {
+		return api.Response{}, err
+	}
+
+	verifications := maps.Clone(user.RatingVerifications)
+	if verifications == nil {
+		verifications = make(map[database.RatingSystem]*database.RatingVerification)
+	}
+	verifications[system] = verification
+
+	_, err = repository.UpdateUser(user.Username, &database.UserUpdate{
+		RatingVerifications: &verifications,
+		ExpectedVersion:     aws.Int(user.Version),
+	})
+	if err != nil {
+		return api.Response{}, err
+	}
+
+	return api.Success(&RequestVerificationResponse{
+		RatingVerification: verification,
+		ProfileURL:         provider.ProfileURL(verification.Username),
+	}), nil
+}
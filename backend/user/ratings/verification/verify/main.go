@@ -0,0 +1,62 @@
+// Implements a Lambda handler which checks that the profile of the caller's account in a
+// rating system contains the token issued by the request handler. If it does, the caller's
+// rating is marked verified.
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/user/ratings"
+)
+
+var repository = database.Repository[database.UserUpdater](database.DynamoDB)
+
+func main() {
+	lambda.Start(api.Handle(handler, api.RequireUser(repository)))
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	user := api.UserFromContext(ctx)
+	system := database.RatingSystem(event.PathParameters["system"])
+
+	provider, ok := ratings.GetBioProvider(system)
+	if !ok {
+		return api.Response{}, errors.New(400, fmt.Sprintf("Invalid request: rating system `%s` does not support verification", system), "")
+	}
+
+	rating := user.Ratings[system]
+	verification := user.RatingVerifications[system]
+	if rating == nil || verification == nil || !strings.EqualFold(strings.TrimSpace(rating.Username), verification.Username) {
+		return api.Response{}, errors.New(400, fmt.Sprintf("Invalid request: request a verification token for your current %s username first", provider.Name()), "")
+	}
+
+	if err := ratings.Verify(provider, verification); err != nil {
+		return api.Response{}, err
+	}
+
+	verified := *rating
+	verified.Verified = true
+	userRatings := maps.Clone(user.Ratings)
+	userRatings[system] = &verified
+
+	verifications := maps.Clone(user.RatingVerifications)
+	delete(verifications, system)
+
+	newUser, err := repository.UpdateUser(user.Username, &database.UserUpdate{
+		Ratings:             &userRatings,
+		RatingVerifications: &verifications,
+		ExpectedVersion:     aws.Int(user.Version),
+	})
+	if err != nil {
+		return api.Response{}, err
+	}
+	return api.Success(newUser), nil
+}
@@ -0,0 +1,136 @@
+package ratings
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+// VerificationTokenTTL is how long a verification token can be used after it is issued.
+const VerificationTokenTTL = 24 * time.Hour
+
+// BioFetchFunc returns the text of a profile field which only the owner of the provided
+// username can edit.
+type BioFetchFunc func(username string) (string, error)
+
+// BioProvider is a RatingProvider whose accounts can be verified by placing a token in
+// the account's profile.
+type BioProvider interface {
+	RatingProvider
+
+	// FetchBio returns the text of the provided username's profile which only the owner
+	// of the account can edit.
+	FetchBio(username string) (string, error)
+}
+
+// bioProvider is a provider which also fetches profile bios with a BioFetchFunc.
+type bioProvider struct {
+	provider
+	bio BioFetchFunc
+}
+
+func (p *bioProvider) FetchBio(username string) (string, error) {
+	return p.bio(username)
+}
+
+// GetBioProvider returns the BioProvider registered for the provided rating system. It
+// returns false if the rating system does not support verification.
+func GetBioProvider(system database.RatingSystem) (BioProvider, bool) {
+	p, ok := providers[system].(BioProvider)
+	return p, ok
+}
+
+// NewVerification returns a RatingVerification with a new random token for the provided username.
+func NewVerification(username string) (*database.RatingVerification, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(500, "Temporary server error", "Failed to generate verification token", err)
+	}
+	return &database.RatingVerification{
+		Username:  strings.TrimSpace(username),
+		Token:     "chessdojo-" + hex.EncodeToString(b),
+		ExpiresAt: time.Now().Add(VerificationTokenTTL).Format(time.RFC3339),
+	}, nil
+}
+
+// Verify fetches the profile of the verification's username and returns an error if it
+// does not contain the verification's token.
+func Verify(p BioProvider, verification *database.RatingVerification) error {
+	if expiresAt, err := time.Parse(time.RFC3339, verification.ExpiresAt); err != nil || time.Now().After(expiresAt) {
+		return errors.New(400, "Invalid request: your verification token has expired. Please request a new one", "")
+	}
+
+	bio, err := p.FetchBio(verification.Username)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(bio, verification.Token) {
+		return errors.New(400, fmt.Sprintf("Invalid request: the token was not found in the profile of %s account `%s`", p.Name(), verification.Username), "")
+	}
+	return nil
+}
+
+// CheckVerified returns an error if the user has not verified the provided username in the
+// provided rating system. Rating systems which do not support verification are always allowed.
+func CheckVerified(user *database.User, system database.RatingSystem, username string) error {
+	p, ok := GetBioProvider(system)
+	if !ok || user.IsRatingVerified(system, username) {
+		return nil
+	}
+	return errors.New(400, fmt.Sprintf("Invalid request: you must verify your %s account `%s` first", p.Name(), strings.TrimSpace(username)), "")
+}
+
+type LichessProfileResponse struct {
+	Profile struct {
+		Bio string `json:"bio"`
+	} `json:"profile"`
+}
+
+// FetchLichessBio returns the bio of the provided Lichess username.
+func FetchLichessBio(lichessUsername string) (string, error) {
+	resp, err := client.Get(fmt.Sprintf("https://lichess.org/api/user/%s", lichessUsername))
+	if err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to get lichess profile", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", errors.New(400, fmt.Sprintf("Invalid request: lichess returned status `%d` for given player", resp.StatusCode), "")
+	}
+
+	var profile LichessProfileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to read lichess response", err)
+	}
+	return profile.Profile.Bio, nil
+}
+
+type ChesscomProfileResponse struct {
+	Location string `json:"location"`
+}
+
+// FetchChesscomBio returns the location of the provided chess.com username. The chess.com
+// API does not return the profile's bio, so the location field is used instead.
+func FetchChesscomBio(chesscomUsername string) (string, error) {
+	resp, err := client.Get(fmt.Sprintf("https://api.chess.com/pub/player/%s", chesscomUsername))
+	if err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to get chess.com profile", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", errors.New(400, fmt.Sprintf("Invalid request: chess.com returned status `%d` for given player", resp.StatusCode), "")
+	}
+
+	var profile ChesscomProfileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return "", errors.Wrap(500, "Temporary server error", "Failed to read chess.com response", err)
+	}
+	return profile.Location, nil
+}
@@ -0,0 +1,106 @@
+package ratings
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+func TestVerify(t *testing.T) {
+	SetTransport(fixtures)
+	defer SetTransport(nil)
+
+	expiresAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	table := []struct {
+		system       database.RatingSystem
+		verification database.RatingVerification
+		wantErr      bool
+	}{
+		{
+			system:       database.Lichess,
+			verification: database.RatingVerification{Username: "dojotester", Token: "chessdojo-0123456789abcdef", ExpiresAt: expiresAt},
+		},
+		{
+			system:       database.Lichess,
+			verification: database.RatingVerification{Username: "dojotester", Token: "chessdojo-fedcba9876543210", ExpiresAt: expiresAt},
+			wantErr:      true,
+		},
+		{
+			system:       database.Chesscom,
+			verification: database.RatingVerification{Username: "dojotester", Token: "chessdojo-0123456789abcdef", ExpiresAt: expiresAt},
+		},
+		{
+			system:       database.Chesscom,
+			verification: database.RatingVerification{Username: "nobody", Token: "chessdojo-0123456789abcdef", ExpiresAt: expiresAt},
+			wantErr:      true,
+		},
+		{
+			system:       database.Chesscom,
+			verification: database.RatingVerification{Username: "dojotester", Token: "chessdojo-0123456789abcdef", ExpiresAt: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+			wantErr:      true,
+		},
+	}
+
+	for i, tc := range table {
+		t.Run(fmt.Sprintf("%s/%d", tc.system, i), func(t *testing.T) {
+			provider, ok := GetBioProvider(tc.system)
+			if !ok {
+				t.Fatalf("GetBioProvider(%s) found no provider", tc.system)
+			}
+
+			err := Verify(provider, &tc.verification)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Verify got err %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewVerification(t *testing.T) {
+	a, err := NewVerification(" DojoTester ")
+	if err != nil {
+		t.Fatalf("NewVerification got err %v", err)
+	}
+	b, err := NewVerification("DojoTester")
+	if err != nil {
+		t.Fatalf("NewVerification got err %v", err)
+	}
+
+	if a.Username != "DojoTester" {
+		t.Errorf("NewVerification got username %q, want %q", a.Username, "DojoTester")
+	}
+	if a.Token == b.Token {
+		t.Errorf("NewVerification got the same token %q twice", a.Token)
+	}
+}
+
+func TestCheckVerified(t *testing.T) {
+	user := &database.User{
+		Ratings: map[database.RatingSystem]*database.Rating{
+			database.Lichess:  {Username: "DojoTester", Verified: true},
+			database.Chesscom: {Username: "dojotester"},
+		},
+	}
+
+	table := []struct {
+		system   database.RatingSystem
+		username string
+		wantErr  bool
+	}{
+		{system: database.Lichess, username: "dojotester"},
+		{system: database.Lichess, username: "someoneelse", wantErr: true},
+		{system: database.Chesscom, username: "dojotester", wantErr: true},
+		{system: database.Fide, username: "1503014"},
+	}
+
+	for _, tc := range table {
+		t.Run(fmt.Sprintf("%s/%s", tc.system, tc.username), func(t *testing.T) {
+			err := CheckVerified(user, tc.system, tc.username)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("CheckVerified got err %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
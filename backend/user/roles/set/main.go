@@ -0,0 +1,61 @@
+// Implements a Lambda handler that allows the caller to set the roles
+// of another user.
+//
+// The caller must have the MANAGE_ROLES permission.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
+)
+
+type SetRolesRequest struct {
+	// The new roles of the user. Any roles not in this list are removed.
+	Roles []database.Role `json:"roles"`
+}
+
+var repository = database.Repository[database.UserRoleSetter](database.DynamoDB)
+
+func main() {
+	lambda.Start(api.Handle(handler,
+		api.RequireUser(repository),
+		api.RequirePermission(database.Permission_ManageRoles),
+		api.DecodeJSON[SetRolesRequest](),
+		api.Validate(validateRequest),
+	))
+}
+
+func validateRequest(request *SetRolesRequest) error {
+	for _, role := range request.Roles {
+		if !database.IsValidRole(role) {
+			return errors.New(400, fmt.Sprintf("Invalid request: role `%s` does not exist", role), "")
+		}
+	}
+	return nil
+}
+
+func handler(ctx context.Context, event api.Request) (api.Response, error) {
+	username := event.PathParameters["username"]
+	if username == "" {
+		return api.Response{}, errors.New(400, "Invalid request: username is required", "")
+	}
+
+	before, err := repository.GetUser(username)
+	if err != nil {
+		return api.Response{}, err
+	}
+
+	request := api.Body[SetRolesRequest](ctx)
+	user, err := repository.SetUserRoles(username, request.Roles)
+	if err != nil {
+		return api.Response{}, err
+	}
+
+	api.Audit(repository, event, database.AuditAction_SetRoles, database.UserAuditTarget(username), before.Roles, user.Roles)
+	return api.Success(user), nil
+}
@@ -7,11 +7,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.UserLister = database.DynamoDB
+var repository = database.Repository[database.UserLister](database.DynamoDB)
 
 type SearchUsersResponse struct {
 	Users            []*database.User `json:"users"`
@@ -19,13 +18,10 @@ type SearchUsersResponse struct {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	query, _ := event.QueryStringParameters["query"]
 	fieldStr, _ := event.QueryStringParameters["fields"]
 	startKey, _ := event.QueryStringParameters["startKey"]
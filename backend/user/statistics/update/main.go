@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
@@ -13,7 +15,7 @@ import (
 
 type Event events.CloudWatchEvent
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.UserStatisticsUpdater](database.DynamoDB)
 var monthAgo = time.Now().Add(database.ONE_MONTH_AGO).Format(time.RFC3339)
 
 func main() {
@@ -21,10 +23,11 @@ func main() {
 }
 
 func Handler(ctx context.Context, event Event) (Event, error) {
-	log.Infof("Event: %#v", event)
-	log.SetRequestId(event.ID)
+	logger := log.Default().With(log.RequestId(event.ID))
+	ctx = log.NewContext(ctx, logger)
+	logger.Infof("Event: %#v", event)
 
-	requirements, err := fetchRequirements()
+	requirements, err := fetchRequirements(ctx)
 	if err != nil {
 		return event, err
 	}
@@ -40,22 +43,20 @@ func Handler(ctx context.Context, event Event) (Event, error) {
 		stats.Cohorts[cohort].SubscriptionCancelations = currentStats.Cohorts[cohort].SubscriptionCancelations
 	}
 
+	distributions := database.NewRatingDistributions()
 	for _, cohort := range database.Cohorts {
-		log.Debugf("Processing cohort %s", cohort)
+		logger.Debugf("Processing cohort %s", cohort)
 
-		var users []*database.User
-		var startKey = ""
-		for ok := true; ok; ok = startKey != "" {
-			users, startKey, err = repository.ListUserRatings(cohort, startKey)
+		users := database.All(ctx, func(startKey string) ([]*database.User, string, error) {
+			return repository.ListUserRatings(cohort, startKey)
+		})
+		for u, err := range users {
 			if err != nil {
-				log.Errorf("Failed to scan users: %v", err)
+				logger.Errorf("Failed to scan users: %v", err)
 				return event, err
 			}
-
-			log.Infof("Processing %d users", len(users))
-			for _, u := range users {
-				updateStats(stats, u, requirements)
-			}
+			updateStats(stats, u, requirements)
+			database.AddRatingDistribution(distributions, u)
 		}
 
 		if stats.Cohorts[cohort].ActiveParticipants > 0 || stats.Cohorts[cohort].InactiveParticipants > 0 {
@@ -63,45 +64,39 @@ func Handler(ctx context.Context, event Event) (Event, error) {
 		}
 	}
 
-	log.Debugf("Processing graduations")
-	var graduations []database.Graduation
-	var startKey = ""
-	for ok := true; ok; ok = startKey != "" {
-		graduations, startKey, err = repository.ScanGraduations(startKey)
+	logger.Debugf("Processing graduations")
+	for g, err := range database.All(ctx, repository.ScanGraduations) {
 		if err != nil {
-			log.Errorf("Failed to scan graduations: %v", err)
+			logger.Errorf("Failed to scan graduations: %v", err)
 			return event, err
 		}
-
-		log.Infof("Processing %d graduations", len(graduations))
-		for _, g := range graduations {
-			updateGradStats(stats, &g, requirements)
-		}
+		updateGradStats(stats, &g, requirements)
 	}
 
 	if err := repository.SetUserStatistics(stats); err != nil {
-		log.Error(err)
+		logger.Errorf("Failed to save user statistics: %v", err)
+		return event, err
+	}
+
+	if _, err := repository.PutRatingDistributions(slices.Collect(maps.Values(distributions))); err != nil {
+		logger.Errorf("Failed to save rating distributions: %v", err)
 		return event, err
 	}
 
 	return event, nil
 }
 
-func fetchRequirements() ([]*database.Requirement, error) {
-	log.Debug("Fetching requirements")
-	var requirements []*database.Requirement
-	var rs []*database.Requirement
-	var startKey string
-	var err error
-	for ok := true; ok; ok = startKey != "" {
-		rs, startKey, err = repository.ScanRequirements("", startKey)
-		if err != nil {
-			log.Errorf("Failed to scan requirements: %v", err)
-			return nil, err
-		}
-		requirements = append(requirements, rs...)
+func fetchRequirements(ctx context.Context) ([]*database.Requirement, error) {
+	logger := log.FromContext(ctx)
+	logger.Debug("Fetching requirements")
+	requirements, err := database.Collect(ctx, func(startKey string) ([]*database.Requirement, string, error) {
+		return repository.ScanRequirements("", startKey)
+	})
+	if err != nil {
+		logger.Errorf("Failed to scan requirements: %v", err)
+		return nil, err
 	}
-	log.Debugf("Got %d requirements", len(requirements))
+	logger.Debugf("Got %d requirements", len(requirements))
 	return requirements, nil
 }
 
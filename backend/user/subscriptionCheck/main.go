@@ -13,7 +13,7 @@ import (
 
 type Event events.CloudWatchEvent
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.UserSubscriptionChecker](database.DynamoDB)
 var monthAgo = time.Now().Add(database.ONE_MONTH_AGO).Format(time.RFC3339)
 
 func main() {
@@ -21,13 +21,13 @@ func main() {
 }
 
 func handler(ctx context.Context, event Event) (Event, error) {
-	log.Infof("Event: %#v", event)
-	log.SetRequestId(event.ID)
+	logger := log.Default().With(log.RequestId(event.ID))
+	logger.Infof("Event: %#v", event)
 
 	var queuedUpdates []*database.User
 
 	for _, cohort := range database.Cohorts {
-		log.Debugf("Processing cohort %s", cohort)
+		logger.Debugf("Processing cohort %s", cohort)
 
 		var users []*database.User
 		var startKey = ""
@@ -35,20 +35,20 @@ func handler(ctx context.Context, event Event) (Event, error) {
 		for ok := true; ok; ok = startKey != "" {
 			users, startKey, err = repository.ListUserRatings(cohort, startKey)
 			if err != nil {
-				log.Errorf("Failed to scan users: %v", err)
+				logger.Errorf("Failed to scan users: %v", err)
 				return event, err
 			}
 
-			log.Infof("Processing %d users", len(users))
+			logger.Infof("Processing %d users", len(users))
 			for _, u := range users {
 				shouldUpdate := updateUser(u)
 				if shouldUpdate {
 					queuedUpdates = append(queuedUpdates, u)
 					if len(queuedUpdates) == 25 {
 						if err := repository.UpdateUserSubscriptionStatuses(queuedUpdates); err != nil {
-							log.Error(err)
+							logger.Errorf("Failed to update subscription statuses: %v", err)
 						} else {
-							log.Infof("Updated %d users", len(queuedUpdates))
+							logger.Infof("Updated %d users", len(queuedUpdates))
 						}
 						queuedUpdates = nil
 					}
@@ -59,9 +59,9 @@ func handler(ctx context.Context, event Event) (Event, error) {
 
 	if len(queuedUpdates) > 0 {
 		if err := repository.UpdateUserSubscriptionStatuses(queuedUpdates); err != nil {
-			log.Error(err)
+			logger.Errorf("Failed to update subscription statuses: %v", err)
 		} else {
-			log.Infof("Updated %d users", len(queuedUpdates))
+			logger.Infof("Updated %d users", len(queuedUpdates))
 		}
 	}
 
@@ -6,11 +6,10 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
-	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
-var repository database.TimelineLister = database.DynamoDB
+var repository = database.Repository[database.TimelineLister](database.DynamoDB)
 
 type ListTimelineEntriesResponse struct {
 	Entries          []*database.TimelineEntry `json:"entries"`
@@ -18,9 +17,6 @@ type ListTimelineEntriesResponse struct {
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
 	owner, _ := event.PathParameters["owner"]
 	if owner == "" {
 		return api.Failure(errors.New(400, "Invalid request: owner is required", "")), nil
@@ -40,5 +36,5 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 }
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
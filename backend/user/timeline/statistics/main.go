@@ -18,7 +18,7 @@ type UpdateRequest struct {
 	Cohorts []database.DojoCohort `json:"cohorts"`
 }
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.UserTimeUpdater](database.DynamoDB)
 
 var weekAgo = time.Now().Add(-time.Hour * 24 * 7).Format(time.RFC3339)
 var thirtyDaysAgo = time.Now().Add(-time.Hour * 24 * 30).Format(time.RFC3339)
@@ -31,10 +31,11 @@ func main() {
 }
 
 func Handler(ctx context.Context, event Event) (Event, error) {
-	log.SetRequestId(event.ID)
-	log.Infof("Event: %#v", event)
+	logger := log.Default().With(log.RequestId(event.ID))
+	ctx = log.NewContext(ctx, logger)
+	logger.Infof("Event: %#v", event)
 
-	requirements, err := fetchRequirements()
+	requirements, err := fetchRequirements(ctx)
 	if err != nil {
 		return event, err
 	}
@@ -46,36 +47,32 @@ func Handler(ctx context.Context, event Event) (Event, error) {
 	var req UpdateRequest
 	err = json.Unmarshal(event.Detail, &req)
 	if err != nil {
-		log.Errorf("Failed to unmarshal request: %v", err)
+		logger.Errorf("Failed to unmarshal request: %v", err)
 		return event, err
 	}
-	log.Infof("Request: %+v", req)
+	logger.Infof("Request: %+v", req)
 
 	var queuedUpdates []*database.User
 
 	for _, cohort := range req.Cohorts {
-		log.Debugf("Processing cohort %s", cohort)
+		logger.Debugf("Processing cohort %s", cohort)
 
 		var users []*database.User
 		var startKey = ""
 		for ok := true; ok; ok = startKey != "" {
 			users, startKey, err = repository.ListUserRatings(cohort, startKey)
 			if err != nil {
-				log.Errorf("Failed to scan users: %v", err)
+				logger.Errorf("Failed to scan users: %v", err)
 				return event, err
 			}
 
-			log.Infof("Processing %d users", len(users))
+			logger.Infof("Processing %d users", len(users))
 			for _, u := range users {
-				shouldUpdate := updateUser(u, requirements, requirementsMap)
+				shouldUpdate := updateUser(ctx, u, requirements, requirementsMap)
 				if shouldUpdate {
 					queuedUpdates = append(queuedUpdates, u)
 					if len(queuedUpdates) == 25 {
-						if err := repository.UpdateUserTimes(queuedUpdates); err != nil {
-							log.Error(err)
-						} else {
-							log.Infof("Updated %d users", len(queuedUpdates))
-						}
+						saveTimes(ctx, queuedUpdates, requirements, requirementsMap)
 						queuedUpdates = nil
 					}
 				}
@@ -84,18 +81,29 @@ func Handler(ctx context.Context, event Event) (Event, error) {
 	}
 
 	if len(queuedUpdates) > 0 {
-		if err := repository.UpdateUserTimes(queuedUpdates); err != nil {
-			log.Error(err)
-		} else {
-			log.Infof("Updated %d users", len(queuedUpdates))
-		}
+		saveTimes(ctx, queuedUpdates, requirements, requirementsMap)
 	}
 
 	return event, nil
 }
 
-func fetchRequirements() ([]*database.Requirement, error) {
-	log.Debug("Fetching requirements")
+// saveTimes saves the scores and times of the provided users. If some users were modified after
+// they were read, their latest version is read again and the times are recalculated from it.
+func saveTimes(ctx context.Context, users []*database.User, requirements []*database.Requirement, requirementsMap map[string]bool) {
+	logger := log.FromContext(ctx)
+	merge := func(latest *database.User) bool {
+		return updateUser(ctx, latest, requirements, requirementsMap)
+	}
+	if err := database.RetryUserConflicts(repository, users, repository.UpdateUserTimes, merge); err != nil {
+		logger.Errorf("Failed to save user times: %v", err)
+	} else {
+		logger.Infof("Updated %d users", len(users))
+	}
+}
+
+func fetchRequirements(ctx context.Context) ([]*database.Requirement, error) {
+	logger := log.FromContext(ctx)
+	logger.Debug("Fetching requirements")
 	var requirements []*database.Requirement
 	var rs []*database.Requirement
 	var startKey string
@@ -103,16 +111,17 @@ func fetchRequirements() ([]*database.Requirement, error) {
 	for ok := true; ok; ok = startKey != "" {
 		rs, startKey, err = repository.ScanRequirements("", startKey)
 		if err != nil {
-			log.Errorf("Failed to scan requirements: %v", err)
+			logger.Errorf("Failed to scan requirements: %v", err)
 			return nil, err
 		}
 		requirements = append(requirements, rs...)
 	}
-	log.Debugf("Got %d requirements", len(requirements))
+	logger.Debugf("Got %d requirements", len(requirements))
 	return requirements, nil
 }
 
-func updateUser(user *database.User, requirements []*database.Requirement, requirementsMap map[string]bool) bool {
+func updateUser(ctx context.Context, user *database.User, requirements []*database.Requirement, requirementsMap map[string]bool) bool {
+	logger := log.FromContext(ctx)
 	var timeline []*database.TimelineEntry
 	var startKey = ""
 	var err error
@@ -131,7 +140,7 @@ func updateUser(user *database.User, requirements []*database.Requirement, requi
 	for ok := true; ok; ok = startKey != "" {
 		timeline, startKey, err = repository.ListTimelineEntries(user.Username, startKey)
 		if err != nil {
-			log.Errorf("Failed to get user's timeline: %s", user.Username)
+			logger.Errorf("Failed to get user's timeline: %s", user.Username)
 			return false
 		}
 
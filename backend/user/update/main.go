@@ -13,6 +13,7 @@ import (
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/errors"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/cohorts"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/discord"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/user/ratings"
@@ -20,23 +21,31 @@ import (
 	"google.golang.org/api/sheets/v4"
 )
 
-var repository = database.DynamoDB
+type UserUpdater interface {
+	database.UserUpdater
+	database.TimelineLister
+	database.TimelineEditor
+}
+
+var repository = database.Repository[UserUpdater](database.DynamoDB)
 var mediaStore database.MediaStore = database.S3
 var stage = os.Getenv("stage")
 
+// requireVerifiedRatings is true if users must verify the account of their preferred
+// rating system before choosing a cohort.
+var requireVerifiedRatings = os.Getenv("requireVerifiedRatings") == "true"
+
 const (
 	referralSheetId = "198Me8Qm7YVKEtlY0Y56PbePaJz-Quqr4cA6hhhdRkgc"
 	sheetRange      = "Sheet1"
 )
 
 func main() {
-	lambda.Start(Handler)
+	lambda.Start(api.Logged(Handler))
 }
 
 func Handler(ctx context.Context, event api.Request) (api.Response, error) {
-	log.SetRequestId(event.RequestContext.RequestID)
-	log.Infof("Event: %#v", event)
-
+	logger := log.FromContext(ctx)
 	info := api.GetUserInfo(event)
 	if info.Username == "" {
 		return api.Failure(errors.New(400, "Invalid request: username is required", "")), nil
@@ -86,6 +95,11 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	if err := fetchRatings(user, update); err != nil {
 		return api.Failure(err), nil
 	}
+	if update.DojoCohort != nil && *update.DojoCohort != user.DojoCohort {
+		if err := checkCohortVerification(user, update); err != nil {
+			return api.Failure(err), nil
+		}
+	}
 
 	if update.ProfilePictureData != nil {
 		if *update.ProfilePictureData == "" {
@@ -106,26 +120,29 @@ func Handler(ctx context.Context, event api.Request) (api.Response, error) {
 	}
 
 	if update.CustomTasks != nil {
-		handleCustomTaskUpdate(user, newUser)
+		handleCustomTaskUpdate(ctx, user, newUser)
 	}
 	if update.DojoCohort != nil {
 		if err := discord.SetCohortRole(newUser); err != nil {
-			log.Errorf("Failed to set Discord roles: %v", err)
+			logger.Errorf("Failed to set Discord roles: %v", err)
 		}
 	}
 
 	return api.Success(newUser), nil
 }
 
-func fetchCurrentRating(rating *database.Rating, fetcher ratings.RatingFetchFunc) error {
+func fetchCurrentRating(rating *database.Rating, provider ratings.RatingProvider) error {
 	rating.Username = strings.TrimSpace(rating.Username)
 	if rating.Username == "" {
 		rating.CurrentRating = 0
 		rating.StartRating = 0
 		return nil
 	}
+	if err := provider.ValidateUsername(rating.Username); err != nil {
+		return err
+	}
 
-	data, err := fetcher(rating.Username)
+	data, err := provider.Fetch(rating.Username)
 	if err != nil {
 		return err
 	}
@@ -146,8 +163,17 @@ func fetchRatings(user *database.User, update *database.UserUpdate) error {
 
 	for system, rating := range *update.Ratings {
 		existingRating := user.Ratings[system]
+
+		// Verification cannot be set by the user, and is lost when the username changes other
+		// than in case.
+		rating.Verified = user.IsRatingVerified(system, rating.Username)
+
 		if system != database.Custom && system != database.Custom2 && system != database.Custom3 && (existingRating == nil || rating.Username != existingRating.Username || rating.CurrentRating == 0 || rating.StartRating == 0) {
-			if err := fetchCurrentRating(rating, ratings.RatingFetchFuncs[system]); err != nil {
+			provider, ok := ratings.GetProvider(system)
+			if !ok {
+				return errors.New(400, fmt.Sprintf("Invalid request: rating system `%s` is not supported", system), "")
+			}
+			if err := fetchCurrentRating(rating, provider); err != nil {
 				return err
 			}
 		}
@@ -166,7 +192,10 @@ func handleAutopickCohort(user *database.User, update *database.UserUpdate) api.
 	if err := fetchRatings(user, update); err != nil {
 		return api.Failure(err)
 	}
-	if cohort := update.AutopickCohort(); cohort == database.NoCohort {
+	if err := checkCohortVerification(user, update); err != nil {
+		return api.Failure(err)
+	}
+	if cohort := cohorts.Autopick(update); cohort == database.NoCohort {
 		return api.Failure(errors.New(500, "Unable to choose cohort. Please contact support", fmt.Sprintf("Autopick cohort returned NoCohort for update %#v", update)))
 	}
 
@@ -177,7 +206,31 @@ func handleAutopickCohort(user *database.User, update *database.UserUpdate) api.
 	return api.Success(user)
 }
 
+// checkCohortVerification returns an error if verified ratings are required and the user has
+// not verified the account of the rating system their cohort is based on, after the update is
+// applied.
+func checkCohortVerification(user *database.User, update *database.UserUpdate) error {
+	if !requireVerifiedRatings {
+		return nil
+	}
+
+	updated := &database.User{RatingSystem: user.RatingSystem, Ratings: user.Ratings}
+	if update.RatingSystem != nil {
+		updated.RatingSystem = *update.RatingSystem
+	}
+	if update.Ratings != nil {
+		updated.Ratings = *update.Ratings
+	}
+
+	rating := updated.Ratings[updated.RatingSystem]
+	if rating == nil || strings.TrimSpace(rating.Username) == "" {
+		return nil
+	}
+	return ratings.CheckVerified(updated, updated.RatingSystem, rating.Username)
+}
+
 func saveReferralSource(ctx context.Context, user *database.User, update *database.UserUpdate) error {
+	logger := log.FromContext(ctx)
 	if update.ReferralSource == nil {
 		return nil
 	}
@@ -194,7 +247,7 @@ func saveReferralSource(ctx context.Context, user *database.User, update *databa
 
 	client, err := getSheetsClient(ctx)
 	if err != nil {
-		log.Errorf("Failed to get sheets client: %v", err)
+		logger.Errorf("Failed to get sheets client: %v", err)
 		return nil
 	}
 
@@ -214,11 +267,11 @@ func saveReferralSource(ctx context.Context, user *database.User, update *databa
 	_, err = call.Do()
 	if err != nil {
 		err = errors.Wrap(500, "Temporary server error", "Failed Spreadsheet append call", err)
-		log.Error(err)
+		logger.Errorf("Failed to save referral source: %v", err)
 	}
 
 	if err := os.Remove("/tmp/openClassicalServiceAccountKey.json"); err != nil {
-		log.Error(errors.Wrap(500, "Failed to remove JSON file: %v", "", err))
+		logger.Errorf("Failed to remove JSON file: %v", err)
 	}
 
 	return nil
@@ -248,11 +301,11 @@ func getSheetsClient(ctx context.Context) (*sheets.Service, error) {
 // Handles updating the user's timeline for a custom task update, if necessary.
 // Note that we assume the user can only update a single custom task at a time,
 // as that is currently the only flow supported by the frontend.
-func handleCustomTaskUpdate(before *database.User, after *database.User) {
+func handleCustomTaskUpdate(ctx context.Context, before *database.User, after *database.User) {
 	for _, t := range before.CustomTasks {
 		for _, t2 := range after.CustomTasks {
 			if t.Id == t2.Id && t.Category != t2.Category {
-				updateTimeline(after, t2)
+				updateTimeline(ctx, after, t2)
 				return
 			}
 		}
@@ -261,17 +314,18 @@ func handleCustomTaskUpdate(before *database.User, after *database.User) {
 
 // Updates the timeline for the given user so that entries matching the given
 // task have the correct requirement category and scoreboard display.
-func updateTimeline(user *database.User, task *database.CustomTask) {
-	log.Infof("Updating timeline for task %q: %v", task.Id, task)
+func updateTimeline(ctx context.Context, user *database.User, task *database.CustomTask) {
+	logger := log.FromContext(ctx)
+	logger.Infof("Updating timeline for task %q: %v", task.Id, task)
 
 	startKey := ""
 	for loop := true; loop; loop = (startKey != "") {
 		entries, lastKey, err := repository.ListTimelineEntries(user.Username, startKey)
 		if err != nil {
-			log.Errorf("Failed to fetch timeline entries: %v", err)
+			logger.Errorf("Failed to fetch timeline entries: %v", err)
 			return
 		}
-		log.Infof("Checking %d entries: %v", len(entries), entries)
+		logger.Infof("Checking %d entries: %v", len(entries), entries)
 
 		updatedEntries := make([]*database.TimelineEntry, 0)
 		for _, entry := range entries {
@@ -282,10 +336,10 @@ func updateTimeline(user *database.User, task *database.CustomTask) {
 			}
 		}
 
-		log.Infof("Updating %d entries: %v", len(updatedEntries), updatedEntries)
+		logger.Infof("Updating %d entries: %v", len(updatedEntries), updatedEntries)
 		_, err = repository.PutTimelineEntries(updatedEntries)
 		if err != nil {
-			log.Errorf("Failed to update timeline entries: %v", err)
+			logger.Errorf("Failed to update timeline entries: %v", err)
 			return
 		}
 
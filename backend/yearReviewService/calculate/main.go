@@ -1,14 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"runtime/debug"
-	"strconv"
 	"strings"
 
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/api/log"
+	"github.com/jackstenglein/chess-dojo-scheduler/backend/cohorts"
 	"github.com/jackstenglein/chess-dojo-scheduler/backend/database"
 )
 
@@ -19,7 +20,7 @@ const END_DATE = PERIOD + "-12-31"
 const PREFERRED = "preferred"
 const ACTIVE_DEADLINE = PERIOD + "-09-31"
 
-var repository = database.DynamoDB
+var repository = database.Repository[database.YearReviewCalculator](database.DynamoDB)
 
 type percentileTrackers struct {
 	ratings    map[string]map[database.DojoCohort][]float32
@@ -55,7 +56,8 @@ func main() {
 		}
 	}()
 
-	requirements, err := fetchRequirements()
+	ctx := context.Background()
+	requirements, err := fetchRequirements(ctx)
 	if err != nil {
 		log.Errorf("Failed to get requirements: %v", err)
 		os.Exit(1)
@@ -68,23 +70,17 @@ func main() {
 	var reviews []*database.YearReview
 	log.Debug("Scanning users")
 
-	var users []*database.User
-	var startKey = ""
-	for ok := true; ok; ok = startKey != "" {
-		users, startKey, err = repository.ScanUsers(startKey)
+	for u, err := range database.All(ctx, repository.ScanUsers) {
 		if err != nil {
 			log.Errorf("Failed to scan users: %v", err)
 			os.Exit(1)
 		}
 
-		log.Infof("Processing %d users", len(users))
-		for _, u := range users {
-			review, err := processUser(u, dojoRequirements)
-			if err != nil {
-				log.Errorf("Failed to process user %s: %v", u.Username, err)
-			} else if review != nil {
-				reviews = append(reviews, review)
-			}
+		review, err := processUser(ctx, u, dojoRequirements)
+		if err != nil {
+			log.Errorf("Failed to process user %s: %v", u.Username, err)
+		} else if review != nil {
+			reviews = append(reviews, review)
 		}
 	}
 
@@ -136,19 +132,14 @@ func readFromFile() ([]*database.YearReview, error) {
 	return data, nil
 }
 
-func fetchRequirements() ([]*database.Requirement, error) {
+func fetchRequirements(ctx context.Context) ([]*database.Requirement, error) {
 	log.Debug("Fetching requirements\n")
-	var requirements []*database.Requirement
-	var rs []*database.Requirement
-	var startKey string
-	var err error
-	for ok := true; ok; ok = startKey != "" {
-		rs, startKey, err = repository.ScanRequirements("", startKey)
-		if err != nil {
-			log.Errorf("Failed to scan requirements: %v\n", err)
-			return nil, err
-		}
-		requirements = append(requirements, rs...)
+	requirements, err := database.Collect(ctx, func(startKey string) ([]*database.Requirement, string, error) {
+		return repository.ScanRequirements("", startKey)
+	})
+	if err != nil {
+		log.Errorf("Failed to scan requirements: %v\n", err)
+		return nil, err
 	}
 	log.Debugf("Got %d requirements\n", len(requirements))
 	return requirements, nil
@@ -158,7 +149,7 @@ func isUserActive(user *database.User) bool {
 	return user.UpdatedAt >= ACTIVE_DEADLINE
 }
 
-func processUser(user *database.User, dojoRequirements map[string]*database.Requirement) (*database.YearReview, error) {
+func processUser(ctx context.Context, user *database.User, dojoRequirements map[string]*database.Requirement) (*database.YearReview, error) {
 	if !user.HasCreatedProfile || user.Ratings == nil || !user.DojoCohort.IsValid() {
 		log.Debugf("Skipping user %s because they have not created profile", user.Username)
 		return nil, nil
@@ -176,17 +167,21 @@ func processUser(user *database.User, dojoRequirements map[string]*database.Requ
 		Graduations:   []database.DojoCohort{},
 	}
 
-	processRatings(user, &yearReview)
+	ratingHistories, err := database.GetRatingHistories(repository, user, "")
+	if err != nil {
+		return nil, err
+	}
+	processRatings(user, ratingHistories, &yearReview)
 
-	if err := processGraduations(user, &yearReview); err != nil {
+	if err := processGraduations(ctx, user, &yearReview); err != nil {
 		return nil, err
 	}
 
-	if err := processGames(user, &yearReview); err != nil {
+	if err := processGames(ctx, user, &yearReview); err != nil {
 		return nil, err
 	}
 
-	if err := processTimeline(user, &yearReview, dojoRequirements); err != nil {
+	if err := processTimeline(ctx, user, &yearReview, dojoRequirements); err != nil {
 		return nil, err
 	}
 	return &yearReview, nil
@@ -216,8 +211,8 @@ func initializeYearReviewData() *database.YearReviewData {
 	}
 }
 
-func processRatings(user *database.User, review *database.YearReview) {
-	for rs, history := range user.RatingHistories {
+func processRatings(user *database.User, ratingHistories map[database.RatingSystem][]database.RatingHistory, review *database.YearReview) {
+	for rs, history := range ratingHistories {
 		if user.Ratings[rs] == nil {
 			continue
 		}
@@ -275,8 +270,11 @@ func processRatings(user *database.User, review *database.YearReview) {
 			percentiles.ratings[string(rs)][database.AllCohorts] = append(percentiles.ratings[string(rs)][database.AllCohorts], float32(currentRating))
 			percentiles.ratings[string(rs)][user.DojoCohort] = append(percentiles.ratings[string(rs)][user.DojoCohort], float32(currentRating))
 
-			if isPreferred {
-				normalizedRating := getNormalizedRating(currentRating, rs)
+			// Ratings are normalized with the boundaries used for cohort assignment, so FIDE
+			// ratings below 1400 fall in the cohorts below 900. Earlier year reviews used no FIDE
+			// boundaries below 1450 and normalized those ratings to 900-1000 instead.
+			if normalized, ok := cohorts.At(END_DATE).Normalize(currentRating, rs); isPreferred && ok {
+				normalizedRating := float32(normalized)
 				percentiles.ratings[PREFERRED][database.AllCohorts] = append(percentiles.ratings[PREFERRED][database.AllCohorts], normalizedRating)
 				percentiles.ratings[PREFERRED][user.DojoCohort] = append(percentiles.ratings[PREFERRED][user.DojoCohort], normalizedRating)
 			}
@@ -284,90 +282,81 @@ func processRatings(user *database.User, review *database.YearReview) {
 	}
 }
 
-func processGraduations(user *database.User, review *database.YearReview) error {
-	var graduations []database.Graduation
-	var startKey = ""
-	var err error
-
-	for ok := true; ok; ok = startKey != "" {
-		graduations, startKey, err = repository.ListGraduationsByOwner(user.Username, startKey)
+func processGraduations(ctx context.Context, user *database.User, review *database.YearReview) error {
+	graduations := database.All(ctx, func(startKey string) ([]database.Graduation, string, error) {
+		return repository.ListGraduationsByOwner(user.Username, startKey)
+	})
+	for grad, err := range graduations {
 		if err != nil {
 			return err
 		}
-
-		for _, grad := range graduations {
-			if grad.CreatedAt >= START_DATE {
-				review.Graduations = append(review.Graduations, grad.PreviousCohort)
-			}
+		if grad.CreatedAt >= START_DATE {
+			review.Graduations = append(review.Graduations, grad.PreviousCohort)
 		}
 	}
 	return nil
 }
 
-func processGames(user *database.User, review *database.YearReview) error {
-	var games []*database.Game
-	var startKey = ""
-	var err error
-
-	for ok := true; ok; ok = startKey != "" {
-		games, startKey, err = repository.ListGamesByOwner(true, user.Username, strings.ReplaceAll(START_DATE, "-", "."), strings.ReplaceAll(END_DATE, "-", "."), startKey)
+func processGames(ctx context.Context, user *database.User, review *database.YearReview) error {
+	games := database.All(ctx, func(startKey string) ([]*database.Game, string, error) {
+		return repository.ListGamesByOwner(true, user.Username, strings.ReplaceAll(START_DATE, "-", "."), strings.ReplaceAll(END_DATE, "-", "."), startKey)
+	})
+	for g, err := range games {
 		if err != nil {
 			return err
 		}
 
-		for _, g := range games {
-			month := strings.Split(strings.Split(g.Id, "_")[0], ".")[1]
-			review.Total.Games.Total.Value += 1
+		month := strings.Split(strings.Split(g.Id, "_")[0], ".")[1]
+		review.Total.Games.Total.Value += 1
 
+		if g.Unlisted {
+			review.Total.Games.ByPeriod[fmt.Sprintf("%s-hidden", month)] += 1
+		} else {
+			review.Total.Games.Published.Value += 1
+			review.Total.Games.ByPeriod[month] += 1
+		}
+
+		result := g.Headers["Result"]
+		if result == "1/2-1/2" {
 			if g.Unlisted {
-				review.Total.Games.ByPeriod[fmt.Sprintf("%s-hidden", month)] += 1
+				review.Total.Games.DrawHidden += 1
 			} else {
-				review.Total.Games.Published.Value += 1
-				review.Total.Games.ByPeriod[month] += 1
+				review.Total.Games.Draw.Value += 1
 			}
-
-			result := g.Headers["Result"]
-			if result == "1/2-1/2" {
+		} else if result == "1-0" {
+			if g.Orientation == "black" {
 				if g.Unlisted {
-					review.Total.Games.DrawHidden += 1
+					review.Total.Games.LossHidden += 1
 				} else {
-					review.Total.Games.Draw.Value += 1
+					review.Total.Games.Loss.Value += 1
 				}
-			} else if result == "1-0" {
-				if g.Orientation == "black" {
-					if g.Unlisted {
-						review.Total.Games.LossHidden += 1
-					} else {
-						review.Total.Games.Loss.Value += 1
-					}
+			} else {
+				if g.Unlisted {
+					review.Total.Games.WinHidden += 1
 				} else {
-					if g.Unlisted {
-						review.Total.Games.WinHidden += 1
-					} else {
-						review.Total.Games.Win.Value += 1
-					}
+					review.Total.Games.Win.Value += 1
 				}
-			} else if result == "0-1" {
-				if g.Orientation == "black" {
-					if g.Unlisted {
-						review.Total.Games.WinHidden += 1
-					} else {
-						review.Total.Games.Win.Value += 1
-					}
+			}
+		} else if result == "0-1" {
+			if g.Orientation == "black" {
+				if g.Unlisted {
+					review.Total.Games.WinHidden += 1
 				} else {
-					if g.Unlisted {
-						review.Total.Games.LossHidden += 1
-					} else {
-						review.Total.Games.Loss.Value += 1
-					}
+					review.Total.Games.Win.Value += 1
 				}
 			} else {
 				if g.Unlisted {
-					review.Total.Games.AnalysisHidden += 1
+					review.Total.Games.LossHidden += 1
 				} else {
-					review.Total.Games.Analysis.Value += 1
+					review.Total.Games.Loss.Value += 1
 				}
 			}
+		} else {
+			if g.Unlisted {
+				review.Total.Games.AnalysisHidden += 1
+			} else {
+				review.Total.Games.Analysis.Value += 1
+			}
 		}
 	}
 
@@ -394,48 +383,44 @@ func processGames(user *database.User, review *database.YearReview) error {
 	return nil
 }
 
-func processTimeline(user *database.User, review *database.YearReview, requirements map[string]*database.Requirement) error {
-	var timeline []*database.TimelineEntry
-	var startKey = ""
-	var err error
-
-	for ok := true; ok; ok = startKey != "" {
-		timeline, startKey, err = repository.ListTimelineEntries(user.Username, startKey)
+func processTimeline(ctx context.Context, user *database.User, review *database.YearReview, requirements map[string]*database.Requirement) error {
+	timeline := database.All(ctx, func(startKey string) ([]*database.TimelineEntry, string, error) {
+		return repository.ListTimelineEntries(user.Username, startKey)
+	})
+	for t, err := range timeline {
 		if err != nil {
 			return err
 		}
 
-		for _, t := range timeline {
-			if t.RequirementCategory == "" {
-				continue
-			}
+		if t.RequirementCategory == "" {
+			continue
+		}
 
-			date := t.Id[0:10]
-			if date < START_DATE || date > END_DATE {
-				continue
-			}
-			month := strings.Split(date, "-")[1]
+		date := t.Id[0:10]
+		if date < START_DATE || date > END_DATE {
+			continue
+		}
+		month := strings.Split(date, "-")[1]
 
-			points := t.DojoPoints
-			if points == 0 {
-				points = calculatePoints(t, requirements)
-			}
+		points := t.DojoPoints
+		if points == 0 {
+			points = calculatePoints(t, requirements)
+		}
 
-			requirementName := t.RequirementName
-			if requirementName == "GameSubmission" {
-				requirementName = "Annotate Games"
-			}
+		requirementName := t.RequirementName
+		if requirementName == "GameSubmission" {
+			requirementName = "Annotate Games"
+		}
 
-			review.Total.MinutesSpent.Total.Value += t.MinutesSpent
-			review.Total.MinutesSpent.ByPeriod[month] += t.MinutesSpent
-			review.Total.MinutesSpent.ByCategory[t.RequirementCategory] += t.MinutesSpent
-			review.Total.MinutesSpent.ByTask[requirementName] += t.MinutesSpent
+		review.Total.MinutesSpent.Total.Value += t.MinutesSpent
+		review.Total.MinutesSpent.ByPeriod[month] += t.MinutesSpent
+		review.Total.MinutesSpent.ByCategory[t.RequirementCategory] += t.MinutesSpent
+		review.Total.MinutesSpent.ByTask[requirementName] += t.MinutesSpent
 
-			review.Total.DojoPoints.Total.Value += points
-			review.Total.DojoPoints.ByPeriod[month] += points
-			review.Total.DojoPoints.ByCategory[t.RequirementCategory] += points
-			review.Total.DojoPoints.ByTask[requirementName] += points
-		}
+		review.Total.DojoPoints.Total.Value += points
+		review.Total.DojoPoints.ByPeriod[month] += points
+		review.Total.DojoPoints.ByCategory[t.RequirementCategory] += points
+		review.Total.DojoPoints.ByTask[requirementName] += points
 	}
 
 	if isUserActive(user) {
@@ -549,356 +534,3 @@ func calculatePercentile(dataset []float32, value float32) float32 {
 	}
 	return float32(lower) / float32(len(dataset)) * 100
 }
-
-func getNormalizedRating(rating int, ratingSystem database.RatingSystem) float32 {
-	if ratingSystem == database.Custom {
-		return -1
-	}
-
-	for _, cohort := range database.Cohorts {
-		x2 := getRatingBoundary(cohort, ratingSystem)
-		if x2 <= 0 {
-			continue
-		}
-
-		if x2 >= rating {
-			x1 := getMinRatingBoundary(cohort, ratingSystem)
-
-			y1, y2 := getCohortRangeInt(cohort)
-
-			if y1 == -1 {
-				y1 = 0
-			}
-			if y2 == -1 {
-				y2 = 0
-			}
-
-			result := (float32(y2-y1)/float32(x2-x1))*float32(rating-x1) + float32(y1)
-			return result
-		}
-	}
-
-	// We are in the 2400+ cohort if we make it here, so we just extrapolate from the 2300-2400 line
-	x1 := getMinRatingBoundary("2300-2400", ratingSystem)
-	x2 := getRatingBoundary("2300-2400", ratingSystem)
-	y1 := 2300
-	y2 := 2400
-	result := (float32(y2-y1)/float32(x2-x1))*float32(rating-x1) + float32(y1)
-	return result
-}
-
-func getRatingBoundary(cohort database.DojoCohort, ratingSystem database.RatingSystem) int {
-	cohortBoundaries := ratingBoundaries[cohort]
-	if cohortBoundaries == nil {
-		return -1
-	}
-
-	boundary := cohortBoundaries[ratingSystem]
-	if boundary <= 0 {
-		return -1
-	}
-	return boundary
-}
-
-func getMinRatingBoundary(cohort database.DojoCohort, ratingSystem database.RatingSystem) int {
-	cohortIdx := 0
-	for i, c := range database.Cohorts {
-		if c == cohort {
-			cohortIdx = i
-			break
-		}
-	}
-
-	if cohortIdx <= 0 {
-		return 0
-	}
-	return getRatingBoundary(database.Cohorts[cohortIdx-1], ratingSystem)
-}
-
-func getCohortRangeInt(cohort database.DojoCohort) (int, int) {
-	strCohort := strings.ReplaceAll(string(cohort), "+", "")
-	tokens := strings.Split(strCohort, "-")
-
-	minCohort, err := strconv.Atoi(tokens[0])
-	if err != nil {
-		return -1, -1
-	}
-
-	if len(tokens) < 2 {
-		return minCohort, 2500
-	}
-
-	maxCohort, err := strconv.Atoi((tokens[1]))
-	if err != nil {
-		return minCohort, 2500
-	}
-
-	return minCohort, maxCohort
-}
-
-var ratingBoundaries = map[database.DojoCohort]map[database.RatingSystem]int{
-	"0-300": {
-		database.Chesscom: 550,
-		database.Lichess:  1250,
-		database.Fide:     0,
-		database.Uscf:     350,
-		database.Ecf:      400,
-		database.Cfc:      350,
-		database.Dwz:      450,
-		database.Acf:      300,
-		database.Knsb:     400,
-		database.Custom:   -1,
-	},
-	"300-400": {
-		database.Chesscom: 650,
-		database.Lichess:  1310,
-		database.Fide:     0,
-		database.Uscf:     460,
-		database.Ecf:      625,
-		database.Cfc:      460,
-		database.Dwz:      540,
-		database.Acf:      395,
-		database.Knsb:     600,
-		database.Custom:   -1,
-	},
-	"400-500": {
-		database.Chesscom: 750,
-		database.Lichess:  1370,
-		database.Fide:     0,
-		database.Uscf:     570,
-		database.Ecf:      850,
-		database.Cfc:      570,
-		database.Dwz:      630,
-		database.Acf:      490,
-		database.Knsb:     800,
-		database.Custom:   -1,
-	},
-	"500-600": {
-		database.Chesscom: 850,
-		database.Lichess:  1435,
-		database.Fide:     0,
-		database.Uscf:     680,
-		database.Ecf:      1000,
-		database.Cfc:      680,
-		database.Dwz:      725,
-		database.Acf:      585,
-		database.Knsb:     1000,
-		database.Custom:   -1,
-	},
-	"600-700": {
-		database.Chesscom: 950,
-		database.Lichess:  1500,
-		database.Fide:     0,
-		database.Uscf:     790,
-		database.Ecf:      1130,
-		database.Cfc:      780,
-		database.Dwz:      815,
-		database.Acf:      680,
-		database.Knsb:     1140,
-		database.Custom:   -1,
-	},
-	"700-800": {
-		database.Chesscom: 1050,
-		database.Lichess:  1550,
-		database.Fide:     0,
-		database.Uscf:     900,
-		database.Ecf:      1210,
-		database.Cfc:      880,
-		database.Dwz:      920,
-		database.Acf:      775,
-		database.Knsb:     1280,
-		database.Custom:   -1,
-	},
-	"800-900": {
-		database.Chesscom: 1150,
-		database.Lichess:  1600,
-		database.Fide:     0,
-		database.Uscf:     1010,
-		database.Ecf:      1270,
-		database.Cfc:      980,
-		database.Dwz:      1025,
-		database.Acf:      870,
-		database.Knsb:     1400,
-		database.Custom:   -1,
-	},
-	"900-1000": {
-		database.Fide:     1450,
-		database.Uscf:     1120,
-		database.Chesscom: 1250,
-		database.Lichess:  1665,
-		database.Ecf:      1325,
-		database.Cfc:      1090,
-		database.Dwz:      1110,
-		database.Acf:      990,
-		database.Knsb:     1450,
-		database.Custom:   -1,
-	},
-	"1000-1100": {
-		database.Fide:     1500,
-		database.Uscf:     1230,
-		database.Chesscom: 1350,
-		database.Lichess:  1730,
-		database.Ecf:      1390,
-		database.Cfc:      1200,
-		database.Dwz:      1185,
-		database.Acf:      1100,
-		database.Knsb:     1500,
-		database.Custom:   -1,
-	},
-	"1100-1200": {
-		database.Fide:     1550,
-		database.Uscf:     1330,
-		database.Chesscom: 1450,
-		database.Lichess:  1795,
-		database.Ecf:      1455,
-		database.Cfc:      1300,
-		database.Dwz:      1260,
-		database.Acf:      1210,
-		database.Knsb:     1550,
-		database.Custom:   -1,
-	},
-	"1200-1300": {
-		database.Fide:     1600,
-		database.Uscf:     1420,
-		database.Chesscom: 1550,
-		database.Lichess:  1850,
-		database.Ecf:      1535,
-		database.Cfc:      1390,
-		database.Dwz:      1335,
-		database.Acf:      1320,
-		database.Knsb:     1600,
-		database.Custom:   -1,
-	},
-	"1300-1400": {
-		database.Fide:     1650,
-		database.Uscf:     1510,
-		database.Chesscom: 1650,
-		database.Lichess:  1910,
-		database.Ecf:      1595,
-		database.Cfc:      1480,
-		database.Dwz:      1410,
-		database.Acf:      1415,
-		database.Knsb:     1650,
-		database.Custom:   -1,
-	},
-	"1400-1500": {
-		database.Fide:     1700,
-		database.Uscf:     1600,
-		database.Chesscom: 1750,
-		database.Lichess:  1970,
-		database.Ecf:      1665,
-		database.Cfc:      1570,
-		database.Dwz:      1480,
-		database.Acf:      1510,
-		database.Knsb:     1700,
-		database.Custom:   -1,
-	},
-	"1500-1600": {
-		database.Fide:     1750,
-		database.Uscf:     1675,
-		database.Chesscom: 1850,
-		database.Lichess:  2030,
-		database.Ecf:      1735,
-		database.Cfc:      1645,
-		database.Dwz:      1560,
-		database.Acf:      1605,
-		database.Knsb:     1750,
-		database.Custom:   -1,
-	},
-	"1600-1700": {
-		database.Fide:     1800,
-		database.Uscf:     1750,
-		database.Chesscom: 1950,
-		database.Lichess:  2090,
-		database.Ecf:      1805,
-		database.Cfc:      1730,
-		database.Dwz:      1640,
-		database.Acf:      1700,
-		database.Knsb:     1800,
-		database.Custom:   -1,
-	},
-	"1700-1800": {
-		database.Fide:     1850,
-		database.Uscf:     1825,
-		database.Chesscom: 2050,
-		database.Lichess:  2150,
-		database.Ecf:      1875,
-		database.Cfc:      1825,
-		database.Dwz:      1720,
-		database.Acf:      1790,
-		database.Knsb:     1850,
-		database.Custom:   -1,
-	},
-	"1800-1900": {
-		database.Fide:     1910,
-		database.Uscf:     1930,
-		database.Chesscom: 2165,
-		database.Lichess:  2225,
-		database.Ecf:      1955,
-		database.Cfc:      1925,
-		database.Dwz:      1815,
-		database.Acf:      1900,
-		database.Knsb:     1910,
-		database.Custom:   -1,
-	},
-	"1900-2000": {
-		database.Fide:     2000,
-		database.Uscf:     2055,
-		database.Chesscom: 2275,
-		database.Lichess:  2310,
-		database.Ecf:      2065,
-		database.Cfc:      2060,
-		database.Dwz:      1940,
-		database.Acf:      2000,
-		database.Knsb:     2000,
-		database.Custom:   -1,
-	},
-	"2000-2100": {
-		database.Fide:     2100,
-		database.Uscf:     2185,
-		database.Chesscom: 2360,
-		database.Lichess:  2370,
-		database.Ecf:      2165,
-		database.Cfc:      2185,
-		database.Dwz:      2070,
-		database.Acf:      2105,
-		database.Knsb:     2100,
-		database.Custom:   -1,
-	},
-	"2100-2200": {
-		database.Fide:     2200,
-		database.Uscf:     2290,
-		database.Chesscom: 2425,
-		database.Lichess:  2410,
-		database.Ecf:      2260,
-		database.Cfc:      2290,
-		database.Dwz:      2185,
-		database.Acf:      2215,
-		database.Knsb:     2200,
-		database.Custom:   -1,
-	},
-	"2200-2300": {
-		database.Fide:     2300,
-		database.Uscf:     2395,
-		database.Chesscom: 2485,
-		database.Lichess:  2440,
-		database.Ecf:      2360,
-		database.Cfc:      2395,
-		database.Dwz:      2285,
-		database.Acf:      2330,
-		database.Knsb:     2300,
-		database.Custom:   -1,
-	},
-	"2300-2400": {
-		database.Fide:     2400,
-		database.Uscf:     2500,
-		database.Chesscom: 2550,
-		database.Lichess:  2470,
-		database.Ecf:      2460,
-		database.Cfc:      2500,
-		database.Dwz:      2385,
-		database.Acf:      2450,
-		database.Knsb:     2400,
-		database.Custom:   -1,
-	},
-}
@@ -36,6 +36,7 @@ export interface User {
     progress: Record<string, RequirementProgress>;
     disableBookingNotifications: boolean;
     disableCancellationNotifications: boolean;
+    roles?: string[];
     isAdmin: boolean;
     isCalendarAdmin: boolean;
     isTournamentAdmin: boolean;
@@ -43,6 +44,11 @@ export interface User {
     isCoach: boolean;
     createdAt: string;
     updatedAt: string;
+    /**
+     * The version of the user, incremented on every update. Passing it back in an
+     * update request fails the request with a 409 if the user was changed since it was read.
+     */
+    version?: number;
     numberOfGraduations: number;
     previousCohort: string;
     graduationCohorts?: string[];